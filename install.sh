@@ -144,10 +144,12 @@ download_scripts() {
         "router/opt/vpn-director/lib/ipset.sh" \
         "router/opt/vpn-director/lib/tunnel.sh" \
         "router/opt/vpn-director/lib/tproxy.sh" \
+        "router/opt/vpn-director/lib/ipv6.sh" \
         "router/opt/vpn-director/lib/send-email.sh" \
         "router/opt/vpn-director/setup_telegram_bot.sh" \
         "router/jffs/scripts/firewall-start" \
         "router/jffs/scripts/wan-event" \
+        "router/jffs/scripts/dnsmasq.postconf" \
         "router/opt/etc/init.d/S99vpn-director" \
         "router/opt/etc/init.d/S98telegram-bot" \
         "router/opt/etc/init.d/S98vpn-director-webui"
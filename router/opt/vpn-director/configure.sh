@@ -426,8 +426,13 @@ step_generate_configs() {
     # Generate xray/config.json from template
     print_info "Generating Xray config..."
 
-    sed "s|{{XRAY_SERVER_ADDRESS}}|$SELECTED_SERVER_ADDRESS|g" \
+    # Dual-stack listener is required for IPv6 TPROXY
+    local xray_listen="0.0.0.0"
+    [[ -e /proc/net/if_inet6 ]] && xray_listen="::"
+
+    sed "s|{{XRAY_SERVER_ADDRESS}}|${SELECTED_SERVER_ADDRESS//[\[\]]/}|g" \
         /opt/etc/xray/config.json.template 2>/dev/null | \
+        sed "s|{{XRAY_LISTEN}}|$xray_listen|g" | \
         sed "s|{{XRAY_SERVER_PORT}}|$SELECTED_SERVER_PORT|g" | \
         sed "s|{{XRAY_USER_UUID}}|$SELECTED_SERVER_UUID|g" \
         > "$XRAY_CONFIG_DIR/config.json"
@@ -213,33 +213,38 @@ step_parse_and_save_servers() {
             continue
         fi
 
-        # Resolve ALL IPv4 addresses for the server hostname
+        # Resolve ALL IPv4 and IPv6 addresses for the server hostname
         ips_raw=$(resolve_ip -a -q "$server" 2>/dev/null) || ips_raw=""
+        ips6_raw=$(resolve_ip -6 -g -a -q "$server" 2>/dev/null) || ips6_raw=""
 
-        if [[ -z "$ips_raw" ]]; then
+        if [[ -z "$ips_raw" ]] && [[ -z "$ips6_raw" ]]; then
             log -l DEBUG "SKIP: cannot resolve $server"
             log -l WARN "Cannot resolve $server, skipping"
             continue
         fi
 
-        ips_oneline=$(printf '%s' "$ips_raw" | tr '\n' ',' | sed 's/,$//')
+        # Pack multi-line IPs into comma-separated for the pipe
+        ips_csv=$(printf '%s' "$ips_raw" | tr '\n' ',' | sed 's/,$//')
+        ips6_csv=$(printf '%s' "$ips6_raw" | tr '\n' ',' | sed 's/,$//')
+
+        ips_oneline=$(printf '%s,%s' "$ips_csv" "$ips6_csv" | sed 's/^,//; s/,$//')
         log -l DEBUG "Resolved: $server -> $ips_oneline"
         printf "  %s (%s) -> %s\n" "$name" "$server" "$ips_oneline" >&2
 
-        # Pack multi-line IPs into comma-separated for the pipe
-        ips_csv=$(printf '%s' "$ips_raw" | tr '\n' ',' | sed 's/,$//')
-        printf '%s\n%s\n%s\n%s\n%s\n' "$server" "$port" "$uuid" "$name" "$ips_csv"
+        printf '%s\n%s\n%s\n%s\n%s\n%s\n' "$server" "$port" "$uuid" "$name" "$ips_csv" "$ips6_csv"
     done | {
         # Build JSON from piped data using jq for proper escaping
         printf '[\n'
         first=1
-        while IFS= read -r server && IFS= read -r port && IFS= read -r uuid && IFS= read -r name && IFS= read -r ips_csv; do
+        while IFS= read -r server && IFS= read -r port && IFS= read -r uuid && IFS= read -r name &&
+              IFS= read -r ips_csv && IFS= read -r ips6_csv; do
             [[ -z "$server" ]] && continue
             [[ "$first" -eq 0 ]] && printf ',\n'
             first=0
 
             # Build ips JSON array from comma-separated IPs
             ips_json=$(printf '%s\n' "$ips_csv" | tr ',' '\n' | jq -R 'select(length > 0)' | jq -s .)
+            ips6_json=$(printf '%s\n' "$ips6_csv" | tr ',' '\n' | jq -R 'select(length > 0)' | jq -s .)
 
             # Use jq to create properly escaped JSON object
             jq -n \
@@ -248,7 +253,9 @@ step_parse_and_save_servers() {
                 --arg uuid "$uuid" \
                 --arg name "$name" \
                 --argjson ips "$ips_json" \
-                '{address: $addr, port: ($port | tonumber), uuid: $uuid, name: $name, ips: $ips}' | tr -d '\n'
+                --argjson ips6 "$ips6_json" \
+                '{address: $addr, port: ($port | tonumber), uuid: $uuid, name: $name, ips: $ips}
+                 + (if ($ips6 | length) > 0 then {ips6: $ips6} else {} end)' | tr -d '\n'
         done
         printf '\n]\n'
     } > "$SERVERS_FILE"
@@ -68,6 +68,7 @@ XRAY_CLIENTS=$(_cfg_arr_active '.xray.clients')
 XRAY_SERVERS=$(_cfg_arr '.xray.servers')
 XRAY_EXCLUDE_IPS=$(_cfg_arr '.xray.exclude_ips')
 XRAY_EXCLUDE_SETS=$(_cfg_arr '.xray.exclude_sets')
+XRAY_EXCLUDE_DOMAINS=$(_cfg_arr '.xray.exclude_domains')
 
 ###################################################################################################
 # 6. Advanced: Xray
@@ -96,15 +97,21 @@ MIN_BOOT_TIME=$(_cfg '.advanced.boot.min_time')
 BOOT_WAIT_DELAY=$(_cfg '.advanced.boot.wait_delay')
 
 ###################################################################################################
-# 9. Make all variables read-only
+# 9. Advanced: IPv6 (block_leaks defaults to true; `// empty` would also swallow false)
+###################################################################################################
+IPV6_BLOCK_LEAKS=$(jq -r 'if .advanced.ipv6.block_leaks == false then 0 else 1 end' "$VPD_CONFIG_FILE")
+
+###################################################################################################
+# 10. Make all variables read-only
 ###################################################################################################
 readonly \
     VPD_CONFIG_FILE \
     TUN_DIR_TUNNELS_JSON IPS_BDR_DIR \
-    XRAY_CLIENTS XRAY_SERVERS XRAY_EXCLUDE_IPS XRAY_EXCLUDE_SETS \
+    XRAY_CLIENTS XRAY_SERVERS XRAY_EXCLUDE_IPS XRAY_EXCLUDE_SETS XRAY_EXCLUDE_DOMAINS \
     XRAY_TPROXY_PORT XRAY_ROUTE_TABLE XRAY_RULE_PREF \
     XRAY_FWMARK XRAY_FWMARK_MASK XRAY_CHAIN \
     XRAY_CLIENTS_IPSET XRAY_BYPASS_IPSET \
     TUN_DIR_CHAIN TUN_DIR_PREF_BASE \
     TUN_DIR_MARK_MASK TUN_DIR_MARK_SHIFT \
-    MIN_BOOT_TIME BOOT_WAIT_DELAY
+    MIN_BOOT_TIME BOOT_WAIT_DELAY \
+    IPV6_BLOCK_LEAKS
@@ -0,0 +1,262 @@
+#!/usr/bin/env bash
+
+###################################################################################################
+# ipv6.sh - IPv6 leak block module for VPN Director
+# -------------------------------------------------------------------------------------------------
+# Purpose:
+#   Routed clients configured by IPv4 address still get native IPv6 from the router, and that
+#   traffic bypasses Xray and the tunnels. This module rejects forwarded IPv6 traffic from such
+#   clients so applications fall back to IPv4 (which is routed).
+#
+#   Blocked:
+#     - Xray and Tunnel Director IPv4 clients, matched by MAC address (looked up in the
+#       neighbour table and dnsmasq leases at apply time; subnets via the neighbour table only)
+#     - Tunnel Director IPv6 clients, matched by source (OpenVPN/WireGuard client tunnels do
+#       not carry IPv6)
+#   Not blocked:
+#     - Xray IPv6 clients: TPROXY delivers their traffic locally, so it never hits FORWARD.
+#       Sources in the Xray IPv6 clients ipset are exempt even if their MAC is blocked.
+#
+# Dependencies:
+#   - common.sh (log, get_ipv6_enabled)
+#   - firewall.sh (create_fw_chain, delete_fw_chain, ensure_fw_rule, sync_fw_rule,
+#                  purge_fw_rules, fw_chain_exists)
+#   - config.sh (IPV6_BLOCK_LEAKS, XRAY_CLIENTS, XRAY_CLIENTS_IPSET, TUN_DIR_TUNNELS_JSON)
+#
+# Public API:
+#   ipv6_status()  - show leak block chain
+#   ipv6_apply()   - build leak block rules (idempotent)
+#   ipv6_stop()    - remove leak block rules
+#
+# Internal functions (for testing):
+#   _ipv6_client_macs()     - print MAC addresses of an IPv4 client (host or subnet)
+#   _ipv6_routed_clients()  - print active clients of all routes, one per line
+#   _ipv6_init()            - initialize module state
+#
+# Usage:
+#   source lib/ipv6.sh              # source and run main if any
+#   source lib/ipv6.sh --source-only # source only for testing
+###################################################################################################
+
+# -------------------------------------------------------------------------------------------------
+# Disable unneeded shellcheck warnings
+# -------------------------------------------------------------------------------------------------
+# shellcheck disable=SC2086
+
+# -------------------------------------------------------------------------------------------------
+# Abort script on any error
+# -------------------------------------------------------------------------------------------------
+set -euo pipefail
+
+# -------------------------------------------------------------------------------------------------
+# Debug mode: set DEBUG=1 to enable tracing
+# -------------------------------------------------------------------------------------------------
+if [[ ${DEBUG:-0} == 1 ]]; then
+    set -x
+    PS4='+${BASH_SOURCE[0]##*/}:${LINENO}:${FUNCNAME[0]:-main}: '
+fi
+
+###################################################################################################
+# Module state variables (initialized by _ipv6_init)
+###################################################################################################
+
+# Leak block chain in the ip6tables filter table
+IPV6_LEAK_CHAIN="VPD_LEAK6"
+
+# dnsmasq lease file (can be overridden for testing)
+DNSMASQ_LEASES_FILE="${DNSMASQ_LEASES_FILE:-/var/lib/misc/dnsmasq.leases}"
+
+# Initialization flag
+_ipv6_initialized=0
+_ipv6_enabled=0
+
+###################################################################################################
+# Internal helper functions (defined before --source-only for testability)
+###################################################################################################
+
+# -------------------------------------------------------------------------------------------------
+# _ipv6_init - initialize module state
+# -------------------------------------------------------------------------------------------------
+# Safe to call multiple times (idempotent).
+# -------------------------------------------------------------------------------------------------
+_ipv6_init() {
+    [[ $_ipv6_initialized -eq 1 ]] && return 0
+
+    _ipv6_enabled=$(get_ipv6_enabled)
+
+    _ipv6_initialized=1
+}
+
+# -------------------------------------------------------------------------------------------------
+# _ipv6_client_macs - print MAC addresses of an IPv4 client (host or subnet)
+# -------------------------------------------------------------------------------------------------
+# Input: IPv4 address or CIDR
+# Output: lower-case MAC addresses, one per line (may be empty)
+# Hosts fall back to the dnsmasq lease file when not in the neighbour table.
+# -------------------------------------------------------------------------------------------------
+_ipv6_client_macs() {
+    local client="$1"
+    local macs
+
+    macs=$(ip -4 neigh show to "$client" 2>/dev/null |
+        awk '{ for (i = 1; i < NF; i++) if ($i == "lladdr") print $(i + 1) }')
+
+    if [[ -z $macs ]] && [[ $client != */* ]] && [[ -f $DNSMASQ_LEASES_FILE ]]; then
+        macs=$(awk -v ip="$client" '$3 == ip { print $2 }' "$DNSMASQ_LEASES_FILE")
+    fi
+
+    [[ -n $macs ]] || return 0
+    printf '%s\n' "$macs" | tr 'A-F' 'a-f' | sort -u
+}
+
+# -------------------------------------------------------------------------------------------------
+# _ipv6_routed_clients - print active clients of all routes, one per line
+# -------------------------------------------------------------------------------------------------
+# Output format: "<route> <client>" (route is "xray" or the tunnel name)
+# Paused clients are already removed by config.sh.
+# -------------------------------------------------------------------------------------------------
+_ipv6_routed_clients() {
+    local client
+    local -a clients_array=()
+
+    if [[ -n ${XRAY_CLIENTS:-} ]]; then
+        read -ra clients_array <<< "$XRAY_CLIENTS"
+        for client in "${clients_array[@]}"; do
+            [[ -n $client ]] && printf 'xray %s\n' "$client"
+        done
+    fi
+
+    if [[ -n $TUN_DIR_TUNNELS_JSON ]] && [[ $TUN_DIR_TUNNELS_JSON != "{}" ]]; then
+        printf '%s\n' "$TUN_DIR_TUNNELS_JSON" | jq -r '
+            to_entries[]
+            | select(.value | type == "object")
+            | .key as $t
+            | (.value.clients // [] | if type == "array" then .[] else empty end)
+            | "\($t) \(.)"
+        ' 2>/dev/null || true
+    fi
+}
+
+###################################################################################################
+# Public API (defined before --source-only for testability)
+###################################################################################################
+
+# -------------------------------------------------------------------------------------------------
+# ipv6_status - show leak block status
+# -------------------------------------------------------------------------------------------------
+ipv6_status() {
+    _ipv6_init
+
+    printf '%s\n' "=== IPv6 Leak Block Status ==="
+    printf '\n'
+
+    if [[ $_ipv6_enabled -ne 1 ]]; then
+        printf '%s\n' "IPv6 disabled in router; nothing to block"
+        return 0
+    fi
+    if [[ ${IPV6_BLOCK_LEAKS:-1} -ne 1 ]]; then
+        printf '%s\n' "Leak block disabled (advanced.ipv6.block_leaks = false)"
+    fi
+
+    printf '%s\n' "--- Chain: $IPV6_LEAK_CHAIN ---"
+    if fw_chain_exists -6 filter "$IPV6_LEAK_CHAIN"; then
+        ip6tables -t filter -S "$IPV6_LEAK_CHAIN" 2>/dev/null | tail -n +2
+    else
+        printf '%s\n' "Chain not found (not applied)"
+    fi
+    printf '\n'
+
+    return 0
+}
+
+# -------------------------------------------------------------------------------------------------
+# ipv6_stop - remove leak block rules
+# -------------------------------------------------------------------------------------------------
+ipv6_stop() {
+    _ipv6_init
+
+    purge_fw_rules -q -6 "filter FORWARD" "-j ${IPV6_LEAK_CHAIN}\$"
+    delete_fw_chain -q -6 filter "$IPV6_LEAK_CHAIN"
+    log "Removed IPv6 leak block"
+
+    return 0
+}
+
+# -------------------------------------------------------------------------------------------------
+# ipv6_apply - build leak block rules (idempotent)
+# -------------------------------------------------------------------------------------------------
+# The chain is rebuilt on every apply because client MAC addresses may have changed.
+# -------------------------------------------------------------------------------------------------
+ipv6_apply() {
+    _ipv6_init
+
+    if [[ $_ipv6_enabled -ne 1 ]] || [[ ${IPV6_BLOCK_LEAKS:-1} -ne 1 ]]; then
+        if fw_chain_exists -6 filter "$IPV6_LEAK_CHAIN"; then
+            ipv6_stop
+        fi
+        return 0
+    fi
+
+    local route client mac
+    local blocked=0 warnings=0
+    local -A seen_macs=()
+
+    create_fw_chain -6 -q -f filter "$IPV6_LEAK_CHAIN"
+
+    # Xray IPv6 clients are proxied (see header), so never block their sources
+    local xray_set6="${XRAY_CLIENTS_IPSET}6"
+    if ipset list -n "$xray_set6" >/dev/null 2>&1; then
+        ensure_fw_rule -6 -q filter "$IPV6_LEAK_CHAIN" \
+            -m set --match-set "$xray_set6" src -j RETURN
+    fi
+
+    while read -r route client; do
+        [[ -n $client ]] || continue
+
+        if [[ $client == *:* ]]; then
+            # IPv6 client: Xray proxies it, tunnels cannot carry it
+            [[ $route == xray ]] && continue
+            ensure_fw_rule -6 -q filter "$IPV6_LEAK_CHAIN" \
+                -s "$client" -j REJECT --reject-with icmp6-adm-prohibited
+            blocked=$((blocked + 1))
+            continue
+        fi
+
+        local macs
+        macs=$(_ipv6_client_macs "$client")
+        if [[ -z $macs ]]; then
+            log -l WARN "No MAC address known for $route client $client; IPv6 not blocked"
+            warnings=1
+            continue
+        fi
+
+        while IFS= read -r mac; do
+            [[ -n $mac ]] || continue
+            [[ -z ${seen_macs[$mac]:-} ]] || continue
+            seen_macs[$mac]=1
+            ensure_fw_rule -6 -q filter "$IPV6_LEAK_CHAIN" \
+                -m mac --mac-source "$mac" -j REJECT --reject-with icmp6-adm-prohibited
+            blocked=$((blocked + 1))
+        done <<< "$macs"
+    done < <(_ipv6_routed_clients)
+
+    # LAN-originated traffic leaving the LAN only
+    sync_fw_rule -6 -q filter FORWARD "-j ${IPV6_LEAK_CHAIN}\$" \
+        "-i br0 ! -o br0 -j $IPV6_LEAK_CHAIN" 1
+
+    if [[ $warnings -eq 0 ]]; then
+        log "IPv6 leak block applied ($blocked rules)"
+    else
+        log -l WARN "IPv6 leak block applied with warnings ($blocked rules)"
+    fi
+
+    return 0
+}
+
+###################################################################################################
+# Allow sourcing for testing
+###################################################################################################
+if [[ ${1:-} == "--source-only" ]]; then
+    # shellcheck disable=SC2317
+    return 0 2>/dev/null || exit 0
+fi
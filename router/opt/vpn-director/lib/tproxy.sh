@@ -6,9 +6,12 @@
 # Purpose:
 #   Modular library for Xray TPROXY operations: status, apply, stop.
 #   Migrated from xray_tproxy.sh to provide independent, testable functions.
+#   Dual-stack: IPv6 entries of clients/servers/exclude_ips go to "<ipset>6" (family inet6)
+#   sets matched by an ip6tables chain of the same name. IPv6 rules are skipped when IPv6
+#   is disabled in the router. Country ipsets are IPv4-only and do not apply to IPv6.
 #
 # Dependencies:
-#   - common.sh (log, tmp_file)
+#   - common.sh (log, tmp_file, get_ipv6_enabled)
 #   - firewall.sh (create_fw_chain, delete_fw_chain, ensure_fw_rule, sync_fw_rule, purge_fw_rules)
 #   - config.sh (XRAY_* variables)
 #
@@ -27,8 +30,13 @@
 #   _tproxy_teardown_routing()      - remove routing table and ip rule
 #   _tproxy_setup_clients_ipset()   - setup clients ipset
 #   _tproxy_validate_ipv4_cidr()    - validate IPv4 address or CIDR notation
+#   _tproxy_validate_ipv6_cidr()    - validate IPv6 address or CIDR notation
+#   _tproxy_add_to_family_set()     - add an entry to the IPv4 or IPv6 variant of an ipset
 #   _tproxy_setup_bypass_ipset()    - setup bypass ipset (3-source assembly)
+#   _tproxy_setup_domain_ipset()    - create dnsmasq-populated bypass ipset for exclude_domains
+#   _tproxy_add_accounting_rules()  - add per-client upload and download counter rules
 #   _tproxy_setup_iptables()        - build iptables rules
+#   _tproxy_setup_ip6tables()       - build ip6tables rules (IPv6 clients only)
 #   _tproxy_teardown_iptables()     - remove iptables rules and ipsets
 #   _tproxy_init()                  - initialize module state
 #
@@ -62,6 +70,20 @@ fi
 # Initialization flag
 _tproxy_initialized=0
 
+# IPv6 state (computed by _tproxy_init)
+_tproxy_ipv6_enabled=0
+_tproxy_clients_ipset6=""
+_tproxy_bypass_ipset6=""
+
+# Bypass ipset filled by dnsmasq with addresses of xray.exclude_domains.
+# Keep in sync with server/internal/dnsmasq (BypassSet, BypassSet6).
+_tproxy_domain_ipset="VPD_DOM_BYPASS"
+_tproxy_domain_ipset6="VPD_DOM_BYPASS6"
+
+# Chain counting traffic Xray sends back to clients (computed by _tproxy_init).
+# Keep in sync with server/internal/traffic (DownloadChain).
+_tproxy_download_chain=""
+
 ###################################################################################################
 # Internal helper functions (defined before --source-only for testability)
 ###################################################################################################
@@ -75,6 +97,11 @@ _tproxy_init() {
     # Skip if already initialized
     [[ $_tproxy_initialized -eq 1 ]] && return 0
 
+    _tproxy_ipv6_enabled=$(get_ipv6_enabled)
+    _tproxy_clients_ipset6="${XRAY_CLIENTS_IPSET}6"
+    _tproxy_bypass_ipset6="${XRAY_BYPASS_IPSET}6"
+    _tproxy_download_chain="${XRAY_CHAIN}_DL"
+
     _tproxy_initialized=1
 }
 
@@ -165,6 +192,18 @@ _tproxy_setup_routing() {
         ip rule add pref "$XRAY_RULE_PREF" fwmark "$XRAY_FWMARK/$XRAY_FWMARK_MASK" table "$XRAY_ROUTE_TABLE"
         log "Added ip rule: pref $XRAY_RULE_PREF fwmark $XRAY_FWMARK/$XRAY_FWMARK_MASK table $XRAY_ROUTE_TABLE"
     fi
+
+    [[ $_tproxy_ipv6_enabled -eq 1 ]] || return 0
+
+    if ! ip -6 route show table "$XRAY_ROUTE_TABLE" 2>/dev/null | grep -q "local default"; then
+        ip -6 route add local default dev lo table "$XRAY_ROUTE_TABLE" 2>/dev/null ||
+            log -l WARN "Failed to add IPv6 route: local default dev lo table $XRAY_ROUTE_TABLE"
+    fi
+
+    if ! ip -6 rule show 2>/dev/null | grep -q "fwmark $XRAY_FWMARK.*lookup $XRAY_ROUTE_TABLE"; then
+        ip -6 rule add pref "$XRAY_RULE_PREF" fwmark "$XRAY_FWMARK/$XRAY_FWMARK_MASK" table "$XRAY_ROUTE_TABLE" 2>/dev/null ||
+            log -l WARN "Failed to add IPv6 ip rule: pref $XRAY_RULE_PREF"
+    fi
 }
 
 # -------------------------------------------------------------------------------------------------
@@ -173,39 +212,72 @@ _tproxy_setup_routing() {
 _tproxy_teardown_routing() {
     ip rule del pref "$XRAY_RULE_PREF" 2>/dev/null || true
     ip route del local default dev lo table "$XRAY_ROUTE_TABLE" 2>/dev/null || true
+    ip -6 rule del pref "$XRAY_RULE_PREF" 2>/dev/null || true
+    ip -6 route del local default dev lo table "$XRAY_ROUTE_TABLE" 2>/dev/null || true
     log "Removed TPROXY routing configuration"
 }
 
 # -------------------------------------------------------------------------------------------------
-# _tproxy_setup_clients_ipset - setup clients ipset
+# _tproxy_create_family_sets - create and flush the IPv4 and IPv6 variants of an ipset
+# -------------------------------------------------------------------------------------------------
+# Input: IPv4 set name, IPv6 set name
+# Always creates both sets (even if empty) so firewall rules can reference them.
+# -------------------------------------------------------------------------------------------------
+_tproxy_create_family_sets() {
+    local set4="$1" set6="$2"
+
+    if ! ipset list "$set4" >/dev/null 2>&1; then
+        ipset create "$set4" hash:net
+        log "Created ipset: $set4"
+    fi
+    if ! ipset list "$set6" >/dev/null 2>&1; then
+        ipset create "$set6" hash:net family inet6
+        log "Created ipset: $set6"
+    fi
+
+    ipset flush "$set4"
+    ipset flush "$set6"
+}
+
+# -------------------------------------------------------------------------------------------------
+# _tproxy_add_to_family_set - add an entry to the IPv4 or IPv6 variant of an ipset
+# -------------------------------------------------------------------------------------------------
+# Input: IPv4 set name, IPv6 set name, address or CIDR
+# Entries containing ':' are IPv6. Returns ipset's exit status.
+# -------------------------------------------------------------------------------------------------
+_tproxy_add_to_family_set() {
+    local set4="$1" set6="$2" entry="$3"
+
+    if [[ $entry == *:* ]]; then
+        ipset add "$set6" "$entry" 2>/dev/null
+    else
+        ipset add "$set4" "$entry" 2>/dev/null
+    fi
+}
+
+# -------------------------------------------------------------------------------------------------
+# _tproxy_setup_clients_ipset - setup clients ipsets
 # -------------------------------------------------------------------------------------------------
-# Always creates the ipset (even if empty) so iptables rules can reference it.
+# Always creates the ipsets (even if empty) so iptables rules can reference them.
 # -------------------------------------------------------------------------------------------------
 _tproxy_setup_clients_ipset() {
     local ip
     local -a clients_array=()
 
-    # Create ipset if not exists
-    if ! ipset list "$XRAY_CLIENTS_IPSET" >/dev/null 2>&1; then
-        ipset create "$XRAY_CLIENTS_IPSET" hash:net
-        log "Created ipset: $XRAY_CLIENTS_IPSET"
-    fi
-
-    # Flush and repopulate
-    ipset flush "$XRAY_CLIENTS_IPSET"
+    _tproxy_create_family_sets "$XRAY_CLIENTS_IPSET" "$_tproxy_clients_ipset6"
 
     # Handle empty XRAY_CLIENTS gracefully
     if [[ -n ${XRAY_CLIENTS:-} ]]; then
         read -ra clients_array <<< "$XRAY_CLIENTS"
         for ip in "${clients_array[@]}"; do
             [[ -n $ip ]] || continue
-            ipset add "$XRAY_CLIENTS_IPSET" "$ip" 2>/dev/null || {
-                log -l WARN "Failed to add $ip to $XRAY_CLIENTS_IPSET"
+            _tproxy_add_to_family_set "$XRAY_CLIENTS_IPSET" "$_tproxy_clients_ipset6" "$ip" || {
+                log -l WARN "Failed to add client $ip to clients ipset"
             }
         done
     fi
 
-    log "Populated $XRAY_CLIENTS_IPSET ipset (${#clients_array[@]} entries)"
+    log "Populated $XRAY_CLIENTS_IPSET/$_tproxy_clients_ipset6 ipsets (${#clients_array[@]} entries)"
 }
 
 # -------------------------------------------------------------------------------------------------
@@ -237,6 +309,41 @@ _tproxy_validate_ipv4_cidr() {
     return 0
 }
 
+# -------------------------------------------------------------------------------------------------
+# _tproxy_validate_ipv6_cidr - validate IPv6 address or CIDR notation
+# -------------------------------------------------------------------------------------------------
+# Checks the character set, group count and mask range; ipset rejects anything subtler.
+# Returns 0 if valid, 1 otherwise.
+# -------------------------------------------------------------------------------------------------
+_tproxy_validate_ipv6_cidr() {
+    local input="$1"
+    local ip mask groups
+
+    if [[ $input == */* ]]; then
+        ip="${input%/*}"
+        mask="${input#*/}"
+        [[ $mask =~ ^[0-9]+$ ]] || return 1
+        [[ 10#$mask -le 128 ]] || return 1
+    else
+        ip="$input"
+    fi
+
+    [[ $ip == *:* ]] || return 1
+    [[ $ip =~ ^[0-9A-Fa-f:]+$ ]] || return 1
+    [[ $ip != *:::* ]] || return 1
+
+    # At most one "::", and exactly 8 groups without it
+    groups="${ip//[^:]/}"
+    if [[ $ip == *::* ]]; then
+        local rest="${ip#*::}"
+        [[ $rest != *::* ]] || return 1
+        [[ ${#groups} -le 7 ]] || return 1
+    else
+        [[ ${#groups} -eq 7 ]] || return 1
+    fi
+    return 0
+}
+
 # -------------------------------------------------------------------------------------------------
 # _tproxy_setup_bypass_ipset - setup bypass ipset (3-source assembly)
 # -------------------------------------------------------------------------------------------------
@@ -244,7 +351,8 @@ _tproxy_validate_ipv4_cidr() {
 #   1. Xray server IPs from config (xray.servers)
 #   2. User-defined exclude IPs from config (xray.exclude_ips)
 #   3. OpenVPN client endpoints from nvram (resolved on the fly)
-# Always creates the ipset (even if empty) so iptables rules can reference it.
+# IPv6 entries go to the "<ipset>6" variant.
+# Always creates the ipsets (even if empty) so iptables rules can reference them.
 # -------------------------------------------------------------------------------------------------
 _tproxy_setup_bypass_ipset() {
     local ip addr resolved
@@ -252,22 +360,16 @@ _tproxy_setup_bypass_ipset() {
     local -a exclude_ips_array=()
     local xray_count=0 user_count=0 ovpn_count=0
 
-    # Create ipset if not exists
-    if ! ipset list "$XRAY_BYPASS_IPSET" >/dev/null 2>&1; then
-        ipset create "$XRAY_BYPASS_IPSET" hash:net
-        log "Created ipset: $XRAY_BYPASS_IPSET"
-    fi
-
-    # Flush and repopulate
-    ipset flush "$XRAY_BYPASS_IPSET"
+    _tproxy_create_family_sets "$XRAY_BYPASS_IPSET" "$_tproxy_bypass_ipset6"
 
     # Source 1: Xray server IPs from config
     if [[ -n ${XRAY_SERVERS:-} ]]; then
         read -ra servers_array <<< "$XRAY_SERVERS"
         for ip in "${servers_array[@]}"; do
             [[ -n $ip ]] || continue
-            ipset add "$XRAY_BYPASS_IPSET" "$ip" 2>/dev/null && xray_count=$((xray_count + 1)) || {
-                log -l WARN "Failed to add xray server $ip to $XRAY_BYPASS_IPSET"
+            _tproxy_add_to_family_set "$XRAY_BYPASS_IPSET" "$_tproxy_bypass_ipset6" "$ip" &&
+                xray_count=$((xray_count + 1)) || {
+                log -l WARN "Failed to add xray server $ip to bypass ipset"
             }
         done
     fi
@@ -277,13 +379,14 @@ _tproxy_setup_bypass_ipset() {
         read -ra exclude_ips_array <<< "$XRAY_EXCLUDE_IPS"
         for ip in "${exclude_ips_array[@]}"; do
             [[ -n $ip ]] || continue
-            # Validate IPv4 or IPv4 CIDR before adding
-            if ! _tproxy_validate_ipv4_cidr "$ip"; then
+            # Validate IPv4/IPv6 address or CIDR before adding
+            if ! _tproxy_validate_ipv4_cidr "$ip" && ! _tproxy_validate_ipv6_cidr "$ip"; then
                 log -l WARN "Invalid exclude_ips entry '$ip', skipping"
                 continue
             fi
-            ipset add "$XRAY_BYPASS_IPSET" "$ip" 2>/dev/null && user_count=$((user_count + 1)) || {
-                log -l WARN "Failed to add user exclude IP $ip to $XRAY_BYPASS_IPSET"
+            _tproxy_add_to_family_set "$XRAY_BYPASS_IPSET" "$_tproxy_bypass_ipset6" "$ip" &&
+                user_count=$((user_count + 1)) || {
+                log -l WARN "Failed to add user exclude IP $ip to bypass ipset"
             }
         done
     fi
@@ -301,7 +404,8 @@ _tproxy_setup_bypass_ipset() {
 
         while IFS= read -r ip; do
             [[ -n $ip ]] || continue
-            ipset add "$XRAY_BYPASS_IPSET" "$ip" 2>/dev/null && ovpn_count=$((ovpn_count + 1)) || true
+            _tproxy_add_to_family_set "$XRAY_BYPASS_IPSET" "$_tproxy_bypass_ipset6" "$ip" &&
+                ovpn_count=$((ovpn_count + 1)) || true
         done <<< "$resolved"
     done
 
@@ -309,6 +413,23 @@ _tproxy_setup_bypass_ipset() {
     log "Populated $XRAY_BYPASS_IPSET ipset: $xray_count xray, $user_count user, $ovpn_count openvpn = $total total"
 }
 
+# -------------------------------------------------------------------------------------------------
+# _tproxy_setup_domain_ipset - create dnsmasq-populated bypass ipset for exclude_domains
+# -------------------------------------------------------------------------------------------------
+# Created only when xray.exclude_domains is non-empty. Never flushed: dnsmasq adds resolved
+# addresses on each lookup, and a flush would drop them until the next query.
+# The IPv6 variant is always created because the dnsmasq fragment references both sets.
+# Returns 0 if the ipsets are in use, 1 otherwise.
+# -------------------------------------------------------------------------------------------------
+_tproxy_setup_domain_ipset() {
+    [[ -n ${XRAY_EXCLUDE_DOMAINS:-} ]] || return 1
+
+    ipset create -exist "$_tproxy_domain_ipset" hash:ip
+    ipset create -exist "$_tproxy_domain_ipset6" hash:ip family inet6
+    log "Using ipset $_tproxy_domain_ipset for xray.exclude_domains"
+    return 0
+}
+
 # -------------------------------------------------------------------------------------------------
 # _tproxy_setup_iptables - build iptables rules
 # -------------------------------------------------------------------------------------------------
@@ -334,6 +455,12 @@ _tproxy_setup_iptables() {
     ensure_fw_rule -q mangle "$XRAY_CHAIN" \
         -m set --match-set "$XRAY_BYPASS_IPSET" dst -j RETURN
 
+    # Rule 2a: Skip destinations resolved from xray.exclude_domains (filled by dnsmasq)
+    if _tproxy_setup_domain_ipset; then
+        ensure_fw_rule -q mangle "$XRAY_CHAIN" \
+            -m set --match-set "$_tproxy_domain_ipset" dst -j RETURN
+    fi
+
     # Rule 3: Skip local destinations (loopback)
     ensure_fw_rule -q mangle "$XRAY_CHAIN" \
         -d 127.0.0.0/8 -j RETURN
@@ -371,7 +498,10 @@ _tproxy_setup_iptables() {
         log "Added exclusion for ipset: $resolved_set"
     done
 
-    # Rule 9: Apply TPROXY for remaining traffic
+    # Rule 9: Per-client counters for traffic accounting
+    _tproxy_add_accounting_rules
+
+    # Rule 10: Apply TPROXY for remaining traffic
     # TCP
     ensure_fw_rule -q mangle "$XRAY_CHAIN" \
         -p tcp -j TPROXY --on-port "$XRAY_TPROXY_PORT" \
@@ -389,16 +519,111 @@ _tproxy_setup_iptables() {
     log "Applied TPROXY iptables rules"
 }
 
+# -------------------------------------------------------------------------------------------------
+# _tproxy_add_accounting_rules - add counter-only rules per Xray client for both directions
+# -------------------------------------------------------------------------------------------------
+# Usage: _tproxy_add_accounting_rules [-6]
+# Rules have no target, so they only count packets. Upload rules match the client as source in
+# XRAY_CHAIN, after all exclusions, so they see only proxied packets. Xray answers clients from
+# transparent sockets with the remote address as source, so its replies leave through mangle
+# OUTPUT with a non-local source; only those jump to the download chain, where the client is
+# matched as destination. The Go traffic collector reads the counters via iptables-save -c.
+# -------------------------------------------------------------------------------------------------
+_tproxy_add_accounting_rules() {
+    local fam="" client
+    local -a clients_array=()
+
+    [[ ${1:-} == "-6" ]] && fam="-6"
+
+    create_fw_chain $fam -q -f mangle "$_tproxy_download_chain"
+    sync_fw_rule $fam -q mangle OUTPUT "-j $_tproxy_download_chain\$" \
+        "-o br0 -m addrtype ! --src-type LOCAL -j $_tproxy_download_chain"
+
+    [[ -n ${XRAY_CLIENTS:-} ]] || return 0
+
+    read -ra clients_array <<< "$XRAY_CLIENTS"
+    for client in "${clients_array[@]}"; do
+        [[ -n $client ]] || continue
+        if [[ -n $fam ]]; then
+            [[ $client == *:* ]] || continue
+        else
+            [[ $client != *:* ]] || continue
+        fi
+        ensure_fw_rule $fam -q mangle "$XRAY_CHAIN" -s "$client" ||
+            log -l WARN "Failed to add accounting rule for Xray client $client"
+        ensure_fw_rule $fam -q mangle "$_tproxy_download_chain" -d "$client" ||
+            log -l WARN "Failed to add download accounting rule for Xray client $client"
+    done
+}
+
+# -------------------------------------------------------------------------------------------------
+# _tproxy_setup_ip6tables - build ip6tables rules
+# -------------------------------------------------------------------------------------------------
+# Mirrors _tproxy_setup_iptables for IPv6 clients. Skipped (and any previous chain removed)
+# when IPv6 is disabled or no IPv6 clients are configured.
+# -------------------------------------------------------------------------------------------------
+_tproxy_setup_ip6tables() {
+    if [[ $_tproxy_ipv6_enabled -ne 1 ]] || [[ " ${XRAY_CLIENTS:-} " != *:* ]]; then
+        purge_fw_rules -q -6 "mangle PREROUTING" "-j $XRAY_CHAIN\$"
+        delete_fw_chain -q -6 mangle "$XRAY_CHAIN"
+        purge_fw_rules -q -6 "mangle OUTPUT" "-j $_tproxy_download_chain\$"
+        delete_fw_chain -q -6 mangle "$_tproxy_download_chain"
+        return 0
+    fi
+
+    create_fw_chain -6 -f mangle "$XRAY_CHAIN"
+
+    # Skip if source is not an IPv6 client
+    ensure_fw_rule -6 -q mangle "$XRAY_CHAIN" \
+        -m set ! --match-set "$_tproxy_clients_ipset6" src -j RETURN
+
+    # Skip bypass destinations
+    ensure_fw_rule -6 -q mangle "$XRAY_CHAIN" \
+        -m set --match-set "$_tproxy_bypass_ipset6" dst -j RETURN
+    if _tproxy_setup_domain_ipset; then
+        ensure_fw_rule -6 -q mangle "$XRAY_CHAIN" \
+            -m set --match-set "$_tproxy_domain_ipset6" dst -j RETURN
+    fi
+
+    # Skip loopback, ULA, link-local and multicast destinations
+    local net
+    for net in ::1/128 fc00::/7 fe80::/10 ff00::/8; do
+        ensure_fw_rule -6 -q mangle "$XRAY_CHAIN" -d "$net" -j RETURN
+    done
+
+    _tproxy_add_accounting_rules -6
+
+    ensure_fw_rule -6 -q mangle "$XRAY_CHAIN" \
+        -p tcp -j TPROXY --on-port "$XRAY_TPROXY_PORT" \
+        --tproxy-mark "$XRAY_FWMARK/$XRAY_FWMARK_MASK"
+    ensure_fw_rule -6 -q mangle "$XRAY_CHAIN" \
+        -p udp -j TPROXY --on-port "$XRAY_TPROXY_PORT" \
+        --tproxy-mark "$XRAY_FWMARK/$XRAY_FWMARK_MASK"
+
+    sync_fw_rule -6 -q mangle PREROUTING "-j $XRAY_CHAIN\$" \
+        "-i br0 -j $XRAY_CHAIN" 1
+
+    log "Applied TPROXY ip6tables rules"
+}
+
 # -------------------------------------------------------------------------------------------------
 # _tproxy_teardown_iptables - remove all iptables rules
 # -------------------------------------------------------------------------------------------------
 _tproxy_teardown_iptables() {
     purge_fw_rules -q "mangle PREROUTING" "-j $XRAY_CHAIN\$"
     delete_fw_chain -q mangle "$XRAY_CHAIN"
+    purge_fw_rules -q -6 "mangle PREROUTING" "-j $XRAY_CHAIN\$"
+    delete_fw_chain -q -6 mangle "$XRAY_CHAIN"
+    purge_fw_rules -q "mangle OUTPUT" "-j $_tproxy_download_chain\$"
+    delete_fw_chain -q mangle "$_tproxy_download_chain"
+    purge_fw_rules -q -6 "mangle OUTPUT" "-j $_tproxy_download_chain\$"
+    delete_fw_chain -q -6 mangle "$_tproxy_download_chain"
 
     # Remove ipsets
     ipset destroy "$XRAY_CLIENTS_IPSET" 2>/dev/null || true
     ipset destroy "$XRAY_BYPASS_IPSET" 2>/dev/null || true
+    ipset destroy "$_tproxy_clients_ipset6" 2>/dev/null || true
+    ipset destroy "$_tproxy_bypass_ipset6" 2>/dev/null || true
 
     log "Removed TPROXY iptables rules and ipsets"
 }
@@ -478,6 +703,15 @@ tproxy_status() {
     iptables -t mangle -S PREROUTING 2>/dev/null | grep "$XRAY_CHAIN" || printf 'No jump to %s\n' "$XRAY_CHAIN"
     printf '\n'
 
+    printf '%s\n' "--- IPv6 ---"
+    if [[ $_tproxy_ipv6_enabled -eq 1 ]]; then
+        ip6tables -t mangle -S "$XRAY_CHAIN" 2>/dev/null ||
+            printf 'Chain %s not found (no IPv6 clients)\n' "$XRAY_CHAIN"
+    else
+        printf '%s\n' "IPv6 disabled"
+    fi
+    printf '\n'
+
     printf '%s\n' "--- Xray Process ---"
     pgrep -la xray || printf '%s\n' "Xray not running"
 
@@ -554,6 +788,10 @@ tproxy_apply() {
         return 0
     fi
 
+    if ! _tproxy_setup_ip6tables; then
+        log -l WARN "Failed to setup ip6tables rules; IPv6 clients are not proxied"
+    fi
+
     log "Xray TPROXY routing applied successfully"
 
     return 0
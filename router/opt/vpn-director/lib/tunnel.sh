@@ -6,6 +6,8 @@
 # Purpose:
 #   Modular library for tunnel director operations: status, apply, stop.
 #   Routes LAN client traffic through VPN tunnels with exclusion-based routing.
+#   Tunnels may also list domains: dnsmasq fills a per-tunnel ipset (VPD_DOM_<TUNNEL>)
+#   with their resolved addresses, and traffic from any LAN host to them is routed via the tunnel.
 #
 # Dependencies:
 #   - common.sh (log, tmp_file, compute_hash, is_lan_ip)
@@ -24,6 +26,8 @@
 # Internal functions (for testing):
 #   _tunnel_table_allowed()         - check if routing table is valid (wgcN, ovpncN, main)
 #   _tunnel_get_prerouting_base_pos() - find insert position after system rules
+#   _tunnel_domain_ipset()          - return dnsmasq-populated ipset name for a tunnel
+#   _tunnel_iface()                 - return the network interface of a tunnel
 #   _tunnel_init()                  - initialize module state
 #
 # Usage:
@@ -63,6 +67,10 @@ _tunnel_mark_shift_val=""
 _tunnel_mark_field_max=""
 _tunnel_mark_mask_hex=""
 
+# Chain counting traffic tunnels send back to clients (computed by _tunnel_init).
+# Keep in sync with server/internal/traffic (DownloadChain).
+_tunnel_download_chain=""
+
 # Initialization flag
 _tunnel_initialized=0
 
@@ -103,6 +111,8 @@ _tunnel_init() {
     _tunnel_mark_field_max=$((_tunnel_mark_mask_val >> _tunnel_mark_shift_val))
     _tunnel_mark_mask_hex="$(printf '0x%x' "$_tunnel_mark_mask_val")"
 
+    _tunnel_download_chain="${TUN_DIR_CHAIN}_DL"
+
     _tunnel_initialized=1
 }
 
@@ -144,6 +154,32 @@ _tunnel_get_prerouting_base_pos() {
     '
 }
 
+# -------------------------------------------------------------------------------------------------
+# _tunnel_domain_ipset - return dnsmasq-populated ipset name for a tunnel
+# -------------------------------------------------------------------------------------------------
+# Input: tunnel name (e.g., "wgc1")
+# Output: ipset name (e.g., "VPD_DOM_WGC1")
+# Keep in sync with server/internal/dnsmasq (SetName).
+# -------------------------------------------------------------------------------------------------
+_tunnel_domain_ipset() {
+    printf 'VPD_DOM_%s\n' "$(printf '%s' "$1" | tr 'a-z' 'A-Z')"
+}
+
+# -------------------------------------------------------------------------------------------------
+# _tunnel_iface - return the network interface of a tunnel
+# -------------------------------------------------------------------------------------------------
+# Input: tunnel name (e.g., "wgc1", "ovpnc2")
+# Output: interface name (e.g., "wgc1", "tun12")
+# Returns 1 for tables without a tunnel interface (main).
+# -------------------------------------------------------------------------------------------------
+_tunnel_iface() {
+    case "$1" in
+        wgc[0-9]*)   printf '%s\n' "$1" ;;
+        ovpnc[0-9]*) printf 'tun1%s\n' "${1#ovpnc}" ;;
+        *)           return 1 ;;
+    esac
+}
+
 ###################################################################################################
 # Public API (defined before --source-only for testability)
 ###################################################################################################
@@ -187,7 +223,7 @@ tunnel_status() {
     else
         printf '%s\n' "$TUN_DIR_TUNNELS_JSON" | jq -r '
             to_entries[] |
-            "Tunnel: \(.key)\n  Clients: \(.value.clients // [] | join(", "))\n  Exclude: \(.value.exclude // [] | join(", "))"
+            "Tunnel: \(.key)\n  Clients: \(.value.clients // [] | join(", "))\n  Exclude: \(.value.exclude // [] | join(", "))\n  Domains: \(.value.domains // [] | join(", "))"
         ' 2>/dev/null || printf '%s\n' "Error: Failed to parse tunnels JSON"
     fi
     printf '\n'
@@ -221,6 +257,10 @@ tunnel_stop() {
     # Remove PREROUTING jump
     purge_fw_rules -q "mangle PREROUTING" "-j ${TUN_DIR_CHAIN}\$"
 
+    # Remove download accounting
+    purge_fw_rules -q "mangle FORWARD" "-j ${_tunnel_download_chain}\$"
+    delete_fw_chain -q mangle "$_tunnel_download_chain"
+
     # Delete chain if exists
     if fw_chain_exists mangle "$TUN_DIR_CHAIN"; then
         delete_fw_chain -q mangle "$TUN_DIR_CHAIN"
@@ -276,7 +316,8 @@ tunnel_apply() {
     local rebuild=0
     if [[ $new_hash != "$old_hash" ]]; then
         rebuild=1
-    elif ! fw_chain_exists mangle "$TUN_DIR_CHAIN"; then
+    elif ! fw_chain_exists mangle "$TUN_DIR_CHAIN" ||
+        ! fw_chain_exists mangle "$_tunnel_download_chain"; then
         rebuild=1
     fi
 
@@ -295,12 +336,19 @@ tunnel_apply() {
     # Create the single chain
     create_fw_chain -q -f mangle "$TUN_DIR_CHAIN"
 
+    # Replies from tunnels to clients are counted in the download chain; the
+    # Go traffic collector reads its counters via iptables-save -c
+    create_fw_chain -q -f mangle "$_tunnel_download_chain"
+
     # Get base position in PREROUTING
     local base_pos
     base_pos=$(_tunnel_get_prerouting_base_pos)
 
     # Process each tunnel
     local tunnel_idx=0
+    # Domain rules are inserted at the top of the chain (in tunnel order), so a
+    # destination domain takes priority over the per-client tunnel assignment
+    local domain_pos=1
     local tunnels
     # Use keys_unsorted to preserve JSON file order (not alphabetical sorting)
     tunnels=$(printf '%s\n' "$TUN_DIR_TUNNELS_JSON" | jq -r 'keys_unsorted[]')
@@ -342,17 +390,18 @@ tunnel_apply() {
             exclude_type="null"  # Skip excludes but continue with clients
         fi
 
-        # Get clients and excludes for this tunnel
-        local clients excludes
+        # Get clients, excludes and domains for this tunnel
+        local clients excludes domains
         clients=$(printf '%s\n' "$TUN_DIR_TUNNELS_JSON" | jq -r --arg t "$tunnel" '.[$t].clients // [] | .[]')
+        domains=$(printf '%s\n' "$TUN_DIR_TUNNELS_JSON" | jq -r --arg t "$tunnel" '.[$t].domains // [] | if type == "array" then .[] else empty end')
         if [[ $exclude_type == "array" ]]; then
             excludes=$(printf '%s\n' "$TUN_DIR_TUNNELS_JSON" | jq -r --arg t "$tunnel" '.[$t].exclude // [] | .[]')
         else
             excludes=""
         fi
 
-        if [[ -z $clients ]]; then
-            log -l WARN "Tunnel '$tunnel' has no clients; skipping"
+        if [[ -z $clients ]] && [[ -z $domains ]]; then
+            log -l WARN "Tunnel '$tunnel' has no clients or domains; skipping"
             warnings=1
             continue
         fi
@@ -369,10 +418,33 @@ tunnel_apply() {
         local mark_hex
         mark_hex=$(printf '0x%x' "$mark_val")
 
+        # Add rule for destinations resolved from the tunnel's domains.
+        # The ipset is never flushed here: dnsmasq fills it on each lookup.
+        if [[ -n $domains ]]; then
+            local dom_set
+            dom_set=$(_tunnel_domain_ipset "$tunnel")
+            ipset create -exist "$dom_set" hash:ip
+            ensure_fw_rule -q mangle "$TUN_DIR_CHAIN" -I "$domain_pos" \
+                -m set --match-set "$dom_set" dst -m mark --mark "0x0/$_tunnel_mark_mask_hex" \
+                -j MARK --set-xmark "$mark_hex/$_tunnel_mark_mask_hex"
+            domain_pos=$((domain_pos + 1))
+            log "Added: domains ipset=$dom_set tunnel=$tunnel mark=$mark_hex"
+            changes=1
+        fi
+
+        local iface
+        iface=$(_tunnel_iface "$tunnel") || iface=""
+
         # Add rules for each client
         while IFS= read -r client; do
             [[ -n $client ]] || continue
 
+            # Client tunnels do not carry IPv6; such clients are handled by the leak block
+            if [[ $client == *:* ]]; then
+                log "Client '$client' is IPv6; blocked by IPv6 leak block instead of routed"
+                continue
+            fi
+
             # Validate client is RFC1918
             local client_ip="${client%%/*}"
             if ! is_lan_ip "$client_ip"; then
@@ -402,6 +474,12 @@ tunnel_apply() {
                 -s "$client" -m mark --mark "0x0/$_tunnel_mark_mask_hex" \
                 -j MARK --set-xmark "$mark_hex/$_tunnel_mark_mask_hex"
 
+            # Count traffic coming back from the tunnel to this client
+            if [[ -n $iface ]]; then
+                ensure_fw_rule -q mangle "$_tunnel_download_chain" -i "$iface" -d "$client" ||
+                    log -l WARN "Failed to add download accounting rule for client $client"
+            fi
+
             log "Added: client=$client tunnel=$tunnel mark=$mark_hex"
             changes=1
         done <<< "$clients"
@@ -421,6 +499,10 @@ tunnel_apply() {
     sync_fw_rule -q mangle PREROUTING "-j ${TUN_DIR_CHAIN}\$" \
         "-i br0 -m mark --mark 0x0/$_tunnel_mark_mask_hex -j $TUN_DIR_CHAIN" "$base_pos"
 
+    # Add jump from FORWARD to the download chain (traffic to LAN clients)
+    sync_fw_rule -q mangle FORWARD "-j ${_tunnel_download_chain}\$" \
+        "-o br0 -j $_tunnel_download_chain"
+
     # Save hash
     mkdir -p "$(dirname "$TUN_DIR_HASH")"
     printf '%s\n' "$new_hash" > "$TUN_DIR_HASH"
@@ -78,7 +78,7 @@ jq -n \
     --argjson users "$USERS_JSON" \
     --arg proxy "$PROXY_URL" \
     --argjson fallback "$PROXY_FALLBACK" \
-    '{bot_token: $token, allowed_users: $users, log_level: "info", update_check_interval: "24h"} +
+    '{bot_token: $token, allowed_users: $users, log_level: "info", update_check_interval: "24h", server_resolve_interval: "1h", weekly_summary: "mon 09:00"} +
      (if $proxy != "" then {proxy: $proxy, proxy_fallback_direct: $fallback} else {} end)' > "$CONFIG_FILE"
 
 echo
@@ -4,11 +4,12 @@
 # vpn-director.sh - Unified CLI for VPN Director
 # -------------------------------------------------------------------------------------------------
 # Usage:
-#   vpn-director status [tunnel|xray|ipset]  - Show status
-#   vpn-director apply [tunnel|xray]         - Apply configuration
-#   vpn-director stop [tunnel|xray]          - Stop components
-#   vpn-director restart [tunnel|xray]       - Restart components
-#   vpn-director update                      - Update ipsets and reapply all
+#   vpn-director status [tunnel|xray|ipset|ipv6] - Show status
+#   vpn-director apply [tunnel|xray]              - Apply configuration
+#   vpn-director stop [tunnel|xray]               - Stop components
+#   vpn-director restart [tunnel|xray]            - Restart components
+#   vpn-director update                           - Update ipsets and reapply all
+#   vpn-director event wan <up|down> [message]    - Record a WAN state change
 #
 # Options:
 #   -f, --force    Force operation (ignore hash checks)
@@ -37,6 +38,7 @@ VERBOSE=0
 DRY_RUN=0
 COMMAND=""
 COMPONENT=""
+EXTRA_ARGS=()
 
 parse_option() {
     case $1 in
@@ -58,6 +60,9 @@ while [[ $# -gt 0 ]]; do
         COMMAND="$1"; shift
     elif [[ -z $COMPONENT ]]; then
         COMPONENT="$1"; shift
+    elif [[ $COMMAND == event ]]; then
+        # event takes a state and a free-form message
+        EXTRA_ARGS+=("$1"); shift
     else
         # Extra positional argument
         echo "Unexpected argument: $1" >&2; exit 1
@@ -84,11 +89,12 @@ Usage:
   vpn-director <command> [component] [options]
 
 Commands:
-  status [tunnel|xray|ipset]  Show status (all or specific component)
-  apply [tunnel|xray]         Apply configuration
-  stop [tunnel|xray]          Stop components
-  restart [tunnel|xray]       Restart (stop + apply)
-  update                      Download fresh ipsets and reapply all
+  status [tunnel|xray|ipset|ipv6]  Show status (all or specific component)
+  apply [tunnel|xray]              Apply configuration
+  stop [tunnel|xray]               Stop components
+  restart [tunnel|xray]            Restart (stop + apply)
+  update                           Download fresh ipsets and reapply all
+  event wan <up|down> [message]    Record a WAN state change (used by wan-event)
 
 Options:
   -f, --force    Force operation (ignore hash checks)
@@ -117,6 +123,7 @@ _load_modules() {
     . "$SCRIPT_DIR/lib/ipset.sh" --source-only
     . "$SCRIPT_DIR/lib/tunnel.sh" --source-only
     . "$SCRIPT_DIR/lib/tproxy.sh" --source-only
+    . "$SCRIPT_DIR/lib/ipv6.sh" --source-only
     _MODULES_LOADED=1
 }
 
@@ -131,6 +138,48 @@ _ensure_ipsets() {
     done
 }
 
+###################################################################################################
+# Append an event to the timeline shown by the Web UI and bot (<data_dir>/events.jsonl)
+# -------------------------------------------------------------------------------------------------
+# Usage: _record_event <kind> <true|false> <component> <up|down> [message]
+# Best effort: a failure to record never fails the command.
+###################################################################################################
+_record_event() {
+    local kind=$1 ok=$2 component=$3 state=$4 message=${5:-}
+    local dir="${IPS_BDR_DIR:-/opt/vpn-director/data}"
+
+    [[ $DRY_RUN -eq 1 ]] && return 0
+    mkdir -p "$dir" 2>/dev/null || return 0
+    jq -nc \
+        --arg time "$(date -u '+%Y-%m-%dT%H:%M:%SZ')" \
+        --arg kind "$kind" \
+        --argjson ok "$ok" \
+        --arg component "$component" \
+        --arg state "$state" \
+        --arg source "${EVENT_SOURCE:-vpn-director}" \
+        --arg message "$message" \
+        '{time: $time, kind: $kind, ok: $ok, component: $component, state: $state,
+          source: $source, message: $message} | with_entries(select(.value != ""))' \
+        >> "$dir/events.jsonl" 2>/dev/null || true
+}
+
+###################################################################################################
+# Make sure dnsmasq loaded the domain routing fragment (written by the Go server)
+# -------------------------------------------------------------------------------------------------
+# /jffs/scripts/dnsmasq.postconf includes the fragment only if it exists when dnsmasq starts.
+# At boot Entware may not be mounted yet, so restart dnsmasq once the fragment is available.
+###################################################################################################
+_ensure_dnsmasq_domains() {
+    local fragment="$SCRIPT_DIR/dnsmasq-domains.conf"
+    local dnsmasq_conf="${DNSMASQ_CONF_FILE:-/etc/dnsmasq.conf}"
+
+    [[ -f $fragment ]] || return 0
+    grep -qF "conf-file=$fragment" "$dnsmasq_conf" 2>/dev/null && return 0
+
+    log "Restarting dnsmasq to load domain routing from $fragment"
+    service restart_dnsmasq >/dev/null 2>&1 || log -l WARN "Failed to restart dnsmasq"
+}
+
 ###################################################################################################
 # Commands
 ###################################################################################################
@@ -144,6 +193,8 @@ cmd_status() {
             tunnel_status
             echo ""
             tproxy_status
+            echo ""
+            ipv6_status
             ;;
         ipset)
             ipset_status
@@ -154,6 +205,9 @@ cmd_status() {
         xray|tproxy)
             tproxy_status
             ;;
+        ipv6)
+            ipv6_status
+            ;;
         *)
             echo "Unknown component: $COMPONENT" >&2
             exit 1
@@ -210,6 +264,8 @@ cmd_apply() {
 
             tunnel_apply
             tproxy_apply
+            ipv6_apply
+            _ensure_dnsmasq_domains
             ;;
         tunnel)
             required_ipsets=$(tunnel_get_required_ipsets)
@@ -218,6 +274,7 @@ cmd_apply() {
                 _ensure_ipsets $required_ipsets
             fi
             tunnel_apply
+            ipv6_apply
             ;;
         xray|tproxy)
             required_ipsets=$(tproxy_get_required_ipsets)
@@ -226,6 +283,7 @@ cmd_apply() {
                 _ensure_ipsets $required_ipsets
             fi
             tproxy_apply
+            ipv6_apply
             ;;
         *)
             echo "Unknown component: $COMPONENT" >&2
@@ -240,6 +298,7 @@ cmd_stop() {
 
     case "$COMPONENT" in
         ""|all)
+            ipv6_stop
             tproxy_stop
             tunnel_stop
             ;;
@@ -280,16 +339,30 @@ cmd_restart() {
     esac
 }
 
+# Records the outcome of cmd_update; runs on EXIT so failures that abort the script count too
+_record_update_result() {
+    local rc=$1
+    if [[ $rc -eq 0 ]]; then
+        _record_event ipset_update true ipsets up "Updated ipsets: ${_UPDATE_IPSETS:-none}"
+    else
+        _record_event ipset_update false ipsets down "Update failed (exit $rc): ${_UPDATE_IPSETS:-none}"
+    fi
+}
+
 cmd_update() {
     _load_modules
     acquire_lock "vpn-director"
 
+    # Keep temp file cleanup from common.sh
+    trap '_record_update_result $?; _cleanup_tmp' EXIT
+
     # Wait for network if system just booted (before any downloads)
     _ipset_boot_wait
 
     local required_ipsets
     required_ipsets="$(tunnel_get_required_ipsets) $(tproxy_get_required_ipsets)"
     required_ipsets=$(echo $required_ipsets | xargs -n1 | sort -u | xargs)
+    _UPDATE_IPSETS=$required_ipsets
 
     if [[ -n $required_ipsets ]]; then
         log "Updating ipsets: $required_ipsets"
@@ -301,10 +374,29 @@ cmd_update() {
 
     tunnel_apply
     tproxy_apply
+    ipv6_apply
 
     log "Update complete"
 }
 
+cmd_event() {
+    _load_modules
+    local state=${1:-} message=${2:-}
+
+    if [[ $COMPONENT != wan ]]; then
+        echo "Unknown event: ${COMPONENT:-<none>} (expected: wan)" >&2
+        exit 1
+    fi
+    case "$state" in
+        up)   _record_event wan true wan up "$message" ;;
+        down) _record_event wan false wan down "$message" ;;
+        *)
+            echo "Usage: vpn-director event wan <up|down> [message]" >&2
+            exit 1
+            ;;
+    esac
+}
+
 ###################################################################################################
 # Main
 ###################################################################################################
@@ -331,6 +423,9 @@ case "$COMMAND" in
     update)
         cmd_update
         ;;
+    event)
+        cmd_event "${EXTRA_ARGS[@]}"
+        ;;
     *)
         echo "Unknown command: $COMMAND" >&2
         echo "Run 'vpn-director --help' for usage" >&2
@@ -184,3 +184,46 @@ vless://uuid2@server2:443#Name2"
 
     rm -rf "$DATA_DIR"
 }
+
+@test "step_parse_and_save_servers: saves ips6 for IPv6-only server" {
+    load_import_server_list
+
+    DATA_DIR="/tmp/bats_test_import_data"
+    SERVERS_FILE="$DATA_DIR/servers.json"
+    mkdir -p "$DATA_DIR"
+
+    VPD_CONFIG="/tmp/bats_test_import_data/vpn-director.json"
+    printf '{"data_dir": "%s"}\n' "$DATA_DIR" > "$VPD_CONFIG"
+
+    VLESS_SERVERS="vless://test-uuid@ipv6.example.com:443?type=tcp#V6Server"
+
+    step_parse_and_save_servers
+
+    result=$(jq -r '.[0].ips | length' "$SERVERS_FILE")
+    [ "$result" = "0" ]
+
+    result=$(jq -r '.[0].ips6[0]' "$SERVERS_FILE")
+    [ "$result" = "2606:2800:220:1:248:1893:25c8:1946" ]
+
+    rm -rf "$DATA_DIR"
+}
+
+@test "step_parse_and_save_servers: omits ips6 when no AAAA records" {
+    load_import_server_list
+
+    DATA_DIR="/tmp/bats_test_import_data"
+    SERVERS_FILE="$DATA_DIR/servers.json"
+    mkdir -p "$DATA_DIR"
+
+    VPD_CONFIG="/tmp/bats_test_import_data/vpn-director.json"
+    printf '{"data_dir": "%s"}\n' "$DATA_DIR" > "$VPD_CONFIG"
+
+    VLESS_SERVERS="vless://test-uuid@example.com:443?type=tcp#TestServer"
+
+    step_parse_and_save_servers
+
+    result=$(jq -r '.[0] | has("ips6")' "$SERVERS_FILE")
+    [ "$result" = "false" ]
+
+    rm -rf "$DATA_DIR"
+}
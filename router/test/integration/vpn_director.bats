@@ -246,3 +246,41 @@ setup() {
     assert_success
     assert_output --partial "Usage:"
 }
+
+# ============================================================================
+# Event timeline tests
+# ============================================================================
+
+@test "vpn-director: event wan up appends to events.jsonl" {
+    EVENT_SOURCE=wan-event run "$SCRIPTS_DIR/vpn-director.sh" event wan up "wan0 connected"
+    assert_success
+
+    run jq -c '{kind, ok, component, state, source, message}' /tmp/bats_test_data/events.jsonl
+    assert_output '{"kind":"wan","ok":true,"component":"wan","state":"up","source":"wan-event","message":"wan0 connected"}'
+}
+
+@test "vpn-director: event wan down records a failed state" {
+    run "$SCRIPTS_DIR/vpn-director.sh" event wan down
+    assert_success
+
+    run jq -r '"\(.ok) \(.state)"' /tmp/bats_test_data/events.jsonl
+    assert_output "false down"
+}
+
+@test "vpn-director: event with unknown state fails" {
+    run "$SCRIPTS_DIR/vpn-director.sh" event wan sideways
+    assert_failure
+    assert_output --partial "Usage:"
+}
+
+@test "vpn-director: event with unknown kind fails" {
+    run "$SCRIPTS_DIR/vpn-director.sh" event apply up
+    assert_failure
+    assert_output --partial "Unknown event"
+}
+
+@test "vpn-director: event --dry-run records nothing" {
+    run "$SCRIPTS_DIR/vpn-director.sh" --dry-run event wan up
+    assert_success
+    assert [ ! -f /tmp/bats_test_data/events.jsonl ]
+}
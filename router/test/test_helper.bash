@@ -91,6 +91,16 @@ load_tproxy_module() {
     source "$LIB_DIR/tproxy.sh" --source-only
 }
 
+# Helper to source ipv6.sh module
+load_ipv6_module() {
+    load_common
+    export VPD_CONFIG_FILE="$TEST_ROOT/fixtures/vpn-director-ipv6.json"
+    export DNSMASQ_LEASES_FILE="$TEST_ROOT/fixtures/dnsmasq.leases"
+    source "$LIB_DIR/config.sh"
+    source "$LIB_DIR/firewall.sh"
+    source "$LIB_DIR/ipv6.sh" --source-only
+}
+
 # Helper to source import_server_list.sh without running main
 load_import_server_list() {
     load_common
@@ -0,0 +1,105 @@
+#!/usr/bin/env bats
+
+load '../test_helper'
+
+# Note: load_ipv6_module is provided by test_helper.bash
+# It loads: common.sh, config.sh (vpn-director-ipv6.json), firewall.sh, ipv6.sh
+
+# ============================================================================
+# Config
+# ============================================================================
+
+@test "config: block_leaks defaults to enabled" {
+    load_ipv6_module
+    [ "$IPV6_BLOCK_LEAKS" -eq 1 ]
+}
+
+@test "config: block_leaks false disables the leak block" {
+    load_common
+    export VPD_CONFIG_FILE="/tmp/bats_test_ipv6_off.json"
+    jq '.advanced.ipv6.block_leaks = false' "$TEST_ROOT/fixtures/vpn-director-ipv6.json" > "$VPD_CONFIG_FILE"
+    source "$LIB_DIR/config.sh"
+    [ "$IPV6_BLOCK_LEAKS" -eq 0 ]
+}
+
+# ============================================================================
+# _ipv6_client_macs
+# ============================================================================
+
+@test "_ipv6_client_macs: falls back to dnsmasq leases" {
+    load_ipv6_module
+    run _ipv6_client_macs "192.168.50.10"
+    assert_success
+    assert_output "aa:bb:cc:dd:ee:50"
+}
+
+@test "_ipv6_client_macs: prints nothing for unknown host" {
+    load_ipv6_module
+    run _ipv6_client_macs "192.168.1.250"
+    assert_success
+    assert_output ""
+}
+
+# ============================================================================
+# _ipv6_routed_clients
+# ============================================================================
+
+@test "_ipv6_routed_clients: lists active clients of all routes" {
+    load_ipv6_module
+    run _ipv6_routed_clients
+    assert_success
+    assert_output --partial "xray 192.168.1.100"
+    assert_output --partial "xray 2001:db8:1::100"
+    assert_output --partial "wgc1 2001:db8:50::10"
+    refute_output --partial "192.168.1.102"
+}
+
+# ============================================================================
+# ipv6_apply / ipv6_stop
+# ============================================================================
+
+@test "ipv6_apply: blocks IPv4 clients by MAC and tunnel IPv6 clients by source" {
+    load_ipv6_module
+    : > /tmp/bats_ip6tables_calls.log
+
+    run ipv6_apply
+    assert_success
+    grep -q -- "-A VPD_LEAK6 -m mac --mac-source aa:bb:cc:dd:ee:01 -j REJECT" /tmp/bats_ip6tables_calls.log
+    grep -q -- "-A VPD_LEAK6 -m mac --mac-source aa:bb:cc:dd:ee:50 -j REJECT" /tmp/bats_ip6tables_calls.log
+    grep -q -- "-A VPD_LEAK6 -s 2001:db8:50::10 -j REJECT" /tmp/bats_ip6tables_calls.log
+    grep -q -- "-I FORWARD 1 -i br0 ! -o br0 -j VPD_LEAK6" /tmp/bats_ip6tables_calls.log
+    # Xray IPv6 clients are proxied, not blocked
+    ! grep -q -- "-s 2001:db8:1::100" /tmp/bats_ip6tables_calls.log
+}
+
+@test "ipv6_apply: warns about clients without a known MAC" {
+    load_ipv6_module
+    run ipv6_apply
+    assert_success
+    assert_output --partial "No MAC address known for xray client 192.168.1.101"
+}
+
+@test "ipv6_apply: does nothing when IPv6 is disabled" {
+    load_ipv6_module
+    _ipv6_init
+    _ipv6_enabled=0
+    : > /tmp/bats_ip6tables_calls.log
+
+    run ipv6_apply
+    assert_success
+    ! grep -q -- "-N VPD_LEAK6" /tmp/bats_ip6tables_calls.log
+}
+
+@test "ipv6_stop: removes FORWARD jump and chain" {
+    load_ipv6_module
+    run ipv6_stop
+    assert_success
+    assert_output --partial "Removed IPv6 leak block"
+}
+
+@test "ipv6_status: outputs status header" {
+    load_ipv6_module
+    run ipv6_status
+    assert_success
+    assert_output --partial "IPv6 Leak Block Status"
+}
@@ -301,3 +301,172 @@ EOF
     run _tproxy_setup_bypass_ipset
     assert_success
 }
+
+# ============================================================================
+# _tproxy_setup_domain_ipset - dnsmasq-populated bypass for exclude_domains
+# ============================================================================
+
+@test "_tproxy_setup_domain_ipset: skipped when exclude_domains empty" {
+    load_tproxy_module
+    run _tproxy_setup_domain_ipset
+    assert_failure
+}
+
+@test "_tproxy_setup_iptables: adds RETURN rule for exclude_domains ipset" {
+    load_common
+    export VPD_CONFIG_FILE="$TEST_ROOT/fixtures/vpn-director-domains.json"
+    source "$LIB_DIR/config.sh"
+    source "$LIB_DIR/ipset.sh" --source-only
+    source "$LIB_DIR/firewall.sh"
+    source "$LIB_DIR/tproxy.sh" --source-only
+    _tproxy_init
+
+    : > /tmp/bats_ipset_calls.log
+    : > /tmp/bats_iptables_calls.log
+
+    run _tproxy_setup_iptables
+    assert_success
+    grep -q "ipset create -exist VPD_DOM_BYPASS hash:ip" /tmp/bats_ipset_calls.log
+    grep -q -- "--match-set VPD_DOM_BYPASS dst -j RETURN" /tmp/bats_iptables_calls.log
+}
+
+@test "_tproxy_setup_iptables: adds per-client accounting rule before TPROXY" {
+    load_common
+    export VPD_CONFIG_FILE="$TEST_ROOT/fixtures/vpn-director-domains.json"
+    source "$LIB_DIR/config.sh"
+    source "$LIB_DIR/ipset.sh" --source-only
+    source "$LIB_DIR/firewall.sh"
+    source "$LIB_DIR/tproxy.sh" --source-only
+    _tproxy_init
+
+    : > /tmp/bats_iptables_calls.log
+
+    run _tproxy_setup_iptables
+    assert_success
+    local acct tproxy
+    acct=$(grep -n -- "-A XRAY_TPROXY -s 192.168.1.100$" /tmp/bats_iptables_calls.log | head -1 | cut -d: -f1)
+    tproxy=$(grep -n -- "-A XRAY_TPROXY -p tcp -j TPROXY" /tmp/bats_iptables_calls.log | head -1 | cut -d: -f1)
+    [ -n "$acct" ]
+    [ "$acct" -lt "$tproxy" ]
+}
+
+@test "_tproxy_setup_iptables: counts Xray replies to each client as download" {
+    load_common
+    export VPD_CONFIG_FILE="$TEST_ROOT/fixtures/vpn-director-domains.json"
+    source "$LIB_DIR/config.sh"
+    source "$LIB_DIR/ipset.sh" --source-only
+    source "$LIB_DIR/firewall.sh"
+    source "$LIB_DIR/tproxy.sh" --source-only
+    _tproxy_init
+
+    : > /tmp/bats_iptables_calls.log
+
+    run _tproxy_setup_iptables
+    assert_success
+    grep -q -- "-A XRAY_TPROXY_DL -d 192.168.1.100$" /tmp/bats_iptables_calls.log
+    grep -q -- "-A OUTPUT -o br0 -m addrtype ! --src-type LOCAL -j XRAY_TPROXY_DL" /tmp/bats_iptables_calls.log
+}
+
+# ============================================================================
+# IPv6 (dual-stack)
+# ============================================================================
+
+load_tproxy_ipv6_fixture() {
+    load_common
+    export VPD_CONFIG_FILE="$TEST_ROOT/fixtures/vpn-director-ipv6.json"
+    source "$LIB_DIR/config.sh"
+    source "$LIB_DIR/ipset.sh" --source-only
+    source "$LIB_DIR/firewall.sh"
+    source "$LIB_DIR/tproxy.sh" --source-only
+    _tproxy_init
+}
+
+@test "_tproxy_validate_ipv6_cidr: accepts addresses and prefixes" {
+    load_tproxy_module
+    run _tproxy_validate_ipv6_cidr "2001:db8::1"
+    assert_success
+    run _tproxy_validate_ipv6_cidr "2001:db8:ffff::/48"
+    assert_success
+    run _tproxy_validate_ipv6_cidr "2001:0db8:0000:0000:0000:0000:0000:0001"
+    assert_success
+}
+
+@test "_tproxy_validate_ipv6_cidr: rejects invalid input" {
+    load_tproxy_module
+    run _tproxy_validate_ipv6_cidr "2001:db8::zz"
+    assert_failure
+    run _tproxy_validate_ipv6_cidr "2001:db8::/129"
+    assert_failure
+    run _tproxy_validate_ipv6_cidr "2001::db8::1"
+    assert_failure
+    run _tproxy_validate_ipv6_cidr "10.0.0.1"
+    assert_failure
+}
+
+@test "_tproxy_setup_clients_ipset: splits clients by family" {
+    load_tproxy_ipv6_fixture
+    : > /tmp/bats_ipset_calls.log
+
+    run _tproxy_setup_clients_ipset
+    assert_success
+    grep -q "ipset create XRAY_CLIENTS6 hash:net family inet6" /tmp/bats_ipset_calls.log
+    grep -q "ipset add XRAY_CLIENTS 192.168.1.100" /tmp/bats_ipset_calls.log
+    grep -q "ipset add XRAY_CLIENTS6 2001:db8:1::100" /tmp/bats_ipset_calls.log
+    ! grep -q "ipset add XRAY_CLIENTS 2001:" /tmp/bats_ipset_calls.log
+    # Paused client is not added
+    ! grep -q "192.168.1.102" /tmp/bats_ipset_calls.log
+}
+
+@test "_tproxy_setup_bypass_ipset: adds IPv6 servers and exclude_ips to the inet6 set" {
+    load_tproxy_ipv6_fixture
+    : > /tmp/bats_ipset_calls.log
+
+    run _tproxy_setup_bypass_ipset
+    assert_success
+    grep -q "ipset add TPROXY_BYPASS6 2001:db8::1" /tmp/bats_ipset_calls.log
+    grep -q "ipset add TPROXY_BYPASS6 2001:db8:ffff::/48" /tmp/bats_ipset_calls.log
+    grep -q "ipset add TPROXY_BYPASS 5.6.7.8" /tmp/bats_ipset_calls.log
+    assert_output --partial "Invalid exclude_ips entry '2001:db8::zz'"
+}
+
+@test "_tproxy_setup_ip6tables: builds chain for IPv6 clients" {
+    load_tproxy_ipv6_fixture
+    : > /tmp/bats_ip6tables_calls.log
+
+    run _tproxy_setup_ip6tables
+    assert_success
+    grep -q -- "-A XRAY_TPROXY -m set ! --match-set XRAY_CLIENTS6 src -j RETURN" /tmp/bats_ip6tables_calls.log
+    grep -q -- "-A XRAY_TPROXY -d fe80::/10 -j RETURN" /tmp/bats_ip6tables_calls.log
+    grep -q -- "-p tcp -j TPROXY --on-port 12345" /tmp/bats_ip6tables_calls.log
+}
+
+@test "_tproxy_setup_ip6tables: adds accounting rules for IPv6 clients only" {
+    load_tproxy_ipv6_fixture
+    : > /tmp/bats_ip6tables_calls.log
+
+    run _tproxy_setup_ip6tables
+    assert_success
+    grep -q -- "-A XRAY_TPROXY -s 2001:db8:1::100$" /tmp/bats_ip6tables_calls.log
+    grep -q -- "-A XRAY_TPROXY_DL -d 2001:db8:1::100$" /tmp/bats_ip6tables_calls.log
+    ! grep -q -- "-A XRAY_TPROXY -s 192.168.1.100$" /tmp/bats_ip6tables_calls.log
+}
+
+@test "_tproxy_setup_ip6tables: skipped without IPv6 clients" {
+    load_tproxy_module
+    _tproxy_init
+    : > /tmp/bats_ip6tables_calls.log
+
+    run _tproxy_setup_ip6tables
+    assert_success
+    ! grep -q -- "-N XRAY_TPROXY" /tmp/bats_ip6tables_calls.log
+}
+
+@test "_tproxy_setup_routing: adds IPv6 rule when IPv6 enabled" {
+    load_tproxy_module
+    _tproxy_init
+    : > /tmp/bats_ip_calls.log
+
+    run _tproxy_setup_routing
+    assert_success
+    grep -q "ip -6 rule add pref 200 fwmark 0x100/0x100 table 100" /tmp/bats_ip_calls.log
+}
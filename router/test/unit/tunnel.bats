@@ -206,6 +206,23 @@ load '../test_helper'
     grep -q -- '-i br0.*-j TUN_DIR' /tmp/bats_iptables_calls.log
 }
 
+@test "tunnel_apply: counts replies from the tunnel as download" {
+    load_tunnel_module
+    : > /tmp/bats_iptables_calls.log
+    run tunnel_apply
+    assert_success
+    grep -q -- '-A TUN_DIR_DL -i wgc1 -d 192.168.50.0/24$' /tmp/bats_iptables_calls.log
+    grep -q -- '-A FORWARD -o br0 -j TUN_DIR_DL' /tmp/bats_iptables_calls.log
+}
+
+@test "_tunnel_iface: maps tunnels to interfaces" {
+    load_tunnel_module
+    [ "$(_tunnel_iface wgc1)" = "wgc1" ]
+    [ "$(_tunnel_iface ovpnc2)" = "tun12" ]
+    run _tunnel_iface main
+    assert_failure
+}
+
 # ============================================================================
 # Module loading
 # ============================================================================
@@ -323,3 +340,36 @@ load '../test_helper'
     assert_output --partial "192.168.50.0/24"
     assert_output --partial "mark="
 }
+
+# ============================================================================
+# Domain routing (dnsmasq-populated ipsets)
+# ============================================================================
+
+@test "_tunnel_domain_ipset: upper-cases tunnel name" {
+    load_tunnel_module
+    run _tunnel_domain_ipset "ovpnc2"
+    assert_success
+    assert_output "VPD_DOM_OVPNC2"
+}
+
+@test "tunnel_apply: routes domain-only tunnel via its ipset" {
+    load_common
+    source "$LIB_DIR/firewall.sh"
+    export VPD_CONFIG_FILE="$TEST_ROOT/fixtures/vpn-director-domains.json"
+    source "$LIB_DIR/config.sh"
+    source "$LIB_DIR/ipset.sh" --source-only
+    source "$LIB_DIR/tunnel.sh" --source-only
+
+    : > /tmp/bats_ipset_calls.log
+    : > /tmp/bats_iptables_calls.log
+
+    run tunnel_apply
+    assert_success
+    refute_output --partial "no clients"
+    assert_output --partial "ipset=VPD_DOM_WGC1"
+
+    # ipset is created without flush so dnsmasq entries survive re-apply
+    grep -q "ipset create -exist VPD_DOM_WGC1 hash:ip" /tmp/bats_ipset_calls.log
+    ! grep -q "ipset flush VPD_DOM_WGC1" /tmp/bats_ipset_calls.log
+    grep -q -- "--match-set VPD_DOM_WGC1 dst" /tmp/bats_iptables_calls.log
+}
@@ -13,12 +13,21 @@ import (
 
 	"errors"
 
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/accesslog"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/banlist"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/bot"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/chatstore"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/config"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/devmode"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/events"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/logging"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/loginapproval"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/notify"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/paths"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/resolver"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/service"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/tlscert"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/traffic"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/updatechecker"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/updater"
 )
@@ -42,10 +51,12 @@ func main() {
 
 	var p paths.Paths
 	var opts []bot.Option
+	var executor service.ShellExecutor
 
 	if *devFlag {
 		p = paths.DevPaths()
-		opts = append(opts, bot.WithDevMode(devmode.NewExecutor()))
+		executor = devmode.NewExecutor()
+		opts = append(opts, bot.WithDevMode(executor))
 		// Validate testdata/dev exists before proceeding
 		if _, err := os.Stat(p.ScriptsDir); os.IsNotExist(err) {
 			fmt.Fprintf(os.Stderr, "Error: %s not found\n", p.ScriptsDir)
@@ -103,7 +114,7 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	logger.StartRotation(ctx, []string{p.BotLogPath, p.VPNLogPath}, maxLogSize, time.Minute)
+	logger.StartRotation(ctx, []string{p.BotLogPath, p.VPNLogPath, p.XrayLogPath}, maxLogSize, time.Minute)
 
 	// Create chat store for update notifications (not in dev mode)
 	var store *chatstore.Store
@@ -157,6 +168,58 @@ func main() {
 		go checker.Run(ctx, cfg.UpdateCheckInterval)
 	}
 
+	// Start server re-resolver if configured
+	if cfg.ServerResolveInterval > 0 {
+		var notifier resolver.Notifier
+		if store != nil {
+			notifier = notify.New(store, b.Sender(), b.Auth())
+		}
+		res := resolver.New(
+			service.NewConfigService(p.ScriptsDir, p.DefaultDataDir),
+			events.NewVPNDirector(service.NewVPNDirectorService(p.ScriptsDir, executor), b.Events(), "resolver"),
+			notifier,
+			resolver.WithAudit(b.Audit()),
+		)
+		go res.Run(ctx, cfg.ServerResolveInterval)
+	}
+
+	// Send the weekly uptime summary if configured (not in dev mode)
+	if cfg.WeeklySummary != "" && store != nil {
+		schedule, err := events.ParseSchedule(cfg.WeeklySummary)
+		if err != nil {
+			slog.Warn("Invalid weekly_summary, summary disabled", "error", err)
+		} else {
+			summary := events.NewWeeklySummary(b.Events(), notify.New(store, b.Sender(), b.Auth()), schedule)
+			go summary.Run(ctx)
+		}
+	}
+
+	// Warn before the Web UI certificate expires (not in dev mode)
+	if store != nil {
+		monitor := tlscert.NewMonitor(service.NewConfigService(p.ScriptsDir, p.DefaultDataDir), notify.New(store, b.Sender(), b.Auth()))
+		go monitor.Run(ctx, tlscert.DefaultCheckInterval)
+	}
+
+	// Tell users about login bans set here or by the Web UI (not in dev mode)
+	if store != nil {
+		announcer := banlist.NewAnnouncer(b.Bans(), notify.New(store, b.Sender(), b.Auth()))
+		go announcer.Run(ctx, banlist.DefaultAnnounceInterval)
+	}
+
+	// Start traffic accounting (the Web UI runs one too; a lock picks one)
+	collector := traffic.NewCollector(service.NewConfigService(p.ScriptsDir, p.DefaultDataDir), executor)
+	go collector.Run(ctx, traffic.DefaultInterval)
+
+	// Answer Web UI login requests sent over the local socket
+	go func() {
+		if err := loginapproval.NewServer(p.LoginSocket, b.LoginApprover()).Serve(ctx); err != nil {
+			slog.Warn("Login approval socket stopped", "error", err)
+		}
+	}()
+
+	// Index the Xray access log for /access
+	go b.AccessLog().Follow(ctx, p.XrayLogPath, accesslog.DefaultPollInterval)
+
 	slog.Info("Telegram Bot started", "version", versionString())
 	b.Run(ctx)
 	slog.Info("Bot stopped")
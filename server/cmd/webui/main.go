@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"crypto/rand"
+	"crypto/tls"
 	"encoding/base64"
 	"flag"
 	"fmt"
@@ -15,14 +16,37 @@ import (
 	"syscall"
 	"time"
 
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/accesslog"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/apitoken"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/audit"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/auth"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/banlist"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/conntrack"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/devmode"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/diagnostics"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/events"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/localuser"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/loginapproval"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/netaccess"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/paths"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/service"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/session"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/speedtest"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/tlscert"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/totp"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/traffic"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/updater"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/webapi"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/xraystats"
 )
 
+// updateCheckInterval is how often the Web UI checks for a newer release
+// (exported as vpnd_update_available on /metrics).
+const updateCheckInterval = 6 * time.Hour
+
 var (
 	Version   = "dev"
 	Commit    = "unknown"
@@ -33,6 +57,7 @@ func main() {
 	configPath := flag.String("config", "/opt/vpn-director/vpn-director.json", "path to vpn-director.json")
 	shadowPath := flag.String("shadow", "/etc/shadow", "path to shadow file")
 	devFlag := flag.Bool("dev", false, "run in development mode (HTTP, mock executor, testdata paths)")
+	rotateFlag := flag.Bool("rotate-jwt-secret", false, "replace jwt_secret, sign out all sessions and exit")
 	flag.Parse()
 
 	// In dev mode, override defaults with testdata paths.
@@ -56,6 +81,23 @@ func main() {
 		p = paths.Default()
 	}
 
+	if *rotateFlag {
+		if err := rotateJWTSecret(*configPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("jwt_secret replaced and all sessions signed out. Restart the Web UI to use the new secret.")
+		return
+	}
+
+	if flag.Arg(0) == "users" {
+		if err := runUsers(*configPath, flag.Args()[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	slog.Info("starting VPN Director Web UI", "version", Version, "commit", Commit, "dev", *devFlag)
 
 	// Load config
@@ -69,24 +111,42 @@ func main() {
 		vpnCfg.WebUI.Port = 8444
 	}
 	if vpnCfg.WebUI.CertFile == "" {
-		vpnCfg.WebUI.CertFile = "/opt/vpn-director/certs/server.crt"
+		vpnCfg.WebUI.CertFile = tlscert.DefaultCertFile
 	}
 	if vpnCfg.WebUI.KeyFile == "" {
-		vpnCfg.WebUI.KeyFile = "/opt/vpn-director/certs/server.key"
+		vpnCfg.WebUI.KeyFile = tlscert.DefaultKeyFile
+	}
+	for _, backend := range vpnCfg.WebUI.AuthBackends {
+		if !auth.ValidBackend(backend) {
+			slog.Warn("Unknown auth backend ignored", "backend", backend, "valid", auth.Backends)
+		}
 	}
 
-	// Auto-generate JWT secret if empty
-	if vpnCfg.WebUI.JWTSecret == "" {
-		slog.Warn("jwt_secret not set, generating random secret")
-		secret := make([]byte, 32)
-		if _, err := rand.Read(secret); err != nil {
-			slog.Error("failed to generate jwt secret", "error", err)
+	// Auto-generate JWT and TOTP secrets if empty
+	generated := false
+	for _, field := range []struct {
+		name  string
+		value *string
+	}{
+		{"jwt_secret", &vpnCfg.WebUI.JWTSecret},
+		{"totp_key", &vpnCfg.WebUI.TOTPKey},
+	} {
+		if *field.value != "" {
+			continue
+		}
+		slog.Warn(field.name + " not set, generating random secret")
+		secret, err := randomSecret()
+		if err != nil {
+			slog.Error("failed to generate "+field.name, "error", err)
 			os.Exit(1)
 		}
-		vpnCfg.WebUI.JWTSecret = base64.StdEncoding.EncodeToString(secret)
+		*field.value = secret
+		generated = true
+	}
+	if generated {
 		if err := vpnconfig.SaveVPNDirectorConfig(*configPath, vpnCfg); err != nil {
-			slog.Warn("failed to save auto-generated jwt_secret", "error", err)
-			// Continue anyway — secret is in memory for this session
+			slog.Warn("failed to save auto-generated secrets", "error", err)
+			// Continue anyway — secrets are in memory for this session
 		}
 	}
 
@@ -102,26 +162,70 @@ func main() {
 	defaultDataDir := filepath.Join(scriptsDir, "data")
 
 	configSvc := service.NewConfigService(scriptsDir, defaultDataDir)
-	vpnSvc := service.NewVPNDirectorService(scriptsDir, executor)
+	eventSvc := events.NewService(configSvc)
+	vpnSvc := events.NewVPNDirector(service.NewVPNDirectorService(scriptsDir, executor), eventSvc, "webui")
 	xraySvc := service.NewXrayService(p.XrayTemplate, p.XrayConfig)
-	networkSvc := service.NewNetworkService(executor)
+	networkSvc := service.NewNetworkService(configSvc)
 	logSvc := service.NewLogService(executor)
+	domainSvc := service.NewDomainService(scriptsDir, executor)
+	trafficSvc := traffic.NewService(configSvc)
+	xrayStats := xraystats.NewClient(xraystats.DefaultAddr)
+	systemSvc := service.NewSystemService(executor)
+	updates := updater.NewWatcher(updater.New(), Version)
+	accessIdx := accesslog.NewIndex(accesslog.DefaultCapacity)
+	connSvc := conntrack.NewService(configSvc, executor, p.ConntrackPath)
+	diagSvc := diagnostics.NewService(configSvc, executor, networkSvc)
+	speedSvc := speedtest.NewService(configSvc)
 
 	// Auth
 	shadowAuth := auth.NewShadowAuth(*shadowPath)
 	jwtSvc := auth.NewJWTService(vpnCfg.WebUI.JWTSecret, 24*time.Hour)
+	tokenSvc := apitoken.NewService(configSvc)
+	totpSvc := totp.NewService(configSvc, vpnCfg.WebUI.TOTPKey)
+	sessionSvc := session.NewService(configSvc)
+	banSvc := banlist.NewService(configSvc)
+	userSvc := localuser.NewService(configSvc)
+	auditSvc := audit.NewService(configSvc)
+
+	// TLS: certificates are reloaded from disk without a restart; dev mode
+	// serves plain HTTP and has nothing to manage.
+	var serverTLS *tls.Config
+	var certSvc tlscert.Manager
+	if !*devFlag {
+		serverTLS, certSvc = setupTLS(vpnCfg.WebUI, configSvc.DataDirOrDefault())
+	}
 
 	deps := &webapi.Deps{
-		Config:  configSvc,
-		VPN:     vpnSvc,
-		Xray:    xraySvc,
-		Network: networkSvc,
-		Logs:    logSvc,
-		Shadow:  shadowAuth,
-		JWT:     jwtSvc,
-		Version: Version,
-		Commit:  Commit,
-		OpMutex: &sync.Mutex{},
+		Config:      configSvc,
+		VPN:         vpnSvc,
+		Xray:        xraySvc,
+		Network:     networkSvc,
+		Logs:        logSvc,
+		Domains:     domainSvc,
+		Traffic:     trafficSvc,
+		XrayStats:   xrayStats,
+		XrayConfig:  xraySvc,
+		System:      systemSvc,
+		Access:      accessIdx,
+		Connections: connSvc,
+		Diagnostics: diagSvc,
+		SpeedTest:   speedSvc,
+		Events:      eventSvc,
+		Tokens:      tokenSvc,
+		TOTP:        totpSvc,
+		Sessions:    sessionSvc,
+		Certs:       certSvc,
+		Bans:        banSvc,
+		Users:       userSvc,
+		Audit:       auditSvc,
+		Approvals:   loginapproval.NewClient(p.LoginSocket),
+		Updates:     updates,
+		Paths:       p,
+		Shadow:      shadowAuth,
+		JWT:         jwtSvc,
+		Version:     Version,
+		Commit:      Commit,
+		OpMutex:     &sync.Mutex{},
 	}
 
 	// Embedded SPA files
@@ -137,11 +241,28 @@ func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
+	// Traffic accounting (the bot runs one too; a lock picks one)
+	go traffic.NewCollector(configSvc, executor).Run(ctx, traffic.DefaultInterval)
+
+	// Xray access log index for /api/access
+	go accessIdx.Follow(ctx, p.XrayLogPath, accesslog.DefaultPollInterval)
+
+	// Record Xray crashes and recoveries on the event timeline
+	go events.NewMonitor(eventSvc, systemSvc).Run(ctx, events.DefaultProbeInterval)
+
+	// Release check for the metrics endpoint (dev builds are never checked)
+	if !*devFlag {
+		go updates.Run(ctx, updateCheckInterval)
+	}
+
 	serverCfg := webapi.ServerConfig{
-		Port:     vpnCfg.WebUI.Port,
-		CertFile: vpnCfg.WebUI.CertFile,
-		KeyFile:  vpnCfg.WebUI.KeyFile,
-		DevMode:  *devFlag,
+		Port:    vpnCfg.WebUI.Port,
+		TLS:     serverTLS,
+		DevMode: *devFlag,
+	}
+	if err := setupListeners(&serverCfg, vpnCfg.WebUI); err != nil {
+		slog.Error("invalid listen/allow settings", "error", err)
+		os.Exit(1)
 	}
 
 	if *devFlag {
@@ -158,6 +279,113 @@ func main() {
 	}
 }
 
+// randomSecret returns 32 random bytes, base64-encoded.
+func randomSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(secret), nil
+}
+
+// rotateJWTSecret saves a new webui.jwt_secret and empties the session
+// registry, so every token signed with the old secret is refused at once,
+// even by a Web UI still running with it. The new secret is used after a
+// restart; the init script's rotate-secret action does both.
+func rotateJWTSecret(configPath string) error {
+	vpnCfg, err := vpnconfig.LoadVPNDirectorConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	secret, err := randomSecret()
+	if err != nil {
+		return fmt.Errorf("generate jwt_secret: %w", err)
+	}
+	vpnCfg.WebUI.JWTSecret = secret
+	if err := vpnconfig.SaveVPNDirectorConfig(configPath, vpnCfg); err != nil {
+		return fmt.Errorf("save config: %w", err)
+	}
+	scriptsDir := filepath.Dir(configPath)
+	configSvc := service.NewConfigService(scriptsDir, filepath.Join(scriptsDir, "data"))
+	n, err := session.NewService(configSvc).RevokeAll("", "")
+	if err != nil {
+		return fmt.Errorf("revoke sessions: %w", err)
+	}
+	slog.Info("jwt_secret rotated", "revoked_sessions", n)
+	return nil
+}
+
+// setupTLS returns the server TLS configuration and the certificate
+// manager. A certificate from the local CA is generated on first start;
+// when webui.acme lists domains, those are served by ACME. Exits when the
+// certificate cannot be loaded.
+func setupTLS(cfg vpnconfig.WebUIConfig, dataDir string) (*tls.Config, tlscert.Manager) {
+	if _, err := os.Stat(cfg.CertFile); os.IsNotExist(err) {
+		slog.Info("no TLS certificate, generating one", "file", cfg.CertFile)
+		if _, err := tlscert.Generate(cfg.CertFile, cfg.KeyFile, tlscert.DefaultHosts()); err != nil {
+			slog.Error("failed to generate TLS certificate", "error", err)
+			os.Exit(1)
+		}
+	}
+	reloader, err := tlscert.NewReloader(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		slog.Error("failed to load TLS certificate", "error", err)
+		os.Exit(1)
+	}
+
+	var acm *autocert.Manager
+	var domains []string
+	if cfg.ACME != nil && len(cfg.ACME.Domains) > 0 {
+		acm = tlscert.NewACME(*cfg.ACME, filepath.Join(dataDir, "acme"))
+		domains = cfg.ACME.Domains
+		slog.Info("ACME enabled", "domains", domains)
+	}
+	return tlscert.ServerTLS(reloader, acm, domains), tlscert.NewService(cfg.CertFile, cfg.KeyFile, reloader)
+}
+
+// bridgeWait is how long setupListeners waits for the LAN bridge, which may
+// come up after the Web UI at boot.
+const bridgeWait = time.Minute
+
+// setupListeners fills in the listen addresses, the plain-HTTP redirect
+// listeners and the client policy from webui.listen, allow, deny and
+// redirect_port. Without webui.listen it waits for the LAN bridge and fails
+// if it does not appear; only dev mode falls back to every interface.
+func setupListeners(serverCfg *webapi.ServerConfig, cfg vpnconfig.WebUIConfig) error {
+	ifs, err := netaccess.SystemInterfaces()
+	if len(cfg.Listen) == 0 && !serverCfg.DevMode {
+		deadline := time.Now().Add(bridgeWait)
+		for (err != nil || ifs[netaccess.LANBridge] == nil) && time.Now().Before(deadline) {
+			slog.Info("waiting for LAN bridge", "bridge", netaccess.LANBridge, "error", err)
+			time.Sleep(2 * time.Second)
+			ifs, err = netaccess.SystemInterfaces()
+		}
+	}
+	if err != nil {
+		if !serverCfg.DevMode {
+			return fmt.Errorf("read network interfaces: %w", err)
+		}
+		slog.Warn("failed to read network interfaces", "error", err)
+	}
+	if serverCfg.DevMode && len(cfg.Listen) == 0 && ifs[netaccess.LANBridge] == nil {
+		cfg.Listen = []string{"*"}
+	}
+
+	if serverCfg.Addrs, err = ifs.ListenAddrs(cfg.Listen, cfg.Port); err != nil {
+		return err
+	}
+	if cfg.RedirectPort > 0 {
+		if serverCfg.RedirectAddrs, err = ifs.ListenAddrs(cfg.Listen, cfg.RedirectPort); err != nil {
+			return err
+		}
+	}
+	if serverCfg.Access, err = netaccess.NewPolicy(cfg.Allow, cfg.Deny, ifs); err != nil {
+		return err
+	}
+	slog.Info("Web UI clients", "policy", serverCfg.Access.String())
+	return nil
+}
+
 // ensureDevFiles creates default dev config and shadow files if they don't exist,
 // so that `go run ./cmd/webui --dev` works out of the box.
 func ensureDevFiles(configPath, shadowPath, dataDir string) {
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/auth"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/localuser"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/service"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/session"
+)
+
+const usersUsage = `usage: webui [-config path] users <command>
+
+  list                  show local users
+  add <name> [role]     add a user (role: admin, operator or viewer; default viewer)
+  remove <name>         delete a user
+  passwd <name>         change a user's password
+
+Passwords are read from standard input.`
+
+// runUsers manages local Web UI users from the command line, so the first
+// account can be created without logging in.
+func runUsers(configPath string, args []string) error {
+	if len(args) == 0 {
+		return errors.New(usersUsage)
+	}
+	scriptsDir := filepath.Dir(configPath)
+	configSvc := service.NewConfigService(scriptsDir, filepath.Join(scriptsDir, "data"))
+	users := localuser.NewService(configSvc)
+
+	cmd, args := args[0], args[1:]
+	switch {
+	case cmd == "list" && len(args) == 0:
+		list, err := users.List()
+		if err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			fmt.Println("No local users.")
+			return nil
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "USER\tROLE\tPASSWORD CHANGED")
+		for _, u := range list {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", u.Username, u.Role, u.Updated.Local().Format("2006-01-02 15:04"))
+		}
+		return tw.Flush()
+
+	case cmd == "add" && (len(args) == 1 || len(args) == 2):
+		role := auth.RoleViewer
+		if len(args) == 2 {
+			role = args[1]
+		}
+		if !auth.ValidRole(role) {
+			return fmt.Errorf("role must be one of %s", strings.Join(auth.Roles, ", "))
+		}
+		if err := localuser.ValidateUsername(args[0]); err != nil {
+			return err
+		}
+		password, err := readPassword()
+		if err != nil {
+			return err
+		}
+		if _, err := users.Add(args[0], password, role); err != nil {
+			return err
+		}
+		fmt.Printf("User %s added as %s.\n", args[0], role)
+		return nil
+
+	case cmd == "remove" && len(args) == 1:
+		if err := users.Remove(args[0]); err != nil {
+			return err
+		}
+		if _, err := session.NewService(configSvc).RevokeAll(args[0], ""); err != nil {
+			return fmt.Errorf("sign out %s: %w", args[0], err)
+		}
+		fmt.Printf("User %s removed.\n", args[0])
+		return nil
+
+	case cmd == "passwd" && len(args) == 1:
+		if _, err := users.Get(args[0]); err != nil {
+			return err
+		}
+		password, err := readPassword()
+		if err != nil {
+			return err
+		}
+		if err := users.SetPassword(args[0], password); err != nil {
+			return err
+		}
+		if _, err := session.NewService(configSvc).RevokeAll(args[0], ""); err != nil {
+			return fmt.Errorf("sign out %s: %w", args[0], err)
+		}
+		fmt.Printf("Password of %s changed.\n", args[0])
+		return nil
+	}
+	return errors.New(usersUsage)
+}
+
+// readPassword reads a new password from stdin. On a terminal it prompts
+// twice with echo turned off; otherwise it reads the first line, so
+// passwords can be piped in from scripts.
+func readPassword() (string, error) {
+	in := bufio.NewReader(os.Stdin)
+	if fi, err := os.Stdin.Stat(); err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		line, err := in.ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("read password: %w", err)
+		}
+		password := strings.TrimRight(line, "\r\n")
+		return password, localuser.ValidatePassword(password)
+	}
+
+	stty := func(arg string) {
+		cmd := exec.Command("stty", arg)
+		cmd.Stdin = os.Stdin
+		_ = cmd.Run()
+	}
+	stty("-echo")
+	defer stty("echo")
+
+	prompt := func(label string) (string, error) {
+		fmt.Fprint(os.Stderr, label)
+		line, err := in.ReadString('\n')
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("read password: %w", err)
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+	password, err := prompt("Password: ")
+	if err != nil {
+		return "", err
+	}
+	if err := localuser.ValidatePassword(password); err != nil {
+		return "", err
+	}
+	again, err := prompt("Repeat password: ")
+	if err != nil {
+		return "", err
+	}
+	if again != password {
+		return "", errors.New("passwords do not match")
+	}
+	return password, nil
+}
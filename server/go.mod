@@ -9,3 +9,9 @@ require golang.org/x/net v0.52.0
 require github.com/tredoe/osutil v1.5.0
 
 require github.com/golang-jwt/jwt/v5 v5.3.1
+
+require github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
+
+require golang.org/x/crypto v0.49.0
+
+require golang.org/x/text v0.35.0 // indirect
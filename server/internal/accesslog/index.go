@@ -0,0 +1,266 @@
+package accesslog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/logstream"
+)
+
+const (
+	// DefaultCapacity is the number of records kept in memory.
+	DefaultCapacity = 20000
+
+	// DefaultPollInterval is how often the access log is read.
+	DefaultPollInterval = 2 * time.Second
+
+	// Retention drops records older than this.
+	Retention = 24 * time.Hour
+
+	// DefaultLimit and MaxLimit bound the number of results returned.
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+// Group keys for Top.
+const (
+	GroupDestination = "destination"
+	GroupClient      = "client"
+	GroupOutbound    = "outbound"
+)
+
+// Searcher searches and aggregates access records.
+type Searcher interface {
+	Search(q Query) Result
+	Top(q Query, by string) ([]Group, error)
+}
+
+// Query filters records. Empty fields match everything.
+type Query struct {
+	Client      string    // exact source IP
+	Destination string    // case-insensitive substring of the destination
+	Outbound    string    // exact outbound tag
+	Status      string    // accepted or rejected
+	Since       time.Time // only records at or after this time
+	Limit       int       // max results (DefaultLimit if 0, capped at MaxLimit)
+}
+
+// Result is a page of matching records, newest first.
+type Result struct {
+	Records []Record `json:"records"`
+	// Total is the number of matching records before the limit.
+	Total int `json:"total"`
+}
+
+// Group is an aggregation bucket.
+type Group struct {
+	Key      string    `json:"key"`
+	Count    int       `json:"count"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// Index is a ring buffer of the most recent access records.
+type Index struct {
+	mu       sync.RWMutex
+	records  []Record
+	next     int // write position once the buffer is full
+	full     bool
+	capacity int
+	loc      *time.Location
+	now      func() time.Time
+}
+
+var _ Searcher = (*Index)(nil)
+
+// NewIndex creates an index holding up to capacity records.
+func NewIndex(capacity int) *Index {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	return &Index{
+		records:  make([]Record, 0, capacity),
+		capacity: capacity,
+		loc:      time.Local,
+		now:      time.Now,
+	}
+}
+
+// Add stores a record, evicting the oldest when full.
+func (x *Index) Add(rec Record) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if !x.full {
+		x.records = append(x.records, rec)
+		if len(x.records) == x.capacity {
+			x.full = true
+		}
+		return
+	}
+	x.records[x.next] = rec
+	x.next = (x.next + 1) % x.capacity
+}
+
+// AddLine parses and stores a log line. It returns false for lines that are
+// not access entries.
+func (x *Index) AddLine(line string) bool {
+	rec, ok := ParseLine(line, x.loc)
+	if ok {
+		x.Add(rec)
+	}
+	return ok
+}
+
+// Follow reads the access log at path from the beginning and keeps
+// following it. Blocks until ctx is cancelled.
+func (x *Index) Follow(ctx context.Context, path string, interval time.Duration) {
+	slog.Info("Access log indexer started", "path", path)
+	f := logstream.NewFollower("xray", path, 0)
+	defer f.Close()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		lines, _, err := f.Poll()
+		if err != nil {
+			slog.Warn("Access log read failed", "path", path, "error", err)
+		}
+		for _, l := range lines {
+			x.AddLine(l.Text)
+		}
+
+		select {
+		case <-ctx.Done():
+			slog.Info("Access log indexer stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// each calls fn for records newest first, skipping expired ones, until fn
+// returns false.
+func (x *Index) each(fn func(Record) bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	cutoff := x.now().Add(-Retention)
+	n := len(x.records)
+	for i := 0; i < n; i++ {
+		// Newest record is just before next (or at the end when not full).
+		pos := (x.next - 1 - i + n) % n
+		if !x.full {
+			pos = n - 1 - i
+		}
+		rec := x.records[pos]
+		if rec.Time.Before(cutoff) {
+			continue
+		}
+		if !fn(rec) {
+			return
+		}
+	}
+}
+
+func (q Query) match(rec Record) bool {
+	if q.Client != "" && rec.Source != q.Client {
+		return false
+	}
+	if q.Destination != "" && !strings.Contains(strings.ToLower(rec.Destination), strings.ToLower(q.Destination)) {
+		return false
+	}
+	if q.Outbound != "" && rec.Outbound != q.Outbound {
+		return false
+	}
+	if q.Status != "" && rec.Status != q.Status {
+		return false
+	}
+	if !q.Since.IsZero() && rec.Time.Before(q.Since) {
+		return false
+	}
+	return true
+}
+
+func (q Query) limit() int {
+	switch {
+	case q.Limit <= 0:
+		return DefaultLimit
+	case q.Limit > MaxLimit:
+		return MaxLimit
+	}
+	return q.Limit
+}
+
+// Search returns matching records, newest first.
+func (x *Index) Search(q Query) Result {
+	limit := q.limit()
+	res := Result{Records: []Record{}}
+	x.each(func(rec Record) bool {
+		if q.match(rec) {
+			res.Total++
+			if len(res.Records) < limit {
+				res.Records = append(res.Records, rec)
+			}
+		}
+		return true
+	})
+	return res
+}
+
+// Top counts matching records by destination, client or outbound and
+// returns the largest groups first.
+func (x *Index) Top(q Query, by string) ([]Group, error) {
+	var key func(Record) string
+	switch by {
+	case GroupDestination, "":
+		key = func(r Record) string { return r.Destination }
+	case GroupClient:
+		key = func(r Record) string { return r.Source }
+	case GroupOutbound:
+		key = func(r Record) string { return r.Outbound }
+	default:
+		return nil, fmt.Errorf("unknown group %q: valid values are destination, client, outbound", by)
+	}
+
+	groups := map[string]*Group{}
+	x.each(func(rec Record) bool {
+		if !q.match(rec) {
+			return true
+		}
+		k := key(rec)
+		if k == "" {
+			return true
+		}
+		g, ok := groups[k]
+		if !ok {
+			g = &Group{Key: k, LastSeen: rec.Time}
+			groups[k] = g
+		}
+		g.Count++
+		if rec.Time.After(g.LastSeen) {
+			g.LastSeen = rec.Time
+		}
+		return true
+	})
+
+	list := make([]Group, 0, len(groups))
+	for _, g := range groups {
+		list = append(list, *g)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Key < list[j].Key
+	})
+	if limit := q.limit(); len(list) > limit {
+		list = list[:limit]
+	}
+	return list, nil
+}
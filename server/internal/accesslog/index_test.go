@@ -0,0 +1,180 @@
+package accesslog
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2026, 1, 11, 12, 0, 0, 0, time.UTC)
+
+func newTestIndex(capacity int) *Index {
+	x := NewIndex(capacity)
+	x.loc = time.UTC
+	x.now = func() time.Time { return testNow }
+	return x
+}
+
+func rec(ago time.Duration, client, dest, outbound string) Record {
+	return Record{
+		Time:        testNow.Add(-ago),
+		Source:      client,
+		Destination: dest,
+		DestPort:    443,
+		Outbound:    outbound,
+		Status:      StatusAccepted,
+	}
+}
+
+func TestIndex_SearchNewestFirst(t *testing.T) {
+	x := newTestIndex(10)
+	x.Add(rec(3*time.Minute, "192.168.50.2", "a.com", "proxy-out"))
+	x.Add(rec(2*time.Minute, "192.168.50.3", "b.com", "direct"))
+	x.Add(rec(1*time.Minute, "192.168.50.2", "c.com", "proxy-out"))
+
+	res := x.Search(Query{})
+	if res.Total != 3 || len(res.Records) != 3 {
+		t.Fatalf("expected 3 records, got %+v", res)
+	}
+	if res.Records[0].Destination != "c.com" || res.Records[2].Destination != "a.com" {
+		t.Errorf("expected newest first, got %v, %v", res.Records[0].Destination, res.Records[2].Destination)
+	}
+}
+
+func TestIndex_SearchFilters(t *testing.T) {
+	x := newTestIndex(10)
+	x.Add(rec(2*time.Hour, "192.168.50.2", "old.example.com", "proxy-out"))
+	x.Add(rec(30*time.Minute, "192.168.50.2", "www.Example.com", "proxy-out"))
+	x.Add(rec(20*time.Minute, "192.168.50.3", "example.org", "direct"))
+	x.Add(Record{Time: testNow.Add(-10 * time.Minute), Source: "192.168.50.2", Status: StatusRejected})
+
+	tests := []struct {
+		name string
+		q    Query
+		want int
+	}{
+		{"client", Query{Client: "192.168.50.2"}, 3},
+		{"destination substring case-insensitive", Query{Destination: "example.COM"}, 2},
+		{"outbound", Query{Outbound: "direct"}, 1},
+		{"status", Query{Status: StatusRejected}, 1},
+		{"since", Query{Since: testNow.Add(-time.Hour)}, 3},
+		{"combined", Query{Client: "192.168.50.2", Since: testNow.Add(-time.Hour), Status: StatusAccepted}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := x.Search(tt.q).Total; got != tt.want {
+				t.Errorf("Total = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndex_SearchLimit(t *testing.T) {
+	x := newTestIndex(10)
+	for i := 0; i < 5; i++ {
+		x.Add(rec(time.Duration(5-i)*time.Minute, "192.168.50.2", "a.com", "proxy-out"))
+	}
+
+	res := x.Search(Query{Limit: 2})
+	if res.Total != 5 || len(res.Records) != 2 {
+		t.Errorf("expected 2 of 5 records, got %d of %d", len(res.Records), res.Total)
+	}
+}
+
+func TestIndex_EvictsOldestWhenFull(t *testing.T) {
+	x := newTestIndex(3)
+	for i, dest := range []string{"a.com", "b.com", "c.com", "d.com", "e.com"} {
+		x.Add(rec(time.Duration(10-i)*time.Minute, "192.168.50.2", dest, "proxy-out"))
+	}
+
+	res := x.Search(Query{})
+	if res.Total != 3 {
+		t.Fatalf("expected 3 records, got %d", res.Total)
+	}
+	got := []string{res.Records[0].Destination, res.Records[1].Destination, res.Records[2].Destination}
+	if got[0] != "e.com" || got[1] != "d.com" || got[2] != "c.com" {
+		t.Errorf("expected e, d, c; got %v", got)
+	}
+}
+
+func TestIndex_DropsExpiredRecords(t *testing.T) {
+	x := newTestIndex(10)
+	x.Add(rec(Retention+time.Minute, "192.168.50.2", "a.com", "proxy-out"))
+	x.Add(rec(time.Minute, "192.168.50.2", "b.com", "proxy-out"))
+
+	if got := x.Search(Query{}).Total; got != 1 {
+		t.Errorf("expected 1 record within retention, got %d", got)
+	}
+}
+
+func TestIndex_Top(t *testing.T) {
+	x := newTestIndex(20)
+	x.Add(rec(5*time.Minute, "192.168.50.2", "a.com", "proxy-out"))
+	x.Add(rec(4*time.Minute, "192.168.50.2", "b.com", "direct"))
+	x.Add(rec(3*time.Minute, "192.168.50.2", "a.com", "proxy-out"))
+	x.Add(rec(2*time.Minute, "192.168.50.3", "a.com", "proxy-out"))
+	x.Add(rec(1*time.Minute, "192.168.50.3", "c.com", "proxy-out"))
+
+	groups, err := x.Top(Query{}, GroupDestination)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(groups) != 3 || groups[0].Key != "a.com" || groups[0].Count != 3 {
+		t.Fatalf("unexpected groups: %+v", groups)
+	}
+	if !groups[0].LastSeen.Equal(testNow.Add(-2 * time.Minute)) {
+		t.Errorf("LastSeen = %v", groups[0].LastSeen)
+	}
+	// Ties are ordered by key
+	if groups[1].Key != "b.com" || groups[2].Key != "c.com" {
+		t.Errorf("expected b.com, c.com; got %+v", groups[1:])
+	}
+
+	groups, _ = x.Top(Query{Client: "192.168.50.2"}, GroupOutbound)
+	if len(groups) != 2 || groups[0].Key != "proxy-out" || groups[0].Count != 2 {
+		t.Errorf("unexpected outbound groups: %+v", groups)
+	}
+
+	groups, _ = x.Top(Query{Limit: 1}, GroupClient)
+	if len(groups) != 1 || groups[0].Key != "192.168.50.2" {
+		t.Errorf("unexpected client groups: %+v", groups)
+	}
+}
+
+func TestIndex_TopUnknownGroup(t *testing.T) {
+	x := newTestIndex(10)
+	if _, err := x.Top(Query{}, "port"); err == nil {
+		t.Error("expected error for unknown group")
+	}
+}
+
+func TestIndex_Follow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	content := "2026/01/11 11:59:00 from 192.168.50.2:1000 accepted tcp:a.com:443 [tproxy-in -> proxy-out]\n" +
+		"2026/01/11 11:59:00 [Warning] core: something\n" +
+		"2026/01/11 11:59:30 from 192.168.50.3:1001 accepted tcp:b.com:443 [tproxy-in -> proxy-out]\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	x := newTestIndex(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		x.Follow(ctx, path, 10*time.Millisecond)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for x.Search(Query{}).Total < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if got := x.Search(Query{}).Total; got != 2 {
+		t.Errorf("expected 2 indexed records, got %d", got)
+	}
+}
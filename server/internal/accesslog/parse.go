@@ -0,0 +1,140 @@
+// Package accesslog parses the Xray access log into structured records and
+// keeps the most recent ones in a bounded in-memory index for search and
+// aggregation.
+package accesslog
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Connection status reported by Xray.
+const (
+	StatusAccepted = "accepted"
+	StatusRejected = "rejected"
+)
+
+// timeLayout is the timestamp format of Xray log lines (local time).
+const timeLayout = "2006/01/02 15:04:05.999999"
+
+// Record is one parsed access log entry.
+type Record struct {
+	Time       time.Time `json:"time"`
+	Source     string    `json:"source"`
+	SourcePort int       `json:"source_port"`
+	Network    string    `json:"network,omitempty"`
+	// Destination is the sniffed domain when available, else the IP.
+	Destination string `json:"destination"`
+	DestPort    int    `json:"dest_port"`
+	Inbound     string `json:"inbound,omitempty"`
+	Outbound    string `json:"outbound,omitempty"`
+	Status      string `json:"status"`
+	// Reason is the rejection message for rejected connections.
+	Reason string `json:"reason,omitempty"`
+	Email  string `json:"email,omitempty"`
+}
+
+// ParseLine parses one access log line, e.g.
+//
+//	2026/01/11 12:35:16.123456 from 192.168.50.23:54321 accepted tcp:www.google.com:443 [tproxy-in -> proxy-out]
+//
+// Older Xray versions omit "from" and the inbound tag. ok is false for lines
+// that are not access entries (e.g. error log lines).
+func ParseLine(line string, loc *time.Location) (rec Record, ok bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 5 {
+		return Record{}, false
+	}
+
+	t, err := time.ParseInLocation(timeLayout, fields[0]+" "+fields[1], loc)
+	if err != nil {
+		return Record{}, false
+	}
+	rec.Time = t
+
+	rest := fields[2:]
+	if rest[0] == "from" {
+		rest = rest[1:]
+	}
+	if len(rest) < 2 {
+		return Record{}, false
+	}
+
+	// Some versions prefix the source with its network ("tcp:1.2.3.4:5").
+	_, source := splitNetwork(rest[0])
+	host, port, ok := splitAddr(source)
+	if !ok {
+		return Record{}, false
+	}
+	rec.Source, rec.SourcePort = host, port
+
+	rec.Status = rest[1]
+	switch rec.Status {
+	case StatusAccepted:
+		if len(rest) < 3 {
+			return Record{}, false
+		}
+		network, dest := splitNetwork(rest[2])
+		host, port, ok := splitAddr(dest)
+		if !ok {
+			return Record{}, false
+		}
+		rec.Network, rec.Destination, rec.DestPort = network, host, port
+		parseRoute(&rec, rest[3:])
+	case StatusRejected:
+		// "rejected  proxy/vless/encoding: failed to read request version > EOF"
+		rec.Reason = strings.Join(rest[2:], " ")
+	default:
+		return Record{}, false
+	}
+	return rec, true
+}
+
+// parseRoute reads the optional "[in -> out]" (or "[in >> out]", "[out]")
+// tag and "email: x" suffix.
+func parseRoute(rec *Record, fields []string) {
+	rest := strings.Join(fields, " ")
+	if strings.HasPrefix(rest, "[") {
+		if end := strings.Index(rest, "]"); end > 0 {
+			tags := rest[1:end]
+			rest = strings.TrimSpace(rest[end+1:])
+			in, out, found := strings.Cut(tags, " -> ")
+			if !found {
+				in, out, found = strings.Cut(tags, " >> ")
+			}
+			if found {
+				rec.Inbound, rec.Outbound = strings.TrimSpace(in), strings.TrimSpace(out)
+			} else {
+				rec.Outbound = strings.TrimSpace(tags)
+			}
+		}
+	}
+	if email, ok := strings.CutPrefix(rest, "email: "); ok {
+		rec.Email = strings.TrimSpace(email)
+	}
+}
+
+// splitNetwork splits "tcp:host:port" into "tcp" and "host:port".
+func splitNetwork(s string) (network, addr string) {
+	for _, n := range []string{"tcp:", "udp:"} {
+		if strings.HasPrefix(s, n) {
+			return n[:3], s[len(n):]
+		}
+	}
+	return "", s
+}
+
+// splitAddr splits "host:port" or "[v6]:port".
+func splitAddr(s string) (string, int, bool) {
+	host, portStr, err := net.SplitHostPort(s)
+	if err != nil || host == "" {
+		return "", 0, false
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return "", 0, false
+	}
+	return host, port, true
+}
@@ -0,0 +1,119 @@
+package accesslog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLine_AcceptedWithTags(t *testing.T) {
+	line := "2026/01/11 12:35:16.123456 from 192.168.50.23:54321 accepted tcp:www.google.com:443 [tproxy-in -> proxy-out]"
+
+	rec, ok := ParseLine(line, time.UTC)
+	if !ok {
+		t.Fatal("expected line to parse")
+	}
+	want := time.Date(2026, 1, 11, 12, 35, 16, 123456000, time.UTC)
+	if !rec.Time.Equal(want) {
+		t.Errorf("Time = %v, want %v", rec.Time, want)
+	}
+	if rec.Source != "192.168.50.23" || rec.SourcePort != 54321 {
+		t.Errorf("source = %s:%d", rec.Source, rec.SourcePort)
+	}
+	if rec.Network != "tcp" || rec.Destination != "www.google.com" || rec.DestPort != 443 {
+		t.Errorf("destination = %s:%s:%d", rec.Network, rec.Destination, rec.DestPort)
+	}
+	if rec.Inbound != "tproxy-in" || rec.Outbound != "proxy-out" {
+		t.Errorf("tags = %q -> %q", rec.Inbound, rec.Outbound)
+	}
+	if rec.Status != StatusAccepted {
+		t.Errorf("Status = %q", rec.Status)
+	}
+}
+
+func TestParseLine_Variants(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		source   string
+		dest     string
+		port     int
+		inbound  string
+		outbound string
+		email    string
+	}{
+		{
+			name:     "no from, outbound only",
+			line:     "2026/01/11 12:35:16 192.168.50.23:54321 accepted udp:8.8.8.8:53 [direct]",
+			source:   "192.168.50.23",
+			dest:     "8.8.8.8",
+			port:     53,
+			outbound: "direct",
+		},
+		{
+			name:     "double arrow and email",
+			line:     "2026/01/11 12:35:16 from tcp:192.168.50.5:40000 accepted tcp:example.com:80 [socks-in >> proxy-out] email: user@example.com",
+			source:   "192.168.50.5",
+			dest:     "example.com",
+			port:     80,
+			inbound:  "socks-in",
+			outbound: "proxy-out",
+			email:    "user@example.com",
+		},
+		{
+			name:   "IPv6 addresses",
+			line:   "2026/01/11 12:35:16 from [fd00::5]:1234 accepted tcp:[2a00:1450::1]:443",
+			source: "fd00::5",
+			dest:   "2a00:1450::1",
+			port:   443,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, ok := ParseLine(tt.line, time.UTC)
+			if !ok {
+				t.Fatal("expected line to parse")
+			}
+			if rec.Source != tt.source || rec.Destination != tt.dest || rec.DestPort != tt.port {
+				t.Errorf("got %s -> %s:%d", rec.Source, rec.Destination, rec.DestPort)
+			}
+			if rec.Inbound != tt.inbound || rec.Outbound != tt.outbound {
+				t.Errorf("tags = %q -> %q", rec.Inbound, rec.Outbound)
+			}
+			if rec.Email != tt.email {
+				t.Errorf("Email = %q, want %q", rec.Email, tt.email)
+			}
+		})
+	}
+}
+
+func TestParseLine_Rejected(t *testing.T) {
+	line := "2026/01/11 12:35:16 from 10.0.0.9:5555 rejected  proxy/vless/encoding: failed to read request version > EOF"
+
+	rec, ok := ParseLine(line, time.UTC)
+	if !ok {
+		t.Fatal("expected line to parse")
+	}
+	if rec.Status != StatusRejected || rec.Source != "10.0.0.9" {
+		t.Errorf("got %+v", rec)
+	}
+	if rec.Reason != "proxy/vless/encoding: failed to read request version > EOF" {
+		t.Errorf("Reason = %q", rec.Reason)
+	}
+}
+
+func TestParseLine_NotAccessEntry(t *testing.T) {
+	lines := []string{
+		"",
+		"Xray 1.8.24 started",
+		"2026/01/11 12:35:16 [Warning] core: Xray 1.8.24 started",
+		"2026/01/11 12:35:16 from 192.168.50.23:54321 accepted",
+		"2026/01/11 12:35:16 from nonsense accepted tcp:a.com:443",
+		"not a date at all accepted tcp:a.com:443",
+	}
+	for _, line := range lines {
+		if _, ok := ParseLine(line, time.UTC); ok {
+			t.Errorf("expected %q to be rejected", line)
+		}
+	}
+}
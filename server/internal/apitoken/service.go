@@ -0,0 +1,47 @@
+package apitoken
+
+import (
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/service"
+)
+
+// Compile-time interface check
+var _ Manager = (*Service)(nil)
+
+// Service manages tokens in the configured data directory, which is looked
+// up on every call so a data_dir change takes effect without a restart.
+// The store is shared between calls: its lock keeps a concurrent last-use
+// update from writing back a token that was just revoked.
+type Service struct {
+	stores *service.StoreCache[*Store]
+}
+
+// NewService creates a new Service.
+func NewService(config service.ConfigStore) *Service {
+	return &Service{stores: service.NewStoreCache(config, func(dataDir string) *Store {
+		return NewStore(StorePath(dataDir))
+	})}
+}
+
+func (s *Service) store() *Store {
+	return s.stores.Get()
+}
+
+// Create adds a token and returns its secret.
+func (s *Service) Create(name string, scopes []string) (string, Token, error) {
+	return s.store().Create(name, scopes)
+}
+
+// List returns all tokens without their hashes.
+func (s *Service) List() ([]Token, error) {
+	return s.store().List()
+}
+
+// Revoke deletes the token with the given ID.
+func (s *Service) Revoke(id string) error {
+	return s.store().Revoke(id)
+}
+
+// Authenticate returns the token matching secret, or ErrInvalid.
+func (s *Service) Authenticate(secret string) (*Token, error) {
+	return s.store().Authenticate(secret)
+}
@@ -0,0 +1,282 @@
+// Package apitoken manages long-lived API tokens for automation (Home
+// Assistant, scripts). Only a SHA-256 hash of each token is stored; the
+// token itself is shown once, when it is created.
+package apitoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// StoreFile is the token file inside the data directory.
+const StoreFile = "api_tokens.json"
+
+// Prefix starts every token, so the auth middleware can tell them from JWTs.
+const Prefix = "vpd_"
+
+// Scopes. Every scope includes read; clients and servers additionally allow
+// managing those, admin allows everything. The web API maps them to route
+// permissions.
+const (
+	ScopeRead    = "read"
+	ScopeClients = "clients"
+	ScopeServers = "servers"
+	ScopeAdmin   = "admin"
+)
+
+// Scopes lists the valid scopes.
+var Scopes = []string{ScopeRead, ScopeClients, ScopeServers, ScopeAdmin}
+
+const (
+	maxTokens  = 50
+	maxNameLen = 64
+	// lastUsedInterval limits how often use is written to disk.
+	lastUsedInterval = time.Hour
+)
+
+var (
+	// ErrInvalid is returned by Authenticate for unknown tokens.
+	ErrInvalid = errors.New("invalid API token")
+	// ErrNotFound is returned by Revoke for unknown IDs.
+	ErrNotFound = errors.New("token not found")
+)
+
+// Token is a stored token. Hash is only set on disk, never returned.
+type Token struct {
+	ID       string     `json:"id"`
+	Name     string     `json:"name"`
+	Scopes   []string   `json:"scopes"`
+	Prefix   string     `json:"prefix"` // first characters, to recognise a token
+	Hash     string     `json:"hash,omitempty"`
+	Created  time.Time  `json:"created"`
+	LastUsed *time.Time `json:"last_used,omitempty"`
+}
+
+// Manager creates, lists, revokes and checks tokens.
+type Manager interface {
+	Create(name string, scopes []string) (string, Token, error)
+	List() ([]Token, error)
+	Revoke(id string) error
+	Authenticate(secret string) (*Token, error)
+}
+
+// ValidateName checks a token name.
+func ValidateName(name string) error {
+	if name == "" {
+		return errors.New("name is required")
+	}
+	if utf8.RuneCountInString(name) > maxNameLen {
+		return fmt.Errorf("name must be at most %d characters", maxNameLen)
+	}
+	return nil
+}
+
+// NormalizeScopes validates scopes and returns them sorted without duplicates.
+func NormalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+	out := make([]string, 0, len(scopes))
+	for _, s := range scopes {
+		if !slices.Contains(Scopes, s) {
+			return nil, fmt.Errorf("unknown scope %q (valid: %s)", s, strings.Join(Scopes, ", "))
+		}
+		if !slices.Contains(out, s) {
+			out = append(out, s)
+		}
+	}
+	slices.Sort(out)
+	return out, nil
+}
+
+// Store keeps tokens in a JSON file. Writes are atomic (temp file + rename).
+type Store struct {
+	path string
+	mu   sync.Mutex
+	now  func() time.Time
+}
+
+// NewStore creates a store backed by the file at path.
+func NewStore(path string) *Store {
+	return &Store{path: path, now: time.Now}
+}
+
+// StorePath returns the token file location inside dataDir.
+func StorePath(dataDir string) string {
+	return filepath.Join(dataDir, StoreFile)
+}
+
+// Create adds a token and returns its secret, which is not stored.
+func (s *Store) Create(name string, scopes []string) (string, Token, error) {
+	name = strings.TrimSpace(name)
+	if err := ValidateName(name); err != nil {
+		return "", Token{}, err
+	}
+	scopes, err := NormalizeScopes(scopes)
+	if err != nil {
+		return "", Token{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens, err := s.load()
+	if err != nil {
+		return "", Token{}, err
+	}
+	if len(tokens) >= maxTokens {
+		return "", Token{}, fmt.Errorf("too many tokens (max %d)", maxTokens)
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return "", Token{}, err
+	}
+	id, err := newID()
+	if err != nil {
+		return "", Token{}, err
+	}
+	t := Token{
+		ID:      id,
+		Name:    name,
+		Scopes:  scopes,
+		Prefix:  secret[:len(Prefix)+4],
+		Hash:    hashSecret(secret),
+		Created: s.now().UTC(),
+	}
+	if err := s.save(append(tokens, t)); err != nil {
+		return "", Token{}, err
+	}
+	t.Hash = ""
+	return secret, t, nil
+}
+
+// List returns all tokens without their hashes, oldest first.
+func (s *Store) List() ([]Token, error) {
+	s.mu.Lock()
+	tokens, err := s.load()
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	for i := range tokens {
+		tokens[i].Hash = ""
+	}
+	return tokens, nil
+}
+
+// Revoke deletes the token with the given ID.
+func (s *Store) Revoke(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens, err := s.load()
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(tokens, func(t Token) bool { return t.ID == id })
+	if i < 0 {
+		return ErrNotFound
+	}
+	return s.save(slices.Delete(tokens, i, i+1))
+}
+
+// Authenticate returns the token matching secret, or ErrInvalid. The last
+// use is saved at most once per hour to spare the router's flash.
+func (s *Store) Authenticate(secret string) (*Token, error) {
+	if !strings.HasPrefix(secret, Prefix) {
+		return nil, ErrInvalid
+	}
+	hash := []byte(hashSecret(secret))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	for i := range tokens {
+		t := &tokens[i]
+		if subtle.ConstantTimeCompare([]byte(t.Hash), hash) != 1 {
+			continue
+		}
+		now := s.now().UTC()
+		if t.LastUsed == nil || now.Sub(*t.LastUsed) >= lastUsedInterval {
+			t.LastUsed = &now
+			if err := s.save(tokens); err != nil {
+				return nil, err
+			}
+		}
+		found := *t
+		found.Hash = ""
+		return &found, nil
+	}
+	return nil, ErrInvalid
+}
+
+func (s *Store) load() ([]Token, error) {
+	raw, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read API tokens: %w", err)
+	}
+	var tokens []Token
+	if err := json.Unmarshal(raw, &tokens); err != nil {
+		return nil, fmt.Errorf("parse API tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+func (s *Store) save(tokens []Token) error {
+	raw, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, raw, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.path)
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate token: %w", err)
+	}
+	return Prefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func newID() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate token id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashSecret returns the hex SHA-256 of a token. Tokens carry 256 random
+// bits, so a fast hash is enough.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,141 @@
+package apitoken
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	return NewStore(filepath.Join(t.TempDir(), StoreFile))
+}
+
+func TestStore_CreateAndAuthenticate(t *testing.T) {
+	s := newTestStore(t)
+
+	secret, tok, err := s.Create(" Home Assistant ", []string{ScopeClients, ScopeRead, ScopeClients})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(secret, Prefix) || len(secret) < 40 {
+		t.Errorf("unexpected secret %q", secret)
+	}
+	if tok.Name != "Home Assistant" || tok.Hash != "" || !strings.HasPrefix(secret, tok.Prefix) {
+		t.Errorf("unexpected token: %+v", tok)
+	}
+	if strings.Join(tok.Scopes, ",") != "clients,read" {
+		t.Errorf("expected sorted, deduplicated scopes, got %v", tok.Scopes)
+	}
+
+	got, err := s.Authenticate(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != tok.ID || got.Hash != "" || got.LastUsed == nil {
+		t.Errorf("unexpected authenticated token: %+v", got)
+	}
+
+	if _, err := s.Authenticate(secret + "x"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("expected ErrInvalid for a wrong secret, got %v", err)
+	}
+	if _, err := s.Authenticate("eyJhbGciOi"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("expected ErrInvalid without prefix, got %v", err)
+	}
+}
+
+func TestStore_OnlyHashStored(t *testing.T) {
+	s := newTestStore(t)
+	secret, _, err := s.Create("script", []string{ScopeRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := os.ReadFile(s.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), secret) {
+		t.Error("secret must not be stored")
+	}
+	if !strings.Contains(string(raw), hashSecret(secret)) {
+		t.Error("expected the hash in the file")
+	}
+	if info, _ := os.Stat(s.path); info.Mode().Perm() != 0600 {
+		t.Errorf("expected 0600, got %v", info.Mode().Perm())
+	}
+}
+
+func TestStore_CreateValidation(t *testing.T) {
+	s := newTestStore(t)
+	tests := []struct {
+		name   string
+		scopes []string
+	}{
+		{"", []string{ScopeRead}},
+		{strings.Repeat("x", maxNameLen+1), []string{ScopeRead}},
+		{"ha", nil},
+		{"ha", []string{"write"}},
+	}
+	for _, tt := range tests {
+		if _, _, err := s.Create(tt.name, tt.scopes); err == nil {
+			t.Errorf("Create(%q, %v): expected error", tt.name, tt.scopes)
+		}
+	}
+}
+
+func TestStore_Revoke(t *testing.T) {
+	s := newTestStore(t)
+	secret, tok, _ := s.Create("a", []string{ScopeRead})
+	_, other, _ := s.Create("b", []string{ScopeAdmin})
+
+	if err := s.Revoke(tok.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Authenticate(secret); !errors.Is(err, ErrInvalid) {
+		t.Errorf("expected revoked token to be rejected, got %v", err)
+	}
+	if err := s.Revoke(tok.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	list, err := s.List()
+	if err != nil || len(list) != 1 || list[0].ID != other.ID || list[0].Hash != "" {
+		t.Errorf("unexpected list: %+v, %v", list, err)
+	}
+}
+
+func TestStore_LastUsedThrottled(t *testing.T) {
+	s := newTestStore(t)
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	secret, _, _ := s.Create("a", []string{ScopeRead})
+
+	first, _ := s.Authenticate(secret)
+	now = now.Add(10 * time.Minute)
+	second, _ := s.Authenticate(secret)
+	if !second.LastUsed.Equal(*first.LastUsed) {
+		t.Errorf("expected last use unchanged within the interval, got %v", second.LastUsed)
+	}
+
+	now = now.Add(lastUsedInterval)
+	third, _ := s.Authenticate(secret)
+	if !third.LastUsed.Equal(now) {
+		t.Errorf("expected last use updated, got %v", third.LastUsed)
+	}
+}
+
+func TestStore_MaxTokens(t *testing.T) {
+	s := newTestStore(t)
+	for i := 0; i < maxTokens; i++ {
+		if _, _, err := s.Create("t", []string{ScopeRead}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := s.Create("t", []string{ScopeRead}); err == nil {
+		t.Error("expected an error beyond the token limit")
+	}
+}
@@ -0,0 +1,36 @@
+package audit
+
+import (
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/service"
+)
+
+// Compile-time interface check
+var _ Log = (*Service)(nil)
+
+// Service reads and writes the audit log in the configured data directory,
+// which is looked up on every call so a data_dir change takes effect
+// without a restart.
+type Service struct {
+	stores *service.StoreCache[*Store]
+}
+
+// NewService creates a new Service.
+func NewService(config service.ConfigStore) *Service {
+	return &Service{stores: service.NewStoreCache(config, func(dataDir string) *Store {
+		return NewStore(StorePath(dataDir))
+	})}
+}
+
+func (s *Service) store() *Store {
+	return s.stores.Get()
+}
+
+// Record appends an entry and logs a failure.
+func (s *Service) Record(e Entry) {
+	s.store().Record(e)
+}
+
+// List returns matching entries, newest first.
+func (s *Service) List(f Filter) ([]Entry, error) {
+	return s.store().List(f)
+}
@@ -0,0 +1,289 @@
+// Package audit keeps a trail of configuration and control actions: who
+// did what from where, to what, and the values before and after. The bot,
+// the Web UI and scheduled jobs append to the same JSON Lines file in the
+// data directory, which is rotated by size.
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// StoreFile is the audit log inside the data directory. Rotated files
+	// get a numeric suffix (audit.jsonl.1 is the newest).
+	StoreFile = "audit.jsonl"
+
+	// maxSize is the file size that triggers a rotation.
+	maxSize = 512 << 10
+	// maxRotated is the number of rotated files kept.
+	maxRotated = 3
+	// maxValue bounds the encoded size of Before and After.
+	maxValue = 1000
+	// maxText bounds the target and error lengths.
+	maxText = 200
+)
+
+// Sources of actions.
+const (
+	SourceWeb       = "web"       // Web UI or API token
+	SourceBot       = "bot"       // Telegram bot command
+	SourceScheduler = "scheduler" // periodic job
+)
+
+// Sources lists every source.
+var Sources = []string{SourceWeb, SourceBot, SourceScheduler}
+
+// Entry is one audited action. Before and After hold the values the action
+// changed; Error is set when it failed.
+type Entry struct {
+	Time   time.Time `json:"time"`
+	Source string    `json:"source"`
+	Actor  string    `json:"actor"`
+	Action string    `json:"action"`
+	Target string    `json:"target,omitempty"`
+	Before any       `json:"before,omitempty"`
+	After  any       `json:"after,omitempty"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// Recorder accepts entries. Recording is best effort: failures are logged,
+// never returned, so they cannot break the action being recorded.
+type Recorder interface {
+	Record(e Entry)
+}
+
+// Log is the read and write side of the audit log.
+type Log interface {
+	Recorder
+	List(f Filter) ([]Entry, error)
+}
+
+// Filter selects entries for List. Zero fields match everything.
+type Filter struct {
+	Since  time.Time
+	Until  time.Time
+	Source string
+	Actor  string // case-insensitive
+	Action string // the action or its group: "clients" matches "clients.pause"
+	Target string // substring
+	Limit  int
+}
+
+func (f Filter) match(e Entry) bool {
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && e.Time.After(f.Until) {
+		return false
+	}
+	if f.Source != "" && e.Source != f.Source {
+		return false
+	}
+	if f.Actor != "" && !strings.EqualFold(e.Actor, f.Actor) {
+		return false
+	}
+	if f.Action != "" && e.Action != f.Action && !strings.HasPrefix(e.Action, f.Action+".") {
+		return false
+	}
+	if f.Target != "" && !strings.Contains(e.Target, f.Target) {
+		return false
+	}
+	return true
+}
+
+// Compile-time interface check
+var _ Log = (*Store)(nil)
+
+// Store is the append-only audit file. Each entry is one line written with
+// O_APPEND, so the bot and the Web UI can append concurrently. Once the file
+// grows past maxSize it is renamed to audit.jsonl.1, shifting older files
+// up to maxRotated; rotations are serialized between processes with a lock
+// file.
+type Store struct {
+	path    string
+	maxSize int64
+	mu      sync.Mutex
+	now     func() time.Time
+}
+
+// NewStore creates a store backed by the file at path.
+func NewStore(path string) *Store {
+	return &Store{path: path, maxSize: maxSize, now: time.Now}
+}
+
+// StorePath returns the audit log location inside dataDir.
+func StorePath(dataDir string) string {
+	return filepath.Join(dataDir, StoreFile)
+}
+
+// Append writes an entry. A zero Time is set to now.
+func (s *Store) Append(e Entry) error {
+	if e.Time.IsZero() {
+		e.Time = s.now()
+	}
+	e.Time = e.Time.UTC()
+	e.Target = truncate(e.Target, maxText)
+	e.Error = truncate(e.Error, maxText)
+	e.Before = bound(e.Before)
+	e.After = bound(e.After)
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("open audit log: %w", err)
+	}
+	_, err = f.Write(append(line, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("write audit entry: %w", err)
+	}
+
+	if info, err := os.Stat(s.path); err == nil && info.Size() > s.maxSize {
+		if err := s.rotate(); err != nil {
+			slog.Warn("Failed to rotate audit log", "path", s.path, "error", err)
+		}
+	}
+	return nil
+}
+
+// Record appends an entry and logs a failure.
+func (s *Store) Record(e Entry) {
+	if err := s.Append(e); err != nil {
+		slog.Warn("Failed to record audit entry", "action", e.Action, "error", err)
+	}
+}
+
+// List returns matching entries from the current and rotated files, newest
+// first.
+func (s *Store) List(f Filter) ([]Entry, error) {
+	all, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Entry, 0, min(len(all), max(f.Limit, 0)))
+	for i := len(all) - 1; i >= 0; i-- {
+		if !f.match(all[i]) {
+			continue
+		}
+		out = append(out, all[i])
+		if f.Limit > 0 && len(out) == f.Limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+// rotatedPath returns the path of the n-th rotated file; 0 is the current
+// one.
+func (s *Store) rotatedPath(n int) string {
+	if n == 0 {
+		return s.path
+	}
+	return s.path + "." + strconv.Itoa(n)
+}
+
+// rotate shifts the files by one, dropping the oldest. Must be called with
+// s.mu held. The size is checked again under the lock, as another process
+// may have rotated first.
+func (s *Store) rotate() error {
+	lock, err := os.OpenFile(s.path+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("lock audit log: %w", err)
+	}
+
+	info, err := os.Stat(s.path)
+	if err != nil || info.Size() <= s.maxSize {
+		return nil
+	}
+	for n := maxRotated; n > 0; n-- {
+		err := os.Rename(s.rotatedPath(n-1), s.rotatedPath(n))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// load reads all entries, oldest first. Lines that do not parse (e.g. a
+// write cut short by a power loss) are skipped.
+func (s *Store) load() ([]Entry, error) {
+	var all []Entry
+	for n := maxRotated; n >= 0; n-- {
+		raw, err := os.ReadFile(s.rotatedPath(n))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read audit log: %w", err)
+		}
+		for _, line := range bytes.Split(raw, []byte("\n")) {
+			line = bytes.TrimSpace(line)
+			if len(line) == 0 {
+				continue
+			}
+			var e Entry
+			if err := json.Unmarshal(line, &e); err != nil || e.Time.IsZero() || e.Action == "" {
+				continue
+			}
+			all = append(all, e)
+		}
+	}
+	// Writers append in time order, but clocks and processes may interleave
+	sort.SliceStable(all, func(i, j int) bool { return all[i].Time.Before(all[j].Time) })
+	return all, nil
+}
+
+// bound replaces a value whose encoding exceeds maxValue with its truncated
+// encoding, so one large change cannot bloat the log.
+func bound(v any) any {
+	if v == nil {
+		return nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	if len(raw) <= maxValue {
+		return v
+	}
+	return truncate(string(raw), maxValue)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	cut := n
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "…"
+}
@@ -0,0 +1,139 @@
+package audit
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+var base = time.Date(2026, 10, 11, 12, 0, 0, 0, time.UTC)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	s := NewStore(StorePath(t.TempDir()))
+	s.now = func() time.Time { return base }
+	return s
+}
+
+func TestStore_AppendAndList(t *testing.T) {
+	s := newTestStore(t)
+
+	s.Record(Entry{Time: base.Add(-2 * time.Hour), Source: SourceWeb, Actor: "admin", Action: "clients.pause", Target: "192.168.50.10", Before: "xray", After: "xray, paused"})
+	s.Record(Entry{Time: base.Add(-time.Hour), Source: SourceBot, Actor: "@Alice", Action: "servers.active", Target: "NL-1", After: "NL-1"})
+	s.Record(Entry{Source: SourceScheduler, Actor: "resolver", Action: "servers.resolve", Target: "NL-1", Error: "apply failed"})
+
+	all, err := s.List(Filter{})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(all))
+	}
+	if all[0].Action != "servers.resolve" || !all[0].Time.Equal(base) || all[0].Error != "apply failed" {
+		t.Errorf("expected newest first with default time, got %+v", all[0])
+	}
+	if all[2].Before != "xray" || all[2].After != "xray, paused" {
+		t.Errorf("expected before and after values kept, got %+v", all[2])
+	}
+
+	tests := []struct {
+		name string
+		f    Filter
+		want int
+	}{
+		{"source", Filter{Source: SourceBot}, 1},
+		{"actor ignores case", Filter{Actor: "@alice"}, 1},
+		{"action group", Filter{Action: "servers"}, 2},
+		{"exact action", Filter{Action: "clients.pause"}, 1},
+		{"action prefix is not a group", Filter{Action: "client"}, 0},
+		{"target substring", Filter{Target: "NL"}, 2},
+		{"since", Filter{Since: base.Add(-90 * time.Minute)}, 2},
+		{"until", Filter{Until: base.Add(-90 * time.Minute)}, 1},
+		{"limit", Filter{Limit: 2}, 2},
+	}
+	for _, tt := range tests {
+		got, err := s.List(tt.f)
+		if err != nil || len(got) != tt.want {
+			t.Errorf("%s: expected %d entries, got %d (%v)", tt.name, tt.want, len(got), err)
+		}
+	}
+}
+
+func TestStore_SkipsBadLines(t *testing.T) {
+	s := newTestStore(t)
+	s.Record(Entry{Source: SourceWeb, Actor: "admin", Action: "apply"})
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("{\"time\":\"2026-10-11T12:00:00Z\",\"act\n{}\n")
+	f.Close()
+	s.Record(Entry{Source: SourceWeb, Actor: "admin", Action: "restart"})
+
+	all, err := s.List(Filter{})
+	if err != nil || len(all) != 2 {
+		t.Errorf("expected 2 entries around bad lines, got %+v, %v", all, err)
+	}
+}
+
+func TestStore_Rotates(t *testing.T) {
+	s := newTestStore(t)
+	s.maxSize = 300
+
+	for i := 0; i < 40; i++ {
+		s.Record(Entry{Time: base.Add(time.Duration(i) * time.Second), Source: SourceWeb, Actor: "admin", Action: "apply"})
+	}
+
+	for n := 1; n <= maxRotated; n++ {
+		if _, err := os.Stat(s.rotatedPath(n)); err != nil {
+			t.Errorf("expected rotated file %d: %v", n, err)
+		}
+	}
+	if _, err := os.Stat(s.rotatedPath(maxRotated + 1)); !os.IsNotExist(err) {
+		t.Errorf("expected at most %d rotated files, got %v", maxRotated, err)
+	}
+	if info, err := os.Stat(s.path); err == nil && info.Size() > s.maxSize {
+		t.Errorf("expected the current file below the limit, got %d bytes", info.Size())
+	}
+
+	all, err := s.List(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) == 0 || len(all) >= 40 {
+		t.Fatalf("expected the oldest entries dropped, got %d", len(all))
+	}
+	if !all[0].Time.Equal(base.Add(39 * time.Second)) {
+		t.Errorf("expected the newest entry first, got %v", all[0].Time)
+	}
+	for i := 1; i < len(all); i++ {
+		if all[i].Time.After(all[i-1].Time) {
+			t.Fatalf("expected entries across files newest first, got %v after %v", all[i].Time, all[i-1].Time)
+		}
+	}
+}
+
+func TestStore_BoundsValues(t *testing.T) {
+	s := newTestStore(t)
+	big := make([]string, 500)
+	for i := range big {
+		big[i] = "10.0.0.1"
+	}
+	s.Record(Entry{Source: SourceWeb, Actor: "admin", Action: "excludes.sets", Target: strings.Repeat("x", 500), Before: big, After: []string{"ru"}})
+
+	all, err := s.List(Filter{})
+	if err != nil || len(all) != 1 {
+		t.Fatalf("unexpected entries %+v, %v", all, err)
+	}
+	before, ok := all[0].Before.(string)
+	if !ok || len(before) > maxValue+len("…") || !strings.HasSuffix(before, "…") {
+		t.Errorf("expected a truncated before value, got %T %v", all[0].Before, all[0].Before)
+	}
+	if after, ok := all[0].After.([]any); !ok || len(after) != 1 || after[0] != "ru" {
+		t.Errorf("expected small values kept, got %#v", all[0].After)
+	}
+	if len(all[0].Target) > maxText+len("…") {
+		t.Errorf("expected a truncated target, got %d bytes", len(all[0].Target))
+	}
+}
@@ -0,0 +1,17 @@
+package auth
+
+import "slices"
+
+// Password backends for Web UI logins, chosen with webui.auth_backends.
+const (
+	BackendLocal  = "local"  // Web UI accounts in the data dir
+	BackendShadow = "shadow" // the router's /etc/shadow
+)
+
+// Backends lists the valid backends, in the order tried by default.
+var Backends = []string{BackendLocal, BackendShadow}
+
+// ValidBackend reports whether backend is one of Backends.
+func ValidBackend(backend string) bool {
+	return slices.Contains(Backends, backend)
+}
@@ -9,7 +9,9 @@ import (
 
 // Claims holds the decoded JWT claims returned by Validate.
 type Claims struct {
+	ID        string // session ID (jti); empty for tokens issued before sessions were tracked
 	Subject   string
+	Role      string // empty for tokens issued before roles existed
 	IssuedAt  int64
 	ExpiresAt int64
 }
@@ -29,14 +31,25 @@ func NewJWTService(secret string, duration time.Duration) *JWTService {
 	}
 }
 
-// Create returns a signed JWT with sub, iat, and exp claims.
-func (s *JWTService) Create(subject string) (string, error) {
+// Duration returns how long created tokens are valid.
+func (s *JWTService) Duration() time.Duration {
+	return s.duration
+}
+
+// Create returns a signed JWT with sub, role, iat, and exp claims, and a jti
+// claim naming the server-side session when id is not empty.
+func (s *JWTService) Create(subject, role, id string) (string, error) {
 	now := time.Now()
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub": subject,
-		"iat": jwt.NewNumericDate(now),
-		"exp": jwt.NewNumericDate(now.Add(s.duration)),
-	})
+	claims := jwt.MapClaims{
+		"sub":  subject,
+		"role": role,
+		"iat":  jwt.NewNumericDate(now),
+		"exp":  jwt.NewNumericDate(now.Add(s.duration)),
+	}
+	if id != "" {
+		claims["jti"] = id
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	signed, err := token.SignedString(s.secret)
 	if err != nil {
 		return "", fmt.Errorf("sign token: %w", err)
@@ -77,8 +90,13 @@ func (s *JWTService) Validate(tokenString string) (*Claims, error) {
 		return nil, fmt.Errorf("get expiration: %w", err)
 	}
 
+	role, _ := mapClaims["role"].(string)
+	id, _ := mapClaims["jti"].(string)
+
 	return &Claims{
+		ID:        id,
 		Subject:   sub,
+		Role:      role,
 		IssuedAt:  iat.Unix(),
 		ExpiresAt: exp.Unix(),
 	}, nil
@@ -8,7 +8,7 @@ import (
 func TestJWT_CreateAndValidate(t *testing.T) {
 	svc := NewJWTService("test-secret-key", time.Hour)
 
-	token, err := svc.Create("admin")
+	token, err := svc.Create("admin", RoleAdmin, "")
 	if err != nil {
 		t.Fatalf("Create: unexpected error: %v", err)
 	}
@@ -23,6 +23,9 @@ func TestJWT_CreateAndValidate(t *testing.T) {
 	if claims.Subject != "admin" {
 		t.Errorf("expected subject %q, got %q", "admin", claims.Subject)
 	}
+	if claims.Role != RoleAdmin {
+		t.Errorf("expected role %q, got %q", RoleAdmin, claims.Role)
+	}
 	if claims.IssuedAt <= 0 {
 		t.Errorf("expected positive IssuedAt, got %d", claims.IssuedAt)
 	}
@@ -31,10 +34,29 @@ func TestJWT_CreateAndValidate(t *testing.T) {
 	}
 }
 
+func TestJWT_SessionID(t *testing.T) {
+	svc := NewJWTService("test-secret-key", time.Hour)
+
+	token, err := svc.Create("admin", RoleAdmin, "SESSION1")
+	if err != nil {
+		t.Fatalf("Create: unexpected error: %v", err)
+	}
+	claims, err := svc.Validate(token)
+	if err != nil {
+		t.Fatalf("Validate: unexpected error: %v", err)
+	}
+	if claims.ID != "SESSION1" {
+		t.Errorf("expected jti %q, got %q", "SESSION1", claims.ID)
+	}
+	if svc.Duration() != time.Hour {
+		t.Errorf("expected duration 1h, got %v", svc.Duration())
+	}
+}
+
 func TestJWT_ExpiredToken(t *testing.T) {
 	svc := NewJWTService("test-secret-key", -time.Hour)
 
-	token, err := svc.Create("admin")
+	token, err := svc.Create("admin", RoleAdmin, "")
 	if err != nil {
 		t.Fatalf("Create: unexpected error: %v", err)
 	}
@@ -49,7 +71,7 @@ func TestJWT_WrongSecret(t *testing.T) {
 	creator := NewJWTService("secret-one", time.Hour)
 	validator := NewJWTService("secret-two", time.Hour)
 
-	token, err := creator.Create("admin")
+	token, err := creator.Create("admin", RoleAdmin, "")
 	if err != nil {
 		t.Fatalf("Create: unexpected error: %v", err)
 	}
@@ -77,3 +99,16 @@ func TestJWT_EmptyToken(t *testing.T) {
 		t.Fatal("Validate: expected error for empty token")
 	}
 }
+
+func TestValidRole(t *testing.T) {
+	for _, role := range Roles {
+		if !ValidRole(role) {
+			t.Errorf("expected %q to be valid", role)
+		}
+	}
+	for _, role := range []string{"", "root", "Admin"} {
+		if ValidRole(role) {
+			t.Errorf("expected %q to be invalid", role)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package auth
+
+import "slices"
+
+// Web UI roles, carried in the JWT "role" claim.
+const (
+	RoleAdmin    = "admin"    // full control
+	RoleOperator = "operator" // view, pause and resume clients
+	RoleViewer   = "viewer"   // view only
+)
+
+// Roles lists the valid roles.
+var Roles = []string{RoleAdmin, RoleOperator, RoleViewer}
+
+// ValidRole reports whether role is one of Roles.
+func ValidRole(role string) bool {
+	return slices.Contains(Roles, role)
+}
@@ -0,0 +1,87 @@
+package banlist
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// DefaultAnnounceInterval is how often the announcer looks for new bans.
+const DefaultAnnounceInterval = 30 * time.Second
+
+// Notifier delivers messages to bot users.
+type Notifier interface {
+	Broadcast(ctx context.Context, text string)
+}
+
+// Announcer tells bot users about new bans, whichever process set them.
+type Announcer struct {
+	bans     Manager
+	notifier Notifier
+}
+
+// NewAnnouncer creates an announcer for the bans in bans.
+func NewAnnouncer(bans Manager, notifier Notifier) *Announcer {
+	return &Announcer{bans: bans, notifier: notifier}
+}
+
+// Run checks now and then every interval. Blocks until ctx is cancelled.
+func (a *Announcer) Run(ctx context.Context, interval time.Duration) {
+	slog.Info("Ban announcer started", "interval", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	a.check(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.check(ctx)
+		}
+	}
+}
+
+func (a *Announcer) check(ctx context.Context) {
+	bans, err := a.bans.Unannounced()
+	if err != nil {
+		slog.Warn("Failed to check ban list", "error", err)
+		return
+	}
+	for _, e := range bans {
+		a.notifier.Broadcast(ctx, Message(e))
+	}
+}
+
+// Message describes a new ban for bot users.
+func Message(e Entry) string {
+	where := "Web UI login"
+	if e.Kind == KindTelegram {
+		where = "Telegram bot"
+	}
+	text := fmt.Sprintf("🚫 Blocked %s (%s) for %s after %d failed attempts", e.Source, where, FormatDuration(e.Duration()), maxFailures)
+	if e.Reason != "" {
+		text += ": " + e.Reason
+	}
+	return text + ".\nLift it with /unban " + e.Source
+}
+
+// FormatDuration writes a ban length in whole days, hours or minutes.
+func FormatDuration(d time.Duration) string {
+	unit := func(n int, name string) string {
+		if n == 1 {
+			return "1 " + name
+		}
+		return fmt.Sprintf("%d %ss", n, name)
+	}
+	switch {
+	case d >= 24*time.Hour && d%(24*time.Hour) == 0:
+		return unit(int(d/(24*time.Hour)), "day")
+	case d >= time.Hour && d%time.Hour == 0:
+		return unit(int(d/time.Hour), "hour")
+	default:
+		return unit(max(int(d.Round(time.Minute)/time.Minute), 1), "minute")
+	}
+}
@@ -0,0 +1,67 @@
+package banlist
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mockNotifier struct {
+	messages []string
+}
+
+func (m *mockNotifier) Broadcast(_ context.Context, text string) {
+	m.messages = append(m.messages, text)
+}
+
+func TestAnnouncer_Check(t *testing.T) {
+	s, _ := newTestStore(t)
+	n := &mockNotifier{}
+	a := NewAnnouncer(s, n)
+
+	a.check(context.Background())
+	if len(n.messages) != 0 {
+		t.Fatalf("expected no messages, got %v", n.messages)
+	}
+
+	failTimes(t, s, maxFailures)
+	a.check(context.Background())
+	a.check(context.Background())
+	if len(n.messages) != 1 {
+		t.Fatalf("expected one message, got %v", n.messages)
+	}
+	for _, want := range []string{"192.168.50.23", "Web UI", "15 minutes", "bad credentials", "/unban 192.168.50.23"} {
+		if !strings.Contains(n.messages[0], want) {
+			t.Errorf("expected %q in %q", want, n.messages[0])
+		}
+	}
+}
+
+func TestMessage_Telegram(t *testing.T) {
+	msg := Message(Entry{Kind: KindTelegram, Source: "@stranger", Bans: 2, Reason: "unauthorized message"})
+	if !strings.Contains(msg, "@stranger (Telegram bot) for 1 hour") {
+		t.Errorf("unexpected message %q", msg)
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{15 * time.Minute, "15 minutes"},
+		{time.Minute, "1 minute"},
+		{10 * time.Second, "1 minute"},
+		{time.Hour, "1 hour"},
+		{6 * time.Hour, "6 hours"},
+		{24 * time.Hour, "1 day"},
+		{7 * 24 * time.Hour, "7 days"},
+		{90 * time.Minute, "90 minutes"},
+	}
+	for _, tt := range tests {
+		if got := FormatDuration(tt.d); got != tt.want {
+			t.Errorf("FormatDuration(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package banlist
+
+import (
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/service"
+)
+
+// Compile-time interface check
+var _ Manager = (*Service)(nil)
+
+// Service manages the ban list in the configured data directory, which is
+// looked up on every call so a data_dir change takes effect without a
+// restart.
+type Service struct {
+	stores *service.StoreCache[*Store]
+}
+
+// NewService creates a new Service.
+func NewService(config service.ConfigStore) *Service {
+	return &Service{stores: service.NewStoreCache(config, func(dataDir string) *Store {
+		return NewStore(StorePath(dataDir))
+	})}
+}
+
+func (s *Service) store() *Store {
+	return s.stores.Get()
+}
+
+// Banned reports whether the source is banned and until when.
+func (s *Service) Banned(kind, source string) (time.Time, bool) {
+	return s.store().Banned(kind, source)
+}
+
+// Fail records a failed attempt by the source.
+func (s *Service) Fail(kind, source, reason string) (Entry, error) {
+	return s.store().Fail(kind, source, reason)
+}
+
+// Reset forgets the source's failed attempts.
+func (s *Service) Reset(kind, source string) error {
+	return s.store().Reset(kind, source)
+}
+
+// List returns the active bans.
+func (s *Service) List() ([]Entry, error) {
+	return s.store().List()
+}
+
+// Clear lifts one ban.
+func (s *Service) Clear(id string) error {
+	return s.store().Clear(id)
+}
+
+// ClearAll lifts every ban.
+func (s *Service) ClearAll() (int, error) {
+	return s.store().ClearAll()
+}
+
+// Unannounced returns the bans not yet announced and marks them announced.
+func (s *Service) Unannounced() ([]Entry, error) {
+	return s.store().Unannounced()
+}
@@ -0,0 +1,340 @@
+// Package banlist keeps the sources blocked after repeated failed logins:
+// client IPs of the Web UI and Telegram users the bot does not know. The
+// list is a file in the data directory shared by the bot and the Web UI, so
+// a ban set by one process is honoured by the other and survives restarts.
+package banlist
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// StoreFile is the ban list file inside the data directory.
+const StoreFile = "bans.json"
+
+// Kinds of banned sources.
+const (
+	KindIP       = "ip"       // Web UI client address
+	KindTelegram = "telegram" // Telegram "@username", or "id:<user ID>" without one
+)
+
+const (
+	// maxFailures within failureWindow trigger a ban.
+	maxFailures   = 5
+	failureWindow = 15 * time.Minute
+	// forgetAfter drops sources without failures for this long, which also
+	// resets their escalation.
+	forgetAfter = 7 * 24 * time.Hour
+	// maxEntries bounds the list; sources not currently banned and seen
+	// least recently are dropped first.
+	maxEntries = 1000
+	// maxReason bounds the stored description of the last failure.
+	maxReason = 100
+)
+
+// banDurations are the lengths of consecutive bans of one source; the last
+// one repeats.
+var banDurations = []time.Duration{
+	15 * time.Minute,
+	time.Hour,
+	6 * time.Hour,
+	24 * time.Hour,
+	7 * 24 * time.Hour,
+}
+
+// ErrNotFound is returned when clearing a source that is not banned.
+var ErrNotFound = errors.New("ban not found")
+
+// Entry tracks the failures and bans of one source.
+type Entry struct {
+	ID          string    `json:"id"` // Kind:Source
+	Kind        string    `json:"kind"`
+	Source      string    `json:"source"`
+	Failures    int       `json:"failures"` // within the current window
+	WindowStart time.Time `json:"window_start"`
+	LastFailure time.Time `json:"last_failure"`
+	Reason      string    `json:"reason"` // last failure
+	Bans        int       `json:"bans"`   // bans so far; picks the next duration
+	BannedUntil time.Time `json:"banned_until"`
+	Notified    bool      `json:"notified"` // current ban announced to bot users
+}
+
+// ID returns the entry ID for a source.
+func ID(kind, source string) string {
+	return kind + ":" + source
+}
+
+// Active reports whether the source is banned at now.
+func (e Entry) Active(now time.Time) bool {
+	return now.Before(e.BannedUntil)
+}
+
+// Duration returns the length of the current or last ban.
+func (e Entry) Duration() time.Duration {
+	return banDuration(e.Bans - 1)
+}
+
+func banDuration(n int) time.Duration {
+	return banDurations[min(max(n, 0), len(banDurations)-1)]
+}
+
+// Manager records failures and reports, lists and clears bans.
+type Manager interface {
+	Banned(kind, source string) (time.Time, bool)
+	Fail(kind, source, reason string) (Entry, error)
+	Reset(kind, source string) error
+	List() ([]Entry, error)
+	Clear(id string) error
+	ClearAll() (int, error)
+	Unannounced() ([]Entry, error)
+}
+
+// Store keeps the list in a JSON file. Writes are atomic (temp file +
+// rename) and serialized between processes with a lock file next to it.
+type Store struct {
+	path string
+	mu   sync.Mutex
+	now  func() time.Time
+}
+
+// NewStore creates a store backed by the file at path.
+func NewStore(path string) *Store {
+	return &Store{path: path, now: time.Now}
+}
+
+// StorePath returns the ban list location inside dataDir.
+func StorePath(dataDir string) string {
+	return filepath.Join(dataDir, StoreFile)
+}
+
+// Banned reports whether the source is banned and until when. A list that
+// cannot be read is logged and treated as empty, so a broken file never
+// locks everyone out.
+func (s *Store) Banned(kind, source string) (time.Time, bool) {
+	entries, err := s.load()
+	if err != nil {
+		slog.Warn("Failed to read ban list", "error", err)
+		return time.Time{}, false
+	}
+	i := slices.IndexFunc(entries, func(e Entry) bool { return e.ID == ID(kind, source) })
+	if i < 0 || !entries[i].Active(s.now()) {
+		return time.Time{}, false
+	}
+	return entries[i].BannedUntil, true
+}
+
+// Fail records a failed attempt by the source and bans it once it has
+// maxFailures within failureWindow. Each ban of the same source lasts
+// longer. Failures while banned are not counted. Returns the updated entry.
+func (s *Store) Fail(kind, source, reason string) (Entry, error) {
+	var entry Entry
+	err := s.update(func(entries []Entry) ([]Entry, bool) {
+		now := s.now().UTC()
+		id := ID(kind, source)
+		i := slices.IndexFunc(entries, func(e Entry) bool { return e.ID == id })
+		if i < 0 {
+			entries = append(entries, Entry{ID: id, Kind: kind, Source: source})
+			i = len(entries) - 1
+		}
+
+		e := &entries[i]
+		e.LastFailure = now
+		e.Reason = truncate(reason)
+		if !e.Active(now) {
+			if now.Sub(e.WindowStart) > failureWindow {
+				e.Failures, e.WindowStart = 0, now
+			}
+			e.Failures++
+			if e.Failures >= maxFailures {
+				e.BannedUntil = now.Add(banDuration(e.Bans))
+				e.Bans++
+				e.Failures = 0
+				e.Notified = false
+				slog.Warn("Source banned", "kind", kind, "source", source, "until", e.BannedUntil, "reason", e.Reason)
+			}
+		}
+		entry = *e
+		return entries, true
+	})
+	return entry, err
+}
+
+// Reset forgets the source's failed attempts after it logged in. Earlier
+// bans still count towards the next one.
+func (s *Store) Reset(kind, source string) error {
+	return s.update(func(entries []Entry) ([]Entry, bool) {
+		i := slices.IndexFunc(entries, func(e Entry) bool { return e.ID == ID(kind, source) })
+		if i < 0 || entries[i].Failures == 0 {
+			return entries, false
+		}
+		entries[i].Failures = 0
+		return entries, true
+	})
+}
+
+// List returns the active bans, the latest first.
+func (s *Store) List() ([]Entry, error) {
+	entries, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	bans := []Entry{}
+	for _, e := range entries {
+		if e.Active(now) {
+			bans = append(bans, e)
+		}
+	}
+	slices.SortFunc(bans, func(a, b Entry) int {
+		return cmp.Or(b.BannedUntil.Compare(a.BannedUntil), cmp.Compare(a.ID, b.ID))
+	})
+	return bans, nil
+}
+
+// Clear lifts the ban with the given ID and forgets the source's history.
+func (s *Store) Clear(id string) error {
+	found := false
+	err := s.update(func(entries []Entry) ([]Entry, bool) {
+		now := s.now()
+		i := slices.IndexFunc(entries, func(e Entry) bool { return e.ID == id && e.Active(now) })
+		if i < 0 {
+			return entries, false
+		}
+		found = true
+		return slices.Delete(entries, i, i+1), true
+	})
+	if err == nil && !found {
+		return ErrNotFound
+	}
+	return err
+}
+
+// ClearAll lifts every ban, forgets all failures and returns how many bans
+// were lifted.
+func (s *Store) ClearAll() (int, error) {
+	n := 0
+	err := s.update(func(entries []Entry) ([]Entry, bool) {
+		now := s.now()
+		for _, e := range entries {
+			if e.Active(now) {
+				n++
+			}
+		}
+		return nil, len(entries) > 0
+	})
+	return n, err
+}
+
+// Unannounced returns the active bans not yet announced to bot users and
+// marks them announced.
+func (s *Store) Unannounced() ([]Entry, error) {
+	var fresh []Entry
+	err := s.update(func(entries []Entry) ([]Entry, bool) {
+		now := s.now()
+		for i := range entries {
+			if entries[i].Active(now) && !entries[i].Notified {
+				entries[i].Notified = true
+				fresh = append(fresh, entries[i])
+			}
+		}
+		return entries, len(fresh) > 0
+	})
+	return fresh, err
+}
+
+// update runs fn on the list under the in-process and cross-process locks
+// and saves the result when fn reports a change.
+func (s *Store) update(fn func([]Entry) ([]Entry, bool)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	lock, err := os.OpenFile(s.path+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("lock ban list: %w", err)
+	}
+
+	entries, err := s.load()
+	if err != nil {
+		return err
+	}
+	entries, changed := fn(entries)
+	if !changed {
+		return nil
+	}
+	return s.save(s.prune(entries))
+}
+
+// prune drops forgotten sources and bounds the list to maxEntries.
+func (s *Store) prune(entries []Entry) []Entry {
+	now := s.now()
+	entries = slices.DeleteFunc(entries, func(e Entry) bool {
+		return !e.Active(now) && now.Sub(e.LastFailure) > forgetAfter
+	})
+	if len(entries) > maxEntries {
+		slices.SortFunc(entries, func(a, b Entry) int {
+			if a.Active(now) != b.Active(now) {
+				if a.Active(now) {
+					return -1
+				}
+				return 1
+			}
+			return b.LastFailure.Compare(a.LastFailure)
+		})
+		entries = entries[:maxEntries]
+	}
+	return entries
+}
+
+// load reads the list; a missing file is an empty list.
+func (s *Store) load() ([]Entry, error) {
+	raw, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read ban list: %w", err)
+	}
+	var entries []Entry
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil, fmt.Errorf("parse ban list: %w", err)
+	}
+	return entries, nil
+}
+
+func (s *Store) save(entries []Entry) error {
+	if entries == nil {
+		entries = []Entry{}
+	}
+	raw, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, raw, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.path)
+}
+
+func truncate(reason string) string {
+	if len(reason) > maxReason {
+		return reason[:maxReason]
+	}
+	return reason
+}
@@ -0,0 +1,211 @@
+package banlist
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestStore returns a store with a settable clock.
+func newTestStore(t *testing.T) (*Store, *time.Time) {
+	t.Helper()
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	s := NewStore(StorePath(t.TempDir()))
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+func failTimes(t *testing.T, s *Store, n int) Entry {
+	t.Helper()
+	var e Entry
+	for range n {
+		var err error
+		if e, err = s.Fail(KindIP, "192.168.50.23", "bad credentials"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return e
+}
+
+func TestStore_BansAfterMaxFailures(t *testing.T) {
+	s, now := newTestStore(t)
+
+	e := failTimes(t, s, maxFailures-1)
+	if e.Active(*now) || e.Failures != maxFailures-1 {
+		t.Fatalf("expected no ban yet, got %+v", e)
+	}
+	if _, banned := s.Banned(KindIP, "192.168.50.23"); banned {
+		t.Fatal("expected no ban yet")
+	}
+
+	e = failTimes(t, s, 1)
+	until, banned := s.Banned(KindIP, "192.168.50.23")
+	if !banned || !until.Equal(now.Add(15*time.Minute)) || e.Bans != 1 {
+		t.Fatalf("expected a 15-minute ban, got %v, %v, %+v", until, banned, e)
+	}
+	if _, banned := s.Banned(KindTelegram, "192.168.50.23"); banned {
+		t.Error("expected bans to be per kind")
+	}
+
+	// Failures while banned neither count nor extend the ban
+	e = failTimes(t, s, maxFailures)
+	if e.Failures != 0 || e.Bans != 1 {
+		t.Errorf("expected failures during a ban to be ignored, got %+v", e)
+	}
+
+	*now = now.Add(16 * time.Minute)
+	if _, banned := s.Banned(KindIP, "192.168.50.23"); banned {
+		t.Error("expected the ban to expire")
+	}
+}
+
+func TestStore_Escalates(t *testing.T) {
+	s, now := newTestStore(t)
+
+	want := []time.Duration{15 * time.Minute, time.Hour, 6 * time.Hour, 24 * time.Hour, 7 * 24 * time.Hour, 7 * 24 * time.Hour}
+	for i, d := range want {
+		e := failTimes(t, s, maxFailures)
+		if e.Duration() != d || !e.BannedUntil.Equal(now.Add(d)) {
+			t.Fatalf("ban %d: expected %v, got %v until %v", i+1, d, e.Duration(), e.BannedUntil)
+		}
+		*now = e.BannedUntil
+	}
+}
+
+func TestStore_WindowExpires(t *testing.T) {
+	s, now := newTestStore(t)
+
+	failTimes(t, s, maxFailures-1)
+	*now = now.Add(failureWindow + time.Second)
+	if e := failTimes(t, s, 1); e.Failures != 1 || e.Bans != 0 {
+		t.Errorf("expected a new window, got %+v", e)
+	}
+}
+
+func TestStore_Reset(t *testing.T) {
+	s, _ := newTestStore(t)
+
+	failTimes(t, s, maxFailures-1)
+	if err := s.Reset(KindIP, "192.168.50.23"); err != nil {
+		t.Fatal(err)
+	}
+	if e := failTimes(t, s, 1); e.Failures != 1 {
+		t.Errorf("expected failures to be forgotten, got %+v", e)
+	}
+
+	// Nothing to reset: the file is not rewritten
+	info, _ := os.Stat(s.path)
+	if err := s.Reset(KindIP, "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := os.Stat(s.path); !after.ModTime().Equal(info.ModTime()) {
+		t.Error("expected no write")
+	}
+}
+
+func TestStore_ForgetsOldSources(t *testing.T) {
+	s, now := newTestStore(t)
+
+	failTimes(t, s, maxFailures)
+	*now = now.Add(forgetAfter + time.Hour)
+	if _, err := s.Fail(KindIP, "10.0.0.1", "bad credentials"); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := s.load()
+	if err != nil || len(entries) != 1 || entries[0].Source != "10.0.0.1" {
+		t.Errorf("expected the old source to be forgotten, got %+v, %v", entries, err)
+	}
+}
+
+func TestStore_ListAndClear(t *testing.T) {
+	s, _ := newTestStore(t)
+
+	failTimes(t, s, maxFailures)
+	for range maxFailures {
+		if _, err := s.Fail(KindTelegram, "@stranger", "unauthorized message"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.Fail(KindIP, "10.0.0.1", "bad credentials"); err != nil {
+		t.Fatal(err)
+	}
+
+	bans, err := s.List()
+	if err != nil || len(bans) != 2 {
+		t.Fatalf("expected 2 bans, got %+v, %v", bans, err)
+	}
+
+	if err := s.Clear(ID(KindTelegram, "@stranger")); err != nil {
+		t.Fatal(err)
+	}
+	if _, banned := s.Banned(KindTelegram, "@stranger"); banned {
+		t.Error("expected the ban to be lifted")
+	}
+	if err := s.Clear(ID(KindTelegram, "@stranger")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if err := s.Clear(ID(KindIP, "10.0.0.1")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a source that is not banned, got %v", err)
+	}
+
+	n, err := s.ClearAll()
+	if err != nil || n != 1 {
+		t.Errorf("expected 1 ban lifted, got %d, %v", n, err)
+	}
+	if bans, _ := s.List(); len(bans) != 0 {
+		t.Errorf("expected no bans, got %+v", bans)
+	}
+}
+
+func TestStore_Unannounced(t *testing.T) {
+	s, now := newTestStore(t)
+
+	failTimes(t, s, maxFailures)
+	fresh, err := s.Unannounced()
+	if err != nil || len(fresh) != 1 || fresh[0].Source != "192.168.50.23" {
+		t.Fatalf("expected the new ban, got %+v, %v", fresh, err)
+	}
+	if fresh, _ := s.Unannounced(); len(fresh) != 0 {
+		t.Errorf("expected the ban to be announced once, got %+v", fresh)
+	}
+
+	// The next ban is announced again
+	*now = now.Add(time.Hour)
+	failTimes(t, s, maxFailures)
+	if fresh, _ := s.Unannounced(); len(fresh) != 1 {
+		t.Errorf("expected the second ban, got %+v", fresh)
+	}
+}
+
+func TestStore_SharedBetweenStores(t *testing.T) {
+	s, _ := newTestStore(t)
+	other := NewStore(s.path)
+	other.now = s.now
+
+	failTimes(t, s, maxFailures-1)
+	if _, err := other.Fail(KindIP, "192.168.50.23", "bad TOTP code"); err != nil {
+		t.Fatal(err)
+	}
+	if _, banned := s.Banned(KindIP, "192.168.50.23"); !banned {
+		t.Error("expected failures from both stores to add up")
+	}
+}
+
+func TestStore_BrokenFile(t *testing.T) {
+	s, _ := newTestStore(t)
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(s.path, []byte("not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, banned := s.Banned(KindIP, "192.168.50.23"); banned {
+		t.Error("expected a broken list not to ban anyone")
+	}
+	if _, err := s.Fail(KindIP, "192.168.50.23", "bad credentials"); err == nil {
+		t.Error("expected an error")
+	}
+}
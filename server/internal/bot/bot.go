@@ -8,15 +8,25 @@ import (
 	"log/slog"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/accesslog"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/audit"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/banlist"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/chatstore"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/config"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/conntrack"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/diagnostics"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/events"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/handler"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/loginapproval"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/paths"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/service"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/speedtest"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/startup"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/telegram"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/traffic"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/updater"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/wizard"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/xraystats"
 )
 
 // Bot is the main Telegram bot struct with DI
@@ -29,6 +39,11 @@ type Bot struct {
 	executor  service.ShellExecutor
 	updater   updater.Updater
 	chatStore *chatstore.Store
+	access    *accesslog.Index
+	events    *events.Service
+	bans      banlist.Manager
+	audit     *audit.Service
+	login     *handler.LoginHandler
 }
 
 // Option configures the Bot.
@@ -83,6 +98,7 @@ func New(cfg *config.Config, p paths.Paths, version, versionFull, commit, buildD
 		api:    api,
 		auth:   NewAuth(cfg.AllowedUsers),
 		sender: sender,
+		access: accesslog.NewIndex(accesslog.DefaultCapacity),
 	}
 
 	// Apply options
@@ -92,10 +108,19 @@ func New(cfg *config.Config, p paths.Paths, version, versionFull, commit, buildD
 
 	// Create services (executor may be set by WithDevMode option)
 	configSvc := service.NewConfigService(p.ScriptsDir, p.DefaultDataDir)
-	vpnSvc := service.NewVPNDirectorService(p.ScriptsDir, b.executor)
+	b.events = events.NewService(configSvc)
+	b.bans = banlist.NewService(configSvc)
+	b.audit = audit.NewService(configSvc)
+	vpnSvc := events.NewVPNDirector(service.NewVPNDirectorService(p.ScriptsDir, b.executor), b.events, "bot")
 	xraySvc := service.NewXrayService(p.XrayTemplate, p.XrayConfig)
-	networkSvc := service.NewNetworkService(b.executor)
+	networkSvc := service.NewNetworkService(configSvc)
 	logSvc := service.NewLogService(b.executor)
+	domainSvc := service.NewDomainService(p.ScriptsDir, b.executor)
+	trafficSvc := traffic.NewService(configSvc)
+	xrayStats := xraystats.NewClient(xraystats.DefaultAddr)
+	connSvc := conntrack.NewService(configSvc, b.executor, p.ConntrackPath)
+	diagSvc := diagnostics.NewService(configSvc, b.executor, networkSvc)
+	speedSvc := speedtest.NewService(configSvc)
 
 	// Create handler dependencies
 	deps := &handler.Deps{
@@ -103,8 +128,20 @@ func New(cfg *config.Config, p paths.Paths, version, versionFull, commit, buildD
 		Config:      configSvc,
 		VPN:         vpnSvc,
 		Xray:        xraySvc,
+		XrayConfig:  xraySvc,
 		Network:     networkSvc,
+		System:      service.NewSystemService(b.executor),
 		Logs:        logSvc,
+		Domains:     domainSvc,
+		Traffic:     trafficSvc,
+		XrayStats:   xrayStats,
+		Access:      b.access,
+		Connections: connSvc,
+		Diagnostics: diagSvc,
+		SpeedTest:   speedSvc,
+		Events:      b.events,
+		Bans:        b.bans,
+		Audit:       b.audit,
 		Paths:       p,
 		Version:     version,
 		VersionFull: versionFull,
@@ -119,14 +156,28 @@ func New(cfg *config.Config, p paths.Paths, version, versionFull, commit, buildD
 	serversHandler := handler.NewServersHandler(deps)
 	importHandler := handler.NewImportHandler(deps)
 	miscHandler := handler.NewMiscHandler(deps)
-	updateHandler := handler.NewUpdateHandler(sender, b.updater, b.devMode, version)
-	wizardHandler := wizard.NewHandler(sender, configSvc, vpnSvc, xraySvc)
+	updateHandler := handler.NewUpdateHandler(sender, b.updater, b.devMode, version, b.audit)
+	wizardHandler := wizard.NewHandler(sender, configSvc, vpnSvc, xraySvc, b.audit)
 	xrayHandler := handler.NewXrayHandler(deps)
 	excludeHandler := handler.NewExcludeHandler(deps)
 	clientsHandler := handler.NewClientsHandler(deps)
+	domainsHandler := handler.NewDomainsHandler(deps)
+	optimizeHandler := handler.NewOptimizeHandler(deps)
+	trafficHandler := handler.NewTrafficHandler(deps)
+	accessHandler := handler.NewAccessHandler(deps)
+	checkHandler := handler.NewCheckHandler(deps)
+	speedTestHandler := handler.NewSpeedTestHandler(deps)
+	bansHandler := handler.NewBansHandler(deps)
+	auditHandler := handler.NewAuditHandler(deps)
+	// Only users who have talked to the bot can be asked (no chat store in dev mode)
+	var loginChats handler.LoginChats
+	if b.chatStore != nil {
+		loginChats = b.chatStore
+	}
+	b.login = handler.NewLoginHandler(sender, loginChats, b.auth)
 
 	// Create router
-	router := NewRouter(statusHandler, serversHandler, importHandler, miscHandler, updateHandler, wizardHandler, xrayHandler, excludeHandler, clientsHandler)
+	router := NewRouter(statusHandler, serversHandler, importHandler, miscHandler, updateHandler, wizardHandler, xrayHandler, excludeHandler, clientsHandler, domainsHandler, optimizeHandler, trafficHandler, accessHandler, checkHandler, speedTestHandler, bansHandler, auditHandler, b.login)
 	b.router = router
 
 	return b, nil
@@ -142,6 +193,15 @@ func (b *Bot) RegisterCommands() error {
 		{Command: "configure", Description: "Configuration wizard"},
 		{Command: "exclude", Description: "Manage excluded IPs"},
 		{Command: "clients", Description: "Manage VPN clients"},
+		{Command: "domains", Description: "Domain-based routing"},
+		{Command: "optimize", Description: "Merge excluded IP ranges"},
+		{Command: "traffic", Description: "Traffic per client"},
+		{Command: "access", Description: "Top destinations per client"},
+		{Command: "check", Description: "Verify routes and DNS"},
+		{Command: "speedtest", Description: "Measure throughput of a route"},
+		{Command: "bans", Description: "Blocked login attempts"},
+		{Command: "unban", Description: "Lift a login ban"},
+		{Command: "audit", Description: "Recent configuration changes"},
 		{Command: "restart", Description: "Restart VPN Director"},
 		{Command: "stop", Description: "Stop VPN Director"},
 		{Command: "logs", Description: "Recent logs"},
@@ -191,8 +251,7 @@ func (b *Bot) Run(ctx context.Context) {
 				}
 				username := msg.From.UserName
 				if !b.auth.IsAuthorized(username) {
-					slog.Warn("Unauthorized access attempt", "username", username)
-					b.sender.SendPlain(msg.Chat.ID, "Access denied")
+					b.deny(msg.Chat.ID, msg.From)
 					continue
 				}
 				// Record interaction for update notifications
@@ -227,6 +286,22 @@ func (b *Bot) Run(ctx context.Context) {
 	}
 }
 
+// deny answers a message from a user not in allowed_users and counts it
+// against the shared ban list. Banned users get no answer at all, so a
+// flood of messages costs neither Telegram requests nor disk writes.
+func (b *Bot) deny(chatID int64, from *tgbotapi.User) {
+	source := telegram.UserRef(from)
+	if _, banned := b.bans.Banned(banlist.KindTelegram, source); banned {
+		slog.Debug("Ignoring banned user", "source", source)
+		return
+	}
+	slog.Warn("Unauthorized access attempt", "username", from.UserName, "id", from.ID)
+	if _, err := b.bans.Fail(banlist.KindTelegram, source, "unauthorized message"); err != nil {
+		slog.Warn("Failed to record unauthorized access", "source", source, "error", err)
+	}
+	b.sender.SendPlain(chatID, "Access denied")
+}
+
 // sanitizeLogMessage returns a safe-to-log representation of the message.
 // Sensitive commands (like /import) have their arguments redacted.
 func sanitizeLogMessage(msg *tgbotapi.Message) string {
@@ -246,6 +321,32 @@ func (b *Bot) Auth() *Auth {
 	return b.auth
 }
 
+// AccessLog returns the Xray access log index; the caller runs Follow on it.
+func (b *Bot) AccessLog() *accesslog.Index {
+	return b.access
+}
+
+// Events returns the event history (for the weekly summary).
+func (b *Bot) Events() *events.Service {
+	return b.events
+}
+
+// Bans returns the shared ban list (for the ban announcer).
+func (b *Bot) Bans() banlist.Manager {
+	return b.bans
+}
+
+// Audit returns the audit log (for the server re-resolver).
+func (b *Bot) Audit() audit.Recorder {
+	return b.audit
+}
+
+// LoginApprover answers Web UI login requests; the caller serves it on the
+// login socket.
+func (b *Bot) LoginApprover() loginapproval.Approver {
+	return b.login
+}
+
 // Sender returns the message sender (for update checker).
 func (b *Bot) Sender() telegram.MessageSender {
 	return b.sender
@@ -1,4 +1,40 @@
 package bot
 
-// Tests for Bot struct will be added in Task 7.3
 // Auth tests are in auth_test.go
+
+import (
+	"path/filepath"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/banlist"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/telegram"
+)
+
+type mockSender struct {
+	telegram.MessageSender
+	plain []string
+}
+
+func (m *mockSender) SendPlain(chatID int64, text string) error {
+	m.plain = append(m.plain, text)
+	return nil
+}
+
+func TestBot_DenyBansRepeatedAttempts(t *testing.T) {
+	sender := &mockSender{}
+	bans := banlist.NewStore(filepath.Join(t.TempDir(), banlist.StoreFile))
+	b := &Bot{sender: sender, bans: bans}
+	from := &tgbotapi.User{ID: 42, UserName: "stranger"}
+
+	for range 8 {
+		b.deny(100, from)
+	}
+
+	if len(sender.plain) != 5 {
+		t.Errorf("expected 5 replies before the ban, got %d", len(sender.plain))
+	}
+	if _, banned := bans.Banned(banlist.KindTelegram, "@stranger"); !banned {
+		t.Error("expected the user to be banned")
+	}
+}
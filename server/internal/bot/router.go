@@ -18,6 +18,7 @@ type StatusRouterHandler interface {
 type ServersRouterHandler interface {
 	HandleServers(msg *tgbotapi.Message)
 	HandleCallback(cb *tgbotapi.CallbackQuery)
+	HandleTextInput(msg *tgbotapi.Message)
 }
 
 // ImportRouterHandler defines methods for import command
@@ -69,17 +70,73 @@ type ClientsRouterHandler interface {
 	HandleTextInput(msg *tgbotapi.Message)
 }
 
+// DomainsRouterHandler defines methods for domains command
+type DomainsRouterHandler interface {
+	HandleDomains(msg *tgbotapi.Message)
+}
+
+// OptimizeRouterHandler defines methods for optimize command
+type OptimizeRouterHandler interface {
+	HandleOptimize(msg *tgbotapi.Message)
+	HandleCallback(cb *tgbotapi.CallbackQuery)
+}
+
+// TrafficRouterHandler defines methods for traffic command
+type TrafficRouterHandler interface {
+	HandleTraffic(msg *tgbotapi.Message)
+}
+
+// AccessRouterHandler defines methods for access command
+type AccessRouterHandler interface {
+	HandleAccess(msg *tgbotapi.Message)
+}
+
+// CheckRouterHandler defines methods for check command
+type CheckRouterHandler interface {
+	HandleCheck(msg *tgbotapi.Message)
+}
+
+// SpeedTestRouterHandler defines methods for speedtest command
+type SpeedTestRouterHandler interface {
+	HandleSpeedTest(msg *tgbotapi.Message)
+}
+
+// BansRouterHandler defines methods for bans commands
+type BansRouterHandler interface {
+	HandleBans(msg *tgbotapi.Message)
+	HandleUnban(msg *tgbotapi.Message)
+}
+
+// AuditRouterHandler defines methods for the audit command
+type AuditRouterHandler interface {
+	HandleAudit(msg *tgbotapi.Message)
+}
+
+// LoginRouterHandler defines methods for Web UI login approval
+type LoginRouterHandler interface {
+	HandleCallback(cb *tgbotapi.CallbackQuery)
+}
+
 // Router routes messages and callbacks to appropriate handlers
 type Router struct {
-	status  StatusRouterHandler
-	servers ServersRouterHandler
-	import_ ImportRouterHandler
-	misc    MiscRouterHandler
-	update  UpdateRouterHandler
-	wizard  WizardRouterHandler
-	xray    XrayRouterHandler
-	exclude ExcludeRouterHandler
-	clients ClientsRouterHandler
+	status   StatusRouterHandler
+	servers  ServersRouterHandler
+	import_  ImportRouterHandler
+	misc     MiscRouterHandler
+	update   UpdateRouterHandler
+	wizard   WizardRouterHandler
+	xray     XrayRouterHandler
+	exclude  ExcludeRouterHandler
+	clients  ClientsRouterHandler
+	domains  DomainsRouterHandler
+	optimize OptimizeRouterHandler
+	traffic  TrafficRouterHandler
+	access   AccessRouterHandler
+	check    CheckRouterHandler
+	speed    SpeedTestRouterHandler
+	bans     BansRouterHandler
+	audit    AuditRouterHandler
+	login    LoginRouterHandler
 }
 
 // NewRouter creates a new Router with all handlers
@@ -93,17 +150,35 @@ func NewRouter(
 	xray XrayRouterHandler,
 	exclude ExcludeRouterHandler,
 	clients ClientsRouterHandler,
+	domains DomainsRouterHandler,
+	optimize OptimizeRouterHandler,
+	traffic TrafficRouterHandler,
+	access AccessRouterHandler,
+	check CheckRouterHandler,
+	speed SpeedTestRouterHandler,
+	bans BansRouterHandler,
+	audit AuditRouterHandler,
+	login LoginRouterHandler,
 ) *Router {
 	return &Router{
-		status:  status,
-		servers: servers,
-		import_: import_,
-		misc:    misc,
-		update:  update,
-		wizard:  wizard,
-		xray:    xray,
-		exclude: exclude,
-		clients: clients,
+		status:   status,
+		servers:  servers,
+		import_:  import_,
+		misc:     misc,
+		update:   update,
+		wizard:   wizard,
+		xray:     xray,
+		exclude:  exclude,
+		clients:  clients,
+		domains:  domains,
+		optimize: optimize,
+		traffic:  traffic,
+		access:   access,
+		check:    check,
+		speed:    speed,
+		bans:     bans,
+		audit:    audit,
+		login:    login,
 	}
 }
 
@@ -144,7 +219,30 @@ func (r *Router) RouteMessage(msg *tgbotapi.Message) {
 		r.exclude.ClearState(msg.Chat.ID)
 		r.wizard.ClearState(msg.Chat.ID)
 		r.clients.HandleClients(msg)
+	case "domains":
+		r.domains.HandleDomains(msg)
+	case "optimize":
+		r.optimize.HandleOptimize(msg)
+	case "traffic":
+		r.traffic.HandleTraffic(msg)
+	case "access":
+		r.access.HandleAccess(msg)
+	case "check":
+		r.check.HandleCheck(msg)
+	case "speedtest":
+		r.speed.HandleSpeedTest(msg)
+	case "bans":
+		r.bans.HandleBans(msg)
+	case "unban":
+		r.bans.HandleUnban(msg)
+	case "audit":
+		r.audit.HandleAudit(msg)
 	default:
+		// A pasted vless:// link offers to add a server, whatever else is active.
+		if strings.HasPrefix(strings.TrimSpace(msg.Text), "vless://") {
+			r.servers.HandleTextInput(msg)
+			return
+		}
 		// Non-command messages go to clients, exclude, and wizard text handlers.
 		// All handlers check their own manager state, so multi-dispatch
 		// is safe — only one will have active state.
@@ -176,5 +274,13 @@ func (r *Router) RouteCallback(cb *tgbotapi.CallbackQuery) {
 		r.clients.HandleCallback(cb)
 		return
 	}
+	if strings.HasPrefix(cb.Data, "optimize:") {
+		r.optimize.HandleCallback(cb)
+		return
+	}
+	if strings.HasPrefix(cb.Data, "login:") {
+		r.login.HandleCallback(cb)
+		return
+	}
 	r.wizard.HandleCallback(cb)
 }
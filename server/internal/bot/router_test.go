@@ -20,12 +20,14 @@ func (m *mockStatusHandler) HandleRestart(msg *tgbotapi.Message) { m.restartCall
 func (m *mockStatusHandler) HandleStop(msg *tgbotapi.Message)    { m.stopCalled = true }
 
 type mockServersHandler struct {
-	serversCalled  bool
-	callbackCalled bool
+	serversCalled   bool
+	callbackCalled  bool
+	textInputCalled bool
 }
 
 func (m *mockServersHandler) HandleServers(msg *tgbotapi.Message)       { m.serversCalled = true }
 func (m *mockServersHandler) HandleCallback(cb *tgbotapi.CallbackQuery) { m.callbackCalled = true }
+func (m *mockServersHandler) HandleTextInput(msg *tgbotapi.Message)     { m.textInputCalled = true }
 
 type mockImportHandler struct {
 	importCalled bool
@@ -90,6 +92,64 @@ func (m *mockClientsHandler) HandleCallback(cb *tgbotapi.CallbackQuery) { m.call
 func (m *mockClientsHandler) HandleTextInput(msg *tgbotapi.Message)     { m.textInputCalled = true }
 func (m *mockClientsHandler) ClearState(chatID int64)                   { m.clearStateCalled = true }
 
+type mockDomainsHandler struct {
+	domainsCalled bool
+}
+
+func (m *mockDomainsHandler) HandleDomains(msg *tgbotapi.Message) { m.domainsCalled = true }
+
+type mockOptimizeHandler struct {
+	optimizeCalled bool
+	callbackCalled bool
+}
+
+func (m *mockOptimizeHandler) HandleOptimize(msg *tgbotapi.Message)      { m.optimizeCalled = true }
+func (m *mockOptimizeHandler) HandleCallback(cb *tgbotapi.CallbackQuery) { m.callbackCalled = true }
+
+type mockTrafficHandler struct {
+	trafficCalled bool
+}
+
+func (m *mockTrafficHandler) HandleTraffic(msg *tgbotapi.Message) { m.trafficCalled = true }
+
+type mockAccessHandler struct {
+	accessCalled bool
+}
+
+func (m *mockAccessHandler) HandleAccess(msg *tgbotapi.Message) { m.accessCalled = true }
+
+type mockCheckHandler struct {
+	checkCalled bool
+}
+
+func (m *mockCheckHandler) HandleCheck(msg *tgbotapi.Message) { m.checkCalled = true }
+
+type mockSpeedTestHandler struct {
+	speedTestCalled bool
+}
+
+func (m *mockSpeedTestHandler) HandleSpeedTest(msg *tgbotapi.Message) { m.speedTestCalled = true }
+
+type mockBansHandler struct {
+	bansCalled  bool
+	unbanCalled bool
+}
+
+func (m *mockBansHandler) HandleBans(msg *tgbotapi.Message)  { m.bansCalled = true }
+func (m *mockBansHandler) HandleUnban(msg *tgbotapi.Message) { m.unbanCalled = true }
+
+type mockAuditHandler struct {
+	auditCalled bool
+}
+
+func (m *mockAuditHandler) HandleAudit(msg *tgbotapi.Message) { m.auditCalled = true }
+
+type mockLoginHandler struct {
+	callbackCalled bool
+}
+
+func (m *mockLoginHandler) HandleCallback(cb *tgbotapi.CallbackQuery) { m.callbackCalled = true }
+
 // Helper to create a message with command entity
 func msgWithCommand(text string) *tgbotapi.Message {
 	cmdLen := len(text)
@@ -169,6 +229,117 @@ func TestRouter_RouteMessage_Import(t *testing.T) {
 	}
 }
 
+func TestRouter_RouteMessage_Domains(t *testing.T) {
+	h := &mockDomainsHandler{}
+	router := &Router{domains: h}
+
+	router.RouteMessage(msgWithCommand("/domains add wgc1 netflix.com"))
+
+	if !h.domainsCalled {
+		t.Error("expected HandleDomains to be called")
+	}
+}
+
+func TestRouter_RouteMessage_Optimize(t *testing.T) {
+	h := &mockOptimizeHandler{}
+	router := &Router{optimize: h}
+
+	router.RouteMessage(msgWithCommand("/optimize"))
+
+	if !h.optimizeCalled {
+		t.Error("expected HandleOptimize to be called")
+	}
+}
+
+func TestRouter_RouteMessage_Traffic(t *testing.T) {
+	h := &mockTrafficHandler{}
+	router := &Router{traffic: h}
+
+	router.RouteMessage(msgWithCommand("/traffic day"))
+
+	if !h.trafficCalled {
+		t.Error("expected HandleTraffic to be called")
+	}
+}
+
+func TestRouter_RouteMessage_Access(t *testing.T) {
+	h := &mockAccessHandler{}
+	router := &Router{access: h}
+
+	router.RouteMessage(msgWithCommand("/access 192.168.50.23 2h"))
+
+	if !h.accessCalled {
+		t.Error("expected HandleAccess to be called")
+	}
+}
+
+func TestRouter_RouteMessage_Check(t *testing.T) {
+	h := &mockCheckHandler{}
+	router := &Router{check: h}
+
+	router.RouteMessage(msgWithCommand("/check"))
+
+	if !h.checkCalled {
+		t.Error("expected HandleCheck to be called")
+	}
+}
+
+func TestRouter_RouteMessage_SpeedTest(t *testing.T) {
+	h := &mockSpeedTestHandler{}
+	router := &Router{speed: h}
+
+	router.RouteMessage(msgWithCommand("/speedtest xray"))
+
+	if !h.speedTestCalled {
+		t.Error("expected HandleSpeedTest to be called")
+	}
+}
+
+func TestRouter_RouteMessage_Bans(t *testing.T) {
+	h := &mockBansHandler{}
+	router := &Router{bans: h}
+
+	router.RouteMessage(msgWithCommand("/bans"))
+	router.RouteMessage(msgWithCommand("/unban 192.168.50.23"))
+
+	if !h.bansCalled || !h.unbanCalled {
+		t.Error("expected HandleBans and HandleUnban to be called")
+	}
+}
+
+func TestRouter_RouteMessage_Audit(t *testing.T) {
+	h := &mockAuditHandler{}
+	router := &Router{audit: h}
+
+	router.RouteMessage(msgWithCommand("/audit bot 10"))
+
+	if !h.auditCalled {
+		t.Error("expected HandleAudit to be called")
+	}
+}
+
+func TestRouter_RouteCallback_Optimize(t *testing.T) {
+	h := &mockOptimizeHandler{}
+	router := &Router{optimize: h}
+
+	router.RouteCallback(&tgbotapi.CallbackQuery{Data: "optimize:apply"})
+
+	if !h.callbackCalled {
+		t.Error("expected optimize HandleCallback to be called")
+	}
+}
+
+func TestRouter_RouteCallback_Login(t *testing.T) {
+	h := &mockLoginHandler{}
+	router := &Router{login: h}
+
+	router.RouteCallback(&tgbotapi.CallbackQuery{Data: "login:approve:abc"})
+
+	if !h.callbackCalled {
+		t.Error("expected login HandleCallback to be called")
+	}
+}
+
 func TestRouter_RouteMessage_Start(t *testing.T) {
 	h := &mockMiscHandler{}
 	router := &Router{misc: h}
@@ -254,6 +425,27 @@ func TestRouter_RouteMessage_UnknownCommand_RoutesToWizardText(t *testing.T) {
 	}
 }
 
+func TestRouter_RouteMessage_VlessLink_RoutesToServers(t *testing.T) {
+	sh := &mockServersHandler{}
+	wh := &mockWizardHandler{}
+	eh := &mockExcludeHandler{}
+	ch := &mockClientsHandler{}
+	router := &Router{servers: sh, wizard: wh, exclude: eh, clients: ch}
+
+	msg := &tgbotapi.Message{
+		Text: "vless://uuid@example.com:443?security=reality#Test",
+		Chat: &tgbotapi.Chat{ID: 123},
+	}
+	router.RouteMessage(msg)
+
+	if !sh.textInputCalled {
+		t.Error("expected servers.HandleTextInput to be called for vless link")
+	}
+	if ch.textInputCalled || wh.textCalled || eh.textCalled {
+		t.Error("expected vless link not to reach other text handlers")
+	}
+}
+
 // Tests for RouteCallback
 
 func TestRouter_RouteCallback_Servers(t *testing.T) {
@@ -291,7 +483,7 @@ func TestRouter_RouteCallback_Wizard(t *testing.T) {
 	router := &Router{wizard: h}
 
 	cb := &tgbotapi.CallbackQuery{
-		Data:    "server:1",
+		Data:    "server:srv2",
 		Message: &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 123}},
 	}
 	router.RouteCallback(cb)
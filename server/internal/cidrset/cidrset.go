@@ -0,0 +1,270 @@
+// Package cidrset normalises, compacts and aggregates lists of IP addresses
+// and CIDR prefixes, and detects entries that overlap across routes.
+//
+// Entries are strings as stored in vpn-director.json: either a bare address
+// ("192.168.1.10") or a prefix ("10.0.0.0/8"). A single-host prefix is
+// always formatted as a bare address.
+package cidrset
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+// Parse parses an IP address or CIDR prefix. Host bits are masked off
+// ("10.1.2.3/8" becomes 10.0.0.0/8) and IPv4-mapped IPv6 addresses are
+// unmapped.
+func Parse(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "/") {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid CIDR %q", s)
+		}
+		addr := p.Addr()
+		bits := p.Bits()
+		if addr.Is4In6() {
+			if bits < 96 {
+				return netip.Prefix{}, fmt.Errorf("invalid CIDR %q", s)
+			}
+			addr = addr.Unmap()
+			bits -= 96
+		}
+		return netip.PrefixFrom(addr, bits).Masked(), nil
+	}
+
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid IP address %q", s)
+	}
+	if addr.Zone() != "" {
+		return netip.Prefix{}, fmt.Errorf("invalid IP address %q", s)
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// Format returns the canonical string for p: a bare address for
+// single-host prefixes, CIDR notation otherwise.
+func Format(p netip.Prefix) string {
+	if p.IsSingleIP() {
+		return p.Addr().String()
+	}
+	return p.String()
+}
+
+// Canonical returns the canonical form of an IP or CIDR string.
+func Canonical(s string) (string, error) {
+	p, err := Parse(s)
+	if err != nil {
+		return "", err
+	}
+	return Format(p), nil
+}
+
+// Contains reports whether outer covers every address of inner.
+func Contains(outer, inner netip.Prefix) bool {
+	return outer.Bits() <= inner.Bits() && outer.Contains(inner.Addr())
+}
+
+// Match reports whether addr is in one of the entries (IPs or CIDRs).
+// Invalid entries are ignored.
+func Match(entries []string, addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, entry := range entries {
+		if p, err := Parse(entry); err == nil && p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Add appends the canonical form of p to entries. An entry equal to p
+// leaves the list unchanged; a broader entry covering p is an error, since
+// p would add nothing to the set. Invalid entries are ignored.
+func Add(entries []string, p netip.Prefix) ([]string, error) {
+	for _, e := range entries {
+		q, err := Parse(e)
+		if err != nil || !Contains(q, p) {
+			continue
+		}
+		if q == p {
+			return entries, nil
+		}
+		return entries, fmt.Errorf("%s is already covered by %s", Format(p), e)
+	}
+	return append(entries, Format(p)), nil
+}
+
+// Redundant returns the indexes of entries that add nothing to the set:
+// duplicates of an earlier entry (after normalisation) and entries covered
+// by a broader entry. Invalid entries are never redundant.
+func Redundant(entries []string) map[int]bool {
+	prefixes := make([]netip.Prefix, len(entries))
+	valid := make([]bool, len(entries))
+	for i, e := range entries {
+		if p, err := Parse(e); err == nil {
+			prefixes[i], valid[i] = p, true
+		}
+	}
+
+	redundant := make(map[int]bool)
+	for i := range entries {
+		if !valid[i] {
+			continue
+		}
+		for j := range entries {
+			if i == j || !valid[j] || redundant[j] || !Contains(prefixes[j], prefixes[i]) {
+				continue
+			}
+			// Equal prefixes: keep the first occurrence
+			if prefixes[i] == prefixes[j] && i < j {
+				continue
+			}
+			redundant[i] = true
+			break
+		}
+	}
+	return redundant
+}
+
+// Compact removes redundant entries (see Redundant) and keeps the original
+// spelling and order of the remaining ones.
+func Compact(entries []string) []string {
+	redundant := Redundant(entries)
+	if len(redundant) == 0 {
+		return entries
+	}
+	result := make([]string, 0, len(entries)-len(redundant))
+	for i, e := range entries {
+		if !redundant[i] {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// Merge returns the smallest sorted list of prefixes covering exactly the
+// union of the input: covered prefixes are dropped and adjacent sibling
+// prefixes are joined into their parent.
+func Merge(prefixes []netip.Prefix) []netip.Prefix {
+	sorted := append([]netip.Prefix(nil), prefixes...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if c := sorted[i].Addr().Compare(sorted[j].Addr()); c != 0 {
+			return c < 0
+		}
+		return sorted[i].Bits() < sorted[j].Bits()
+	})
+
+	var stack []netip.Prefix
+	for _, p := range sorted {
+		if n := len(stack); n > 0 && Contains(stack[n-1], p) {
+			continue
+		}
+		stack = append(stack, p)
+		// Join siblings bottom-up while the last two share a parent
+		for len(stack) >= 2 {
+			a, b := stack[len(stack)-2], stack[len(stack)-1]
+			parent, ok := siblingParent(a, b)
+			if !ok {
+				break
+			}
+			stack = append(stack[:len(stack)-2], parent)
+		}
+	}
+	return stack
+}
+
+// siblingParent returns the parent prefix if a and b are the two halves of it.
+func siblingParent(a, b netip.Prefix) (netip.Prefix, bool) {
+	if a.Bits() != b.Bits() || a.Bits() == 0 || a.Addr().Is4() != b.Addr().Is4() {
+		return netip.Prefix{}, false
+	}
+	parent := netip.PrefixFrom(a.Addr(), a.Bits()-1).Masked()
+	if parent.Addr() != a.Addr() || !parent.Contains(b.Addr()) || a == b {
+		return netip.Prefix{}, false
+	}
+	return parent, true
+}
+
+// Aggregate merges entries into the minimal canonical list (see Merge).
+// Invalid entries are returned separately and left out of the result.
+func Aggregate(entries []string) (result []string, invalid []string) {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, e := range entries {
+		p, err := Parse(e)
+		if err != nil {
+			invalid = append(invalid, e)
+			continue
+		}
+		prefixes = append(prefixes, p)
+	}
+
+	merged := Merge(prefixes)
+	result = make([]string, len(merged))
+	for i, p := range merged {
+		result[i] = Format(p)
+	}
+	return result, invalid
+}
+
+// Conflict describes an entry of one route overlapping an entry of another.
+type Conflict struct {
+	Entry      string `json:"entry"`
+	Route      string `json:"route"`
+	Overlaps   string `json:"overlaps"`
+	OtherRoute string `json:"other_route"`
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s (%s) overlaps %s (%s)", c.Entry, c.Route, c.Overlaps, c.OtherRoute)
+}
+
+// FindConflicts reports entries assigned to different routes that overlap.
+// Two prefixes overlap only if one contains the other, so each pair is
+// reported once with the narrower entry first. Output is sorted by route
+// name, then by position in the route's list.
+func FindConflicts(routes map[string][]string) []Conflict {
+	type item struct {
+		entry  string
+		route  string
+		prefix netip.Prefix
+	}
+
+	names := make([]string, 0, len(routes))
+	for name := range routes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var items []item
+	for _, name := range names {
+		for _, e := range routes[name] {
+			if p, err := Parse(e); err == nil {
+				items = append(items, item{entry: e, route: name, prefix: p})
+			}
+		}
+	}
+
+	var conflicts []Conflict
+	for i, a := range items {
+		for j, b := range items {
+			if a.route == b.route || !Contains(b.prefix, a.prefix) {
+				continue
+			}
+			// Identical prefixes on two routes: report once
+			if a.prefix == b.prefix && i > j {
+				continue
+			}
+			conflicts = append(conflicts, Conflict{
+				Entry:      a.entry,
+				Route:      a.route,
+				Overlaps:   b.entry,
+				OtherRoute: b.route,
+			})
+		}
+	}
+	return conflicts
+}
@@ -0,0 +1,238 @@
+package cidrset
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"192.168.1.10", "192.168.1.10/32", false},
+		{" 192.168.1.10 ", "192.168.1.10/32", false},
+		{"10.1.2.3/8", "10.0.0.0/8", false},
+		{"::ffff:10.0.0.1", "10.0.0.1/32", false},
+		{"::ffff:10.0.0.0/120", "10.0.0.0/24", false},
+		{"2001:db8::1", "2001:db8::1/128", false},
+		{"2001:db8::/32", "2001:db8::/32", false},
+		{"fe80::1%eth0", "", true},
+		{"10.0.0.0/33", "", true},
+		{"not-an-ip", "", true},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %s", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	tests := map[string]string{
+		"192.168.1.10":    "192.168.1.10",
+		"192.168.1.10/32": "192.168.1.10",
+		"192.168.1.77/24": "192.168.1.0/24",
+		"2001:db8::1/128": "2001:db8::1",
+	}
+	for in, want := range tests {
+		got, err := Canonical(in)
+		if err != nil {
+			t.Fatalf("Canonical(%q): %v", in, err)
+		}
+		if got != want {
+			t.Errorf("Canonical(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	p := netip.MustParsePrefix
+	if !Contains(p("10.0.0.0/8"), p("10.1.0.0/16")) {
+		t.Error("10.0.0.0/8 should contain 10.1.0.0/16")
+	}
+	if Contains(p("10.1.0.0/16"), p("10.0.0.0/8")) {
+		t.Error("10.1.0.0/16 should not contain 10.0.0.0/8")
+	}
+	if !Contains(p("10.0.0.0/8"), p("10.0.0.0/8")) {
+		t.Error("prefix should contain itself")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	entries := []string{"10.0.0.0/8", "192.168.1.10", "bogus"}
+	for addr, want := range map[string]bool{
+		"10.1.2.3":            true,
+		"192.168.1.10":        true,
+		"::ffff:192.168.1.10": true,
+		"192.168.1.11":        false,
+		"2001:db8::1":         false,
+	} {
+		if got := Match(entries, netip.MustParseAddr(addr)); got != want {
+			t.Errorf("Match(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestAdd(t *testing.T) {
+	p := netip.MustParsePrefix
+	entries := []string{"10.0.0.0/8", "192.168.1.10/32"}
+
+	got, err := Add(entries, p("172.16.5.0/24"))
+	if err != nil || len(got) != 3 || got[2] != "172.16.5.0/24" {
+		t.Errorf("expected the prefix appended, got %v, %v", got, err)
+	}
+	got, err = Add(entries, p("192.168.1.10/32"))
+	if err != nil || len(got) != 2 {
+		t.Errorf("expected an equal entry to be a no-op, got %v, %v", got, err)
+	}
+	if _, err := Add(entries, p("10.1.0.0/16")); err == nil || err.Error() != "10.1.0.0/16 is already covered by 10.0.0.0/8" {
+		t.Errorf("expected a covered entry to be refused, got %v", err)
+	}
+}
+
+func TestRedundant(t *testing.T) {
+	entries := []string{
+		"192.168.1.10",
+		"192.168.1.0/24",
+		"192.168.1.10/32", // duplicate of entry 0, also covered
+		"bad",
+		"10.0.0.1",
+		"10.0.0.1", // duplicate
+	}
+	got := Redundant(entries)
+	want := map[int]bool{0: true, 2: true, 5: true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	entries := []string{"10.0.0.5", "192.168.0.0/16", "10.0.0.0/24", "192.168.5.1", "bad", "10.0.0.5"}
+	got := Compact(entries)
+	want := []string{"192.168.0.0/16", "10.0.0.0/24", "bad"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestCompact_NoChangeReturnsInput(t *testing.T) {
+	entries := []string{"10.0.0.1", "10.0.0.2"}
+	got := Compact(entries)
+	if &got[0] != &entries[0] {
+		t.Error("expected input slice to be returned unchanged")
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      []string
+		want    []string
+		invalid []string
+	}{
+		{
+			name: "adjacent halves merge",
+			in:   []string{"10.0.0.128/25", "10.0.0.0/25"},
+			want: []string{"10.0.0.0/24"},
+		},
+		{
+			name: "cascading merge",
+			in:   []string{"10.0.0.0/26", "10.0.0.64/26", "10.0.0.128/25"},
+			want: []string{"10.0.0.0/24"},
+		},
+		{
+			name: "two hosts merge into /31",
+			in:   []string{"192.168.1.3", "192.168.1.2"},
+			want: []string{"192.168.1.2/31"},
+		},
+		{
+			name: "non-aligned neighbours stay apart",
+			in:   []string{"192.168.1.1", "192.168.1.2"},
+			want: []string{"192.168.1.1", "192.168.1.2"},
+		},
+		{
+			name: "covered and duplicate entries dropped",
+			in:   []string{"10.0.0.0/8", "10.1.2.3", "10.0.0.0/8"},
+			want: []string{"10.0.0.0/8"},
+		},
+		{
+			name:    "invalid entries separated",
+			in:      []string{"bad", "10.0.0.1"},
+			want:    []string{"10.0.0.1"},
+			invalid: []string{"bad"},
+		},
+		{
+			name: "families never merge",
+			in:   []string{"::/1", "128.0.0.0/1"},
+			want: []string{"128.0.0.0/1", "::/1"},
+		},
+		{
+			name: "empty",
+			in:   nil,
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, invalid := Aggregate(tt.in)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(invalid, tt.invalid) {
+				t.Errorf("invalid = %v, want %v", invalid, tt.invalid)
+			}
+		})
+	}
+}
+
+func TestFindConflicts(t *testing.T) {
+	routes := map[string][]string{
+		"xray": {"192.168.1.0/24", "10.0.0.5"},
+		"wgc1": {"192.168.1.10", "192.168.2.1"},
+		"wgc2": {"10.0.0.5"},
+	}
+
+	got := FindConflicts(routes)
+	want := []Conflict{
+		{Entry: "192.168.1.10", Route: "wgc1", Overlaps: "192.168.1.0/24", OtherRoute: "xray"},
+		{Entry: "10.0.0.5", Route: "wgc2", Overlaps: "10.0.0.5", OtherRoute: "xray"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestFindConflicts_SameRouteIgnored(t *testing.T) {
+	routes := map[string][]string{
+		"xray": {"192.168.1.0/24", "192.168.1.10"},
+	}
+	if got := FindConflicts(routes); len(got) != 0 {
+		t.Errorf("expected no conflicts, got %v", got)
+	}
+}
+
+func TestConflict_String(t *testing.T) {
+	c := Conflict{Entry: "192.168.1.10", Route: "wgc1", Overlaps: "192.168.1.0/24", OtherRoute: "xray"}
+	want := "192.168.1.10 (wgc1) overlaps 192.168.1.0/24 (xray)"
+	if c.String() != want {
+		t.Errorf("got %q, want %q", c.String(), want)
+	}
+}
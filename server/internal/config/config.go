@@ -7,22 +7,26 @@ import (
 )
 
 type Config struct {
-	BotToken            string        `json:"bot_token"`
-	AllowedUsers        []string      `json:"allowed_users"`
-	LogLevel            string        `json:"log_level"`
-	UpdateCheckInterval time.Duration `json:"-"` // Parsed from string
-	Proxy               string
-	ProxyFallbackDirect bool
+	BotToken              string        `json:"bot_token"`
+	AllowedUsers          []string      `json:"allowed_users"`
+	LogLevel              string        `json:"log_level"`
+	UpdateCheckInterval   time.Duration `json:"-"`              // Parsed from string
+	ServerResolveInterval time.Duration `json:"-"`              // Parsed from string
+	WeeklySummary         string        `json:"weekly_summary"` // "<weekday> HH:MM", empty disables
+	Proxy                 string
+	ProxyFallbackDirect   bool
 }
 
 // rawConfig is used for JSON unmarshaling with string duration
 type rawConfig struct {
-	BotToken            string   `json:"bot_token"`
-	AllowedUsers        []string `json:"allowed_users"`
-	LogLevel            string   `json:"log_level"`
-	UpdateCheckInterval string   `json:"update_check_interval"`
-	Proxy               string   `json:"proxy"`
-	ProxyFallbackDirect bool     `json:"proxy_fallback_direct"`
+	BotToken              string   `json:"bot_token"`
+	AllowedUsers          []string `json:"allowed_users"`
+	LogLevel              string   `json:"log_level"`
+	UpdateCheckInterval   string   `json:"update_check_interval"`
+	ServerResolveInterval string   `json:"server_resolve_interval"`
+	WeeklySummary         string   `json:"weekly_summary"`
+	Proxy                 string   `json:"proxy"`
+	ProxyFallbackDirect   bool     `json:"proxy_fallback_direct"`
 }
 
 func Load(path string) (*Config, error) {
@@ -40,18 +44,25 @@ func Load(path string) (*Config, error) {
 		BotToken:            raw.BotToken,
 		AllowedUsers:        raw.AllowedUsers,
 		LogLevel:            raw.LogLevel,
+		WeeklySummary:       raw.WeeklySummary,
 		Proxy:               raw.Proxy,
 		ProxyFallbackDirect: raw.ProxyFallbackDirect,
 	}
 
-	// Parse duration if provided and not "0"
-	if raw.UpdateCheckInterval != "" && raw.UpdateCheckInterval != "0" {
-		d, err := time.ParseDuration(raw.UpdateCheckInterval)
-		if err != nil {
-			return nil, err
-		}
-		cfg.UpdateCheckInterval = d
+	if cfg.UpdateCheckInterval, err = parseInterval(raw.UpdateCheckInterval); err != nil {
+		return nil, err
+	}
+	if cfg.ServerResolveInterval, err = parseInterval(raw.ServerResolveInterval); err != nil {
+		return nil, err
 	}
 
 	return cfg, nil
 }
+
+// parseInterval parses a duration string; empty or "0" means disabled.
+func parseInterval(s string) (time.Duration, error) {
+	if s == "" || s == "0" {
+		return 0, nil
+	}
+	return time.ParseDuration(s)
+}
@@ -218,6 +218,62 @@ func TestLoad_UpdateCheckIntervalExplicitZero(t *testing.T) {
 	}
 }
 
+func TestLoad_ServerResolveInterval(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.json")
+
+	jsonContent := `{
+		"bot_token": "test-token",
+		"allowed_users": [],
+		"server_resolve_interval": "30m"
+	}`
+
+	if err := os.WriteFile(configPath, []byte(jsonContent), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.ServerResolveInterval != 30*time.Minute {
+		t.Errorf("expected server_resolve_interval 30m, got %v", cfg.ServerResolveInterval)
+	}
+}
+
+func TestLoad_InvalidServerResolveInterval(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.json")
+
+	jsonContent := `{"bot_token": "test-token", "server_resolve_interval": "often"}`
+	if err := os.WriteFile(configPath, []byte(jsonContent), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	if _, err := Load(configPath); err == nil {
+		t.Error("expected error for invalid duration")
+	}
+}
+
+func TestLoad_WeeklySummary(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.json")
+
+	jsonContent := `{"bot_token": "test-token", "weekly_summary": "mon 09:00"}`
+	if err := os.WriteFile(configPath, []byte(jsonContent), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.WeeklySummary != "mon 09:00" {
+		t.Errorf("expected weekly_summary 'mon 09:00', got %q", cfg.WeeklySummary)
+	}
+}
+
 func TestLoad_WithProxy(t *testing.T) {
 	tmpDir := t.TempDir()
 	configPath := filepath.Join(tmpDir, "config.json")
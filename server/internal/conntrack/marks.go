@@ -0,0 +1,116 @@
+package conntrack
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+)
+
+// Default marks, matching advanced.* in vpn-director.json.
+const (
+	defaultXrayMark    = 0x100
+	defaultXrayMask    = 0x100
+	defaultTunnelMask  = 0x00ff0000
+	defaultTunnelShift = 16
+)
+
+// Marks maps packet marks to routes: Xray's TPROXY fwmark, and the Tunnel
+// Director slot stored in the tunnel mark field.
+type Marks struct {
+	XrayMark    uint32
+	XrayMask    uint32
+	TunnelMask  uint32
+	TunnelShift uint
+	// Tunnels maps a tunnel mark value (already masked) to the routing
+	// table it is looked up in, e.g. 0x10000 -> wgc1.
+	Tunnels map[uint32]string
+}
+
+// MarksFromConfig reads the mark settings from advanced.xray and
+// advanced.tunnel_director; tunnels come from "ip rule show" output.
+func MarksFromConfig(cfg *vpnconfig.VPNDirectorConfig, ipRules string) Marks {
+	m := Marks{
+		XrayMark:    advancedUint(cfg, "xray", "fwmark", defaultXrayMark),
+		XrayMask:    advancedUint(cfg, "xray", "fwmark_mask", defaultXrayMask),
+		TunnelMask:  advancedUint(cfg, "tunnel_director", "mark_mask", defaultTunnelMask),
+		TunnelShift: uint(advancedUint(cfg, "tunnel_director", "mark_shift", defaultTunnelShift)),
+	}
+	m.Tunnels = ParseIPRules(ipRules, m.TunnelMask)
+	return m
+}
+
+// Route returns the route a mark selects: "xray", a tunnel name, or "" for
+// an unmarked connection. A tunnel slot with no ip rule is reported as
+// "tunnel#N".
+func (m Marks) Route(mark uint32) string {
+	if m.XrayMask != 0 && mark&m.XrayMask == m.XrayMark {
+		return "xray"
+	}
+	if m.TunnelMask == 0 {
+		return ""
+	}
+	value := mark & m.TunnelMask
+	if value == 0 {
+		return ""
+	}
+	if name, ok := m.Tunnels[value]; ok {
+		return name
+	}
+	return fmt.Sprintf("tunnel#%d", value>>m.TunnelShift)
+}
+
+// ParseIPRules extracts "fwmark <value>/<mask> lookup <table>" rules whose
+// mask equals the tunnel mask, e.g.
+//
+//	16384:	from all fwmark 0x10000/0xff0000 lookup wgc1
+func ParseIPRules(output string, mask uint32) map[uint32]string {
+	tunnels := make(map[uint32]string)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		mark, table := "", ""
+		for i := 0; i+1 < len(fields); i++ {
+			switch fields[i] {
+			case "fwmark":
+				mark = fields[i+1]
+			case "lookup", "table":
+				table = fields[i+1]
+			}
+		}
+		if mark == "" || table == "" {
+			continue
+		}
+		valueStr, maskStr, ok := strings.Cut(mark, "/")
+		if !ok {
+			continue
+		}
+		value, err1 := strconv.ParseUint(valueStr, 0, 32)
+		ruleMask, err2 := strconv.ParseUint(maskStr, 0, 32)
+		if err1 != nil || err2 != nil || uint32(ruleMask) != mask {
+			continue
+		}
+		tunnels[uint32(value)] = table
+	}
+	return tunnels
+}
+
+// advancedUint reads advanced.<section>.<key>, given as a hex/decimal string
+// ("0x100") or a JSON number (16).
+func advancedUint(cfg *vpnconfig.VPNDirectorConfig, section, key string, def uint32) uint32 {
+	sec, ok := cfg.Advanced[section].(map[string]interface{})
+	if !ok {
+		return def
+	}
+	switch v := sec[key].(type) {
+	case string:
+		if n, err := strconv.ParseUint(v, 0, 32); err == nil {
+			return uint32(n)
+		}
+	case float64:
+		if v >= 0 {
+			return uint32(v)
+		}
+	}
+	return def
+}
@@ -0,0 +1,60 @@
+package conntrack
+
+import (
+	"testing"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+)
+
+const testIPRules = `0:	from all lookup local
+200:	from all fwmark 0x100/0x100 lookup 100
+16384:	from all fwmark 0x10000/0xff0000 lookup wgc1
+16385:	from all fwmark 0x20000/0xff0000 lookup ovpnc1
+32766:	from all lookup main
+`
+
+func TestParseIPRules(t *testing.T) {
+	tunnels := ParseIPRules(testIPRules, 0xff0000)
+	if len(tunnels) != 2 || tunnels[0x10000] != "wgc1" || tunnels[0x20000] != "ovpnc1" {
+		t.Errorf("unexpected tunnels: %v", tunnels)
+	}
+}
+
+func TestMarksFromConfig_Defaults(t *testing.T) {
+	m := MarksFromConfig(&vpnconfig.VPNDirectorConfig{}, testIPRules)
+
+	tests := []struct {
+		mark uint32
+		want string
+	}{
+		{0, ""},
+		{0x100, "xray"},
+		{0x10000, "wgc1"},
+		{0x20000, "ovpnc1"},
+		{0x30000, "tunnel#3"},
+		{0x1, ""},
+	}
+	for _, tt := range tests {
+		if got := m.Route(tt.mark); got != tt.want {
+			t.Errorf("Route(%#x) = %q, want %q", tt.mark, got, tt.want)
+		}
+	}
+}
+
+func TestMarksFromConfig_Advanced(t *testing.T) {
+	cfg := &vpnconfig.VPNDirectorConfig{Advanced: map[string]interface{}{
+		"xray":            map[string]interface{}{"fwmark": "0x200", "fwmark_mask": "0x200"},
+		"tunnel_director": map[string]interface{}{"mark_mask": "0x0000ff00", "mark_shift": float64(8)},
+	}}
+	m := MarksFromConfig(cfg, "100:	from all fwmark 0x100/0xff00 lookup wgc2\n")
+
+	if m.Route(0x200) != "xray" {
+		t.Errorf("expected custom Xray mark to map to xray")
+	}
+	if m.Route(0x100) != "wgc2" {
+		t.Errorf("expected tunnel mark 0x100 to map to wgc2, got %q", m.Route(0x100))
+	}
+	if m.TunnelShift != 8 {
+		t.Errorf("TunnelShift = %d", m.TunnelShift)
+	}
+}
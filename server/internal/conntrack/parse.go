@@ -0,0 +1,141 @@
+// Package conntrack lists active connections from the kernel connection
+// tracking table (/proc/net/nf_conntrack) and annotates them with the route
+// their mark points to.
+package conntrack
+
+import (
+	"bufio"
+	"io"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// DefaultPath is the conntrack table exported by the kernel.
+const DefaultPath = "/proc/net/nf_conntrack"
+
+// Entry is one tracked connection. Addresses and ports are those of the
+// original direction (client to destination).
+type Entry struct {
+	Family  string `json:"family"`          // ipv4 or ipv6
+	Proto   string `json:"proto"`           // tcp, udp, icmp...
+	State   string `json:"state,omitempty"` // TCP state, e.g. ESTABLISHED
+	Timeout int    `json:"timeout"`         // seconds until the entry expires
+	Src     string `json:"src"`
+	SrcPort int    `json:"src_port,omitempty"`
+	Dst     string `json:"dst"`
+	DstPort int    `json:"dst_port,omitempty"`
+	// ReplyDst differs from Src when the connection is NATed (e.g. the
+	// router's WAN address for masqueraded traffic).
+	ReplyDst  string `json:"reply_dst,omitempty"`
+	Mark      uint32 `json:"mark"`
+	Unreplied bool   `json:"unreplied,omitempty"`
+	// Packets and Bytes cover both directions; they are only present when
+	// conntrack accounting (nf_conntrack_acct) is enabled.
+	Packets uint64 `json:"packets,omitempty"`
+	Bytes   uint64 `json:"bytes,omitempty"`
+}
+
+// Parse reads conntrack lines, skipping the ones it does not understand.
+func Parse(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 4096), 64*1024)
+	for sc.Scan() {
+		if e, ok := ParseLine(sc.Text()); ok {
+			entries = append(entries, e)
+		}
+	}
+	return entries, sc.Err()
+}
+
+// ParseLine parses one line of /proc/net/nf_conntrack, e.g.
+//
+//	ipv4     2 tcp      6 431999 ESTABLISHED src=192.168.50.23 dst=142.250.74.46 sport=54321 dport=443 src=142.250.74.46 dst=100.64.0.2 sport=443 dport=54321 [ASSURED] mark=256 use=1
+//
+// The older /proc/net/ip_conntrack format without the leading family
+// columns is accepted too.
+func ParseLine(line string) (Entry, bool) {
+	fields := strings.Fields(line)
+	var e Entry
+
+	if len(fields) > 0 && (fields[0] == "ipv4" || fields[0] == "ipv6") {
+		e.Family = fields[0]
+		fields = fields[2:]
+	}
+	if len(fields) < 3 {
+		return Entry{}, false
+	}
+	e.Proto = fields[0]
+	timeout, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return Entry{}, false
+	}
+	e.Timeout = timeout
+	fields = fields[3:]
+
+	// The state column only exists for stateful protocols.
+	if len(fields) > 0 && !strings.Contains(fields[0], "=") && !strings.HasPrefix(fields[0], "[") {
+		e.State = fields[0]
+		fields = fields[1:]
+	}
+
+	// Keys repeat for the reply direction; count occurrences to tell them apart.
+	seen := make(map[string]int)
+	for _, f := range fields {
+		if f == "[UNREPLIED]" {
+			e.Unreplied = true
+			continue
+		}
+		key, value, ok := strings.Cut(f, "=")
+		if !ok {
+			continue
+		}
+		n := seen[key]
+		seen[key] = n + 1
+
+		switch {
+		case key == "src" && n == 0:
+			e.Src = normalizeAddr(value)
+		case key == "dst" && n == 0:
+			e.Dst = normalizeAddr(value)
+		case key == "dst" && n == 1:
+			e.ReplyDst = normalizeAddr(value)
+		case key == "sport" && n == 0:
+			e.SrcPort, _ = strconv.Atoi(value)
+		case key == "dport" && n == 0:
+			e.DstPort, _ = strconv.Atoi(value)
+		case key == "mark":
+			mark, _ := strconv.ParseUint(value, 10, 32)
+			e.Mark = uint32(mark)
+		case key == "packets":
+			v, _ := strconv.ParseUint(value, 10, 64)
+			e.Packets += v
+		case key == "bytes":
+			v, _ := strconv.ParseUint(value, 10, 64)
+			e.Bytes += v
+		}
+	}
+
+	if e.Src == "" || e.Dst == "" {
+		return Entry{}, false
+	}
+	if e.ReplyDst == e.Src {
+		e.ReplyDst = ""
+	}
+	if e.Family == "" {
+		e.Family = "ipv4"
+		if strings.Contains(e.Src, ":") {
+			e.Family = "ipv6"
+		}
+	}
+	return e, true
+}
+
+// normalizeAddr compacts the fully expanded IPv6 form used by the kernel.
+func normalizeAddr(s string) string {
+	if a, err := netip.ParseAddr(s); err == nil {
+		return a.String()
+	}
+	return s
+}
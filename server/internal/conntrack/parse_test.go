@@ -0,0 +1,86 @@
+package conntrack
+
+import (
+	"os"
+	"testing"
+)
+
+func loadFixture(t *testing.T) []Entry {
+	t.Helper()
+	f, err := os.Open("testdata/nf_conntrack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	entries, err := Parse(f)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return entries
+}
+
+func TestParse_Fixture(t *testing.T) {
+	entries := loadFixture(t)
+	if len(entries) != 7 {
+		t.Fatalf("expected 7 entries (garbage skipped), got %d", len(entries))
+	}
+
+	tcp := entries[0]
+	if tcp.Family != "ipv4" || tcp.Proto != "tcp" || tcp.State != "ESTABLISHED" || tcp.Timeout != 431999 {
+		t.Errorf("unexpected header fields: %+v", tcp)
+	}
+	if tcp.Src != "192.168.50.23" || tcp.SrcPort != 54321 || tcp.Dst != "142.250.74.46" || tcp.DstPort != 443 {
+		t.Errorf("unexpected original tuple: %+v", tcp)
+	}
+	if tcp.Mark != 0x100 || tcp.ReplyDst != "" {
+		t.Errorf("expected mark 0x100 and no NAT, got %+v", tcp)
+	}
+}
+
+func TestParse_NATAndAccounting(t *testing.T) {
+	entries := loadFixture(t)
+
+	nat := entries[1]
+	if nat.ReplyDst != "100.64.12.7" {
+		t.Errorf("ReplyDst = %q, want WAN address", nat.ReplyDst)
+	}
+
+	acct := entries[3]
+	if acct.Packets != 22 || acct.Bytes != 10400 {
+		t.Errorf("expected both directions summed, got %d packets %d bytes", acct.Packets, acct.Bytes)
+	}
+	if acct.Mark != 0x10000 {
+		t.Errorf("Mark = %#x", acct.Mark)
+	}
+}
+
+func TestParse_UDPAndICMP(t *testing.T) {
+	entries := loadFixture(t)
+
+	udp := entries[2]
+	if udp.Proto != "udp" || udp.State != "" || !udp.Unreplied || udp.DstPort != 53 {
+		t.Errorf("unexpected UDP entry: %+v", udp)
+	}
+
+	icmp := entries[4]
+	if icmp.Proto != "icmp" || icmp.SrcPort != 0 || icmp.Dst != "1.1.1.1" {
+		t.Errorf("unexpected ICMP entry: %+v", icmp)
+	}
+}
+
+func TestParse_IPv6Compacted(t *testing.T) {
+	e := loadFixture(t)[6]
+	if e.Family != "ipv6" || e.Src != "fd00::23" || e.Dst != "2a00:1450:4010:c0e::64" {
+		t.Errorf("unexpected IPv6 entry: %+v", e)
+	}
+}
+
+func TestParseLine_IPConntrackFormat(t *testing.T) {
+	e, ok := ParseLine("tcp      6 117 TIME_WAIT src=192.168.1.5 dst=1.2.3.4 sport=1000 dport=80 src=1.2.3.4 dst=192.168.1.5 sport=80 dport=1000 [ASSURED] mark=0 use=1")
+	if !ok {
+		t.Fatal("expected line to parse")
+	}
+	if e.Family != "ipv4" || e.State != "TIME_WAIT" || e.Src != "192.168.1.5" {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+}
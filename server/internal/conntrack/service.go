@@ -0,0 +1,242 @@
+package conntrack
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/service"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+)
+
+// DefaultLimit and MaxLimit bound the number of connections returned.
+const (
+	DefaultLimit = 200
+	MaxLimit     = 2000
+)
+
+// Lister lists active connections of configured clients.
+type Lister interface {
+	Connections(q Query) (*Snapshot, error)
+}
+
+// Query filters connections. Empty fields match everything.
+type Query struct {
+	Client string // IP address or CIDR the source must fall in
+	Route  string // configured route of the client (xray, wgc1...)
+	Limit  int    // max connections (DefaultLimit if 0, capped at MaxLimit)
+}
+
+// Connection is a conntrack entry of a configured client.
+type Connection struct {
+	Entry
+	// Route is the route configured for the client; MarkRoute is the route
+	// the connection's mark selects ("" when unmarked).
+	Route     string `json:"route"`
+	MarkRoute string `json:"mark_route,omitempty"`
+}
+
+// ClientSummary counts connections per source address.
+type ClientSummary struct {
+	IP          string `json:"ip"`
+	Client      string `json:"client"` // matching client entry (IP or CIDR)
+	Route       string `json:"route"`
+	Paused      bool   `json:"paused"`
+	Connections int    `json:"connections"`
+	// Marked is the number of connections whose mark selects Route.
+	Marked int `json:"marked"`
+}
+
+// Snapshot is the current connection table of configured clients.
+type Snapshot struct {
+	Clients     []ClientSummary `json:"clients"`
+	Connections []Connection    `json:"connections"`
+	// Total is the number of matching connections before the limit.
+	Total int `json:"total"`
+}
+
+// Service reads the conntrack table and the mark-to-route mapping.
+type Service struct {
+	config   service.ConfigStore
+	executor service.ShellExecutor
+	path     string
+}
+
+var _ Lister = (*Service)(nil)
+
+// NewService creates a connection lister reading path (DefaultPath on a
+// router). executor may be nil.
+func NewService(config service.ConfigStore, executor service.ShellExecutor, path string) *Service {
+	if executor == nil {
+		executor = service.DefaultExecutor()
+	}
+	return &Service{config: config, executor: executor, path: path}
+}
+
+// Connections returns the connections of configured clients, grouped by
+// client and destination.
+func (s *Service) Connections(q Query) (*Snapshot, error) {
+	cfg, err := s.config.LoadVPNConfig()
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("read conntrack table: %w", err)
+	}
+	defer f.Close()
+	entries, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("read conntrack table: %w", err)
+	}
+
+	// Tunnel marks are resolved through ip rules; without them tunnels are
+	// still reported, by slot number.
+	var rules string
+	if res, err := s.executor.Exec("ip", "rule", "show"); err == nil && res.ExitCode == 0 {
+		rules = res.Output
+	}
+
+	return BuildSnapshot(entries, vpnconfig.CollectClients(cfg), MarksFromConfig(cfg, rules), q)
+}
+
+// clientMatcher finds the configured client a source address belongs to.
+type clientMatcher struct {
+	clients  []vpnconfig.ClientInfo
+	prefixes []netip.Prefix
+}
+
+func newClientMatcher(clients []vpnconfig.ClientInfo) *clientMatcher {
+	m := &clientMatcher{}
+	for _, c := range clients {
+		p, err := parsePrefix(c.IP)
+		if err != nil {
+			continue
+		}
+		m.clients = append(m.clients, c)
+		m.prefixes = append(m.prefixes, p)
+	}
+	return m
+}
+
+// match returns the first client containing ip, in config order (Xray
+// rules are evaluated before Tunnel Director).
+func (m *clientMatcher) match(ip string) (vpnconfig.ClientInfo, bool) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return vpnconfig.ClientInfo{}, false
+	}
+	for i, p := range m.prefixes {
+		if p.Contains(addr.Unmap()) {
+			return m.clients[i], true
+		}
+	}
+	return vpnconfig.ClientInfo{}, false
+}
+
+// BuildSnapshot keeps entries of configured clients and annotates them.
+func BuildSnapshot(entries []Entry, clients []vpnconfig.ClientInfo, marks Marks, q Query) (*Snapshot, error) {
+	var filter netip.Prefix
+	if q.Client != "" {
+		p, err := parsePrefix(q.Client)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client %q: expected an IP address or CIDR", q.Client)
+		}
+		filter = p
+	}
+
+	matcher := newClientMatcher(clients)
+	summaries := make(map[string]*ClientSummary)
+	var conns []Connection
+
+	for _, e := range entries {
+		client, ok := matcher.match(e.Src)
+		if !ok {
+			continue
+		}
+		if q.Route != "" && client.Route != q.Route {
+			continue
+		}
+		if filter.IsValid() {
+			addr, err := netip.ParseAddr(e.Src)
+			if err != nil || !filter.Contains(addr.Unmap()) {
+				continue
+			}
+		}
+
+		c := Connection{Entry: e, Route: client.Route, MarkRoute: marks.Route(e.Mark)}
+		conns = append(conns, c)
+
+		sum, ok := summaries[e.Src]
+		if !ok {
+			sum = &ClientSummary{IP: e.Src, Client: client.IP, Route: client.Route, Paused: client.Paused}
+			summaries[e.Src] = sum
+		}
+		sum.Connections++
+		if c.MarkRoute == c.Route {
+			sum.Marked++
+		}
+	}
+
+	sort.Slice(conns, func(i, j int) bool {
+		if conns[i].Src != conns[j].Src {
+			return addrLess(conns[i].Src, conns[j].Src)
+		}
+		if conns[i].Dst != conns[j].Dst {
+			return addrLess(conns[i].Dst, conns[j].Dst)
+		}
+		return conns[i].DstPort < conns[j].DstPort
+	})
+
+	snap := &Snapshot{Clients: make([]ClientSummary, 0, len(summaries)), Total: len(conns)}
+	for _, sum := range summaries {
+		snap.Clients = append(snap.Clients, *sum)
+	}
+	sort.Slice(snap.Clients, func(i, j int) bool { return addrLess(snap.Clients[i].IP, snap.Clients[j].IP) })
+
+	limit := q.Limit
+	switch {
+	case limit <= 0:
+		limit = DefaultLimit
+	case limit > MaxLimit:
+		limit = MaxLimit
+	}
+	if len(conns) > limit {
+		conns = conns[:limit]
+	}
+	snap.Connections = conns
+	if snap.Connections == nil {
+		snap.Connections = []Connection{}
+	}
+	return snap, nil
+}
+
+// parsePrefix accepts "192.168.1.10" or "192.168.1.0/24".
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return p.Masked(), nil
+	}
+	a, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	a = a.Unmap()
+	return netip.PrefixFrom(a, a.BitLen()), nil
+}
+
+// addrLess orders addresses numerically, falling back to string order.
+func addrLess(a, b string) bool {
+	x, errX := netip.ParseAddr(a)
+	y, errY := netip.ParseAddr(b)
+	if errX != nil || errY != nil {
+		return a < b
+	}
+	return x.Less(y)
+}
@@ -0,0 +1,132 @@
+package conntrack
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/shell"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/testutil"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+)
+
+type mockExecutor struct {
+	output string
+	err    error
+	calls  [][]string
+}
+
+func (m *mockExecutor) Exec(name string, args ...string) (*shell.Result, error) {
+	m.calls = append(m.calls, append([]string{name}, args...))
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &shell.Result{Output: m.output}, nil
+}
+
+func testConfig() *vpnconfig.VPNDirectorConfig {
+	return &vpnconfig.VPNDirectorConfig{
+		PausedClients: []string{"fd00::23"},
+		Xray:          vpnconfig.XrayConfig{Clients: []string{"192.168.50.23", "fd00::23"}},
+		TunnelDirector: vpnconfig.TunnelDirectorConfig{
+			Tunnels: map[string]vpnconfig.TunnelConfig{
+				"wgc1": {Clients: []string{"192.168.50.16/28"}},
+			},
+		},
+	}
+}
+
+func TestService_Connections(t *testing.T) {
+	exec := &mockExecutor{output: testIPRules}
+	svc := NewService(&testutil.Config{Cfg: testConfig()}, exec, "testdata/nf_conntrack")
+
+	snap, err := svc.Connections(Query{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(exec.calls) != 1 || exec.calls[0][0] != "ip" || exec.calls[0][1] != "rule" {
+		t.Errorf("expected ip rule show, got %v", exec.calls)
+	}
+
+	// 192.168.50.99 is not a configured client
+	if snap.Total != 6 || len(snap.Connections) != 6 {
+		t.Fatalf("expected 6 connections, got %d (total %d)", len(snap.Connections), snap.Total)
+	}
+	if len(snap.Clients) != 3 {
+		t.Fatalf("expected 3 clients, got %+v", snap.Clients)
+	}
+
+	// Sorted numerically: 192.168.50.20 before .23, IPv4 before IPv6
+	tun := snap.Clients[0]
+	if tun.IP != "192.168.50.20" || tun.Client != "192.168.50.16/28" || tun.Route != "wgc1" {
+		t.Errorf("unexpected tunnel client: %+v", tun)
+	}
+	// The ICMP entry carries the ovpnc1 mark, not wgc1
+	if tun.Connections != 2 || tun.Marked != 1 {
+		t.Errorf("expected 1 of 2 tunnel connections marked, got %+v", tun)
+	}
+
+	xray := snap.Clients[1]
+	if xray.IP != "192.168.50.23" || xray.Route != "xray" || xray.Connections != 3 || xray.Marked != 2 {
+		t.Errorf("unexpected xray client: %+v", xray)
+	}
+
+	if v6 := snap.Clients[2]; v6.IP != "fd00::23" || !v6.Paused {
+		t.Errorf("unexpected IPv6 client: %+v", v6)
+	}
+
+	for _, c := range snap.Connections {
+		if c.Dst == "87.250.250.242" && c.MarkRoute != "" {
+			t.Errorf("expected unmarked connection, got %+v", c)
+		}
+		if c.Dst == "1.1.1.1" && c.MarkRoute != "ovpnc1" {
+			t.Errorf("expected ovpnc1 mark route, got %+v", c)
+		}
+	}
+}
+
+func TestService_ConnectionsFiltered(t *testing.T) {
+	svc := NewService(&testutil.Config{Cfg: testConfig()}, &mockExecutor{output: testIPRules}, "testdata/nf_conntrack")
+
+	snap, err := svc.Connections(Query{Client: "192.168.50.23", Limit: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if snap.Total != 3 || len(snap.Connections) != 2 || len(snap.Clients) != 1 {
+		t.Errorf("expected 2 of 3 connections for one client, got %d of %d", len(snap.Connections), snap.Total)
+	}
+
+	snap, _ = svc.Connections(Query{Route: "wgc1"})
+	if snap.Total != 2 {
+		t.Errorf("expected 2 wgc1 connections, got %d", snap.Total)
+	}
+
+	if _, err := svc.Connections(Query{Client: "not-an-ip"}); err == nil {
+		t.Error("expected error for invalid client")
+	}
+}
+
+func TestService_TunnelsWithoutIPRules(t *testing.T) {
+	svc := NewService(&testutil.Config{Cfg: testConfig()}, &mockExecutor{err: errors.New("ip: not found")}, "testdata/nf_conntrack")
+
+	snap, err := svc.Connections(Query{Route: "wgc1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, c := range snap.Connections {
+		if c.Dst == "104.16.132.229" && c.MarkRoute != "tunnel#1" {
+			t.Errorf("expected slot fallback, got %q", c.MarkRoute)
+		}
+	}
+}
+
+func TestService_Errors(t *testing.T) {
+	svc := NewService(&testutil.Config{Err: errors.New("broken")}, &mockExecutor{}, "testdata/nf_conntrack")
+	if _, err := svc.Connections(Query{}); err == nil {
+		t.Error("expected config error")
+	}
+
+	svc = NewService(&testutil.Config{Cfg: testConfig()}, &mockExecutor{}, "testdata/missing")
+	if _, err := svc.Connections(Query{}); err == nil {
+		t.Error("expected error for missing conntrack table")
+	}
+}
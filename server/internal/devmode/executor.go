@@ -2,8 +2,10 @@
 package devmode
 
 import (
+	"fmt"
 	"log/slog"
 	"path/filepath"
+	"time"
 
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/service"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/shell"
@@ -17,10 +19,11 @@ var safeCommands = map[string]bool{
 
 // Executor implements ShellExecutor with safe/mock command handling for dev mode.
 // Safe commands (curl, tail) execute via real executor.
-// Router commands (vpn-director.sh) return mock responses.
+// Router commands (vpn-director.sh, service, ipset, iptables-save, pidof, ip rule) return mock responses.
 // Unknown commands fail with exit code 1.
 type Executor struct {
-	real service.ShellExecutor
+	real    service.ShellExecutor
+	started time.Time // base for mock iptables counters
 }
 
 // Compile-time interface check
@@ -28,13 +31,13 @@ var _ service.ShellExecutor = (*Executor)(nil)
 
 // NewExecutor creates a new dev mode executor with default real executor
 func NewExecutor() *Executor {
-	return &Executor{real: service.DefaultExecutor()}
+	return &Executor{real: service.DefaultExecutor(), started: time.Now()}
 }
 
 // NewExecutorWithReal creates a new dev mode executor with a custom real executor
 // (useful for testing)
 func NewExecutorWithReal(real service.ShellExecutor) *Executor {
-	return &Executor{real: real}
+	return &Executor{real: real, started: time.Now()}
 }
 
 // Exec executes a command, routing to real executor for safe commands,
@@ -53,6 +56,11 @@ func (e *Executor) Exec(name string, args ...string) (*shell.Result, error) {
 		return e.mockVPNDirector(args...)
 	}
 
+	// Check if it's a firmware command used for domain routing
+	if baseName == "service" || baseName == "ipset" || baseName == "iptables-save" || baseName == "pidof" || baseName == "ip" {
+		return e.mockRouterCommand(baseName, args...)
+	}
+
 	// Unknown command - fail
 	slog.Warn("DEV: unknown command blocked", "command", name, "args", args)
 	return &shell.Result{
@@ -112,3 +120,65 @@ func (e *Executor) mockVPNDirector(args ...string) (*shell.Result, error) {
 		}, nil
 	}
 }
+
+// mockRouterCommand returns mock responses for `service restart_dnsmasq`,
+// `ipset list -t`, `ipset list <set>`, `iptables-save -c -t mangle`,
+// `pidof <name>` and `ip rule show`
+func (e *Executor) mockRouterCommand(name string, args ...string) (*shell.Result, error) {
+	slog.Info("DEV: mock command", "command", name, "args", args)
+
+	switch {
+	case name == "service" && len(args) == 1 && args[0] == "restart_dnsmasq":
+		return &shell.Result{
+			Output:   "[DEV MODE] dnsmasq restarted",
+			ExitCode: 0,
+		}, nil
+	case name == "ipset" && len(args) == 2 && args[0] == "list" && args[1] == "-t":
+		return &shell.Result{
+			Output:   "Name: ru\nType: hash:net\nNumber of entries: 12000\nName: VPD_DOM_XRAY\nType: hash:ip\nNumber of entries: 0\n",
+			ExitCode: 0,
+		}, nil
+	case name == "ipset" && len(args) == 2 && args[0] == "list":
+		return &shell.Result{
+			Output:   "Name: " + args[1] + "\nType: hash:ip\nMembers:\n",
+			ExitCode: 0,
+		}, nil
+	case name == "iptables-save":
+		return &shell.Result{
+			Output:   e.mockMangleCounters(),
+			ExitCode: 0,
+		}, nil
+	case name == "pidof" && len(args) == 1:
+		return &shell.Result{
+			Output:   "1234",
+			ExitCode: 0,
+		}, nil
+	case name == "ip" && len(args) == 2 && args[0] == "rule" && args[1] == "show":
+		return &shell.Result{
+			Output:   "0:\tfrom all lookup local\n200:\tfrom all fwmark 0x100/0x100 lookup 100\n16384:\tfrom all fwmark 0x10000/0xff0000 lookup wgc1\n32766:\tfrom all lookup main\n",
+			ExitCode: 0,
+		}, nil
+	default:
+		return &shell.Result{
+			Output:   "[DEV MODE] " + name + ": command not mocked",
+			ExitCode: 1,
+		}, nil
+	}
+}
+
+// mockMangleCounters returns accounting rules whose counters grow with the
+// time since the executor was created, so traffic stats change in dev mode.
+func (e *Executor) mockMangleCounters() string {
+	secs := uint64(time.Since(e.started).Seconds()) + 1
+	return fmt.Sprintf(`*mangle
+:XRAY_TPROXY - [0:0]
+:TUN_DIR - [0:0]
+:XRAY_TPROXY_DL - [0:0]
+:TUN_DIR_DL - [0:0]
+[%d:%d] -A XRAY_TPROXY -s 192.168.50.0/24
+[%d:%d] -A TUN_DIR -s 192.168.50.20/32 -m mark --mark 0x0/0xff0000 -j MARK --set-xmark 0x10000/0xff0000
+[%d:%d] -A XRAY_TPROXY_DL -d 192.168.50.0/24
+[%d:%d] -A TUN_DIR_DL -i wgc1 -d 192.168.50.20/32
+COMMIT
+`, secs*40, secs*48000, secs*10, secs*6000, secs*120, secs*180000, secs*30, secs*36000)
+}
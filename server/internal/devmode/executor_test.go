@@ -180,6 +180,98 @@ func TestExecutor_MockCommand_VPNDirectorUnknownCommand(t *testing.T) {
 	}
 }
 
+func TestExecutor_MockCommand_RestartDnsmasq(t *testing.T) {
+	mock := &mockExecutor{}
+	exec := NewExecutorWithReal(mock)
+
+	result, err := exec.Exec("service", "restart_dnsmasq")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if result.ExitCode != 0 {
+		t.Errorf("expected exit code 0, got %d", result.ExitCode)
+	}
+	if len(mock.calls) != 0 {
+		t.Errorf("expected 0 calls to real executor, got %d", len(mock.calls))
+	}
+}
+
+func TestExecutor_MockCommand_IPSetList(t *testing.T) {
+	mock := &mockExecutor{}
+	exec := NewExecutorWithReal(mock)
+
+	result, err := exec.Exec("ipset", "list", "VPD_DOM_WGC1")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if result.ExitCode != 0 || !strings.Contains(result.Output, "Members:") {
+		t.Errorf("expected empty ipset listing, got %d %q", result.ExitCode, result.Output)
+	}
+
+	// Other service/ipset invocations are not mocked
+	result, _ = exec.Exec("ipset", "flush", "VPD_DOM_WGC1")
+	if result.ExitCode != 1 {
+		t.Errorf("expected exit code 1 for unmocked ipset command, got %d", result.ExitCode)
+	}
+}
+
+func TestExecutor_MockCommand_IPSetListTerse(t *testing.T) {
+	exec := NewExecutorWithReal(&mockExecutor{})
+
+	result, err := exec.Exec("ipset", "list", "-t")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if result.ExitCode != 0 || !strings.Contains(result.Output, "Number of entries:") {
+		t.Errorf("expected terse ipset listing, got %d %q", result.ExitCode, result.Output)
+	}
+}
+
+func TestExecutor_MockCommand_Pidof(t *testing.T) {
+	exec := NewExecutorWithReal(&mockExecutor{})
+
+	result, err := exec.Exec("pidof", "xray")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if result.ExitCode != 0 {
+		t.Errorf("expected mock process to be running, got %d %q", result.ExitCode, result.Output)
+	}
+}
+
+func TestExecutor_MockCommand_IPRule(t *testing.T) {
+	exec := NewExecutorWithReal(&mockExecutor{})
+
+	result, err := exec.Exec("ip", "rule", "show")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if result.ExitCode != 0 || !strings.Contains(result.Output, "fwmark 0x10000/0xff0000 lookup wgc1") {
+		t.Errorf("expected mock ip rules, got %d %q", result.ExitCode, result.Output)
+	}
+
+	result, _ = exec.Exec("ip", "route", "flush", "all")
+	if result.ExitCode == 0 {
+		t.Error("expected other ip commands to stay blocked")
+	}
+}
+
+func TestExecutor_MockCommand_IptablesSave(t *testing.T) {
+	mock := &mockExecutor{}
+	exec := NewExecutorWithReal(mock)
+
+	result, err := exec.Exec("iptables-save", "-c", "-t", "mangle")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if result.ExitCode != 0 || !strings.Contains(result.Output, "-A XRAY_TPROXY -s 192.168.50.0/24") {
+		t.Errorf("expected mock mangle counters, got %d %q", result.ExitCode, result.Output)
+	}
+	if len(mock.calls) != 0 {
+		t.Errorf("expected 0 calls to real executor, got %d", len(mock.calls))
+	}
+}
+
 func TestExecutor_UnknownCommand_Fails(t *testing.T) {
 	mock := &mockExecutor{}
 	exec := NewExecutorWithReal(mock)
@@ -0,0 +1,233 @@
+package diagnostics
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/service"
+)
+
+// dnsURLFmt is the DNS probe endpoint. A random subdomain makes sure the
+// lookup is never answered from a cache and the resolver that asked is
+// reported.
+const dnsURLFmt = "http://%s.edns.ip-api.com/json"
+
+// Route status.
+const (
+	StatusOK      = "ok"
+	StatusWarning = "warning"
+	StatusError   = "error"
+)
+
+// RouteChecker verifies the routes in use.
+type RouteChecker interface {
+	CheckRoutes() (*Report, error)
+}
+
+// Resolver is the DNS server seen by the authoritative server.
+type Resolver struct {
+	IP  string `json:"ip"`
+	Geo string `json:"geo,omitempty"` // "Country - Provider"
+}
+
+// Result is the outcome of probing one route.
+type Result struct {
+	Route
+	Exit     *service.IPInfo `json:"exit,omitempty"`
+	Resolver *Resolver       `json:"resolver,omitempty"`
+	Status   string          `json:"status"`
+	Problems []string        `json:"problems,omitempty"`
+}
+
+// Report covers all routes in use.
+type Report struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Routes    []Result  `json:"routes"`
+	OK        bool      `json:"ok"`
+}
+
+// Service probes routes: the exit through the network service's IP lookup,
+// the resolver with curl.
+type Service struct {
+	config   service.ConfigStore
+	executor service.ShellExecutor
+	network  service.NetworkInfo
+	now      func() time.Time
+}
+
+var _ RouteChecker = (*Service)(nil)
+
+// NewService creates a route checker. executor may be nil.
+func NewService(config service.ConfigStore, executor service.ShellExecutor, network service.NetworkInfo) *Service {
+	if executor == nil {
+		executor = service.DefaultExecutor()
+	}
+	return &Service{config: config, executor: executor, network: network, now: time.Now}
+}
+
+// CheckRoutes probes all routes in parallel and compares them with the WAN.
+func (s *Service) CheckRoutes() (*Report, error) {
+	cfg, err := s.config.LoadVPNConfig()
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+
+	routes := RoutesInUse(cfg)
+	results := make([]Result, len(routes))
+	var wg sync.WaitGroup
+	for i, r := range routes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = s.probe(r)
+		}()
+	}
+	wg.Wait()
+
+	return Evaluate(s.now(), results), nil
+}
+
+// probe looks up the exit and the resolver of one route.
+func (s *Service) probe(r Route) Result {
+	res := Result{Route: r}
+
+	var exitErr, dnsErr error
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		res.Exit, exitErr = s.lookupExit(r)
+	}()
+	go func() {
+		defer wg.Done()
+		res.Resolver, dnsErr = s.lookupResolver(r)
+	}()
+	wg.Wait()
+
+	if exitErr != nil {
+		res.Problems = append(res.Problems, fmt.Sprintf("exit lookup failed: %v", exitErr))
+	}
+	if dnsErr != nil {
+		res.Problems = append(res.Problems, fmt.Sprintf("DNS lookup failed: %v", dnsErr))
+	}
+	return res
+}
+
+func (s *Service) lookupExit(r Route) (*service.IPInfo, error) {
+	switch r.Kind {
+	case KindXray:
+		return s.network.GetExternalIP(service.ViaXray)
+	case KindTunnel:
+		return s.network.GetExternalIP(r.Name)
+	}
+	return s.network.GetExternalIP("")
+}
+
+func (s *Service) lookupResolver(r Route) (*Resolver, error) {
+	token, err := randomLabel()
+	if err != nil {
+		return nil, err
+	}
+	out, err := s.curl(r, fmt.Sprintf(dnsURLFmt, token))
+	if err != nil {
+		return nil, err
+	}
+	return ParseResolver(out)
+}
+
+// curl fetches url through the route.
+func (s *Service) curl(r Route, url string) (string, error) {
+	args := []string{"-s", "--connect-timeout", "5", "--max-time", "10"}
+	switch r.Kind {
+	case KindXray:
+		// Resolve through the proxy, so DNS follows the route too
+		args = append(args, "--socks5-hostname", r.Via)
+	case KindTunnel:
+		args = append(args, "--interface", r.Via)
+	}
+	args = append(args, url)
+
+	res, err := s.executor.Exec("curl", args...)
+	if err != nil {
+		return "", err
+	}
+	if res.ExitCode != 0 {
+		return "", fmt.Errorf("curl exited with code %d", res.ExitCode)
+	}
+	return res.Output, nil
+}
+
+// ParseResolver parses an edns.ip-api.com response.
+func ParseResolver(body string) (*Resolver, error) {
+	var resp struct {
+		DNS struct {
+			IP  string `json:"ip"`
+			Geo string `json:"geo"`
+		} `json:"dns"`
+	}
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	if net.ParseIP(resp.DNS.IP) == nil {
+		return nil, fmt.Errorf("invalid resolver address: %q", resp.DNS.IP)
+	}
+	return &Resolver{IP: resp.DNS.IP, Geo: resp.DNS.Geo}, nil
+}
+
+// Evaluate flags routes that exit via the WAN or resolve DNS through the
+// WAN's resolver, and sets each route's status.
+func Evaluate(now time.Time, results []Result) *Report {
+	var wan *Result
+	for i := range results {
+		if results[i].Kind == KindWAN {
+			wan = &results[i]
+			break
+		}
+	}
+
+	report := &Report{CheckedAt: now, Routes: results, OK: true}
+	for i := range results {
+		r := &results[i]
+		status := StatusOK
+		if len(r.Problems) > 0 {
+			status = StatusWarning
+		}
+
+		if r.Kind != KindWAN {
+			switch {
+			case r.Exit == nil:
+				status = StatusError
+			case wan != nil && wan.Exit != nil && r.Exit.IP == wan.Exit.IP:
+				r.Problems = append(r.Problems, fmt.Sprintf("%s clients exit via WAN (%s)", r.Name, r.Exit.IP))
+				status = StatusError
+			}
+			if r.Resolver != nil && wan != nil && wan.Resolver != nil && r.Resolver.IP == wan.Resolver.IP {
+				r.Problems = append(r.Problems, fmt.Sprintf("DNS is resolved by the WAN resolver (%s)", r.Resolver.IP))
+				if status == StatusOK {
+					status = StatusWarning
+				}
+			}
+		} else if r.Exit == nil {
+			status = StatusError
+		}
+
+		r.Status = status
+		if status == StatusError {
+			report.OK = false
+		}
+	}
+	return report
+}
+
+func randomLabel() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
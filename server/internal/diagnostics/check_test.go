@@ -0,0 +1,160 @@
+package diagnostics
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/service"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/shell"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/testutil"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+)
+
+// routeNetwork answers exit lookups per route, keyed by via.
+type routeNetwork struct {
+	mu    sync.Mutex
+	exits map[string]*service.IPInfo
+	calls []string
+}
+
+func (m *routeNetwork) GetExternalIP(via string) (*service.IPInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, via)
+	if info, ok := m.exits[via]; ok {
+		return info, nil
+	}
+	return nil, errors.New("all providers failed")
+}
+
+// routeExecutor answers DNS probes per route, keyed by the --interface or
+// --socks5-hostname value ("" for the WAN).
+type routeExecutor struct {
+	mu    sync.Mutex
+	dns   map[string]string
+	calls [][]string
+}
+
+func (m *routeExecutor) Exec(name string, args ...string) (*shell.Result, error) {
+	m.mu.Lock()
+	m.calls = append(m.calls, args)
+	m.mu.Unlock()
+
+	via := ""
+	for i, a := range args {
+		if (a == "--interface" || a == "--socks5-hostname") && i+1 < len(args) {
+			via = args[i+1]
+		}
+	}
+	out, ok := m.dns[via]
+	if !ok || !strings.Contains(args[len(args)-1], "edns.ip-api.com") {
+		return &shell.Result{ExitCode: 28}, nil
+	}
+	return &shell.Result{Output: out}, nil
+}
+
+var (
+	wanExit = &service.IPInfo{IP: "100.64.12.7", City: "Moscow", Country: "RU", ASN: "AS8359", ISP: "MTS PJSC"}
+	vpnExit = &service.IPInfo{IP: "185.10.10.10", City: "Amsterdam", Country: "NL", ASN: "AS60781", ISP: "LeaseWeb"}
+)
+
+const (
+	wanDNS   = `{"dns":{"geo":"Russia - MTS PJSC","ip":"195.34.32.1"}}`
+	proxyDNS = `{"dns":{"geo":"Netherlands - Google LLC","ip":"74.125.47.1"}}`
+)
+
+func testConfig() *vpnconfig.VPNDirectorConfig {
+	return &vpnconfig.VPNDirectorConfig{
+		Xray: vpnconfig.XrayConfig{Clients: []string{"192.168.50.10"}},
+		TunnelDirector: vpnconfig.TunnelDirectorConfig{Tunnels: map[string]vpnconfig.TunnelConfig{
+			"ovpnc1": {Clients: []string{"192.168.50.30"}},
+			"wgc1":   {Clients: []string{"192.168.50.20"}},
+		}},
+	}
+}
+
+func findRoute(t *testing.T, report *Report, name string) Result {
+	t.Helper()
+	for _, r := range report.Routes {
+		if r.Name == name {
+			return r
+		}
+	}
+	t.Fatalf("route %s not in report", name)
+	return Result{}
+}
+
+func TestCheckRoutes(t *testing.T) {
+	network := &routeNetwork{exits: map[string]*service.IPInfo{"": wanExit, "xray": vpnExit, "wgc1": vpnExit, "ovpnc1": wanExit}}
+	exec := &routeExecutor{
+		dns: map[string]string{"": wanDNS, "127.0.0.1:12346": proxyDNS, "wgc1": wanDNS, "tun11": wanDNS},
+	}
+	svc := NewService(&testutil.Config{Cfg: testConfig()}, exec, network)
+	svc.now = func() time.Time { return time.Date(2026, 1, 11, 12, 0, 0, 0, time.UTC) }
+
+	report, err := svc.CheckRoutes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(report.Routes) != 4 || len(network.calls) != 4 || len(exec.calls) != 4 {
+		t.Fatalf("expected 4 routes, 4 exit lookups and 4 DNS probes, got %d, %d and %d",
+			len(report.Routes), len(network.calls), len(exec.calls))
+	}
+	if report.OK {
+		t.Error("expected report to fail: ovpnc1 exits via WAN")
+	}
+
+	wan := findRoute(t, report, "wan")
+	if wan.Status != StatusOK || wan.Exit.ASN != "AS8359" || wan.Exit.ISP != "MTS PJSC" || wan.Exit.Country != "RU" {
+		t.Errorf("unexpected WAN result: %+v %+v", wan, wan.Exit)
+	}
+
+	xray := findRoute(t, report, "xray")
+	if xray.Status != StatusOK || xray.Resolver.IP != "74.125.47.1" {
+		t.Errorf("unexpected xray result: %+v", xray)
+	}
+
+	wg := findRoute(t, report, "wgc1")
+	if wg.Status != StatusWarning || len(wg.Problems) != 1 || !strings.Contains(wg.Problems[0], "WAN resolver") {
+		t.Errorf("expected DNS warning for wgc1, got %+v", wg)
+	}
+
+	ovpn := findRoute(t, report, "ovpnc1")
+	if ovpn.Status != StatusError || !strings.Contains(strings.Join(ovpn.Problems, "; "), "ovpnc1 clients exit via WAN") {
+		t.Errorf("expected WAN leak for ovpnc1, got %+v", ovpn)
+	}
+}
+
+func TestCheckRoutes_Unreachable(t *testing.T) {
+	network := &routeNetwork{exits: map[string]*service.IPInfo{"": wanExit}}
+	exec := &routeExecutor{dns: map[string]string{"": wanDNS}}
+	svc := NewService(&testutil.Config{Cfg: &vpnconfig.VPNDirectorConfig{Xray: vpnconfig.XrayConfig{Clients: []string{"192.168.50.10"}}}}, exec, network)
+
+	report, err := svc.CheckRoutes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	xray := findRoute(t, report, "xray")
+	if xray.Status != StatusError || xray.Exit != nil || len(xray.Problems) != 2 {
+		t.Errorf("expected failed probes for xray, got %+v", xray)
+	}
+	if report.OK {
+		t.Error("expected report to fail")
+	}
+}
+
+func TestCheckRoutes_ConfigError(t *testing.T) {
+	svc := NewService(&testutil.Config{Err: errors.New("broken")}, &routeExecutor{}, &routeNetwork{})
+	if _, err := svc.CheckRoutes(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestParseResolver_Invalid(t *testing.T) {
+	if _, err := ParseResolver(`{"dns":{}}`); err == nil {
+		t.Error("expected error for missing resolver")
+	}
+}
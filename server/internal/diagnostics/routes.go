@@ -0,0 +1,52 @@
+// Package diagnostics verifies that each route in use actually exits where
+// it should: it looks up the exit IP, its country and ASN, and the DNS
+// resolver seen through every route, and flags routes that leak to the WAN.
+package diagnostics
+
+import (
+	"sort"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+)
+
+// Route kinds.
+const (
+	KindWAN    = "wan"
+	KindXray   = "xray"
+	KindTunnel = "tunnel"
+)
+
+// Route is a path traffic can take out of the router.
+type Route struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	// Via is how probes are sent: the SOCKS address for Xray, the interface
+	// for tunnels, empty for the WAN.
+	Via string `json:"via,omitempty"`
+}
+
+// RoutesInUse returns the WAN, Xray (when it has clients) and every tunnel
+// with clients or domains, in that order.
+func RoutesInUse(cfg *vpnconfig.VPNDirectorConfig) []Route {
+	routes := []Route{{Name: KindWAN, Kind: KindWAN}}
+
+	if len(cfg.Xray.Clients) > 0 {
+		routes = append(routes, Route{
+			Name: KindXray,
+			Kind: KindXray,
+			Via:  vpnconfig.XraySocksAddr(cfg),
+		})
+	}
+
+	names := make([]string, 0, len(cfg.TunnelDirector.Tunnels))
+	for name, t := range cfg.TunnelDirector.Tunnels {
+		if len(t.Clients) > 0 || len(t.Domains) > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		routes = append(routes, Route{Name: name, Kind: KindTunnel, Via: vpnconfig.TunnelInterface(name)})
+	}
+	return routes
+}
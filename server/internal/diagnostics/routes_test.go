@@ -0,0 +1,43 @@
+package diagnostics
+
+import (
+	"testing"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+)
+
+func TestRoutesInUse(t *testing.T) {
+	cfg := &vpnconfig.VPNDirectorConfig{
+		Xray: vpnconfig.XrayConfig{Clients: []string{"192.168.50.10"}},
+		TunnelDirector: vpnconfig.TunnelDirectorConfig{Tunnels: map[string]vpnconfig.TunnelConfig{
+			"wgc1":   {Clients: []string{"192.168.50.20"}},
+			"ovpnc2": {Domains: []string{"netflix.com"}},
+			"wgc5":   {},
+		}},
+		Advanced: map[string]interface{}{"xray": map[string]interface{}{"socks_port": float64(1080)}},
+	}
+
+	routes := RoutesInUse(cfg)
+
+	want := []Route{
+		{Name: "wan", Kind: KindWAN},
+		{Name: "xray", Kind: KindXray, Via: "127.0.0.1:1080"},
+		{Name: "ovpnc2", Kind: KindTunnel, Via: "tun12"},
+		{Name: "wgc1", Kind: KindTunnel, Via: "wgc1"},
+	}
+	if len(routes) != len(want) {
+		t.Fatalf("expected %d routes, got %+v", len(want), routes)
+	}
+	for i := range want {
+		if routes[i] != want[i] {
+			t.Errorf("route %d = %+v, want %+v", i, routes[i], want[i])
+		}
+	}
+}
+
+func TestRoutesInUse_OnlyWAN(t *testing.T) {
+	routes := RoutesInUse(&vpnconfig.VPNDirectorConfig{})
+	if len(routes) != 1 || routes[0].Kind != KindWAN {
+		t.Errorf("expected only the WAN route, got %+v", routes)
+	}
+}
@@ -0,0 +1,220 @@
+// Package dnsmasq renders per-route domain lists from vpn-director.json into a
+// dnsmasq config fragment. dnsmasq adds every address it resolves for a listed
+// domain to the route's ipset, which vpn-director.sh then matches in iptables.
+package dnsmasq
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+)
+
+const (
+	// FragmentName is the file name of the generated fragment inside the
+	// scripts directory. /jffs/scripts/dnsmasq.postconf includes it.
+	FragmentName = "dnsmasq-domains.conf"
+
+	// RouteDirect is the pseudo-route for domains that bypass Xray
+	// (xray.exclude_domains).
+	RouteDirect = "direct"
+
+	// BypassSet is the ipset filled with addresses of RouteDirect domains.
+	BypassSet = "VPD_DOM_BYPASS"
+
+	// BypassSet6 receives the IPv6 addresses of RouteDirect domains. Tunnel
+	// routes have no IPv6 set: client tunnels do not carry IPv6.
+	BypassSet6 = BypassSet + "6"
+
+	// setPrefix is prepended to the upper-cased tunnel name for tunnel ipsets.
+	setPrefix = "VPD_DOM_"
+)
+
+// SetName returns the ipset name used for the given route.
+// Keep in sync with tunnel.sh and tproxy.sh.
+func SetName(route string) string {
+	if route == RouteDirect {
+		return BypassSet
+	}
+	return setPrefix + strings.ToUpper(route)
+}
+
+// ValidRoute reports whether route can carry a domain list: RouteDirect or
+// a WireGuard/OpenVPN client tunnel (wgc1-wgc5, ovpnc1-ovpnc5).
+func ValidRoute(route string) bool {
+	if route == RouteDirect {
+		return true
+	}
+	for _, prefix := range []string{"wgc", "ovpnc"} {
+		if n, ok := strings.CutPrefix(route, prefix); ok {
+			return len(n) == 1 && n[0] >= '1' && n[0] <= '5'
+		}
+	}
+	return false
+}
+
+// DomainLists returns the configured domains keyed by route. Routes without
+// domains are omitted.
+func DomainLists(cfg *vpnconfig.VPNDirectorConfig) map[string][]string {
+	lists := make(map[string][]string)
+	if len(cfg.Xray.ExcludeDomains) > 0 {
+		lists[RouteDirect] = cfg.Xray.ExcludeDomains
+	}
+	for name, tunnel := range cfg.TunnelDirector.Tunnels {
+		if len(tunnel.Domains) > 0 {
+			lists[name] = tunnel.Domains
+		}
+	}
+	return lists
+}
+
+// RouteOf returns the route whose list has domain, or "" if none has.
+func RouteOf(cfg *vpnconfig.VPNDirectorConfig, domain string) string {
+	for route, domains := range DomainLists(cfg) {
+		if containsString(domains, domain) {
+			return route
+		}
+	}
+	return ""
+}
+
+// AddDomain adds domain to the route's list, creating the tunnel entry if
+// needed. Returns false if any route already has the domain: dnsmasq would
+// put its addresses into both ipsets and the first matching rule would win.
+func AddDomain(cfg *vpnconfig.VPNDirectorConfig, route, domain string) bool {
+	if RouteOf(cfg, domain) != "" {
+		return false
+	}
+	if route == RouteDirect {
+		cfg.Xray.ExcludeDomains = append(cfg.Xray.ExcludeDomains, domain)
+		return true
+	}
+
+	if cfg.TunnelDirector.Tunnels == nil {
+		cfg.TunnelDirector.Tunnels = make(map[string]vpnconfig.TunnelConfig)
+	}
+	tunnel, ok := cfg.TunnelDirector.Tunnels[route]
+	if !ok {
+		tunnel = vpnconfig.TunnelConfig{
+			Clients: []string{},
+			Exclude: []string{},
+		}
+	}
+	tunnel.Domains = append(tunnel.Domains, domain)
+	cfg.TunnelDirector.Tunnels[route] = tunnel
+	return true
+}
+
+// RemoveDomain removes domain from the route's list. Returns false if the
+// domain was not present.
+func RemoveDomain(cfg *vpnconfig.VPNDirectorConfig, route, domain string) bool {
+	if route == RouteDirect {
+		if !containsString(cfg.Xray.ExcludeDomains, domain) {
+			return false
+		}
+		cfg.Xray.ExcludeDomains = removeString(cfg.Xray.ExcludeDomains, domain)
+		return true
+	}
+
+	tunnel, ok := cfg.TunnelDirector.Tunnels[route]
+	if !ok || !containsString(tunnel.Domains, domain) {
+		return false
+	}
+	tunnel.Domains = removeString(tunnel.Domains, domain)
+	cfg.TunnelDirector.Tunnels[route] = tunnel
+	return true
+}
+
+// Render builds the dnsmasq fragment for cfg. Output is deterministic:
+// routes and domains are sorted so unchanged config renders identical bytes.
+func Render(cfg *vpnconfig.VPNDirectorConfig) []byte {
+	lists := DomainLists(cfg)
+
+	routes := make([]string, 0, len(lists))
+	for route := range lists {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+
+	var sb strings.Builder
+	sb.WriteString("# Generated by VPN Director. Do not edit: changes are overwritten.\n")
+	for _, route := range routes {
+		domains := append([]string(nil), lists[route]...)
+		sort.Strings(domains)
+		sb.WriteString(fmt.Sprintf("# route: %s\n", route))
+		sets := SetName(route)
+		if route == RouteDirect {
+			sets += "," + BypassSet6
+		}
+		for _, d := range domains {
+			sb.WriteString(fmt.Sprintf("ipset=/%s/%s\n", d, sets))
+		}
+	}
+	return []byte(sb.String())
+}
+
+// NormalizeDomain lower-cases s, strips a leading "*." or "." and a trailing
+// dot, and validates the result as a DNS name. dnsmasq ipset= entries already
+// match all subdomains, so wildcards are not needed.
+func NormalizeDomain(s string) (string, error) {
+	d := strings.ToLower(strings.TrimSpace(s))
+	d = strings.TrimPrefix(d, "*.")
+	d = strings.TrimPrefix(d, ".")
+	d = strings.TrimSuffix(d, ".")
+
+	if d == "" {
+		return "", errors.New("empty domain")
+	}
+	if len(d) > 253 {
+		return "", errors.New("domain too long")
+	}
+
+	labels := strings.Split(d, ".")
+	if len(labels) < 2 {
+		return "", fmt.Errorf("domain %q must contain at least one dot", d)
+	}
+	for _, label := range labels {
+		if !isValidLabel(label) {
+			return "", fmt.Errorf("invalid domain %q", d)
+		}
+	}
+	return d, nil
+}
+
+func isValidLabel(label string) bool {
+	if label == "" || len(label) > 63 {
+		return false
+	}
+	if label[0] == '-' || label[len(label)-1] == '-' {
+		return false
+	}
+	for _, r := range label {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
+
+func removeString(slice []string, item string) []string {
+	result := make([]string, 0, len(slice))
+	for _, s := range slice {
+		if s != item {
+			result = append(result, s)
+		}
+	}
+	return result
+}
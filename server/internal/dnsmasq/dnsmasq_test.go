@@ -0,0 +1,131 @@
+package dnsmasq
+
+import (
+	"testing"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+)
+
+func TestSetName(t *testing.T) {
+	tests := map[string]string{
+		"direct": "VPD_DOM_BYPASS",
+		"wgc1":   "VPD_DOM_WGC1",
+		"ovpnc3": "VPD_DOM_OVPNC3",
+	}
+	for route, want := range tests {
+		if got := SetName(route); got != want {
+			t.Errorf("SetName(%q) = %q, want %q", route, got, want)
+		}
+	}
+}
+
+func TestValidRoute(t *testing.T) {
+	valid := []string{"direct", "wgc1", "wgc5", "ovpnc1", "ovpnc5"}
+	invalid := []string{"", "xray", "wgc0", "wgc6", "wgc10", "ovpnc", "eth0"}
+
+	for _, r := range valid {
+		if !ValidRoute(r) {
+			t.Errorf("ValidRoute(%q) = false, want true", r)
+		}
+	}
+	for _, r := range invalid {
+		if ValidRoute(r) {
+			t.Errorf("ValidRoute(%q) = true, want false", r)
+		}
+	}
+}
+
+func TestNormalizeDomain(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"Example.COM", "example.com", false},
+		{"*.netflix.com", "netflix.com", false},
+		{".cdn.example.org.", "cdn.example.org", false},
+		{"  my_host.lan  ", "my_host.lan", false},
+		{"", "", true},
+		{"localhost", "", true},
+		{"bad..domain", "", true},
+		{"-bad.com", "", true},
+		{"sp ace.com", "", true},
+		{"a/b.com", "", true},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeDomain(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NormalizeDomain(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("NormalizeDomain(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestAddRemoveDomain(t *testing.T) {
+	cfg := &vpnconfig.VPNDirectorConfig{}
+
+	if !AddDomain(cfg, "wgc1", "netflix.com") {
+		t.Fatal("expected first add to succeed")
+	}
+	if AddDomain(cfg, "wgc1", "netflix.com") {
+		t.Error("expected duplicate add to return false")
+	}
+	if AddDomain(cfg, "ovpnc1", "netflix.com") || RouteOf(cfg, "netflix.com") != "wgc1" {
+		t.Error("expected add on a second route to be refused")
+	}
+	tunnel := cfg.TunnelDirector.Tunnels["wgc1"]
+	if tunnel.Clients == nil || tunnel.Exclude == nil {
+		t.Error("expected new tunnel to have non-nil clients and exclude")
+	}
+
+	if !AddDomain(cfg, "direct", "bank.example") {
+		t.Fatal("expected direct add to succeed")
+	}
+	if len(cfg.Xray.ExcludeDomains) != 1 {
+		t.Errorf("expected exclude_domains to be set, got %v", cfg.Xray.ExcludeDomains)
+	}
+
+	if !RemoveDomain(cfg, "wgc1", "netflix.com") {
+		t.Error("expected remove to succeed")
+	}
+	if RemoveDomain(cfg, "wgc1", "netflix.com") {
+		t.Error("expected second remove to return false")
+	}
+	if RemoveDomain(cfg, "ovpnc1", "netflix.com") {
+		t.Error("expected remove from missing tunnel to return false")
+	}
+	if !RemoveDomain(cfg, "direct", "bank.example") || len(cfg.Xray.ExcludeDomains) != 0 {
+		t.Error("expected direct remove to empty the list")
+	}
+}
+
+func TestRender(t *testing.T) {
+	cfg := &vpnconfig.VPNDirectorConfig{
+		Xray: vpnconfig.XrayConfig{ExcludeDomains: []string{"bank.example"}},
+		TunnelDirector: vpnconfig.TunnelDirectorConfig{
+			Tunnels: map[string]vpnconfig.TunnelConfig{
+				"wgc1":   {Domains: []string{"nflxvideo.net", "netflix.com"}},
+				"ovpnc1": {Clients: []string{"192.168.50.10"}},
+			},
+		},
+	}
+
+	want := "# Generated by VPN Director. Do not edit: changes are overwritten.\n" +
+		"# route: direct\n" +
+		"ipset=/bank.example/VPD_DOM_BYPASS,VPD_DOM_BYPASS6\n" +
+		"# route: wgc1\n" +
+		"ipset=/netflix.com/VPD_DOM_WGC1\n" +
+		"ipset=/nflxvideo.net/VPD_DOM_WGC1\n"
+
+	if got := string(Render(cfg)); got != want {
+		t.Errorf("Render() =\n%s\nwant:\n%s", got, want)
+	}
+
+	// Render must not reorder the config slice in place
+	if cfg.TunnelDirector.Tunnels["wgc1"].Domains[0] != "nflxvideo.net" {
+		t.Error("Render mutated config domain order")
+	}
+}
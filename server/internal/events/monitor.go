@@ -0,0 +1,83 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// DefaultProbeInterval is how often the monitor checks the Xray process.
+const DefaultProbeInterval = time.Minute
+
+// ProcessChecker reports whether a process is running.
+type ProcessChecker interface {
+	ProcessRunning(name string) (bool, error)
+}
+
+// StateStore records events and reports the last recorded state.
+type StateStore interface {
+	Recorder
+	LastState(component string) (string, error)
+}
+
+// Monitor records Xray going up or down between explicit restarts, e.g.
+// after a crash. Only changes against the last recorded state are written.
+type Monitor struct {
+	store  StateStore
+	system ProcessChecker
+}
+
+// NewMonitor creates a monitor recording into store.
+func NewMonitor(store StateStore, system ProcessChecker) *Monitor {
+	return &Monitor{store: store, system: system}
+}
+
+// Run probes on an interval. Blocks until ctx is cancelled.
+func (m *Monitor) Run(ctx context.Context, interval time.Duration) {
+	slog.Info("Xray monitor started", "interval", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		m.Probe()
+
+		select {
+		case <-ctx.Done():
+			slog.Info("Xray monitor stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Probe checks the process once and records a state change.
+func (m *Monitor) Probe() {
+	running, err := m.system.ProcessRunning("xray")
+	if err != nil {
+		slog.Debug("Xray probe failed", "error", err)
+		return
+	}
+	state, message := StateDown, "Xray process is not running"
+	if running {
+		state, message = StateUp, "Xray process is running"
+	}
+
+	// The bot and the scripts record state too, so compare with the file
+	last, err := m.store.LastState(ComponentXray)
+	if err != nil {
+		slog.Warn("Failed to read event store", "error", err)
+		return
+	}
+	if last == state {
+		return
+	}
+	m.store.Record(Event{
+		Kind:      KindXrayProcess,
+		OK:        running,
+		Component: ComponentXray,
+		State:     state,
+		Source:    "monitor",
+		Message:   message,
+	})
+}
@@ -0,0 +1,60 @@
+package events
+
+import (
+	"errors"
+	"testing"
+)
+
+type mockProcess struct {
+	running bool
+	err     error
+}
+
+func (m *mockProcess) ProcessRunning(name string) (bool, error) {
+	return m.running, m.err
+}
+
+func TestMonitor_RecordsChanges(t *testing.T) {
+	s := newTestStore(t)
+	proc := &mockProcess{running: true}
+	m := NewMonitor(s, proc)
+
+	m.Probe()
+	m.Probe() // unchanged: not recorded again
+	proc.running = false
+	m.Probe()
+
+	list, _ := s.List(Filter{Component: ComponentXray})
+	if len(list) != 2 {
+		t.Fatalf("expected 2 events, got %+v", list)
+	}
+	if list[0].State != StateDown || list[0].OK || list[0].Kind != KindXrayProcess {
+		t.Errorf("unexpected latest event: %+v", list[0])
+	}
+	if list[1].State != StateUp || !list[1].OK {
+		t.Errorf("unexpected first event: %+v", list[1])
+	}
+}
+
+func TestMonitor_FollowsOtherWriters(t *testing.T) {
+	s := newTestStore(t)
+	// The bot recorded a successful restart; the process is up, nothing new
+	s.Record(Event{Kind: KindXrayRestart, OK: true, Component: ComponentXray, State: StateUp, Source: "bot"})
+
+	NewMonitor(s, &mockProcess{running: true}).Probe()
+
+	list, _ := s.List(Filter{})
+	if len(list) != 1 {
+		t.Errorf("expected no new event, got %+v", list)
+	}
+}
+
+func TestMonitor_ProbeError(t *testing.T) {
+	s := newTestStore(t)
+	NewMonitor(s, &mockProcess{err: errors.New("pidof failed")}).Probe()
+
+	list, _ := s.List(Filter{})
+	if len(list) != 0 {
+		t.Errorf("expected nothing recorded on probe error, got %+v", list)
+	}
+}
@@ -0,0 +1,48 @@
+package events
+
+import (
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/service"
+)
+
+// Compile-time interface check
+var _ Timeline = (*Service)(nil)
+
+// Service reads and writes the timeline in the configured data directory,
+// which is looked up on every call so a data_dir change takes effect
+// without a restart.
+type Service struct {
+	stores *service.StoreCache[*Store]
+}
+
+// NewService creates a new Service.
+func NewService(config service.ConfigStore) *Service {
+	return &Service{stores: service.NewStoreCache(config, func(dataDir string) *Store {
+		return NewStore(StorePath(dataDir))
+	})}
+}
+
+func (s *Service) store() *Store {
+	return s.stores.Get()
+}
+
+// Record appends an event and logs a failure.
+func (s *Service) Record(e Event) {
+	s.store().Record(e)
+}
+
+// List returns matching events, newest first.
+func (s *Service) List(f Filter) ([]Event, error) {
+	return s.store().List(f)
+}
+
+// Uptime reports every component's uptime between from and to.
+func (s *Service) Uptime(from, to time.Time) ([]Uptime, error) {
+	return s.store().Uptime(from, to)
+}
+
+// LastState returns the most recent state recorded for a component.
+func (s *Service) LastState(component string) (string, error) {
+	return s.store().LastState(component)
+}
@@ -0,0 +1,311 @@
+// Package events keeps a persisted timeline of service events (apply runs,
+// Xray restarts, server switches, WAN changes, ipset updates, logins) and
+// computes per-component uptime from it. The bot, the Web UI and the shell
+// scripts all append to the same JSON Lines file in the data directory.
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// StoreFile is the timeline file inside the data directory.
+	StoreFile = "events.jsonl"
+
+	// Retention is how long events are kept.
+	Retention = 90 * 24 * time.Hour
+
+	// maxEvents caps the timeline regardless of age.
+	maxEvents = 5000
+	// maxSize is the file size that triggers dropping old events. Compaction
+	// shrinks the file to half of it, so it does not run on every append.
+	maxSize = 1 << 20
+	// maxMessage bounds the message length (script output can be long).
+	maxMessage = 300
+)
+
+// Event kinds.
+const (
+	KindApply        = "apply"
+	KindXrayApply    = "xray_apply"
+	KindRestart      = "restart"
+	KindXrayRestart  = "xray_restart"
+	KindStop         = "stop"
+	KindServerSwitch = "server_switch"
+	KindWAN          = "wan"
+	KindIPSetUpdate  = "ipset_update"
+	KindLogin        = "login"
+	KindXrayProcess  = "xray_process"
+)
+
+// Components with an up/down state, in report order.
+const (
+	ComponentVPNDirector = "vpn-director"
+	ComponentXray        = "xray"
+	ComponentWAN         = "wan"
+	ComponentIPSets      = "ipsets"
+)
+
+// Components lists every component uptime is reported for.
+var Components = []string{ComponentVPNDirector, ComponentXray, ComponentWAN, ComponentIPSets}
+
+// States of a component.
+const (
+	StateUp   = "up"
+	StateDown = "down"
+)
+
+// Event is one timeline entry. Component and State are set only for events
+// that change a component's state; uptime is computed from those.
+type Event struct {
+	Time      time.Time `json:"time"`
+	Kind      string    `json:"kind"`
+	OK        bool      `json:"ok"`
+	Component string    `json:"component,omitempty"`
+	State     string    `json:"state,omitempty"`
+	Source    string    `json:"source,omitempty"`
+	Message   string    `json:"message,omitempty"`
+}
+
+// Recorder accepts events. Recording is best effort: failures are logged,
+// never returned, so they cannot break the operation being recorded.
+type Recorder interface {
+	Record(e Event)
+}
+
+// Timeline is the read and write side of the event store.
+type Timeline interface {
+	Recorder
+	List(f Filter) ([]Event, error)
+	Uptime(from, to time.Time) ([]Uptime, error)
+}
+
+// Filter selects events for List. Zero fields match everything.
+type Filter struct {
+	Since     time.Time
+	Until     time.Time
+	Kind      string
+	Component string
+	Limit     int
+}
+
+func (f Filter) match(e Event) bool {
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && e.Time.After(f.Until) {
+		return false
+	}
+	if f.Kind != "" && e.Kind != f.Kind {
+		return false
+	}
+	if f.Component != "" && e.Component != f.Component {
+		return false
+	}
+	return true
+}
+
+// Compile-time interface check
+var _ Timeline = (*Store)(nil)
+
+// Store is the append-only timeline file. Each event is one line written
+// with O_APPEND, so the bot, the Web UI and the shell hooks can append
+// concurrently. Old events are dropped by an atomic rewrite once the file
+// grows past maxSize.
+type Store struct {
+	path    string
+	maxSize int64
+	mu      sync.Mutex
+	now     func() time.Time
+}
+
+// NewStore creates a store backed by the file at path.
+func NewStore(path string) *Store {
+	return &Store{path: path, maxSize: maxSize, now: time.Now}
+}
+
+// StorePath returns the timeline location inside dataDir.
+func StorePath(dataDir string) string {
+	return filepath.Join(dataDir, StoreFile)
+}
+
+// Append writes an event. A zero Time is set to now.
+func (s *Store) Append(e Event) error {
+	if e.Time.IsZero() {
+		e.Time = s.now()
+	}
+	e.Time = e.Time.UTC()
+	if len(e.Message) > maxMessage {
+		cut := maxMessage
+		for cut > 0 && !utf8.RuneStart(e.Message[cut]) {
+			cut--
+		}
+		e.Message = e.Message[:cut] + "…"
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("open event store: %w", err)
+	}
+	_, err = f.Write(append(line, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("write event: %w", err)
+	}
+
+	if info, err := os.Stat(s.path); err == nil && info.Size() > s.maxSize {
+		if err := s.compact(); err != nil {
+			slog.Warn("Failed to compact event store", "path", s.path, "error", err)
+		}
+	}
+	return nil
+}
+
+// Record appends an event and logs a failure.
+func (s *Store) Record(e Event) {
+	if err := s.Append(e); err != nil {
+		slog.Warn("Failed to record event", "kind", e.Kind, "error", err)
+	}
+}
+
+// List returns matching events, newest first.
+func (s *Store) List(f Filter) ([]Event, error) {
+	all, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Event, 0, len(all))
+	for i := len(all) - 1; i >= 0; i-- {
+		if !f.match(all[i]) {
+			continue
+		}
+		out = append(out, all[i])
+		if f.Limit > 0 && len(out) == f.Limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+// Uptime reports every component's uptime between from and to.
+func (s *Store) Uptime(from, to time.Time) ([]Uptime, error) {
+	all, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Uptime, 0, len(Components))
+	for _, c := range Components {
+		out = append(out, ComputeUptime(all, c, from, to))
+	}
+	return out, nil
+}
+
+// LastState returns the most recent state recorded for a component, or ""
+// if there is none.
+func (s *Store) LastState(component string) (string, error) {
+	all, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	for i := len(all) - 1; i >= 0; i-- {
+		if all[i].Component == component && all[i].State != "" {
+			return all[i].State, nil
+		}
+	}
+	return "", nil
+}
+
+// load reads all events, oldest first. Lines that do not parse (e.g. a write
+// cut short by a power loss) are skipped.
+func (s *Store) load() ([]Event, error) {
+	raw, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read event store: %w", err)
+	}
+
+	var all []Event
+	for _, line := range bytes.Split(raw, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal(line, &e); err != nil || e.Time.IsZero() || e.Kind == "" {
+			continue
+		}
+		all = append(all, e)
+	}
+	// Writers append in time order, but clocks and processes may interleave
+	sort.SliceStable(all, func(i, j int) bool { return all[i].Time.Before(all[j].Time) })
+	return all, nil
+}
+
+// compact drops events older than Retention, beyond maxEvents and beyond half
+// of maxSize, oldest first. Must be called with s.mu held. An event appended
+// by another process between the read and the rename is lost; compaction is
+// rare enough for that to be fine.
+func (s *Store) compact() error {
+	all, err := s.load()
+	if err != nil {
+		return err
+	}
+	cutoff := s.now().Add(-Retention)
+	i := 0
+	for i < len(all) && all[i].Time.Before(cutoff) {
+		i++
+	}
+	all = all[i:]
+	if len(all) > maxEvents {
+		all = all[len(all)-maxEvents:]
+	}
+
+	lines := make([][]byte, len(all))
+	var size int64
+	for i, e := range all {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		lines[i] = append(line, '\n')
+		size += int64(len(lines[i]))
+	}
+	for len(lines) > 0 && size > s.maxSize/2 {
+		size -= int64(len(lines[0]))
+		lines = lines[1:]
+	}
+
+	var buf bytes.Buffer
+	for _, line := range lines {
+		buf.Write(line)
+	}
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.path)
+}
@@ -0,0 +1,146 @@
+package events
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var base = time.Date(2026, 1, 11, 12, 0, 0, 0, time.UTC)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	s := NewStore(StorePath(t.TempDir()))
+	s.now = func() time.Time { return base }
+	return s
+}
+
+func TestStore_AppendAndList(t *testing.T) {
+	s := newTestStore(t)
+
+	s.Record(Event{Time: base.Add(-2 * time.Hour), Kind: KindApply, OK: true, Component: ComponentVPNDirector, State: StateUp})
+	s.Record(Event{Time: base.Add(-time.Hour), Kind: KindLogin, OK: false, Message: "admin from 10.0.0.2"})
+	s.Record(Event{Kind: KindXrayRestart, OK: true, Component: ComponentXray, State: StateUp})
+
+	all, err := s.List(Filter{})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(all))
+	}
+	if all[0].Kind != KindXrayRestart || !all[0].Time.Equal(base) {
+		t.Errorf("expected newest first with default time, got %+v", all[0])
+	}
+
+	logins, _ := s.List(Filter{Kind: KindLogin})
+	if len(logins) != 1 || logins[0].Message != "admin from 10.0.0.2" {
+		t.Errorf("unexpected kind filter result: %+v", logins)
+	}
+
+	recent, _ := s.List(Filter{Since: base.Add(-90 * time.Minute)})
+	if len(recent) != 2 {
+		t.Errorf("expected 2 events since filter, got %d", len(recent))
+	}
+
+	xray, _ := s.List(Filter{Component: ComponentXray, Limit: 5})
+	if len(xray) != 1 {
+		t.Errorf("expected 1 xray event, got %d", len(xray))
+	}
+
+	limited, _ := s.List(Filter{Limit: 2})
+	if len(limited) != 2 {
+		t.Errorf("expected 2 events with limit, got %d", len(limited))
+	}
+}
+
+func TestStore_SkipsBadLines(t *testing.T) {
+	s := newTestStore(t)
+	content := `{"time":"2026-01-11T10:00:00Z","kind":"wan","ok":true,"component":"wan","state":"up","source":"wan-event"}
+not json
+{"time":"2026-01-11T11:00:00Z","kind":"ipset_update","ok":false,"component":"ipsets","state":"down"}
+{"kind":"missing time"}
+{"time":"2026-01-11T11:30:00Z","kind":"apply","ok":tr`
+	if err := os.WriteFile(s.path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := s.List(Filter{})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(all) != 2 || all[0].Kind != KindIPSetUpdate || all[1].Source != "wan-event" {
+		t.Errorf("unexpected events: %+v", all)
+	}
+}
+
+func TestStore_Missing(t *testing.T) {
+	s := NewStore(filepath.Join(t.TempDir(), "none.jsonl"))
+	all, err := s.List(Filter{})
+	if err != nil || len(all) != 0 {
+		t.Errorf("expected empty list, got %v, %v", all, err)
+	}
+	state, err := s.LastState(ComponentXray)
+	if err != nil || state != "" {
+		t.Errorf("expected no state, got %q, %v", state, err)
+	}
+}
+
+func TestStore_LastState(t *testing.T) {
+	s := newTestStore(t)
+	s.Record(Event{Time: base.Add(-time.Hour), Kind: KindXrayRestart, Component: ComponentXray, State: StateDown})
+	s.Record(Event{Time: base.Add(-30 * time.Minute), Kind: KindXrayProcess, Component: ComponentXray, State: StateUp})
+	s.Record(Event{Time: base.Add(-10 * time.Minute), Kind: KindStop, Component: ComponentVPNDirector})
+
+	state, err := s.LastState(ComponentXray)
+	if err != nil || state != StateUp {
+		t.Errorf("expected up, got %q, %v", state, err)
+	}
+	state, _ = s.LastState(ComponentVPNDirector)
+	if state != "" {
+		t.Errorf("expected no vpn-director state, got %q", state)
+	}
+}
+
+func TestStore_TruncatesMessage(t *testing.T) {
+	s := newTestStore(t)
+	s.Record(Event{Kind: KindApply, Message: strings.Repeat("x", maxMessage*2)})
+
+	all, _ := s.List(Filter{})
+	if len(all) != 1 || len([]rune(all[0].Message)) != maxMessage+1 {
+		t.Errorf("expected truncated message, got %d runes", len([]rune(all[0].Message)))
+	}
+}
+
+func TestStore_Compact(t *testing.T) {
+	s := newTestStore(t)
+	s.maxSize = 4096
+
+	old := Event{Time: base.Add(-Retention - time.Hour), Kind: KindApply, OK: true}
+	if err := s.Append(old); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		e := Event{Time: base.Add(-time.Duration(100-i) * time.Second), Kind: KindLogin, Message: "admin from 192.168.50.10"}
+		if err := s.Append(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() > s.maxSize {
+		t.Errorf("expected file below %d bytes, got %d", s.maxSize, info.Size())
+	}
+	if apply, _ := s.List(Filter{Kind: KindApply}); len(apply) != 0 {
+		t.Errorf("expected expired event to be dropped, got %+v", apply)
+	}
+	all, _ := s.List(Filter{})
+	if len(all) == 0 || all[0].Time != base.Add(-time.Second) {
+		t.Errorf("expected newest event to survive, got %+v", all)
+	}
+}
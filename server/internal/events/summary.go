@@ -0,0 +1,185 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SummaryWindow is the period covered by the weekly summary.
+const SummaryWindow = 7 * 24 * time.Hour
+
+// Notifier delivers the summary to users.
+type Notifier interface {
+	Broadcast(ctx context.Context, text string)
+}
+
+// Schedule is a weekday and time of day in local time.
+type Schedule struct {
+	Weekday time.Weekday
+	Hour    int
+	Minute  int
+}
+
+// ParseSchedule parses "<weekday> HH:MM", e.g. "mon 09:00". Weekdays may be
+// abbreviated to three letters and are case-insensitive.
+func ParseSchedule(s string) (Schedule, error) {
+	day, clock, ok := strings.Cut(strings.TrimSpace(s), " ")
+	if !ok {
+		return Schedule{}, fmt.Errorf("invalid schedule %q: want \"<weekday> HH:MM\"", s)
+	}
+	var sched Schedule
+	found := false
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if l := strings.ToLower(day); l == name || l == name[:3] {
+			sched.Weekday, found = d, true
+			break
+		}
+	}
+	if !found {
+		return Schedule{}, fmt.Errorf("invalid weekday %q", day)
+	}
+	t, err := time.Parse("15:04", strings.TrimSpace(clock))
+	if err != nil {
+		return Schedule{}, fmt.Errorf("invalid time %q: want HH:MM", clock)
+	}
+	sched.Hour, sched.Minute = t.Hour(), t.Minute()
+	return sched, nil
+}
+
+// Next returns the first scheduled time strictly after t, in t's location.
+func (s Schedule) Next(t time.Time) time.Time {
+	next := time.Date(t.Year(), t.Month(), t.Day(), s.Hour, s.Minute, 0, 0, t.Location())
+	for next.Weekday() != s.Weekday || !next.After(t) {
+		next = time.Date(next.Year(), next.Month(), next.Day()+1, s.Hour, s.Minute, 0, 0, t.Location())
+	}
+	return next
+}
+
+// WeeklySummary broadcasts uptime and event counts for the past week.
+type WeeklySummary struct {
+	store    Timeline
+	notifier Notifier
+	schedule Schedule
+	now      func() time.Time
+}
+
+// NewWeeklySummary creates a summary sender.
+func NewWeeklySummary(store Timeline, notifier Notifier, schedule Schedule) *WeeklySummary {
+	return &WeeklySummary{store: store, notifier: notifier, schedule: schedule, now: time.Now}
+}
+
+// Run sends the summary on schedule. Blocks until ctx is cancelled.
+func (w *WeeklySummary) Run(ctx context.Context) {
+	for {
+		next := w.schedule.Next(w.now())
+		slog.Info("Weekly summary scheduled", "at", next)
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			slog.Info("Weekly summary stopped")
+			return
+		case <-timer.C:
+		}
+
+		text, err := w.Build(next.Add(-SummaryWindow), next)
+		if err != nil {
+			slog.Warn("Failed to build weekly summary", "error", err)
+			continue
+		}
+		w.notifier.Broadcast(ctx, text)
+	}
+}
+
+// Build formats the summary for the window between from and to.
+func (w *WeeklySummary) Build(from, to time.Time) (string, error) {
+	uptime, err := w.store.Uptime(from, to)
+	if err != nil {
+		return "", err
+	}
+	list, err := w.store.List(Filter{Since: from, Until: to})
+	if err != nil {
+		return "", err
+	}
+	return FormatSummary(from, to, uptime, list), nil
+}
+
+// summaryCounts are the event kinds counted in the summary.
+var summaryCounts = []struct {
+	kind   string
+	label  string
+	failed string // how events with OK=false are described
+}{
+	{KindApply, "Apply runs", "failed"},
+	{KindRestart, "Restarts", "failed"},
+	{KindXrayRestart, "Xray restarts", "failed"},
+	{KindServerSwitch, "Server switches", "failed"},
+	{KindIPSetUpdate, "ipset updates", "failed"},
+	{KindWAN, "WAN events", "down"},
+	{KindLogin, "Web UI logins", "failed"},
+}
+
+// FormatSummary renders the weekly summary as plain text.
+func FormatSummary(from, to time.Time, uptime []Uptime, list []Event) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "📊 Weekly summary %s – %s\n\n", from.Format("Jan 2"), to.Format("Jan 2"))
+
+	sb.WriteString("Uptime:\n")
+	for _, u := range uptime {
+		if u.Percent == nil {
+			fmt.Fprintf(&sb, "  %s: no data\n", u.Component)
+			continue
+		}
+		fmt.Fprintf(&sb, "  %s: %s%%", u.Component, strconv.FormatFloat(*u.Percent, 'f', 2, 64))
+		if u.Outages > 0 || u.DowntimeSeconds > 0 {
+			fmt.Fprintf(&sb, " (%d outage(s), %s down)", u.Outages, FormatDuration(time.Duration(u.DowntimeSeconds)*time.Second))
+		}
+		sb.WriteString("\n")
+	}
+
+	total := map[string]int{}
+	failed := map[string]int{}
+	for _, e := range list {
+		total[e.Kind]++
+		if !e.OK {
+			failed[e.Kind]++
+		}
+	}
+	sb.WriteString("\nEvents:\n")
+	listed := false
+	for _, c := range summaryCounts {
+		if total[c.kind] == 0 {
+			continue
+		}
+		listed = true
+		fmt.Fprintf(&sb, "  %s: %d", c.label, total[c.kind])
+		if failed[c.kind] > 0 {
+			fmt.Fprintf(&sb, " (%d %s)", failed[c.kind], c.failed)
+		}
+		sb.WriteString("\n")
+	}
+	if !listed {
+		sb.WriteString("  none\n")
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// FormatDuration renders d as "2d 3h", "1h 5m", "12m" or "40s".
+func FormatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd %dh", d/(24*time.Hour), d%(24*time.Hour)/time.Hour)
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh %dm", d/time.Hour, d%time.Hour/time.Minute)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+	return fmt.Sprintf("%ds", d/time.Second)
+}
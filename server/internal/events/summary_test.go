@@ -0,0 +1,138 @@
+package events
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Schedule
+		wantErr bool
+	}{
+		{"mon 09:00", Schedule{Weekday: time.Monday, Hour: 9}, false},
+		{"Sunday 18:30", Schedule{Weekday: time.Sunday, Hour: 18, Minute: 30}, false},
+		{" FRI 7:05 ", Schedule{Weekday: time.Friday, Hour: 7, Minute: 5}, false},
+		{"mon", Schedule{}, true},
+		{"funday 09:00", Schedule{}, true},
+		{"mon 25:00", Schedule{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSchedule(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSchedule(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseSchedule(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	s := Schedule{Weekday: time.Monday, Hour: 9}
+	// base is Sunday 2026-01-11 12:00 UTC
+	if got := s.Next(base); !got.Equal(time.Date(2026, 1, 12, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected next Monday, got %v", got)
+	}
+	monday := time.Date(2026, 1, 12, 9, 0, 0, 0, time.UTC)
+	if got := s.Next(monday); !got.Equal(monday.AddDate(0, 0, 7)) {
+		t.Errorf("expected a week later at the exact time, got %v", got)
+	}
+	if got := s.Next(monday.Add(-time.Minute)); !got.Equal(monday) {
+		t.Errorf("expected same day, got %v", got)
+	}
+}
+
+func TestFormatSummary(t *testing.T) {
+	p := 99.5
+	full := 100.0
+	uptime := []Uptime{
+		{Component: ComponentVPNDirector, Percent: &full},
+		{Component: ComponentWAN, Percent: &p, Outages: 1, DowntimeSeconds: 3000},
+		{Component: ComponentIPSets},
+	}
+	list := []Event{
+		{Kind: KindApply, OK: true},
+		{Kind: KindApply, OK: false},
+		{Kind: KindWAN, OK: false},
+		{Kind: KindLogin, OK: true},
+		{Kind: KindXrayProcess, OK: true}, // not counted
+	}
+
+	text := FormatSummary(base.Add(-SummaryWindow), base, uptime, list)
+
+	for _, want := range []string{
+		"Weekly summary Jan 4 – Jan 11",
+		"vpn-director: 100.00%",
+		"wan: 99.50% (1 outage(s), 50m down)",
+		"ipsets: no data",
+		"Apply runs: 2 (1 failed)",
+		"WAN events: 1 (1 down)",
+		"Web UI logins: 1",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "xray_process") {
+		t.Errorf("unexpected process events in summary:\n%s", text)
+	}
+}
+
+func TestFormatSummary_NoEvents(t *testing.T) {
+	text := FormatSummary(base.Add(-SummaryWindow), base, nil, nil)
+	if !strings.HasSuffix(text, "Events:\n  none") {
+		t.Errorf("expected empty events section, got:\n%s", text)
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := map[time.Duration]string{
+		40 * time.Second:                "40s",
+		12*time.Minute + 10*time.Second: "12m",
+		65 * time.Minute:                "1h 5m",
+		51 * time.Hour:                  "2d 3h",
+	}
+	for d, want := range tests {
+		if got := FormatDuration(d); got != want {
+			t.Errorf("FormatDuration(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+type mockNotifier struct {
+	texts []string
+}
+
+func (m *mockNotifier) Broadcast(_ context.Context, text string) {
+	m.texts = append(m.texts, text)
+}
+
+func TestWeeklySummary_Build(t *testing.T) {
+	s := newTestStore(t)
+	s.Record(Event{Time: base.Add(-SummaryWindow - time.Hour), Kind: KindServerSwitch, OK: true})
+	s.Record(Event{Time: base.Add(-time.Hour), Kind: KindServerSwitch, OK: true})
+
+	text, err := NewWeeklySummary(s, &mockNotifier{}, Schedule{}).Build(base.Add(-SummaryWindow), base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text, "Server switches: 1") {
+		t.Errorf("expected only events in the window, got:\n%s", text)
+	}
+}
+
+func TestWeeklySummary_RunStops(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	n := &mockNotifier{}
+	NewWeeklySummary(newTestStore(t), n, Schedule{Weekday: time.Monday, Hour: 9}).Run(ctx)
+	if len(n.texts) != 0 {
+		t.Errorf("expected no broadcast after cancel, got %v", n.texts)
+	}
+}
@@ -0,0 +1,71 @@
+package events
+
+import (
+	"sort"
+	"time"
+)
+
+// Uptime is a component's availability within a window. Time before the
+// first recorded state is unknown and left out, so Percent is nil when the
+// window holds no state at all.
+type Uptime struct {
+	Component       string   `json:"component"`
+	State           string   `json:"state,omitempty"` // state at the end of the window
+	Percent         *float64 `json:"percent"`
+	KnownSeconds    int64    `json:"known_seconds"`
+	DowntimeSeconds int64    `json:"downtime_seconds"`
+	Outages         int      `json:"outages"` // up → down transitions in the window
+}
+
+// ComputeUptime derives a component's uptime between from and to from its
+// state changes. events may be in any order.
+func ComputeUptime(events []Event, component string, from, to time.Time) Uptime {
+	var changes []Event
+	for _, e := range events {
+		if e.Component == component && (e.State == StateUp || e.State == StateDown) {
+			changes = append(changes, e)
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Time.Before(changes[j].Time) })
+
+	u := Uptime{Component: component}
+	var known, down time.Duration
+	state := ""
+	cursor := from
+	account := func(until time.Time) {
+		if state == "" || !until.After(cursor) {
+			return
+		}
+		d := until.Sub(cursor)
+		known += d
+		if state == StateDown {
+			down += d
+		}
+	}
+
+	for _, e := range changes {
+		if !e.Time.After(from) {
+			state = e.State
+			continue
+		}
+		if e.Time.After(to) {
+			break
+		}
+		account(e.Time)
+		if e.State == StateDown && state != StateDown {
+			u.Outages++
+		}
+		state = e.State
+		cursor = e.Time
+	}
+	account(to)
+
+	u.State = state
+	u.KnownSeconds = int64(known / time.Second)
+	u.DowntimeSeconds = int64(down / time.Second)
+	if known > 0 {
+		p := float64(known-down) / float64(known) * 100
+		u.Percent = &p
+	}
+	return u
+}
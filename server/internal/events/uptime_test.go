@@ -0,0 +1,84 @@
+package events
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestComputeUptime(t *testing.T) {
+	from := base
+	to := base.Add(10 * time.Hour)
+	list := []Event{
+		// Before the window: sets the initial state
+		{Time: from.Add(-time.Hour), Component: ComponentWAN, State: StateUp},
+		{Time: from.Add(2 * time.Hour), Component: ComponentWAN, State: StateDown},
+		{Time: from.Add(3 * time.Hour), Component: ComponentWAN, State: StateUp},
+		// A repeated down is one outage
+		{Time: from.Add(8 * time.Hour), Component: ComponentWAN, State: StateDown},
+		{Time: from.Add(8*time.Hour + 30*time.Minute), Component: ComponentWAN, State: StateDown},
+		// After the window: ignored
+		{Time: to.Add(time.Hour), Component: ComponentWAN, State: StateUp},
+		// Other component and stateless events: ignored
+		{Time: from.Add(time.Hour), Component: ComponentXray, State: StateDown},
+		{Time: from.Add(time.Hour), Kind: KindLogin},
+	}
+
+	u := ComputeUptime(list, ComponentWAN, from, to)
+	if u.Percent == nil {
+		t.Fatal("expected percent")
+	}
+	// Down 2h-3h and 8h-10h: 3h of 10h
+	if math.Abs(*u.Percent-70) > 0.001 {
+		t.Errorf("expected 70%%, got %v", *u.Percent)
+	}
+	if u.Outages != 2 {
+		t.Errorf("expected 2 outages, got %d", u.Outages)
+	}
+	if u.DowntimeSeconds != 3*3600 || u.KnownSeconds != 10*3600 {
+		t.Errorf("unexpected seconds: %+v", u)
+	}
+	if u.State != StateDown {
+		t.Errorf("expected down at the end, got %q", u.State)
+	}
+}
+
+func TestComputeUptime_UnknownStart(t *testing.T) {
+	from := base
+	to := base.Add(4 * time.Hour)
+	list := []Event{
+		{Time: from.Add(2 * time.Hour), Component: ComponentIPSets, State: StateUp},
+		{Time: from.Add(3 * time.Hour), Component: ComponentIPSets, State: StateDown},
+	}
+
+	u := ComputeUptime(list, ComponentIPSets, from, to)
+	if u.KnownSeconds != 2*3600 || u.Percent == nil || *u.Percent != 50 {
+		t.Errorf("expected 50%% over 2h, got %+v", u)
+	}
+}
+
+func TestComputeUptime_NoData(t *testing.T) {
+	u := ComputeUptime(nil, ComponentXray, base, base.Add(time.Hour))
+	if u.Percent != nil || u.KnownSeconds != 0 || u.State != "" {
+		t.Errorf("expected no data, got %+v", u)
+	}
+}
+
+func TestStore_Uptime(t *testing.T) {
+	s := newTestStore(t)
+	s.Record(Event{Time: base.Add(-2 * time.Hour), Kind: KindApply, Component: ComponentVPNDirector, State: StateUp})
+
+	list, err := s.Uptime(base.Add(-time.Hour), base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != len(Components) {
+		t.Fatalf("expected %d components, got %d", len(Components), len(list))
+	}
+	if list[0].Component != ComponentVPNDirector || list[0].Percent == nil || *list[0].Percent != 100 {
+		t.Errorf("expected vpn-director 100%%, got %+v", list[0])
+	}
+	if list[1].Percent != nil {
+		t.Errorf("expected no xray data, got %+v", list[1])
+	}
+}
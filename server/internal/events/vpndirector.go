@@ -0,0 +1,76 @@
+package events
+
+import (
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/service"
+)
+
+// Compile-time interface check
+var _ service.VPNDirector = (*VPNDirector)(nil)
+
+// VPNDirector records the outcome of apply/restart/stop runs on the
+// timeline and passes every call through to the wrapped service.
+type VPNDirector struct {
+	vpn    service.VPNDirector
+	rec    Recorder
+	source string
+}
+
+// NewVPNDirector wraps vpn. source names the caller (e.g. "bot", "webui").
+// A nil rec returns vpn unchanged.
+func NewVPNDirector(vpn service.VPNDirector, rec Recorder, source string) service.VPNDirector {
+	if rec == nil {
+		return vpn
+	}
+	return &VPNDirector{vpn: vpn, rec: rec, source: source}
+}
+
+// Status passes through without recording.
+func (v *VPNDirector) Status() (string, error) {
+	return v.vpn.Status()
+}
+
+// Apply records an apply run.
+func (v *VPNDirector) Apply() error {
+	err := v.vpn.Apply()
+	v.record(KindApply, ComponentVPNDirector, err, StateUp, StateDown)
+	return err
+}
+
+// ApplyXray records a re-apply of the Xray rules.
+func (v *VPNDirector) ApplyXray() error {
+	err := v.vpn.ApplyXray()
+	v.record(KindXrayApply, ComponentXray, err, StateUp, StateDown)
+	return err
+}
+
+// Restart records a full restart.
+func (v *VPNDirector) Restart() error {
+	err := v.vpn.Restart()
+	v.record(KindRestart, ComponentVPNDirector, err, StateUp, StateDown)
+	return err
+}
+
+// RestartXray records an Xray restart.
+func (v *VPNDirector) RestartXray() error {
+	err := v.vpn.RestartXray()
+	v.record(KindXrayRestart, ComponentXray, err, StateUp, StateDown)
+	return err
+}
+
+// Stop records a stop. A successful stop takes VPN Director down; after a
+// failed one the state is unknown, so it is left as it was.
+func (v *VPNDirector) Stop() error {
+	err := v.vpn.Stop()
+	v.record(KindStop, ComponentVPNDirector, err, StateDown, "")
+	return err
+}
+
+// record appends the outcome with the component state it leaves behind.
+func (v *VPNDirector) record(kind, component string, err error, okState, failState string) {
+	e := Event{Kind: kind, OK: err == nil, Component: component, State: okState, Source: v.source}
+	if err != nil {
+		e.State = failState
+		e.Message = err.Error()
+	}
+	v.rec.Record(e)
+}
@@ -0,0 +1,85 @@
+package events
+
+import (
+	"errors"
+	"testing"
+)
+
+type mockVPN struct {
+	err   error
+	calls []string
+}
+
+func (m *mockVPN) Status() (string, error) { m.calls = append(m.calls, "status"); return "ok", m.err }
+func (m *mockVPN) Apply() error            { m.calls = append(m.calls, "apply"); return m.err }
+func (m *mockVPN) ApplyXray() error        { m.calls = append(m.calls, "apply xray"); return m.err }
+func (m *mockVPN) Restart() error          { m.calls = append(m.calls, "restart"); return m.err }
+func (m *mockVPN) RestartXray() error      { m.calls = append(m.calls, "restart xray"); return m.err }
+func (m *mockVPN) Stop() error             { m.calls = append(m.calls, "stop"); return m.err }
+
+type mockRecorder struct {
+	events []Event
+}
+
+func (m *mockRecorder) Record(e Event) { m.events = append(m.events, e) }
+
+func TestVPNDirector_RecordsOutcome(t *testing.T) {
+	vpn := &mockVPN{}
+	rec := &mockRecorder{}
+	wrapped := NewVPNDirector(vpn, rec, "webui")
+
+	wrapped.Status()
+	wrapped.Apply()
+	wrapped.ApplyXray()
+	wrapped.Restart()
+	wrapped.RestartXray()
+	wrapped.Stop()
+
+	if len(vpn.calls) != 6 {
+		t.Fatalf("expected all calls passed through, got %v", vpn.calls)
+	}
+	want := []Event{
+		{Kind: KindApply, OK: true, Component: ComponentVPNDirector, State: StateUp, Source: "webui"},
+		{Kind: KindXrayApply, OK: true, Component: ComponentXray, State: StateUp, Source: "webui"},
+		{Kind: KindRestart, OK: true, Component: ComponentVPNDirector, State: StateUp, Source: "webui"},
+		{Kind: KindXrayRestart, OK: true, Component: ComponentXray, State: StateUp, Source: "webui"},
+		{Kind: KindStop, OK: true, Component: ComponentVPNDirector, State: StateDown, Source: "webui"},
+	}
+	if len(rec.events) != len(want) {
+		t.Fatalf("expected %d events (status is not recorded), got %+v", len(want), rec.events)
+	}
+	for i, e := range want {
+		if rec.events[i] != e {
+			t.Errorf("event %d: expected %+v, got %+v", i, e, rec.events[i])
+		}
+	}
+}
+
+func TestVPNDirector_RecordsFailure(t *testing.T) {
+	vpn := &mockVPN{err: errors.New("apply failed (exit 1): boom")}
+	rec := &mockRecorder{}
+	wrapped := NewVPNDirector(vpn, rec, "bot")
+
+	if err := wrapped.RestartXray(); err != vpn.err {
+		t.Errorf("expected error passed through, got %v", err)
+	}
+	wrapped.Stop()
+
+	if len(rec.events) != 2 {
+		t.Fatalf("expected 2 events, got %+v", rec.events)
+	}
+	e := rec.events[0]
+	if e.OK || e.State != StateDown || e.Message != "apply failed (exit 1): boom" {
+		t.Errorf("unexpected failure event: %+v", e)
+	}
+	if stop := rec.events[1]; stop.OK || stop.State != "" {
+		t.Errorf("expected failed stop to leave state unchanged, got %+v", stop)
+	}
+}
+
+func TestNewVPNDirector_NilRecorder(t *testing.T) {
+	vpn := &mockVPN{}
+	if got := NewVPNDirector(vpn, nil, "bot"); got != vpn {
+		t.Errorf("expected unwrapped service, got %T", got)
+	}
+}
@@ -0,0 +1,128 @@
+// internal/handler/access.go
+package handler
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/accesslog"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/telegram"
+)
+
+const (
+	// topDestinations is the number of groups listed by /access
+	topDestinations = 15
+
+	defaultAccessPeriod = time.Hour
+)
+
+const accessUsage = "Usage: /access [ip] [period]\nExamples: /access, /access 192.168.50.23, /access 192.168.50.23 6h"
+
+// AccessHandler handles the /access command
+type AccessHandler struct {
+	deps *Deps
+	now  func() time.Time
+}
+
+// NewAccessHandler creates a new AccessHandler
+func NewAccessHandler(deps *Deps) *AccessHandler {
+	return &AccessHandler{deps: deps, now: time.Now}
+}
+
+// HandleAccess handles /access [ip] [period] - shows the top destinations of
+// a client from the Xray access log, or the busiest clients without an IP
+// (period default: 1h, at most 24h)
+func (h *AccessHandler) HandleAccess(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+
+	client, period, ok := parseAccessArgs(strings.Fields(msg.CommandArguments()))
+	if !ok {
+		h.deps.Sender.SendPlain(chatID, accessUsage)
+		return
+	}
+
+	if h.deps.Access == nil {
+		h.deps.Sender.SendPlain(chatID, "Access log is not available")
+		return
+	}
+
+	q := accesslog.Query{
+		Client: client,
+		Status: accesslog.StatusAccepted,
+		Since:  h.now().Add(-period),
+		Limit:  topDestinations,
+	}
+	by := accesslog.GroupDestination
+	if client == "" {
+		by = accesslog.GroupClient
+	}
+
+	groups, err := h.deps.Access.Top(q, by)
+	if err != nil {
+		h.deps.Sender.SendPlain(chatID, fmt.Sprintf("Access log error: %v", err))
+		return
+	}
+
+	h.deps.Sender.Send(chatID, telegram.EscapeMarkdownV2(formatAccessTop(client, period, groups)))
+}
+
+// parseAccessArgs accepts an optional IP and an optional period in any order
+func parseAccessArgs(args []string) (client string, period time.Duration, ok bool) {
+	period = defaultAccessPeriod
+	if len(args) > 2 {
+		return "", 0, false
+	}
+	for _, arg := range args {
+		if addr, err := netip.ParseAddr(arg); err == nil && client == "" {
+			client = addr.String()
+			continue
+		}
+		d, err := time.ParseDuration(arg)
+		if err != nil || d <= 0 {
+			return "", 0, false
+		}
+		period = min(d, accesslog.Retention)
+	}
+	return client, period, true
+}
+
+// formatAccessTop renders the groups as plain text (escape before sending)
+func formatAccessTop(client string, period time.Duration, groups []accesslog.Group) string {
+	var sb strings.Builder
+
+	if client != "" {
+		sb.WriteString(fmt.Sprintf("🌐 Top destinations of %s, last %s\n", client, formatPeriod(period)))
+	} else {
+		sb.WriteString(fmt.Sprintf("🌐 Busiest clients, last %s\n", formatPeriod(period)))
+	}
+
+	if len(groups) == 0 {
+		sb.WriteString("\nNo connections in the Xray access log for this period.")
+		return sb.String()
+	}
+
+	sb.WriteString("\n")
+	for i, g := range groups {
+		sb.WriteString(fmt.Sprintf("%d. %s — %d conn, last %s\n", i+1, g.Key, g.Count, g.LastSeen.Local().Format("15:04")))
+	}
+
+	if client == "" {
+		sb.WriteString("\nUse /access <ip> for a client's destinations.")
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// formatPeriod renders 1h0m0s as 1h and 30m0s as 30m
+func formatPeriod(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/accesslog"
+)
+
+func accessCommand(text string) *tgbotapi.Message {
+	return &tgbotapi.Message{
+		Text:     text,
+		Chat:     &tgbotapi.Chat{ID: 100},
+		Entities: []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len("/access")}},
+	}
+}
+
+func testAccessIndex(now time.Time) *accesslog.Index {
+	idx := accesslog.NewIndex(100)
+	add := func(ago time.Duration, client, dest string) {
+		idx.Add(accesslog.Record{Time: now.Add(-ago), Source: client, Destination: dest, Status: accesslog.StatusAccepted})
+	}
+	add(3*time.Hour, "192.168.50.23", "old.example.com")
+	add(20*time.Minute, "192.168.50.23", "www.google.com")
+	add(10*time.Minute, "192.168.50.23", "www.google.com")
+	add(5*time.Minute, "192.168.50.23", "ya.ru")
+	add(time.Minute, "192.168.50.40", "ya.ru")
+	return idx
+}
+
+func TestAccessHandler_ClientDestinations(t *testing.T) {
+	sender := &mockSenderClients{}
+	h := NewAccessHandler(&Deps{Sender: sender, Access: testAccessIndex(time.Now())})
+
+	h.HandleAccess(accessCommand("/access 192.168.50.23"))
+
+	for _, want := range []string{"192\\.168\\.50\\.23, last 1h", "1\\. www\\.google\\.com — 2 conn", "2\\. ya\\.ru — 1 conn"} {
+		if !strings.Contains(sender.lastText, want) {
+			t.Errorf("expected %q in message, got: %s", want, sender.lastText)
+		}
+	}
+	if strings.Contains(sender.lastText, "old\\.example\\.com") {
+		t.Errorf("expected entries outside the period to be hidden, got: %s", sender.lastText)
+	}
+}
+
+func TestAccessHandler_PeriodAndTopClients(t *testing.T) {
+	sender := &mockSenderClients{}
+	h := NewAccessHandler(&Deps{Sender: sender, Access: testAccessIndex(time.Now())})
+
+	h.HandleAccess(accessCommand("/access 6h"))
+
+	for _, want := range []string{"Busiest clients, last 6h", "1\\. 192\\.168\\.50\\.23 — 4 conn", "2\\. 192\\.168\\.50\\.40 — 1 conn"} {
+		if !strings.Contains(sender.lastText, want) {
+			t.Errorf("expected %q in message, got: %s", want, sender.lastText)
+		}
+	}
+}
+
+func TestAccessHandler_NoData(t *testing.T) {
+	sender := &mockSenderClients{}
+	h := NewAccessHandler(&Deps{Sender: sender, Access: accesslog.NewIndex(10)})
+
+	h.HandleAccess(accessCommand("/access 10.0.0.1 30m"))
+
+	if !strings.Contains(sender.lastText, "last 30m") || !strings.Contains(sender.lastText, "No connections") {
+		t.Errorf("unexpected message: %s", sender.lastText)
+	}
+}
+
+func TestAccessHandler_BadArgs(t *testing.T) {
+	for _, text := range []string{"/access yesterday", "/access 10.0.0.1 1h extra", "/access -1h"} {
+		sender := &mockSenderClients{}
+		h := NewAccessHandler(&Deps{Sender: sender, Access: accesslog.NewIndex(10)})
+
+		h.HandleAccess(accessCommand(text))
+
+		if len(sender.plainTexts) != 1 || !strings.Contains(sender.plainTexts[0], "Usage: /access") {
+			t.Errorf("%s: expected usage, got: %v", text, sender.plainTexts)
+		}
+	}
+}
+
+func TestAccessHandler_NotAvailable(t *testing.T) {
+	sender := &mockSenderClients{}
+	h := NewAccessHandler(&Deps{Sender: sender})
+
+	h.HandleAccess(accessCommand("/access"))
+
+	if len(sender.plainTexts) != 1 || !strings.Contains(sender.plainTexts[0], "not available") {
+		t.Errorf("unexpected messages: %v", sender.plainTexts)
+	}
+}
+
+func TestParseAccessArgs_CapsPeriod(t *testing.T) {
+	_, period, ok := parseAccessArgs([]string{"72h"})
+	if !ok || period != accesslog.Retention {
+		t.Errorf("expected period capped at %v, got %v (ok=%v)", accesslog.Retention, period, ok)
+	}
+}
@@ -0,0 +1,135 @@
+// internal/handler/audit.go
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/audit"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/telegram"
+)
+
+const (
+	defaultAuditEntries = 15
+	maxAuditEntries     = 30
+	// maxAuditValue bounds the before/after values shown per entry
+	maxAuditValue = 60
+)
+
+// AuditHandler handles the /audit command
+type AuditHandler struct {
+	deps *Deps
+}
+
+// NewAuditHandler creates a new AuditHandler
+func NewAuditHandler(deps *Deps) *AuditHandler {
+	return &AuditHandler{deps: deps}
+}
+
+// HandleAudit handles /audit [web|bot|scheduler] [@user|id:N|token:name]
+// [action] [N] - shows the latest configuration and control actions
+func (h *AuditHandler) HandleAudit(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+
+	if h.deps.Audit == nil {
+		h.deps.Sender.SendPlain(chatID, "Audit log is not available")
+		return
+	}
+	filter, ok := parseAuditArgs(strings.Fields(msg.CommandArguments()))
+	if !ok {
+		h.deps.Sender.SendPlain(chatID, "Usage: /audit [web|bot|scheduler] [@user] [action] [N]")
+		return
+	}
+	entries, err := h.deps.Audit.List(filter)
+	if err != nil {
+		h.deps.Sender.SendPlain(chatID, fmt.Sprintf("Audit log error: %v", err))
+		return
+	}
+	h.deps.Sender.SendPlain(chatID, formatAudit(entries))
+}
+
+// parseAuditArgs builds a filter from /audit arguments: a source, an actor,
+// an action or its group, and the number of entries, in any order
+func parseAuditArgs(args []string) (audit.Filter, bool) {
+	filter := audit.Filter{Limit: defaultAuditEntries}
+	for _, arg := range args {
+		switch {
+		case slices.Contains(audit.Sources, arg):
+			filter.Source = arg
+		case strings.HasPrefix(arg, "@") || strings.HasPrefix(arg, "id:") || strings.HasPrefix(arg, "token:"):
+			filter.Actor = arg
+		default:
+			if n, err := strconv.Atoi(arg); err == nil {
+				if n < 1 || n > maxAuditEntries {
+					return filter, false
+				}
+				filter.Limit = n
+				continue
+			}
+			if filter.Action != "" {
+				return filter, false
+			}
+			filter.Action = arg
+		}
+	}
+	return filter, true
+}
+
+// formatAudit renders entries, newest first, as plain text
+func formatAudit(entries []audit.Entry) string {
+	if len(entries) == 0 {
+		return "No audit entries"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📝 Audit log (%d):\n", len(entries)))
+	for _, e := range entries {
+		var line strings.Builder
+		status := "✓"
+		if e.Error != "" {
+			status = "✗"
+		}
+		line.WriteString(fmt.Sprintf("\n%s %s %s %s", status, e.Time.In(time.Local).Format("01-02 15:04"), e.Actor, e.Action))
+		if e.Target != "" {
+			line.WriteString(" " + e.Target)
+		}
+		line.WriteString(" (" + e.Source + ")")
+		if e.Before != nil || e.After != nil {
+			line.WriteString(fmt.Sprintf("\n  %s → %s", auditValue(e.Before), auditValue(e.After)))
+		}
+		if e.Error != "" {
+			line.WriteString("\n  Error: " + e.Error)
+		}
+		line.WriteString("\n")
+		if sb.Len()+line.Len() > telegram.MaxMessageLength {
+			sb.WriteString("\n…")
+			break
+		}
+		sb.WriteString(line.String())
+	}
+	return sb.String()
+}
+
+// auditValue renders a before/after value compactly; "-" stands for none
+func auditValue(v any) string {
+	if v == nil {
+		return "-"
+	}
+	s, ok := v.(string)
+	if !ok {
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return "?"
+		}
+		s = string(raw)
+	}
+	if r := []rune(s); len(r) > maxAuditValue {
+		return string(r[:maxAuditValue]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/audit"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/banlist"
+)
+
+func newAuditStore(t *testing.T) *audit.Store {
+	t.Helper()
+	return audit.NewStore(filepath.Join(t.TempDir(), audit.StoreFile))
+}
+
+func TestAuditHandler_List(t *testing.T) {
+	store := newAuditStore(t)
+	store.Record(audit.Entry{Source: audit.SourceWeb, Actor: "admin", Action: "clients.pause", Target: "192.168.50.10",
+		Before: map[string]bool{"paused": false}, After: map[string]bool{"paused": true}})
+	store.Record(audit.Entry{Source: audit.SourceScheduler, Actor: "resolver", Action: "servers.resolve", Target: "fra.example.com",
+		Error: "lookup failed"})
+	sender := &mockSenderClients{}
+	h := NewAuditHandler(&Deps{Sender: sender, Audit: store})
+
+	h.HandleAudit(bansCommand("/audit"))
+	text := sender.plainTexts[len(sender.plainTexts)-1]
+	for _, want := range []string{
+		"Audit log (2)",
+		"admin clients.pause 192.168.50.10 (web)",
+		`{"paused":false} → {"paused":true}`,
+		"✗", "resolver servers.resolve fra.example.com (scheduler)", "Error: lookup failed",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in:\n%s", want, text)
+		}
+	}
+
+	h.HandleAudit(bansCommand("/audit web clients 5"))
+	text = sender.plainTexts[len(sender.plainTexts)-1]
+	if !strings.Contains(text, "Audit log (1)") || strings.Contains(text, "resolver") {
+		t.Errorf("expected only the web entry, got:\n%s", text)
+	}
+
+	h.HandleAudit(bansCommand("/audit @nobody"))
+	if got := sender.plainTexts[len(sender.plainTexts)-1]; got != "No audit entries" {
+		t.Errorf("expected no entries, got %q", got)
+	}
+}
+
+func TestParseAuditArgs(t *testing.T) {
+	f, ok := parseAuditArgs([]string{"bot", "@alice", "servers", "20"})
+	if !ok || f.Source != audit.SourceBot || f.Actor != "@alice" || f.Action != "servers" || f.Limit != 20 {
+		t.Errorf("unexpected filter %+v, %v", f, ok)
+	}
+	for _, args := range [][]string{{"0"}, {"100"}, {"clients", "servers"}} {
+		if _, ok := parseAuditArgs(args); ok {
+			t.Errorf("%v: expected usage error", args)
+		}
+	}
+}
+
+func TestAuditHandler_Unavailable(t *testing.T) {
+	sender := &mockSenderClients{}
+	h := NewAuditHandler(&Deps{Sender: sender})
+
+	h.HandleAudit(bansCommand("/audit"))
+	if got := sender.plainTexts[len(sender.plainTexts)-1]; got != "Audit log is not available" {
+		t.Errorf("unexpected reply %q", got)
+	}
+}
+
+func TestDeps_AuditRecordsActor(t *testing.T) {
+	store := newAuditStore(t)
+	bans := banlist.NewStore(filepath.Join(t.TempDir(), banlist.StoreFile))
+	h := NewBansHandler(&Deps{Sender: &mockSenderClients{}, Bans: bans, Audit: store})
+
+	msg := bansCommand("/unban 10.0.0.1")
+	msg.From = &tgbotapi.User{ID: 42}
+	h.HandleUnban(msg)
+
+	list, _ := store.List(audit.Filter{})
+	if len(list) != 1 {
+		t.Fatalf("expected one entry, got %d", len(list))
+	}
+	e := list[0]
+	if e.Source != audit.SourceBot || e.Actor != "id:42" || e.Action != "bans.clear" || e.Error == "" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+}
@@ -0,0 +1,129 @@
+// internal/handler/bans.go
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/banlist"
+)
+
+// BansHandler handles the /bans and /unban commands
+type BansHandler struct {
+	deps *Deps
+}
+
+// NewBansHandler creates a new BansHandler
+func NewBansHandler(deps *Deps) *BansHandler {
+	return &BansHandler{deps: deps}
+}
+
+// HandleBans handles /bans - lists Web UI addresses and Telegram users
+// blocked after repeated failed attempts
+func (h *BansHandler) HandleBans(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+
+	if h.deps.Bans == nil {
+		h.deps.Sender.SendPlain(chatID, "Ban list is not available")
+		return
+	}
+	bans, err := h.deps.Bans.List()
+	if err != nil {
+		h.deps.Sender.SendPlain(chatID, fmt.Sprintf("Ban list error: %v", err))
+		return
+	}
+	h.deps.Sender.SendPlain(chatID, formatBans(bans))
+}
+
+// formatBans renders the active bans as plain text
+func formatBans(bans []banlist.Entry) string {
+	if len(bans) == 0 {
+		return "No bans"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🚫 Bans (%d):\n", len(bans)))
+	for _, b := range bans {
+		where := "Web UI"
+		if b.Kind == banlist.KindTelegram {
+			where = "Telegram"
+		}
+		sb.WriteString(fmt.Sprintf("\n%s (%s) until %s", b.Source, where, b.BannedUntil.Local().Format("2006-01-02 15:04")))
+		if b.Reason != "" {
+			sb.WriteString("\nLast: " + b.Reason)
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\nLift with /unban <ip|@user|all>")
+	return sb.String()
+}
+
+// HandleUnban handles /unban <ip|@user|id:N|all> - lifts one ban or all of them
+func (h *BansHandler) HandleUnban(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+
+	if h.deps.Bans == nil {
+		h.deps.Sender.SendPlain(chatID, "Ban list is not available")
+		return
+	}
+
+	arg := strings.TrimSpace(msg.CommandArguments())
+	if arg == "all" {
+		n, err := h.deps.Bans.ClearAll()
+		h.deps.audit(msg.From, "bans.clear_all", "", map[string]int{"bans": n}, map[string]int{"bans": 0}, err)
+		if err != nil {
+			h.deps.Sender.SendPlain(chatID, fmt.Sprintf("Unban error: %v", err))
+			return
+		}
+		h.deps.Sender.SendPlain(chatID, fmt.Sprintf("Lifted %d ban(s)", n))
+		return
+	}
+
+	id, ok := banID(arg)
+	if !ok {
+		h.deps.Sender.SendPlain(chatID, "Usage: /unban <ip|@user|all>")
+		return
+	}
+	before := h.auditBan(id)
+	err := h.deps.Bans.Clear(id)
+	h.deps.audit(msg.From, "bans.clear", id, before, nil, err)
+	if errors.Is(err, banlist.ErrNotFound) {
+		h.deps.Sender.SendPlain(chatID, arg+" is not banned")
+		return
+	}
+	if err != nil {
+		h.deps.Sender.SendPlain(chatID, fmt.Sprintf("Unban error: %v", err))
+		return
+	}
+	h.deps.Sender.SendPlain(chatID, "Lifted the ban of "+arg)
+}
+
+// banID returns the ban list ID for an /unban argument: an IP address or a
+// Telegram "@username" / "id:<user ID>"
+func banID(arg string) (string, bool) {
+	if strings.HasPrefix(arg, "@") && len(arg) > 1 || strings.HasPrefix(arg, "id:") && len(arg) > 3 {
+		return banlist.ID(banlist.KindTelegram, arg), true
+	}
+	if addr, err := netip.ParseAddr(arg); err == nil {
+		return banlist.ID(banlist.KindIP, addr.Unmap().String()), true
+	}
+	return "", false
+}
+
+// auditBan describes the ban with the given ID for the audit log, or
+// returns nil when there is none.
+func (h *BansHandler) auditBan(id string) map[string]any {
+	bans, err := h.deps.Bans.List()
+	if err != nil {
+		return nil
+	}
+	for _, b := range bans {
+		if b.ID == id {
+			return map[string]any{"reason": b.Reason, "banned_until": b.BannedUntil}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/audit"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/banlist"
+)
+
+func bansCommand(text string) *tgbotapi.Message {
+	cmd, _, _ := strings.Cut(text, " ")
+	return &tgbotapi.Message{
+		Text:     text,
+		Chat:     &tgbotapi.Chat{ID: 100},
+		Entities: []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(cmd)}},
+	}
+}
+
+// newBansHandler returns a handler over a ban list with 10.0.0.1 and
+// @stranger banned.
+func newBansHandler(t *testing.T) (*BansHandler, *mockSenderClients, *banlist.Store) {
+	t.Helper()
+	store := banlist.NewStore(filepath.Join(t.TempDir(), banlist.StoreFile))
+	for range 5 {
+		store.Fail(banlist.KindIP, "10.0.0.1", "bad credentials for admin")
+		store.Fail(banlist.KindTelegram, "@stranger", "unauthorized message")
+	}
+	sender := &mockSenderClients{}
+	return NewBansHandler(&Deps{Sender: sender, Bans: store}), sender, store
+}
+
+func TestBansHandler_List(t *testing.T) {
+	h, sender, _ := newBansHandler(t)
+
+	h.HandleBans(bansCommand("/bans"))
+
+	text := sender.plainTexts[len(sender.plainTexts)-1]
+	for _, want := range []string{"Bans (2)", "10.0.0.1 (Web UI) until", "@stranger (Telegram) until", "Last: bad credentials for admin", "/unban"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in:\n%s", want, text)
+		}
+	}
+}
+
+func TestBansHandler_Unban(t *testing.T) {
+	h, sender, store := newBansHandler(t)
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"/unban", "Usage"},
+		{"/unban nobody", "Usage"},
+		{"/unban 10.0.0.2", "10.0.0.2 is not banned"},
+		{"/unban 10.0.0.1", "Lifted the ban of 10.0.0.1"},
+		{"/unban all", "Lifted 1 ban(s)"},
+	}
+	for _, tt := range tests {
+		h.HandleUnban(bansCommand(tt.text))
+		if got := sender.plainTexts[len(sender.plainTexts)-1]; !strings.Contains(got, tt.want) {
+			t.Errorf("%s: expected %q, got %q", tt.text, tt.want, got)
+		}
+	}
+	if bans, _ := store.List(); len(bans) != 0 {
+		t.Errorf("expected no bans, got %+v", bans)
+	}
+
+	h.HandleBans(bansCommand("/bans"))
+	if got := sender.plainTexts[len(sender.plainTexts)-1]; got != "No bans" {
+		t.Errorf("expected no bans, got %q", got)
+	}
+}
+
+func TestBansHandler_UnbanAudit(t *testing.T) {
+	h, _, _ := newBansHandler(t)
+	rec := newAuditStore(t)
+	h.deps.Audit = rec
+
+	h.HandleUnban(bansCommand("/unban 10.0.0.1"))
+	h.HandleUnban(bansCommand("/unban all"))
+
+	list, _ := rec.List(audit.Filter{})
+	if len(list) != 2 {
+		t.Fatalf("expected two entries, got %+v", list)
+	}
+	for _, e := range list {
+		before, _ := json.Marshal(e.Before)
+		after, _ := json.Marshal(e.After)
+		switch e.Action {
+		case "bans.clear":
+			if e.Target != "ip:10.0.0.1" || !strings.Contains(string(before), "bad credentials for admin") || string(after) != "null" {
+				t.Errorf("unexpected entry %+v", e)
+			}
+		case "bans.clear_all":
+			if string(before) != `{"bans":1}` || string(after) != `{"bans":0}` {
+				t.Errorf("unexpected entry %+v", e)
+			}
+		default:
+			t.Errorf("unexpected entry %+v", e)
+		}
+	}
+}
+
+func TestBansHandler_Unavailable(t *testing.T) {
+	sender := &mockSenderClients{}
+	h := NewBansHandler(&Deps{Sender: sender})
+
+	h.HandleBans(bansCommand("/bans"))
+	h.HandleUnban(bansCommand("/unban all"))
+
+	for _, got := range sender.plainTexts {
+		if got != "Ban list is not available" {
+			t.Errorf("unexpected reply %q", got)
+		}
+	}
+}
+
+func TestBanID(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want string
+		ok   bool
+	}{
+		{"192.168.50.23", "ip:192.168.50.23", true},
+		{"::ffff:192.168.50.23", "ip:192.168.50.23", true},
+		{"@stranger", "telegram:@stranger", true},
+		{"id:12345", "telegram:id:12345", true},
+		{"@", "", false},
+		{"stranger", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := banID(tt.arg)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("banID(%q) = %q, %v; want %q, %v", tt.arg, got, ok, tt.want, tt.ok)
+		}
+	}
+}
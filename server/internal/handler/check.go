@@ -0,0 +1,92 @@
+// internal/handler/check.go
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/diagnostics"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/telegram"
+)
+
+// CheckHandler handles the /check command
+type CheckHandler struct {
+	deps *Deps
+}
+
+// NewCheckHandler creates a new CheckHandler
+func NewCheckHandler(deps *Deps) *CheckHandler {
+	return &CheckHandler{deps: deps}
+}
+
+// HandleCheck handles /check - probes the exit IP and DNS resolver of every
+// route in use and reports routes that leak to the WAN
+func (h *CheckHandler) HandleCheck(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+
+	if h.deps.Diagnostics == nil {
+		h.deps.Sender.SendPlain(chatID, "Route diagnostics are not available")
+		return
+	}
+
+	h.deps.Sender.SendPlain(chatID, "Checking routes...")
+
+	report, err := h.deps.Diagnostics.CheckRoutes()
+	if err != nil {
+		h.deps.Sender.SendPlain(chatID, fmt.Sprintf("Check error: %v", err))
+		return
+	}
+
+	h.deps.Sender.Send(chatID, telegram.EscapeMarkdownV2(formatCheckReport(report)))
+}
+
+// formatCheckReport renders a report as plain text (escape before sending)
+func formatCheckReport(report *diagnostics.Report) string {
+	var sb strings.Builder
+
+	if report.OK {
+		sb.WriteString("🧭 Route check: OK\n")
+	} else {
+		sb.WriteString("🧭 Route check: problems found\n")
+	}
+
+	for _, r := range report.Routes {
+		sb.WriteString(fmt.Sprintf("\n%s %s", checkStatusIcon(r.Status), r.Name))
+		if r.Via != "" {
+			sb.WriteString(fmt.Sprintf(" (%s)", r.Via))
+		}
+		sb.WriteString("\n")
+
+		if r.Exit != nil {
+			sb.WriteString("Exit: " + r.Exit.IP)
+			if loc := formatIPLocation(r.Exit); loc != "" {
+				sb.WriteString(", " + loc)
+			}
+			sb.WriteString("\n")
+		}
+		if r.Resolver != nil {
+			sb.WriteString("DNS: " + r.Resolver.IP)
+			if r.Resolver.Geo != "" {
+				sb.WriteString(" (" + r.Resolver.Geo + ")")
+			}
+			sb.WriteString("\n")
+		}
+		for _, p := range r.Problems {
+			sb.WriteString("• " + p + "\n")
+		}
+	}
+
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func checkStatusIcon(status string) string {
+	switch status {
+	case diagnostics.StatusOK:
+		return "✅"
+	case diagnostics.StatusWarning:
+		return "⚠️"
+	default:
+		return "❌"
+	}
+}
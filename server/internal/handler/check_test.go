@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/diagnostics"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/service"
+)
+
+type mockDiagnostics struct {
+	report *diagnostics.Report
+	err    error
+}
+
+func (m *mockDiagnostics) CheckRoutes() (*diagnostics.Report, error) { return m.report, m.err }
+
+func checkCommand() *tgbotapi.Message {
+	return &tgbotapi.Message{
+		Text:     "/check",
+		Chat:     &tgbotapi.Chat{ID: 100},
+		Entities: []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len("/check")}},
+	}
+}
+
+func TestCheckHandler_Report(t *testing.T) {
+	sender := &mockSenderClients{}
+	h := NewCheckHandler(&Deps{Sender: sender, Diagnostics: &mockDiagnostics{report: &diagnostics.Report{
+		Routes: []diagnostics.Result{
+			{
+				Route:    diagnostics.Route{Name: "wan", Kind: diagnostics.KindWAN},
+				Exit:     &service.IPInfo{IP: "100.64.12.7", Country: "RU", ASN: "AS8359", ISP: "MTS PJSC"},
+				Resolver: &diagnostics.Resolver{IP: "195.34.32.1", Geo: "Russia - MTS PJSC"},
+				Status:   diagnostics.StatusOK,
+			},
+			{
+				Route:    diagnostics.Route{Name: "ovpnc1", Kind: diagnostics.KindTunnel, Via: "tun11"},
+				Exit:     &service.IPInfo{IP: "100.64.12.7", Country: "RU"},
+				Status:   diagnostics.StatusError,
+				Problems: []string{"ovpnc1 clients exit via WAN (100.64.12.7)"},
+			},
+		},
+	}}})
+
+	h.HandleCheck(checkCommand())
+
+	if len(sender.plainTexts) != 1 || sender.plainTexts[0] != "Checking routes..." {
+		t.Errorf("expected progress message, got %v", sender.plainTexts)
+	}
+	for _, want := range []string{
+		"Route check: problems found",
+		"✅ wan",
+		"Exit: 100\\.64\\.12\\.7, RU, AS8359 MTS PJSC",
+		"DNS: 195\\.34\\.32\\.1 \\(Russia \\- MTS PJSC\\)",
+		"❌ ovpnc1 \\(tun11\\)",
+		"• ovpnc1 clients exit via WAN \\(100\\.64\\.12\\.7\\)",
+	} {
+		if !strings.Contains(sender.lastText, want) {
+			t.Errorf("expected %q in message, got: %s", want, sender.lastText)
+		}
+	}
+}
+
+func TestCheckHandler_Error(t *testing.T) {
+	sender := &mockSenderClients{}
+	h := NewCheckHandler(&Deps{Sender: sender, Diagnostics: &mockDiagnostics{err: errors.New("config missing")}})
+
+	h.HandleCheck(checkCommand())
+
+	if len(sender.plainTexts) != 2 || !strings.Contains(sender.plainTexts[1], "config missing") {
+		t.Errorf("expected error message, got %v", sender.plainTexts)
+	}
+}
+
+func TestCheckHandler_Unavailable(t *testing.T) {
+	sender := &mockSenderClients{}
+	h := NewCheckHandler(&Deps{Sender: sender})
+
+	h.HandleCheck(checkCommand())
+
+	if len(sender.plainTexts) != 1 || sender.plainTexts[0] != "Route diagnostics are not available" {
+		t.Errorf("unexpected messages: %v", sender.plainTexts)
+	}
+}
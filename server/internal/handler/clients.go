@@ -2,12 +2,13 @@ package handler
 
 import (
 	"fmt"
-	"net"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/cidrset"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/telegram"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
 )
@@ -67,6 +68,7 @@ func (h *ClientsHandler) buildClientList(cfg *vpnconfig.VPNDirectorConfig) (stri
 				kb.Button(fmt.Sprintf("\u23f8 %s", c.IP), fmt.Sprintf("clients:pause:%s", c.IP))
 			}
 			kb.Button(fmt.Sprintf("\U0001f5d1 %s", c.IP), fmt.Sprintf("clients:remove:%s", c.IP))
+			kb.Button("\U0001f50c", fmt.Sprintf("clients:conns:%s", c.IP))
 			kb.Row()
 		}
 	}
@@ -92,16 +94,19 @@ func (h *ClientsHandler) HandleCallback(cb *tgbotapi.CallbackQuery) {
 	switch {
 	case strings.HasPrefix(action, "pause:"):
 		ip := strings.TrimPrefix(action, "pause:")
-		h.handlePauseResume(chatID, msgID, ip, true)
+		h.handlePauseResume(cb.From, chatID, msgID, ip, true)
 	case strings.HasPrefix(action, "resume:"):
 		ip := strings.TrimPrefix(action, "resume:")
-		h.handlePauseResume(chatID, msgID, ip, false)
+		h.handlePauseResume(cb.From, chatID, msgID, ip, false)
 	case strings.HasPrefix(action, "remove:"):
 		ip := strings.TrimPrefix(action, "remove:")
 		h.handleRemoveConfirm(chatID, msgID, ip)
 	case strings.HasPrefix(action, "rm_yes:"):
 		ip := strings.TrimPrefix(action, "rm_yes:")
-		h.handleRemove(chatID, msgID, ip)
+		h.handleRemove(cb.From, chatID, msgID, ip)
+	case strings.HasPrefix(action, "conns:"):
+		ip := strings.TrimPrefix(action, "conns:")
+		h.handleConnections(chatID, ip)
 	case action == "rm_no":
 		h.handleRefreshList(chatID, msgID)
 	case action == "add":
@@ -110,11 +115,17 @@ func (h *ClientsHandler) HandleCallback(cb *tgbotapi.CallbackQuery) {
 		h.handleClose(chatID, msgID)
 	case strings.HasPrefix(action, "route:"):
 		route := strings.TrimPrefix(action, "route:")
-		h.handleAddRoute(chatID, msgID, route)
+		h.handleAddRoute(cb.From, chatID, msgID, route)
 	}
 }
 
-func (h *ClientsHandler) handlePauseResume(chatID int64, msgID int, ip string, pause bool) {
+func (h *ClientsHandler) handlePauseResume(from *tgbotapi.User, chatID int64, msgID int, ip string, pause bool) {
+	unlock, ok := h.deps.lockConfig(chatID)
+	if !ok {
+		return
+	}
+	defer unlock()
+
 	cfg, err := h.deps.Config.LoadVPNConfig()
 	if err != nil {
 		h.deps.Sender.SendPlain(chatID, fmt.Sprintf("Config load error: %v", err))
@@ -136,6 +147,11 @@ func (h *ClientsHandler) handlePauseResume(chatID int64, msgID int, ip string, p
 		return
 	}
 
+	action := "clients.resume"
+	if pause {
+		action = "clients.pause"
+	}
+	before := map[string]bool{"paused": slices.Contains(cfg.PausedClients, ip)}
 	if pause {
 		found := false
 		for _, p := range cfg.PausedClients {
@@ -157,7 +173,9 @@ func (h *ClientsHandler) handlePauseResume(chatID int64, msgID int, ip string, p
 		cfg.PausedClients = filtered
 	}
 
-	if err := h.deps.Config.SaveVPNConfig(cfg); err != nil {
+	err = h.deps.Config.SaveVPNConfig(cfg)
+	h.deps.audit(from, action, ip, before, map[string]bool{"paused": pause}, err)
+	if err != nil {
 		h.deps.Sender.SendPlain(chatID, fmt.Sprintf("Save error: %v", err))
 		return
 	}
@@ -216,7 +234,13 @@ func (h *ClientsHandler) handleRemoveConfirm(chatID int64, msgID int, ip string)
 	h.deps.Sender.EditMessage(chatID, msgID, text, kb.Build())
 }
 
-func (h *ClientsHandler) handleRemove(chatID int64, msgID int, ip string) {
+func (h *ClientsHandler) handleRemove(from *tgbotapi.User, chatID int64, msgID int, ip string) {
+	unlock, ok := h.deps.lockConfig(chatID)
+	if !ok {
+		return
+	}
+	defer unlock()
+
 	cfg, err := h.deps.Config.LoadVPNConfig()
 	if err != nil {
 		h.deps.Sender.SendPlain(chatID, fmt.Sprintf("Config load error: %v", err))
@@ -250,7 +274,9 @@ func (h *ClientsHandler) handleRemove(chatID int64, msgID int, ip string) {
 
 	cfg.PausedClients = removeString(cfg.PausedClients, ip)
 
-	if err := h.deps.Config.SaveVPNConfig(cfg); err != nil {
+	err = h.deps.Config.SaveVPNConfig(cfg)
+	h.deps.audit(from, "clients.delete", ip, []string{route}, nil, err)
+	if err != nil {
 		h.deps.Sender.SendPlain(chatID, fmt.Sprintf("Save error: %v", err))
 		return
 	}
@@ -298,13 +324,12 @@ func (h *ClientsHandler) HandleTextInput(msg *tgbotapi.Message) {
 		return
 	}
 
-	if !isValidIPOrCIDR(input) {
-		h.deps.Sender.SendPlain(chatID, "Invalid format. Enter IPv4 (192.168.50.10) or CIDR (192.168.50.0/24):")
+	ip, err := cidrset.Canonical(input)
+	if err != nil {
+		h.deps.Sender.SendPlain(chatID, "Invalid format. Enter IPv4/IPv6 (192.168.50.10, 2001:db8::10) or CIDR (192.168.50.0/24):")
 		return
 	}
 
-	// Normalize for duplicate check
-	normalized := normalizeIP(input)
 	cfg, err := h.deps.Config.LoadVPNConfig()
 	if err != nil {
 		h.deps.Sender.SendPlain(chatID, fmt.Sprintf("Config load error: %v", err))
@@ -313,7 +338,7 @@ func (h *ClientsHandler) HandleTextInput(msg *tgbotapi.Message) {
 
 	clients := vpnconfig.CollectClients(cfg)
 	for _, c := range clients {
-		if normalizeIP(c.IP) == normalized {
+		if existing, err := cidrset.Canonical(c.IP); err == nil && existing == ip {
 			h.deps.Sender.SendPlain(chatID, fmt.Sprintf("This IP is already configured for %s", c.Route))
 			return
 		}
@@ -321,10 +346,10 @@ func (h *ClientsHandler) HandleTextInput(msg *tgbotapi.Message) {
 
 	// Save pending IP and show route selection
 	h.mu.Lock()
-	h.addState[chatID] = input
+	h.addState[chatID] = ip
 	h.mu.Unlock()
 
-	h.showRouteSelection(chatID, input, cfg)
+	h.showRouteSelection(chatID, ip, cfg)
 }
 
 func (h *ClientsHandler) showRouteSelection(chatID int64, ip string, cfg *vpnconfig.VPNDirectorConfig) {
@@ -348,7 +373,7 @@ func (h *ClientsHandler) showRouteSelection(chatID int64, ip string, cfg *vpncon
 	h.deps.Sender.SendWithKeyboard(chatID, text, kb.Build())
 }
 
-func (h *ClientsHandler) handleAddRoute(chatID int64, msgID int, route string) {
+func (h *ClientsHandler) handleAddRoute(from *tgbotapi.User, chatID int64, msgID int, route string) {
 	if route == "cancel" {
 		h.ClearState(chatID)
 		h.handleRefreshList(chatID, msgID)
@@ -364,17 +389,26 @@ func (h *ClientsHandler) handleAddRoute(chatID int64, msgID int, route string) {
 		return
 	}
 
+	unlock, ok := h.deps.lockConfig(chatID)
+	if !ok {
+		return
+	}
+	defer unlock()
+
 	cfg, err := h.deps.Config.LoadVPNConfig()
 	if err != nil {
 		h.deps.Sender.SendPlain(chatID, fmt.Sprintf("Config load error: %v", err))
 		return
 	}
 
-	// Normalize IP: strip /32 for consistent storage
-	ip = normalizeIP(ip)
+	prefix, err := cidrset.Parse(ip)
+	if err != nil {
+		h.deps.Sender.SendPlain(chatID, fmt.Sprintf("Invalid address: %v", err))
+		return
+	}
 
 	if route == "xray" {
-		cfg.Xray.Clients = append(cfg.Xray.Clients, ip)
+		cfg.Xray.Clients, err = cidrset.Add(cfg.Xray.Clients, prefix)
 	} else {
 		if _, ok := cfg.TunnelDirector.Tunnels[route]; !ok {
 			// Stale keyboard — tunnel no longer exists
@@ -383,11 +417,17 @@ func (h *ClientsHandler) handleAddRoute(chatID int64, msgID int, route string) {
 			return
 		}
 		tunnel := cfg.TunnelDirector.Tunnels[route]
-		tunnel.Clients = append(tunnel.Clients, ip)
+		tunnel.Clients, err = cidrset.Add(tunnel.Clients, prefix)
 		cfg.TunnelDirector.Tunnels[route] = tunnel
 	}
+	if err != nil {
+		h.deps.Sender.SendPlain(chatID, err.Error())
+		return
+	}
 
-	if err := h.deps.Config.SaveVPNConfig(cfg); err != nil {
+	err = h.deps.Config.SaveVPNConfig(cfg)
+	h.deps.audit(from, "clients.add", ip, nil, []string{route}, err)
+	if err != nil {
 		h.deps.Sender.SendPlain(chatID, fmt.Sprintf("Save error: %v", err))
 		return
 	}
@@ -397,23 +437,10 @@ func (h *ClientsHandler) handleAddRoute(chatID int64, msgID int, route string) {
 		return
 	}
 
-	text, kb := h.buildClientList(cfg)
-	h.deps.Sender.EditMessage(chatID, msgID, text, kb)
-}
-
-func isValidIPOrCIDR(s string) bool {
-	if strings.Contains(s, "/") {
-		ip, _, err := net.ParseCIDR(s)
-		if err != nil {
-			return false
-		}
-		return ip.To4() != nil
+	for _, c := range vpnconfig.ClientConflictsOf(cfg, ip) {
+		h.deps.Sender.SendPlain(chatID, "Warning: "+c.String())
 	}
-	ip := net.ParseIP(s)
-	return ip != nil && ip.To4() != nil
-}
 
-// normalizeIP strips /32 suffix from single-host addresses for consistent storage.
-func normalizeIP(ip string) string {
-	return strings.TrimSuffix(ip, "/32")
+	text, kb := h.buildClientList(cfg)
+	h.deps.Sender.EditMessage(chatID, msgID, text, kb)
 }
@@ -65,6 +65,7 @@ func (m *mockConfigClients) SaveServers(s []vpnconfig.Server) error     { return
 func (m *mockConfigClients) DataDir() (string, error)                   { return "/data", nil }
 func (m *mockConfigClients) DataDirOrDefault() string                   { return "/data" }
 func (m *mockConfigClients) ScriptsDir() string                         { return "/scripts" }
+func (m *mockConfigClients) LockConfig() (func(), error)                { return func() {}, nil }
 
 type mockVPNClients struct {
 	applyErr error
@@ -73,6 +74,7 @@ type mockVPNClients struct {
 func (m *mockVPNClients) Status() (string, error) { return "", nil }
 func (m *mockVPNClients) Apply() error            { return m.applyErr }
 func (m *mockVPNClients) Restart() error          { return nil }
+func (m *mockVPNClients) ApplyXray() error        { return nil }
 func (m *mockVPNClients) RestartXray() error      { return nil }
 func (m *mockVPNClients) Stop() error             { return nil }
 
@@ -106,7 +108,7 @@ func TestClientsHandler_HandleClients_WithClients(t *testing.T) {
 	if !strings.Contains(sender.lastText, "192\\.168\\.50\\.20") {
 		t.Errorf("expected message to contain escaped 192.168.50.20/32, got: %s", sender.lastText)
 	}
-	// 2 clients x 2 buttons + 1 Add row = 3 rows
+	// 2 clients x 3 buttons + 1 Add row = 3 rows
 	if len(sender.lastKeyboard.InlineKeyboard) != 3 {
 		t.Errorf("expected 3 keyboard rows, got %d", len(sender.lastKeyboard.InlineKeyboard))
 	}
@@ -443,3 +445,53 @@ func TestClientsHandler_HandleTextInput_NotInAddState(t *testing.T) {
 		t.Error("expected no message sent when not in add state")
 	}
 }
+
+func TestClientsHandler_HandleTextInput_Canonical(t *testing.T) {
+	sender := &mockSenderClients{}
+	config := &mockConfigClients{
+		vpnConfig: &vpnconfig.VPNDirectorConfig{
+			Xray: vpnconfig.XrayConfig{Clients: []string{"192.168.50.10"}},
+		},
+	}
+	h := NewClientsHandler(&Deps{Sender: sender, Config: config})
+
+	h.addState[100] = ""
+	h.HandleTextInput(&tgbotapi.Message{Text: "192.168.50.10/32", Chat: &tgbotapi.Chat{ID: 100}})
+	if len(sender.plainTexts) != 1 || !strings.Contains(sender.plainTexts[0], "already configured for xray") {
+		t.Errorf("expected the /32 form to be a duplicate, got %v", sender.plainTexts)
+	}
+
+	h.addState[100] = ""
+	h.HandleTextInput(&tgbotapi.Message{Text: "2001:db8::10/128", Chat: &tgbotapi.Chat{ID: 100}})
+	if got := h.addState[100]; got != "2001:db8::10" {
+		t.Errorf("expected the canonical address pending, got %q", got)
+	}
+}
+
+func TestClientsHandler_HandleAddRoute_Covered(t *testing.T) {
+	sender := &mockSenderClients{}
+	cfg := &vpnconfig.VPNDirectorConfig{
+		Xray: vpnconfig.XrayConfig{Clients: []string{"192.168.50.0/24"}},
+		TunnelDirector: vpnconfig.TunnelDirectorConfig{
+			Tunnels: map[string]vpnconfig.TunnelConfig{"wgc1": {Clients: []string{}}},
+		},
+	}
+	config := &mockConfigClients{vpnConfig: cfg}
+	h := NewClientsHandler(&Deps{Sender: sender, Config: config, VPN: &mockVPNClients{}})
+	msg := &tgbotapi.Message{MessageID: 42, Chat: &tgbotapi.Chat{ID: 100}}
+
+	h.addState[100] = "192.168.50.7"
+	h.HandleCallback(&tgbotapi.CallbackQuery{Data: "clients:route:xray", Message: msg})
+	if config.savedConfig != nil || len(sender.plainTexts) != 1 || sender.plainTexts[0] != "192.168.50.7 is already covered by 192.168.50.0/24" {
+		t.Fatalf("expected the covered client to be refused, got %v", sender.plainTexts)
+	}
+
+	h.addState[100] = "192.168.50.7"
+	h.HandleCallback(&tgbotapi.CallbackQuery{Data: "clients:route:wgc1", Message: msg})
+	if config.savedConfig == nil {
+		t.Fatal("expected config to be saved")
+	}
+	if got := sender.plainTexts[len(sender.plainTexts)-1]; got != "Warning: 192.168.50.7 (wgc1) overlaps 192.168.50.0/24 (xray)" {
+		t.Errorf("expected an overlap warning, got %q", got)
+	}
+}
@@ -0,0 +1,91 @@
+// internal/handler/connections.go
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/conntrack"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/telegram"
+)
+
+// maxListedConnections is the number of connections shown by the /clients
+// drill-down
+const maxListedConnections = 20
+
+// handleConnections sends the active connections of a client (IP or CIDR)
+// from the conntrack table, with the route each connection is marked for
+func (h *ClientsHandler) handleConnections(chatID int64, client string) {
+	if h.deps.Connections == nil {
+		h.deps.Sender.SendPlain(chatID, "Connection tracking is not available")
+		return
+	}
+
+	snap, err := h.deps.Connections.Connections(conntrack.Query{Client: client, Limit: maxListedConnections})
+	if err != nil {
+		h.deps.Sender.SendPlain(chatID, fmt.Sprintf("Connections error: %v", err))
+		return
+	}
+
+	h.deps.Sender.Send(chatID, telegram.EscapeMarkdownV2(formatConnections(client, snap)))
+}
+
+// formatConnections renders a snapshot as plain text (escape before sending)
+func formatConnections(client string, snap *conntrack.Snapshot) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("🔌 Connections of %s\n", client))
+
+	if snap.Total == 0 {
+		sb.WriteString("\nNo active connections.")
+		return sb.String()
+	}
+
+	sb.WriteString("\n")
+	for _, c := range snap.Clients {
+		sb.WriteString(fmt.Sprintf("%s → %s: %d active, %d marked", c.IP, c.Route, c.Connections, c.Marked))
+		if c.Paused {
+			sb.WriteString(" (paused)")
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("\n")
+	multi := len(snap.Clients) > 1
+	for i, c := range snap.Connections {
+		sb.WriteString(fmt.Sprintf("%d. ", i+1))
+		if multi {
+			sb.WriteString(c.Src + " ")
+		}
+		sb.WriteString(fmt.Sprintf("%s %s", c.Proto, hostPort(c.Dst, c.DstPort)))
+		if c.State != "" {
+			sb.WriteString(" " + c.State)
+		}
+		switch {
+		case c.MarkRoute == "":
+			sb.WriteString(" — unmarked")
+		case c.MarkRoute != c.Route:
+			sb.WriteString(" — marked " + c.MarkRoute)
+		default:
+			sb.WriteString(" — " + c.MarkRoute)
+		}
+		sb.WriteString("\n")
+	}
+	if more := snap.Total - len(snap.Connections); more > 0 {
+		sb.WriteString(fmt.Sprintf("…and %d more\n", more))
+	}
+
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// hostPort joins an address and port, bracketing IPv6 addresses
+func hostPort(addr string, port int) string {
+	if port == 0 {
+		return addr
+	}
+	if strings.Contains(addr, ":") {
+		return "[" + addr + "]:" + strconv.Itoa(port)
+	}
+	return addr + ":" + strconv.Itoa(port)
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/conntrack"
+)
+
+type mockConnections struct {
+	snap  *conntrack.Snapshot
+	err   error
+	query conntrack.Query
+}
+
+func (m *mockConnections) Connections(q conntrack.Query) (*conntrack.Snapshot, error) {
+	m.query = q
+	return m.snap, m.err
+}
+
+func connectionsCallback(client string) *tgbotapi.CallbackQuery {
+	return &tgbotapi.CallbackQuery{
+		ID:      "cb1",
+		Data:    "clients:conns:" + client,
+		Message: &tgbotapi.Message{MessageID: 7, Chat: &tgbotapi.Chat{ID: 100}},
+	}
+}
+
+func TestClientsHandler_Connections(t *testing.T) {
+	sender := &mockSenderClients{}
+	conns := &mockConnections{snap: &conntrack.Snapshot{
+		Clients: []conntrack.ClientSummary{{IP: "192.168.50.10", Client: "192.168.50.10", Route: "xray", Connections: 3, Marked: 2}},
+		Connections: []conntrack.Connection{
+			{Entry: conntrack.Entry{Proto: "tcp", Src: "192.168.50.10", Dst: "142.250.74.46", DstPort: 443, State: "ESTABLISHED"}, Route: "xray", MarkRoute: "xray"},
+			{Entry: conntrack.Entry{Proto: "tcp", Src: "192.168.50.10", Dst: "2a00:1450::64", DstPort: 443}, Route: "xray", MarkRoute: "xray"},
+		},
+		Total: 3,
+	}}
+	h := NewClientsHandler(&Deps{Sender: sender, Connections: conns})
+
+	h.HandleCallback(connectionsCallback("192.168.50.10"))
+
+	if conns.query.Client != "192.168.50.10" || conns.query.Limit != maxListedConnections {
+		t.Errorf("unexpected query: %+v", conns.query)
+	}
+	for _, want := range []string{
+		"192\\.168\\.50\\.10 → xray: 3 active, 2 marked",
+		"1\\. tcp 142\\.250\\.74\\.46:443 ESTABLISHED — xray",
+		"2\\. tcp \\[2a00:1450::64\\]:443 — xray",
+		"and 1 more",
+	} {
+		if !strings.Contains(sender.lastText, want) {
+			t.Errorf("expected %q in message, got: %s", want, sender.lastText)
+		}
+	}
+}
+
+func TestFormatConnections_MarkMismatch(t *testing.T) {
+	snap := &conntrack.Snapshot{
+		Clients: []conntrack.ClientSummary{
+			{IP: "192.168.50.20", Route: "wgc1", Connections: 1},
+			{IP: "192.168.50.21", Route: "wgc1", Connections: 1, Paused: true},
+		},
+		Connections: []conntrack.Connection{
+			{Entry: conntrack.Entry{Proto: "udp", Src: "192.168.50.20", Dst: "1.1.1.1", DstPort: 53}, Route: "wgc1", MarkRoute: "xray"},
+			{Entry: conntrack.Entry{Proto: "icmp", Src: "192.168.50.21", Dst: "8.8.8.8"}, Route: "wgc1"},
+		},
+		Total: 2,
+	}
+
+	text := formatConnections("192.168.50.16/28", snap)
+
+	for _, want := range []string{
+		"192.168.50.21 → wgc1: 1 active, 0 marked (paused)",
+		"1. 192.168.50.20 udp 1.1.1.1:53 — marked xray",
+		"2. 192.168.50.21 icmp 8.8.8.8 — unmarked",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in:\n%s", want, text)
+		}
+	}
+}
+
+func TestFormatConnections_Empty(t *testing.T) {
+	text := formatConnections("192.168.50.10", &conntrack.Snapshot{})
+	if !strings.Contains(text, "No active connections") {
+		t.Errorf("unexpected text: %s", text)
+	}
+}
+
+func TestClientsHandler_ConnectionsError(t *testing.T) {
+	sender := &mockSenderClients{}
+	h := NewClientsHandler(&Deps{Sender: sender, Connections: &mockConnections{err: errors.New("permission denied")}})
+
+	h.HandleCallback(connectionsCallback("192.168.50.10"))
+
+	if len(sender.plainTexts) != 1 || !strings.Contains(sender.plainTexts[0], "permission denied") {
+		t.Errorf("expected error message, got %v", sender.plainTexts)
+	}
+}
+
+func TestClientsHandler_ConnectionsNotAvailable(t *testing.T) {
+	sender := &mockSenderClients{}
+	h := NewClientsHandler(&Deps{Sender: sender})
+
+	h.HandleCallback(connectionsCallback("192.168.50.10"))
+
+	if len(sender.plainTexts) != 1 || !strings.Contains(sender.plainTexts[0], "not available") {
+		t.Errorf("expected not available message, got %v", sender.plainTexts)
+	}
+}
@@ -0,0 +1,169 @@
+// internal/handler/domains.go
+package handler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/dnsmasq"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/telegram"
+)
+
+const domainsUsage = "Usage:\n" +
+	"/domains - list domains per route\n" +
+	"/domains add <route> <domain>\n" +
+	"/domains rm <route> <domain>\n" +
+	"/domains preview <route>\n\n" +
+	"Routes: direct (bypass Xray), wgc1-wgc5, ovpnc1-ovpnc5"
+
+// DomainsHandler handles the /domains command
+type DomainsHandler struct {
+	deps *Deps
+}
+
+// NewDomainsHandler creates a new DomainsHandler
+func NewDomainsHandler(deps *Deps) *DomainsHandler {
+	return &DomainsHandler{deps: deps}
+}
+
+// HandleDomains handles /domains [add|rm|preview] ... command
+func (h *DomainsHandler) HandleDomains(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	args := strings.Fields(msg.CommandArguments())
+
+	if len(args) == 0 {
+		h.sendList(chatID)
+		return
+	}
+
+	switch {
+	case (args[0] == "add" || args[0] == "rm") && len(args) == 3:
+		h.modify(msg.From, chatID, args[0], args[1], args[2])
+	case args[0] == "preview" && len(args) == 2:
+		h.sendPreview(chatID, args[1])
+	default:
+		h.deps.Sender.SendPlain(chatID, domainsUsage)
+	}
+}
+
+func (h *DomainsHandler) sendList(chatID int64) {
+	cfg, err := h.deps.Config.LoadVPNConfig()
+	if err != nil {
+		h.deps.Sender.SendPlain(chatID, fmt.Sprintf("Config load error: %v", err))
+		return
+	}
+
+	lists := dnsmasq.DomainLists(cfg)
+	if len(lists) == 0 {
+		h.deps.Sender.SendPlain(chatID, "No domains configured.\n\n"+domainsUsage)
+		return
+	}
+
+	routes := make([]string, 0, len(lists))
+	for route := range lists {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+
+	var sb strings.Builder
+	sb.WriteString("🌐 *Domain routing*\n")
+	for _, route := range routes {
+		sb.WriteString(fmt.Sprintf("\n*%s*:\n", telegram.EscapeMarkdownV2(route)))
+		for _, d := range lists[route] {
+			sb.WriteString(fmt.Sprintf("• `%s`\n", telegram.EscapeMarkdownV2(d)))
+		}
+	}
+	h.deps.Sender.Send(chatID, sb.String())
+}
+
+func (h *DomainsHandler) modify(from *tgbotapi.User, chatID int64, action, route, input string) {
+	if !dnsmasq.ValidRoute(route) {
+		h.deps.Sender.SendPlain(chatID, "Invalid route: must be one of direct, wgc1-wgc5, ovpnc1-ovpnc5")
+		return
+	}
+	domain, err := dnsmasq.NormalizeDomain(input)
+	if err != nil {
+		h.deps.Sender.SendPlain(chatID, fmt.Sprintf("Invalid domain: %v", err))
+		return
+	}
+
+	unlock, ok := h.deps.lockConfig(chatID)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	cfg, err := h.deps.Config.LoadVPNConfig()
+	if err != nil {
+		h.deps.Sender.SendPlain(chatID, fmt.Sprintf("Config load error: %v", err))
+		return
+	}
+
+	var applyRequired bool
+	if action == "add" {
+		if !dnsmasq.AddDomain(cfg, route, domain) {
+			h.deps.Sender.SendPlain(chatID, fmt.Sprintf("%s is already routed via %s", domain, dnsmasq.RouteOf(cfg, domain)))
+			return
+		}
+		// The first domain of a route needs an apply to create its ipset rule
+		applyRequired = len(dnsmasq.DomainLists(cfg)[route]) == 1
+	} else {
+		if !dnsmasq.RemoveDomain(cfg, route, domain) {
+			h.deps.Sender.SendPlain(chatID, fmt.Sprintf("%s not found for %s", domain, route))
+			return
+		}
+		applyRequired = len(dnsmasq.DomainLists(cfg)[route]) == 0
+	}
+
+	err = h.deps.Config.SaveVPNConfig(cfg)
+	if action == "add" {
+		h.deps.audit(from, "domains.add", domain, nil, route, err)
+	} else {
+		h.deps.audit(from, "domains.delete", domain, route, nil, err)
+	}
+	if err != nil {
+		h.deps.Sender.SendPlain(chatID, fmt.Sprintf("Save error: %v", err))
+		return
+	}
+
+	if _, err := h.deps.Domains.Sync(cfg); err != nil {
+		h.deps.Sender.SendPlain(chatID, fmt.Sprintf("dnsmasq update error: %v", err))
+		return
+	}
+
+	if applyRequired {
+		if err := h.deps.VPN.Apply(); err != nil {
+			h.deps.Sender.SendPlain(chatID, fmt.Sprintf("Apply error: %v", err))
+			return
+		}
+	}
+
+	if action == "add" {
+		h.deps.Sender.Send(chatID, telegram.EscapeMarkdownV2(fmt.Sprintf("✅ %s → %s", domain, route)))
+	} else {
+		h.deps.Sender.Send(chatID, telegram.EscapeMarkdownV2(fmt.Sprintf("🗑 %s removed from %s", domain, route)))
+	}
+}
+
+func (h *DomainsHandler) sendPreview(chatID int64, route string) {
+	if !dnsmasq.ValidRoute(route) {
+		h.deps.Sender.SendPlain(chatID, "Invalid route: must be one of direct, wgc1-wgc5, ovpnc1-ovpnc5")
+		return
+	}
+
+	entries, err := h.deps.Domains.Resolved(route)
+	if err != nil {
+		h.deps.Sender.Send(chatID, telegram.EscapeMarkdownV2(fmt.Sprintf("Error: %v", err)))
+		return
+	}
+
+	content := strings.Join(entries, "\n")
+	if content == "" {
+		content = "(empty)"
+	}
+	header := fmt.Sprintf("🔎 *%s* resolved entries \\(%d\\):",
+		telegram.EscapeMarkdownV2(dnsmasq.SetName(route)), len(entries))
+	h.deps.Sender.SendCodeBlock(chatID, header, content)
+}
@@ -0,0 +1,178 @@
+// internal/handler/domains_test.go
+package handler
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+)
+
+type mockDomainRouter struct {
+	entries   []string
+	syncErr   error
+	syncCalls int
+}
+
+func (m *mockDomainRouter) Sync(_ *vpnconfig.VPNDirectorConfig) (bool, error) {
+	m.syncCalls++
+	return m.syncErr == nil, m.syncErr
+}
+func (m *mockDomainRouter) Resolved(_ string) ([]string, error) { return m.entries, nil }
+
+type mockVPNApplyCounter struct {
+	mockVPNClients
+	applyCalls int
+}
+
+func (m *mockVPNApplyCounter) Apply() error {
+	m.applyCalls++
+	return m.applyErr
+}
+
+func domainsMsg(args string) *tgbotapi.Message {
+	text := "/domains"
+	if args != "" {
+		text += " " + args
+	}
+	return &tgbotapi.Message{
+		Text:     text,
+		Entities: []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len("/domains")}},
+		Chat:     &tgbotapi.Chat{ID: 100},
+	}
+}
+
+func TestDomainsHandler_List(t *testing.T) {
+	sender := &mockSenderClients{}
+	config := &mockConfigClients{vpnConfig: &vpnconfig.VPNDirectorConfig{
+		Xray: vpnconfig.XrayConfig{ExcludeDomains: []string{"bank.example"}},
+		TunnelDirector: vpnconfig.TunnelDirectorConfig{
+			Tunnels: map[string]vpnconfig.TunnelConfig{
+				"wgc1": {Domains: []string{"netflix.com"}},
+			},
+		},
+	}}
+	h := NewDomainsHandler(&Deps{Sender: sender, Config: config})
+
+	h.HandleDomains(domainsMsg(""))
+
+	if !strings.Contains(sender.lastText, "bank\\.example") || !strings.Contains(sender.lastText, "netflix\\.com") {
+		t.Errorf("expected both domains in list, got: %s", sender.lastText)
+	}
+	if strings.Index(sender.lastText, "direct") > strings.Index(sender.lastText, "wgc1") {
+		t.Errorf("expected routes sorted, got: %s", sender.lastText)
+	}
+}
+
+func TestDomainsHandler_AddFirstDomainApplies(t *testing.T) {
+	sender := &mockSenderClients{}
+	config := &mockConfigClients{vpnConfig: &vpnconfig.VPNDirectorConfig{}}
+	domains := &mockDomainRouter{}
+	vpn := &mockVPNApplyCounter{}
+	h := NewDomainsHandler(&Deps{Sender: sender, Config: config, Domains: domains, VPN: vpn})
+
+	h.HandleDomains(domainsMsg("add wgc1 *.Netflix.com"))
+
+	if config.savedConfig == nil {
+		t.Fatal("expected config to be saved")
+	}
+	if got := config.savedConfig.TunnelDirector.Tunnels["wgc1"].Domains; len(got) != 1 || got[0] != "netflix.com" {
+		t.Errorf("expected [netflix.com], got %v", got)
+	}
+	if domains.syncCalls != 1 {
+		t.Errorf("expected 1 sync, got %d", domains.syncCalls)
+	}
+	if vpn.applyCalls != 1 {
+		t.Errorf("expected apply for new route ipset, got %d calls", vpn.applyCalls)
+	}
+}
+
+func TestDomainsHandler_AddToExistingListSkipsApply(t *testing.T) {
+	sender := &mockSenderClients{}
+	config := &mockConfigClients{vpnConfig: &vpnconfig.VPNDirectorConfig{
+		Xray: vpnconfig.XrayConfig{ExcludeDomains: []string{"bank.example"}},
+	}}
+	vpn := &mockVPNApplyCounter{}
+	h := NewDomainsHandler(&Deps{Sender: sender, Config: config, Domains: &mockDomainRouter{}, VPN: vpn})
+
+	h.HandleDomains(domainsMsg("add direct gosuslugi.ru"))
+
+	if got := config.savedConfig.Xray.ExcludeDomains; len(got) != 2 {
+		t.Errorf("expected 2 domains, got %v", got)
+	}
+	if vpn.applyCalls != 0 {
+		t.Errorf("expected no apply, got %d calls", vpn.applyCalls)
+	}
+}
+
+func TestDomainsHandler_RemoveMissing(t *testing.T) {
+	sender := &mockSenderClients{}
+	config := &mockConfigClients{vpnConfig: &vpnconfig.VPNDirectorConfig{}}
+	h := NewDomainsHandler(&Deps{Sender: sender, Config: config, Domains: &mockDomainRouter{}})
+
+	h.HandleDomains(domainsMsg("rm wgc1 netflix.com"))
+
+	if config.savedConfig != nil {
+		t.Error("expected no save for missing domain")
+	}
+	if len(sender.plainTexts) != 1 || !strings.Contains(sender.plainTexts[0], "not found") {
+		t.Errorf("expected not found message, got %v", sender.plainTexts)
+	}
+}
+
+func TestDomainsHandler_InvalidInput(t *testing.T) {
+	tests := []struct {
+		name string
+		args string
+		want string
+	}{
+		{"bad route", "add xray example.com", "Invalid route"},
+		{"bad domain", "add wgc1 localhost", "Invalid domain"},
+		{"unknown action", "purge wgc1", "Usage"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &mockSenderClients{}
+			config := &mockConfigClients{vpnConfig: &vpnconfig.VPNDirectorConfig{}}
+			h := NewDomainsHandler(&Deps{Sender: sender, Config: config, Domains: &mockDomainRouter{}})
+
+			h.HandleDomains(domainsMsg(tt.args))
+
+			if len(sender.plainTexts) != 1 || !strings.Contains(sender.plainTexts[0], tt.want) {
+				t.Errorf("expected %q message, got %v", tt.want, sender.plainTexts)
+			}
+		})
+	}
+}
+
+func TestDomainsHandler_SyncError(t *testing.T) {
+	sender := &mockSenderClients{}
+	config := &mockConfigClients{vpnConfig: &vpnconfig.VPNDirectorConfig{}}
+	domains := &mockDomainRouter{syncErr: errors.New("restart failed")}
+	h := NewDomainsHandler(&Deps{Sender: sender, Config: config, Domains: domains})
+
+	h.HandleDomains(domainsMsg("add direct example.com"))
+
+	if len(sender.plainTexts) != 1 || !strings.Contains(sender.plainTexts[0], "restart failed") {
+		t.Errorf("expected sync error message, got %v", sender.plainTexts)
+	}
+}
+
+func TestDomainsHandler_Preview(t *testing.T) {
+	sender := &mockSender{}
+	domains := &mockDomainRouter{entries: []string{"1.2.3.4", "5.6.7.8"}}
+	h := NewDomainsHandler(&Deps{Sender: sender, Domains: domains})
+
+	msg := domainsMsg("preview wgc1")
+	msg.Chat.ID = 123
+	h.HandleDomains(msg)
+
+	if !strings.Contains(sender.lastCodeHeader, "VPD\\_DOM\\_WGC1") {
+		t.Errorf("expected ipset name in header, got: %s", sender.lastCodeHeader)
+	}
+	if sender.lastCodeContent != "1.2.3.4\n5.6.7.8" {
+		t.Errorf("unexpected content: %q", sender.lastCodeContent)
+	}
+}
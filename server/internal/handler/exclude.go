@@ -74,7 +74,7 @@ func (h *ExcludeHandler) HandleCallback(cb *tgbotapi.CallbackQuery) {
 		return
 
 	case action == "done":
-		h.saveAndApply(chatID, state)
+		h.saveAndApply(cb.From, chatID, state)
 		return
 
 	case action == "skip":
@@ -109,21 +109,10 @@ func (h *ExcludeHandler) HandleTextInput(msg *tgbotapi.Message) {
 		return
 	}
 
-	if !wizard.IsValidIPOrCIDR(input) {
-		h.sender.SendPlain(msg.Chat.ID,
-			"Invalid format. Enter IPv4 (1.2.3.4) or CIDR (10.0.0.0/8):")
+	if reply, ok := wizard.AddExcludeInput(state, input); !ok {
+		h.sender.SendPlain(msg.Chat.ID, reply)
 		return
 	}
-
-	// Check for duplicate
-	for _, existing := range state.GetExcludeIPs() {
-		if existing == input {
-			h.sender.SendPlain(msg.Chat.ID, "This IP/CIDR is already in the list")
-			return
-		}
-	}
-
-	state.AddExcludeIP(input)
 	h.renderUI(msg.Chat.ID, state)
 }
 
@@ -158,18 +147,27 @@ func (h *ExcludeHandler) buildUI(state *wizard.State) (string, tgbotapi.InlineKe
 	return sb.String(), kb.Build()
 }
 
-func (h *ExcludeHandler) saveAndApply(chatID int64, state *wizard.State) {
+func (h *ExcludeHandler) saveAndApply(from *tgbotapi.User, chatID int64, state *wizard.State) {
 	defer h.manager.Clear(chatID)
 
+	unlock, ok := h.deps.lockConfig(chatID)
+	if !ok {
+		return
+	}
+	defer unlock()
+
 	cfg, err := h.deps.Config.LoadVPNConfig()
 	if err != nil {
 		h.sender.SendPlain(chatID, fmt.Sprintf("Config load error: %v", err))
 		return
 	}
 
+	before := cfg.Xray.ExcludeIPs
 	cfg.Xray.ExcludeIPs = state.GetExcludeIPs()
 
-	if err := h.deps.Config.SaveVPNConfig(cfg); err != nil {
+	err = h.deps.Config.SaveVPNConfig(cfg)
+	h.deps.audit(from, "excludes.ips", "", before, cfg.Xray.ExcludeIPs, err)
+	if err != nil {
 		h.sender.SendPlain(chatID, fmt.Sprintf("Save error: %v", err))
 		return
 	}
@@ -2,21 +2,44 @@
 package handler
 
 import (
+	"fmt"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/accesslog"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/audit"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/banlist"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/conntrack"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/diagnostics"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/events"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/paths"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/service"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/speedtest"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/telegram"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/traffic"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/updater"
 )
 
 // Deps holds dependencies for all handlers
 type Deps struct {
-	Sender  telegram.MessageSender
-	Config  service.ConfigStore   // interface from service/
-	VPN     service.VPNDirector   // interface from service/
-	Xray    service.XrayGenerator // interface from service/
-	Network service.NetworkInfo   // interface from service/
-	Logs    service.LogReader     // interface from service/
-	Paths   paths.Paths
+	Sender      telegram.MessageSender
+	Config      service.ConfigStore      // interface from service/
+	VPN         service.VPNDirector      // interface from service/
+	Xray        service.XrayGenerator    // interface from service/
+	XrayConfig  service.XrayConfigReader // active server lookup; nil skips it
+	Network     service.NetworkInfo      // interface from service/
+	System      service.SystemInfo       // process state; nil skips it
+	Logs        service.LogReader        // interface from service/
+	Domains     service.DomainRouter     // interface from service/
+	Traffic     traffic.Reporter         // traffic accounting reports
+	XrayStats   service.XrayStats        // interface from service/
+	Access      accesslog.Searcher       // indexed Xray access log
+	Connections conntrack.Lister         // active connections from conntrack
+	Diagnostics diagnostics.RouteChecker // per-route exit and DNS checks
+	SpeedTest   speedtest.Tester         // background speed tests
+	Events      events.Recorder          // event history; nil disables recording
+	Audit       audit.Log                // audit trail; nil disables recording and /audit
+	Bans        banlist.Manager          // failed-login bans; nil disables /bans
+	Paths       paths.Paths
 	Version     string          // Clean version for semver parsing (v1.2.0)
 	VersionFull string          // Full git describe output (v1.2.0-5-gabc1234)
 	Commit      string          // Git commit hash
@@ -24,3 +47,52 @@ type Deps struct {
 	DevMode     bool            // Development mode flag
 	Updater     updater.Updater // Update service for /update command
 }
+
+// recordEvent adds an event to the history if one is configured.
+func (d *Deps) recordEvent(e events.Event) {
+	if d.Events != nil {
+		d.Events.Record(e)
+	}
+}
+
+// audit records an action done by from in the audit log if one is
+// configured. err is the reason the action failed, if it did.
+func (d *Deps) audit(from *tgbotapi.User, action, target string, before, after any, err error) {
+	recordAudit(d.Audit, from, action, target, before, after, err)
+}
+
+// lockConfig takes the cross-process config lock for a load-modify-save,
+// telling the chat when it cannot. The caller defers the returned unlock.
+func (d *Deps) lockConfig(chatID int64) (func(), bool) {
+	unlock, err := d.Config.LockConfig()
+	if err != nil {
+		d.Sender.SendPlain(chatID, fmt.Sprintf("Config lock error: %v", err))
+		return nil, false
+	}
+	return unlock, true
+}
+
+// xrayState is the state audited around restart and stop: whether Xray is
+// running. It is nil when that is unknown.
+func (d *Deps) xrayState() map[string]bool {
+	if d.System == nil {
+		return nil
+	}
+	running, err := d.System.ProcessRunning("xray")
+	if err != nil {
+		return nil
+	}
+	return map[string]bool{"xray_running": running}
+}
+
+// recordAudit records an action done by from in rec unless it is nil.
+func recordAudit(rec audit.Recorder, from *tgbotapi.User, action, target string, before, after any, err error) {
+	if rec == nil {
+		return
+	}
+	e := audit.Entry{Source: audit.SourceBot, Actor: telegram.UserRef(from), Action: action, Target: target, Before: before, After: after}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	rec.Record(e)
+}
@@ -6,7 +6,6 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"sort"
 	"strings"
 	"time"
 
@@ -101,6 +100,7 @@ func (h *ImportHandler) HandleImport(msg *tgbotapi.Message) {
 			UUID:    s.UUID,
 			Name:    s.Name,
 			IPs:     s.IPs,
+			IPs6:    s.IPs6,
 		})
 	}
 
@@ -109,29 +109,34 @@ func (h *ImportHandler) HandleImport(msg *tgbotapi.Message) {
 		return
 	}
 
+	unlock, ok := h.deps.lockConfig(msg.Chat.ID)
+	if !ok {
+		return
+	}
+	defer unlock()
+
 	// Save servers (SaveServers creates directory if needed)
-	if err := h.deps.Config.SaveServers(resolved); err != nil {
+	var before any
+	if old, err := h.deps.Config.LoadServers(); err == nil {
+		before = map[string]int{"servers": len(old)}
+	}
+	err = h.deps.Config.SaveServers(resolved)
+	h.deps.audit(msg.From, "servers.import", parsedURL.Host, before, map[string]int{"servers": len(resolved)}, err)
+	if err != nil {
 		h.deps.Sender.Send(msg.Chat.ID, telegram.EscapeMarkdownV2(fmt.Sprintf("Save error: %v", err)))
 		return
 	}
 
-	// Auto-sync xray.servers with IPs from all imported servers
+	// Auto-sync xray.servers with IPs from all imported servers and re-apply
+	// the Xray rules so the TPROXY bypass list matches
 	if vpnCfg, err := h.deps.Config.LoadVPNConfig(); err == nil && vpnCfg != nil {
-		seen := make(map[string]bool)
-		var serverIPs []string
-		for _, s := range resolved {
-			for _, ip := range s.IPs {
-				if ip != "" && !seen[ip] {
-					seen[ip] = true
-					serverIPs = append(serverIPs, ip)
-				}
-			}
-		}
-		sort.Strings(serverIPs)
-		vpnCfg.Xray.Servers = serverIPs
+		vpnCfg.Xray.Servers = vpnconfig.ServerIPs(resolved)
 		if err := h.deps.Config.SaveVPNConfig(vpnCfg); err != nil {
 			h.deps.Sender.Send(msg.Chat.ID, telegram.EscapeMarkdownV2(
 				fmt.Sprintf("Warning: servers imported but xray.servers sync failed: %v", err)))
+		} else if err := h.deps.VPN.ApplyXray(); err != nil {
+			h.deps.Sender.Send(msg.Chat.ID, telegram.EscapeMarkdownV2(
+				fmt.Sprintf("Warning: servers imported but applying Xray rules failed: %v", err)))
 		}
 	}
 
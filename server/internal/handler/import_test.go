@@ -109,7 +109,7 @@ func TestImportHandler_HandleImport_ValidSubscription(t *testing.T) {
 
 	sender := &mockSender{}
 	config := &mockConfigStoreForImport{dataDirVal: t.TempDir()}
-	deps := &Deps{Sender: sender, Config: config}
+	deps := &Deps{Sender: sender, Config: config, VPN: &mockVPNClients{}}
 	h := NewImportHandler(deps)
 
 	msg := &tgbotapi.Message{
@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/chatstore"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/loginapproval"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/telegram"
+)
+
+// maxUserAgent bounds the browser description shown in approval messages.
+const maxUserAgent = 120
+
+// LoginChats lists the chats asked to approve Web UI logins.
+type LoginChats interface {
+	GetActiveUsers() ([]chatstore.UserChat, error)
+}
+
+// LoginAuthorizer checks that a chat user is still on the allow-list.
+type LoginAuthorizer interface {
+	IsAuthorized(username string) bool
+}
+
+// LoginHandler asks authorized users to approve Web UI logins and handles
+// their login:approve / login:deny answers. The first answer wins.
+type LoginHandler struct {
+	sender  telegram.MessageSender
+	chats   LoginChats
+	auth    LoginAuthorizer
+	mu      sync.Mutex
+	pending map[string]chan loginapproval.Decision
+}
+
+// Compile-time interface check
+var _ loginapproval.Approver = (*LoginHandler)(nil)
+
+// NewLoginHandler creates a new login approval handler. chats may be nil
+// (dev mode), in which case no one can approve.
+func NewLoginHandler(sender telegram.MessageSender, chats LoginChats, auth LoginAuthorizer) *LoginHandler {
+	return &LoginHandler{
+		sender:  sender,
+		chats:   chats,
+		auth:    auth,
+		pending: make(map[string]chan loginapproval.Decision),
+	}
+}
+
+// Approve sends the request to every active authorized user and waits for
+// the first answer or for ctx to end.
+func (h *LoginHandler) Approve(ctx context.Context, req loginapproval.Request) loginapproval.Decision {
+	answer := make(chan loginapproval.Decision, 1)
+	h.mu.Lock()
+	if _, dup := h.pending[req.ID]; dup || req.ID == "" {
+		h.mu.Unlock()
+		return loginapproval.Decision{Status: loginapproval.StatusError, Error: "invalid request ID"}
+	}
+	h.pending[req.ID] = answer
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.pending, req.ID)
+		h.mu.Unlock()
+	}()
+
+	if h.sendRequest(req) == 0 {
+		return loginapproval.Decision{Status: loginapproval.StatusError, Error: "no Telegram users to ask; send the bot a command first"}
+	}
+
+	select {
+	case dec := <-answer:
+		return dec
+	case <-ctx.Done():
+		return loginapproval.Decision{Status: loginapproval.StatusExpired}
+	}
+}
+
+// sendRequest messages the approvers and returns how many were reached.
+func (h *LoginHandler) sendRequest(req loginapproval.Request) int {
+	if h.chats == nil {
+		return 0
+	}
+	users, err := h.chats.GetActiveUsers()
+	if err != nil {
+		slog.Warn("Failed to get active users", "error", err)
+		return 0
+	}
+
+	text := h.requestText(req)
+	kb := telegram.NewKeyboard().
+		Button("✅ Approve", "login:approve:"+req.ID).
+		Button("❌ Deny", "login:deny:"+req.ID).
+		Build()
+
+	sent := 0
+	for _, u := range users {
+		if !h.auth.IsAuthorized(u.Username) {
+			continue
+		}
+		if err := h.sender.SendWithKeyboard(u.ChatID, text, kb); err == nil {
+			sent++
+		}
+	}
+	return sent
+}
+
+func (h *LoginHandler) requestText(req loginapproval.Request) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🔐 *Approve Web UI login from %s?*\n\n", telegram.EscapeMarkdownV2(req.IP)))
+	sb.WriteString(fmt.Sprintf("Code: `%s`\n", telegram.EscapeMarkdownV2(req.Code)))
+	if ua := req.UserAgent; ua != "" {
+		if len(ua) > maxUserAgent {
+			ua = ua[:maxUserAgent] + "…"
+		}
+		sb.WriteString(fmt.Sprintf("Browser: %s\n", telegram.EscapeMarkdownV2(ua)))
+	}
+	sb.WriteString(telegram.EscapeMarkdownV2("\nApprove only if you are logging in now and the code matches the login page."))
+	return sb.String()
+}
+
+// HandleCallback handles login:approve:<id> and login:deny:<id> callbacks.
+func (h *LoginHandler) HandleCallback(cb *tgbotapi.CallbackQuery) {
+	if cb.Message == nil {
+		return
+	}
+	chatID := cb.Message.Chat.ID
+	msgID := cb.Message.MessageID
+	emptyKeyboard := tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}}
+
+	parts := strings.SplitN(cb.Data, ":", 3)
+	if len(parts) != 3 || (parts[1] != "approve" && parts[1] != "deny") {
+		return
+	}
+	action, id := parts[1], parts[2]
+
+	h.mu.Lock()
+	answer, ok := h.pending[id]
+	delete(h.pending, id)
+	h.mu.Unlock()
+	if !ok {
+		h.sender.EditMessage(chatID, msgID, telegram.EscapeMarkdownV2("Login request expired or already answered."), emptyKeyboard)
+		return
+	}
+
+	by := ""
+	if cb.From != nil {
+		by = cb.From.UserName
+	}
+	dec := loginapproval.Decision{Status: loginapproval.StatusDenied, By: by}
+	text := "✗ Login denied."
+	if action == "approve" {
+		dec.Status = loginapproval.StatusApproved
+		text = "✓ Login approved. You are signed in to the Web UI as " + by + "."
+	}
+	answer <- dec
+	slog.Info("Web UI login answered", "id", id, "status", dec.Status, "by", by)
+	h.sender.EditMessage(chatID, msgID, telegram.EscapeMarkdownV2(text), emptyKeyboard)
+}
@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/chatstore"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/loginapproval"
+)
+
+// mockSenderLogin records keyboards sent from the Approve goroutine.
+type mockSenderLogin struct {
+	mu       sync.Mutex
+	sentTo   []int64
+	texts    []string
+	buttons  []string // callback data of sent keyboards
+	editText string
+}
+
+func (m *mockSenderLogin) Send(chatID int64, text string) error          { return nil }
+func (m *mockSenderLogin) SendPlain(chatID int64, text string) error     { return nil }
+func (m *mockSenderLogin) SendLongPlain(chatID int64, text string) error { return nil }
+func (m *mockSenderLogin) SendWithKeyboard(chatID int64, text string, kb tgbotapi.InlineKeyboardMarkup) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sentTo = append(m.sentTo, chatID)
+	m.texts = append(m.texts, text)
+	for _, row := range kb.InlineKeyboard {
+		for _, b := range row {
+			m.buttons = append(m.buttons, *b.CallbackData)
+		}
+	}
+	return nil
+}
+func (m *mockSenderLogin) SendCodeBlock(chatID int64, header, content string) error { return nil }
+func (m *mockSenderLogin) EditMessage(chatID int64, msgID int, text string, kb tgbotapi.InlineKeyboardMarkup) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.editText = text
+	return nil
+}
+func (m *mockSenderLogin) AckCallback(callbackID string) error { return nil }
+
+func (m *mockSenderLogin) sentCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.sentTo)
+}
+
+type mockLoginChats struct {
+	users []chatstore.UserChat
+}
+
+func (m *mockLoginChats) GetActiveUsers() ([]chatstore.UserChat, error) { return m.users, nil }
+
+type mockLoginAuth struct {
+	allowed map[string]bool
+}
+
+func (m *mockLoginAuth) IsAuthorized(username string) bool { return m.allowed[username] }
+
+func newTestLoginHandler() (*LoginHandler, *mockSenderLogin) {
+	sender := &mockSenderLogin{}
+	chats := &mockLoginChats{users: []chatstore.UserChat{
+		{Username: "alice", ChatID: 1},
+		{Username: "bob", ChatID: 2},
+		{Username: "mallory", ChatID: 3}, // no longer allowed
+	}}
+	auth := &mockLoginAuth{allowed: map[string]bool{"alice": true, "bob": true}}
+	return NewLoginHandler(sender, chats, auth), sender
+}
+
+func loginCallback(data, user string) *tgbotapi.CallbackQuery {
+	return &tgbotapi.CallbackQuery{
+		Data:    data,
+		From:    &tgbotapi.User{UserName: user},
+		Message: &tgbotapi.Message{MessageID: 7, Chat: &tgbotapi.Chat{ID: 1}},
+	}
+}
+
+// approveAsync runs Approve in the background and waits for the messages.
+func approveAsync(t *testing.T, h *LoginHandler, sender *mockSenderLogin, ctx context.Context) <-chan loginapproval.Decision {
+	t.Helper()
+	out := make(chan loginapproval.Decision, 1)
+	req := loginapproval.Request{ID: "req1", Code: "482913", IP: "192.168.50.10", UserAgent: "Firefox"}
+	go func() { out <- h.Approve(ctx, req) }()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for sender.sentCount() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("approval messages not sent")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return out
+}
+
+func TestLoginHandler_Approve(t *testing.T) {
+	h, sender := newTestLoginHandler()
+	out := approveAsync(t, h, sender, context.Background())
+
+	sender.mu.Lock()
+	if len(sender.sentTo) != 2 || sender.sentTo[0] != 1 || sender.sentTo[1] != 2 {
+		t.Errorf("expected messages to alice and bob only, got %v", sender.sentTo)
+	}
+	if !strings.Contains(sender.texts[0], "192\\.168\\.50\\.10") || !strings.Contains(sender.texts[0], "482913") {
+		t.Errorf("expected IP and code in message, got %q", sender.texts[0])
+	}
+	if sender.buttons[0] != "login:approve:req1" || sender.buttons[1] != "login:deny:req1" {
+		t.Errorf("unexpected buttons: %v", sender.buttons)
+	}
+	sender.mu.Unlock()
+
+	h.HandleCallback(loginCallback("login:approve:req1", "alice"))
+	dec := <-out
+	if dec.Status != loginapproval.StatusApproved || dec.By != "alice" {
+		t.Errorf("unexpected decision: %+v", dec)
+	}
+	if !strings.Contains(sender.editText, "approved") {
+		t.Errorf("expected the message edited, got %q", sender.editText)
+	}
+
+	// A second answer finds nothing pending
+	h.HandleCallback(loginCallback("login:deny:req1", "bob"))
+	if !strings.Contains(sender.editText, "expired or already answered") {
+		t.Errorf("expected stale answer notice, got %q", sender.editText)
+	}
+}
+
+func TestLoginHandler_Deny(t *testing.T) {
+	h, sender := newTestLoginHandler()
+	out := approveAsync(t, h, sender, context.Background())
+
+	h.HandleCallback(loginCallback("login:deny:req1", "bob"))
+	if dec := <-out; dec.Status != loginapproval.StatusDenied || dec.By != "bob" {
+		t.Errorf("unexpected decision: %+v", dec)
+	}
+}
+
+func TestLoginHandler_Expires(t *testing.T) {
+	h, sender := newTestLoginHandler()
+	ctx, cancel := context.WithCancel(context.Background())
+	out := approveAsync(t, h, sender, ctx)
+
+	cancel()
+	if dec := <-out; dec.Status != loginapproval.StatusExpired {
+		t.Errorf("expected expired, got %+v", dec)
+	}
+}
+
+func TestLoginHandler_NoApprovers(t *testing.T) {
+	h := NewLoginHandler(&mockSenderLogin{}, nil, &mockLoginAuth{})
+
+	dec := h.Approve(context.Background(), loginapproval.Request{ID: "req1"})
+	if dec.Status != loginapproval.StatusError {
+		t.Errorf("expected error without chats, got %+v", dec)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/service"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/telegram"
 )
 
@@ -34,10 +35,19 @@ Commands:
 /servers \- server list
 /import \<url\> \- import servers
 /configure \- configuration
+/domains \- domain\-based routing
+/optimize \- merge excluded IP ranges
+/traffic \[hour\|day\|month\] \- top talkers
+/access \[ip\] \[period\] \- top destinations
+/check \- verify routes and DNS
+/speedtest \[route\|history\] \- measure throughput
+/bans \- blocked login attempts
+/unban \<ip\|@user\|all\> \- lift a ban
+/audit \[web\|bot\|scheduler\] \[@user\] \[action\] \- recent changes
 /restart \- restart VPN Director
 /stop \- stop VPN Director
 /logs \- recent logs
-/ip \- external IP
+/ip \[xray\|tunnel\] \- external IP
 /update \- update to latest release
 /version \- bot version`
 
@@ -50,14 +60,41 @@ func (h *MiscHandler) HandleVersion(msg *tgbotapi.Message) {
 	h.deps.Sender.Send(msg.Chat.ID, telegram.EscapeMarkdownV2(text))
 }
 
-// HandleIP handles /ip command
+// HandleIP handles /ip [xray|<tunnel>] - external IP of the WAN, or as seen
+// through Xray or a tunnel
 func (h *MiscHandler) HandleIP(msg *tgbotapi.Message) {
-	ip, err := h.deps.Network.GetExternalIP()
+	via := strings.TrimSpace(msg.CommandArguments())
+	if !service.ValidVia(via) {
+		h.deps.Sender.SendPlain(msg.Chat.ID, "Usage: /ip [xray|<tunnel>]\nExamples: /ip, /ip xray, /ip wgc1")
+		return
+	}
+
+	info, err := h.deps.Network.GetExternalIP(via)
 	if err != nil {
 		h.deps.Sender.Send(msg.Chat.ID, telegram.EscapeMarkdownV2(err.Error()))
 		return
 	}
-	h.deps.Sender.Send(msg.Chat.ID, "\U0001F310 External IP: `"+ip+"`")
+
+	text := "\U0001F310 External IP: `" + info.IP + "`"
+	if via != "" {
+		text += " via " + telegram.EscapeMarkdownV2(via)
+	}
+	if loc := formatIPLocation(info); loc != "" {
+		text += "\n" + telegram.EscapeMarkdownV2(loc)
+	}
+	h.deps.Sender.Send(msg.Chat.ID, text)
+}
+
+// formatIPLocation renders country, city and network of an address, e.g.
+// "NL, Amsterdam, AS60781 LeaseWeb" (empty when the provider gave none)
+func formatIPLocation(info *service.IPInfo) string {
+	var parts []string
+	for _, p := range []string{info.Country, info.City, strings.TrimSpace(info.ASN + " " + info.ISP)} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, ", ")
 }
 
 // HandleLogs handles /logs command
@@ -70,14 +107,14 @@ func (h *MiscHandler) HandleLogs(msg *tgbotapi.Message) {
 	// Parse arguments: /logs [source] [lines]
 	if len(args) >= 1 {
 		switch args[0] {
-		case "bot", "vpn", "all":
+		case "bot", "vpn", "xray", "all":
 			source = args[0]
 		default:
 			// Maybe it's a number
 			if n, err := strconv.Atoi(args[0]); err == nil && n > 0 {
 				lines = n
 			} else {
-				h.deps.Sender.Send(msg.Chat.ID, "Usage: `/logs [bot|vpn|all] [lines]`")
+				h.deps.Sender.Send(msg.Chat.ID, "Usage: `/logs [bot|vpn|xray|all] [lines]`")
 				return
 			}
 		}
@@ -101,6 +138,11 @@ func (h *MiscHandler) HandleLogs(msg *tgbotapi.Message) {
 	if source == "vpn" || source == "all" {
 		h.sendLogFile(msg.Chat.ID, h.deps.Paths.VPNLogPath, "VPN Director", lines)
 	}
+
+	// The Xray access log is noisy, so "all" leaves it out
+	if source == "xray" {
+		h.sendLogFile(msg.Chat.ID, h.deps.Paths.XrayLogPath, "Xray", lines)
+	}
 }
 
 func (h *MiscHandler) sendLogFile(chatID int64, path, name string, lines int) {
@@ -7,6 +7,7 @@ import (
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/paths"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/service"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/telegram"
 )
 
@@ -39,12 +40,14 @@ func (m *mockSender) EditMessage(chatID int64, msgID int, text string, kb tgbota
 func (m *mockSender) AckCallback(callbackID string) error { return nil }
 
 type mockNetworkInfo struct {
-	ip  string
-	err error
+	info *service.IPInfo
+	err  error
+	via  string
 }
 
-func (m *mockNetworkInfo) GetExternalIP() (string, error) {
-	return m.ip, m.err
+func (m *mockNetworkInfo) GetExternalIP(via string) (*service.IPInfo, error) {
+	m.via = via
+	return m.info, m.err
 }
 
 type mockLogReader struct {
@@ -104,7 +107,7 @@ func TestMiscHandler_HandleStart(t *testing.T) {
 
 func TestMiscHandler_HandleIP_Success(t *testing.T) {
 	sender := &mockSender{}
-	network := &mockNetworkInfo{ip: "1.2.3.4"}
+	network := &mockNetworkInfo{info: &service.IPInfo{IP: "1.2.3.4"}}
 	deps := &Deps{Sender: sender, Network: network}
 	h := NewMiscHandler(deps)
 
@@ -138,6 +141,42 @@ func TestMiscHandler_HandleIP_Error(t *testing.T) {
 	}
 }
 
+func TestMiscHandler_HandleIP_ViaWithLocation(t *testing.T) {
+	sender := &mockSender{}
+	network := &mockNetworkInfo{info: &service.IPInfo{IP: "185.10.10.10", Country: "NL", City: "Amsterdam", ASN: "AS60781", ISP: "LeaseWeb B.V."}}
+	h := NewMiscHandler(&Deps{Sender: sender, Network: network})
+
+	h.HandleIP(&tgbotapi.Message{
+		Text:     "/ip xray",
+		Chat:     &tgbotapi.Chat{ID: 789},
+		Entities: []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len("/ip")}},
+	})
+
+	if network.via != "xray" {
+		t.Errorf("expected lookup via xray, got %q", network.via)
+	}
+	expected := "\U0001F310 External IP: `185.10.10.10` via xray\nNL, Amsterdam, AS60781 LeaseWeb B\\.V\\."
+	if sender.lastText != expected {
+		t.Errorf("expected %q, got %q", expected, sender.lastText)
+	}
+}
+
+func TestMiscHandler_HandleIP_InvalidVia(t *testing.T) {
+	sender := &mockSender{}
+	network := &mockNetworkInfo{info: &service.IPInfo{IP: "1.2.3.4"}}
+	h := NewMiscHandler(&Deps{Sender: sender, Network: network})
+
+	h.HandleIP(&tgbotapi.Message{
+		Text:     "/ip wgc1 && reboot",
+		Chat:     &tgbotapi.Chat{ID: 789},
+		Entities: []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len("/ip")}},
+	})
+
+	if network.via != "" || sender.lastText != "" {
+		t.Errorf("expected usage instead of a lookup, got via %q and %q", network.via, sender.lastText)
+	}
+}
+
 func TestMiscHandler_HandleLogs_DefaultArgs(t *testing.T) {
 	sender := &mockSender{}
 	logReader := &mockLogReader{output: "log line 1\nlog line 2"}
@@ -201,6 +240,34 @@ func TestMiscHandler_HandleLogs_SourceBot(t *testing.T) {
 	}
 }
 
+func TestMiscHandler_HandleLogs_SourceXray(t *testing.T) {
+	sender := &mockSender{}
+	logReader := &mockLogReader{output: "xray log"}
+	testPaths := paths.Paths{
+		BotLogPath:  "/tmp/bot.log",
+		VPNLogPath:  "/tmp/vpn.log",
+		XrayLogPath: "/tmp/xray.log",
+	}
+	deps := &Deps{Sender: sender, Logs: logReader, Paths: testPaths}
+	h := NewMiscHandler(deps)
+
+	msg := &tgbotapi.Message{
+		Chat: &tgbotapi.Chat{ID: 100},
+		Text: "/logs xray",
+		Entities: []tgbotapi.MessageEntity{
+			{Type: "bot_command", Offset: 0, Length: 5},
+		},
+	}
+	h.HandleLogs(msg)
+
+	if len(logReader.calls) != 1 {
+		t.Fatalf("expected 1 log read call, got %d", len(logReader.calls))
+	}
+	if logReader.calls[0].path != "/tmp/xray.log" {
+		t.Errorf("expected xray log path, got %q", logReader.calls[0].path)
+	}
+}
+
 func TestMiscHandler_HandleLogs_SourceVPN(t *testing.T) {
 	sender := &mockSender{}
 	logReader := &mockLogReader{output: "vpn log"}
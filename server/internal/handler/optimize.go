@@ -0,0 +1,139 @@
+// internal/handler/optimize.go
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/telegram"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+)
+
+// OptimizeHandler handles the /optimize command
+type OptimizeHandler struct {
+	deps *Deps
+}
+
+// NewOptimizeHandler creates a new OptimizeHandler
+func NewOptimizeHandler(deps *Deps) *OptimizeHandler {
+	return &OptimizeHandler{deps: deps}
+}
+
+// HandleOptimize handles /optimize - previews CIDR aggregation of exclude_ips,
+// reports overlapping clients and asks for confirmation if anything would
+// change
+func (h *OptimizeHandler) HandleOptimize(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+
+	cfg, err := h.deps.Config.LoadVPNConfig()
+	if err != nil {
+		h.deps.Sender.SendPlain(chatID, fmt.Sprintf("Config load error: %v", err))
+		return
+	}
+
+	report := vpnconfig.Optimize(cfg)
+	text := telegram.EscapeMarkdownV2(formatOptimizeReport(report))
+
+	if len(report.Changes) == 0 {
+		h.deps.Sender.Send(chatID, text)
+		return
+	}
+	h.deps.Sender.SendWithKeyboard(chatID, text, optimizeKeyboard(report))
+}
+
+// optimizeKeyboard asks to confirm the previewed changes; the apply button
+// carries their fingerprint
+func optimizeKeyboard(report vpnconfig.OptimizeReport) tgbotapi.InlineKeyboardMarkup {
+	return telegram.NewKeyboard().
+		Button("✅ Apply", "optimize:apply:"+report.Fingerprint()).
+		Button("❌ Cancel", "optimize:cancel").
+		Row().
+		Build()
+}
+
+// HandleCallback handles optimize:apply:<fingerprint> and optimize:cancel
+// callbacks
+func (h *OptimizeHandler) HandleCallback(cb *tgbotapi.CallbackQuery) {
+	if cb.Message == nil {
+		return
+	}
+
+	chatID := cb.Message.Chat.ID
+	msgID := cb.Message.MessageID
+	emptyKeyboard := tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}}
+
+	fingerprint, apply := strings.CutPrefix(cb.Data, "optimize:apply:")
+	switch {
+	case cb.Data == "optimize:cancel":
+		h.deps.Sender.EditMessage(chatID, msgID, telegram.EscapeMarkdownV2("Optimization cancelled."), emptyKeyboard)
+	case apply:
+		unlock, ok := h.deps.lockConfig(chatID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		// Re-run on the current config: it may have changed since the preview
+		cfg, err := h.deps.Config.LoadVPNConfig()
+		if err != nil {
+			h.deps.Sender.SendPlain(chatID, fmt.Sprintf("Config load error: %v", err))
+			return
+		}
+
+		report := vpnconfig.Optimize(cfg)
+		if len(report.Changes) == 0 {
+			h.deps.Sender.EditMessage(chatID, msgID, telegram.EscapeMarkdownV2("Nothing to optimize."), emptyKeyboard)
+			return
+		}
+		if report.Fingerprint() != fingerprint {
+			text := "The lists changed since the preview, nothing was saved.\n\n" + formatOptimizeReport(report)
+			h.deps.Sender.EditMessage(chatID, msgID, telegram.EscapeMarkdownV2(text), optimizeKeyboard(report))
+			return
+		}
+
+		before, after := report.Lists()
+		err = h.deps.Config.SaveVPNConfig(cfg)
+		h.deps.audit(cb.From, "optimize", "", before, after, err)
+		if err != nil {
+			h.deps.Sender.SendPlain(chatID, fmt.Sprintf("Config save error: %v", err))
+			return
+		}
+		if err := h.deps.VPN.Apply(); err != nil {
+			h.deps.Sender.SendPlain(chatID, fmt.Sprintf("Saved, but apply failed: %v", err))
+			return
+		}
+
+		text := fmt.Sprintf("✓ Optimized %d list(s) and applied.", len(report.Changes))
+		h.deps.Sender.EditMessage(chatID, msgID, telegram.EscapeMarkdownV2(text), emptyKeyboard)
+	}
+}
+
+// formatOptimizeReport renders the report as plain text (escape before sending)
+func formatOptimizeReport(report vpnconfig.OptimizeReport) string {
+	var sb strings.Builder
+
+	if len(report.Changes) == 0 {
+		sb.WriteString("✓ Address lists are already optimal.\n")
+	} else {
+		sb.WriteString("🧮 Optimization preview\n")
+		for _, c := range report.Changes {
+			sb.WriteString(fmt.Sprintf("\n%s (%d → %d):\n", c.List, len(c.Before), len(c.After)))
+			sb.WriteString(fmt.Sprintf("- %s\n", strings.Join(c.Before, ", ")))
+			sb.WriteString(fmt.Sprintf("+ %s\n", strings.Join(c.After, ", ")))
+		}
+	}
+
+	if len(report.Conflicts) > 0 {
+		sb.WriteString("\n⚠️ Overlapping clients (first matching rule wins):\n")
+		for _, c := range report.Conflicts {
+			sb.WriteString(fmt.Sprintf("• %s\n", c.String()))
+		}
+	}
+
+	if len(report.Invalid) > 0 {
+		sb.WriteString(fmt.Sprintf("\n⚠️ Invalid entries (kept as-is): %s\n", strings.Join(report.Invalid, ", ")))
+	}
+
+	return strings.TrimRight(sb.String(), "\n")
+}
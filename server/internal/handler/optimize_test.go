@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+)
+
+func optimizeConfig() *vpnconfig.VPNDirectorConfig {
+	return &vpnconfig.VPNDirectorConfig{
+		Xray: vpnconfig.XrayConfig{
+			Clients:    []string{"192.168.1.0/24"},
+			ExcludeIPs: []string{"10.0.0.0/25", "10.0.0.128/25"},
+		},
+		TunnelDirector: vpnconfig.TunnelDirectorConfig{
+			Tunnels: map[string]vpnconfig.TunnelConfig{
+				"wgc1": {Clients: []string{"192.168.1.50"}},
+			},
+		},
+	}
+}
+
+// applyCallbackData returns the apply button data for optimizeConfig.
+func applyCallbackData() string {
+	return "optimize:apply:" + vpnconfig.Optimize(optimizeConfig()).Fingerprint()
+}
+
+func optimizeCallback(data string) *tgbotapi.CallbackQuery {
+	return &tgbotapi.CallbackQuery{
+		Data:    data,
+		Message: &tgbotapi.Message{MessageID: 7, Chat: &tgbotapi.Chat{ID: 100}},
+	}
+}
+
+func TestOptimizeHandler_HandleOptimize_Preview(t *testing.T) {
+	sender := &mockSenderClients{}
+	config := &mockConfigClients{vpnConfig: optimizeConfig()}
+	h := NewOptimizeHandler(&Deps{Sender: sender, Config: config})
+
+	h.HandleOptimize(&tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 100}})
+
+	if !strings.Contains(sender.lastText, "10\\.0\\.0\\.0/24") {
+		t.Errorf("expected aggregated prefix in preview, got: %s", sender.lastText)
+	}
+	if !strings.Contains(sender.lastText, "Overlapping clients") {
+		t.Errorf("expected conflict warning, got: %s", sender.lastText)
+	}
+	if len(sender.lastKeyboard.InlineKeyboard) != 1 || len(sender.lastKeyboard.InlineKeyboard[0]) != 2 {
+		t.Fatalf("expected apply/cancel keyboard, got %+v", sender.lastKeyboard)
+	}
+	if got := *sender.lastKeyboard.InlineKeyboard[0][0].CallbackData; got != applyCallbackData() {
+		t.Errorf("expected the apply button to carry the fingerprint, got %q", got)
+	}
+	if config.savedConfig != nil {
+		t.Error("preview must not save configuration")
+	}
+}
+
+func TestOptimizeHandler_HandleOptimize_NothingToDo(t *testing.T) {
+	sender := &mockSenderClients{}
+	config := &mockConfigClients{vpnConfig: &vpnconfig.VPNDirectorConfig{
+		Xray: vpnconfig.XrayConfig{Clients: []string{"192.168.1.10"}},
+	}}
+	h := NewOptimizeHandler(&Deps{Sender: sender, Config: config})
+
+	h.HandleOptimize(&tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 100}})
+
+	if !strings.Contains(sender.lastText, "already optimal") {
+		t.Errorf("expected already optimal message, got: %s", sender.lastText)
+	}
+	if len(sender.lastKeyboard.InlineKeyboard) != 0 {
+		t.Error("expected no keyboard when nothing changes")
+	}
+}
+
+func TestOptimizeHandler_HandleOptimize_LoadError(t *testing.T) {
+	sender := &mockSenderClients{}
+	config := &mockConfigClients{loadErr: errors.New("boom")}
+	h := NewOptimizeHandler(&Deps{Sender: sender, Config: config})
+
+	h.HandleOptimize(&tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 100}})
+
+	if len(sender.plainTexts) != 1 || !strings.Contains(sender.plainTexts[0], "boom") {
+		t.Errorf("expected load error message, got %v", sender.plainTexts)
+	}
+}
+
+func TestOptimizeHandler_HandleCallback_Apply(t *testing.T) {
+	sender := &mockSenderClients{}
+	config := &mockConfigClients{vpnConfig: optimizeConfig()}
+	vpn := &mockVPNApplyCounter{}
+	h := NewOptimizeHandler(&Deps{Sender: sender, Config: config, VPN: vpn})
+
+	h.HandleCallback(optimizeCallback(applyCallbackData()))
+
+	if config.savedConfig == nil {
+		t.Fatal("expected config to be saved")
+	}
+	if got := config.savedConfig.Xray.ExcludeIPs; len(got) != 1 || got[0] != "10.0.0.0/24" {
+		t.Errorf("unexpected saved exclude_ips: %v", got)
+	}
+	if vpn.applyCalls != 1 {
+		t.Errorf("expected 1 apply call, got %d", vpn.applyCalls)
+	}
+	if sender.editMsgID != 7 || !strings.Contains(sender.editText, "Optimized 1 list") {
+		t.Errorf("unexpected edit: msgID=%d text=%s", sender.editMsgID, sender.editText)
+	}
+}
+
+func TestOptimizeHandler_HandleCallback_ApplyError(t *testing.T) {
+	sender := &mockSenderClients{}
+	config := &mockConfigClients{vpnConfig: optimizeConfig()}
+	vpn := &mockVPNApplyCounter{mockVPNClients: mockVPNClients{applyErr: errors.New("apply failed")}}
+	h := NewOptimizeHandler(&Deps{Sender: sender, Config: config, VPN: vpn})
+
+	h.HandleCallback(optimizeCallback(applyCallbackData()))
+
+	if len(sender.plainTexts) != 1 || !strings.Contains(sender.plainTexts[0], "apply failed") {
+		t.Errorf("expected apply error message, got %v", sender.plainTexts)
+	}
+}
+
+func TestOptimizeHandler_HandleCallback_ChangedSincePreview(t *testing.T) {
+	sender := &mockSenderClients{}
+	config := &mockConfigClients{vpnConfig: optimizeConfig()}
+	h := NewOptimizeHandler(&Deps{Sender: sender, Config: config, VPN: &mockVPNApplyCounter{}})
+	preview := applyCallbackData()
+
+	// Another entry was added after the preview was shown
+	config.vpnConfig.Xray.ExcludeIPs = append(config.vpnConfig.Xray.ExcludeIPs, "10.0.1.0/24")
+	h.HandleCallback(optimizeCallback(preview))
+
+	if config.savedConfig != nil {
+		t.Error("expected nothing saved when the lists changed since the preview")
+	}
+	if !strings.Contains(sender.editText, "changed since the preview") || !strings.Contains(sender.editText, "10\\.0\\.0\\.0/23") {
+		t.Errorf("expected the new preview, got: %s", sender.editText)
+	}
+	if len(sender.editKeyboard.InlineKeyboard) != 1 {
+		t.Errorf("expected a new apply/cancel keyboard, got %+v", sender.editKeyboard)
+	}
+}
+
+func TestOptimizeHandler_HandleCallback_Cancel(t *testing.T) {
+	sender := &mockSenderClients{}
+	config := &mockConfigClients{vpnConfig: optimizeConfig()}
+	h := NewOptimizeHandler(&Deps{Sender: sender, Config: config})
+
+	h.HandleCallback(optimizeCallback("optimize:cancel"))
+
+	if config.savedConfig != nil {
+		t.Error("cancel must not save configuration")
+	}
+	if !strings.Contains(sender.editText, "cancelled") {
+		t.Errorf("expected cancelled message, got: %s", sender.editText)
+	}
+}
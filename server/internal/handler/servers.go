@@ -5,22 +5,37 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"sync"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/telegram"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vless"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
 )
 
 const serversPerPage = 15
 
-// ServersHandler handles /servers command
+// ServersHandler handles /servers command and adding a server by pasting
+// a vless:// link
 type ServersHandler struct {
-	deps *Deps
+	deps    *Deps
+	mu      sync.Mutex
+	pending map[int64]*vless.Server // parsed link awaiting confirmation
 }
 
 // NewServersHandler creates a new ServersHandler
 func NewServersHandler(deps *Deps) *ServersHandler {
-	return &ServersHandler{deps: deps}
+	return &ServersHandler{
+		deps:    deps,
+		pending: make(map[int64]*vless.Server),
+	}
+}
+
+// ClearState drops a link awaiting confirmation
+func (h *ServersHandler) ClearState(chatID int64) {
+	h.mu.Lock()
+	delete(h.pending, chatID)
+	h.mu.Unlock()
 }
 
 // HandleServers handles /servers command - loads servers from config, builds paginated list
@@ -40,7 +55,8 @@ func (h *ServersHandler) HandleServers(msg *tgbotapi.Message) {
 	h.deps.Sender.SendWithKeyboard(msg.Chat.ID, text, keyboard)
 }
 
-// HandleCallback handles servers pagination callbacks (servers:page:N)
+// HandleCallback handles servers pagination (servers:page:N) and add
+// confirmation (servers:add, servers:cancel) callbacks
 func (h *ServersHandler) HandleCallback(cb *tgbotapi.CallbackQuery) {
 	// Acknowledge callback
 	h.deps.Sender.AckCallback(cb.ID)
@@ -53,6 +69,17 @@ func (h *ServersHandler) HandleCallback(cb *tgbotapi.CallbackQuery) {
 	chatID := cb.Message.Chat.ID
 	data := cb.Data
 
+	switch data {
+	case "servers:add":
+		h.handleAddConfirm(cb.From, chatID, cb.Message.MessageID)
+		return
+	case "servers:cancel":
+		h.ClearState(chatID)
+		h.deps.Sender.EditMessage(chatID, cb.Message.MessageID,
+			telegram.EscapeMarkdownV2("Cancelled."), tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}})
+		return
+	}
+
 	// Parse page number from "servers:page:N"
 	var page int
 	if _, err := fmt.Sscanf(data, "servers:page:%d", &page); err != nil {
@@ -70,6 +97,110 @@ func (h *ServersHandler) HandleCallback(cb *tgbotapi.CallbackQuery) {
 	h.deps.Sender.EditMessage(chatID, cb.Message.MessageID, text, keyboard)
 }
 
+// HandleTextInput offers to add a server when a vless:// link is pasted.
+// Other text is ignored.
+func (h *ServersHandler) HandleTextInput(msg *tgbotapi.Message) {
+	text := strings.TrimSpace(msg.Text)
+	if !strings.HasPrefix(text, "vless://") {
+		return
+	}
+	chatID := msg.Chat.ID
+
+	srv, err := vless.ParseURI(text)
+	if err != nil {
+		h.deps.Sender.Send(chatID, telegram.EscapeMarkdownV2(fmt.Sprintf("Invalid vless link: %v", err)))
+		return
+	}
+
+	h.mu.Lock()
+	h.pending[chatID] = srv
+	h.mu.Unlock()
+
+	preview := fmt.Sprintf("Add server?\n\nName: %s\nAddress: %s:%d\nUUID: %s", srv.Name, srv.Address, srv.Port, srv.UUID)
+	kb := telegram.NewKeyboard().
+		Button("\u2795 Add", "servers:add").
+		Button("\u2716 Cancel", "servers:cancel").
+		Row().
+		Build()
+	h.deps.Sender.SendWithKeyboard(chatID, telegram.EscapeMarkdownV2(preview), kb)
+}
+
+// handleAddConfirm resolves the pending server and appends it to servers.json.
+// xray.servers is synced so the new server is bypassed once rules are applied.
+func (h *ServersHandler) handleAddConfirm(from *tgbotapi.User, chatID int64, msgID int) {
+	h.mu.Lock()
+	srv := h.pending[chatID]
+	delete(h.pending, chatID)
+	h.mu.Unlock()
+
+	empty := tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}}
+	reply := func(text string) {
+		h.deps.Sender.EditMessage(chatID, msgID, telegram.EscapeMarkdownV2(text), empty)
+	}
+
+	if srv == nil {
+		reply("Nothing to add. Paste a vless:// link first.")
+		return
+	}
+
+	if err := srv.ResolveIPs(); err != nil {
+		reply(fmt.Sprintf("Cannot resolve %s: %v", srv.Address, err))
+		return
+	}
+
+	unlock, ok := h.deps.lockConfig(chatID)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	servers, err := h.deps.Config.LoadServers()
+	if err != nil {
+		reply(fmt.Sprintf("Error: %v", err))
+		return
+	}
+
+	server := vpnconfig.Server{
+		Address: srv.Address,
+		Port:    srv.Port,
+		UUID:    srv.UUID,
+		Name:    srv.Name,
+		IPs:     srv.IPs,
+		IPs6:    srv.IPs6,
+	}
+	if dup := vpnconfig.FindDuplicate(servers, server, -1); dup >= 0 {
+		reply(fmt.Sprintf("Server already exists: %s", servers[dup].Name))
+		return
+	}
+
+	servers = append(append([]vpnconfig.Server{}, servers...), server)
+	vpnconfig.AssignServerIDs(servers)
+	err = h.deps.Config.SaveServers(servers)
+	// The UUID is a credential and stays out of the audit log
+	h.deps.audit(from, "servers.add", servers[len(servers)-1].ID, nil,
+		map[string]any{"name": server.Name, "address": server.Address, "port": server.Port}, err)
+	if err != nil {
+		reply(fmt.Sprintf("Save error: %v", err))
+		return
+	}
+
+	cfg, err := h.deps.Config.LoadVPNConfig()
+	if err == nil && cfg != nil {
+		cfg.Xray.Servers = vpnconfig.ServerIPs(servers)
+		err = h.deps.Config.SaveVPNConfig(cfg)
+	}
+	if err != nil {
+		reply(fmt.Sprintf("Server added, but xray.servers sync failed: %v", err))
+		return
+	}
+	if err := h.deps.VPN.ApplyXray(); err != nil {
+		reply(fmt.Sprintf("Server added, but applying Xray rules failed: %v", err))
+		return
+	}
+
+	reply(fmt.Sprintf("\u2713 Added %s (%s). Use /xray to switch to it.", server.Name, strings.Join(server.AllIPs(), ", ")))
+}
+
 // extractCountry extracts country name from server name format "Country, City"
 func extractCountry(name string) string {
 	parts := strings.SplitN(name, ",", 2)
@@ -150,7 +281,7 @@ func buildServersPage(servers []vpnconfig.Server, page int) (string, tgbotapi.In
 			i+1,
 			telegram.EscapeMarkdownV2(s.Name),
 			telegram.EscapeMarkdownV2(s.Address),
-			telegram.EscapeMarkdownV2(strings.Join(s.IPs, ", "))))
+			telegram.EscapeMarkdownV2(strings.Join(s.AllIPs(), ", "))))
 	}
 
 	// Navigation buttons
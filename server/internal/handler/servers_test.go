@@ -463,3 +463,164 @@ func TestServersHandler_HandleCallback_NilMessage(t *testing.T) {
 		t.Errorf("expected callback to be acknowledged even with nil Message")
 	}
 }
+
+// Tests for adding a server by pasting a vless:// link
+
+// mockConfigServers stores servers and config for add flow tests
+type mockConfigServers struct {
+	servers      []vpnconfig.Server
+	cfg          *vpnconfig.VPNDirectorConfig
+	savedServers []vpnconfig.Server
+	savedCfg     *vpnconfig.VPNDirectorConfig
+}
+
+func (m *mockConfigServers) LoadVPNConfig() (*vpnconfig.VPNDirectorConfig, error) { return m.cfg, nil }
+func (m *mockConfigServers) SaveVPNConfig(cfg *vpnconfig.VPNDirectorConfig) error {
+	m.savedCfg = cfg
+	return nil
+}
+func (m *mockConfigServers) LoadServers() ([]vpnconfig.Server, error) { return m.servers, nil }
+func (m *mockConfigServers) SaveServers(s []vpnconfig.Server) error {
+	m.savedServers = s
+	return nil
+}
+func (m *mockConfigServers) DataDir() (string, error)    { return "/data", nil }
+func (m *mockConfigServers) DataDirOrDefault() string    { return "/data" }
+func (m *mockConfigServers) ScriptsDir() string          { return "/scripts" }
+func (m *mockConfigServers) LockConfig() (func(), error) { return func() {}, nil }
+
+func pasteLink(h *ServersHandler, text string) {
+	h.HandleTextInput(&tgbotapi.Message{Text: text, Chat: &tgbotapi.Chat{ID: 100}})
+}
+
+func serversCallback(h *ServersHandler, data string) {
+	h.HandleCallback(&tgbotapi.CallbackQuery{
+		ID:      "cb",
+		Data:    data,
+		Message: &tgbotapi.Message{MessageID: 42, Chat: &tgbotapi.Chat{ID: 100}},
+	})
+}
+
+func TestServersHandler_HandleTextInput_ShowsPreview(t *testing.T) {
+	sender := &mockSenderWithKeyboard{}
+	h := NewServersHandler(&Deps{Sender: sender, Config: &mockConfigServers{}})
+
+	pasteLink(h, "vless://uuid-1@203.0.113.10:443?security=reality#Germany")
+
+	if !strings.Contains(sender.lastText, "Germany") || !strings.Contains(sender.lastText, "203\\.0\\.113\\.10:443") {
+		t.Errorf("expected preview with name and address, got %q", sender.lastText)
+	}
+	kb := sender.lastKeyboard.InlineKeyboard
+	if len(kb) != 1 || len(kb[0]) != 2 || *kb[0][0].CallbackData != "servers:add" {
+		t.Errorf("expected Add/Cancel buttons, got %+v", kb)
+	}
+}
+
+func TestServersHandler_HandleTextInput_IgnoresOtherText(t *testing.T) {
+	sender := &mockSenderWithKeyboard{}
+	h := NewServersHandler(&Deps{Sender: sender, Config: &mockConfigServers{}})
+
+	pasteLink(h, "192.168.1.10")
+
+	if sender.lastChatID != 0 {
+		t.Errorf("expected no reply, got %q", sender.lastText)
+	}
+}
+
+func TestServersHandler_HandleTextInput_InvalidLink(t *testing.T) {
+	sender := &mockSenderWithKeyboard{}
+	h := NewServersHandler(&Deps{Sender: sender, Config: &mockConfigServers{}})
+
+	pasteLink(h, "vless://broken")
+
+	if !strings.Contains(sender.lastText, "Invalid vless link") {
+		t.Errorf("expected error, got %q", sender.lastText)
+	}
+}
+
+type mockVPNXrayCounter struct {
+	mockVPNClients
+	applyXrayCalls int
+}
+
+func (m *mockVPNXrayCounter) ApplyXray() error {
+	m.applyXrayCalls++
+	return nil
+}
+
+func TestServersHandler_AddConfirm(t *testing.T) {
+	sender := &mockSenderWithKeyboard{}
+	config := &mockConfigServers{
+		servers: []vpnconfig.Server{
+			{ID: "aaaa1111", Name: "Old", Address: "198.51.100.1", Port: 443, UUID: "uuid-0", IPs: []string{"198.51.100.1"}},
+		},
+		cfg: &vpnconfig.VPNDirectorConfig{},
+	}
+	vpn := &mockVPNXrayCounter{}
+	h := NewServersHandler(&Deps{Sender: sender, Config: config, VPN: vpn})
+
+	// IP literal resolves without DNS
+	pasteLink(h, "vless://uuid-1@203.0.113.10:443?security=reality#Germany")
+	serversCallback(h, "servers:add")
+
+	if len(config.savedServers) != 2 {
+		t.Fatalf("expected 2 saved servers, got %d", len(config.savedServers))
+	}
+	added := config.savedServers[1]
+	if added.ID == "" || added.Name != "Germany" || len(added.IPs) != 1 || added.IPs[0] != "203.0.113.10" {
+		t.Errorf("unexpected added server: %+v", added)
+	}
+	if len(config.servers) != 1 {
+		t.Error("loaded servers slice must not be modified in place")
+	}
+	if config.savedCfg == nil || len(config.savedCfg.Xray.Servers) != 2 {
+		t.Errorf("expected xray.servers synced, got %+v", config.savedCfg)
+	}
+	if vpn.applyXrayCalls != 1 {
+		t.Errorf("expected xray rules applied once, got %d", vpn.applyXrayCalls)
+	}
+	if sender.lastMsgID != 42 || !strings.Contains(sender.lastText, "Added Germany") {
+		t.Errorf("expected edited confirmation, got %q", sender.lastText)
+	}
+
+	// Pending link is consumed
+	serversCallback(h, "servers:add")
+	if !strings.Contains(sender.lastText, "Nothing to add") {
+		t.Errorf("expected nothing pending, got %q", sender.lastText)
+	}
+}
+
+func TestServersHandler_AddConfirm_Duplicate(t *testing.T) {
+	sender := &mockSenderWithKeyboard{}
+	config := &mockConfigServers{
+		servers: []vpnconfig.Server{
+			{ID: "aaaa1111", Name: "Existing", Address: "203.0.113.10", Port: 443, UUID: "uuid-1"},
+		},
+		cfg: &vpnconfig.VPNDirectorConfig{},
+	}
+	h := NewServersHandler(&Deps{Sender: sender, Config: config})
+
+	pasteLink(h, "vless://uuid-1@203.0.113.10:443?security=reality#Germany")
+	serversCallback(h, "servers:add")
+
+	if config.savedServers != nil {
+		t.Error("expected duplicate not to be saved")
+	}
+	if !strings.Contains(sender.lastText, "already exists") {
+		t.Errorf("expected duplicate message, got %q", sender.lastText)
+	}
+}
+
+func TestServersHandler_AddCancel(t *testing.T) {
+	sender := &mockSenderWithKeyboard{}
+	config := &mockConfigServers{cfg: &vpnconfig.VPNDirectorConfig{}}
+	h := NewServersHandler(&Deps{Sender: sender, Config: config})
+
+	pasteLink(h, "vless://uuid-1@203.0.113.10:443?security=reality#Germany")
+	serversCallback(h, "servers:cancel")
+	serversCallback(h, "servers:add")
+
+	if config.savedServers != nil {
+		t.Error("expected nothing saved after cancel")
+	}
+}
@@ -0,0 +1,114 @@
+// internal/handler/speedtest.go
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/speedtest"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/telegram"
+)
+
+// speedTestHistoryLines is the number of results listed by /speedtest history
+const speedTestHistoryLines = 10
+
+const speedTestUsage = "Usage: /speedtest [wan|xray|<tunnel>|history]\nExamples: /speedtest, /speedtest xray, /speedtest wgc1"
+
+// SpeedTestHandler handles the /speedtest command
+type SpeedTestHandler struct {
+	deps *Deps
+}
+
+// NewSpeedTestHandler creates a new SpeedTestHandler
+func NewSpeedTestHandler(deps *Deps) *SpeedTestHandler {
+	return &SpeedTestHandler{deps: deps}
+}
+
+// HandleSpeedTest handles /speedtest [route|history] - starts a background
+// speed test (default route: wan) and sends the result when it finishes
+func (h *SpeedTestHandler) HandleSpeedTest(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+
+	if h.deps.SpeedTest == nil {
+		h.deps.Sender.SendPlain(chatID, "Speed test is not available")
+		return
+	}
+
+	arg := strings.TrimSpace(msg.CommandArguments())
+	if arg == "history" {
+		h.sendHistory(chatID)
+		return
+	}
+	if arg == "" {
+		arg = speedtest.RouteWAN
+	}
+	if !speedtest.ValidRoute(arg) {
+		h.deps.Sender.SendPlain(chatID, speedTestUsage)
+		return
+	}
+
+	_, err := h.deps.SpeedTest.Start(arg, func(job speedtest.Job) {
+		h.deps.Sender.Send(chatID, telegram.EscapeMarkdownV2(formatSpeedTestJob(job)))
+	})
+	if errors.Is(err, speedtest.ErrRunning) {
+		h.deps.Sender.SendPlain(chatID, "A speed test is already running, try again when it finishes")
+		return
+	}
+	if err != nil {
+		h.deps.Sender.SendPlain(chatID, fmt.Sprintf("Speed test error: %v", err))
+		return
+	}
+
+	h.deps.Sender.SendPlain(chatID, fmt.Sprintf("Running speed test via %s, this takes up to a minute...", arg))
+}
+
+func (h *SpeedTestHandler) sendHistory(chatID int64) {
+	results, err := h.deps.SpeedTest.History(speedTestHistoryLines)
+	if err != nil {
+		h.deps.Sender.SendPlain(chatID, fmt.Sprintf("Speed test error: %v", err))
+		return
+	}
+	h.deps.Sender.Send(chatID, telegram.EscapeMarkdownV2(formatSpeedTestHistory(results)))
+}
+
+// formatSpeedTestJob renders a finished job as plain text (escape before sending)
+func formatSpeedTestJob(job speedtest.Job) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("🚀 Speed test via %s\n\n", job.Route))
+	if r := job.Result; r != nil && r.LatencyMs > 0 {
+		sb.WriteString(fmt.Sprintf("Latency: %.0f ms (jitter %.1f ms)\n", r.LatencyMs, r.JitterMs))
+	}
+	if r := job.Result; r != nil && r.DownloadBytes > 0 {
+		sb.WriteString(fmt.Sprintf("Download: %.1f Mbit/s\n", r.DownloadMbps))
+	}
+	if r := job.Result; r != nil && r.UploadBytes > 0 {
+		sb.WriteString(fmt.Sprintf("Upload: %.1f Mbit/s\n", r.UploadMbps))
+	}
+	if job.Error != "" {
+		sb.WriteString(fmt.Sprintf("❌ %s\n", job.Error))
+	}
+
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// formatSpeedTestHistory renders past results as plain text (escape before sending)
+func formatSpeedTestHistory(results []speedtest.Result) string {
+	if len(results) == 0 {
+		return "No speed tests yet."
+	}
+
+	var sb strings.Builder
+	sb.WriteString("🚀 Recent speed tests\n\n")
+	for _, r := range results {
+		sb.WriteString(fmt.Sprintf("%s %s: ", r.Time.Local().Format("01-02 15:04"), r.Route))
+		if r.Error != "" {
+			sb.WriteString("failed\n")
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("↓ %.1f ↑ %.1f Mbit/s, %.0f ms\n", r.DownloadMbps, r.UploadMbps, r.LatencyMs))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/speedtest"
+)
+
+type mockSpeedTest struct {
+	job     speedtest.Job
+	err     error
+	route   string
+	history []speedtest.Result
+}
+
+func (m *mockSpeedTest) Start(route string, onDone func(speedtest.Job)) (speedtest.Job, error) {
+	m.route = route
+	if m.err != nil {
+		return speedtest.Job{}, m.err
+	}
+	if onDone != nil {
+		onDone(m.job)
+	}
+	return m.job, nil
+}
+
+func (m *mockSpeedTest) Job(id string) (speedtest.Job, bool) { return m.job, id == m.job.ID }
+
+func (m *mockSpeedTest) History(limit int) ([]speedtest.Result, error) { return m.history, m.err }
+
+func speedTestCommand(text string) *tgbotapi.Message {
+	return &tgbotapi.Message{
+		Text:     text,
+		Chat:     &tgbotapi.Chat{ID: 100},
+		Entities: []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len("/speedtest")}},
+	}
+}
+
+func TestSpeedTestHandler_Run(t *testing.T) {
+	sender := &mockSenderClients{}
+	mock := &mockSpeedTest{job: speedtest.Job{
+		ID: "abc", Route: "xray", Status: speedtest.StatusDone,
+		Result: &speedtest.Result{LatencyMs: 42.4, JitterMs: 3.25, DownloadMbps: 93.46, UploadMbps: 41.2, DownloadBytes: 1, UploadBytes: 1},
+	}}
+	h := NewSpeedTestHandler(&Deps{Sender: sender, SpeedTest: mock})
+
+	h.HandleSpeedTest(speedTestCommand("/speedtest xray"))
+
+	if mock.route != "xray" {
+		t.Errorf("expected route xray, got %q", mock.route)
+	}
+	if len(sender.plainTexts) != 1 || !strings.Contains(sender.plainTexts[0], "Running speed test via xray") {
+		t.Errorf("expected progress message, got %v", sender.plainTexts)
+	}
+	for _, want := range []string{"Speed test via xray", "Latency: 42 ms \\(jitter 3\\.2 ms\\)", "Download: 93\\.5 Mbit/s", "Upload: 41\\.2 Mbit/s"} {
+		if !strings.Contains(sender.lastText, want) {
+			t.Errorf("expected %q in message, got: %s", want, sender.lastText)
+		}
+	}
+}
+
+func TestSpeedTestHandler_DefaultRouteAndFailure(t *testing.T) {
+	sender := &mockSenderClients{}
+	mock := &mockSpeedTest{job: speedtest.Job{
+		Route: "wan", Status: speedtest.StatusFailed, Error: "download: HTTP 403",
+		Result: &speedtest.Result{LatencyMs: 12},
+	}}
+	h := NewSpeedTestHandler(&Deps{Sender: sender, SpeedTest: mock})
+
+	h.HandleSpeedTest(speedTestCommand("/speedtest"))
+
+	if mock.route != "wan" {
+		t.Errorf("expected route wan, got %q", mock.route)
+	}
+	if !strings.Contains(sender.lastText, "❌ download: HTTP 403") || strings.Contains(sender.lastText, "Download:") {
+		t.Errorf("unexpected message: %s", sender.lastText)
+	}
+}
+
+func TestSpeedTestHandler_Running(t *testing.T) {
+	sender := &mockSenderClients{}
+	h := NewSpeedTestHandler(&Deps{Sender: sender, SpeedTest: &mockSpeedTest{err: speedtest.ErrRunning}})
+
+	h.HandleSpeedTest(speedTestCommand("/speedtest"))
+
+	if len(sender.plainTexts) != 1 || !strings.Contains(sender.plainTexts[0], "already running") {
+		t.Errorf("unexpected messages: %v", sender.plainTexts)
+	}
+}
+
+func TestSpeedTestHandler_InvalidRoute(t *testing.T) {
+	sender := &mockSenderClients{}
+	mock := &mockSpeedTest{}
+	h := NewSpeedTestHandler(&Deps{Sender: sender, SpeedTest: mock})
+
+	h.HandleSpeedTest(speedTestCommand("/speedtest wgc1 now"))
+
+	if mock.route != "" || len(sender.plainTexts) != 1 || !strings.HasPrefix(sender.plainTexts[0], "Usage:") {
+		t.Errorf("expected usage, got %v", sender.plainTexts)
+	}
+}
+
+func TestSpeedTestHandler_History(t *testing.T) {
+	sender := &mockSenderClients{}
+	at := time.Date(2026, 1, 11, 12, 30, 0, 0, time.Local)
+	h := NewSpeedTestHandler(&Deps{Sender: sender, SpeedTest: &mockSpeedTest{history: []speedtest.Result{
+		{Time: at, Route: "xray", DownloadMbps: 90, UploadMbps: 40, LatencyMs: 35},
+		{Time: at.Add(-time.Hour), Route: "wan", Error: "latency: timeout"},
+	}}})
+
+	h.HandleSpeedTest(speedTestCommand("/speedtest history"))
+
+	for _, want := range []string{"01\\-11 12:30 xray: ↓ 90\\.0 ↑ 40\\.0 Mbit/s, 35 ms", "01\\-11 11:30 wan: failed"} {
+		if !strings.Contains(sender.lastText, want) {
+			t.Errorf("expected %q in message, got: %s", want, sender.lastText)
+		}
+	}
+}
+
+func TestSpeedTestHandler_Unavailable(t *testing.T) {
+	sender := &mockSenderClients{}
+	NewSpeedTestHandler(&Deps{Sender: sender}).HandleSpeedTest(speedTestCommand("/speedtest"))
+
+	if len(sender.plainTexts) != 1 || sender.plainTexts[0] != "Speed test is not available" {
+		t.Errorf("unexpected messages: %v", sender.plainTexts)
+	}
+}
@@ -2,10 +2,13 @@
 package handler
 
 import (
+	"context"
 	"fmt"
+	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/telegram"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/xraystats"
 )
 
 // StatusHandler handles status-related commands
@@ -25,13 +28,49 @@ func (h *StatusHandler) HandleStatus(msg *tgbotapi.Message) {
 		h.deps.Sender.Send(msg.Chat.ID, telegram.EscapeMarkdownV2(fmt.Sprintf("Error: %v", err)))
 		return
 	}
+
+	if h.deps.XrayStats != nil {
+		stats, err := h.deps.XrayStats.Summary(context.Background())
+		if err != nil {
+			output += "\n\nXray traffic: unavailable"
+		} else {
+			output += "\n\n" + formatXrayStats(stats)
+		}
+	}
 	h.deps.Sender.SendCodeBlock(msg.Chat.ID, "📊 *VPN Director Status*:", output)
 }
 
+// formatXrayStats renders Xray counters per inbound, outbound and user
+func formatXrayStats(s *xraystats.Summary) string {
+	var sb strings.Builder
+	sb.WriteString("Xray traffic since start (↑ up / ↓ down):")
+	sections := []struct {
+		title   string
+		entries []xraystats.Traffic
+	}{
+		{"Inbounds", s.Inbounds},
+		{"Outbounds", s.Outbounds},
+		{"Users", s.Users},
+	}
+	for _, sec := range sections {
+		if len(sec.entries) == 0 {
+			continue
+		}
+		sb.WriteString("\n" + sec.title + ":")
+		for _, t := range sec.entries {
+			sb.WriteString(fmt.Sprintf("\n  %s ↑ %s ↓ %s", t.Tag, formatBytes(uint64(t.Uplink)), formatBytes(uint64(t.Downlink))))
+		}
+	}
+	return sb.String()
+}
+
 // HandleRestart handles /restart command
 func (h *StatusHandler) HandleRestart(msg *tgbotapi.Message) {
 	h.deps.Sender.Send(msg.Chat.ID, "Restarting VPN Director\\.\\.\\.")
-	if err := h.deps.VPN.Restart(); err != nil {
+	before := h.deps.xrayState()
+	err := h.deps.VPN.Restart()
+	h.deps.audit(msg.From, "restart", "", before, h.deps.xrayState(), err)
+	if err != nil {
 		h.deps.Sender.Send(msg.Chat.ID, telegram.EscapeMarkdownV2(fmt.Sprintf("Error: %v", err)))
 		return
 	}
@@ -40,7 +79,10 @@ func (h *StatusHandler) HandleRestart(msg *tgbotapi.Message) {
 
 // HandleStop handles /stop command
 func (h *StatusHandler) HandleStop(msg *tgbotapi.Message) {
-	if err := h.deps.VPN.Stop(); err != nil {
+	before := h.deps.xrayState()
+	err := h.deps.VPN.Stop()
+	h.deps.audit(msg.From, "stop", "", before, h.deps.xrayState(), err)
+	if err != nil {
 		h.deps.Sender.Send(msg.Chat.ID, telegram.EscapeMarkdownV2(fmt.Sprintf("Error: %v", err)))
 		return
 	}
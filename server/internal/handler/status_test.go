@@ -2,12 +2,14 @@
 package handler
 
 import (
+	"context"
 	"errors"
 	"strings"
 	"testing"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/xraystats"
 )
 
 type mockVPNDirector struct {
@@ -20,6 +22,7 @@ type mockVPNDirector struct {
 func (m *mockVPNDirector) Status() (string, error)  { return m.statusOutput, m.statusErr }
 func (m *mockVPNDirector) Apply() error             { return nil }
 func (m *mockVPNDirector) Restart() error           { return m.restartErr }
+func (m *mockVPNDirector) ApplyXray() error         { return nil }
 func (m *mockVPNDirector) RestartXray() error       { return nil }
 func (m *mockVPNDirector) Stop() error              { return m.stopErr }
 
@@ -36,6 +39,7 @@ func (m *mockConfigStore) SaveServers([]vpnconfig.Server) error
 func (m *mockConfigStore) DataDir() (string, error)                             { return "/data", m.err }
 func (m *mockConfigStore) DataDirOrDefault() string                             { return "/data" }
 func (m *mockConfigStore) ScriptsDir() string                                   { return "/scripts" }
+func (m *mockConfigStore) LockConfig() (func(), error)                          { return func() {}, nil }
 
 func TestStatusHandler_HandleStatus(t *testing.T) {
 	sender := &mockSender{}
@@ -61,6 +65,55 @@ func TestStatusHandler_HandleStatus(t *testing.T) {
 	}
 }
 
+type mockXrayStats struct {
+	summary *xraystats.Summary
+	err     error
+}
+
+func (m *mockXrayStats) Summary(_ context.Context) (*xraystats.Summary, error) {
+	return m.summary, m.err
+}
+
+func TestStatusHandler_HandleStatus_XrayStats(t *testing.T) {
+	sender := &mockSender{}
+	deps := &Deps{
+		Sender: sender,
+		VPN:    &mockVPNDirector{statusOutput: "Xray: running"},
+		XrayStats: &mockXrayStats{summary: &xraystats.Summary{
+			Inbounds:  []xraystats.Traffic{{Tag: "tproxy-in", Uplink: 2048, Downlink: 3 << 20}},
+			Outbounds: []xraystats.Traffic{{Tag: "proxy-out", Uplink: 4096, Downlink: 5 << 20}},
+		}},
+	}
+	h := NewStatusHandler(deps)
+
+	h.HandleStatus(&tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 456}})
+
+	for _, want := range []string{"Xray: running", "Inbounds:", "tproxy-in ↑ 2.0 KiB ↓ 3.0 MiB", "proxy-out ↑ 4.0 KiB ↓ 5.0 MiB"} {
+		if !strings.Contains(sender.lastCodeContent, want) {
+			t.Errorf("expected %q in status, got %q", want, sender.lastCodeContent)
+		}
+	}
+	if strings.Contains(sender.lastCodeContent, "Users:") {
+		t.Errorf("empty sections should be omitted, got %q", sender.lastCodeContent)
+	}
+}
+
+func TestStatusHandler_HandleStatus_XrayStatsUnavailable(t *testing.T) {
+	sender := &mockSender{}
+	deps := &Deps{
+		Sender:    sender,
+		VPN:       &mockVPNDirector{statusOutput: "Xray: stopped"},
+		XrayStats: &mockXrayStats{err: errors.New("connection refused")},
+	}
+	h := NewStatusHandler(deps)
+
+	h.HandleStatus(&tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 456}})
+
+	if !strings.HasPrefix(sender.lastCodeContent, "Xray: stopped") || !strings.Contains(sender.lastCodeContent, "Xray traffic: unavailable") {
+		t.Errorf("expected status with unavailable note, got %q", sender.lastCodeContent)
+	}
+}
+
 func TestStatusHandler_HandleStatus_Error(t *testing.T) {
 	sender := &mockSender{}
 	vpn := &mockVPNDirector{statusErr: errors.New("exec failed")}
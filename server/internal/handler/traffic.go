@@ -0,0 +1,108 @@
+// internal/handler/traffic.go
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/telegram"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/traffic"
+)
+
+// topTalkers is the number of clients listed by /traffic
+const topTalkers = 10
+
+// TrafficHandler handles the /traffic command
+type TrafficHandler struct {
+	deps *Deps
+}
+
+// NewTrafficHandler creates a new TrafficHandler
+func NewTrafficHandler(deps *Deps) *TrafficHandler {
+	return &TrafficHandler{deps: deps}
+}
+
+// HandleTraffic handles /traffic [hour|day|month] - shows top talkers and
+// per-route totals for the window (default: day)
+func (h *TrafficHandler) HandleTraffic(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+
+	window := "day"
+	if args := strings.Fields(msg.CommandArguments()); len(args) > 0 {
+		window = strings.ToLower(args[0])
+	}
+
+	if h.deps.Traffic == nil {
+		h.deps.Sender.SendPlain(chatID, "Traffic accounting is not available")
+		return
+	}
+
+	report, err := h.deps.Traffic.Report()
+	if err != nil {
+		h.deps.Sender.SendPlain(chatID, fmt.Sprintf("Traffic stats error: %v", err))
+		return
+	}
+
+	w, ok := report.WindowByName(window)
+	if !ok {
+		h.deps.Sender.SendPlain(chatID, "Usage: /traffic [hour|day|month]")
+		return
+	}
+
+	h.deps.Sender.Send(chatID, telegram.EscapeMarkdownV2(formatTrafficReport(window, report, w)))
+}
+
+// formatTrafficReport renders a window as plain text (escape before sending)
+func formatTrafficReport(name string, report *traffic.Report, w traffic.Window) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("📊 Traffic, last %s (↑ upload, ↓ download)\n", name))
+
+	if report.UpdatedAt.IsZero() {
+		sb.WriteString("\nNo data yet: the first sample is taken a few minutes after start.")
+		return sb.String()
+	}
+	if len(w.Clients) == 0 {
+		sb.WriteString("\nNo traffic in this period.")
+		return sb.String()
+	}
+
+	sb.WriteString(fmt.Sprintf("Total: %s\n", formatTraffic(w.Traffic)))
+
+	sb.WriteString("\nTop clients:\n")
+	for i, u := range w.Clients {
+		if i == topTalkers {
+			sb.WriteString(fmt.Sprintf("…and %d more\n", len(w.Clients)-topTalkers))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("%d. %s (%s) — %s\n", i+1, u.Client, u.Route, formatTraffic(u.Traffic)))
+	}
+
+	sb.WriteString("\nBy route:\n")
+	for _, u := range w.Routes {
+		sb.WriteString(fmt.Sprintf("• %s — %s\n", u.Route, formatTraffic(u.Traffic)))
+	}
+
+	sb.WriteString(fmt.Sprintf("\nUpdated: %s", report.UpdatedAt.Local().Format("2006-01-02 15:04")))
+	return sb.String()
+}
+
+// formatTraffic renders upload and download byte counts
+func formatTraffic(t traffic.Traffic) string {
+	return fmt.Sprintf("↑ %s ↓ %s", formatBytes(t.Upload.Bytes), formatBytes(t.Download.Bytes))
+}
+
+// formatBytes renders a byte count with a binary unit
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
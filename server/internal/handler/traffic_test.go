@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/traffic"
+)
+
+type mockTraffic struct {
+	report *traffic.Report
+	err    error
+}
+
+func (m *mockTraffic) Report() (*traffic.Report, error) { return m.report, m.err }
+
+func trafficCommand(text string) *tgbotapi.Message {
+	return &tgbotapi.Message{
+		Text:     text,
+		Chat:     &tgbotapi.Chat{ID: 100},
+		Entities: []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len("/traffic")}},
+	}
+}
+
+func testTrafficReport() *traffic.Report {
+	total := traffic.Traffic{
+		Upload:   traffic.Counter{Bytes: 78 * 1024 * 1024},
+		Download: traffic.Counter{Bytes: 312 * 1024 * 1024},
+	}
+	var clients []traffic.Usage
+	for i := 0; i < 12; i++ {
+		clients = append(clients, traffic.Usage{
+			Client: fmt.Sprintf("192.168.1.%d", 100+i),
+			Route:  "xray",
+			Traffic: traffic.Traffic{
+				Upload:   traffic.Counter{Bytes: uint64(12-i) * 1024 * 1024},
+				Download: traffic.Counter{Bytes: uint64(12-i) * 4 * 1024 * 1024},
+			},
+		})
+	}
+	return &traffic.Report{
+		UpdatedAt: time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC),
+		Hour:      traffic.Window{Clients: []traffic.Usage{}, Routes: []traffic.Usage{}},
+		Day: traffic.Window{
+			Clients: clients,
+			Routes:  []traffic.Usage{{Route: "xray", Traffic: total}},
+			Traffic: total,
+		},
+	}
+}
+
+func TestTrafficHandler_DefaultsToDay(t *testing.T) {
+	sender := &mockSenderClients{}
+	h := NewTrafficHandler(&Deps{Sender: sender, Traffic: &mockTraffic{report: testTrafficReport()}})
+
+	h.HandleTraffic(trafficCommand("/traffic"))
+
+	for _, want := range []string{"last day", "Total: ↑ 78\\.0 MiB ↓ 312\\.0 MiB", "1\\. 192\\.168\\.1\\.100 \\(xray\\) — ↑ 12\\.0 MiB ↓ 48\\.0 MiB", "and 2 more", "By route"} {
+		if !strings.Contains(sender.lastText, want) {
+			t.Errorf("expected %q in message, got: %s", want, sender.lastText)
+		}
+	}
+	if strings.Contains(sender.lastText, "192\\.168\\.1\\.111") {
+		t.Errorf("expected only top %d clients, got: %s", topTalkers, sender.lastText)
+	}
+}
+
+func TestTrafficHandler_EmptyWindow(t *testing.T) {
+	sender := &mockSenderClients{}
+	h := NewTrafficHandler(&Deps{Sender: sender, Traffic: &mockTraffic{report: testTrafficReport()}})
+
+	h.HandleTraffic(trafficCommand("/traffic hour"))
+
+	if !strings.Contains(sender.lastText, "No traffic in this period") {
+		t.Errorf("expected empty window message, got: %s", sender.lastText)
+	}
+}
+
+func TestTrafficHandler_NoData(t *testing.T) {
+	sender := &mockSenderClients{}
+	h := NewTrafficHandler(&Deps{Sender: sender, Traffic: &mockTraffic{report: &traffic.Report{}}})
+
+	h.HandleTraffic(trafficCommand("/traffic month"))
+
+	if !strings.Contains(sender.lastText, "No data yet") {
+		t.Errorf("expected no data message, got: %s", sender.lastText)
+	}
+}
+
+func TestTrafficHandler_InvalidWindow(t *testing.T) {
+	sender := &mockSenderClients{}
+	h := NewTrafficHandler(&Deps{Sender: sender, Traffic: &mockTraffic{report: testTrafficReport()}})
+
+	h.HandleTraffic(trafficCommand("/traffic week"))
+
+	if len(sender.plainTexts) != 1 || !strings.Contains(sender.plainTexts[0], "Usage") {
+		t.Errorf("expected usage message, got %v", sender.plainTexts)
+	}
+}
+
+func TestTrafficHandler_Error(t *testing.T) {
+	sender := &mockSenderClients{}
+	h := NewTrafficHandler(&Deps{Sender: sender, Traffic: &mockTraffic{err: errors.New("boom")}})
+
+	h.HandleTraffic(trafficCommand("/traffic"))
+
+	if len(sender.plainTexts) != 1 || !strings.Contains(sender.plainTexts[0], "boom") {
+		t.Errorf("expected error message, got %v", sender.plainTexts)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[uint64]string{
+		0:               "0 B",
+		1023:            "1023 B",
+		1536:            "1.5 KiB",
+		5 * 1024 * 1024: "5.0 MiB",
+		3 << 30:         "3.0 GiB",
+	}
+	for in, want := range tests {
+		if got := formatBytes(in); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", in, got, want)
+		}
+	}
+}
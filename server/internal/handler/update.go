@@ -7,6 +7,7 @@ import (
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/audit"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/telegram"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/updater"
 )
@@ -17,15 +18,18 @@ type UpdateHandler struct {
 	updater updater.Updater
 	devMode bool
 	version string
+	audit   audit.Recorder // nil disables recording
 }
 
-// NewUpdateHandler creates a new update handler.
-func NewUpdateHandler(sender telegram.MessageSender, upd updater.Updater, devMode bool, version string) *UpdateHandler {
+// NewUpdateHandler creates a new update handler. Started updates are
+// recorded in rec unless it is nil.
+func NewUpdateHandler(sender telegram.MessageSender, upd updater.Updater, devMode bool, version string, rec audit.Recorder) *UpdateHandler {
 	return &UpdateHandler{
 		sender:  sender,
 		updater: upd,
 		devMode: devMode,
 		version: version,
+		audit:   rec,
 	}
 }
 
@@ -84,7 +88,9 @@ func (h *UpdateHandler) HandleUpdate(msg *tgbotapi.Message) {
 	}
 
 	// 8. Create lock to prevent concurrent updates
-	if err := h.updater.CreateLock(); err != nil {
+	err = h.updater.CreateLock()
+	recordAudit(h.audit, msg.From, "update", "", h.version, release.TagName, err)
+	if err != nil {
 		h.send(chatID, fmt.Sprintf("Failed to start update: %v", err))
 		return
 	}
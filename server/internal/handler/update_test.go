@@ -194,7 +194,7 @@ func (m *mockUpdateSender) waitForMessages(n int, timeout time.Duration) []strin
 func TestUpdateHandler_DevMode(t *testing.T) {
 	sender := newMockUpdateSender()
 	upd := &mockUpdater{}
-	h := NewUpdateHandler(sender, upd, true, "v1.0.0") // devMode=true
+	h := NewUpdateHandler(sender, upd, true, "v1.0.0", nil) // devMode=true
 
 	msg := &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 123}}
 	h.HandleUpdate(msg)
@@ -211,7 +211,7 @@ func TestUpdateHandler_DevMode(t *testing.T) {
 func TestUpdateHandler_DevVersion(t *testing.T) {
 	sender := newMockUpdateSender()
 	upd := &mockUpdater{}
-	h := NewUpdateHandler(sender, upd, false, "dev") // version="dev"
+	h := NewUpdateHandler(sender, upd, false, "dev", nil) // version="dev"
 
 	msg := &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 123}}
 	h.HandleUpdate(msg)
@@ -230,7 +230,7 @@ func TestUpdateHandler_UpdateInProgress(t *testing.T) {
 	upd := &mockUpdater{
 		updateInProgress: true,
 	}
-	h := NewUpdateHandler(sender, upd, false, "v1.0.0")
+	h := NewUpdateHandler(sender, upd, false, "v1.0.0", nil)
 
 	msg := &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 123}}
 	h.HandleUpdate(msg)
@@ -249,7 +249,7 @@ func TestUpdateHandler_GitHubError(t *testing.T) {
 	upd := &mockUpdater{
 		latestReleaseErr: errors.New("network error"),
 	}
-	h := NewUpdateHandler(sender, upd, false, "v1.0.0")
+	h := NewUpdateHandler(sender, upd, false, "v1.0.0", nil)
 
 	msg := &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 123}}
 	h.HandleUpdate(msg)
@@ -270,7 +270,7 @@ func TestUpdateHandler_AlreadyLatest(t *testing.T) {
 		latestRelease: &updater.Release{TagName: "v1.0.0"},
 		shouldUpdate:  false,
 	}
-	h := NewUpdateHandler(sender, upd, false, "v1.0.0")
+	h := NewUpdateHandler(sender, upd, false, "v1.0.0", nil)
 
 	msg := &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 123}}
 	h.HandleUpdate(msg)
@@ -290,7 +290,7 @@ func TestUpdateHandler_InvalidCurrentVersion(t *testing.T) {
 		latestRelease: &updater.Release{TagName: "v1.1.0"},
 	}
 	// Version with shell injection characters
-	h := NewUpdateHandler(sender, upd, false, "v1.0.0;rm -rf /")
+	h := NewUpdateHandler(sender, upd, false, "v1.0.0;rm -rf /", nil)
 
 	msg := &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 123}}
 	h.HandleUpdate(msg)
@@ -309,7 +309,7 @@ func TestUpdateHandler_InvalidReleaseVersion(t *testing.T) {
 	upd := &mockUpdater{
 		latestRelease: &updater.Release{TagName: "v1.1.0$(whoami)"},
 	}
-	h := NewUpdateHandler(sender, upd, false, "v1.0.0")
+	h := NewUpdateHandler(sender, upd, false, "v1.0.0", nil)
 
 	msg := &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 123}}
 	h.HandleUpdate(msg)
@@ -329,7 +329,7 @@ func TestUpdateHandler_VersionParseError(t *testing.T) {
 		latestRelease:   &updater.Release{TagName: "v1.1.0"},
 		shouldUpdateErr: errors.New("invalid version format"),
 	}
-	h := NewUpdateHandler(sender, upd, false, "v1.0.0")
+	h := NewUpdateHandler(sender, upd, false, "v1.0.0", nil)
 
 	msg := &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 123}}
 	h.HandleUpdate(msg)
@@ -351,7 +351,7 @@ func TestUpdateHandler_CreateLockError(t *testing.T) {
 		shouldUpdate:  true,
 		createLockErr: errors.New("lock failed"),
 	}
-	h := NewUpdateHandler(sender, upd, false, "v1.0.0")
+	h := NewUpdateHandler(sender, upd, false, "v1.0.0", nil)
 
 	msg := &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 123}}
 	h.HandleUpdate(msg)
@@ -373,7 +373,7 @@ func TestUpdateHandler_DownloadError(t *testing.T) {
 		shouldUpdate:  true,
 		downloadErr:   errors.New("download failed"),
 	}
-	h := NewUpdateHandler(sender, upd, false, "v1.0.0")
+	h := NewUpdateHandler(sender, upd, false, "v1.0.0", nil)
 
 	msg := &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 123}}
 	h.HandleUpdate(msg)
@@ -405,7 +405,7 @@ func TestUpdateHandler_RunScriptError(t *testing.T) {
 		shouldUpdate:  true,
 		runScriptErr:  errors.New("script failed"),
 	}
-	h := NewUpdateHandler(sender, upd, false, "v1.0.0")
+	h := NewUpdateHandler(sender, upd, false, "v1.0.0", nil)
 
 	msg := &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 123}}
 	h.HandleUpdate(msg)
@@ -442,7 +442,7 @@ func TestUpdateHandler_Success(t *testing.T) {
 		latestRelease: &updater.Release{TagName: "v1.1.0"},
 		shouldUpdate:  true,
 	}
-	h := NewUpdateHandler(sender, upd, false, "v1.0.0")
+	h := NewUpdateHandler(sender, upd, false, "v1.0.0", nil)
 
 	msg := &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 42}}
 	h.HandleUpdate(msg)
@@ -497,7 +497,7 @@ func TestUpdateHandler_HandleCallback_UpdateRun(t *testing.T) {
 		latestRelease: &updater.Release{TagName: "v1.0.0"},
 		shouldUpdate:  false, // No update available
 	}
-	h := NewUpdateHandler(sender, upd, false, "v1.0.0")
+	h := NewUpdateHandler(sender, upd, false, "v1.0.0", nil)
 
 	cb := &tgbotapi.CallbackQuery{
 		Data: "update:run",
@@ -522,7 +522,7 @@ func TestUpdateHandler_HandleCallback_UpdateRun(t *testing.T) {
 func TestUpdateHandler_HandleCallback_IgnoresOtherCallbacks(t *testing.T) {
 	sender := newMockUpdateSender()
 	upd := &mockUpdater{}
-	h := NewUpdateHandler(sender, upd, false, "v1.0.0")
+	h := NewUpdateHandler(sender, upd, false, "v1.0.0", nil)
 
 	cb := &tgbotapi.CallbackQuery{
 		Data: "update:other",
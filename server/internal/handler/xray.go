@@ -3,11 +3,12 @@ package handler
 
 import (
 	"fmt"
-	"strconv"
 	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/events"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/telegram"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
 )
 
 // XrayHandler handles /xray command for quick server switching
@@ -36,7 +37,7 @@ func (h *XrayHandler) HandleXray(msg *tgbotapi.Message) {
 	kb := telegram.NewKeyboard()
 	for i, srv := range servers {
 		btnText := fmt.Sprintf("%d. %s", i+1, srv.Name)
-		kb.Button(btnText, fmt.Sprintf("xray:select:%d", i))
+		kb.Button(btnText, "xray:select:"+srv.ID)
 	}
 	kb.Columns(2)
 
@@ -44,7 +45,7 @@ func (h *XrayHandler) HandleXray(msg *tgbotapi.Message) {
 	h.deps.Sender.SendWithKeyboard(msg.Chat.ID, text, kb.Build())
 }
 
-// HandleCallback handles xray:select:{index} callbacks
+// HandleCallback handles xray:select:{id} callbacks
 func (h *XrayHandler) HandleCallback(cb *tgbotapi.CallbackQuery) {
 	if cb.Message == nil {
 		return
@@ -53,15 +54,14 @@ func (h *XrayHandler) HandleCallback(cb *tgbotapi.CallbackQuery) {
 	chatID := cb.Message.Chat.ID
 	data := cb.Data
 
-	// Parse server index from "xray:select:N"
+	// Parse server ID from "xray:select:ID"
 	if !strings.HasPrefix(data, "xray:select:") {
 		return
 	}
 
-	idxStr := strings.TrimPrefix(data, "xray:select:")
-	idx, err := strconv.Atoi(idxStr)
-	if err != nil {
-		h.deps.Sender.Send(chatID, telegram.EscapeMarkdownV2("Ошибка: неверный индекс"))
+	id := strings.TrimPrefix(data, "xray:select:")
+	if id == "" {
+		h.deps.Sender.Send(chatID, telegram.EscapeMarkdownV2("Ошибка: неверный идентификатор"))
 		return
 	}
 
@@ -72,21 +72,32 @@ func (h *XrayHandler) HandleCallback(cb *tgbotapi.CallbackQuery) {
 		return
 	}
 
-	if idx < 0 || idx >= len(servers) {
+	// IDs survive re-imports and edits, unlike indexes in an old keyboard
+	idx := vpnconfig.FindServer(servers, id)
+	if idx < 0 {
 		h.deps.Sender.Send(chatID, telegram.EscapeMarkdownV2("Ошибка: сервер не найден"))
 		return
 	}
 
 	server := servers[idx]
+	before := h.activeServerName(servers)
 
 	// Generate Xray config
 	if err := h.deps.Xray.GenerateConfig(server); err != nil {
+		h.deps.audit(cb.From, "servers.active", server.ID, before, server.Name, err)
 		h.deps.Sender.Send(chatID, telegram.EscapeMarkdownV2(fmt.Sprintf("Ошибка: %v", err)))
 		return
 	}
 
 	// Restart Xray
-	if err := h.deps.VPN.RestartXray(); err != nil {
+	err = h.deps.VPN.RestartXray()
+	h.deps.audit(cb.From, "servers.active", server.ID, before, server.Name, err)
+	switchEvent := events.Event{Kind: events.KindServerSwitch, OK: err == nil, Source: "bot", Message: server.Name}
+	if err != nil {
+		switchEvent.Message += ": " + err.Error()
+	}
+	h.deps.recordEvent(switchEvent)
+	if err != nil {
 		h.deps.Sender.Send(chatID, telegram.EscapeMarkdownV2(fmt.Sprintf("Ошибка перезапуска: %v", err)))
 		return
 	}
@@ -96,3 +107,19 @@ func (h *XrayHandler) HandleCallback(cb *tgbotapi.CallbackQuery) {
 	emptyKeyboard := tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}}
 	h.deps.Sender.EditMessage(chatID, cb.Message.MessageID, successText, emptyKeyboard)
 }
+
+// activeServerName names the server Xray uses now: its name, its address
+// when it is not in servers, or "" when unknown
+func (h *XrayHandler) activeServerName(servers []vpnconfig.Server) string {
+	if h.deps.XrayConfig == nil {
+		return ""
+	}
+	active, err := h.deps.XrayConfig.ActiveServer()
+	if err != nil {
+		return ""
+	}
+	if idx := vpnconfig.FindActive(servers, *active); idx >= 0 {
+		return servers[idx].Name
+	}
+	return active.Address
+}
@@ -7,9 +7,17 @@ import (
 	"testing"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/events"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
 )
 
+// mockRecorder collects recorded events
+type mockRecorder struct {
+	events []events.Event
+}
+
+func (m *mockRecorder) Record(e events.Event) { m.events = append(m.events, e) }
+
 // mockXrayGenerator for testing
 type mockXrayGenerator struct {
 	lastServer vpnconfig.Server
@@ -24,8 +32,8 @@ func (m *mockXrayGenerator) GenerateConfig(server vpnconfig.Server) error {
 func TestXrayHandler_HandleXray_WithServers(t *testing.T) {
 	sender := &mockSenderWithKeyboard{}
 	servers := []vpnconfig.Server{
-		{Name: "Germany, Berlin", Address: "de.example.com", IPs: []string{"1.1.1.1"}},
-		{Name: "USA, New York", Address: "us.example.com", IPs: []string{"2.2.2.2"}},
+		{ID: "de000001", Name: "Germany, Berlin", Address: "de.example.com", IPs: []string{"1.1.1.1"}},
+		{ID: "us000002", Name: "USA, New York", Address: "us.example.com", IPs: []string{"2.2.2.2"}},
 	}
 	config := &mockConfigStore{servers: servers}
 
@@ -84,8 +92,8 @@ func TestXrayHandler_HandleXray_LoadError(t *testing.T) {
 func TestXrayHandler_HandleCallback_Success(t *testing.T) {
 	sender := &mockSenderWithKeyboard{}
 	servers := []vpnconfig.Server{
-		{Name: "Germany, Berlin", Address: "de.example.com", Port: 443, UUID: "uuid1", IPs: []string{"1.1.1.1"}},
-		{Name: "USA, New York", Address: "us.example.com", Port: 443, UUID: "uuid2", IPs: []string{"2.2.2.2"}},
+		{ID: "de000001", Name: "Germany, Berlin", Address: "de.example.com", Port: 443, UUID: "uuid1", IPs: []string{"1.1.1.1"}},
+		{ID: "us000002", Name: "USA, New York", Address: "us.example.com", Port: 443, UUID: "uuid2", IPs: []string{"2.2.2.2"}},
 	}
 	config := &mockConfigStore{servers: servers}
 	xray := &mockXrayGenerator{}
@@ -96,7 +104,7 @@ func TestXrayHandler_HandleCallback_Success(t *testing.T) {
 
 	cb := &tgbotapi.CallbackQuery{
 		ID:   "cb123",
-		Data: "xray:select:1",
+		Data: "xray:select:us000002",
 		Message: &tgbotapi.Message{
 			MessageID: 42,
 			Chat:      &tgbotapi.Chat{ID: 100},
@@ -104,7 +112,7 @@ func TestXrayHandler_HandleCallback_Success(t *testing.T) {
 	}
 	h.HandleCallback(cb)
 
-	// Should generate config for server index 1 (USA)
+	// Should generate config for server us000002 (USA)
 	if xray.lastServer.Name != "USA, New York" {
 		t.Errorf("expected server 'USA, New York', got %q", xray.lastServer.Name)
 	}
@@ -118,10 +126,10 @@ func TestXrayHandler_HandleCallback_Success(t *testing.T) {
 	}
 }
 
-func TestXrayHandler_HandleCallback_InvalidIndex(t *testing.T) {
+func TestXrayHandler_HandleCallback_UnknownID(t *testing.T) {
 	sender := &mockSenderWithKeyboard{}
 	servers := []vpnconfig.Server{
-		{Name: "Germany, Berlin"},
+		{ID: "de000001", Name: "Germany, Berlin"},
 	}
 	config := &mockConfigStore{servers: servers}
 
@@ -130,7 +138,7 @@ func TestXrayHandler_HandleCallback_InvalidIndex(t *testing.T) {
 
 	cb := &tgbotapi.CallbackQuery{
 		ID:   "cb456",
-		Data: "xray:select:99", // invalid index
+		Data: "xray:select:99", // stale index-based keyboard
 		Message: &tgbotapi.Message{
 			MessageID: 10,
 			Chat:      &tgbotapi.Chat{ID: 200},
@@ -145,7 +153,7 @@ func TestXrayHandler_HandleCallback_InvalidIndex(t *testing.T) {
 
 func TestXrayHandler_HandleCallback_GenerateError(t *testing.T) {
 	sender := &mockSenderWithKeyboard{}
-	servers := []vpnconfig.Server{{Name: "Server1"}}
+	servers := []vpnconfig.Server{{ID: "srv00001", Name: "Server1"}}
 	config := &mockConfigStore{servers: servers}
 	xray := &mockXrayGenerator{err: errors.New("template not found")}
 
@@ -154,7 +162,7 @@ func TestXrayHandler_HandleCallback_GenerateError(t *testing.T) {
 
 	cb := &tgbotapi.CallbackQuery{
 		ID:   "cb789",
-		Data: "xray:select:0",
+		Data: "xray:select:srv00001",
 		Message: &tgbotapi.Message{
 			MessageID: 5,
 			Chat:      &tgbotapi.Chat{ID: 300},
@@ -169,7 +177,7 @@ func TestXrayHandler_HandleCallback_GenerateError(t *testing.T) {
 
 func TestXrayHandler_HandleCallback_RestartError(t *testing.T) {
 	sender := &mockSenderWithKeyboard{}
-	servers := []vpnconfig.Server{{Name: "Server1"}}
+	servers := []vpnconfig.Server{{ID: "srv00001", Name: "Server1"}}
 	config := &mockConfigStore{servers: servers}
 	xray := &mockXrayGenerator{}
 	vpn := &mockVPNDirectorWithXray{restartXrayErr: errors.New("xray not running")}
@@ -179,7 +187,7 @@ func TestXrayHandler_HandleCallback_RestartError(t *testing.T) {
 
 	cb := &tgbotapi.CallbackQuery{
 		ID:   "cb_restart",
-		Data: "xray:select:0",
+		Data: "xray:select:srv00001",
 		Message: &tgbotapi.Message{
 			MessageID: 7,
 			Chat:      &tgbotapi.Chat{ID: 400},
@@ -224,7 +232,7 @@ func TestXrayHandler_HandleCallback_InvalidData(t *testing.T) {
 
 	cb := &tgbotapi.CallbackQuery{
 		ID:   "cb_invalid",
-		Data: "xray:select:abc", // non-numeric index
+		Data: "xray:select:", // missing ID
 		Message: &tgbotapi.Message{
 			MessageID: 1,
 			Chat:      &tgbotapi.Chat{ID: 500},
@@ -232,17 +240,17 @@ func TestXrayHandler_HandleCallback_InvalidData(t *testing.T) {
 	}
 	h.HandleCallback(cb)
 
-	if !strings.Contains(sender.lastText, "неверный индекс") {
-		t.Errorf("expected 'неверный индекс' error, got %q", sender.lastText)
+	if !strings.Contains(sender.lastText, "неверный идентификатор") {
+		t.Errorf("expected 'неверный идентификатор' error, got %q", sender.lastText)
 	}
 }
 
 func TestXrayHandler_FullFlow(t *testing.T) {
 	sender := &mockSenderWithKeyboard{}
 	servers := []vpnconfig.Server{
-		{Name: "Germany, Berlin", Address: "de.example.com", Port: 443, UUID: "uuid1", IPs: []string{"1.1.1.1"}},
-		{Name: "USA, New York", Address: "us.example.com", Port: 443, UUID: "uuid2", IPs: []string{"2.2.2.2"}},
-		{Name: "Japan, Tokyo", Address: "jp.example.com", Port: 443, UUID: "uuid3", IPs: []string{"3.3.3.3"}},
+		{ID: "de000001", Name: "Germany, Berlin", Address: "de.example.com", Port: 443, UUID: "uuid1", IPs: []string{"1.1.1.1"}},
+		{ID: "us000002", Name: "USA, New York", Address: "us.example.com", Port: 443, UUID: "uuid2", IPs: []string{"2.2.2.2"}},
+		{ID: "jp000003", Name: "Japan, Tokyo", Address: "jp.example.com", Port: 443, UUID: "uuid3", IPs: []string{"3.3.3.3"}},
 	}
 	config := &mockConfigStore{servers: servers}
 	xray := &mockXrayGenerator{}
@@ -265,14 +273,14 @@ func TestXrayHandler_FullFlow(t *testing.T) {
 	}
 	// Verify callback data format
 	btn := sender.lastKeyboard.InlineKeyboard[0][0]
-	if btn.CallbackData == nil || *btn.CallbackData != "xray:select:0" {
-		t.Errorf("expected callback data 'xray:select:0', got %v", btn.CallbackData)
+	if btn.CallbackData == nil || *btn.CallbackData != "xray:select:de000001" {
+		t.Errorf("expected callback data 'xray:select:de000001', got %v", btn.CallbackData)
 	}
 
-	// Step 2: User clicks on USA server (index 1)
+	// Step 2: User clicks on USA server
 	cb := &tgbotapi.CallbackQuery{
 		ID:   "cb",
-		Data: "xray:select:1",
+		Data: "xray:select:us000002",
 		Message: &tgbotapi.Message{
 			MessageID: 42,
 			Chat:      &tgbotapi.Chat{ID: 100},
@@ -306,5 +314,42 @@ type mockVPNDirectorWithXray struct {
 func (m *mockVPNDirectorWithXray) Status() (string, error) { return m.statusOutput, m.statusErr }
 func (m *mockVPNDirectorWithXray) Apply() error            { return nil }
 func (m *mockVPNDirectorWithXray) Restart() error          { return m.restartErr }
+func (m *mockVPNDirectorWithXray) ApplyXray() error        { return nil }
 func (m *mockVPNDirectorWithXray) RestartXray() error      { return m.restartXrayErr }
 func (m *mockVPNDirectorWithXray) Stop() error             { return m.stopErr }
+
+func TestXrayHandler_HandleCallback_RecordsSwitch(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantOK  bool
+		wantMsg string
+	}{
+		{"success", nil, true, "Server1"},
+		{"restart error", errors.New("xray not running"), false, "Server1: xray not running"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &mockRecorder{}
+			deps := &Deps{
+				Sender: &mockSenderWithKeyboard{},
+				Config: &mockConfigStore{servers: []vpnconfig.Server{{ID: "srv00001", Name: "Server1"}}},
+				Xray:   &mockXrayGenerator{},
+				VPN:    &mockVPNDirectorWithXray{restartXrayErr: tt.err},
+				Events: rec,
+			}
+			NewXrayHandler(deps).HandleCallback(&tgbotapi.CallbackQuery{
+				Data:    "xray:select:srv00001",
+				Message: &tgbotapi.Message{MessageID: 1, Chat: &tgbotapi.Chat{ID: 100}},
+			})
+
+			if len(rec.events) != 1 {
+				t.Fatalf("expected 1 event, got %+v", rec.events)
+			}
+			e := rec.events[0]
+			if e.Kind != events.KindServerSwitch || e.OK != tt.wantOK || e.Message != tt.wantMsg || e.Source != "bot" {
+				t.Errorf("unexpected event: %+v", e)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package localuser
+
+import (
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/service"
+)
+
+// Compile-time interface check
+var _ Manager = (*Service)(nil)
+
+// Service manages users in the configured data directory, which is looked
+// up on every call so a data_dir change takes effect without a restart.
+type Service struct {
+	stores *service.StoreCache[*Store]
+}
+
+// NewService creates a new Service.
+func NewService(config service.ConfigStore) *Service {
+	return &Service{stores: service.NewStoreCache(config, func(dataDir string) *Store {
+		return NewStore(StorePath(dataDir))
+	})}
+}
+
+func (s *Service) store() *Store {
+	return s.stores.Get()
+}
+
+// Verify checks the password of username.
+func (s *Service) Verify(username, password string) (bool, error) {
+	return s.store().Verify(username, password)
+}
+
+// Get returns one user.
+func (s *Service) Get(username string) (*User, error) {
+	return s.store().Get(username)
+}
+
+// List returns all users.
+func (s *Service) List() ([]User, error) {
+	return s.store().List()
+}
+
+// Add creates a user.
+func (s *Service) Add(username, password, role string) (User, error) {
+	return s.store().Add(username, password, role)
+}
+
+// Remove deletes a user.
+func (s *Service) Remove(username string) error {
+	return s.store().Remove(username)
+}
+
+// SetPassword replaces a user's password.
+func (s *Service) SetPassword(username, password string) error {
+	return s.store().SetPassword(username, password)
+}
@@ -0,0 +1,276 @@
+// Package localuser keeps Web UI accounts that exist only for the Web UI,
+// so the router's root password need not be shared. Passwords are stored
+// as bcrypt hashes in the data directory.
+package localuser
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/auth"
+)
+
+// StoreFile is the user file inside the data directory.
+const StoreFile = "users.json"
+
+const (
+	maxUsers       = 50
+	maxUsernameLen = 32
+	minPasswordLen = 8
+	maxPasswordLen = 72 // bcrypt ignores anything longer
+)
+
+var (
+	// ErrNotFound is returned for unknown usernames.
+	ErrNotFound = errors.New("user not found")
+	// ErrExists is returned when adding a username that is taken.
+	ErrExists = errors.New("user already exists")
+)
+
+// dummyHash is compared against for unknown users, so a login takes as
+// long whether or not the user exists.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("vpn-director"), bcrypt.DefaultCost)
+
+// User is a stored account. Hash is only set on disk, never returned.
+type User struct {
+	Username string    `json:"username"`
+	Role     string    `json:"role"`
+	Hash     string    `json:"hash,omitempty"`
+	Created  time.Time `json:"created"`
+	Updated  time.Time `json:"updated"` // last password change
+}
+
+// Manager verifies, lists, adds and removes users and changes passwords.
+type Manager interface {
+	Verify(username, password string) (bool, error)
+	Get(username string) (*User, error)
+	List() ([]User, error)
+	Add(username, password, role string) (User, error)
+	Remove(username string) error
+	SetPassword(username, password string) error
+}
+
+// ValidateUsername checks a username.
+func ValidateUsername(username string) error {
+	if username == "" {
+		return errors.New("username is required")
+	}
+	if len(username) > maxUsernameLen {
+		return fmt.Errorf("username must be at most %d characters", maxUsernameLen)
+	}
+	for _, c := range username {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '_' || c == '-') {
+			return errors.New("username may only contain letters, digits, '.', '_' and '-'")
+		}
+	}
+	return nil
+}
+
+// ValidatePassword checks a new password.
+func ValidatePassword(password string) error {
+	if len(password) < minPasswordLen {
+		return fmt.Errorf("password must be at least %d characters", minPasswordLen)
+	}
+	if len(password) > maxPasswordLen {
+		return fmt.Errorf("password must be at most %d bytes", maxPasswordLen)
+	}
+	return nil
+}
+
+// Store keeps users in a JSON file. Writes are atomic (temp file + rename).
+type Store struct {
+	path string
+	mu   sync.Mutex
+	now  func() time.Time
+	cost int
+}
+
+// NewStore creates a store backed by the file at path.
+func NewStore(path string) *Store {
+	return &Store{path: path, now: time.Now, cost: bcrypt.DefaultCost}
+}
+
+// StorePath returns the user file location inside dataDir.
+func StorePath(dataDir string) string {
+	return filepath.Join(dataDir, StoreFile)
+}
+
+// Verify checks the password of username. Returns (false, nil) for unknown
+// users and wrong passwords.
+func (s *Store) Verify(username, password string) (bool, error) {
+	s.mu.Lock()
+	users, err := s.load()
+	s.mu.Unlock()
+	if err != nil {
+		return false, err
+	}
+
+	hash := dummyHash
+	i := slices.IndexFunc(users, func(u User) bool { return u.Username == username })
+	if i >= 0 {
+		hash = []byte(users[i].Hash)
+	}
+	err = bcrypt.CompareHashAndPassword(hash, []byte(password))
+	if i < 0 || errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("verify password: %w", err)
+	}
+	return true, nil
+}
+
+// Get returns the user with the given name, or ErrNotFound.
+func (s *Store) Get(username string) (*User, error) {
+	s.mu.Lock()
+	users, err := s.load()
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	i := slices.IndexFunc(users, func(u User) bool { return u.Username == username })
+	if i < 0 {
+		return nil, ErrNotFound
+	}
+	u := users[i]
+	u.Hash = ""
+	return &u, nil
+}
+
+// List returns all users by name, without their hashes.
+func (s *Store) List() ([]User, error) {
+	s.mu.Lock()
+	users, err := s.load()
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	for i := range users {
+		users[i].Hash = ""
+	}
+	slices.SortFunc(users, func(a, b User) int { return cmp.Compare(a.Username, b.Username) })
+	return users, nil
+}
+
+// Add creates a user with one of the auth.Roles.
+func (s *Store) Add(username, password, role string) (User, error) {
+	if err := ValidateUsername(username); err != nil {
+		return User{}, err
+	}
+	if err := ValidatePassword(password); err != nil {
+		return User{}, err
+	}
+	if !auth.ValidRole(role) {
+		return User{}, fmt.Errorf("unknown role %q", role)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), s.cost)
+	if err != nil {
+		return User{}, fmt.Errorf("hash password: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users, err := s.load()
+	if err != nil {
+		return User{}, err
+	}
+	if slices.ContainsFunc(users, func(u User) bool { return u.Username == username }) {
+		return User{}, ErrExists
+	}
+	if len(users) >= maxUsers {
+		return User{}, fmt.Errorf("at most %d users", maxUsers)
+	}
+
+	now := s.now().UTC()
+	u := User{Username: username, Role: role, Hash: string(hash), Created: now, Updated: now}
+	if err := s.save(append(users, u)); err != nil {
+		return User{}, err
+	}
+	u.Hash = ""
+	return u, nil
+}
+
+// Remove deletes a user.
+func (s *Store) Remove(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users, err := s.load()
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(users, func(u User) bool { return u.Username == username })
+	if i < 0 {
+		return ErrNotFound
+	}
+	return s.save(slices.Delete(users, i, i+1))
+}
+
+// SetPassword replaces the password of username.
+func (s *Store) SetPassword(username, password string) error {
+	if err := ValidatePassword(password); err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), s.cost)
+	if err != nil {
+		return fmt.Errorf("hash password: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users, err := s.load()
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(users, func(u User) bool { return u.Username == username })
+	if i < 0 {
+		return ErrNotFound
+	}
+	users[i].Hash = string(hash)
+	users[i].Updated = s.now().UTC()
+	return s.save(users)
+}
+
+func (s *Store) load() ([]User, error) {
+	raw, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read users: %w", err)
+	}
+	var users []User
+	if err := json.Unmarshal(raw, &users); err != nil {
+		return nil, fmt.Errorf("parse users: %w", err)
+	}
+	return users, nil
+}
+
+func (s *Store) save(users []User) error {
+	if users == nil {
+		users = []User{}
+	}
+	raw, err := json.MarshalIndent(users, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, raw, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.path)
+}
@@ -0,0 +1,139 @@
+package localuser
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	s := NewStore(StorePath(t.TempDir()))
+	s.cost = bcrypt.MinCost
+	return s
+}
+
+func TestStore_AddAndVerify(t *testing.T) {
+	s := newTestStore(t)
+
+	u, err := s.Add("alice", "correct horse", "operator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Hash != "" || u.Role != "operator" || u.Created.IsZero() {
+		t.Errorf("unexpected user %+v", u)
+	}
+
+	tests := []struct {
+		username, password string
+		want               bool
+	}{
+		{"alice", "correct horse", true},
+		{"alice", "wrong password", false},
+		{"bob", "correct horse", false},
+		{"Alice", "correct horse", false},
+	}
+	for _, tt := range tests {
+		ok, err := s.Verify(tt.username, tt.password)
+		if err != nil || ok != tt.want {
+			t.Errorf("Verify(%q, %q) = %v, %v; want %v", tt.username, tt.password, ok, err, tt.want)
+		}
+	}
+
+	raw, err := os.ReadFile(s.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), "correct horse") || !strings.Contains(string(raw), `"hash": "$2a$`) {
+		t.Errorf("expected only a bcrypt hash on disk:\n%s", raw)
+	}
+}
+
+func TestStore_AddInvalid(t *testing.T) {
+	s := newTestStore(t)
+	if _, err := s.Add("alice", "correct horse", "admin"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, username, password, role string
+	}{
+		{"empty name", "", "correct horse", "admin"},
+		{"bad characters", "al ice", "correct horse", "admin"},
+		{"long name", strings.Repeat("a", maxUsernameLen+1), "correct horse", "admin"},
+		{"short password", "bob", "short", "admin"},
+		{"long password", "bob", strings.Repeat("p", maxPasswordLen+1), "admin"},
+		{"unknown role", "bob", "correct horse", "root"},
+	}
+	for _, tt := range tests {
+		if _, err := s.Add(tt.username, tt.password, tt.role); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+	if _, err := s.Add("alice", "another one", "viewer"); !errors.Is(err, ErrExists) {
+		t.Errorf("expected ErrExists, got %v", err)
+	}
+}
+
+func TestStore_SetPassword(t *testing.T) {
+	s := newTestStore(t)
+	if _, err := s.Add("alice", "correct horse", "admin"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.SetPassword("alice", "battery staple"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := s.Verify("alice", "correct horse"); ok {
+		t.Error("expected the old password to stop working")
+	}
+	if ok, _ := s.Verify("alice", "battery staple"); !ok {
+		t.Error("expected the new password to work")
+	}
+	if err := s.SetPassword("alice", "short"); err == nil {
+		t.Error("expected a short password to be refused")
+	}
+	if err := s.SetPassword("bob", "battery staple"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestStore_ListGetRemove(t *testing.T) {
+	s := newTestStore(t)
+	for _, name := range []string{"carol", "alice"} {
+		if _, err := s.Add(name, "correct horse", "viewer"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	users, err := s.List()
+	if err != nil || len(users) != 2 || users[0].Username != "alice" || users[0].Hash != "" {
+		t.Fatalf("expected users by name without hashes, got %+v, %v", users, err)
+	}
+	if u, err := s.Get("carol"); err != nil || u.Role != "viewer" || u.Hash != "" {
+		t.Errorf("unexpected user %+v, %v", u, err)
+	}
+
+	if err := s.Remove("carol"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get("carol"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if err := s.Remove("carol"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestStore_Empty(t *testing.T) {
+	s := newTestStore(t)
+	if ok, err := s.Verify("admin", "admin"); ok || err != nil {
+		t.Errorf("expected no users, got %v, %v", ok, err)
+	}
+	if users, err := s.List(); err != nil || len(users) != 0 {
+		t.Errorf("expected no users, got %+v, %v", users, err)
+	}
+}
@@ -0,0 +1,101 @@
+// Package loginapproval lets the Telegram bot approve Web UI logins. The
+// Web UI sends a Request over a local Unix socket; the bot asks its
+// authorized users and answers with a Decision on the same connection.
+// The protocol is one JSON object each way.
+package loginapproval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Decision statuses.
+const (
+	StatusApproved = "approved"
+	StatusDenied   = "denied"
+	StatusExpired  = "expired"
+	StatusError    = "error"
+)
+
+// MaxWait bounds how long a request stays open.
+const MaxWait = 5 * time.Minute
+
+// ErrUnavailable is returned when the bot is not listening.
+var ErrUnavailable = errors.New("Telegram bot is not running")
+
+// Request asks for approval of a login.
+type Request struct {
+	ID        string    `json:"id"`
+	Code      string    `json:"code"` // shown on the login page to match the message
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Expires   time.Time `json:"expires"`
+}
+
+// Decision is the answer to a Request.
+type Decision struct {
+	Status string `json:"status"`
+	By     string `json:"by,omitempty"` // Telegram username of the approver
+	Error  string `json:"error,omitempty"`
+}
+
+// Approver decides on a request, blocking until a user answers or ctx ends.
+type Approver interface {
+	Approve(ctx context.Context, req Request) Decision
+}
+
+// Asker sends requests to the bot.
+type Asker interface {
+	Ask(ctx context.Context, req Request) (<-chan Decision, error)
+}
+
+// Client is the Web UI side of the socket.
+type Client struct {
+	path string
+}
+
+// Compile-time interface check
+var _ Asker = (*Client)(nil)
+
+// NewClient creates a client for the socket at path.
+func NewClient(path string) *Client {
+	return &Client{path: path}
+}
+
+// Ask delivers req to the bot and returns a channel that receives the
+// decision once. Fails with ErrUnavailable if the bot is not listening, so
+// the caller can report it right away.
+func (c *Client) Ask(ctx context.Context, req Request) (<-chan Decision, error) {
+	d := net.Dialer{Timeout: 2 * time.Second}
+	conn, err := d.DialContext(ctx, "unix", c.path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	// A little past the expiry so the bot's "expired" answer still arrives.
+	conn.SetDeadline(req.Expires.Add(5 * time.Second))
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("send login request: %w", err)
+	}
+
+	out := make(chan Decision, 1)
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	go func() {
+		defer conn.Close()
+		defer stop()
+
+		var dec Decision
+		if err := json.NewDecoder(conn).Decode(&dec); err != nil {
+			dec = Decision{Status: StatusError, Error: "no answer from Telegram bot"}
+			if time.Now().After(req.Expires) {
+				dec = Decision{Status: StatusExpired}
+			}
+		}
+		out <- dec
+	}()
+	return out, nil
+}
@@ -0,0 +1,157 @@
+package loginapproval
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type approverFunc func(ctx context.Context, req Request) Decision
+
+func (f approverFunc) Approve(ctx context.Context, req Request) Decision { return f(ctx, req) }
+
+// startServer runs a server on a temporary socket and returns its path.
+func startServer(t *testing.T, a Approver) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "login.sock")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- NewServer(path, a).Serve(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	})
+
+	for range 100 {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("server did not start")
+	return ""
+}
+
+func decide(t *testing.T, ch <-chan Decision) Decision {
+	t.Helper()
+	select {
+	case d := <-ch:
+		return d
+	case <-time.After(5 * time.Second):
+		t.Fatal("no decision")
+		return Decision{}
+	}
+}
+
+func TestAsk_Approved(t *testing.T) {
+	requests := make(chan Request, 1)
+	path := startServer(t, approverFunc(func(_ context.Context, req Request) Decision {
+		requests <- req
+		return Decision{Status: StatusApproved, By: "alice"}
+	}))
+
+	req := Request{ID: "abc", Code: "123456", IP: "192.168.50.10", Expires: time.Now().Add(time.Minute)}
+	ch, err := NewClient(path).Ask(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := decide(t, ch); d.Status != StatusApproved || d.By != "alice" {
+		t.Errorf("unexpected decision: %+v", d)
+	}
+	if got := <-requests; got.ID != "abc" || got.Code != "123456" || got.IP != "192.168.50.10" {
+		t.Errorf("unexpected request: %+v", got)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected socket mode 0600, got %o", info.Mode().Perm())
+	}
+}
+
+func TestAsk_Expires(t *testing.T) {
+	path := startServer(t, approverFunc(func(ctx context.Context, _ Request) Decision {
+		<-ctx.Done()
+		return Decision{Status: StatusExpired}
+	}))
+
+	req := Request{ID: "abc", Expires: time.Now().Add(100 * time.Millisecond)}
+	ch, err := NewClient(path).Ask(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := decide(t, ch); d.Status != StatusExpired {
+		t.Errorf("expected expired, got %+v", d)
+	}
+}
+
+func TestAsk_Unavailable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.sock")
+	_, err := NewClient(path).Ask(context.Background(), Request{Expires: time.Now().Add(time.Minute)})
+	if !errors.Is(err, ErrUnavailable) {
+		t.Errorf("expected ErrUnavailable, got %v", err)
+	}
+}
+
+func TestAsk_Cancelled(t *testing.T) {
+	path := startServer(t, approverFunc(func(ctx context.Context, _ Request) Decision {
+		<-ctx.Done()
+		return Decision{Status: StatusExpired}
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := NewClient(path).Ask(ctx, Request{Expires: time.Now().Add(time.Minute)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if d := decide(t, ch); d.Status != StatusError {
+		t.Errorf("expected error after cancel, got %+v", d)
+	}
+}
+
+func TestServe_ReplacesStaleSocket(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "login.sock")
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- NewServer(path, approverFunc(func(context.Context, Request) Decision {
+			return Decision{Status: StatusDenied}
+		})).Serve(ctx)
+	}()
+
+	var ch <-chan Decision
+	var err error
+	for range 100 {
+		if ch, err = NewClient(path).Ask(context.Background(), Request{Expires: time.Now().Add(time.Minute)}); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := decide(t, ch); d.Status != StatusDenied {
+		t.Errorf("expected denied, got %+v", d)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Serve: %v", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Error("expected the socket to be removed on shutdown")
+	}
+}
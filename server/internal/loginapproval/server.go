@@ -0,0 +1,81 @@
+package loginapproval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"os"
+	"time"
+)
+
+// readTimeout bounds how long a client may take to send its request.
+const readTimeout = 5 * time.Second
+
+// Server is the bot side of the socket.
+type Server struct {
+	path     string
+	approver Approver
+}
+
+// NewServer creates a server answering requests with approver.
+func NewServer(path string, approver Approver) *Server {
+	return &Server{path: path, approver: approver}
+}
+
+// Serve listens on the socket until ctx is cancelled. The socket is only
+// accessible to the owner (both daemons run as root on the router).
+func (s *Server) Serve(ctx context.Context) error {
+	// A socket left behind by a crash would make Listen fail.
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	ln, err := net.Listen("unix", s.path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(s.path)
+	if err := os.Chmod(s.path, 0600); err != nil {
+		ln.Close()
+		return err
+	}
+
+	stop := context.AfterFunc(ctx, func() { ln.Close() })
+	defer stop()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go s.handle(ctx, conn)
+	}
+}
+
+func (s *Server) handle(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(readTimeout))
+	var req Request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		slog.Warn("Invalid login approval request", "error", err)
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	deadline := req.Expires
+	if limit := time.Now().Add(MaxWait); deadline.After(limit) {
+		deadline = limit
+	}
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+
+	dec := s.approver.Approve(ctx, req)
+	if err := json.NewEncoder(conn).Encode(dec); err != nil {
+		slog.Warn("Failed to send login decision", "id", req.ID, "error", err)
+	}
+}
@@ -0,0 +1,48 @@
+package logstream
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Cursor holds the resume offset of each source. Its string form,
+// "bot:120,vpn:4096", is used as the SSE event ID so that a reconnecting
+// client (Last-Event-ID) continues where it stopped.
+type Cursor map[string]int64
+
+// ParseCursor parses the string form of a cursor.
+func ParseCursor(s string) (Cursor, error) {
+	c := Cursor{}
+	if s == "" {
+		return c, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		name, off, ok := strings.Cut(part, ":")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid cursor entry %q", part)
+		}
+		n, err := strconv.ParseInt(off, 10, 64)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid offset in cursor entry %q", part)
+		}
+		c[name] = n
+	}
+	return c, nil
+}
+
+// String returns the cursor as "name:offset" pairs sorted by name.
+func (c Cursor) String() string {
+	names := make([]string, 0, len(c))
+	for name := range c {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + ":" + strconv.FormatInt(c[name], 10)
+	}
+	return strings.Join(parts, ",")
+}
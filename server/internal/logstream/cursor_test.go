@@ -0,0 +1,34 @@
+package logstream
+
+import "testing"
+
+func TestCursor_RoundTrip(t *testing.T) {
+	c := Cursor{"vpn": 4096, "bot": 120, "xray": 0}
+	s := c.String()
+	if s != "bot:120,vpn:4096,xray:0" {
+		t.Errorf("unexpected string %q", s)
+	}
+
+	parsed, err := ParseCursor(s)
+	if err != nil {
+		t.Fatalf("ParseCursor: %v", err)
+	}
+	if len(parsed) != 3 || parsed["vpn"] != 4096 || parsed["bot"] != 120 {
+		t.Errorf("unexpected cursor %v", parsed)
+	}
+}
+
+func TestParseCursor_Empty(t *testing.T) {
+	c, err := ParseCursor("")
+	if err != nil || len(c) != 0 {
+		t.Errorf("expected empty cursor, got %v, %v", c, err)
+	}
+}
+
+func TestParseCursor_Invalid(t *testing.T) {
+	for _, s := range []string{"vpn", "vpn:x", ":10", "vpn:-1"} {
+		if _, err := ParseCursor(s); err == nil {
+			t.Errorf("ParseCursor(%q): expected error", s)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package logstream
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strings"
+)
+
+// Level is a log severity, ordered from least to most severe.
+type Level int
+
+// Severity levels recognized in log lines.
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// ParseLevel parses "debug", "info", "warn"/"warning" or "error".
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(s) {
+	case "debug", "trace":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown level %q: valid values are debug, info, warn, error", s)
+}
+
+// levelTokens maps the level markers of the three log formats:
+// slog text ("level=WARN"), vpn-director.sh ("2026-01-11T12:35:16 WARN  [..]")
+// and Xray ("[Warning]").
+var levelTokens = map[string]Level{
+	"TRACE": LevelDebug, "DEBUG": LevelDebug, "[Debug]": LevelDebug,
+	"INFO": LevelInfo, "[Info]": LevelInfo,
+	"WARN": LevelWarn, "WARNING": LevelWarn, "[Warning]": LevelWarn,
+	"ERROR": LevelError, "[Error]": LevelError,
+}
+
+// LineLevel detects the severity of a line. Lines without a recognizable
+// marker (e.g. Xray access log entries) are treated as info.
+func LineLevel(text string) Level {
+	if i := strings.Index(text, "level="); i >= 0 {
+		word := text[i+len("level="):]
+		if end := strings.IndexByte(word, ' '); end >= 0 {
+			word = word[:end]
+		}
+		if l, ok := levelTokens[word]; ok {
+			return l
+		}
+	}
+	// The marker is among the first few fields in the other formats.
+	for i, field := range strings.Fields(text) {
+		if i >= 4 {
+			break
+		}
+		if l, ok := levelTokens[field]; ok {
+			return l
+		}
+	}
+	return LevelInfo
+}
+
+// Filter selects lines. The zero value matches everything.
+type Filter struct {
+	MinLevel Level
+	Pattern  *regexp.Regexp
+	client   netip.Addr
+}
+
+// NewFilter builds a filter. level, pattern and client may be empty; client
+// is an IP address matched as a whole address (so 10.0.0.1 does not match
+// 10.0.0.10).
+func NewFilter(level, pattern, client string) (*Filter, error) {
+	f := &Filter{}
+	if level != "" {
+		l, err := ParseLevel(level)
+		if err != nil {
+			return nil, err
+		}
+		f.MinLevel = l
+	}
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %w", err)
+		}
+		f.Pattern = re
+	}
+	if client != "" {
+		addr, err := netip.ParseAddr(client)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client IP %q", client)
+		}
+		f.client = addr
+	}
+	return f, nil
+}
+
+// Match reports whether a line passes the filter.
+func (f *Filter) Match(text string) bool {
+	if f.MinLevel > LevelDebug && LineLevel(text) < f.MinLevel {
+		return false
+	}
+	if f.Pattern != nil && !f.Pattern.MatchString(text) {
+		return false
+	}
+	if f.client.IsValid() && !containsAddr(text, f.client) {
+		return false
+	}
+	return true
+}
+
+// containsAddr reports whether text mentions addr as a whole address. An
+// IPv4 address may be followed by a port ("10.0.0.1:5353") or preceded by a
+// network prefix ("tcp:10.0.0.1").
+func containsAddr(text string, addr netip.Addr) bool {
+	ip := addr.String()
+	v6 := addr.Is6()
+	for from := 0; ; {
+		i := strings.Index(text[from:], ip)
+		if i < 0 {
+			return false
+		}
+		start, end := from+i, from+i+len(ip)
+		from = start + 1
+
+		if start > 0 && partOfAddr(text[start-1], v6, true) {
+			continue
+		}
+		if end < len(text) && partOfAddr(text[end], v6, false) {
+			// A trailing dot or colon ends a sentence or starts a port.
+			if !(text[end] == '.' || (!v6 && text[end] == ':')) ||
+				(end+1 < len(text) && text[end] == '.' && isDigit(text[end+1])) {
+				continue
+			}
+		}
+		return true
+	}
+}
+
+// partOfAddr reports whether c next to an address would make it part of a
+// longer address.
+func partOfAddr(c byte, v6, before bool) bool {
+	if isDigit(c) || c == '.' {
+		return true
+	}
+	if v6 {
+		return c == ':' || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+	}
+	// Only a port may follow an IPv4 address; a prefix like "tcp:" may precede it.
+	return !before && c == ':'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
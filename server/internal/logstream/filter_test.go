@@ -0,0 +1,112 @@
+package logstream
+
+import "testing"
+
+func TestLineLevel(t *testing.T) {
+	tests := []struct {
+		line string
+		want Level
+	}{
+		{`time=2026-01-11T12:35:16.000Z level=WARN source=main.go:10 msg="slow"`, LevelWarn},
+		{`time=2026-01-11T12:35:16.000Z level=DEBUG msg="x"`, LevelDebug},
+		{`2026-01-11T12:35:16 ERROR [ipset] - download failed`, LevelError},
+		{`2026-01-11T12:35:16 INFO  [tproxy] - Applied TPROXY iptables rules`, LevelInfo},
+		{`2026/01/11 12:35:16 [Warning] core: something`, LevelWarn},
+		{`2026/01/11 12:35:16.123456 from 192.168.50.23:5353 accepted udp:1.1.1.1:53 [proxy]`, LevelInfo},
+		{`plain text that only mentions ERROR later on`, LevelInfo},
+	}
+	for _, tt := range tests {
+		if got := LineLevel(tt.line); got != tt.want {
+			t.Errorf("LineLevel(%q) = %d, want %d", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, s := range []string{"debug", "INFO", "warn", "warning", "error"} {
+		if _, err := ParseLevel(s); err != nil {
+			t.Errorf("ParseLevel(%q): %v", s, err)
+		}
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("expected error for unknown level")
+	}
+}
+
+func TestFilter_Level(t *testing.T) {
+	f, err := NewFilter("warn", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Match("2026-01-11T12:35:16 INFO  [x] - fine") {
+		t.Error("info should not pass a warn filter")
+	}
+	if !f.Match("2026-01-11T12:35:16 ERROR [x] - broken") {
+		t.Error("error should pass a warn filter")
+	}
+}
+
+func TestFilter_Pattern(t *testing.T) {
+	f, err := NewFilter("", `ipset|tproxy`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.Match("updating ipset ru") || f.Match("something else") {
+		t.Error("pattern filter mismatch")
+	}
+
+	if _, err := NewFilter("", "(", ""); err == nil {
+		t.Error("expected error for invalid regex")
+	}
+}
+
+func TestFilter_Client(t *testing.T) {
+	f, err := NewFilter("", "", "192.168.50.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{"from 192.168.50.2:5353 accepted udp:1.1.1.1:53", true},
+		{"tcp:192.168.50.2:443 accepted", true},
+		{"client 192.168.50.2 paused.", true},
+		{"client 192.168.50.23 paused", false},
+		{"client 192.168.50.2.5 odd", false},
+		{"client 10.192.168.50.2 odd", false},
+		{"no address here", false},
+	}
+	for _, tt := range tests {
+		if got := f.Match(tt.line); got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+
+	if _, err := NewFilter("", "", "not-an-ip"); err == nil {
+		t.Error("expected error for invalid client IP")
+	}
+}
+
+func TestFilter_ClientIPv6(t *testing.T) {
+	f, err := NewFilter("", "", "fd00::1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.Match("from [fd00::1]:5353 accepted") {
+		t.Error("expected bracketed IPv6 match")
+	}
+	if f.Match("from [fd00::12]:5353 accepted") {
+		t.Error("fd00::1 should not match fd00::12")
+	}
+}
+
+func TestFilter_ZeroMatchesAll(t *testing.T) {
+	f, err := NewFilter("", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.Match("anything") {
+		t.Error("empty filter should match everything")
+	}
+}
@@ -0,0 +1,202 @@
+// Package logstream follows log files in Go and filters their lines. It
+// copes with the two kinds of rotation used on the router: truncation in
+// place (logging.StartRotation) and replacement by a new file
+// (vpn-director.sh moves the log to *.old).
+package logstream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+)
+
+const (
+	// maxReadPerPoll bounds how much a single Poll reads, so a large backlog
+	// is streamed over several polls instead of buffered at once.
+	maxReadPerPoll = 256 * 1024
+
+	// maxLineLength splits lines longer than this so a file without
+	// newlines cannot stall the follower.
+	maxLineLength = 64 * 1024
+)
+
+// Line is one complete line read from a log file.
+type Line struct {
+	Source string `json:"source"`
+	// Offset is the byte offset just past the line; resuming from it
+	// continues with the next line.
+	Offset int64  `json:"offset"`
+	Text   string `json:"line"`
+}
+
+// Follower reads lines appended to a file, starting at an offset.
+type Follower struct {
+	source string
+	path   string
+	offset int64
+
+	file *os.File
+	info os.FileInfo
+}
+
+// NewFollower creates a follower for path starting at offset. An offset past
+// the end of the file (e.g. after rotation) restarts from the beginning.
+func NewFollower(source, path string, offset int64) *Follower {
+	return &Follower{source: source, path: path, offset: offset}
+}
+
+// Offset returns the offset of the next unread byte.
+func (f *Follower) Offset() int64 {
+	return f.offset
+}
+
+// Poll returns complete lines appended since the last call. rotated is true
+// when the file was truncated or replaced; reading then restarts at 0.
+// A missing file yields no lines and no error.
+func (f *Follower) Poll() (lines []Line, rotated bool, err error) {
+	if rotated, err = f.reopenIfRotated(); err != nil || f.file == nil {
+		return nil, rotated, err
+	}
+
+	info, err := f.file.Stat()
+	if err != nil {
+		return nil, rotated, err
+	}
+	if info.Size() < f.offset {
+		f.offset = 0
+		rotated = true
+	}
+	if info.Size() == f.offset {
+		return nil, rotated, nil
+	}
+
+	n := info.Size() - f.offset
+	if n > maxReadPerPoll {
+		n = maxReadPerPoll
+	}
+	buf := make([]byte, n)
+	read, err := f.file.ReadAt(buf, f.offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, rotated, err
+	}
+	buf = buf[:read]
+
+	for len(buf) > 0 {
+		i := bytes.IndexByte(buf, '\n')
+		var text []byte
+		var consumed int
+		switch {
+		case i >= 0 && i < maxLineLength:
+			text, consumed = buf[:i], i+1
+		case len(buf) >= maxLineLength:
+			text, consumed = buf[:maxLineLength], maxLineLength
+		default:
+			// Incomplete last line: wait until it is finished.
+			return lines, rotated, nil
+		}
+		f.offset += int64(consumed)
+		buf = buf[consumed:]
+		lines = append(lines, Line{
+			Source: f.source,
+			Offset: f.offset,
+			Text:   string(bytes.TrimRight(text, "\r")),
+		})
+	}
+	return lines, rotated, nil
+}
+
+// Close releases the open file.
+func (f *Follower) Close() {
+	if f.file != nil {
+		f.file.Close()
+		f.file = nil
+		f.info = nil
+	}
+}
+
+// reopenIfRotated opens the file on first use and reopens it from the start
+// when the path now refers to a different file.
+func (f *Follower) reopenIfRotated() (bool, error) {
+	info, err := os.Stat(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		// Moved away and not recreated yet: finish later from the new file.
+		if f.file != nil {
+			f.Close()
+			f.offset = 0
+			return true, nil
+		}
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if f.file != nil && os.SameFile(f.info, info) {
+		return false, nil
+	}
+
+	rotated := f.file != nil
+	f.Close()
+	file, err := os.Open(f.path)
+	if err != nil {
+		return false, err
+	}
+	f.file, f.info = file, info
+	if rotated || info.Size() < f.offset {
+		f.offset = 0
+		rotated = true
+	}
+	return rotated, nil
+}
+
+// TailOffset returns the offset where the last n lines of the file start.
+// A missing file has offset 0.
+func TailOffset(path string, n int) (int64, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	end := info.Size()
+	if n <= 0 || end == 0 {
+		return end, nil
+	}
+
+	const chunk = 4096
+	buf := make([]byte, chunk)
+	pos := end
+	newlines := 0
+	for pos > 0 {
+		size := int64(chunk)
+		if pos < size {
+			size = pos
+		}
+		pos -= size
+		if _, err := file.ReadAt(buf[:size], pos); err != nil && !errors.Is(err, io.EOF) {
+			return 0, err
+		}
+		for i := size - 1; i >= 0; i-- {
+			if buf[i] != '\n' {
+				continue
+			}
+			// A trailing newline ends the last line; it does not start one.
+			if pos+i == end-1 {
+				continue
+			}
+			newlines++
+			if newlines == n {
+				return pos + i + 1, nil
+			}
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,246 @@
+package logstream
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func appendFile(t *testing.T, path, s string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(s); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func texts(lines []Line) []string {
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		out[i] = l.Text
+	}
+	return out
+}
+
+func poll(t *testing.T, f *Follower) ([]string, bool) {
+	t.Helper()
+	lines, rotated, err := f.Poll()
+	if err != nil {
+		t.Fatalf("Poll: %v", err)
+	}
+	return texts(lines), rotated
+}
+
+func TestFollower_ReadsCompleteLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "one\ntwo\nthr")
+
+	f := NewFollower("vpn", path, 0)
+	defer f.Close()
+
+	got, _ := poll(t, f)
+	if strings.Join(got, "|") != "one|two" {
+		t.Errorf("expected one|two, got %v", got)
+	}
+	if f.Offset() != 8 {
+		t.Errorf("expected offset 8, got %d", f.Offset())
+	}
+
+	appendFile(t, path, "ee\n")
+	got, _ = poll(t, f)
+	if strings.Join(got, "|") != "three" {
+		t.Errorf("expected partial line to be completed, got %v", got)
+	}
+}
+
+func TestFollower_LineOffsets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "a\nbb\n")
+
+	f := NewFollower("vpn", path, 0)
+	defer f.Close()
+
+	lines, _, err := f.Poll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 || lines[0].Offset != 2 || lines[1].Offset != 5 || lines[1].Source != "vpn" {
+		t.Errorf("unexpected lines: %+v", lines)
+	}
+}
+
+func TestFollower_ResumeFromOffset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "a\nbb\nccc\n")
+
+	f := NewFollower("vpn", path, 5)
+	defer f.Close()
+
+	got, rotated := poll(t, f)
+	if strings.Join(got, "|") != "ccc" || rotated {
+		t.Errorf("expected ccc without rotation, got %v rotated=%v", got, rotated)
+	}
+}
+
+func TestFollower_OffsetPastEndRestarts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "new\n")
+
+	f := NewFollower("vpn", path, 1000)
+	defer f.Close()
+
+	got, rotated := poll(t, f)
+	if strings.Join(got, "|") != "new" || !rotated {
+		t.Errorf("expected restart from 0, got %v rotated=%v", got, rotated)
+	}
+}
+
+func TestFollower_Truncation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "old line one\nold line two\n")
+
+	f := NewFollower("bot", path, 0)
+	defer f.Close()
+	poll(t, f)
+
+	// logging.StartRotation truncates in place.
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, path, "fresh\n")
+
+	got, rotated := poll(t, f)
+	if strings.Join(got, "|") != "fresh" || !rotated {
+		t.Errorf("expected fresh after truncation, got %v rotated=%v", got, rotated)
+	}
+}
+
+func TestFollower_Replacement(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "vpn.log")
+	appendFile(t, path, "before\n")
+
+	f := NewFollower("vpn", path, 0)
+	defer f.Close()
+	poll(t, f)
+
+	// vpn-director.sh moves the log aside and starts a new file.
+	if err := os.Rename(path, path+".old"); err != nil {
+		t.Fatal(err)
+	}
+	got, rotated := poll(t, f)
+	if len(got) != 0 || !rotated {
+		t.Errorf("expected rotation with no lines while file is missing, got %v rotated=%v", got, rotated)
+	}
+
+	appendFile(t, path, "after\n")
+	got, _ = poll(t, f)
+	if strings.Join(got, "|") != "after" {
+		t.Errorf("expected after from new file, got %v", got)
+	}
+}
+
+func TestFollower_ReplacementWhileOpen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "vpn.log")
+	appendFile(t, path, "before line\n")
+
+	f := NewFollower("vpn", path, 0)
+	defer f.Close()
+	poll(t, f)
+
+	os.Rename(path, path+".old")
+	appendFile(t, path, "x\n")
+
+	got, rotated := poll(t, f)
+	if strings.Join(got, "|") != "x" || !rotated {
+		t.Errorf("expected x from replaced file, got %v rotated=%v", got, rotated)
+	}
+}
+
+func TestFollower_MissingFile(t *testing.T) {
+	f := NewFollower("xray", filepath.Join(t.TempDir(), "missing.log"), 0)
+	defer f.Close()
+
+	got, rotated := poll(t, f)
+	if len(got) != 0 || rotated {
+		t.Errorf("expected nothing for missing file, got %v rotated=%v", got, rotated)
+	}
+}
+
+func TestFollower_LongLineIsSplit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, strings.Repeat("x", maxLineLength+10))
+
+	f := NewFollower("vpn", path, 0)
+	defer f.Close()
+
+	got, _ := poll(t, f)
+	if len(got) != 1 || len(got[0]) != maxLineLength {
+		t.Errorf("expected one capped line, got %d lines", len(got))
+	}
+}
+
+func TestTailOffset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "one\ntwo\nthree\n")
+
+	tests := []struct {
+		n    int
+		want int64
+	}{
+		{0, 14},
+		{1, 8},
+		{2, 4},
+		{3, 0},
+		{10, 0},
+	}
+	for _, tt := range tests {
+		got, err := TailOffset(path, tt.n)
+		if err != nil {
+			t.Fatalf("TailOffset(%d): %v", tt.n, err)
+		}
+		if got != tt.want {
+			t.Errorf("TailOffset(%d) = %d, want %d", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestTailOffset_NoTrailingNewline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "one\ntwo")
+
+	got, err := TailOffset(path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 4 {
+		t.Errorf("expected 4, got %d", got)
+	}
+}
+
+func TestTailOffset_LargeFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	line := strings.Repeat("y", 99) + "\n"
+	appendFile(t, path, strings.Repeat(line, 200))
+
+	got, err := TailOffset(path, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 150*100 {
+		t.Errorf("expected %d, got %d", 150*100, got)
+	}
+}
+
+func TestTailOffset_MissingFile(t *testing.T) {
+	got, err := TailOffset(filepath.Join(t.TempDir(), "missing.log"), 10)
+	if err != nil || got != 0 {
+		t.Errorf("expected 0, nil; got %d, %v", got, err)
+	}
+}
@@ -0,0 +1,120 @@
+package logstream
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/paths"
+)
+
+// Source is a named log file.
+type Source struct {
+	Name string
+	Path string
+}
+
+// Sources returns the log files that can be followed, in display order.
+func Sources(p paths.Paths) []Source {
+	return []Source{
+		{Name: "vpn", Path: p.VPNLogPath},
+		{Name: "xray", Path: p.XrayLogPath},
+		{Name: "bot", Path: p.BotLogPath},
+	}
+}
+
+// SelectSources picks sources by a comma-separated list of names. An empty
+// list selects all of them.
+func SelectSources(all []Source, names string) ([]Source, error) {
+	if names == "" {
+		return all, nil
+	}
+	var selected []Source
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, s := range all {
+			if s.Name == name {
+				selected = append(selected, s)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown source %q: valid values are %s", name, sourceNames(all))
+		}
+	}
+	return selected, nil
+}
+
+func sourceNames(sources []Source) string {
+	names := make([]string, len(sources))
+	for i, s := range sources {
+		names[i] = s.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// Stream follows several sources and returns the lines that pass a filter.
+type Stream struct {
+	followers []*Follower
+	filter    *Filter
+}
+
+// NewStream starts following sources. A source present in cursor resumes
+// from its offset; other sources start tail lines before the end.
+func NewStream(sources []Source, cursor Cursor, tail int, filter *Filter) (*Stream, error) {
+	s := &Stream{filter: filter}
+	for _, src := range sources {
+		offset, ok := cursor[src.Name]
+		if !ok {
+			var err error
+			if offset, err = TailOffset(src.Path, tail); err != nil {
+				s.Close()
+				return nil, fmt.Errorf("%s: %w", src.Name, err)
+			}
+		}
+		s.followers = append(s.followers, NewFollower(src.Name, src.Path, offset))
+	}
+	return s, nil
+}
+
+// Cursor returns the current offset of every source.
+func (s *Stream) Cursor() Cursor {
+	c := make(Cursor, len(s.followers))
+	for _, f := range s.followers {
+		c[f.source] = f.Offset()
+	}
+	return c
+}
+
+// Poll returns new matching lines from all sources and the names of sources
+// that were rotated since the last call. A source that fails to read is
+// reported in the error but does not stop the others.
+func (s *Stream) Poll() (lines []Line, rotated []string, err error) {
+	var errs []string
+	for _, f := range s.followers {
+		got, rot, ferr := f.Poll()
+		if ferr != nil {
+			errs = append(errs, f.source+": "+ferr.Error())
+		}
+		if rot {
+			rotated = append(rotated, f.source)
+		}
+		for _, l := range got {
+			if s.filter == nil || s.filter.Match(l.Text) {
+				lines = append(lines, l)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		err = fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return lines, rotated, err
+}
+
+// Close stops following all sources.
+func (s *Stream) Close() {
+	for _, f := range s.followers {
+		f.Close()
+	}
+}
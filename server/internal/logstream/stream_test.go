@@ -0,0 +1,73 @@
+package logstream
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/paths"
+)
+
+func TestSources(t *testing.T) {
+	p := paths.Paths{VPNLogPath: "/v.log", XrayLogPath: "/x.log", BotLogPath: "/b.log"}
+	got := Sources(p)
+	if len(got) != 3 || got[0] != (Source{"vpn", "/v.log"}) || got[1] != (Source{"xray", "/x.log"}) || got[2] != (Source{"bot", "/b.log"}) {
+		t.Errorf("unexpected sources: %v", got)
+	}
+}
+
+func TestSelectSources(t *testing.T) {
+	all := Sources(paths.Default())
+
+	got, err := SelectSources(all, "")
+	if err != nil || len(got) != 3 {
+		t.Errorf("empty list should select all, got %v, %v", got, err)
+	}
+
+	got, err = SelectSources(all, "bot, vpn")
+	if err != nil || len(got) != 2 || got[0].Name != "bot" || got[1].Name != "vpn" {
+		t.Errorf("unexpected selection %v, %v", got, err)
+	}
+
+	if _, err := SelectSources(all, "kernel"); err == nil || !strings.Contains(err.Error(), "vpn, xray, bot") {
+		t.Errorf("expected error listing valid sources, got %v", err)
+	}
+}
+
+func TestStream_TailResumeAndFilter(t *testing.T) {
+	dir := t.TempDir()
+	vpn := filepath.Join(dir, "vpn.log")
+	bot := filepath.Join(dir, "bot.log")
+	appendFile(t, vpn, "2026-01-11T12:00:00 INFO  [a] - v1\n2026-01-11T12:00:01 ERROR [a] - v2\n")
+	appendFile(t, bot, "b1\nb2\nb3\n")
+	sources := []Source{{"vpn", vpn}, {"bot", bot}}
+
+	// bot resumes after "b1"; vpn starts one line before the end.
+	s, err := NewStream(sources, Cursor{"bot": 3}, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	lines, _, err := s.Poll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(texts(lines), "|"); got != "2026-01-11T12:00:01 ERROR [a] - v2|b2|b3" {
+		t.Errorf("unexpected lines %q", got)
+	}
+	if c := s.Cursor(); c["bot"] != 9 || c["vpn"] != int64(len("2026-01-11T12:00:00 INFO  [a] - v1\n2026-01-11T12:00:01 ERROR [a] - v2\n")) {
+		t.Errorf("unexpected cursor %v", c)
+	}
+
+	filter, _ := NewFilter("error", "", "")
+	s2, err := NewStream(sources, Cursor{}, 10, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s2.Close()
+	lines, _, _ = s2.Poll()
+	if len(lines) != 1 || lines[0].Source != "vpn" {
+		t.Errorf("expected only the error line, got %v", texts(lines))
+	}
+}
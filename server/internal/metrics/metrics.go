@@ -0,0 +1,314 @@
+// Package metrics is a small Prometheus text-format exporter. It supports
+// counters, histograms and gauges computed at scrape time, which is all the
+// Web UI needs, without depending on the Prometheus client library.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are histogram buckets (seconds) suited to HTTP latency.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Sample is one labelled value returned by a scrape-time function.
+type Sample struct {
+	LabelValues []string
+	Value       float64
+}
+
+// metric is anything that can write itself in text format.
+type metric interface {
+	name() string
+	write(w *bufio.Writer)
+}
+
+// Registry holds all metrics exported by one endpoint.
+type Registry struct {
+	mu      sync.Mutex
+	metrics map[string]metric
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]metric)}
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.metrics[m.name()]; ok {
+		panic("metrics: duplicate metric " + m.name())
+	}
+	r.metrics[m.name()] = m
+}
+
+// WriteText writes all metrics in Prometheus text format, sorted by name.
+func (r *Registry) WriteText(out io.Writer) error {
+	r.mu.Lock()
+	list := make([]metric, 0, len(r.metrics))
+	for _, m := range r.metrics {
+		list = append(list, m)
+	}
+	r.mu.Unlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].name() < list[j].name() })
+
+	w := bufio.NewWriter(out)
+	for _, m := range list {
+		m.write(w)
+	}
+	return w.Flush()
+}
+
+// Handler serves the registry in text format.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := r.WriteText(w); err != nil {
+			slog.Warn("metrics: write failed", "error", err)
+		}
+	})
+}
+
+// desc is the common part of every metric.
+type desc struct {
+	metricName string
+	help       string
+	kind       string
+	labels     []string
+}
+
+func (d *desc) name() string { return d.metricName }
+
+func (d *desc) header(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.metricName, escapeHelp(d.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.metricName, d.kind)
+}
+
+func (d *desc) checkLabels(values []string) {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", d.metricName, len(d.labels), len(values)))
+	}
+}
+
+// CounterVec is a monotonically increasing counter with labels.
+type CounterVec struct {
+	desc
+	mu     sync.Mutex
+	values map[string]*counterValue
+}
+
+type counterValue struct {
+	labelValues []string
+	value       float64
+}
+
+// NewCounterVec registers a counter.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{
+		desc:   desc{metricName: name, help: help, kind: "counter", labels: labels},
+		values: make(map[string]*counterValue),
+	}
+	r.register(c)
+	return c
+}
+
+// Inc adds one to the counter with the given label values.
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v (must be >= 0) to the counter with the given label values.
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	c.checkLabels(labelValues)
+	key := labelKey(labelValues)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cv, ok := c.values[key]
+	if !ok {
+		cv = &counterValue{labelValues: append([]string(nil), labelValues...)}
+		c.values[key] = cv
+	}
+	cv.value += v
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.values) == 0 {
+		return
+	}
+	c.header(w)
+	for _, key := range sortedKeys(c.values) {
+		cv := c.values[key]
+		writeSample(w, c.metricName, c.labels, cv.labelValues, "", "", cv.value)
+	}
+}
+
+// HistogramVec counts observations into cumulative buckets.
+type HistogramVec struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	values  map[string]*histogramValue
+}
+
+type histogramValue struct {
+	labelValues []string
+	counts      []uint64 // per bucket, not cumulative
+	count       uint64
+	sum         float64
+}
+
+// NewHistogramVec registers a histogram. buckets must be sorted.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{
+		desc:    desc{metricName: name, help: help, kind: "histogram", labels: labels},
+		buckets: buckets,
+		values:  make(map[string]*histogramValue),
+	}
+	r.register(h)
+	return h
+}
+
+// Observe records one value.
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	h.checkLabels(labelValues)
+	key := labelKey(labelValues)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hv, ok := h.values[key]
+	if !ok {
+		hv = &histogramValue{
+			labelValues: append([]string(nil), labelValues...),
+			counts:      make([]uint64, len(h.buckets)),
+		}
+		h.values[key] = hv
+	}
+	for i, upper := range h.buckets {
+		if v <= upper {
+			hv.counts[i]++
+			break
+		}
+	}
+	hv.count++
+	hv.sum += v
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.values) == 0 {
+		return
+	}
+	h.header(w)
+	for _, key := range sortedKeys(h.values) {
+		hv := h.values[key]
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += hv.counts[i]
+			writeSample(w, h.metricName+"_bucket", h.labels, hv.labelValues, "le", formatFloat(upper), float64(cumulative))
+		}
+		writeSample(w, h.metricName+"_bucket", h.labels, hv.labelValues, "le", "+Inf", float64(hv.count))
+		writeSample(w, h.metricName+"_sum", h.labels, hv.labelValues, "", "", hv.sum)
+		writeSample(w, h.metricName+"_count", h.labels, hv.labelValues, "", "", float64(hv.count))
+	}
+}
+
+// funcMetric computes its samples at scrape time.
+type funcMetric struct {
+	desc
+	fn func() []Sample
+}
+
+// NewGaugeFunc registers a gauge whose samples are computed on each scrape.
+func (r *Registry) NewGaugeFunc(name, help string, labels []string, fn func() []Sample) {
+	r.register(&funcMetric{desc: desc{metricName: name, help: help, kind: "gauge", labels: labels}, fn: fn})
+}
+
+// NewCounterFunc registers a counter whose samples are computed on each
+// scrape, for counters kept elsewhere (e.g. by Xray).
+func (r *Registry) NewCounterFunc(name, help string, labels []string, fn func() []Sample) {
+	r.register(&funcMetric{desc: desc{metricName: name, help: help, kind: "counter", labels: labels}, fn: fn})
+}
+
+func (f *funcMetric) write(w *bufio.Writer) {
+	samples := f.fn()
+	if len(samples) == 0 {
+		return
+	}
+	f.header(w)
+	for _, s := range samples {
+		if len(s.LabelValues) != len(f.labels) {
+			slog.Warn("metrics: label mismatch", "metric", f.metricName)
+			continue
+		}
+		writeSample(w, f.metricName, f.labels, s.LabelValues, "", "", s.Value)
+	}
+}
+
+func writeSample(w *bufio.Writer, name string, labels, values []string, extraLabel, extraValue string, v float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || extraLabel != "" {
+		w.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, `%s="%s"`, l, escapeLabel(values[i]))
+		}
+		if extraLabel != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, `%s="%s"`, extraLabel, extraValue)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(v))
+	w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+
+func labelKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
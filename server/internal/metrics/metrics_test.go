@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func render(t *testing.T, r *Registry) string {
+	t.Helper()
+	var sb strings.Builder
+	if err := r.WriteText(&sb); err != nil {
+		t.Fatalf("WriteText: %v", err)
+	}
+	return sb.String()
+}
+
+func TestCounterVec(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("test_requests_total", "Requests.", "method", "code")
+	c.Inc("GET", "200")
+	c.Inc("GET", "200")
+	c.Add(3, "POST", "500")
+
+	got := render(t, r)
+	want := `# HELP test_requests_total Requests.
+# TYPE test_requests_total counter
+test_requests_total{method="GET",code="200"} 2
+test_requests_total{method="POST",code="500"} 3
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestCounterVec_EmptyIsOmitted(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("test_total", "Nothing yet.")
+	if got := render(t, r); got != "" {
+		t.Errorf("expected no output, got %q", got)
+	}
+}
+
+func TestHistogramVec(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogramVec("test_seconds", "Durations.", []float64{0.1, 1}, "op")
+	h.Observe(0.05, "apply")
+	h.Observe(0.5, "apply")
+	h.Observe(5, "apply")
+
+	got := render(t, r)
+	for _, line := range []string{
+		`test_seconds_bucket{op="apply",le="0.1"} 1`,
+		`test_seconds_bucket{op="apply",le="1"} 2`,
+		`test_seconds_bucket{op="apply",le="+Inf"} 3`,
+		`test_seconds_sum{op="apply"} 5.55`,
+		`test_seconds_count{op="apply"} 3`,
+	} {
+		if !strings.Contains(got, line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, got)
+		}
+	}
+}
+
+func TestGaugeFunc(t *testing.T) {
+	r := NewRegistry()
+	r.NewGaugeFunc("test_up", "Up.", nil, func() []Sample { return []Sample{{Value: 1}} })
+	r.NewGaugeFunc("test_info", "Info.", []string{"name"}, func() []Sample {
+		return []Sample{{LabelValues: []string{`a "quoted"\name`}, Value: 1}}
+	})
+	r.NewCounterFunc("test_empty_total", "Empty.", nil, func() []Sample { return nil })
+
+	got := render(t, r)
+	if !strings.Contains(got, "test_up 1\n") {
+		t.Errorf("missing unlabelled gauge:\n%s", got)
+	}
+	if !strings.Contains(got, `test_info{name="a \"quoted\"\\name"} 1`) {
+		t.Errorf("label not escaped:\n%s", got)
+	}
+	if strings.Contains(got, "test_empty_total") {
+		t.Errorf("empty func metric should be omitted:\n%s", got)
+	}
+	if strings.Index(got, "test_info") > strings.Index(got, "test_up") {
+		t.Errorf("metrics should be sorted by name:\n%s", got)
+	}
+}
+
+func TestRegistry_DuplicatePanics(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("dup_total", "x")
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic on duplicate registration")
+		}
+	}()
+	r.NewCounterVec("dup_total", "x")
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("test_total", "x").Inc()
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "test_total 1") {
+		t.Errorf("unexpected body %q", rec.Body.String())
+	}
+}
@@ -0,0 +1,136 @@
+// Package netaccess decides where the Web UI listens and who may connect
+// to it. By default it listens on the LAN bridge only and accepts clients
+// from the bridge's subnets; subnets of other bridges (guest networks) are
+// always refused unless allowed by name.
+package netaccess
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// LANBridge is the router's LAN bridge interface.
+const LANBridge = "br0"
+
+// Interfaces maps interface names to their addresses, each with the
+// prefix length of its subnet.
+type Interfaces map[string][]netip.Prefix
+
+// SystemInterfaces returns the addresses of the interfaces that are up.
+func SystemInterfaces() (Interfaces, error) {
+	list, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("list interfaces: %w", err)
+	}
+	ifs := make(Interfaces)
+	for _, iface := range list {
+		if iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			ipnet, ok := a.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			addr, ok := netip.AddrFromSlice(ipnet.IP)
+			if !ok {
+				continue
+			}
+			bits, _ := ipnet.Mask.Size()
+			addr = addr.Unmap()
+			if addr.Is4() && bits > 32 {
+				bits -= 96
+			}
+			ifs[iface.Name] = append(ifs[iface.Name], netip.PrefixFrom(addr, bits))
+		}
+	}
+	return ifs, nil
+}
+
+// ListenAddrs returns the host:port addresses to listen on for the
+// webui.listen entries: interface names (all their addresses except
+// link-local ones), IP addresses, host:port pairs, or "*" for every
+// interface. Without entries it listens on LANBridge and fails when there
+// is no such bridge, so a missing bridge never exposes the Web UI on WAN.
+func (ifs Interfaces) ListenAddrs(listen []string, port int) ([]string, error) {
+	if len(listen) == 0 {
+		if !ifs.has(LANBridge) {
+			return nil, fmt.Errorf("no %s interface; set webui.listen (\"*\" for every interface)", LANBridge)
+		}
+		listen = []string{LANBridge}
+	}
+
+	var addrs []string
+	add := func(a string) {
+		if !slices.Contains(addrs, a) {
+			addrs = append(addrs, a)
+		}
+	}
+	for _, entry := range listen {
+		entry = strings.TrimSpace(entry)
+		switch {
+		case entry == "*":
+			add(":" + strconv.Itoa(port))
+		case ifs.has(entry):
+			n := len(addrs)
+			for _, p := range ifs[entry] {
+				if !p.Addr().IsLinkLocalUnicast() {
+					add(netip.AddrPortFrom(p.Addr(), uint16(port)).String())
+				}
+			}
+			if len(addrs) == n {
+				return nil, fmt.Errorf("interface %q has no usable address", entry)
+			}
+		default:
+			if addr, err := netip.ParseAddr(entry); err == nil {
+				add(netip.AddrPortFrom(addr.Unmap(), uint16(port)).String())
+				continue
+			}
+			if ap, err := netip.ParseAddrPort(entry); err == nil {
+				add(ap.String())
+				continue
+			}
+			return nil, fmt.Errorf("listen: unknown interface or address %q", entry)
+		}
+	}
+	return addrs, nil
+}
+
+// has reports whether name is a known interface.
+func (ifs Interfaces) has(name string) bool {
+	_, ok := ifs[name]
+	return ok
+}
+
+// subnets returns the networks of the interface's addresses, skipping
+// link-local ones.
+func (ifs Interfaces) subnets(name string) []netip.Prefix {
+	var out []netip.Prefix
+	for _, p := range ifs[name] {
+		if !p.Addr().IsLinkLocalUnicast() {
+			out = append(out, p.Masked())
+		}
+	}
+	return out
+}
+
+// guestBridges returns the bridges other than LANBridge. On Asuswrt-Merlin
+// these carry guest networks isolated from the LAN.
+func (ifs Interfaces) guestBridges() []string {
+	var names []string
+	for name := range ifs {
+		if strings.HasPrefix(name, "br") && name != LANBridge {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,79 @@
+package netaccess
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+// routerInterfaces is a router with a LAN, a guest network and a WAN.
+func routerInterfaces() Interfaces {
+	return Interfaces{
+		"lo":   {netip.MustParsePrefix("127.0.0.1/8")},
+		"br0":  {netip.MustParsePrefix("192.168.50.1/24"), netip.MustParsePrefix("2001:db8:50::1/64"), netip.MustParsePrefix("fe80::1/64")},
+		"br1":  {netip.MustParsePrefix("192.168.101.1/24")},
+		"eth0": {netip.MustParsePrefix("203.0.113.7/24")},
+	}
+}
+
+func TestListenAddrs(t *testing.T) {
+	ifs := routerInterfaces()
+	tests := []struct {
+		name   string
+		listen []string
+		want   []string
+	}{
+		{"default is the LAN bridge", nil, []string{"192.168.50.1:8444", "[2001:db8:50::1]:8444"}},
+		{"interfaces and addresses", []string{"lo", "192.168.50.1", "127.0.0.1"}, []string{"127.0.0.1:8444", "192.168.50.1:8444"}},
+		{"own port", []string{"10.8.0.1:9443"}, []string{"10.8.0.1:9443"}},
+		{"all interfaces", []string{"*"}, []string{":8444"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ifs.ListenAddrs(tt.listen, 8444)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestListenAddrs_NoBridge(t *testing.T) {
+	ifs := Interfaces{"lo": {netip.MustParsePrefix("127.0.0.1/8")}}
+	if got, err := ifs.ListenAddrs(nil, 8444); err == nil {
+		t.Errorf("expected error without a bridge, got %v", got)
+	}
+
+	// Every interface only when asked for explicitly
+	got, err := ifs.ListenAddrs([]string{"*"}, 8444)
+	if err != nil || !slices.Equal(got, []string{":8444"}) {
+		t.Errorf("expected every interface, got %v, %v", got, err)
+	}
+}
+
+func TestListenAddrs_Invalid(t *testing.T) {
+	ifs := routerInterfaces()
+	ifs["wg0"] = []netip.Prefix{netip.MustParsePrefix("fe80::2/64")}
+	for _, listen := range []string{"br9", "not an address", "wg0"} {
+		if _, err := ifs.ListenAddrs([]string{listen}, 8444); err == nil {
+			t.Errorf("%q: expected an error", listen)
+		}
+	}
+}
+
+func TestSystemInterfaces(t *testing.T) {
+	ifs, err := SystemInterfaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, prefixes := range ifs {
+		for _, p := range prefixes {
+			if p.Addr().Is4In6() || !p.IsValid() {
+				t.Errorf("unexpected prefix %v", p)
+			}
+		}
+	}
+}
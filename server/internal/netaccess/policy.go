@@ -0,0 +1,109 @@
+package netaccess
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/cidrset"
+)
+
+// loopback is always allowed, so tools on the router itself keep working.
+var loopback = []netip.Prefix{
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("::1/128"),
+}
+
+// privateRanges are allowed by default when there is no LAN bridge.
+var privateRanges = []netip.Prefix{
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("fc00::/7"),
+}
+
+// Policy decides which client addresses may use the Web UI.
+type Policy struct {
+	allow []netip.Prefix
+	deny  []netip.Prefix
+}
+
+// NewPolicy builds the policy for the webui.allow and webui.deny entries.
+// Entries are IPs, CIDRs or interface names (meaning the interface's
+// subnets). Without allow entries the LANBridge subnets are allowed, or
+// the private ranges when there is no such bridge. Subnets of guest
+// bridges are denied unless the bridge is named in allow. Loopback is
+// always allowed; deny wins over allow.
+func NewPolicy(allow, deny []string, ifs Interfaces) (*Policy, error) {
+	p := &Policy{allow: slices.Clone(loopback)}
+
+	if len(allow) == 0 {
+		if ifs.has(LANBridge) {
+			allow = []string{LANBridge}
+		} else {
+			p.allow = append(p.allow, privateRanges...)
+		}
+	}
+	var err error
+	if p.allow, err = appendEntries(p.allow, allow, ifs); err != nil {
+		return nil, fmt.Errorf("allow: %w", err)
+	}
+	if p.deny, err = appendEntries(nil, deny, ifs); err != nil {
+		return nil, fmt.Errorf("deny: %w", err)
+	}
+	for _, name := range ifs.guestBridges() {
+		if !slices.Contains(allow, name) {
+			p.deny = append(p.deny, ifs.subnets(name)...)
+		}
+	}
+	return p, nil
+}
+
+// appendEntries resolves entries to prefixes and appends them to dst.
+func appendEntries(dst []netip.Prefix, entries []string, ifs Interfaces) ([]netip.Prefix, error) {
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if ifs.has(entry) {
+			dst = append(dst, ifs.subnets(entry)...)
+			continue
+		}
+		prefix, err := cidrset.Parse(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%w (not an interface either)", err)
+		}
+		dst = append(dst, prefix)
+	}
+	return dst, nil
+}
+
+// Allowed reports whether a client at addr may connect.
+func (p *Policy) Allowed(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, d := range p.deny {
+		if d.Contains(addr) {
+			return false
+		}
+	}
+	for _, a := range p.allow {
+		if a.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// String lists the allowed and denied networks, for logging.
+func (p *Policy) String() string {
+	format := func(prefixes []netip.Prefix) string {
+		s := make([]string, len(prefixes))
+		for i, prefix := range prefixes {
+			s[i] = cidrset.Format(prefix)
+		}
+		return strings.Join(s, " ")
+	}
+	if len(p.deny) == 0 {
+		return "allow " + format(p.allow)
+	}
+	return "allow " + format(p.allow) + "; deny " + format(p.deny)
+}
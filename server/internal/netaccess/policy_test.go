@@ -0,0 +1,83 @@
+package netaccess
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestNewPolicy_Default(t *testing.T) {
+	p, err := NewPolicy(nil, nil, routerInterfaces())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"192.168.50.23", true},
+		{"2001:db8:50::abcd", true},
+		{"127.0.0.1", true},
+		{"::ffff:192.168.50.23", true},
+		{"192.168.101.5", false}, // guest network
+		{"203.0.113.9", false},   // WAN
+		{"10.8.0.2", false},
+	}
+	for _, tt := range tests {
+		if got := p.Allowed(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.addr, tt.want, got)
+		}
+	}
+	if s := p.String(); !strings.Contains(s, "192.168.50.0/24") || !strings.Contains(s, "deny 192.168.101.0/24") {
+		t.Errorf("unexpected description: %s", s)
+	}
+}
+
+func TestNewPolicy_Configured(t *testing.T) {
+	p, err := NewPolicy([]string{"br0", "10.8.0.0/24", "br1"}, []string{"192.168.50.66"}, routerInterfaces())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"10.8.0.2", true},
+		{"192.168.101.5", true}, // guest bridge allowed by name
+		{"192.168.50.23", true},
+		{"192.168.50.66", false}, // deny wins
+	}
+	for _, tt := range tests {
+		if got := p.Allowed(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.addr, tt.want, got)
+		}
+	}
+
+	// A guest subnet listed by address stays denied
+	p, err = NewPolicy([]string{"192.168.0.0/16"}, nil, routerInterfaces())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Allowed(netip.MustParseAddr("192.168.101.5")) {
+		t.Error("expected the guest network to be denied")
+	}
+}
+
+func TestNewPolicy_NoBridge(t *testing.T) {
+	p, err := NewPolicy(nil, nil, Interfaces{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Allowed(netip.MustParseAddr("172.20.1.1")) || p.Allowed(netip.MustParseAddr("8.8.8.8")) {
+		t.Error("expected private ranges only")
+	}
+}
+
+func TestNewPolicy_Invalid(t *testing.T) {
+	if _, err := NewPolicy([]string{"br7"}, nil, routerInterfaces()); err == nil {
+		t.Error("expected an error for an unknown allow entry")
+	}
+	if _, err := NewPolicy(nil, []string{"10.0.0.0/33"}, routerInterfaces()); err == nil {
+		t.Error("expected an error for an invalid deny entry")
+	}
+}
@@ -0,0 +1,79 @@
+// Package notify broadcasts plain-text messages to all active bot users.
+package notify
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/chatstore"
+)
+
+// Sender is the interface for sending plain-text messages.
+type Sender interface {
+	SendPlain(chatID int64, text string) error
+}
+
+// Authorizer checks if a user is authorized.
+type Authorizer interface {
+	IsAuthorized(username string) bool
+}
+
+// ChatStore is the interface for chat storage.
+type ChatStore interface {
+	GetActiveUsers() ([]chatstore.UserChat, error)
+	SetInactive(username string) error
+}
+
+// Broadcaster sends messages to every active, still-authorized user.
+type Broadcaster struct {
+	store  ChatStore
+	sender Sender
+	auth   Authorizer
+}
+
+// New creates a new Broadcaster.
+func New(store ChatStore, sender Sender, auth Authorizer) *Broadcaster {
+	return &Broadcaster{
+		store:  store,
+		sender: sender,
+		auth:   auth,
+	}
+}
+
+// Broadcast sends text to all active authorized users. Users who blocked the
+// bot are marked inactive. Delivery errors are logged, not returned.
+func (b *Broadcaster) Broadcast(ctx context.Context, text string) {
+	users, err := b.store.GetActiveUsers()
+	if err != nil {
+		slog.Warn("Failed to get active users", "error", err)
+		return
+	}
+
+	for _, user := range users {
+		if ctx.Err() != nil {
+			slog.Info("Broadcast interrupted by shutdown")
+			return
+		}
+		if !b.auth.IsAuthorized(user.Username) {
+			continue
+		}
+
+		if err := b.sender.SendPlain(user.ChatID, text); err != nil {
+			if isBlockedError(err) {
+				slog.Info("User blocked bot, marking inactive", "username", user.Username)
+				_ = b.store.SetInactive(user.Username)
+			} else {
+				slog.Warn("Failed to send notification", "username", user.Username, "error", err)
+			}
+		}
+	}
+}
+
+// isBlockedError checks if error indicates bot was blocked.
+func isBlockedError(err error) bool {
+	errStr := err.Error()
+	return strings.Contains(errStr, "bot was blocked") ||
+		strings.Contains(errStr, "chat not found") ||
+		strings.Contains(errStr, "user is deactivated")
+}
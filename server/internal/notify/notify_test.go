@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/chatstore"
+)
+
+type mockSender struct {
+	sent []int64
+	errs map[int64]error
+}
+
+func (m *mockSender) SendPlain(chatID int64, text string) error {
+	if err := m.errs[chatID]; err != nil {
+		return err
+	}
+	m.sent = append(m.sent, chatID)
+	return nil
+}
+
+type mockAuth struct {
+	allowed map[string]bool
+}
+
+func (m *mockAuth) IsAuthorized(username string) bool { return m.allowed[username] }
+
+type mockStore struct {
+	users    []chatstore.UserChat
+	inactive []string
+}
+
+func (m *mockStore) GetActiveUsers() ([]chatstore.UserChat, error) { return m.users, nil }
+func (m *mockStore) SetInactive(username string) error {
+	m.inactive = append(m.inactive, username)
+	return nil
+}
+
+func TestBroadcast(t *testing.T) {
+	store := &mockStore{users: []chatstore.UserChat{
+		{Username: "alice", ChatID: 1},
+		{Username: "bob", ChatID: 2},
+		{Username: "mallory", ChatID: 3},
+	}}
+	sender := &mockSender{errs: map[int64]error{2: errors.New("Forbidden: bot was blocked by the user")}}
+	auth := &mockAuth{allowed: map[string]bool{"alice": true, "bob": true}}
+
+	New(store, sender, auth).Broadcast(context.Background(), "hello")
+
+	if len(sender.sent) != 1 || sender.sent[0] != 1 {
+		t.Errorf("expected message to alice only, sent to %v", sender.sent)
+	}
+	if len(store.inactive) != 1 || store.inactive[0] != "bob" {
+		t.Errorf("expected bob marked inactive, got %v", store.inactive)
+	}
+}
+
+func TestBroadcast_CancelledContext(t *testing.T) {
+	store := &mockStore{users: []chatstore.UserChat{{Username: "alice", ChatID: 1}}}
+	sender := &mockSender{}
+	auth := &mockAuth{allowed: map[string]bool{"alice": true}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	New(store, sender, auth).Broadcast(ctx, "hello")
+
+	if len(sender.sent) != 0 {
+		t.Errorf("expected no messages after cancellation, got %v", sender.sent)
+	}
+}
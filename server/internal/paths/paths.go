@@ -10,6 +10,9 @@ type Paths struct {
 	XrayConfig     string // /opt/etc/xray/config.json
 	BotLogPath     string // /tmp/telegram-bot.log
 	VPNLogPath     string // /tmp/vpn-director.log
+	XrayLogPath    string // /tmp/xray-access.log
+	ConntrackPath  string // /proc/net/nf_conntrack
+	LoginSocket    string // /tmp/vpn-director-login.sock (Web UI ↔ bot)
 }
 
 // Default returns the default paths for production use
@@ -22,6 +25,9 @@ func Default() Paths {
 		XrayConfig:     "/opt/etc/xray/config.json",
 		BotLogPath:     "/tmp/telegram-bot.log",
 		VPNLogPath:     "/tmp/vpn-director.log",
+		XrayLogPath:    "/tmp/xray-access.log",
+		ConntrackPath:  "/proc/net/nf_conntrack",
+		LoginSocket:    "/tmp/vpn-director-login.sock",
 	}
 }
 
@@ -35,5 +41,8 @@ func DevPaths() Paths {
 		XrayConfig:     "testdata/dev/xray.json",
 		BotLogPath:     "testdata/dev/bot.log",
 		VPNLogPath:     "testdata/dev/vpn.log",
+		XrayLogPath:    "testdata/dev/xray.log",
+		ConntrackPath:  "testdata/dev/nf_conntrack",
+		LoginSocket:    "testdata/dev/login.sock",
 	}
 }
@@ -21,6 +21,9 @@ func TestDefault(t *testing.T) {
 		{"XrayConfig", p.XrayConfig, "/opt/etc/xray/", ".json"},
 		{"BotLogPath", p.BotLogPath, "/tmp/", "telegram-bot.log"},
 		{"VPNLogPath", p.VPNLogPath, "/tmp/", "vpn-director.log"},
+		{"XrayLogPath", p.XrayLogPath, "/tmp/", "xray-access.log"},
+		{"ConntrackPath", p.ConntrackPath, "/proc/net/", "nf_conntrack"},
+		{"LoginSocket", p.LoginSocket, "/tmp/", ".sock"},
 	}
 
 	for _, tt := range tests {
@@ -80,6 +83,9 @@ func TestDevPaths(t *testing.T) {
 		{"XrayConfig", p.XrayConfig, "testdata/dev/", "xray.json"},
 		{"BotLogPath", p.BotLogPath, "testdata/dev/", "bot.log"},
 		{"VPNLogPath", p.VPNLogPath, "testdata/dev/", "vpn.log"},
+		{"XrayLogPath", p.XrayLogPath, "testdata/dev/", "xray.log"},
+		{"ConntrackPath", p.ConntrackPath, "testdata/dev/", "nf_conntrack"},
+		{"LoginSocket", p.LoginSocket, "testdata/dev/", ".sock"},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,410 @@
+// Package resolver periodically re-resolves Xray server hostnames and keeps
+// servers.json and the TPROXY bypass list (xray.servers) up to date. Stale
+// server IPs would otherwise be missing from the bypass ipset, and traffic to
+// the server would loop back into Xray.
+//
+// Addresses are added as lookups return them and dropped only once no lookup
+// has returned them for the retention period, so round-robin and CDN hosts
+// do not cause a change on every run and connections to an address Xray
+// still uses keep bypassing TPROXY.
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/audit"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/service"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+)
+
+const (
+	// DefaultTimeout bounds a single hostname lookup.
+	DefaultTimeout = 5 * time.Second
+	// DefaultConcurrency is the number of lookups run in parallel.
+	DefaultConcurrency = 4
+	// DefaultRetain is how long an address is kept after a lookup last
+	// returned it.
+	DefaultRetain = 24 * time.Hour
+)
+
+// Applier re-applies the Xray TPROXY rules after the bypass list changed.
+type Applier interface {
+	ApplyXray() error
+}
+
+// Notifier delivers a change summary to users.
+type Notifier interface {
+	Broadcast(ctx context.Context, text string)
+}
+
+// Change is the before/after address list of one server.
+type Change struct {
+	Server string
+	Before []string
+	After  []string
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s → %s", c.Server, joinOrNone(c.Before), joinOrNone(c.After))
+}
+
+// Resolver re-resolves server addresses on an interval.
+type Resolver struct {
+	config      service.ConfigStore
+	vpn         Applier
+	notifier    Notifier
+	audit       audit.Recorder
+	timeout     time.Duration
+	concurrency int
+	retain      time.Duration
+	lookup      func(ctx context.Context, host string) ([]net.IP, error)
+	now         func() time.Time
+
+	mu   sync.Mutex
+	seen map[string]map[string]time.Time // host -> address -> last returned
+}
+
+// Option configures a Resolver.
+type Option func(*Resolver)
+
+// WithTimeout sets the per-lookup timeout.
+func WithTimeout(d time.Duration) Option {
+	return func(r *Resolver) { r.timeout = d }
+}
+
+// WithConcurrency sets the number of parallel lookups.
+func WithConcurrency(n int) Option {
+	return func(r *Resolver) { r.concurrency = n }
+}
+
+// WithRetain sets how long an address is kept after a lookup last
+// returned it.
+func WithRetain(d time.Duration) Option {
+	return func(r *Resolver) { r.retain = d }
+}
+
+// WithAudit records every address change in rec.
+func WithAudit(rec audit.Recorder) Option {
+	return func(r *Resolver) { r.audit = rec }
+}
+
+// New creates a new Resolver. notifier may be nil.
+func New(config service.ConfigStore, vpn Applier, notifier Notifier, opts ...Option) *Resolver {
+	r := &Resolver{
+		config:      config,
+		vpn:         vpn,
+		notifier:    notifier,
+		timeout:     DefaultTimeout,
+		concurrency: DefaultConcurrency,
+		retain:      DefaultRetain,
+		lookup: func(ctx context.Context, host string) ([]net.IP, error) {
+			return net.DefaultResolver.LookupIP(ctx, "ip", host)
+		},
+		now:  time.Now,
+		seen: make(map[string]map[string]time.Time),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.concurrency < 1 {
+		r.concurrency = 1
+	}
+	return r
+}
+
+// Run starts the resolve loop. Blocks until ctx is cancelled.
+func (r *Resolver) Run(ctx context.Context, interval time.Duration) {
+	slog.Info("Server resolver started", "interval", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		r.runOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			slog.Info("Server resolver stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Resolver) runOnce(ctx context.Context) {
+	changes, err := r.ResolveOnce(ctx)
+	if err != nil {
+		slog.Warn("Server re-resolve failed", "error", err)
+	}
+	if r.audit != nil {
+		for _, c := range changes {
+			e := audit.Entry{Source: audit.SourceScheduler, Actor: "resolver", Action: "servers.resolve", Target: c.Server, Before: c.Before, After: c.After}
+			if err != nil {
+				e.Error = err.Error()
+			}
+			r.audit.Record(e)
+		}
+	}
+	if len(changes) == 0 || r.notifier == nil {
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString("🔄 Server IPs changed:\n")
+	for _, c := range changes {
+		sb.WriteString(c.String())
+		sb.WriteString("\n")
+	}
+	if err != nil {
+		sb.WriteString(fmt.Sprintf("\n⚠️ Re-apply failed: %v", err))
+	}
+	r.notifier.Broadcast(ctx, sb.String())
+}
+
+// ResolveOnce re-resolves all servers. If any address changed it saves
+// servers.json, rewrites xray.servers and re-applies Xray rules. Servers whose
+// lookup fails or returns nothing keep their previous addresses.
+// Changes are returned even if saving or applying failed.
+func (r *Resolver) ResolveOnce(ctx context.Context) ([]Change, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	servers, err := r.config.LoadServers()
+	if err != nil {
+		return nil, fmt.Errorf("load servers: %w", err)
+	}
+
+	// Lookups run without the config lock; update reloads the servers
+	results := r.resolveHosts(ctx, servers)
+
+	changes, err := r.update(results)
+	if err != nil || len(changes) == 0 {
+		return changes, err
+	}
+	if err := r.vpn.ApplyXray(); err != nil {
+		return changes, fmt.Errorf("apply xray: %w", err)
+	}
+	return changes, nil
+}
+
+// update merges the lookup results into servers.json and xray.servers under
+// the config lock, so edits made meanwhile by the Web UI or the bot are kept.
+func (r *Resolver) update(results map[string]lookupResult) ([]Change, error) {
+	unlock, err := r.config.LockConfig()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	servers, err := r.config.LoadServers()
+	if err != nil {
+		return nil, fmt.Errorf("load servers: %w", err)
+	}
+
+	var changes []Change
+	for i := range servers {
+		host := hostOf(servers[i])
+		res, ok := results[host]
+		if !ok || res.err != nil || len(res.ips)+len(res.ips6) == 0 {
+			continue
+		}
+		before := sortedCopy(servers[i].AllIPs())
+		servers[i].IPs, servers[i].IPs6 = splitFamilies(r.retained(host, before, slices.Concat(res.ips, res.ips6)))
+		after := sortedCopy(servers[i].AllIPs())
+		if equalStrings(before, after) {
+			continue
+		}
+		changes = append(changes, Change{Server: displayName(servers[i]), Before: before, After: after})
+	}
+	r.forget(servers)
+
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	for _, c := range changes {
+		slog.Info("Server IPs changed", "server", c.Server, "before", c.Before, "after", c.After)
+	}
+
+	if err := r.config.SaveServers(servers); err != nil {
+		return changes, fmt.Errorf("save servers: %w", err)
+	}
+
+	cfg, err := r.config.LoadVPNConfig()
+	if err != nil {
+		return changes, fmt.Errorf("load config: %w", err)
+	}
+	cfg.Xray.Servers = vpnconfig.ServerIPs(servers)
+	if err := r.config.SaveVPNConfig(cfg); err != nil {
+		return changes, fmt.Errorf("save config: %w", err)
+	}
+	return changes, nil
+}
+
+// retained returns the sorted addresses to keep for host: those a lookup
+// returned within r.retain. fresh are the addresses of this lookup; current
+// ones not tracked yet (e.g. after a restart) count as returned now.
+func (r *Resolver) retained(host string, current, fresh []string) []string {
+	now := r.now()
+	seen := r.seen[host]
+	if seen == nil {
+		seen = make(map[string]time.Time)
+		r.seen[host] = seen
+	}
+	for _, ip := range current {
+		if _, ok := seen[ip]; !ok {
+			seen[ip] = now
+		}
+	}
+	for _, ip := range fresh {
+		seen[ip] = now
+	}
+
+	var keep []string
+	for ip, last := range seen {
+		if now.Sub(last) > r.retain {
+			delete(seen, ip)
+			continue
+		}
+		keep = append(keep, ip)
+	}
+	sort.Strings(keep)
+	return keep
+}
+
+// forget drops the history of hosts no server uses any more.
+func (r *Resolver) forget(servers []vpnconfig.Server) {
+	used := make(map[string]bool, len(servers))
+	for _, s := range servers {
+		used[hostOf(s)] = true
+	}
+	for host := range r.seen {
+		if !used[host] {
+			delete(r.seen, host)
+		}
+	}
+}
+
+// splitFamilies splits addresses into IPv4 and IPv6.
+func splitFamilies(ips []string) (v4, v6 []string) {
+	for _, ip := range ips {
+		if strings.Contains(ip, ":") {
+			v6 = append(v6, ip)
+		} else {
+			v4 = append(v4, ip)
+		}
+	}
+	return v4, v6
+}
+
+type lookupResult struct {
+	ips  []string
+	ips6 []string
+	err  error
+}
+
+// resolveHosts looks up each distinct hostname once, at most r.concurrency
+// at a time. IP literals are skipped.
+func (r *Resolver) resolveHosts(ctx context.Context, servers []vpnconfig.Server) map[string]lookupResult {
+	hosts := make(map[string]bool)
+	for _, s := range servers {
+		host := hostOf(s)
+		if host != "" && net.ParseIP(host) == nil {
+			hosts[host] = true
+		}
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		sem     = make(chan struct{}, r.concurrency)
+		results = make(map[string]lookupResult, len(hosts))
+	)
+	for host := range hosts {
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+
+			res := r.resolve(ctx, host)
+			if res.err != nil {
+				slog.Warn("Failed to re-resolve server", "host", host, "error", res.err)
+			}
+			mu.Lock()
+			results[host] = res
+			mu.Unlock()
+		}(host)
+	}
+	wg.Wait()
+	return results
+}
+
+func (r *Resolver) resolve(ctx context.Context, host string) lookupResult {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	ips, err := r.lookup(ctx, host)
+	if err != nil {
+		return lookupResult{err: err}
+	}
+
+	var res lookupResult
+	for _, ip := range ips {
+		if ip4 := ip.To4(); ip4 != nil {
+			res.ips = append(res.ips, ip4.String())
+		} else {
+			res.ips6 = append(res.ips6, ip.String())
+		}
+	}
+	sort.Strings(res.ips)
+	sort.Strings(res.ips6)
+	return res
+}
+
+func hostOf(s vpnconfig.Server) string {
+	return strings.Trim(s.Address, "[]")
+}
+
+func displayName(s vpnconfig.Server) string {
+	if s.Name == "" || s.Name == s.Address {
+		return s.Address
+	}
+	return fmt.Sprintf("%s (%s)", s.Name, s.Address)
+}
+
+func sortedCopy(s []string) []string {
+	out := append([]string{}, s...)
+	sort.Strings(out)
+	return out
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func joinOrNone(ips []string) string {
+	if len(ips) == 0 {
+		return "none"
+	}
+	return strings.Join(ips, ", ")
+}
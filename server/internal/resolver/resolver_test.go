@@ -0,0 +1,282 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/audit"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+)
+
+type mockConfig struct {
+	servers      []vpnconfig.Server
+	cfg          *vpnconfig.VPNDirectorConfig
+	savedServers []vpnconfig.Server
+	savedCfg     *vpnconfig.VPNDirectorConfig
+}
+
+func (m *mockConfig) LoadVPNConfig() (*vpnconfig.VPNDirectorConfig, error) { return m.cfg, nil }
+func (m *mockConfig) LoadServers() ([]vpnconfig.Server, error)             { return m.servers, nil }
+func (m *mockConfig) SaveVPNConfig(cfg *vpnconfig.VPNDirectorConfig) error {
+	m.savedCfg = cfg
+	return nil
+}
+func (m *mockConfig) SaveServers(s []vpnconfig.Server) error {
+	m.savedServers = s
+	return nil
+}
+func (m *mockConfig) DataDir() (string, error)    { return "/data", nil }
+func (m *mockConfig) DataDirOrDefault() string    { return "/data" }
+func (m *mockConfig) ScriptsDir() string          { return "/scripts" }
+func (m *mockConfig) LockConfig() (func(), error) { return func() {}, nil }
+
+type mockApplier struct {
+	calls int
+	err   error
+}
+
+func (m *mockApplier) ApplyXray() error {
+	m.calls++
+	return m.err
+}
+
+type mockNotifier struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (m *mockNotifier) Broadcast(ctx context.Context, text string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, text)
+}
+
+func staticLookup(table map[string][]string) func(context.Context, string) ([]net.IP, error) {
+	return func(ctx context.Context, host string) ([]net.IP, error) {
+		addrs, ok := table[host]
+		if !ok {
+			return nil, errors.New("no such host")
+		}
+		ips := make([]net.IP, 0, len(addrs))
+		for _, a := range addrs {
+			ips = append(ips, net.ParseIP(a))
+		}
+		return ips, nil
+	}
+}
+
+func TestResolveOnce_UpdatesChangedServers(t *testing.T) {
+	config := &mockConfig{
+		servers: []vpnconfig.Server{
+			{Name: "NL", Address: "nl.example.com", IPs: []string{"203.0.113.1"}},
+			{Name: "DE", Address: "de.example.com", IPs: []string{"203.0.113.2", "203.0.113.3"}},
+			{Name: "Static", Address: "198.51.100.9", IPs: []string{"198.51.100.9"}},
+			{Name: "Gone", Address: "gone.example.com", IPs: []string{"203.0.113.9"}},
+		},
+		cfg: &vpnconfig.VPNDirectorConfig{},
+	}
+	vpn := &mockApplier{}
+	r := New(config, vpn, nil)
+	r.lookup = staticLookup(map[string][]string{
+		"nl.example.com": {"203.0.113.10", "2001:db8::10"},
+		// Same set in a different order is not a change
+		"de.example.com": {"203.0.113.3", "203.0.113.2"},
+	})
+
+	changes, err := r.ResolveOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change, got %v", changes)
+	}
+	want := Change{Server: "NL (nl.example.com)", Before: []string{"203.0.113.1"}, After: []string{"2001:db8::10", "203.0.113.1", "203.0.113.10"}}
+	if !reflect.DeepEqual(changes[0], want) {
+		t.Errorf("got %+v, want %+v", changes[0], want)
+	}
+
+	// Failed lookup keeps old IPs
+	if got := config.savedServers[3].IPs; !reflect.DeepEqual(got, []string{"203.0.113.9"}) {
+		t.Errorf("expected failed server to keep its IPs, got %v", got)
+	}
+	if got := config.savedServers[0].IPs6; !reflect.DeepEqual(got, []string{"2001:db8::10"}) {
+		t.Errorf("expected IPv6 stored in ips6, got %v", got)
+	}
+
+	wantServers := []string{"198.51.100.9", "2001:db8::10", "203.0.113.1", "203.0.113.10", "203.0.113.2", "203.0.113.3", "203.0.113.9"}
+	if !reflect.DeepEqual(config.savedCfg.Xray.Servers, wantServers) {
+		t.Errorf("xray.servers = %v, want %v", config.savedCfg.Xray.Servers, wantServers)
+	}
+	if vpn.calls != 1 {
+		t.Errorf("expected 1 ApplyXray call, got %d", vpn.calls)
+	}
+}
+
+func TestResolveOnce_NoChanges(t *testing.T) {
+	config := &mockConfig{
+		servers: []vpnconfig.Server{{Address: "nl.example.com", IPs: []string{"203.0.113.1"}}},
+	}
+	vpn := &mockApplier{}
+	r := New(config, vpn, nil)
+	r.lookup = staticLookup(map[string][]string{"nl.example.com": {"203.0.113.1"}})
+
+	changes, err := r.ResolveOnce(context.Background())
+	if err != nil || changes != nil {
+		t.Fatalf("expected no changes, got %v, %v", changes, err)
+	}
+	if config.savedServers != nil || vpn.calls != 0 {
+		t.Error("expected nothing saved or applied")
+	}
+}
+
+func TestResolveOnce_RetainsAddresses(t *testing.T) {
+	config := &mockConfig{
+		servers: []vpnconfig.Server{{Address: "nl.example.com", IPs: []string{"203.0.113.1"}}},
+		cfg:     &vpnconfig.VPNDirectorConfig{},
+	}
+	r := New(config, &mockApplier{}, nil)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+	r.lookup = staticLookup(map[string][]string{"nl.example.com": {"203.0.113.2"}})
+
+	if _, err := r.ResolveOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := config.savedServers[0].IPs; !reflect.DeepEqual(got, []string{"203.0.113.1", "203.0.113.2"}) {
+		t.Fatalf("IPs = %v, want old and new address", got)
+	}
+
+	// Still within the retention period: nothing changes
+	now = now.Add(DefaultRetain)
+	if changes, err := r.ResolveOnce(context.Background()); err != nil || changes != nil {
+		t.Fatalf("expected no changes, got %v, %v", changes, err)
+	}
+
+	now = now.Add(time.Minute)
+	changes, err := r.ResolveOnce(context.Background())
+	if err != nil || len(changes) != 1 {
+		t.Fatalf("expected one change, got %v, %v", changes, err)
+	}
+	if got := config.savedServers[0].IPs; !reflect.DeepEqual(got, []string{"203.0.113.2"}) {
+		t.Errorf("IPs = %v, want expired address dropped", got)
+	}
+}
+
+func TestResolveOnce_ApplyError(t *testing.T) {
+	config := &mockConfig{
+		servers: []vpnconfig.Server{{Address: "nl.example.com", IPs: []string{"203.0.113.1"}}},
+		cfg:     &vpnconfig.VPNDirectorConfig{},
+	}
+	r := New(config, &mockApplier{err: errors.New("exit 1")}, nil)
+	r.lookup = staticLookup(map[string][]string{"nl.example.com": {"203.0.113.2"}})
+
+	changes, err := r.ResolveOnce(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "apply xray") {
+		t.Errorf("expected apply error, got %v", err)
+	}
+	if len(changes) != 1 {
+		t.Errorf("expected changes to be reported despite error, got %v", changes)
+	}
+}
+
+func TestResolveOnce_ConcurrencyAndDedup(t *testing.T) {
+	var servers []vpnconfig.Server
+	for _, h := range []string{"a", "b", "c", "d", "e", "a"} {
+		servers = append(servers, vpnconfig.Server{Address: h + ".example.com"})
+	}
+	config := &mockConfig{servers: servers, cfg: &vpnconfig.VPNDirectorConfig{}}
+	r := New(config, &mockApplier{}, nil, WithConcurrency(2), WithTimeout(time.Second))
+
+	var active, peak, total int32
+	r.lookup = func(ctx context.Context, host string) ([]net.IP, error) {
+		n := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+		atomic.AddInt32(&total, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return []net.IP{net.ParseIP("203.0.113.1")}, nil
+	}
+
+	if _, err := r.ResolveOnce(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if total != 5 {
+		t.Errorf("expected 5 distinct lookups, got %d", total)
+	}
+	if peak > 2 {
+		t.Errorf("expected at most 2 concurrent lookups, got %d", peak)
+	}
+}
+
+func TestResolveOnce_Timeout(t *testing.T) {
+	config := &mockConfig{
+		servers: []vpnconfig.Server{{Address: "slow.example.com", IPs: []string{"203.0.113.1"}}},
+	}
+	r := New(config, &mockApplier{}, nil, WithTimeout(10*time.Millisecond))
+	r.lookup = func(ctx context.Context, host string) ([]net.IP, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	changes, err := r.ResolveOnce(context.Background())
+	if err != nil || changes != nil {
+		t.Errorf("expected timed-out lookup to be ignored, got %v, %v", changes, err)
+	}
+}
+
+func TestRunOnce_NotifiesChanges(t *testing.T) {
+	config := &mockConfig{
+		servers: []vpnconfig.Server{{Address: "nl.example.com", IPs: []string{"203.0.113.1"}}},
+		cfg:     &vpnconfig.VPNDirectorConfig{},
+	}
+	notifier := &mockNotifier{}
+	r := New(config, &mockApplier{}, notifier)
+	r.lookup = staticLookup(map[string][]string{"nl.example.com": {"203.0.113.2"}})
+
+	r.runOnce(context.Background())
+
+	if len(notifier.messages) != 1 {
+		t.Fatalf("expected 1 notification, got %d", len(notifier.messages))
+	}
+	if !strings.Contains(notifier.messages[0], "nl.example.com: 203.0.113.1 → 203.0.113.1, 203.0.113.2") {
+		t.Errorf("unexpected notification: %q", notifier.messages[0])
+	}
+}
+
+func TestRunOnce_RecordsAudit(t *testing.T) {
+	config := &mockConfig{
+		servers: []vpnconfig.Server{{Address: "nl.example.com", IPs: []string{"203.0.113.1"}}},
+		cfg:     &vpnconfig.VPNDirectorConfig{},
+	}
+	store := audit.NewStore(filepath.Join(t.TempDir(), audit.StoreFile))
+	r := New(config, &mockApplier{}, nil, WithAudit(store))
+	r.lookup = staticLookup(map[string][]string{"nl.example.com": {"203.0.113.2"}})
+
+	r.runOnce(context.Background())
+
+	list, err := store.List(audit.Filter{})
+	if err != nil || len(list) != 1 {
+		t.Fatalf("expected one entry, got %v, %v", list, err)
+	}
+	e := list[0]
+	if e.Source != audit.SourceScheduler || e.Actor != "resolver" || e.Action != "servers.resolve" || e.Error != "" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+	if !strings.Contains(e.Target, "nl.example.com") || !reflect.DeepEqual(e.After, []any{"203.0.113.1", "203.0.113.2"}) {
+		t.Errorf("unexpected change %+v", e)
+	}
+}
@@ -2,8 +2,10 @@
 package service
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"syscall"
 
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
 )
@@ -85,3 +87,19 @@ func (s *ConfigService) SaveServers(servers []vpnconfig.Server) error {
 	}
 	return vpnconfig.SaveServers(filepath.Join(dataDir, "servers.json"), servers)
 }
+
+// LockConfig takes an exclusive lock on vpn-director.json.lock. Every writer
+// of vpn-director.json or servers.json (bot, Web UI, resolver) holds it
+// across load-modify-save, so concurrent updates from another process are
+// not lost. The lock is not reentrant.
+func (s *ConfigService) LockConfig() (func(), error) {
+	f, err := os.OpenFile(s.ConfigPath()+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("lock config: %w", err)
+	}
+	return func() { f.Close() }, nil
+}
@@ -5,6 +5,9 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
 )
 
 func TestConfigService_DataDir(t *testing.T) {
@@ -74,3 +77,61 @@ func TestConfigService_SaveServers_CreatesDir(t *testing.T) {
 		t.Error("SaveServers should create data directory")
 	}
 }
+
+func TestConfigService_SaveVPNConfig_KeepsLists(t *testing.T) {
+	tmpDir := t.TempDir()
+	svc := NewConfigService(tmpDir, filepath.Join(tmpDir, "data"))
+
+	cfg := &vpnconfig.VPNDirectorConfig{
+		Xray: vpnconfig.XrayConfig{
+			Clients:    []string{"192.168.1.0/24", "192.168.1.10"},
+			ExcludeIPs: []string{"10.0.0.1", "10.0.0.1"},
+		},
+	}
+	if err := svc.SaveVPNConfig(cfg); err != nil {
+		t.Fatalf("SaveVPNConfig() error: %v", err)
+	}
+
+	loaded, err := svc.LoadVPNConfig()
+	if err != nil {
+		t.Fatalf("LoadVPNConfig() error: %v", err)
+	}
+	// Lists are only rewritten by the explicit optimize action
+	if len(loaded.Xray.Clients) != 2 {
+		t.Errorf("expected clients saved as given, got %v", loaded.Xray.Clients)
+	}
+	if len(loaded.Xray.ExcludeIPs) != 2 {
+		t.Errorf("expected exclude_ips saved as given, got %v", loaded.Xray.ExcludeIPs)
+	}
+}
+
+func TestConfigService_LockConfig(t *testing.T) {
+	svc := NewConfigService(t.TempDir(), t.TempDir())
+
+	unlock, err := svc.LockConfig()
+	if err != nil {
+		t.Fatalf("LockConfig() error: %v", err)
+	}
+
+	// A second holder (another process opens its own file) waits for the first
+	locked := make(chan struct{})
+	go func() {
+		unlock2, err := svc.LockConfig()
+		if err == nil {
+			unlock2()
+		}
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		t.Fatal("expected the second lock to wait")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unlock()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("expected the second lock after release")
+	}
+}
@@ -0,0 +1,105 @@
+// internal/service/domains.go
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/dnsmasq"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+)
+
+// DomainService renders domain routing lists into a dnsmasq fragment and
+// inspects the resulting ipsets
+type DomainService struct {
+	fragmentPath string
+	executor     ShellExecutor
+}
+
+// Compile-time check that DomainService implements DomainRouter
+var _ DomainRouter = (*DomainService)(nil)
+
+// NewDomainService creates a new DomainService writing the fragment into scriptsDir
+func NewDomainService(scriptsDir string, executor ShellExecutor) *DomainService {
+	if executor == nil {
+		executor = DefaultExecutor()
+	}
+	return &DomainService{
+		fragmentPath: filepath.Join(scriptsDir, dnsmasq.FragmentName),
+		executor:     executor,
+	}
+}
+
+// Sync renders the fragment for cfg and restarts dnsmasq if the content changed
+// or a previous restart failed. Returns true if dnsmasq was restarted.
+func (s *DomainService) Sync(cfg *vpnconfig.VPNDirectorConfig) (bool, error) {
+	content := dnsmasq.Render(cfg)
+	pending := s.fragmentPath + ".pending"
+
+	current, err := os.ReadFile(s.fragmentPath)
+	if err == nil && bytes.Equal(current, content) {
+		if _, err := os.Stat(pending); os.IsNotExist(err) {
+			return false, nil
+		}
+	}
+
+	// The marker outlives a failed restart (or this process), so the next
+	// Sync restarts dnsmasq even though the fragment is already up to date
+	if err := os.WriteFile(pending, nil, 0644); err != nil {
+		return false, fmt.Errorf("write fragment: %w", err)
+	}
+	tmpPath := s.fragmentPath + ".tmp"
+	if err := os.WriteFile(tmpPath, content, 0644); err != nil {
+		return false, fmt.Errorf("write fragment: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.fragmentPath); err != nil {
+		return false, fmt.Errorf("write fragment: %w", err)
+	}
+
+	result, err := s.executor.Exec("service", "restart_dnsmasq")
+	if err != nil {
+		return false, err
+	}
+	if result.ExitCode != 0 {
+		return false, fmt.Errorf("restart dnsmasq failed (exit %d): %s", result.ExitCode, result.Output)
+	}
+	if err := os.Remove(pending); err != nil {
+		return true, fmt.Errorf("remove restart marker: %w", err)
+	}
+	return true, nil
+}
+
+// Resolved returns the addresses dnsmasq has added to the route's ipset so far
+func (s *DomainService) Resolved(route string) ([]string, error) {
+	set := dnsmasq.SetName(route)
+	result, err := s.executor.Exec("ipset", "list", set)
+	if err != nil {
+		return nil, err
+	}
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("ipset %s not found (run apply first)", set)
+	}
+	return parseIPSetMembers(result.Output), nil
+}
+
+// parseIPSetMembers extracts entries listed after the "Members:" line of
+// `ipset list` output. Per-entry options (e.g. "timeout 3600") are dropped.
+func parseIPSetMembers(output string) []string {
+	members := []string{}
+	inMembers := false
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if !inMembers {
+			inMembers = line == "Members:"
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		members = append(members, strings.Fields(line)[0])
+	}
+	return members
+}
@@ -0,0 +1,107 @@
+// internal/service/domains_test.go
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/shell"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+)
+
+func TestDomainService_Sync_WritesAndRestarts(t *testing.T) {
+	dir := t.TempDir()
+	mock := &mockExecutor{result: &shell.Result{ExitCode: 0}}
+	svc := NewDomainService(dir, mock)
+
+	cfg := &vpnconfig.VPNDirectorConfig{
+		Xray: vpnconfig.XrayConfig{ExcludeDomains: []string{"bank.example"}},
+	}
+
+	restarted, err := svc.Sync(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !restarted {
+		t.Error("expected dnsmasq restart on first sync")
+	}
+	if len(mock.calls) != 1 || !reflect.DeepEqual(mock.calls[0], []string{"service", "restart_dnsmasq"}) {
+		t.Errorf("unexpected calls: %v", mock.calls)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "dnsmasq-domains.conf"))
+	if err != nil {
+		t.Fatalf("read fragment: %v", err)
+	}
+	if !strings.Contains(string(data), "ipset=/bank.example/VPD_DOM_BYPASS,VPD_DOM_BYPASS6\n") {
+		t.Errorf("unexpected fragment: %s", data)
+	}
+
+	// Second sync with identical config must not restart dnsmasq
+	restarted, err = svc.Sync(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if restarted || len(mock.calls) != 1 {
+		t.Errorf("expected no restart for unchanged fragment, calls: %v", mock.calls)
+	}
+}
+
+func TestDomainService_Sync_RestartFailure(t *testing.T) {
+	mock := &mockExecutor{result: &shell.Result{Output: "no such service", ExitCode: 1}}
+	svc := NewDomainService(t.TempDir(), mock)
+
+	_, err := svc.Sync(&vpnconfig.VPNDirectorConfig{})
+	if err == nil || !strings.Contains(err.Error(), "exit 1") {
+		t.Errorf("expected exit code error, got %v", err)
+	}
+
+	// The fragment is already written, but the failed restart is retried
+	mock.result = &shell.Result{ExitCode: 0}
+	restarted, err := svc.Sync(&vpnconfig.VPNDirectorConfig{})
+	if err != nil || !restarted || len(mock.calls) != 2 {
+		t.Errorf("expected retried restart, got %v, %v, calls: %v", restarted, err, mock.calls)
+	}
+	if restarted, _ := svc.Sync(&vpnconfig.VPNDirectorConfig{}); restarted {
+		t.Error("expected no restart once dnsmasq restarted")
+	}
+}
+
+func TestDomainService_Resolved(t *testing.T) {
+	output := `Name: VPD_DOM_WGC1
+Type: hash:ip
+Revision: 4
+Header: family inet hashsize 1024 maxelem 65536
+Size in memory: 248
+References: 1
+Number of entries: 2
+Members:
+104.16.1.1
+23.246.0.10 timeout 3600
+`
+	mock := &mockExecutor{result: &shell.Result{Output: output, ExitCode: 0}}
+	svc := NewDomainService(t.TempDir(), mock)
+
+	entries, err := svc.Resolved("wgc1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(entries, []string{"104.16.1.1", "23.246.0.10"}) {
+		t.Errorf("unexpected entries: %v", entries)
+	}
+	if !reflect.DeepEqual(mock.calls[0], []string{"ipset", "list", "VPD_DOM_WGC1"}) {
+		t.Errorf("unexpected call: %v", mock.calls[0])
+	}
+}
+
+func TestDomainService_Resolved_MissingSet(t *testing.T) {
+	mock := &mockExecutor{result: &shell.Result{Output: "The set with the given name does not exist", ExitCode: 1}}
+	svc := NewDomainService(t.TempDir(), mock)
+
+	if _, err := svc.Resolved("direct"); err == nil || !strings.Contains(err.Error(), "VPD_DOM_BYPASS") {
+		t.Errorf("expected missing set error, got %v", err)
+	}
+}
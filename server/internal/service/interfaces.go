@@ -2,11 +2,14 @@
 package service
 
 import (
+	"context"
+
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/shell"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/xraystats"
 )
 
-// ConfigStore, VPNDirector, XrayGenerator, NetworkInfo, LogReader interfaces are defined here
+// ConfigStore, VPNDirector, XrayGenerator, XrayConfigReader, NetworkInfo, LogReader, DomainRouter, XrayStats, SystemInfo interfaces are defined here
 // in service/ rather than handler/ so both handler/ and wizard/ can
 // import them without coupling handler <-> wizard.
 //
@@ -27,12 +30,16 @@ type ConfigStore interface {
 	DataDir() (string, error)
 	DataDirOrDefault() string
 	ScriptsDir() string
+	// LockConfig serializes load-modify-save of both files across
+	// processes; call the returned function to release it.
+	LockConfig() (func(), error)
 }
 
 // VPNDirector is the interface for VPN Director operations
 type VPNDirector interface {
 	Status() (string, error)
 	Apply() error
+	ApplyXray() error
 	Restart() error
 	RestartXray() error
 	Stop() error
@@ -43,9 +50,14 @@ type XrayGenerator interface {
 	GenerateConfig(server vpnconfig.Server) error
 }
 
+// XrayConfigReader is the interface for reading the generated Xray config
+type XrayConfigReader interface {
+	ActiveServer() (*vpnconfig.Server, error)
+}
+
 // NetworkInfo is the interface for network operations
 type NetworkInfo interface {
-	GetExternalIP() (string, error)
+	GetExternalIP(via string) (*IPInfo, error)
 }
 
 // LogReader is the interface for log reading
@@ -53,6 +65,23 @@ type LogReader interface {
 	Read(path string, lines int) (string, error)
 }
 
+// DomainRouter is the interface for dnsmasq-based domain routing
+type DomainRouter interface {
+	Sync(cfg *vpnconfig.VPNDirectorConfig) (bool, error)
+	Resolved(route string) ([]string, error)
+}
+
+// XrayStats is the interface for Xray traffic counters (StatsService)
+type XrayStats interface {
+	Summary(ctx context.Context) (*xraystats.Summary, error)
+}
+
+// SystemInfo is the interface for process and ipset state
+type SystemInfo interface {
+	ProcessRunning(name string) (bool, error)
+	IPSetCounts() (map[string]int, error)
+}
+
 // defaultExecutor wraps shell.Exec
 type defaultExecutor struct{}
 
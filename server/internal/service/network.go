@@ -2,40 +2,311 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net"
+	"net/http"
+	"net/netip"
+	"regexp"
 	"strings"
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+	"golang.org/x/net/proxy"
 )
 
+// ViaXray looks up the external IP through Xray's local SOCKS inbound.
+const ViaXray = "xray"
+
+const (
+	defaultIPLookupTimeout = 5 * time.Second
+	maxIPResponseSize      = 64 << 10
+)
+
+// ipProviders are the built-in external IP providers by name. A provider in
+// advanced.ip_lookup.providers may also be an http(s) URL that returns JSON
+// in one of these formats or a bare IP.
+var ipProviders = map[string]string{
+	"ipinfo":      "https://ipinfo.io/json",
+	"ifconfig.co": "https://ifconfig.co/json",
+	"ip-api":      "http://ip-api.com/json/",
+	"ipify":       "https://api.ipify.org",
+	"ifconfig.me": "https://ifconfig.me/ip",
+}
+
+// DefaultIPProviders are tried in order when no providers are configured.
+var DefaultIPProviders = []string{"ipinfo", "ifconfig.co", "ip-api", "ifconfig.me"}
+
+var interfaceNameRe = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,15}$`)
+
+// IPInfo is an external IP address with the geo data its provider returned.
+type IPInfo struct {
+	IP       string `json:"ip"`
+	Country  string `json:"country,omitempty"` // ISO code where available
+	City     string `json:"city,omitempty"`
+	ASN      string `json:"asn,omitempty"` // "AS13335"
+	ISP      string `json:"isp,omitempty"`
+	Provider string `json:"provider"`
+}
+
 // NetworkService handles network-related operations
 type NetworkService struct {
-	executor ShellExecutor
+	config ConfigStore
 }
 
 // Compile-time check that NetworkService implements NetworkInfo
 var _ NetworkInfo = (*NetworkService)(nil)
 
-// NewNetworkService creates a new NetworkService
-func NewNetworkService(executor ShellExecutor) *NetworkService {
-	if executor == nil {
-		executor = DefaultExecutor()
+// NewNetworkService creates a new NetworkService. config may be nil, in
+// which case the default providers are used.
+func NewNetworkService(config ConfigStore) *NetworkService {
+	return &NetworkService{config: config}
+}
+
+// ipLookupOptions is the advanced.ip_lookup section.
+type ipLookupOptions struct {
+	providers []string
+	race      bool
+	timeout   time.Duration
+	socksAddr string
+}
+
+// ValidVia reports whether via is a lookup path GetExternalIP accepts: empty
+// for the WAN, "xray", or a tunnel or interface name.
+func ValidVia(via string) bool {
+	return via == "" || via == ViaXray || interfaceNameRe.MatchString(via)
+}
+
+// GetExternalIP returns the external IP address seen through via (see
+// ValidVia). Providers are tried in order, or all at once when
+// advanced.ip_lookup.race is set; the first valid answer wins.
+func (s *NetworkService) GetExternalIP(via string) (*IPInfo, error) {
+	if !ValidVia(via) {
+		return nil, fmt.Errorf("invalid lookup path %q", via)
+	}
+
+	opts := s.lookupOptions()
+	client, err := lookupClient(via, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer client.CloseIdleConnections()
+
+	if opts.race {
+		return raceProviders(client, opts.providers)
+	}
+
+	var errs []error
+	for _, p := range opts.providers {
+		info, err := fetchIP(context.Background(), client, p)
+		if err == nil {
+			return info, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, fmt.Errorf("external IP lookup failed: %w", errors.Join(errs...))
+}
+
+// raceProviders queries all providers at once and returns the first answer.
+func raceProviders(client *http.Client, providers []string) (*IPInfo, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type result struct {
+		info *IPInfo
+		err  error
+	}
+	results := make(chan result, len(providers))
+	for _, p := range providers {
+		go func() {
+			info, err := fetchIP(ctx, client, p)
+			results <- result{info, err}
+		}()
+	}
+
+	var errs []error
+	for range providers {
+		r := <-results
+		if r.err == nil {
+			return r.info, nil
+		}
+		errs = append(errs, r.err)
+	}
+	return nil, fmt.Errorf("external IP lookup failed: %w", errors.Join(errs...))
+}
+
+func (s *NetworkService) lookupOptions() ipLookupOptions {
+	opts := ipLookupOptions{
+		providers: DefaultIPProviders,
+		timeout:   defaultIPLookupTimeout,
+		socksAddr: vpnconfig.XraySocksAddr(&vpnconfig.VPNDirectorConfig{}),
+	}
+	if s.config == nil {
+		return opts
+	}
+	cfg, err := s.config.LoadVPNConfig()
+	if err != nil {
+		return opts
+	}
+	opts.socksAddr = vpnconfig.XraySocksAddr(cfg)
+
+	sec, ok := cfg.Advanced["ip_lookup"].(map[string]interface{})
+	if !ok {
+		return opts
+	}
+	if list, ok := sec["providers"].([]interface{}); ok {
+		var providers []string
+		for _, v := range list {
+			if p, ok := v.(string); ok && p != "" {
+				providers = append(providers, p)
+			}
+		}
+		if len(providers) > 0 {
+			opts.providers = providers
+		}
+	}
+	if race, ok := sec["race"].(bool); ok {
+		opts.race = race
 	}
-	return &NetworkService{executor: executor}
+	if secs, ok := sec["timeout"].(float64); ok && secs > 0 {
+		opts.timeout = time.Duration(secs * float64(time.Second))
+	}
+	return opts
 }
 
-// GetExternalIP returns the external IP address
-func (s *NetworkService) GetExternalIP() (string, error) {
-	result, err := s.executor.Exec("curl", "-s", "--connect-timeout", "5", "--max-time", "10", "ifconfig.me")
+// lookupClient builds an HTTP client whose connections take the given path.
+func lookupClient(via string, opts ipLookupOptions) (*http.Client, error) {
+	transport, err := RouteTransport(via, opts.socksAddr, opts.timeout)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	return &http.Client{Transport: transport, Timeout: opts.timeout}, nil
+}
+
+// RouteTransport returns an HTTP transport whose connections take the given
+// path (see ValidVia): the default route, Xray's SOCKS inbound at socksAddr,
+// or a tunnel interface. Environment proxies are ignored so requests always
+// take that path.
+func RouteTransport(via, socksAddr string, dialTimeout time.Duration) (*http.Transport, error) {
+	if !ValidVia(via) {
+		return nil, fmt.Errorf("invalid route %q", via)
 	}
-	if result.ExitCode != 0 {
-		return "", fmt.Errorf("curl failed with exit code %d", result.ExitCode)
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	dialer := &net.Dialer{Timeout: dialTimeout}
+
+	switch via {
+	case "":
+		transport.DialContext = dialer.DialContext
+	case ViaXray:
+		// Hostnames are resolved by Xray, so DNS follows the route too
+		d, err := proxy.SOCKS5("tcp", socksAddr, nil, dialer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create SOCKS5 dialer: %w", err)
+		}
+		ctxDialer, ok := d.(proxy.ContextDialer)
+		if !ok {
+			return nil, fmt.Errorf("SOCKS5 dialer does not support ContextDialer interface")
+		}
+		transport.DialContext = ctxDialer.DialContext
+	default:
+		dialer.Control = bindToDevice(vpnconfig.TunnelInterface(via))
+		transport.DialContext = dialer.DialContext
 	}
-	ip := strings.TrimSpace(result.Output)
-	// Validate that the result is a valid IP address
-	if net.ParseIP(ip) == nil {
-		return "", fmt.Errorf("invalid IP address: %s", ip)
+	return transport, nil
+}
+
+// fetchIP queries one provider (a built-in name or a URL).
+func fetchIP(ctx context.Context, client *http.Client, provider string) (*IPInfo, error) {
+	url, ok := ipProviders[provider]
+	if !ok {
+		if !strings.HasPrefix(provider, "http://") && !strings.HasPrefix(provider, "https://") {
+			return nil, fmt.Errorf("%s: unknown provider", provider)
+		}
+		url = provider
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", provider, err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", provider, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: HTTP %d", provider, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxIPResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", provider, err)
+	}
+
+	info, err := parseIPInfo(body)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", provider, err)
+	}
+	info.Provider = provider
+	return info, nil
+}
+
+// parseIPInfo reads a bare IP or a JSON answer from ipinfo.io, ifconfig.co,
+// ip-api.com or a compatible service.
+func parseIPInfo(body []byte) (*IPInfo, error) {
+	text := strings.TrimSpace(string(body))
+	if !strings.HasPrefix(text, "{") {
+		return validIPInfo(&IPInfo{IP: text})
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(text), &fields); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	str := func(keys ...string) string {
+		for _, k := range keys {
+			if v, ok := fields[k].(string); ok && v != "" {
+				return strings.TrimSpace(v)
+			}
+		}
+		return ""
+	}
+
+	info := &IPInfo{
+		IP:      str("ip", "query"),
+		Country: str("country_iso", "countryCode", "country_code", "country"),
+		City:    str("city"),
+		ASN:     str("asn"),
+		ISP:     str("isp", "asn_org"),
+	}
+	// ipinfo.io "org" and ip-api.com "as" are "AS13335 Cloudflare, Inc."
+	if as := str("org", "as"); as != "" {
+		if num, name, ok := strings.Cut(as, " "); ok && strings.HasPrefix(num, "AS") {
+			if info.ASN == "" {
+				info.ASN = num
+			}
+			if info.ISP == "" {
+				info.ISP = name
+			}
+		} else if info.ISP == "" {
+			info.ISP = as
+		}
+	}
+	return validIPInfo(info)
+}
+
+func validIPInfo(info *IPInfo) (*IPInfo, error) {
+	addr, err := netip.ParseAddr(info.IP)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IP address: %q", info.IP)
 	}
-	return ip, nil
+	info.IP = addr.Unmap().String()
+	return info, nil
 }
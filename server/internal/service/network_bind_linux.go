@@ -0,0 +1,20 @@
+// internal/service/network_bind_linux.go
+package service
+
+import "syscall"
+
+// bindToDevice returns a dialer Control function that sends the connection
+// out of iface (SO_BINDTODEVICE), bypassing policy routing like curl
+// --interface does.
+func bindToDevice(iface string) func(network, address string, c syscall.RawConn) error {
+	return func(_, _ string, c syscall.RawConn) error {
+		var bindErr error
+		err := c.Control(func(fd uintptr) {
+			bindErr = syscall.SetsockoptString(int(fd), syscall.SOL_SOCKET, syscall.SO_BINDTODEVICE, iface)
+		})
+		if err != nil {
+			return err
+		}
+		return bindErr
+	}
+}
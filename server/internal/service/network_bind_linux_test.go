@@ -0,0 +1,27 @@
+// internal/service/network_bind_linux_test.go
+package service
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestNetworkService_GetExternalIP_Interface(t *testing.T) {
+	provider := textServer(t, http.StatusOK, "192.0.2.55")
+
+	info, err := NewNetworkService(providersConfig(false, provider.URL)).GetExternalIP("lo")
+	if err != nil {
+		t.Fatalf("GetExternalIP error: %v", err)
+	}
+	if info.IP != "192.0.2.55" {
+		t.Errorf("expected 192.0.2.55, got %+v", info)
+	}
+}
+
+func TestNetworkService_GetExternalIP_MissingInterface(t *testing.T) {
+	provider := textServer(t, http.StatusOK, "192.0.2.55")
+
+	if _, err := NewNetworkService(providersConfig(false, provider.URL)).GetExternalIP("wgc9"); err == nil {
+		t.Error("expected error for a missing interface")
+	}
+}
@@ -0,0 +1,18 @@
+// internal/service/network_bind_other.go
+
+//go:build !linux
+
+package service
+
+import (
+	"errors"
+	"syscall"
+)
+
+// bindToDevice fails on systems without SO_BINDTODEVICE (e.g. macOS in dev
+// mode); lookups through an interface only work on the router.
+func bindToDevice(iface string) func(network, address string, c syscall.RawConn) error {
+	return func(_, _ string, _ syscall.RawConn) error {
+		return errors.New("binding to interface " + iface + " is only supported on Linux")
+	}
+}
@@ -2,74 +2,269 @@
 package service
 
 import (
+	"encoding/binary"
 	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
-	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/shell"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
 )
 
-func TestNetworkService_GetExternalIP(t *testing.T) {
-	mock := &mockExecutor{
-		result: &shell.Result{Output: "1.2.3.4", ExitCode: 0},
+// ipLookupConfig implements ConfigStore with an advanced.ip_lookup section.
+type ipLookupConfig struct {
+	advanced map[string]interface{}
+	err      error
+}
+
+func (c *ipLookupConfig) LoadVPNConfig() (*vpnconfig.VPNDirectorConfig, error) {
+	return &vpnconfig.VPNDirectorConfig{Advanced: c.advanced}, c.err
+}
+func (c *ipLookupConfig) LoadServers() ([]vpnconfig.Server, error)         { return nil, nil }
+func (c *ipLookupConfig) SaveVPNConfig(*vpnconfig.VPNDirectorConfig) error { return nil }
+func (c *ipLookupConfig) SaveServers([]vpnconfig.Server) error             { return nil }
+func (c *ipLookupConfig) DataDir() (string, error)                         { return "", nil }
+func (c *ipLookupConfig) DataDirOrDefault() string                         { return "" }
+func (c *ipLookupConfig) ScriptsDir() string                               { return "" }
+func (c *ipLookupConfig) LockConfig() (func(), error)                      { return func() {}, nil }
+
+func providersConfig(race bool, providers ...string) *ipLookupConfig {
+	list := make([]interface{}, len(providers))
+	for i, p := range providers {
+		list[i] = p
 	}
-	svc := NewNetworkService(mock)
+	return &ipLookupConfig{advanced: map[string]interface{}{
+		"ip_lookup": map[string]interface{}{"providers": list, "race": race, "timeout": float64(2)},
+	}}
+}
 
-	ip, err := svc.GetExternalIP()
+func textServer(t *testing.T, status int, body string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestNetworkService_GetExternalIP_FallsBack(t *testing.T) {
+	down := textServer(t, http.StatusTooManyRequests, "rate limited")
+	html := textServer(t, http.StatusOK, "<html>error page</html>")
+	ok := textServer(t, http.StatusOK, `{"ip":"203.0.113.7","city":"Amsterdam","country":"NL","org":"AS60781 LeaseWeb Netherlands B.V."}`)
+
+	svc := NewNetworkService(providersConfig(false, down.URL, html.URL, ok.URL))
+	info, err := svc.GetExternalIP("")
 	if err != nil {
 		t.Fatalf("GetExternalIP error: %v", err)
 	}
-	if ip != "1.2.3.4" {
-		t.Errorf("expected 1.2.3.4, got %s", ip)
+	want := IPInfo{IP: "203.0.113.7", Country: "NL", City: "Amsterdam", ASN: "AS60781", ISP: "LeaseWeb Netherlands B.V.", Provider: ok.URL}
+	if *info != want {
+		t.Errorf("got %+v, want %+v", *info, want)
 	}
 }
 
-func TestNetworkService_GetExternalIP_Error(t *testing.T) {
-	mock := &mockExecutor{
-		err: errors.New("network error"),
-	}
-	svc := NewNetworkService(mock)
+func TestNetworkService_GetExternalIP_AllFail(t *testing.T) {
+	down := textServer(t, http.StatusBadGateway, "")
 
-	_, err := svc.GetExternalIP()
+	svc := NewNetworkService(providersConfig(false, down.URL, "nosuchprovider"))
+	_, err := svc.GetExternalIP("")
 	if err == nil {
-		t.Error("expected error, got nil")
+		t.Fatal("expected error, got nil")
+	}
+	for _, want := range []string{"HTTP 502", "nosuchprovider: unknown provider"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in error, got: %v", want, err)
+		}
 	}
 }
 
-func TestNetworkService_GetExternalIP_TrimSpace(t *testing.T) {
-	mock := &mockExecutor{
-		result: &shell.Result{Output: "  5.6.7.8\n", ExitCode: 0},
+func TestNetworkService_GetExternalIP_Race(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+			io.WriteString(w, "198.51.100.1")
+		}
+	}))
+	t.Cleanup(slow.Close)
+	fast := textServer(t, http.StatusOK, "  198.51.100.2\n")
+
+	svc := NewNetworkService(providersConfig(true, slow.URL, fast.URL))
+	start := time.Now()
+	info, err := svc.GetExternalIP("")
+	if err != nil {
+		t.Fatalf("GetExternalIP error: %v", err)
+	}
+	if info.IP != "198.51.100.2" || info.Provider != fast.URL {
+		t.Errorf("expected the fast provider to win, got %+v", info)
 	}
-	svc := NewNetworkService(mock)
+	if time.Since(start) > 500*time.Millisecond {
+		t.Errorf("race waited for the slow provider")
+	}
+}
+
+func TestNetworkService_GetExternalIP_Xray(t *testing.T) {
+	provider := textServer(t, http.StatusOK, "192.0.2.44")
+	socks, used := socksServer(t)
+
+	port, _ := strconv.Atoi(socks[strings.LastIndex(socks, ":")+1:])
+	cfg := providersConfig(false, provider.URL)
+	cfg.advanced["xray"] = map[string]interface{}{"socks_port": float64(port)}
 
-	ip, err := svc.GetExternalIP()
+	info, err := NewNetworkService(cfg).GetExternalIP(ViaXray)
 	if err != nil {
 		t.Fatalf("GetExternalIP error: %v", err)
 	}
-	if ip != "5.6.7.8" {
-		t.Errorf("expected trimmed IP '5.6.7.8', got %q", ip)
+	if info.IP != "192.0.2.44" || used.Load() != 1 {
+		t.Errorf("expected lookup through SOCKS, got %+v (%d proxied)", info, used.Load())
 	}
 }
 
-func TestNetworkService_GetExternalIP_NonZeroExitCode(t *testing.T) {
-	mock := &mockExecutor{
-		result: &shell.Result{Output: "", ExitCode: 1},
+func TestNetworkService_GetExternalIP_InvalidVia(t *testing.T) {
+	svc := NewNetworkService(nil)
+	for _, via := range []string{"wgc1; rm -rf /", "averyveryverylonginterface", "../x y"} {
+		if _, err := svc.GetExternalIP(via); err == nil {
+			t.Errorf("expected error for via %q", via)
+		}
 	}
-	svc := NewNetworkService(mock)
+}
 
-	_, err := svc.GetExternalIP()
-	if err == nil {
-		t.Error("expected error for non-zero exit code, got nil")
+func TestNetworkService_LookupOptions_Defaults(t *testing.T) {
+	for _, cfg := range []ConfigStore{nil, &ipLookupConfig{err: errors.New("missing")}, &ipLookupConfig{}} {
+		opts := NewNetworkService(cfg).lookupOptions()
+		if len(opts.providers) != len(DefaultIPProviders) || opts.race || opts.timeout != defaultIPLookupTimeout {
+			t.Errorf("expected defaults, got %+v", opts)
+		}
+		if opts.socksAddr != "127.0.0.1:12346" {
+			t.Errorf("expected default SOCKS address, got %q", opts.socksAddr)
+		}
 	}
 }
 
-func TestNetworkService_GetExternalIP_InvalidIPFormat(t *testing.T) {
-	mock := &mockExecutor{
-		result: &shell.Result{Output: "<html>error page</html>", ExitCode: 0},
+func TestParseIPInfo(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want IPInfo
+	}{
+		{"plain", "1.2.3.4\n", IPInfo{IP: "1.2.3.4"}},
+		{"ipv6", "2001:db8::1", IPInfo{IP: "2001:db8::1"}},
+		{
+			"ipinfo",
+			`{"ip":"8.8.8.8","city":"Mountain View","country":"US","org":"AS15169 Google LLC"}`,
+			IPInfo{IP: "8.8.8.8", Country: "US", City: "Mountain View", ASN: "AS15169", ISP: "Google LLC"},
+		},
+		{
+			"ifconfig.co",
+			`{"ip":"8.8.8.8","country":"United States","country_iso":"US","city":"Mountain View","asn":"AS15169","asn_org":"GOOGLE"}`,
+			IPInfo{IP: "8.8.8.8", Country: "US", City: "Mountain View", ASN: "AS15169", ISP: "GOOGLE"},
+		},
+		{
+			"ip-api",
+			`{"status":"success","countryCode":"US","city":"Ashburn","isp":"Google LLC","as":"AS15169 Google LLC","query":"8.8.8.8"}`,
+			IPInfo{IP: "8.8.8.8", Country: "US", City: "Ashburn", ASN: "AS15169", ISP: "Google LLC"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := parseIPInfo([]byte(tt.body))
+			if err != nil {
+				t.Fatalf("parseIPInfo error: %v", err)
+			}
+			if *info != tt.want {
+				t.Errorf("got %+v, want %+v", *info, tt.want)
+			}
+		})
 	}
-	svc := NewNetworkService(mock)
+}
 
-	_, err := svc.GetExternalIP()
-	if err == nil {
-		t.Error("expected error for invalid IP format, got nil")
+func TestParseIPInfo_Invalid(t *testing.T) {
+	for _, body := range []string{"", "<html>error page</html>", `{"status":"fail","message":"quota"}`, `{"ip":`} {
+		if _, err := parseIPInfo([]byte(body)); err == nil {
+			t.Errorf("expected error for %q", body)
+		}
+	}
+}
+
+// socksServer runs a minimal SOCKS5 proxy (no auth, CONNECT only) and
+// counts the connections it forwarded.
+func socksServer(t *testing.T) (string, *atomic.Int32) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	var used atomic.Int32
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				target, ok := socksHandshake(conn)
+				if !ok {
+					return
+				}
+				upstream, err := net.Dial("tcp", target)
+				if err != nil {
+					return
+				}
+				defer upstream.Close()
+				used.Add(1)
+				conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+				go io.Copy(upstream, conn)
+				io.Copy(conn, upstream)
+			}()
+		}
+	}()
+	return ln.Addr().String(), &used
+}
+
+func socksHandshake(conn net.Conn) (string, bool) {
+	buf := make([]byte, 262)
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return "", false
+	}
+	if _, err := io.ReadFull(conn, buf[:buf[1]]); err != nil {
+		return "", false
+	}
+	conn.Write([]byte{5, 0})
+
+	if _, err := io.ReadFull(conn, buf[:4]); err != nil || buf[1] != 1 {
+		return "", false
+	}
+	var host string
+	switch buf[3] {
+	case 1:
+		if _, err := io.ReadFull(conn, buf[:4]); err != nil {
+			return "", false
+		}
+		host = net.IP(buf[:4]).String()
+	case 3:
+		if _, err := io.ReadFull(conn, buf[:1]); err != nil {
+			return "", false
+		}
+		n := int(buf[0])
+		if _, err := io.ReadFull(conn, buf[:n]); err != nil {
+			return "", false
+		}
+		host = string(buf[:n])
+	default:
+		return "", false
+	}
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return "", false
 	}
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(buf[:2])))), true
 }
@@ -0,0 +1,35 @@
+// internal/service/storecache.go
+package service
+
+import "sync"
+
+// StoreCache keeps one store for the configured data directory. The
+// directory is looked up on every Get so a data_dir change takes effect
+// without a restart; while it stays the same the store is reused, so its
+// own lock serializes writes within the process.
+type StoreCache[S any] struct {
+	config ConfigStore
+	open   func(dataDir string) S
+
+	mu     sync.Mutex
+	dir    string
+	cur    S
+	opened bool
+}
+
+// NewStoreCache creates a cache that calls open for each new data directory.
+func NewStoreCache[S any](config ConfigStore, open func(dataDir string) S) *StoreCache[S] {
+	return &StoreCache[S]{config: config, open: open}
+}
+
+// Get returns the store for the current data directory.
+func (c *StoreCache[S]) Get() S {
+	dir := c.config.DataDirOrDefault()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.opened || c.dir != dir {
+		c.cur, c.dir, c.opened = c.open(dir), dir, true
+	}
+	return c.cur
+}
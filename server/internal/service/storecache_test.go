@@ -0,0 +1,31 @@
+// internal/service/storecache_test.go
+package service
+
+import (
+	"testing"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/testutil"
+)
+
+func TestStoreCache(t *testing.T) {
+	cfg := &testutil.Config{Dir: "/data"}
+	var opened []string
+	cache := NewStoreCache(cfg, func(dataDir string) *string {
+		opened = append(opened, dataDir)
+		return &dataDir
+	})
+
+	first := cache.Get()
+	if *first != "/data" || cache.Get() != first {
+		t.Errorf("expected one store for /data, got %q", *first)
+	}
+
+	// A data_dir change opens a store in the new directory
+	cfg.Dir = "/mnt/data"
+	if got := cache.Get(); *got != "/mnt/data" || cache.Get() != got {
+		t.Errorf("expected one store for /mnt/data, got %q", *got)
+	}
+	if len(opened) != 2 {
+		t.Errorf("expected 2 stores opened, got %v", opened)
+	}
+}
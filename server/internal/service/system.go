@@ -0,0 +1,75 @@
+// internal/service/system.go
+package service
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SystemService reports process and ipset state of the router
+type SystemService struct {
+	executor ShellExecutor
+}
+
+// Compile-time check that SystemService implements SystemInfo
+var _ SystemInfo = (*SystemService)(nil)
+
+// NewSystemService creates a new SystemService
+func NewSystemService(executor ShellExecutor) *SystemService {
+	if executor == nil {
+		executor = DefaultExecutor()
+	}
+	return &SystemService{executor: executor}
+}
+
+// ProcessRunning reports whether a process with the given name is running
+func (s *SystemService) ProcessRunning(name string) (bool, error) {
+	result, err := s.executor.Exec("pidof", name)
+	if err != nil {
+		return false, err
+	}
+	switch result.ExitCode {
+	case 0:
+		return true, nil
+	case 1:
+		return false, nil
+	}
+	return false, fmt.Errorf("pidof failed with exit code %d", result.ExitCode)
+}
+
+// IPSetCounts returns the number of entries in every ipset
+func (s *SystemService) IPSetCounts() (map[string]int, error) {
+	result, err := s.executor.Exec("ipset", "list", "-t")
+	if err != nil {
+		return nil, err
+	}
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("ipset list failed with exit code %d", result.ExitCode)
+	}
+	return ParseIPSetTerse(result.Output), nil
+}
+
+// ParseIPSetTerse parses "ipset list -t" output into entry counts per set
+func ParseIPSetTerse(output string) map[string]int {
+	counts := make(map[string]int)
+	var name string
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "Name":
+			name = value
+		case "Number of entries":
+			if n, err := strconv.Atoi(value); err == nil && name != "" {
+				counts[name] = n
+			}
+		}
+	}
+	return counts
+}
@@ -0,0 +1,78 @@
+// internal/service/system_test.go
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/shell"
+)
+
+func TestSystemService_ProcessRunning(t *testing.T) {
+	tests := []struct {
+		name     string
+		exitCode int
+		want     bool
+		wantErr  bool
+	}{
+		{"running", 0, true, false},
+		{"not running", 1, false, false},
+		{"failure", 2, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockExecutor{result: &shell.Result{Output: "1234", ExitCode: tt.exitCode}}
+			svc := NewSystemService(mock)
+
+			got, err := svc.ProcessRunning("xray")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+			if mock.calls[0][0] != "pidof" || mock.calls[0][1] != "xray" {
+				t.Errorf("unexpected call: %v", mock.calls[0])
+			}
+		})
+	}
+}
+
+func TestSystemService_ProcessRunning_ExecError(t *testing.T) {
+	svc := NewSystemService(&mockExecutor{err: errors.New("exec failed")})
+
+	if _, err := svc.ProcessRunning("xray"); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestSystemService_IPSetCounts(t *testing.T) {
+	output := `Name: ru
+Type: hash:net
+Revision: 6
+Header: family inet hashsize 4096 maxelem 65536
+Size in memory: 524504
+References: 1
+Number of entries: 12345
+Name: XRAY_EXCLUDE_DOMAINS
+Type: hash:ip
+Number of entries: 7
+`
+	svc := NewSystemService(&mockExecutor{result: &shell.Result{Output: output, ExitCode: 0}})
+
+	counts, err := svc.IPSetCounts()
+	if err != nil {
+		t.Fatalf("IPSetCounts error: %v", err)
+	}
+	if len(counts) != 2 || counts["ru"] != 12345 || counts["XRAY_EXCLUDE_DOMAINS"] != 7 {
+		t.Errorf("unexpected counts: %v", counts)
+	}
+}
+
+func TestSystemService_IPSetCounts_NonZeroExitCode(t *testing.T) {
+	svc := NewSystemService(&mockExecutor{result: &shell.Result{Output: "", ExitCode: 1}})
+
+	if _, err := svc.IPSetCounts(); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
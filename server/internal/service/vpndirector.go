@@ -51,6 +51,18 @@ func (s *VPNDirectorService) Apply() error {
 	return nil
 }
 
+// ApplyXray re-applies only the Xray TPROXY rules and ipsets
+func (s *VPNDirectorService) ApplyXray() error {
+	result, err := s.executor.Exec(s.scriptPath(), "apply", "xray")
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("apply xray failed (exit %d): %s", result.ExitCode, result.Output)
+	}
+	return nil
+}
+
 // Restart restarts VPN Director
 func (s *VPNDirectorService) Restart() error {
 	result, err := s.executor.Exec(s.scriptPath(), "restart")
@@ -48,6 +48,22 @@ func TestVPNDirectorService_StatusError(t *testing.T) {
 	}
 }
 
+func TestVPNDirectorService_ApplyXray(t *testing.T) {
+	mock := &mockExecutor{result: &shell.Result{Output: "ok", ExitCode: 0}}
+	svc := NewVPNDirectorService("/opt/vpn-director", mock)
+
+	if err := svc.ApplyXray(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(mock.calls) != 1 {
+		t.Fatalf("expected 1 call, got %d", len(mock.calls))
+	}
+	// Should call: vpn-director.sh apply xray
+	if mock.calls[0][1] != "apply" || mock.calls[0][2] != "xray" {
+		t.Errorf("wrong args: %v", mock.calls[0])
+	}
+}
+
 func TestVPNDirectorService_RestartXray(t *testing.T) {
 	mock := &mockExecutor{result: &shell.Result{Output: "ok", ExitCode: 0}}
 	svc := NewVPNDirectorService("/opt/vpn-director", mock)
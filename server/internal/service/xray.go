@@ -2,6 +2,8 @@
 package service
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -13,16 +15,21 @@ import (
 type XrayService struct {
 	templatePath string
 	outputPath   string
+	ipv6Probe    string // exists if the kernel has an IPv6 stack
 }
 
 // Compile-time check that XrayService implements XrayGenerator
 var _ XrayGenerator = (*XrayService)(nil)
 
+// Compile-time check that XrayService implements XrayConfigReader
+var _ XrayConfigReader = (*XrayService)(nil)
+
 // NewXrayService creates a new XrayService
 func NewXrayService(templatePath, outputPath string) *XrayService {
 	return &XrayService{
 		templatePath: templatePath,
 		outputPath:   outputPath,
+		ipv6Probe:    "/proc/net/if_inet6",
 	}
 }
 
@@ -33,8 +40,12 @@ func (s *XrayService) GenerateConfig(server vpnconfig.Server) error {
 		return fmt.Errorf("read template: %w", err)
 	}
 
+	// IPv6 literals are stored in URI form ("[2001:db8::1]"); Xray expects them bare
+	address := strings.Trim(server.Address, "[]")
+
 	config := string(template)
-	config = strings.ReplaceAll(config, "{{XRAY_SERVER_ADDRESS}}", server.Address)
+	config = strings.ReplaceAll(config, "{{XRAY_LISTEN}}", s.listenAddress())
+	config = strings.ReplaceAll(config, "{{XRAY_SERVER_ADDRESS}}", address)
 	config = strings.ReplaceAll(config, "{{XRAY_SERVER_PORT}}", fmt.Sprintf("%d", server.Port))
 	config = strings.ReplaceAll(config, "{{XRAY_USER_UUID}}", server.UUID)
 
@@ -44,3 +55,53 @@ func (s *XrayService) GenerateConfig(server vpnconfig.Server) error {
 
 	return nil
 }
+
+// listenAddress returns the TPROXY inbound listen address: dual-stack "::"
+// when the kernel supports IPv6 (needed for ip6tables TPROXY), else "0.0.0.0".
+func (s *XrayService) listenAddress() string {
+	if _, err := os.Stat(s.ipv6Probe); err == nil {
+		return "::"
+	}
+	return "0.0.0.0"
+}
+
+// xrayOutboundConfig is the part of the generated config that names the server
+type xrayOutboundConfig struct {
+	Outbounds []struct {
+		Settings struct {
+			Vnext []struct {
+				Address string `json:"address"`
+				Port    int    `json:"port"`
+				Users   []struct {
+					ID string `json:"id"`
+				} `json:"users"`
+			} `json:"vnext"`
+		} `json:"settings"`
+	} `json:"outbounds"`
+}
+
+// ActiveServer returns the address, port and UUID of the server the generated
+// config points to. Other fields are left empty. The address is bare, so
+// stored IPv6 literals need their brackets trimmed before comparing.
+func (s *XrayService) ActiveServer() (*vpnconfig.Server, error) {
+	data, err := os.ReadFile(s.outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+
+	var cfg xrayOutboundConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+
+	for _, out := range cfg.Outbounds {
+		for _, v := range out.Settings.Vnext {
+			server := &vpnconfig.Server{Address: v.Address, Port: v.Port}
+			if len(v.Users) > 0 {
+				server.UUID = v.Users[0].ID
+			}
+			return server, nil
+		}
+	}
+	return nil, errors.New("no server in config")
+}
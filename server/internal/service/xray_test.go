@@ -57,3 +57,90 @@ func TestXrayService_GenerateConfig_MissingTemplate(t *testing.T) {
 		t.Error("expected error for missing template")
 	}
 }
+
+func TestXrayService_GenerateConfig_IPv6(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	templatePath := filepath.Join(tmpDir, "config.json.template")
+	outputPath := filepath.Join(tmpDir, "config.json")
+	os.WriteFile(templatePath, []byte(`{"listen": "{{XRAY_LISTEN}}", "address": "{{XRAY_SERVER_ADDRESS}}"}`), 0644)
+
+	probe := filepath.Join(tmpDir, "if_inet6")
+	os.WriteFile(probe, nil, 0644)
+
+	svc := NewXrayService(templatePath, outputPath)
+	svc.ipv6Probe = probe
+
+	if err := svc.GenerateConfig(vpnconfig.Server{Address: "[2001:db8::1]", Port: 443}); err != nil {
+		t.Fatalf("GenerateConfig error: %v", err)
+	}
+
+	content, _ := os.ReadFile(outputPath)
+	want := `{"listen": "::", "address": "2001:db8::1"}`
+	if string(content) != want {
+		t.Errorf("got %s, want %s", content, want)
+	}
+}
+
+func TestXrayService_GenerateConfig_NoIPv6(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	templatePath := filepath.Join(tmpDir, "config.json.template")
+	outputPath := filepath.Join(tmpDir, "config.json")
+	os.WriteFile(templatePath, []byte(`{"listen": "{{XRAY_LISTEN}}"}`), 0644)
+
+	svc := NewXrayService(templatePath, outputPath)
+	svc.ipv6Probe = filepath.Join(tmpDir, "missing")
+
+	if err := svc.GenerateConfig(vpnconfig.Server{Address: "example.com"}); err != nil {
+		t.Fatalf("GenerateConfig error: %v", err)
+	}
+
+	content, _ := os.ReadFile(outputPath)
+	if string(content) != `{"listen": "0.0.0.0"}` {
+		t.Errorf("unexpected config: %s", content)
+	}
+}
+
+func TestXrayService_ActiveServer(t *testing.T) {
+	tmpDir := t.TempDir()
+	outputPath := filepath.Join(tmpDir, "config.json")
+	config := `{
+  "outbounds": [
+    {"protocol": "vless", "settings": {"vnext": [{"address": "example.com", "port": 443, "users": [{"id": "abc-123"}]}]}},
+    {"protocol": "freedom", "tag": "direct"}
+  ]
+}`
+	os.WriteFile(outputPath, []byte(config), 0644)
+
+	svc := NewXrayService(filepath.Join(tmpDir, "template"), outputPath)
+
+	server, err := svc.ActiveServer()
+	if err != nil {
+		t.Fatalf("ActiveServer error: %v", err)
+	}
+	if server.Address != "example.com" || server.Port != 443 || server.UUID != "abc-123" {
+		t.Errorf("unexpected server: %+v", server)
+	}
+}
+
+func TestXrayService_ActiveServer_NoServer(t *testing.T) {
+	tmpDir := t.TempDir()
+	outputPath := filepath.Join(tmpDir, "config.json")
+	os.WriteFile(outputPath, []byte(`{"outbounds": [{"protocol": "freedom"}]}`), 0644)
+
+	svc := NewXrayService(filepath.Join(tmpDir, "template"), outputPath)
+
+	if _, err := svc.ActiveServer(); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestXrayService_ActiveServer_MissingConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	svc := NewXrayService(filepath.Join(tmpDir, "template"), filepath.Join(tmpDir, "config.json"))
+
+	if _, err := svc.ActiveServer(); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
@@ -0,0 +1,59 @@
+package session
+
+import (
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/service"
+)
+
+// Compile-time interface check
+var _ Manager = (*Service)(nil)
+
+// Service manages sessions in the configured data directory, which is
+// looked up on every call so a data_dir change takes effect without a
+// restart. The store is shared between calls so its lock keeps an activity
+// update from writing back a session that was just revoked.
+type Service struct {
+	stores *service.StoreCache[*Store]
+}
+
+// NewService creates a new Service.
+func NewService(config service.ConfigStore) *Service {
+	return &Service{stores: service.NewStoreCache(config, func(dataDir string) *Store {
+		return NewStore(StorePath(dataDir))
+	})}
+}
+
+func (s *Service) store() *Store {
+	return s.stores.Get()
+}
+
+// Create registers a session.
+func (s *Service) Create(username, ip, userAgent string, expires time.Time) (Session, error) {
+	return s.store().Create(username, ip, userAgent, expires)
+}
+
+// Check returns the live session with the given ID, or ErrNotFound.
+func (s *Service) Check(id, ip, userAgent string) (*Session, error) {
+	return s.store().Check(id, ip, userAgent)
+}
+
+// Renew moves the end of a session.
+func (s *Service) Renew(id string, expires time.Time) error {
+	return s.store().Renew(id, expires)
+}
+
+// List returns live sessions.
+func (s *Service) List() ([]Session, error) {
+	return s.store().List()
+}
+
+// Revoke deletes a session.
+func (s *Service) Revoke(id string) error {
+	return s.store().Revoke(id)
+}
+
+// RevokeAll deletes the sessions of username, or everyone's.
+func (s *Service) RevokeAll(username, except string) (int, error) {
+	return s.store().RevokeAll(username, except)
+}
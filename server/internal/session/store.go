@@ -0,0 +1,243 @@
+// Package session keeps the server-side registry of Web UI logins. Every
+// session JWT carries the ID (jti) of an entry here, so deleting the entry
+// revokes the token before it expires.
+package session
+
+import (
+	"cmp"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// StoreFile is the session file inside the data directory.
+const StoreFile = "sessions.json"
+
+const (
+	// maxSessions bounds the registry; the least recently seen session is
+	// dropped to make room.
+	maxSessions = 100
+	// maxUserAgent bounds the stored browser description.
+	maxUserAgent = 200
+	// seenInterval limits how often activity is written to disk.
+	seenInterval = 5 * time.Minute
+)
+
+// ErrNotFound is returned for unknown, revoked and expired sessions.
+var ErrNotFound = errors.New("session not found")
+
+// Session is a signed-in browser.
+type Session struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Created   time.Time `json:"created"`
+	LastSeen  time.Time `json:"last_seen"`
+	Expires   time.Time `json:"expires"`
+}
+
+// Manager creates, checks, renews and revokes sessions.
+type Manager interface {
+	Create(username, ip, userAgent string, expires time.Time) (Session, error)
+	Check(id, ip, userAgent string) (*Session, error)
+	Renew(id string, expires time.Time) error
+	List() ([]Session, error)
+	Revoke(id string) error
+	RevokeAll(username, except string) (int, error)
+}
+
+// Store keeps sessions in a JSON file. Writes are atomic (temp file + rename).
+type Store struct {
+	path string
+	mu   sync.Mutex
+	now  func() time.Time
+}
+
+// NewStore creates a store backed by the file at path.
+func NewStore(path string) *Store {
+	return &Store{path: path, now: time.Now}
+}
+
+// StorePath returns the session file location inside dataDir.
+func StorePath(dataDir string) string {
+	return filepath.Join(dataDir, StoreFile)
+}
+
+// Create registers a session for username that ends at expires.
+func (s *Store) Create(username, ip, userAgent string, expires time.Time) (Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions, err := s.load()
+	if err != nil {
+		return Session{}, err
+	}
+	if len(sessions) >= maxSessions {
+		slices.SortFunc(sessions, func(a, b Session) int { return b.LastSeen.Compare(a.LastSeen) })
+		sessions = sessions[:maxSessions-1]
+	}
+
+	now := s.now().UTC()
+	sess := Session{
+		ID:        rand.Text(),
+		Username:  username,
+		IP:        ip,
+		UserAgent: truncate(userAgent),
+		Created:   now,
+		LastSeen:  now,
+		Expires:   expires.UTC(),
+	}
+	if err := s.save(append(sessions, sess)); err != nil {
+		return Session{}, err
+	}
+	return sess, nil
+}
+
+// Check returns the live session with the given ID, or ErrNotFound. The
+// address and browser are updated when they change; otherwise the last
+// activity is saved at most every few minutes to spare the router's flash.
+func (s *Store) Check(id, ip, userAgent string) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	i := slices.IndexFunc(sessions, func(sess Session) bool { return sess.ID == id })
+	if i < 0 {
+		return nil, ErrNotFound
+	}
+
+	sess := &sessions[i]
+	now := s.now().UTC()
+	userAgent = truncate(userAgent)
+	if now.Sub(sess.LastSeen) >= seenInterval || sess.IP != ip || sess.UserAgent != userAgent {
+		sess.LastSeen, sess.IP, sess.UserAgent = now, ip, userAgent
+		if err := s.save(sessions); err != nil {
+			return nil, err
+		}
+	}
+	found := *sess
+	return &found, nil
+}
+
+// Renew moves the end of a session to expires.
+func (s *Store) Renew(id string, expires time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions, err := s.load()
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(sessions, func(sess Session) bool { return sess.ID == id })
+	if i < 0 {
+		return ErrNotFound
+	}
+	sessions[i].Expires = expires.UTC()
+	sessions[i].LastSeen = s.now().UTC()
+	return s.save(sessions)
+}
+
+// List returns live sessions, most recently seen first.
+func (s *Store) List() ([]Session, error) {
+	s.mu.Lock()
+	sessions, err := s.load()
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(sessions, func(a, b Session) int {
+		return cmp.Or(b.LastSeen.Compare(a.LastSeen), cmp.Compare(a.ID, b.ID))
+	})
+	return sessions, nil
+}
+
+// Revoke deletes the session with the given ID.
+func (s *Store) Revoke(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions, err := s.load()
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(sessions, func(sess Session) bool { return sess.ID == id })
+	if i < 0 {
+		return ErrNotFound
+	}
+	return s.save(slices.Delete(sessions, i, i+1))
+}
+
+// RevokeAll deletes the sessions of username (everyone when empty) except
+// the one with ID except, and returns how many were deleted.
+func (s *Store) RevokeAll(username, except string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions, err := s.load()
+	if err != nil {
+		return 0, err
+	}
+	before := len(sessions)
+	sessions = slices.DeleteFunc(sessions, func(sess Session) bool {
+		return sess.ID != except && (username == "" || sess.Username == username)
+	})
+	if len(sessions) == before {
+		return 0, nil
+	}
+	if err := s.save(sessions); err != nil {
+		return 0, err
+	}
+	return before - len(sessions), nil
+}
+
+// load reads the registry, leaving out expired sessions.
+func (s *Store) load() ([]Session, error) {
+	raw, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read sessions: %w", err)
+	}
+	var sessions []Session
+	if err := json.Unmarshal(raw, &sessions); err != nil {
+		return nil, fmt.Errorf("parse sessions: %w", err)
+	}
+	now := s.now()
+	return slices.DeleteFunc(sessions, func(sess Session) bool { return !now.Before(sess.Expires) }), nil
+}
+
+func (s *Store) save(sessions []Session) error {
+	if sessions == nil {
+		sessions = []Session{}
+	}
+	raw, err := json.MarshalIndent(sessions, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, raw, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.path)
+}
+
+func truncate(userAgent string) string {
+	if len(userAgent) > maxUserAgent {
+		return userAgent[:maxUserAgent]
+	}
+	return userAgent
+}
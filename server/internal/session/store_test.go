@@ -0,0 +1,168 @@
+package session
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) (*Store, *time.Time) {
+	t.Helper()
+	s := NewStore(filepath.Join(t.TempDir(), StoreFile))
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+func TestStore_CreateAndCheck(t *testing.T) {
+	s, now := newTestStore(t)
+
+	sess, err := s.Create("admin", "192.168.50.10", "Firefox", now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sess.ID) < 20 || sess.Username != "admin" || !sess.Created.Equal(*now) {
+		t.Errorf("unexpected session: %+v", sess)
+	}
+
+	got, err := s.Check(sess.ID, "192.168.50.10", "Firefox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != sess.ID || got.IP != "192.168.50.10" {
+		t.Errorf("unexpected session: %+v", got)
+	}
+
+	if _, err := s.Check("unknown", "192.168.50.10", "Firefox"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	if info, _ := os.Stat(s.path); info.Mode().Perm() != 0600 {
+		t.Errorf("expected 0600, got %v", info.Mode().Perm())
+	}
+}
+
+func TestStore_CheckUpdatesActivity(t *testing.T) {
+	s, now := newTestStore(t)
+	sess, _ := s.Create("admin", "192.168.50.10", "Firefox", now.Add(time.Hour))
+
+	*now = now.Add(time.Minute)
+	got, _ := s.Check(sess.ID, "192.168.50.10", "Firefox")
+	if !got.LastSeen.Equal(sess.Created) {
+		t.Errorf("expected last seen kept within the interval, got %v", got.LastSeen)
+	}
+
+	// A new address is saved immediately
+	got, _ = s.Check(sess.ID, "192.168.50.20", "Firefox")
+	if got.IP != "192.168.50.20" || !got.LastSeen.Equal(*now) {
+		t.Errorf("expected new address and last seen, got %+v", got)
+	}
+
+	*now = now.Add(seenInterval)
+	got, _ = s.Check(sess.ID, "192.168.50.20", strings.Repeat("x", 500))
+	if !got.LastSeen.Equal(*now) || len(got.UserAgent) != maxUserAgent {
+		t.Errorf("expected updated activity and truncated browser, got %+v", got)
+	}
+}
+
+func TestStore_Expiry(t *testing.T) {
+	s, now := newTestStore(t)
+	sess, _ := s.Create("admin", "192.168.50.10", "Firefox", now.Add(time.Hour))
+
+	*now = now.Add(50 * time.Minute)
+	if err := s.Renew(sess.ID, now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	*now = now.Add(30 * time.Minute)
+	if _, err := s.Check(sess.ID, "192.168.50.10", "Firefox"); err != nil {
+		t.Errorf("expected the renewed session to be live, got %v", err)
+	}
+
+	*now = now.Add(time.Hour)
+	if _, err := s.Check(sess.ID, "192.168.50.10", "Firefox"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after expiry, got %v", err)
+	}
+	if list, _ := s.List(); len(list) != 0 {
+		t.Errorf("expected expired sessions left out, got %v", list)
+	}
+	if err := s.Renew(sess.ID, now.Add(time.Hour)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestStore_Revoke(t *testing.T) {
+	s, now := newTestStore(t)
+	a, _ := s.Create("admin", "192.168.50.10", "Firefox", now.Add(time.Hour))
+	b, _ := s.Create("admin", "192.168.50.11", "Safari", now.Add(time.Hour))
+
+	if err := s.Revoke(a.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Check(a.ID, "", ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected revoked session gone, got %v", err)
+	}
+	if _, err := s.Check(b.ID, "192.168.50.11", "Safari"); err != nil {
+		t.Errorf("expected other session kept, got %v", err)
+	}
+	if err := s.Revoke(a.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestStore_RevokeAll(t *testing.T) {
+	s, now := newTestStore(t)
+	a, _ := s.Create("admin", "192.168.50.10", "Firefox", now.Add(time.Hour))
+	s.Create("admin", "192.168.50.11", "Safari", now.Add(time.Hour))
+	c, _ := s.Create("kid", "192.168.50.12", "Chrome", now.Add(time.Hour))
+
+	n, err := s.RevokeAll("admin", a.ID)
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 revoked, got %d, %v", n, err)
+	}
+	list, _ := s.List()
+	if len(list) != 2 {
+		t.Fatalf("expected the current and other users' sessions kept, got %v", list)
+	}
+
+	n, _ = s.RevokeAll("", "")
+	if n != 2 {
+		t.Errorf("expected everyone signed out, got %d", n)
+	}
+	if _, err := s.Check(c.ID, "", ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if n, err := s.RevokeAll("", ""); n != 0 || err != nil {
+		t.Errorf("expected nothing to revoke, got %d, %v", n, err)
+	}
+}
+
+func TestStore_ListAndLimit(t *testing.T) {
+	s, now := newTestStore(t)
+	first, _ := s.Create("admin", "192.168.50.10", "Firefox", now.Add(time.Hour))
+	for range maxSessions {
+		*now = now.Add(time.Second)
+		if _, err := s.Create("admin", "192.168.50.10", "Firefox", now.Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	list, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != maxSessions {
+		t.Fatalf("expected %d sessions, got %d", maxSessions, len(list))
+	}
+	if !list[0].LastSeen.Equal(*now) {
+		t.Errorf("expected the most recent first, got %v", list[0].LastSeen)
+	}
+	for _, sess := range list {
+		if sess.ID == first.ID {
+			t.Error("expected the least recently seen session dropped")
+		}
+	}
+}
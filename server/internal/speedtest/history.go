@@ -0,0 +1,98 @@
+package speedtest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// HistoryFile is the results file inside the data directory.
+const HistoryFile = "speedtest.json"
+
+// maxHistory is the number of results kept.
+const maxHistory = 100
+
+// History is the on-disk list of results, oldest first. Writes are atomic
+// (temp file + rename) since the bot and the Web UI share the file.
+type History struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewHistory creates a history backed by the file at path.
+func NewHistory(path string) *History {
+	return &History{path: path}
+}
+
+// HistoryPath returns the history location inside dataDir.
+func HistoryPath(dataDir string) string {
+	return filepath.Join(dataDir, HistoryFile)
+}
+
+// Add appends a result, dropping the oldest beyond maxHistory.
+func (h *History) Add(r Result) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	results, err := h.load()
+	if err != nil {
+		return err
+	}
+	results = append(results, r)
+	if len(results) > maxHistory {
+		results = results[len(results)-maxHistory:]
+	}
+	return h.save(results)
+}
+
+// List returns up to limit results, newest first (limit <= 0: all).
+func (h *History) List(limit int) ([]Result, error) {
+	h.mu.Lock()
+	results, err := h.load()
+	h.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Result, 0, len(results))
+	for i := len(results) - 1; i >= 0; i-- {
+		out = append(out, results[i])
+		if limit > 0 && len(out) == limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+func (h *History) load() ([]Result, error) {
+	raw, err := os.ReadFile(h.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read speed test history: %w", err)
+	}
+	var results []Result
+	if err := json.Unmarshal(raw, &results); err != nil {
+		return nil, fmt.Errorf("parse speed test history: %w", err)
+	}
+	return results, nil
+}
+
+func (h *History) save(results []Result) error {
+	raw, err := json.Marshal(results)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		return err
+	}
+	tmpPath := h.path + ".tmp"
+	if err := os.WriteFile(tmpPath, raw, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, h.path)
+}
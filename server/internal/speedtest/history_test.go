@@ -0,0 +1,51 @@
+package speedtest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistory_AddAndList(t *testing.T) {
+	h := NewHistory(HistoryPath(t.TempDir()))
+	base := time.Date(2026, 1, 11, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < maxHistory+5; i++ {
+		if err := h.Add(Result{Time: base.Add(time.Duration(i) * time.Minute), Route: "xray"}); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+
+	all, err := h.List(0)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(all) != maxHistory {
+		t.Fatalf("expected %d results, got %d", maxHistory, len(all))
+	}
+	if !all[0].Time.Equal(base.Add(time.Duration(maxHistory+4) * time.Minute)) {
+		t.Errorf("expected newest first, got %v", all[0].Time)
+	}
+
+	last, _ := h.List(3)
+	if len(last) != 3 || !last[0].Time.Equal(all[0].Time) {
+		t.Errorf("unexpected limited list: %+v", last)
+	}
+}
+
+func TestHistory_Missing(t *testing.T) {
+	results, err := NewHistory(filepath.Join(t.TempDir(), "none.json")).List(10)
+	if err != nil || len(results) != 0 {
+		t.Errorf("expected empty history, got %v, %v", results, err)
+	}
+}
+
+func TestHistory_Corrupt(t *testing.T) {
+	path := HistoryPath(t.TempDir())
+	os.WriteFile(path, []byte("{"), 0644)
+
+	if _, err := NewHistory(path).List(10); err == nil {
+		t.Error("expected error for corrupt history")
+	}
+}
@@ -0,0 +1,246 @@
+// Package speedtest measures latency, jitter and throughput of a route
+// (WAN, Xray or a tunnel) against a configurable HTTP endpoint, runs the
+// measurements as background jobs and keeps a history of results.
+package speedtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// Default endpoints (Cloudflare's speed test backend).
+const (
+	DefaultLatencyURL  = "https://speed.cloudflare.com/__down?bytes=0"
+	DefaultDownloadURL = "https://speed.cloudflare.com/__down?bytes=25000000"
+	DefaultUploadURL   = "https://speed.cloudflare.com/__up"
+)
+
+const (
+	defaultUploadBytes  = 10_000_000
+	defaultPhaseTimeout = 15 * time.Second
+	defaultPings        = 5
+)
+
+// Options configures one measurement (advanced.speedtest).
+type Options struct {
+	LatencyURL  string
+	DownloadURL string
+	UploadURL   string
+	UploadBytes int64
+	// PhaseTimeout caps the download and the upload; a transfer cut short
+	// is measured over the bytes moved so far.
+	PhaseTimeout time.Duration
+	Pings        int
+}
+
+// DefaultOptions returns the options used when nothing is configured.
+func DefaultOptions() Options {
+	return Options{
+		LatencyURL:   DefaultLatencyURL,
+		DownloadURL:  DefaultDownloadURL,
+		UploadURL:    DefaultUploadURL,
+		UploadBytes:  defaultUploadBytes,
+		PhaseTimeout: defaultPhaseTimeout,
+		Pings:        defaultPings,
+	}
+}
+
+// Result is one speed test run.
+type Result struct {
+	Time          time.Time `json:"time"`
+	Route         string    `json:"route"`
+	LatencyMs     float64   `json:"latency_ms"`
+	JitterMs      float64   `json:"jitter_ms"`
+	DownloadMbps  float64   `json:"download_mbps"`
+	UploadMbps    float64   `json:"upload_mbps"`
+	DownloadBytes int64     `json:"download_bytes"`
+	UploadBytes   int64     `json:"upload_bytes"`
+	Error         string    `json:"error,omitempty"`
+}
+
+// Measure runs the latency, download and upload phases over client and
+// fills in res. It stops at the first failing phase; res keeps what was
+// measured before it.
+func Measure(ctx context.Context, client *http.Client, opts Options, res *Result) error {
+	samples, err := ping(ctx, client, opts.LatencyURL, opts.Pings)
+	if err != nil {
+		return fmt.Errorf("latency: %w", err)
+	}
+	res.LatencyMs, res.JitterMs = latencyStats(samples)
+
+	res.DownloadBytes, res.DownloadMbps, err = download(ctx, client, opts.DownloadURL, opts.PhaseTimeout)
+	if err != nil {
+		return fmt.Errorf("download: %w", err)
+	}
+
+	res.UploadBytes, res.UploadMbps, err = upload(ctx, client, opts.UploadURL, opts.UploadBytes, opts.PhaseTimeout)
+	if err != nil {
+		return fmt.Errorf("upload: %w", err)
+	}
+	return nil
+}
+
+// ping times n small requests after one warm-up request that opens the
+// connection, so handshakes are not counted.
+func ping(ctx context.Context, client *http.Client, url string, n int) ([]time.Duration, error) {
+	samples := make([]time.Duration, 0, n)
+	for i := 0; i <= n; i++ {
+		start := time.Now()
+		if err := get(ctx, client, url); err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			samples = append(samples, time.Since(start))
+		}
+	}
+	return samples, nil
+}
+
+func get(ctx context.Context, client *http.Client, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// latencyStats returns the median latency and the jitter (mean difference
+// between consecutive samples) in milliseconds.
+func latencyStats(samples []time.Duration) (latency, jitter float64) {
+	if len(samples) == 0 {
+		return 0, 0
+	}
+	var diff time.Duration
+	for i := 1; i < len(samples); i++ {
+		d := samples[i] - samples[i-1]
+		if d < 0 {
+			d = -d
+		}
+		diff += d
+	}
+	if len(samples) > 1 {
+		jitter = ms(diff) / float64(len(samples)-1)
+	}
+
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return ms(sorted[mid-1]+sorted[mid]) / 2, jitter
+	}
+	return ms(sorted[mid]), jitter
+}
+
+// download reads the body of url for at most limit, timed from the response
+// headers so the latency is not counted.
+func download(ctx context.Context, client *http.Client, url string, limit time.Duration) (int64, float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, limit)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, 0, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	start := time.Now()
+	n, err := io.Copy(io.Discard, resp.Body)
+	elapsed := time.Since(start)
+	if err != nil && !cutShort(ctx, n) {
+		return n, 0, err
+	}
+	return n, mbps(n, elapsed), nil
+}
+
+// upload sends size bytes to url for at most limit.
+func upload(ctx context.Context, client *http.Client, url string, size int64, limit time.Duration) (int64, float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, limit)
+	defer cancel()
+
+	body := &countingReader{remaining: size}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return 0, 0, err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	elapsed := time.Since(start)
+	sent := body.read.Load()
+	if err != nil {
+		if cutShort(ctx, sent) {
+			return sent, mbps(sent, elapsed), nil
+		}
+		return sent, 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return sent, 0, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return sent, mbps(sent, elapsed), nil
+}
+
+// cutShort reports whether a transfer ended because the phase ran out of
+// time after moving some data, which still gives a valid measurement.
+func cutShort(ctx context.Context, n int64) bool {
+	return n > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded)
+}
+
+// countingReader yields remaining zero bytes and counts what was read. The
+// transport may still be reading when the response arrives, so the count is
+// atomic.
+type countingReader struct {
+	remaining int64
+	read      atomic.Int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	if r.remaining <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+	clear(p)
+	r.remaining -= int64(len(p))
+	r.read.Add(int64(len(p)))
+	return len(p), nil
+}
+
+func mbps(n int64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(n) * 8 / d.Seconds() / 1e6
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,117 @@
+package speedtest
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testEndpoint serves /ping, /down?bytes=N and /up, counting uploaded bytes.
+func testEndpoint(t *testing.T) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+	var uploaded atomic.Int64
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ping", func(w http.ResponseWriter, _ *http.Request) {})
+	mux.HandleFunc("/down", func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(r.URL.Query().Get("bytes"))
+		w.Write(make([]byte, n))
+	})
+	mux.HandleFunc("/up", func(w http.ResponseWriter, r *http.Request) {
+		n, _ := io.Copy(io.Discard, r.Body)
+		uploaded.Add(n)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, &uploaded
+}
+
+func endpointOptions(url string) Options {
+	return Options{
+		LatencyURL:   url + "/ping",
+		DownloadURL:  url + "/down?bytes=200000",
+		UploadURL:    url + "/up",
+		UploadBytes:  100000,
+		PhaseTimeout: 5 * time.Second,
+		Pings:        3,
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	srv, uploaded := testEndpoint(t)
+
+	var res Result
+	if err := Measure(context.Background(), srv.Client(), endpointOptions(srv.URL), &res); err != nil {
+		t.Fatalf("Measure error: %v", err)
+	}
+	if res.DownloadBytes != 200000 || res.UploadBytes != 100000 || uploaded.Load() != 100000 {
+		t.Errorf("unexpected byte counts: %+v (server got %d)", res, uploaded.Load())
+	}
+	if res.LatencyMs <= 0 || res.DownloadMbps <= 0 || res.UploadMbps <= 0 {
+		t.Errorf("expected positive measurements, got %+v", res)
+	}
+}
+
+func TestMeasure_DownloadCutShort(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/slow" {
+			return
+		}
+		for {
+			if _, err := w.Write(make([]byte, 1000)); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+			time.Sleep(10 * time.Millisecond)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	n, rate, err := download(context.Background(), srv.Client(), srv.URL+"/slow", 200*time.Millisecond)
+	if err != nil {
+		t.Fatalf("expected a cut-short download to count, got %v", err)
+	}
+	if n == 0 || rate <= 0 {
+		t.Errorf("expected bytes and rate, got %d and %f", n, rate)
+	}
+}
+
+func TestMeasure_EndpointError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	var res Result
+	err := Measure(context.Background(), srv.Client(), endpointOptions(srv.URL), &res)
+	if err == nil || !strings.Contains(err.Error(), "download: HTTP 403") {
+		t.Fatalf("expected download error, got %v", err)
+	}
+	if res.LatencyMs <= 0 {
+		t.Errorf("expected latency to be kept, got %+v", res)
+	}
+}
+
+func TestLatencyStats(t *testing.T) {
+	samples := []time.Duration{20 * time.Millisecond, 30 * time.Millisecond, 10 * time.Millisecond, 40 * time.Millisecond}
+
+	latency, jitter := latencyStats(samples)
+	if latency != 25 {
+		t.Errorf("expected median 25ms, got %f", latency)
+	}
+	// |30-20| + |10-30| + |40-10| = 60 over 3 differences
+	if jitter != 20 {
+		t.Errorf("expected jitter 20ms, got %f", jitter)
+	}
+
+	if l, j := latencyStats([]time.Duration{15 * time.Millisecond}); l != 15 || j != 0 {
+		t.Errorf("single sample: got %f, %f", l, j)
+	}
+}
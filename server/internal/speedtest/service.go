@@ -0,0 +1,214 @@
+package speedtest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/service"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+)
+
+// Job status.
+const (
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+// RouteWAN is the route name of the default route.
+const RouteWAN = "wan"
+
+const (
+	// maxJobs is the number of finished jobs kept for polling.
+	maxJobs = 20
+	// dialTimeout bounds connection setup through the route.
+	dialTimeout = 10 * time.Second
+)
+
+// ErrRunning is returned by Start while another test is running; tests on
+// several routes at once would skew each other.
+var ErrRunning = errors.New("a speed test is already running")
+
+// Tester runs speed tests in the background.
+type Tester interface {
+	// Start begins a test on route ("wan", "xray" or a tunnel) and calls
+	// onDone (if not nil) with the finished job.
+	Start(route string, onDone func(Job)) (Job, error)
+	// Job returns a job started by this process.
+	Job(id string) (Job, bool)
+	// History returns up to limit past results, newest first.
+	History(limit int) ([]Result, error)
+}
+
+// Job is one background test.
+type Job struct {
+	ID         string     `json:"id"`
+	Route      string     `json:"route"`
+	Status     string     `json:"status"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Result     *Result    `json:"result,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// Service runs tests one at a time and records them in the history file.
+type Service struct {
+	config service.ConfigStore
+	now    func() time.Time
+
+	mu      sync.Mutex
+	jobs    map[string]*Job
+	order   []string // job IDs, oldest first
+	running bool
+}
+
+var _ Tester = (*Service)(nil)
+
+// NewService creates a speed test service.
+func NewService(config service.ConfigStore) *Service {
+	return &Service{config: config, now: time.Now, jobs: make(map[string]*Job)}
+}
+
+// ValidRoute reports whether route can be tested: "wan" (or empty), "xray"
+// or a tunnel name.
+func ValidRoute(route string) bool {
+	return route == RouteWAN || service.ValidVia(route)
+}
+
+// Start begins a test on route. It fails with ErrRunning while another test
+// runs.
+func (s *Service) Start(route string, onDone func(Job)) (Job, error) {
+	if route == "" {
+		route = RouteWAN
+	}
+	if !ValidRoute(route) {
+		return Job{}, fmt.Errorf("invalid route %q", route)
+	}
+
+	cfg, err := s.config.LoadVPNConfig()
+	if err != nil {
+		return Job{}, fmt.Errorf("load config: %w", err)
+	}
+	via := route
+	if route == RouteWAN {
+		via = ""
+	}
+	transport, err := service.RouteTransport(via, vpnconfig.XraySocksAddr(cfg), dialTimeout)
+	if err != nil {
+		return Job{}, err
+	}
+	opts := OptionsFromConfig(cfg)
+
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return Job{}, ErrRunning
+	}
+	job := &Job{ID: newJobID(), Route: route, Status: StatusRunning, StartedAt: s.now()}
+	s.running = true
+	s.addJob(job)
+	snapshot := *job
+	s.mu.Unlock()
+
+	go s.run(job, &http.Client{Transport: transport}, opts, onDone)
+	return snapshot, nil
+}
+
+func (s *Service) run(job *Job, client *http.Client, opts Options, onDone func(Job)) {
+	defer client.CloseIdleConnections()
+
+	res := Result{Time: job.StartedAt, Route: job.Route}
+	err := Measure(context.Background(), client, opts, &res)
+	if err != nil {
+		res.Error = err.Error()
+	}
+	if herr := NewHistory(HistoryPath(s.config.DataDirOrDefault())).Add(res); herr != nil {
+		slog.Warn("Failed to save speed test result", "error", herr)
+	}
+
+	s.mu.Lock()
+	finished := s.now()
+	job.FinishedAt = &finished
+	job.Result = &res
+	job.Status = StatusDone
+	if err != nil {
+		job.Status = StatusFailed
+		job.Error = err.Error()
+	}
+	s.running = false
+	snapshot := *job
+	s.mu.Unlock()
+
+	slog.Info("Speed test finished", "route", res.Route, "status", snapshot.Status,
+		"latency_ms", res.LatencyMs, "download_mbps", res.DownloadMbps, "upload_mbps", res.UploadMbps)
+	if onDone != nil {
+		onDone(snapshot)
+	}
+}
+
+// addJob records a job and forgets the oldest beyond maxJobs. Caller holds mu.
+func (s *Service) addJob(job *Job) {
+	s.jobs[job.ID] = job
+	s.order = append(s.order, job.ID)
+	if len(s.order) > maxJobs {
+		delete(s.jobs, s.order[0])
+		s.order = s.order[1:]
+	}
+}
+
+// Job returns a copy of the job with the given ID.
+func (s *Service) Job(id string) (Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// History returns up to limit past results, newest first.
+func (s *Service) History(limit int) ([]Result, error) {
+	return NewHistory(HistoryPath(s.config.DataDirOrDefault())).List(limit)
+}
+
+// OptionsFromConfig reads advanced.speedtest: latency_url, download_url,
+// upload_url, upload_bytes and timeout (seconds per transfer).
+func OptionsFromConfig(cfg *vpnconfig.VPNDirectorConfig) Options {
+	opts := DefaultOptions()
+	sec, ok := cfg.Advanced["speedtest"].(map[string]interface{})
+	if !ok {
+		return opts
+	}
+	if v, ok := sec["latency_url"].(string); ok && v != "" {
+		opts.LatencyURL = v
+	}
+	if v, ok := sec["download_url"].(string); ok && v != "" {
+		opts.DownloadURL = v
+	}
+	if v, ok := sec["upload_url"].(string); ok && v != "" {
+		opts.UploadURL = v
+	}
+	if v, ok := sec["upload_bytes"].(float64); ok && v > 0 {
+		opts.UploadBytes = int64(v)
+	}
+	if v, ok := sec["timeout"].(float64); ok && v > 0 {
+		opts.PhaseTimeout = time.Duration(v * float64(time.Second))
+	}
+	return opts
+}
+
+func newJobID() string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,132 @@
+package speedtest
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/testutil"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+)
+
+func endpointConfig(t *testing.T, url string) *testutil.Config {
+	return &testutil.Config{Dir: t.TempDir(), Cfg: &vpnconfig.VPNDirectorConfig{Advanced: map[string]interface{}{
+		"speedtest": map[string]interface{}{
+			"latency_url":  url + "/ping",
+			"download_url": url + "/down?bytes=100000",
+			"upload_url":   url + "/up",
+			"upload_bytes": float64(50000),
+			"timeout":      float64(5),
+		},
+	}}}
+}
+
+func waitJob(t *testing.T, done <-chan Job) Job {
+	t.Helper()
+	select {
+	case job := <-done:
+		return job
+	case <-time.After(5 * time.Second):
+		t.Fatal("speed test did not finish")
+		return Job{}
+	}
+}
+
+func TestService_Start(t *testing.T) {
+	srv, _ := testEndpoint(t)
+	svc := NewService(endpointConfig(t, srv.URL))
+
+	done := make(chan Job, 1)
+	job, err := svc.Start("", func(j Job) { done <- j })
+	if err != nil {
+		t.Fatalf("Start error: %v", err)
+	}
+	if job.Route != RouteWAN || job.Status != StatusRunning || job.ID == "" {
+		t.Errorf("unexpected job: %+v", job)
+	}
+
+	finished := waitJob(t, done)
+	if finished.Status != StatusDone || finished.Result == nil || finished.Result.DownloadBytes != 100000 || finished.FinishedAt == nil {
+		t.Errorf("unexpected finished job: %+v", finished)
+	}
+
+	polled, ok := svc.Job(job.ID)
+	if !ok || polled.Status != StatusDone {
+		t.Errorf("expected polled job to be done, got %+v", polled)
+	}
+
+	history, err := svc.History(10)
+	if err != nil || len(history) != 1 || history[0].Route != RouteWAN || history[0].UploadBytes != 50000 {
+		t.Errorf("unexpected history: %+v, %v", history, err)
+	}
+}
+
+func TestService_StartWhileRunning(t *testing.T) {
+	release := make(chan struct{})
+	srv, _ := testEndpoint(t)
+	blocking := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { <-release })
+	cfg := endpointConfig(t, srv.URL)
+	slow := startServer(t, blocking)
+	cfg.Cfg.Advanced["speedtest"].(map[string]interface{})["latency_url"] = slow
+
+	svc := NewService(cfg)
+	done := make(chan Job, 1)
+	if _, err := svc.Start("wan", func(j Job) { done <- j }); err != nil {
+		t.Fatalf("Start error: %v", err)
+	}
+	if _, err := svc.Start("wan", nil); !errors.Is(err, ErrRunning) {
+		t.Errorf("expected ErrRunning, got %v", err)
+	}
+
+	close(release)
+	waitJob(t, done)
+	if _, err := svc.Start("wan", func(j Job) { done <- j }); err != nil {
+		t.Errorf("expected a new test to start after the first finished, got %v", err)
+	}
+	waitJob(t, done)
+}
+
+func TestService_Failed(t *testing.T) {
+	cfg := endpointConfig(t, "http://127.0.0.1:1")
+	svc := NewService(cfg)
+
+	done := make(chan Job, 1)
+	if _, err := svc.Start("wan", func(j Job) { done <- j }); err != nil {
+		t.Fatalf("Start error: %v", err)
+	}
+	job := waitJob(t, done)
+	if job.Status != StatusFailed || job.Error == "" {
+		t.Errorf("expected failed job, got %+v", job)
+	}
+
+	history, _ := svc.History(0)
+	if len(history) != 1 || history[0].Error == "" {
+		t.Errorf("expected the failure in history, got %+v", history)
+	}
+}
+
+func TestService_InvalidRoute(t *testing.T) {
+	svc := NewService(&testutil.Config{Cfg: &vpnconfig.VPNDirectorConfig{}, Dir: t.TempDir()})
+	if _, err := svc.Start("wgc1; reboot", nil); err == nil {
+		t.Error("expected error for invalid route")
+	}
+	if _, ok := svc.Job("missing"); ok {
+		t.Error("expected unknown job")
+	}
+}
+
+func TestOptionsFromConfig_Defaults(t *testing.T) {
+	opts := OptionsFromConfig(&vpnconfig.VPNDirectorConfig{})
+	if opts != DefaultOptions() {
+		t.Errorf("expected defaults, got %+v", opts)
+	}
+}
+
+func startServer(t *testing.T, h http.Handler) string {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
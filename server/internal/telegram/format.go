@@ -4,6 +4,8 @@ package telegram
 import (
 	"fmt"
 	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
 // MaxMessageLength is the maximum length for a Telegram message
@@ -54,3 +56,15 @@ func BuildCodeBlockMessage(header, content string, maxLen int) string {
 
 	return fmt.Sprintf("%s\n```\n%s```", header, content)
 }
+
+// UserRef names a Telegram user in the ban list and the audit log:
+// "@username", or "id:<user ID>" for users without a username.
+func UserRef(u *tgbotapi.User) string {
+	switch {
+	case u == nil:
+		return ""
+	case u.UserName != "":
+		return "@" + u.UserName
+	}
+	return fmt.Sprintf("id:%d", u.ID)
+}
@@ -3,6 +3,8 @@ package telegram
 import (
 	"strings"
 	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
 func TestEscapeMarkdownV2(t *testing.T) {
@@ -81,3 +83,15 @@ func TestBuildCodeBlockMessage(t *testing.T) {
 		})
 	}
 }
+
+func TestUserRef(t *testing.T) {
+	if got := UserRef(&tgbotapi.User{ID: 42, UserName: "stranger"}); got != "@stranger" {
+		t.Errorf("expected @stranger, got %q", got)
+	}
+	if got := UserRef(&tgbotapi.User{ID: 42}); got != "id:42" {
+		t.Errorf("expected id:42, got %q", got)
+	}
+	if got := UserRef(nil); got != "" {
+		t.Errorf("expected empty for no user, got %q", got)
+	}
+}
@@ -0,0 +1,21 @@
+// Package testutil holds fakes shared by tests of several packages.
+package testutil
+
+import "github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+
+// Config is a service.ConfigStore that returns Cfg (or Err) and uses Dir as
+// the data directory. Saves are discarded and the lock is a no-op.
+type Config struct {
+	Cfg *vpnconfig.VPNDirectorConfig
+	Err error
+	Dir string
+}
+
+func (c *Config) LoadVPNConfig() (*vpnconfig.VPNDirectorConfig, error) { return c.Cfg, c.Err }
+func (c *Config) LoadServers() ([]vpnconfig.Server, error)             { return nil, nil }
+func (c *Config) SaveVPNConfig(*vpnconfig.VPNDirectorConfig) error     { return nil }
+func (c *Config) SaveServers([]vpnconfig.Server) error                 { return nil }
+func (c *Config) DataDir() (string, error)                             { return c.Dir, nil }
+func (c *Config) DataDirOrDefault() string                             { return c.Dir }
+func (c *Config) ScriptsDir() string                                   { return "/scripts" }
+func (c *Config) LockConfig() (func(), error)                          { return func() {}, nil }
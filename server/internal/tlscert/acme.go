@@ -0,0 +1,137 @@
+package tlscert
+
+import (
+	"crypto/tls"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+)
+
+// NewACME returns a manager that obtains and renews certificates for
+// cfg.Domains from the ACME directory at cfg.DirectoryURL (Let's Encrypt
+// when empty). Challenges are answered with tls-alpn-01 on the Web UI
+// port. Account keys and certificates are cached in cacheDir.
+func NewACME(cfg vpnconfig.ACMEConfig, cacheDir string) *autocert.Manager {
+	directory := cfg.DirectoryURL
+	if directory == "" {
+		directory = autocert.DefaultACMEDirectory
+	}
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(cacheDir),
+		HostPolicy: autocert.HostWhitelist(normalizeHosts(cfg.Domains)...),
+		Email:      cfg.Email,
+		Client:     &acme.Client{DirectoryURL: directory},
+	}
+}
+
+// acmeRetryInterval is how long to wait before asking the ACME directory
+// again after a failed issuance.
+const acmeRetryInterval = time.Hour
+
+// ServerTLS returns the Web UI TLS configuration. Certificates come from
+// r; when acm is set, names in domains are served by ACME instead. ACME
+// certificates are obtained in the background so handshakes never wait for
+// the directory; until one is ready the file certificate is served.
+func ServerTLS(r *Reloader, acm *autocert.Manager, domains []string) *tls.Config {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12, GetCertificate: r.GetCertificate}
+	if acm == nil {
+		return cfg
+	}
+
+	src := &acmeSource{
+		acm:     acm,
+		domains: normalizeHosts(domains),
+		ready:   make(map[string]bool),
+		pending: make(map[string]bool),
+		tried:   make(map[string]time.Time),
+		now:     time.Now,
+	}
+	cfg.NextProtos = []string{"h2", "http/1.1", acme.ALPNProto}
+	cfg.GetCertificate = func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		name := strings.ToLower(hello.ServerName)
+		if !slices.Contains(src.domains, name) {
+			return r.GetCertificate(hello)
+		}
+		if isChallenge(hello) {
+			return acm.GetCertificate(hello)
+		}
+		if cert := src.certificate(name, hello); cert != nil {
+			return cert, nil
+		}
+		return r.GetCertificate(hello)
+	}
+	return cfg
+}
+
+// acmeSource tracks which ACME names already have a certificate.
+type acmeSource struct {
+	acm     *autocert.Manager
+	domains []string
+
+	mu      sync.Mutex
+	ready   map[string]bool
+	pending map[string]bool
+	tried   map[string]time.Time
+	now     func() time.Time
+}
+
+// certificate returns the ACME certificate for name, or nil when there is
+// none yet. In that case issuance is started in the background unless it
+// is already running or failed less than acmeRetryInterval ago.
+func (s *acmeSource) certificate(name string, hello *tls.ClientHelloInfo) *tls.Certificate {
+	s.mu.Lock()
+	ready := s.ready[name]
+	start := !ready && !s.pending[name] && s.now().Sub(s.tried[name]) >= acmeRetryInterval
+	if start {
+		s.pending[name] = true
+		s.tried[name] = s.now()
+	}
+	s.mu.Unlock()
+
+	if ready {
+		cert, err := s.acm.GetCertificate(hello)
+		if err == nil {
+			return cert
+		}
+		slog.Warn("ACME certificate unavailable, serving the file certificate", "name", name, "error", err)
+	}
+	if start {
+		go s.obtain(name)
+	}
+	return nil
+}
+
+// obtain loads or requests the certificate for name.
+func (s *acmeSource) obtain(name string) {
+	hello := &tls.ClientHelloInfo{
+		ServerName:       name,
+		CipherSuites:     []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+		SignatureSchemes: []tls.SignatureScheme{tls.ECDSAWithP256AndSHA256},
+		SupportedCurves:  []tls.CurveID{tls.CurveP256},
+	}
+	_, err := s.acm.GetCertificate(hello)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending[name] = false
+	if err != nil {
+		slog.Warn("ACME certificate request failed", "name", name, "error", err)
+		return
+	}
+	s.ready[name] = true
+	slog.Info("ACME certificate ready", "name", name)
+}
+
+// isChallenge reports whether hello comes from an ACME tls-alpn-01
+// validation.
+func isChallenge(hello *tls.ClientHelloInfo) bool {
+	return len(hello.SupportedProtos) == 1 && hello.SupportedProtos[0] == acme.ALPNProto
+}
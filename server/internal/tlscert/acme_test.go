@@ -0,0 +1,127 @@
+package tlscert
+
+import (
+	"bytes"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/acme"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+)
+
+func TestServerTLS_FileOnly(t *testing.T) {
+	certFile, keyFile := certPaths(t)
+	if _, err := Generate(certFile, keyFile, []string{"router.asus.com"}); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := ServerTLS(r, nil, nil)
+	if cfg.MinVersion != tls.VersionTLS12 || slices.Contains(cfg.NextProtos, acme.ALPNProto) {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+	if cert, err := cfg.GetCertificate(&tls.ClientHelloInfo{ServerName: "router.asus.com"}); err != nil || cert == nil {
+		t.Errorf("expected the file certificate, got %v", err)
+	}
+}
+
+func TestServerTLS_ACMEFallsBack(t *testing.T) {
+	certFile, keyFile := certPaths(t)
+	if _, err := Generate(certFile, keyFile, []string{"router.asus.com"}); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileCert, _ := r.GetCertificate(nil)
+
+	// An ACME directory that is down
+	ca := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "down", http.StatusInternalServerError)
+	}))
+	defer ca.Close()
+	acmeCfg := vpnconfig.ACMEConfig{DirectoryURL: ca.URL, Domains: []string{"VPN.example.com"}}
+	acm := NewACME(acmeCfg, t.TempDir())
+	acm.Client.RetryBackoff = func(int, *http.Request, *http.Response) time.Duration { return -1 }
+	cfg := ServerTLS(r, acm, acmeCfg.Domains)
+
+	if !slices.Contains(cfg.NextProtos, acme.ALPNProto) {
+		t.Errorf("expected the tls-alpn-01 protocol, got %v", cfg.NextProtos)
+	}
+	for _, name := range []string{"192.168.50.1", "vpn.example.com"} {
+		cert, err := cfg.GetCertificate(&tls.ClientHelloInfo{ServerName: name})
+		if err != nil || !bytes.Equal(cert.Certificate[0], fileCert.Certificate[0]) {
+			t.Errorf("%s: expected the file certificate, got %v", name, err)
+		}
+	}
+
+	challenge := &tls.ClientHelloInfo{ServerName: "vpn.example.com", SupportedProtos: []string{acme.ALPNProto}}
+	if _, err := cfg.GetCertificate(challenge); err == nil {
+		t.Error("expected challenges not to fall back")
+	}
+}
+
+func TestACMESource_RetriesAfterInterval(t *testing.T) {
+	ca := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "down", http.StatusInternalServerError)
+	}))
+	defer ca.Close()
+	acm := NewACME(vpnconfig.ACMEConfig{DirectoryURL: ca.URL, Domains: []string{"vpn.example.com"}}, t.TempDir())
+	acm.Client.RetryBackoff = func(int, *http.Request, *http.Response) time.Duration { return -1 }
+
+	start := time.Now()
+	now := start
+	src := &acmeSource{
+		acm:     acm,
+		domains: []string{"vpn.example.com"},
+		ready:   make(map[string]bool),
+		pending: make(map[string]bool),
+		tried:   make(map[string]time.Time),
+		now:     func() time.Time { return now },
+	}
+	hello := &tls.ClientHelloInfo{ServerName: "vpn.example.com"}
+	// lastTry waits for the running attempt and returns when it started
+	lastTry := func() time.Time {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			src.mu.Lock()
+			pending, tried := src.pending["vpn.example.com"], src.tried["vpn.example.com"]
+			src.mu.Unlock()
+			if !pending {
+				return tried
+			}
+		}
+		t.Fatal("ACME attempt did not finish")
+		return time.Time{}
+	}
+
+	if cert := src.certificate("vpn.example.com", hello); cert != nil {
+		t.Fatal("expected no certificate yet")
+	}
+	if !lastTry().Equal(start) {
+		t.Fatal("expected an issuance attempt")
+	}
+
+	now = start.Add(time.Minute)
+	src.certificate("vpn.example.com", hello)
+	if !lastTry().Equal(start) {
+		t.Error("expected no new attempt within the retry interval")
+	}
+	now = start.Add(acmeRetryInterval)
+	src.certificate("vpn.example.com", hello)
+	if !lastTry().Equal(now) {
+		t.Error("expected a new attempt after the retry interval")
+	}
+	if src.ready["vpn.example.com"] {
+		t.Error("expected the name not to be ready")
+	}
+}
@@ -0,0 +1,209 @@
+package tlscert
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Local CA files, kept next to the server certificate. The CA is reused
+// when the server certificate is regenerated, so browsers that trust it
+// accept the new certificate.
+const (
+	CAFile    = "ca.crt"
+	caKeyFile = "ca.key"
+)
+
+const (
+	caValidity   = 10 * 365 * 24 * time.Hour
+	certValidity = 825 * 24 * time.Hour // the most Apple platforms accept
+	maxHostLen   = 253
+)
+
+// localCA is the generated certificate authority.
+type localCA struct {
+	cert    *x509.Certificate
+	certPEM []byte
+	key     crypto.Signer
+}
+
+// Generate issues a server certificate for hosts (DNS names or IPs) from
+// the local CA, creating the CA on first use, and installs it.
+func Generate(certFile, keyFile string, hosts []string) (*Info, error) {
+	hosts = normalizeHosts(hosts)
+	if err := ValidateHosts(hosts); err != nil {
+		return nil, err
+	}
+	tmpl := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "vpn-director"},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+			continue
+		}
+		tmpl.DNSNames = append(tmpl.DNSNames, h)
+	}
+
+	ca, err := loadCA(filepath.Dir(certFile))
+	if errors.Is(err, os.ErrNotExist) {
+		ca, err = createCA(filepath.Dir(certFile))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate key: %w", err)
+	}
+	certPEM, err := sign(tmpl, certValidity, &key.PublicKey, ca.cert, ca.key)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := encodeKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return Install(certFile, keyFile, certPEM, keyPEM)
+}
+
+// ValidateHosts checks that hosts holds at least one name and that each is
+// an IP address or a valid DNS name.
+func ValidateHosts(hosts []string) error {
+	hosts = normalizeHosts(hosts)
+	if len(hosts) == 0 {
+		return errors.New("at least one host name or IP is required")
+	}
+	for _, h := range hosts {
+		if net.ParseIP(h) == nil && (len(h) > maxHostLen || !validHostname(h)) {
+			return fmt.Errorf("invalid host name %q", h)
+		}
+	}
+	return nil
+}
+
+// CA returns the local CA certificate in PEM form, for importing into
+// browsers.
+func CA(certFile string) ([]byte, error) {
+	ca, err := loadCA(filepath.Dir(certFile))
+	if err != nil {
+		return nil, err
+	}
+	return ca.certPEM, nil
+}
+
+func loadCA(dir string) (*localCA, error) {
+	certPEM, err := os.ReadFile(filepath.Join(dir, CAFile))
+	if err != nil {
+		return nil, err
+	}
+	cert, err := parseCertificate(certPEM)
+	if err != nil {
+		return nil, fmt.Errorf("local CA: %w", err)
+	}
+	keyPEM, err := os.ReadFile(filepath.Join(dir, caKeyFile))
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("local CA: no PEM key found")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("local CA: parse key: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("local CA: unsupported key")
+	}
+	return &localCA{cert: cert, certPEM: certPEM, key: signer}, nil
+}
+
+func createCA(dir string) (*localCA, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate CA key: %w", err)
+	}
+	tmpl := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "VPN Director Local CA"},
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	certPEM, err := sign(tmpl, caValidity, &key.PublicKey, nil, key)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := encodeKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFile(filepath.Join(dir, caKeyFile), keyPEM, 0600); err != nil {
+		return nil, fmt.Errorf("write CA key: %w", err)
+	}
+	if err := writeFile(filepath.Join(dir, CAFile), certPEM, 0644); err != nil {
+		return nil, fmt.Errorf("write CA certificate: %w", err)
+	}
+	return loadCA(dir)
+}
+
+// sign creates a certificate from tmpl, self-signed when parent is nil.
+func sign(tmpl *x509.Certificate, validity time.Duration, pub crypto.PublicKey, parent *x509.Certificate, key crypto.Signer) ([]byte, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("generate serial: %w", err)
+	}
+	now := time.Now()
+	tmpl.SerialNumber = serial
+	tmpl.NotBefore = now.Add(-time.Hour) // tolerate clock skew
+	tmpl.NotAfter = now.Add(validity)
+	if parent == nil {
+		parent = tmpl
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, pub, key)
+	if err != nil {
+		return nil, fmt.Errorf("create certificate: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+}
+
+func encodeKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("encode key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// validHostname accepts letters, digits, hyphens and dots, with an
+// optional leading "*." wildcard.
+func validHostname(h string) bool {
+	if len(h) > 2 && h[:2] == "*." {
+		h = h[2:]
+	}
+	if h == "" || h[0] == '.' || h[len(h)-1] == '.' {
+		return false
+	}
+	for _, c := range h {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,84 @@
+package tlscert
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	certFile, keyFile := certPaths(t)
+
+	info, err := Generate(certFile, keyFile, []string{"Router.Asus.com", "192.168.50.1", "router.asus.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.LocalCA || !slices.Equal(info.DNSNames, []string{"router.asus.com"}) || !slices.Equal(info.IPAddresses, []string{"192.168.50.1"}) {
+		t.Errorf("unexpected info: %+v", info)
+	}
+
+	// The certificate chains to the local CA for every name
+	caPEM, err := CA(certFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		t.Fatal("CA certificate not parsed")
+	}
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, _ := x509.ParseCertificate(pair.Certificate[0])
+	for _, name := range []string{"router.asus.com", "192.168.50.1"} {
+		if _, err := leaf.Verify(x509.VerifyOptions{DNSName: name, Roots: pool}); err != nil {
+			t.Errorf("verify %s: %v", name, err)
+		}
+	}
+
+	if st, _ := os.Stat(filepath.Join(filepath.Dir(certFile), caKeyFile)); st.Mode().Perm() != 0600 {
+		t.Errorf("expected CA key mode 0600, got %v", st.Mode().Perm())
+	}
+}
+
+func TestGenerate_ReusesCA(t *testing.T) {
+	certFile, keyFile := certPaths(t)
+
+	first, err := Generate(certFile, keyFile, []string{"router.asus.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	caBefore, _ := CA(certFile)
+
+	second, err := Generate(certFile, keyFile, []string{"router.asus.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	caAfter, _ := CA(certFile)
+	if string(caBefore) != string(caAfter) {
+		t.Error("expected the CA to be kept")
+	}
+	if first.Fingerprint == second.Fingerprint || !second.LocalCA {
+		t.Errorf("expected a new certificate from the same CA, got %+v", second)
+	}
+}
+
+func TestGenerate_InvalidHosts(t *testing.T) {
+	for _, hosts := range [][]string{nil, {" "}, {"bad host"}, {"-x.example/"}, {"router..local."}} {
+		certFile, keyFile := certPaths(t)
+		if _, err := Generate(certFile, keyFile, hosts); err == nil {
+			t.Errorf("%q: expected an error", hosts)
+		}
+	}
+}
+
+func TestCA_Missing(t *testing.T) {
+	certFile, _ := certPaths(t)
+	if _, err := CA(certFile); !os.IsNotExist(err) {
+		t.Errorf("expected not-exist error, got %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package tlscert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/service"
+)
+
+// DefaultCheckInterval is how often the monitor checks the certificate.
+const DefaultCheckInterval = 12 * time.Hour
+
+// warnDays are the days before expiry at which users are warned; 0 means
+// expired.
+var warnDays = []int{30, 14, 7, 3, 1, 0}
+
+// Notifier delivers warnings to users.
+type Notifier interface {
+	Broadcast(ctx context.Context, text string)
+}
+
+// Monitor warns bot users before the Web UI certificate expires. Each
+// threshold in warnDays is reported once per certificate.
+type Monitor struct {
+	config   service.ConfigStore
+	notifier Notifier
+	now      func() time.Time
+	warned   map[string]int // fingerprint -> last threshold reported
+}
+
+// NewMonitor creates a monitor for the certificate named by webui.cert_file.
+func NewMonitor(config service.ConfigStore, notifier Notifier) *Monitor {
+	return &Monitor{config: config, notifier: notifier, now: time.Now, warned: make(map[string]int)}
+}
+
+// Run checks now and then every interval. Blocks until ctx is cancelled.
+func (m *Monitor) Run(ctx context.Context, interval time.Duration) {
+	slog.Info("Certificate monitor started", "interval", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	m.check(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.check(ctx)
+		}
+	}
+}
+
+func (m *Monitor) check(ctx context.Context) {
+	certFile := DefaultCertFile
+	if cfg, err := m.config.LoadVPNConfig(); err == nil && cfg != nil && cfg.WebUI.CertFile != "" {
+		certFile = cfg.WebUI.CertFile
+	}
+
+	info, err := ReadInfo(certFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return // Web UI not installed
+	}
+	if err != nil {
+		slog.Warn("Failed to check TLS certificate", "file", certFile, "error", err)
+		return
+	}
+
+	left := info.NotAfter.Sub(m.now())
+	threshold := -1
+	for _, t := range warnDays {
+		if left <= time.Duration(t)*24*time.Hour {
+			threshold = t
+		}
+	}
+	if threshold < 0 {
+		return
+	}
+	if last, ok := m.warned[info.Fingerprint]; ok && last <= threshold {
+		return
+	}
+	m.warned[info.Fingerprint] = threshold
+
+	date := info.NotAfter.Local().Format(time.DateOnly)
+	var text string
+	switch days := int(math.Ceil(left.Hours() / 24)); {
+	case left <= 0:
+		text = fmt.Sprintf("⚠️ The Web UI TLS certificate expired on %s. Browsers will refuse it: generate or upload a new one on the Settings tab.", date)
+	case days == 1:
+		text = fmt.Sprintf("⚠️ The Web UI TLS certificate expires within a day, on %s. Generate or upload a new one on the Settings tab.", date)
+	default:
+		text = fmt.Sprintf("⚠️ The Web UI TLS certificate expires in %d days, on %s. Generate or upload a new one on the Settings tab.", days, date)
+	}
+	slog.Info("TLS certificate expiring", "file", certFile, "not_after", info.NotAfter)
+	m.notifier.Broadcast(ctx, text)
+}
@@ -0,0 +1,71 @@
+package tlscert
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/testutil"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+)
+
+type mockNotifier struct {
+	sent []string
+}
+
+func (m *mockNotifier) Broadcast(_ context.Context, text string) { m.sent = append(m.sent, text) }
+
+func newTestMonitor(t *testing.T, validFor time.Duration) (*Monitor, *mockNotifier, *time.Time) {
+	t.Helper()
+	certFile, keyFile := certPaths(t)
+	certPEM, keyPEM := selfSigned(t, time.Now().Add(-time.Hour), time.Now().Add(validFor))
+	if _, err := Install(certFile, keyFile, certPEM, keyPEM); err != nil {
+		t.Fatal(err)
+	}
+	n := &mockNotifier{}
+	m := NewMonitor(&testutil.Config{Cfg: &vpnconfig.VPNDirectorConfig{WebUI: vpnconfig.WebUIConfig{CertFile: certFile}}}, n)
+	now := time.Now()
+	m.now = func() time.Time { return now }
+	return m, n, &now
+}
+
+func TestMonitor_WarnsOncePerThreshold(t *testing.T) {
+	m, n, now := newTestMonitor(t, 20*24*time.Hour)
+
+	m.check(context.Background())
+	m.check(context.Background())
+	if len(n.sent) != 1 || !strings.Contains(n.sent[0], "expires in 20 days") {
+		t.Fatalf("expected one warning, got %q", n.sent)
+	}
+
+	*now = now.Add(15 * 24 * time.Hour)
+	m.check(context.Background())
+	if len(n.sent) != 2 || !strings.Contains(n.sent[1], "expires in 5 days") {
+		t.Fatalf("expected a warning at the 7-day threshold, got %q", n.sent)
+	}
+
+	*now = now.Add(5*24*time.Hour + time.Minute)
+	m.check(context.Background())
+	m.check(context.Background())
+	if len(n.sent) != 3 || !strings.Contains(n.sent[2], "expired") {
+		t.Fatalf("expected one expiry warning, got %q", n.sent)
+	}
+}
+
+func TestMonitor_QuietWhenValid(t *testing.T) {
+	m, n, _ := newTestMonitor(t, 90*24*time.Hour)
+	m.check(context.Background())
+	if len(n.sent) != 0 {
+		t.Errorf("expected no warning, got %q", n.sent)
+	}
+}
+
+func TestMonitor_MissingCertificate(t *testing.T) {
+	n := &mockNotifier{}
+	m := NewMonitor(&testutil.Config{Cfg: &vpnconfig.VPNDirectorConfig{WebUI: vpnconfig.WebUIConfig{CertFile: t.TempDir() + "/none.crt"}}}, n)
+	m.check(context.Background())
+	if len(n.sent) != 0 {
+		t.Errorf("expected no warning, got %q", n.sent)
+	}
+}
@@ -0,0 +1,85 @@
+package tlscert
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// reloadInterval limits how often the certificate files are checked for
+// changes during handshakes.
+const reloadInterval = 10 * time.Second
+
+// Reloader serves a certificate from files and picks up replacements
+// without a restart. A pair that fails to load is logged and the previous
+// certificate is kept.
+type Reloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	certMod time.Time
+	keyMod  time.Time
+	checked time.Time
+	now     func() time.Time
+}
+
+// NewReloader loads the certificate pair. It fails if the pair cannot be
+// loaded, so the server does not start without a certificate.
+func NewReloader(certFile, keyFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile, now: time.Now}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now := r.now(); now.Sub(r.checked) >= reloadInterval {
+		r.checked = now
+		if err := r.load(false); err != nil {
+			slog.Warn("Failed to reload TLS certificate, keeping the current one", "error", err)
+		}
+	}
+	return r.cert, nil
+}
+
+// Reload loads the pair now, e.g. right after it was replaced.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checked = r.now()
+	return r.load(true)
+}
+
+// load reads the pair if forced or if either file changed.
+func (r *Reloader) load(force bool) error {
+	certStat, err := os.Stat(r.certFile)
+	if err != nil {
+		return fmt.Errorf("stat certificate: %w", err)
+	}
+	keyStat, err := os.Stat(r.keyFile)
+	if err != nil {
+		return fmt.Errorf("stat key: %w", err)
+	}
+	if !force && r.cert != nil && certStat.ModTime().Equal(r.certMod) && keyStat.ModTime().Equal(r.keyMod) {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load certificate: %w", err)
+	}
+	r.cert = &cert
+	r.certMod, r.keyMod = certStat.ModTime(), keyStat.ModTime()
+	slog.Info("Loaded TLS certificate", "file", r.certFile)
+	return nil
+}
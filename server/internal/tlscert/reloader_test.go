@@ -0,0 +1,75 @@
+package tlscert
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+)
+
+func serving(t *testing.T, r *Reloader) []byte {
+	t.Helper()
+	cert, err := r.GetCertificate(nil)
+	if err != nil || cert == nil {
+		t.Fatalf("GetCertificate: %v", err)
+	}
+	return cert.Certificate[0]
+}
+
+func TestReloader_PicksUpChanges(t *testing.T) {
+	certFile, keyFile := certPaths(t)
+	if _, err := Generate(certFile, keyFile, []string{"router.asus.com"}); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	r.now = func() time.Time { return now }
+	before := serving(t, r)
+
+	if _, err := Generate(certFile, keyFile, []string{"router.asus.com"}); err != nil {
+		t.Fatal(err)
+	}
+	// Make sure the change is visible even on coarse file timestamps
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, later, later)
+
+	if got := serving(t, r); !bytes.Equal(got, before) {
+		t.Error("expected files to be checked at most every reloadInterval")
+	}
+	now = now.Add(reloadInterval)
+	if got := serving(t, r); bytes.Equal(got, before) {
+		t.Error("expected the new certificate after the interval")
+	}
+}
+
+func TestReloader_KeepsCertificateOnError(t *testing.T) {
+	certFile, keyFile := certPaths(t)
+	if _, err := Generate(certFile, keyFile, []string{"router.asus.com"}); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := serving(t, r)
+
+	if err := os.WriteFile(certFile, []byte("garbage"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reload(); err == nil {
+		t.Error("expected a load error")
+	}
+	if got := serving(t, r); !bytes.Equal(got, before) {
+		t.Error("expected the previous certificate to be served")
+	}
+}
+
+func TestNewReloader_Missing(t *testing.T) {
+	certFile, keyFile := certPaths(t)
+	if _, err := NewReloader(certFile, keyFile); err == nil {
+		t.Error("expected an error without certificate files")
+	}
+}
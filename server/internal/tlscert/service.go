@@ -0,0 +1,70 @@
+package tlscert
+
+import (
+	"fmt"
+)
+
+// Compile-time interface check
+var _ Manager = (*Service)(nil)
+
+// Manager reads, generates and installs the Web UI certificate.
+type Manager interface {
+	Info() (*Info, error)
+	CA() ([]byte, error)
+	Generate(hosts []string) (*Info, error)
+	Install(certPEM, keyPEM []byte) (*Info, error)
+}
+
+// Service manages the certificate files served by a Reloader.
+type Service struct {
+	certFile string
+	keyFile  string
+	reloader *Reloader // nil when not serving TLS (dev mode)
+}
+
+// NewService creates a new Service. reloader may be nil.
+func NewService(certFile, keyFile string, reloader *Reloader) *Service {
+	return &Service{certFile: certFile, keyFile: keyFile, reloader: reloader}
+}
+
+// Info describes the current certificate.
+func (s *Service) Info() (*Info, error) {
+	return ReadInfo(s.certFile)
+}
+
+// CA returns the local CA certificate.
+func (s *Service) CA() ([]byte, error) {
+	return CA(s.certFile)
+}
+
+// Generate issues a certificate from the local CA for hosts, or for
+// DefaultHosts when empty, and starts serving it.
+func (s *Service) Generate(hosts []string) (*Info, error) {
+	if len(hosts) == 0 {
+		hosts = DefaultHosts()
+	}
+	info, err := Generate(s.certFile, s.keyFile, hosts)
+	if err != nil {
+		return nil, err
+	}
+	return info, s.reload()
+}
+
+// Install replaces the certificate and starts serving it.
+func (s *Service) Install(certPEM, keyPEM []byte) (*Info, error) {
+	info, err := Install(s.certFile, s.keyFile, certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	return info, s.reload()
+}
+
+func (s *Service) reload() error {
+	if s.reloader == nil {
+		return nil
+	}
+	if err := s.reloader.Reload(); err != nil {
+		return fmt.Errorf("certificate saved but not loaded: %w", err)
+	}
+	return nil
+}
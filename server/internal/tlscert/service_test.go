@@ -0,0 +1,51 @@
+package tlscert
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestService_GenerateAndInstall(t *testing.T) {
+	certFile, keyFile := certPaths(t)
+	if _, err := Generate(certFile, keyFile, []string{"router.asus.com"}); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := NewService(certFile, keyFile, r)
+
+	// No hosts: the router's own names
+	info, err := svc.Generate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.DNSNames) == 0 || len(info.IPAddresses) == 0 {
+		t.Errorf("expected default hosts, got %+v", info)
+	}
+	served, _ := r.GetCertificate(nil)
+	if current, _ := svc.Info(); current.Fingerprint != info.Fingerprint {
+		t.Errorf("expected Info to describe the new certificate")
+	}
+
+	certPEM, keyPEM := selfSigned(t, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	if _, err := svc.Install(certPEM, keyPEM); err != nil {
+		t.Fatal(err)
+	}
+	if now, _ := r.GetCertificate(nil); bytes.Equal(now.Certificate[0], served.Certificate[0]) {
+		t.Error("expected the uploaded certificate to be served at once")
+	}
+	if _, err := svc.CA(); err != nil {
+		t.Errorf("expected the local CA to be kept, got %v", err)
+	}
+}
+
+func TestService_WithoutReloader(t *testing.T) {
+	certFile, keyFile := certPaths(t)
+	svc := NewService(certFile, keyFile, nil)
+	if _, err := svc.Generate([]string{"localhost"}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,168 @@
+// Package tlscert manages the Web UI's TLS certificate: generating a local
+// CA and server certificate, installing uploaded certificates, serving them
+// with hot reload, warning before expiry and, optionally, obtaining them
+// over ACME.
+package tlscert
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Default certificate locations, used when webui.cert_file and
+// webui.key_file are not set.
+const (
+	DefaultCertFile = "/opt/vpn-director/certs/server.crt"
+	DefaultKeyFile  = "/opt/vpn-director/certs/server.key"
+)
+
+// maxPEMSize bounds uploaded certificates and keys.
+const maxPEMSize = 64 << 10
+
+// Info describes a certificate.
+type Info struct {
+	Subject     string    `json:"subject"`
+	Issuer      string    `json:"issuer"`
+	DNSNames    []string  `json:"dns_names"`
+	IPAddresses []string  `json:"ip_addresses"`
+	NotBefore   time.Time `json:"not_before"`
+	NotAfter    time.Time `json:"not_after"`
+	Fingerprint string    `json:"fingerprint"` // SHA-256 of the DER, hex
+	LocalCA     bool      `json:"local_ca"`    // issued by the generated CA
+}
+
+// newInfo describes cert. ca is the local CA certificate, or nil.
+func newInfo(cert, ca *x509.Certificate) *Info {
+	sum := sha256.Sum256(cert.Raw)
+	info := &Info{
+		Subject:     cert.Subject.String(),
+		Issuer:      cert.Issuer.String(),
+		DNSNames:    append([]string{}, cert.DNSNames...),
+		IPAddresses: []string{},
+		NotBefore:   cert.NotBefore.UTC(),
+		NotAfter:    cert.NotAfter.UTC(),
+		Fingerprint: hex.EncodeToString(sum[:]),
+	}
+	for _, ip := range cert.IPAddresses {
+		info.IPAddresses = append(info.IPAddresses, ip.String())
+	}
+	info.LocalCA = ca != nil && cert.CheckSignatureFrom(ca) == nil
+	return info
+}
+
+// ReadInfo describes the first certificate in certFile.
+func ReadInfo(certFile string) (*Info, error) {
+	raw, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, fmt.Errorf("read certificate: %w", err)
+	}
+	cert, err := parseCertificate(raw)
+	if err != nil {
+		return nil, err
+	}
+	ca, _ := loadCA(filepath.Dir(certFile))
+	var caCert *x509.Certificate
+	if ca != nil {
+		caCert = ca.cert
+	}
+	return newInfo(cert, caCert), nil
+}
+
+// Install checks that certPEM and keyPEM form a valid, unexpired pair and
+// writes them to certFile and keyFile. Each file is replaced atomically;
+// the key is only readable by the owner.
+func Install(certFile, keyFile string, certPEM, keyPEM []byte) (*Info, error) {
+	if err := Validate(certPEM, keyPEM); err != nil {
+		return nil, err
+	}
+	if err := writeFile(keyFile, keyPEM, 0600); err != nil {
+		return nil, fmt.Errorf("write key: %w", err)
+	}
+	if err := writeFile(certFile, certPEM, 0644); err != nil {
+		return nil, fmt.Errorf("write certificate: %w", err)
+	}
+	return ReadInfo(certFile)
+}
+
+// Validate checks that certPEM and keyPEM form a valid, unexpired pair.
+func Validate(certPEM, keyPEM []byte) error {
+	if len(certPEM) > maxPEMSize || len(keyPEM) > maxPEMSize {
+		return errors.New("certificate or key too large")
+	}
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return fmt.Errorf("invalid certificate or key: %w", err)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("parse certificate: %w", err)
+	}
+	if time.Now().After(cert.NotAfter) {
+		return fmt.Errorf("certificate expired on %s", cert.NotAfter.Format(time.DateOnly))
+	}
+	return nil
+}
+
+// DefaultHosts returns the names the router is reached by on the LAN: its
+// hostname, its private IPv4 addresses, router.asus.com and localhost.
+func DefaultHosts() []string {
+	hosts := []string{}
+	if name, err := os.Hostname(); err == nil && name != "" {
+		hosts = append(hosts, name)
+	}
+	hosts = append(hosts, "router.asus.com", "localhost", "127.0.0.1")
+	if addrs, err := net.InterfaceAddrs(); err == nil {
+		for _, a := range addrs {
+			if ipnet, ok := a.(*net.IPNet); ok && ipnet.IP.To4() != nil && ipnet.IP.IsPrivate() {
+				hosts = append(hosts, ipnet.IP.String())
+			}
+		}
+	}
+	return normalizeHosts(hosts)
+}
+
+// normalizeHosts lowercases names and drops blanks and duplicates.
+func normalizeHosts(hosts []string) []string {
+	out := []string{}
+	for _, h := range hosts {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if h != "" && !slices.Contains(out, h) {
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+func parseCertificate(raw []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("no PEM certificate found")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse certificate: %w", err)
+	}
+	return cert, nil
+}
+
+func writeFile(path string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
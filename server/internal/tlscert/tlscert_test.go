@@ -0,0 +1,119 @@
+package tlscert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// selfSigned returns a PEM certificate and key valid from notBefore to
+// notAfter.
+func selfSigned(t *testing.T, notBefore, notAfter time.Time) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		DNSNames:     []string{"router.example"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM, err := encodeKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), keyPEM
+}
+
+// certPaths returns certificate and key paths in a temporary directory.
+func certPaths(t *testing.T) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	return filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+}
+
+func TestInstall(t *testing.T) {
+	certFile, keyFile := certPaths(t)
+	certPEM, keyPEM := selfSigned(t, time.Now().Add(-time.Hour), time.Now().Add(24*time.Hour))
+
+	info, err := Install(certFile, keyFile, certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Subject != "CN=test" || !slices.Equal(info.DNSNames, []string{"router.example"}) || info.LocalCA {
+		t.Errorf("unexpected info: %+v", info)
+	}
+	if len(info.Fingerprint) != 64 {
+		t.Errorf("expected a SHA-256 fingerprint, got %q", info.Fingerprint)
+	}
+	if st, _ := os.Stat(keyFile); st.Mode().Perm() != 0600 {
+		t.Errorf("expected key mode 0600, got %v", st.Mode().Perm())
+	}
+
+	got, err := ReadInfo(certFile)
+	if err != nil || got.Fingerprint != info.Fingerprint {
+		t.Errorf("expected ReadInfo to match, got %+v, %v", got, err)
+	}
+}
+
+func TestInstall_Rejects(t *testing.T) {
+	certPEM, keyPEM := selfSigned(t, time.Now().Add(-time.Hour), time.Now().Add(24*time.Hour))
+	_, otherKey := selfSigned(t, time.Now().Add(-time.Hour), time.Now().Add(24*time.Hour))
+	expiredCert, expiredKey := selfSigned(t, time.Now().Add(-48*time.Hour), time.Now().Add(-24*time.Hour))
+
+	tests := []struct {
+		name      string
+		cert, key []byte
+		want      string
+	}{
+		{"mismatched key", certPEM, otherKey, "invalid certificate or key"},
+		{"not PEM", []byte("hello"), keyPEM, "invalid certificate or key"},
+		{"expired", expiredCert, expiredKey, "expired"},
+		{"too large", append(certPEM, make([]byte, maxPEMSize)...), keyPEM, "too large"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			certFile, keyFile := certPaths(t)
+			_, err := Install(certFile, keyFile, tt.cert, tt.key)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+			if _, err := os.Stat(certFile); !os.IsNotExist(err) {
+				t.Error("expected nothing written")
+			}
+		})
+	}
+}
+
+func TestDefaultHosts(t *testing.T) {
+	hosts := DefaultHosts()
+	for _, want := range []string{"localhost", "127.0.0.1", "router.asus.com"} {
+		if !slices.Contains(hosts, want) {
+			t.Errorf("expected %q in %v", want, hosts)
+		}
+	}
+}
+
+func TestNormalizeHosts(t *testing.T) {
+	got := normalizeHosts([]string{" Router.Local ", "", "router.local", "192.168.50.1"})
+	if !slices.Equal(got, []string{"router.local", "192.168.50.1"}) {
+		t.Errorf("unexpected hosts: %v", got)
+	}
+}
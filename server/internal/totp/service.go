@@ -0,0 +1,51 @@
+package totp
+
+import (
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/service"
+)
+
+// Compile-time interface check
+var _ Manager = (*Service)(nil)
+
+// Service manages enrollments in the configured data directory, which is
+// looked up on every call so a data_dir change takes effect without a
+// restart. The store is shared between calls so its lock serializes writes.
+type Service struct {
+	stores *service.StoreCache[*Store]
+}
+
+// NewService creates a new Service encrypting with secret (webui.totp_key).
+func NewService(config service.ConfigStore, secret string) *Service {
+	return &Service{stores: service.NewStoreCache(config, func(dataDir string) *Store {
+		return NewStore(StorePath(dataDir), secret)
+	})}
+}
+
+func (s *Service) store() *Store {
+	return s.stores.Get()
+}
+
+// Status reports whether 2FA is on for user.
+func (s *Service) Status(user string) (Status, error) {
+	return s.store().Status(user)
+}
+
+// Begin starts enrollment and returns a new secret.
+func (s *Service) Begin(user string) (string, error) {
+	return s.store().Begin(user)
+}
+
+// Confirm turns 2FA on and returns recovery codes.
+func (s *Service) Confirm(user, code string) ([]string, error) {
+	return s.store().Confirm(user, code)
+}
+
+// Verify checks a TOTP or recovery code.
+func (s *Service) Verify(user, code string) (bool, error) {
+	return s.store().Verify(user, code)
+}
+
+// Disable turns 2FA off after checking a code.
+func (s *Service) Disable(user, code string) error {
+	return s.store().Disable(user, code)
+}
@@ -0,0 +1,320 @@
+package totp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StoreFile is the enrollment file inside the data directory.
+const StoreFile = "totp.json"
+
+const recoveryCodes = 10
+
+var (
+	// ErrInvalidCode is returned for a wrong, expired or reused code.
+	ErrInvalidCode = errors.New("invalid code")
+	// ErrNotEnrolled is returned when the user has not started enrollment.
+	ErrNotEnrolled = errors.New("two-factor authentication is not set up")
+	// ErrAlreadyEnabled is returned by Begin when 2FA is already on.
+	ErrAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+)
+
+// Status is a user's 2FA state.
+type Status struct {
+	Enabled           bool `json:"enabled"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// Manager enrolls users and checks their second factor.
+type Manager interface {
+	Status(user string) (Status, error)
+	Begin(user string) (string, error)
+	Confirm(user, code string) ([]string, error)
+	Verify(user, code string) (bool, error)
+	Disable(user, code string) error
+}
+
+// enrollment is a user's entry in the store file.
+type enrollment struct {
+	Secret   string    `json:"secret"`  // AES-GCM sealed, base64
+	Enabled  bool      `json:"enabled"` // confirmed with a code
+	Created  time.Time `json:"created"`
+	LastStep int64     `json:"last_step,omitempty"` // last accepted time step
+	Recovery []string  `json:"recovery,omitempty"`  // HMACs of unused codes
+}
+
+// Store keeps enrollments in a JSON file keyed by username. Secrets are
+// encrypted with a key derived from webui.totp_key, so a copy of the data
+// dir alone does not reveal them. Writes are atomic (temp file + rename).
+type Store struct {
+	path string
+	key  []byte
+	mu   sync.Mutex
+	now  func() time.Time
+}
+
+// NewStore creates a store backed by the file at path. secret is the
+// configured encryption secret.
+func NewStore(path, secret string) *Store {
+	key := sha256.Sum256([]byte(secret))
+	return &Store{path: path, key: key[:], now: time.Now}
+}
+
+// StorePath returns the enrollment file location inside dataDir.
+func StorePath(dataDir string) string {
+	return filepath.Join(dataDir, StoreFile)
+}
+
+// Status reports whether 2FA is on for user.
+func (s *Store) Status(user string) (Status, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.load()
+	if err != nil {
+		return Status{}, err
+	}
+	e := all[user]
+	if e == nil || !e.Enabled {
+		return Status{}, nil
+	}
+	return Status{Enabled: true, RecoveryCodesLeft: len(e.Recovery)}, nil
+}
+
+// Begin starts enrollment and returns a new secret. It replaces an
+// unconfirmed enrollment; 2FA stays off until Confirm.
+func (s *Store) Begin(user string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	if e := all[user]; e != nil && e.Enabled {
+		return "", ErrAlreadyEnabled
+	}
+
+	secret, err := GenerateSecret()
+	if err != nil {
+		return "", err
+	}
+	sealed, err := s.seal(secret)
+	if err != nil {
+		return "", err
+	}
+	all[user] = &enrollment{Secret: sealed, Created: s.now().UTC()}
+	if err := s.save(all); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// Confirm turns 2FA on once the user proves the authenticator works, and
+// returns new recovery codes. They are only stored hashed.
+func (s *Store) Confirm(user, code string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	e := all[user]
+	if e == nil {
+		return nil, ErrNotEnrolled
+	}
+	if e.Enabled {
+		return nil, ErrAlreadyEnabled
+	}
+	if err := s.checkTOTP(e, normalize(code)); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodes)
+	e.Recovery = make([]string, recoveryCodes)
+	for i := range codes {
+		if codes[i], err = newRecoveryCode(); err != nil {
+			return nil, err
+		}
+		e.Recovery[i] = s.hashRecovery(normalize(codes[i]))
+	}
+	e.Enabled = true
+	if err := s.save(all); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verify checks a login code: a current TOTP code, or a recovery code,
+// which is used up. Reports whether a recovery code was used.
+func (s *Store) Verify(user, code string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.load()
+	if err != nil {
+		return false, err
+	}
+	e := all[user]
+	if e == nil || !e.Enabled {
+		return false, ErrNotEnrolled
+	}
+	recovery, err := s.checkCode(e, normalize(code))
+	if err != nil {
+		return false, err
+	}
+	return recovery, s.save(all)
+}
+
+// Disable turns 2FA off after checking a code.
+func (s *Store) Disable(user, code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.load()
+	if err != nil {
+		return err
+	}
+	e := all[user]
+	if e == nil || !e.Enabled {
+		return ErrNotEnrolled
+	}
+	if _, err := s.checkCode(e, normalize(code)); err != nil {
+		return err
+	}
+	delete(all, user)
+	return s.save(all)
+}
+
+// checkCode accepts a TOTP code or consumes a recovery code.
+func (s *Store) checkCode(e *enrollment, code string) (bool, error) {
+	if len(code) == digits {
+		return false, s.checkTOTP(e, code)
+	}
+	i := slices.IndexFunc(e.Recovery, func(h string) bool {
+		return hmac.Equal([]byte(h), []byte(s.hashRecovery(code)))
+	})
+	if i < 0 {
+		return false, ErrInvalidCode
+	}
+	e.Recovery = slices.Delete(e.Recovery, i, i+1)
+	return true, nil
+}
+
+// checkTOTP validates a code and rejects one already used, so a code seen
+// over someone's shoulder cannot be replayed.
+func (s *Store) checkTOTP(e *enrollment, code string) error {
+	secret, err := s.open(e.Secret)
+	if err != nil {
+		return err
+	}
+	st, ok := Validate(secret, code, s.now())
+	if !ok || st <= e.LastStep {
+		return ErrInvalidCode
+	}
+	e.LastStep = st
+	return nil
+}
+
+func (s *Store) seal(plain string) (string, error) {
+	gcm, err := s.cipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("generate nonce: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(plain), nil)), nil
+}
+
+func (s *Store) open(sealed string) (string, error) {
+	gcm, err := s.cipher()
+	if err != nil {
+		return "", err
+	}
+	raw, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(raw) < gcm.NonceSize() {
+		return "", errors.New("decrypt TOTP secret: malformed")
+	}
+	plain, err := gcm.Open(nil, raw[:gcm.NonceSize()], raw[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("decrypt TOTP secret: wrong totp_key?")
+	}
+	return string(plain), nil
+}
+
+func (s *Store) cipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(s.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (s *Store) hashRecovery(code string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Store) load() (map[string]*enrollment, error) {
+	all := map[string]*enrollment{}
+	raw, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return all, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read TOTP store: %w", err)
+	}
+	if err := json.Unmarshal(raw, &all); err != nil {
+		return nil, fmt.Errorf("parse TOTP store: %w", err)
+	}
+	return all, nil
+}
+
+func (s *Store) save(all map[string]*enrollment) error {
+	raw, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, raw, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.path)
+}
+
+// newRecoveryCode returns a code like "k3m9q-x7c2p" (50 random bits).
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate recovery code: %w", err)
+	}
+	s := strings.ToLower(b32.EncodeToString(b))[:10]
+	return s[:5] + "-" + s[5:], nil
+}
+
+// normalize strips the spaces and dashes users type into codes.
+func normalize(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer(" ", "", "-", "").Replace(code)
+}
@@ -0,0 +1,143 @@
+package totp
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var base = time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	s := NewStore(filepath.Join(t.TempDir(), StoreFile), "test-key")
+	s.now = func() time.Time { return base }
+	return s
+}
+
+// enroll turns 2FA on for user and returns the secret and recovery codes.
+func enroll(t *testing.T, s *Store, user string) (string, []string) {
+	t.Helper()
+	secret, err := s.Begin(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := Code(secret, s.now())
+	codes, err := s.Confirm(user, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return secret, codes
+}
+
+func TestStore_Enrollment(t *testing.T) {
+	s := newTestStore(t)
+
+	secret, err := s.Begin("admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st, _ := s.Status("admin"); st.Enabled {
+		t.Error("expected 2FA off until confirmed")
+	}
+	if _, err := s.Confirm("admin", "000000"); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("expected ErrInvalidCode, got %v", err)
+	}
+
+	code, _ := Code(secret, base)
+	codes, err := s.Confirm("admin", code[:3]+" "+code[3:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != recoveryCodes || len(codes[0]) != 11 || codes[0][5] != '-' {
+		t.Errorf("unexpected recovery codes: %v", codes)
+	}
+	st, _ := s.Status("admin")
+	if !st.Enabled || st.RecoveryCodesLeft != recoveryCodes {
+		t.Errorf("unexpected status: %+v", st)
+	}
+
+	if _, err := s.Begin("admin"); !errors.Is(err, ErrAlreadyEnabled) {
+		t.Errorf("expected ErrAlreadyEnabled, got %v", err)
+	}
+	if _, err := s.Confirm("nobody", code); !errors.Is(err, ErrNotEnrolled) {
+		t.Errorf("expected ErrNotEnrolled, got %v", err)
+	}
+}
+
+func TestStore_SecretEncrypted(t *testing.T) {
+	s := newTestStore(t)
+	secret, codes := enroll(t, s, "admin")
+
+	raw, err := os.ReadFile(s.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), secret) || strings.Contains(string(raw), codes[0]) {
+		t.Error("secret and recovery codes must not be stored in plain text")
+	}
+
+	// A different key cannot decrypt the secret
+	other := NewStore(s.path, "other-key")
+	other.now = s.now
+	code, _ := Code(secret, base.Add(period))
+	if _, err := other.Verify("admin", code); err == nil || errors.Is(err, ErrInvalidCode) {
+		t.Errorf("expected a decryption error, got %v", err)
+	}
+}
+
+func TestStore_VerifyRejectsReplay(t *testing.T) {
+	s := newTestStore(t)
+	secret, _ := enroll(t, s, "admin")
+
+	// The enrollment code was used at base; the next period is new
+	now := base.Add(period)
+	s.now = func() time.Time { return now }
+	code, _ := Code(secret, now)
+
+	if recovery, err := s.Verify("admin", code); err != nil || recovery {
+		t.Fatalf("expected TOTP code accepted, got %v, %v", recovery, err)
+	}
+	if _, err := s.Verify("admin", code); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("expected a replayed code to be rejected, got %v", err)
+	}
+	if _, err := s.Verify("guest", code); !errors.Is(err, ErrNotEnrolled) {
+		t.Errorf("expected ErrNotEnrolled, got %v", err)
+	}
+}
+
+func TestStore_RecoveryCodesSingleUse(t *testing.T) {
+	s := newTestStore(t)
+	_, codes := enroll(t, s, "admin")
+
+	if recovery, err := s.Verify("admin", strings.ToUpper(codes[3])); err != nil || !recovery {
+		t.Fatalf("expected recovery code accepted, got %v, %v", recovery, err)
+	}
+	if _, err := s.Verify("admin", codes[3]); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("expected a used recovery code to be rejected, got %v", err)
+	}
+	if st, _ := s.Status("admin"); st.RecoveryCodesLeft != recoveryCodes-1 {
+		t.Errorf("expected %d codes left, got %d", recoveryCodes-1, st.RecoveryCodesLeft)
+	}
+}
+
+func TestStore_Disable(t *testing.T) {
+	s := newTestStore(t)
+	_, codes := enroll(t, s, "admin")
+
+	if err := s.Disable("admin", "nope"); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("expected ErrInvalidCode, got %v", err)
+	}
+	if err := s.Disable("admin", codes[0]); err != nil {
+		t.Fatal(err)
+	}
+	if st, _ := s.Status("admin"); st.Enabled {
+		t.Error("expected 2FA off")
+	}
+	if err := s.Disable("admin", codes[1]); !errors.Is(err, ErrNotEnrolled) {
+		t.Errorf("expected ErrNotEnrolled, got %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+// Package totp implements optional two-factor login for the Web UI:
+// RFC 6238 codes (SHA-1, 6 digits, 30 s), enrollment via an otpauth URI
+// and QR code, and single-use recovery codes. Secrets are stored encrypted.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+const (
+	// Issuer is shown by authenticator apps.
+	Issuer = "VPN Director"
+
+	period = 30 * time.Second
+	digits = 6
+	// skew is how many periods before and after now are accepted, for
+	// router and phone clocks that drift apart.
+	skew = 1
+	// secretSize is the secret length in bytes (160 bits, as RFC 4226 recommends).
+	secretSize = 20
+	qrSize     = 256
+)
+
+var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns a random base32-encoded secret.
+func GenerateSecret() (string, error) {
+	b := make([]byte, secretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate secret: %w", err)
+	}
+	return b32.EncodeToString(b), nil
+}
+
+// Code returns the code for secret at time t.
+func Code(secret string, t time.Time) (string, error) {
+	key, err := b32.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", fmt.Errorf("decode secret: %w", err)
+	}
+	return codeAt(key, step(t)), nil
+}
+
+// Validate checks code against secret around time t and returns the matched
+// time step, which callers store to reject a replayed code.
+func Validate(secret, code string, t time.Time) (int64, bool) {
+	key, err := b32.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != digits {
+		return 0, false
+	}
+	now := step(t)
+	for s := now - skew; s <= now+skew; s++ {
+		if hmac.Equal([]byte(codeAt(key, s)), []byte(code)) {
+			return s, true
+		}
+	}
+	return 0, false
+}
+
+// URI returns the otpauth:// URI authenticator apps import.
+func URI(account, secret string) string {
+	label := url.PathEscape(Issuer + ":" + account)
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", Issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(digits))
+	q.Set("period", fmt.Sprint(int(period.Seconds())))
+	return "otpauth://totp/" + label + "?" + q.Encode()
+}
+
+// QRDataURI renders uri as a PNG QR code in a data: URI for an <img> tag.
+func QRDataURI(uri string) (string, error) {
+	png, err := qrcode.Encode(uri, qrcode.Medium, qrSize)
+	if err != nil {
+		return "", fmt.Errorf("render QR code: %w", err)
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(png), nil
+}
+
+func step(t time.Time) int64 {
+	return t.Unix() / int64(period.Seconds())
+}
+
+// codeAt is the RFC 4226 HOTP value for counter s.
+func codeAt(key []byte, s int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(s))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", digits, value%1000000)
+}
@@ -0,0 +1,84 @@
+package totp
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the RFC 6238 SHA-1 test key "12345678901234567890".
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode_RFC6238(t *testing.T) {
+	// Last 6 digits of the RFC 6238 appendix B SHA-1 values
+	tests := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, want := range tests {
+		got, err := Code(rfcSecret, time.Unix(unix, 0))
+		if err != nil || got != want {
+			t.Errorf("Code at %d = %q, %v; want %q", unix, got, err, want)
+		}
+	}
+}
+
+func TestValidate_Skew(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	code, _ := Code(rfcSecret, now)
+
+	if _, ok := Validate(rfcSecret, code, now.Add(period)); !ok {
+		t.Error("expected a code from the previous period to be accepted")
+	}
+	if _, ok := Validate(rfcSecret, code, now.Add(3*period)); ok {
+		t.Error("expected an old code to be rejected")
+	}
+	if _, ok := Validate(rfcSecret, "12345", now); ok {
+		t.Error("expected a short code to be rejected")
+	}
+	if _, ok := Validate("not base32!", code, now); ok {
+		t.Error("expected a bad secret to be rejected")
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	a, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := GenerateSecret()
+	if a == b || len(a) != 32 {
+		t.Errorf("expected distinct 32-character secrets, got %q and %q", a, b)
+	}
+	if _, err := Code(a, time.Now()); err != nil {
+		t.Errorf("generated secret does not decode: %v", err)
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("admin", rfcSecret)
+	u, err := url.Parse(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/VPN Director:admin" {
+		t.Errorf("unexpected URI %q", uri)
+	}
+	if q := u.Query(); q.Get("secret") != rfcSecret || q.Get("issuer") != Issuer || q.Get("digits") != "6" {
+		t.Errorf("unexpected query in %q", uri)
+	}
+}
+
+func TestQRDataURI(t *testing.T) {
+	got, err := QRDataURI(URI("admin", rfcSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(got, "data:image/png;base64,iVBORw0KGgo") {
+		t.Errorf("expected a PNG data URI, got %.40s", got)
+	}
+}
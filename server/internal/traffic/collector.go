@@ -0,0 +1,206 @@
+package traffic
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/service"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+)
+
+const (
+	// DefaultInterval is how often counters are sampled.
+	DefaultInterval = 5 * time.Minute
+
+	lockFile = "traffic.lock"
+
+	defaultXrayChain   = "XRAY_TPROXY"
+	defaultTunnelChain = "TUN_DIR"
+)
+
+// Reporter returns the current traffic report.
+type Reporter interface {
+	Report() (*Report, error)
+}
+
+// Collector samples iptables counters into the store in the data directory.
+// The bot and the Web UI both run a collector; a file lock makes sure only
+// one of them records samples at a time.
+type Collector struct {
+	config   service.ConfigStore
+	executor service.ShellExecutor
+	now      func() time.Time
+
+	mu   sync.Mutex
+	lock *os.File
+}
+
+// NewCollector creates a collector. executor may be nil.
+func NewCollector(config service.ConfigStore, executor service.ShellExecutor) *Collector {
+	if executor == nil {
+		executor = service.DefaultExecutor()
+	}
+	return &Collector{config: config, executor: executor, now: time.Now}
+}
+
+// Run samples counters on an interval. Blocks until ctx is cancelled.
+func (c *Collector) Run(ctx context.Context, interval time.Duration) {
+	slog.Info("Traffic collector started", "interval", interval)
+	defer c.unlock()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.CollectOnce(); err != nil {
+			slog.Warn("Traffic collection failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			slog.Info("Traffic collector stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CollectOnce records one sample. It does nothing if another process holds
+// the collector lock.
+func (c *Collector) CollectOnce() error {
+	cfg, err := c.config.LoadVPNConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	dataDir := c.config.DataDirOrDefault()
+
+	ok, err := c.tryLock(dataDir)
+	if err != nil {
+		return fmt.Errorf("lock: %w", err)
+	}
+	if !ok {
+		return nil
+	}
+
+	res, err := c.executor.Exec("iptables-save", "-c", "-t", "mangle")
+	if err != nil {
+		return fmt.Errorf("iptables-save: %w", err)
+	}
+	if res.ExitCode != 0 {
+		return fmt.Errorf("iptables-save exited with code %d: %s", res.ExitCode, res.Output)
+	}
+	output := res.Output
+
+	// IPv6 is optional: ip6tables may be missing or have no Xray chain.
+	if res6, err := c.executor.Exec("ip6tables-save", "-c", "-t", "mangle"); err == nil && res6.ExitCode == 0 {
+		output += "\n" + res6.Output
+	}
+
+	xrayChain := advancedString(cfg, "xray", "chain", defaultXrayChain)
+	tunChain := advancedString(cfg, "tunnel_director", "chain", defaultTunnelChain)
+	sample := BuildSample(ParseSave(output, xrayChain, tunChain), xrayChain, cfg)
+
+	return NewStore(StorePath(dataDir)).Record(c.now(), sample)
+}
+
+// BuildSample groups rules by client and route. Xray rules map to the "xray"
+// route; Tunnel Director rules map to the tunnel that lists the client.
+func BuildSample(rules []Rule, xrayChain string, cfg *vpnconfig.VPNDirectorConfig) map[string]Traffic {
+	tunnels := make(map[string]string)
+	for name, tunnel := range cfg.TunnelDirector.Tunnels {
+		for _, client := range tunnel.Clients {
+			tunnels[NormalizeSource(client)] = name
+		}
+	}
+
+	sample := make(map[string]Traffic, len(rules))
+	for _, r := range rules {
+		route := "xray"
+		if r.Chain != xrayChain {
+			route = tunnels[r.Client]
+			if route == "" {
+				route = "tunnel"
+			}
+		}
+		key := UsageKey(r.Client, route)
+		t := sample[key]
+		if r.Download {
+			t.Download = t.Download.Add(r.Counter)
+		} else {
+			t.Upload = t.Upload.Add(r.Counter)
+		}
+		sample[key] = t
+	}
+	return sample
+}
+
+// tryLock takes the collector lock once and keeps it until Run returns.
+func (c *Collector) tryLock(dataDir string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.lock != nil {
+		return true, nil
+	}
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return false, err
+	}
+	f, err := os.OpenFile(filepath.Join(dataDir, lockFile), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return false, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if err == syscall.EWOULDBLOCK {
+			return false, nil
+		}
+		return false, err
+	}
+	c.lock = f
+	return true, nil
+}
+
+func (c *Collector) unlock() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.lock != nil {
+		c.lock.Close()
+		c.lock = nil
+	}
+}
+
+// advancedString reads advanced.<section>.<key> from the config.
+func advancedString(cfg *vpnconfig.VPNDirectorConfig, section, key, def string) string {
+	sec, ok := cfg.Advanced[section].(map[string]interface{})
+	if !ok {
+		return def
+	}
+	if v, ok := sec[key].(string); ok && v != "" {
+		return v
+	}
+	return def
+}
+
+// Service reads traffic reports from the store in the configured data dir.
+type Service struct {
+	config service.ConfigStore
+}
+
+var _ Reporter = (*Service)(nil)
+
+// NewService creates a report reader.
+func NewService(config service.ConfigStore) *Service {
+	return &Service{config: config}
+}
+
+// Report returns the summary over the last hour, day and month.
+func (s *Service) Report() (*Report, error) {
+	return NewStore(StorePath(s.config.DataDirOrDefault())).Report(time.Now())
+}
@@ -0,0 +1,155 @@
+package traffic
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/shell"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/testutil"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+)
+
+type mockExecutor struct {
+	outputs map[string]string
+	err6    error
+	calls   []string
+}
+
+func (m *mockExecutor) Exec(name string, args ...string) (*shell.Result, error) {
+	m.calls = append(m.calls, name)
+	if name == "ip6tables-save" && m.err6 != nil {
+		return nil, m.err6
+	}
+	return &shell.Result{Output: m.outputs[name]}, nil
+}
+
+func testConfig() *vpnconfig.VPNDirectorConfig {
+	return &vpnconfig.VPNDirectorConfig{
+		TunnelDirector: vpnconfig.TunnelDirectorConfig{
+			Tunnels: map[string]vpnconfig.TunnelConfig{
+				"wgc1": {Clients: []string{"192.168.1.200/32"}},
+			},
+		},
+	}
+}
+
+func TestBuildSample(t *testing.T) {
+	rules := ParseSave(sampleSave, "XRAY_TPROXY", "TUN_DIR")
+	sample := BuildSample(rules, "XRAY_TPROXY", testConfig())
+
+	if c := sample[UsageKey("192.168.1.100", "xray")]; c.Upload.Bytes != 64000 || c.Download.Bytes != 256000 {
+		t.Errorf("xray client = %+v, want 64000 up, 256000 down", c)
+	}
+	if c := sample[UsageKey("192.168.1.200", "wgc1")]; c.Upload.Bytes != 8000 || c.Download.Bytes != 96000 {
+		t.Errorf("tunnel client = %+v, want 8000 up, 96000 down", c)
+	}
+}
+
+func TestBuildSample_UnknownTunnelClient(t *testing.T) {
+	rules := []Rule{{Chain: "TUN_DIR", Client: "192.168.1.9", Counter: Counter{Bytes: 1}}}
+	sample := BuildSample(rules, "XRAY_TPROXY", testConfig())
+	if _, ok := sample[UsageKey("192.168.1.9", "tunnel")]; !ok {
+		t.Errorf("expected fallback route, got %v", sample)
+	}
+}
+
+func TestCollector_CollectOnce(t *testing.T) {
+	dir := t.TempDir()
+	exec := &mockExecutor{
+		outputs: map[string]string{"iptables-save": sampleSave},
+		err6:    errors.New("ip6tables-save: not found"),
+	}
+	c := NewCollector(&testutil.Config{Cfg: testConfig(), Dir: dir}, exec)
+	t.Cleanup(c.unlock)
+
+	t0 := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return t0 }
+	if err := c.CollectOnce(); err != nil {
+		t.Fatalf("CollectOnce: %v", err)
+	}
+
+	exec.outputs["iptables-save"] = "[200:100000] -A XRAY_TPROXY -s 192.168.1.100/32\n" +
+		"[300:300000] -A XRAY_TPROXY_DL -d 192.168.1.100/32\n"
+	c.now = func() time.Time { return t0.Add(DefaultInterval) }
+	if err := c.CollectOnce(); err != nil {
+		t.Fatalf("CollectOnce: %v", err)
+	}
+
+	r, err := NewStore(StorePath(dir)).Report(t0.Add(DefaultInterval))
+	if err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+	if r.Hour.Upload.Bytes != 36000 || r.Hour.Download.Bytes != 44000 {
+		t.Errorf("hour = %+v, want 36000 up, 44000 down", r.Hour.Traffic)
+	}
+}
+
+func TestCollector_CustomChain(t *testing.T) {
+	cfg := testConfig()
+	cfg.Advanced = map[string]interface{}{
+		"xray": map[string]interface{}{"chain": "MY_XRAY"},
+	}
+	if got := advancedString(cfg, "xray", "chain", defaultXrayChain); got != "MY_XRAY" {
+		t.Errorf("advancedString = %q, want MY_XRAY", got)
+	}
+	if got := advancedString(cfg, "tunnel_director", "chain", defaultTunnelChain); got != defaultTunnelChain {
+		t.Errorf("advancedString = %q, want %s", got, defaultTunnelChain)
+	}
+}
+
+func TestCollector_SecondInstanceSkips(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &testutil.Config{Cfg: testConfig(), Dir: dir}
+
+	first := NewCollector(cfg, &mockExecutor{outputs: map[string]string{"iptables-save": sampleSave}})
+	t.Cleanup(first.unlock)
+	if err := first.CollectOnce(); err != nil {
+		t.Fatalf("first CollectOnce: %v", err)
+	}
+
+	secondExec := &mockExecutor{outputs: map[string]string{"iptables-save": sampleSave}}
+	second := NewCollector(cfg, secondExec)
+	t.Cleanup(second.unlock)
+	if err := second.CollectOnce(); err != nil {
+		t.Fatalf("second CollectOnce: %v", err)
+	}
+	if len(secondExec.calls) != 0 {
+		t.Errorf("second collector should not sample while locked, ran %v", secondExec.calls)
+	}
+
+	first.unlock()
+	if err := second.CollectOnce(); err != nil {
+		t.Fatalf("second CollectOnce after unlock: %v", err)
+	}
+	if len(secondExec.calls) == 0 {
+		t.Error("second collector should take over after the lock is released")
+	}
+}
+
+func TestCollector_IptablesFailure(t *testing.T) {
+	exec := &failingExecutor{}
+	c := NewCollector(&testutil.Config{Cfg: testConfig(), Dir: t.TempDir()}, exec)
+	t.Cleanup(c.unlock)
+	if err := c.CollectOnce(); err == nil {
+		t.Error("expected error when iptables-save fails")
+	}
+}
+
+type failingExecutor struct{}
+
+func (f *failingExecutor) Exec(name string, args ...string) (*shell.Result, error) {
+	return &shell.Result{Output: "permission denied", ExitCode: 1}, nil
+}
+
+func TestService_Report(t *testing.T) {
+	dir := t.TempDir()
+	svc := NewService(&testutil.Config{Cfg: testConfig(), Dir: dir})
+	r, err := svc.Report()
+	if err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+	if r.Day.Bytes() != 0 {
+		t.Errorf("expected empty report, got %+v", r.Day)
+	}
+}
@@ -0,0 +1,149 @@
+// Package traffic collects per-client byte/packet counters from the
+// XRAY_TPROXY and TUN_DIR mangle chains and keeps them in a small on-disk
+// ring buffer of 5-minute and hourly buckets.
+//
+// Upload counters come from rules in the PREROUTING chains that match a
+// client as source. Download counters come from the download chains (see
+// DownloadChain) that match a client as destination on the return path:
+// Xray replies in mangle OUTPUT and tunnel replies in mangle FORWARD.
+package traffic
+
+import (
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// Counter is a packet/byte pair.
+type Counter struct {
+	Packets uint64 `json:"packets"`
+	Bytes   uint64 `json:"bytes"`
+}
+
+// Add returns the sum of two counters.
+func (c Counter) Add(o Counter) Counter {
+	return Counter{Packets: c.Packets + o.Packets, Bytes: c.Bytes + o.Bytes}
+}
+
+// Traffic is what a client sent (upload) and received (download).
+type Traffic struct {
+	Upload   Counter `json:"upload"`
+	Download Counter `json:"download"`
+}
+
+// Add returns the sum of two traffic totals.
+func (t Traffic) Add(o Traffic) Traffic {
+	return Traffic{Upload: t.Upload.Add(o.Upload), Download: t.Download.Add(o.Download)}
+}
+
+// Bytes returns the bytes sent in both directions.
+func (t Traffic) Bytes() uint64 {
+	return t.Upload.Bytes + t.Download.Bytes
+}
+
+// Rule is a counted client rule from iptables-save -c output. Chain is the
+// Xray or Tunnel Director chain, also for rules of its download chain.
+type Rule struct {
+	Chain    string
+	Client   string
+	Download bool
+	Counter
+}
+
+// DownloadChain returns the name of the chain counting replies to the
+// clients of chain. Keep in sync with tproxy.sh and tunnel.sh.
+func DownloadChain(chain string) string {
+	return chain + "_DL"
+}
+
+// ParseSave extracts per-client rules of the given chains and their download
+// chains from "iptables-save -c" output. In the Xray chain only target-less
+// accounting rules count; in the Tunnel Director chain only MARK rules count,
+// so that exclusion RETURN rules are skipped. In the download chains only
+// target-less rules matching a destination count.
+func ParseSave(output, xrayChain, tunChain string) []Rule {
+	xrayDownload, tunDownload := DownloadChain(xrayChain), DownloadChain(tunChain)
+	var rules []Rule
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "[") {
+			continue
+		}
+		end := strings.Index(line, "]")
+		if end < 0 {
+			continue
+		}
+		counter, ok := parseCounter(line[1:end])
+		if !ok {
+			continue
+		}
+
+		fields := strings.Fields(line[end+1:])
+		if len(fields) < 2 || fields[0] != "-A" {
+			continue
+		}
+		chain := fields[1]
+		source := flagValue(fields, "-s")
+		dest := flagValue(fields, "-d")
+		target := flagValue(fields, "-j")
+
+		var rule Rule
+		switch {
+		case chain == xrayChain && target == "" && source != "":
+			rule = Rule{Chain: xrayChain, Client: source}
+		case chain == tunChain && target == "MARK" && source != "":
+			rule = Rule{Chain: tunChain, Client: source}
+		case chain == xrayDownload && target == "" && dest != "":
+			rule = Rule{Chain: xrayChain, Client: dest, Download: true}
+		case chain == tunDownload && target == "" && dest != "":
+			rule = Rule{Chain: tunChain, Client: dest, Download: true}
+		default:
+			continue
+		}
+
+		rule.Client = NormalizeSource(rule.Client)
+		rule.Counter = counter
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// NormalizeSource strips host-length prefixes, so "192.168.1.10/32" and
+// "192.168.1.10" map to the same client. Other prefixes are masked.
+func NormalizeSource(s string) string {
+	if p, err := netip.ParsePrefix(s); err == nil {
+		if p.IsSingleIP() {
+			return p.Addr().String()
+		}
+		return p.Masked().String()
+	}
+	if a, err := netip.ParseAddr(s); err == nil {
+		return a.String()
+	}
+	return s
+}
+
+func parseCounter(s string) (Counter, bool) {
+	pkts, bytes, ok := strings.Cut(s, ":")
+	if !ok {
+		return Counter{}, false
+	}
+	p, err := strconv.ParseUint(pkts, 10, 64)
+	if err != nil {
+		return Counter{}, false
+	}
+	b, err := strconv.ParseUint(bytes, 10, 64)
+	if err != nil {
+		return Counter{}, false
+	}
+	return Counter{Packets: p, Bytes: b}, true
+}
+
+func flagValue(fields []string, flag string) string {
+	for i := 0; i+1 < len(fields); i++ {
+		if fields[i] == flag && (i == 0 || fields[i-1] != "!") {
+			return fields[i+1]
+		}
+	}
+	return ""
+}
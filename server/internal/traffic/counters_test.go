@@ -0,0 +1,74 @@
+package traffic
+
+import (
+	"reflect"
+	"testing"
+)
+
+const sampleSave = `# Generated by iptables-save
+*mangle
+:PREROUTING ACCEPT [1000:200000]
+:XRAY_TPROXY - [0:0]
+:TUN_DIR - [0:0]
+:XRAY_TPROXY_DL - [0:0]
+:TUN_DIR_DL - [0:0]
+[500:100000] -A PREROUTING -i br0 -j XRAY_TPROXY
+[900:500000] -A OUTPUT -o br0 -m addrtype ! --src-type LOCAL -j XRAY_TPROXY_DL
+[300:200000] -A FORWARD -o br0 -j TUN_DIR_DL
+[10:1000] -A XRAY_TPROXY -d 10.0.0.0/8 -j RETURN
+[120:64000] -A XRAY_TPROXY -s 192.168.1.100/32
+[7:700] -A XRAY_TPROXY -s 192.168.50.0/24
+[130:65000] -A XRAY_TPROXY -p tcp -j TPROXY --on-port 12345 --on-ip 0.0.0.0 --tproxy-mark 0x100/0x100
+[3:300] -A TUN_DIR -s 192.168.1.200/32 -m set --match-set ru dst -j RETURN
+[40:8000] -A TUN_DIR -s 192.168.1.200/32 -m mark --mark 0x0/0xff0000 -j MARK --set-xmark 0x10000/0xff0000
+[9:900] -A TUN_DIR ! -s 192.168.1.0/24 -j MARK --set-xmark 0x20000/0xff0000
+[200:256000] -A XRAY_TPROXY_DL -d 192.168.1.100/32
+[80:96000] -A TUN_DIR_DL -i wgc1 -d 192.168.1.200/32
+COMMIT
+`
+
+func TestParseSave(t *testing.T) {
+	got := ParseSave(sampleSave, "XRAY_TPROXY", "TUN_DIR")
+	want := []Rule{
+		{Chain: "XRAY_TPROXY", Client: "192.168.1.100", Counter: Counter{Packets: 120, Bytes: 64000}},
+		{Chain: "XRAY_TPROXY", Client: "192.168.50.0/24", Counter: Counter{Packets: 7, Bytes: 700}},
+		{Chain: "TUN_DIR", Client: "192.168.1.200", Counter: Counter{Packets: 40, Bytes: 8000}},
+		{Chain: "XRAY_TPROXY", Client: "192.168.1.100", Download: true, Counter: Counter{Packets: 200, Bytes: 256000}},
+		{Chain: "TUN_DIR", Client: "192.168.1.200", Download: true, Counter: Counter{Packets: 80, Bytes: 96000}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseSave() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestParseSave_CustomChains(t *testing.T) {
+	out := "[1:2] -A MY_XRAY -s 10.1.1.1/32\n[3:4] -A XRAY_TPROXY -s 10.1.1.2/32\n" +
+		"[5:6] -A MY_XRAY_DL -d 10.1.1.1/32\n[7:8] -A XRAY_TPROXY_DL -d 10.1.1.2/32\n"
+	got := ParseSave(out, "MY_XRAY", "MY_TUN")
+	if len(got) != 2 || got[0].Client != "10.1.1.1" || got[1].Client != "10.1.1.1" || !got[1].Download {
+		t.Errorf("expected only MY_XRAY rules, got %+v", got)
+	}
+}
+
+func TestParseSave_IgnoresGarbage(t *testing.T) {
+	out := "[x:y] -A XRAY_TPROXY -s 10.1.1.1/32\n[1:2]\n-A XRAY_TPROXY -s 10.1.1.2\n"
+	if got := ParseSave(out, "XRAY_TPROXY", "TUN_DIR"); len(got) != 0 {
+		t.Errorf("expected no rules, got %+v", got)
+	}
+}
+
+func TestNormalizeSource(t *testing.T) {
+	tests := map[string]string{
+		"192.168.1.10/32":   "192.168.1.10",
+		"192.168.1.10":      "192.168.1.10",
+		"192.168.1.7/24":    "192.168.1.0/24",
+		"2001:db8::100/128": "2001:db8::100",
+		"2001:db8:0::100":   "2001:db8::100",
+		"not-an-address":    "not-an-address",
+	}
+	for in, want := range tests {
+		if got := NormalizeSource(in); got != want {
+			t.Errorf("NormalizeSource(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package traffic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// StoreFile is the store file name inside the data directory.
+	StoreFile = "traffic.json"
+
+	// storeVersion 2 added download counters.
+	storeVersion = 2
+
+	minuteBucket = 5 * time.Minute
+	hourBucket   = time.Hour
+
+	minuteRetention = time.Hour
+	hourRetention   = 31 * 24 * time.Hour
+
+	hourWindow  = time.Hour
+	dayWindow   = 24 * time.Hour
+	monthWindow = 30 * 24 * time.Hour
+)
+
+var errCorrupt = errors.New("traffic store is not valid JSON")
+
+// Usage is the traffic of one client or route within a window.
+type Usage struct {
+	Client string `json:"client,omitempty"`
+	Route  string `json:"route"`
+	Traffic
+}
+
+// Window holds totals for a time window, sorted by bytes in both directions
+// (descending).
+type Window struct {
+	Clients []Usage `json:"clients"`
+	Routes  []Usage `json:"routes"`
+	Traffic
+}
+
+// Report is the traffic summary over the last hour, day and month.
+type Report struct {
+	UpdatedAt time.Time `json:"updated_at"`
+	Hour      Window    `json:"hour"`
+	Day       Window    `json:"day"`
+	Month     Window    `json:"month"`
+}
+
+// WindowByName returns the window for "hour", "day" or "month".
+func (r *Report) WindowByName(name string) (Window, bool) {
+	switch name {
+	case "hour":
+		return r.Hour, true
+	case "day":
+		return r.Day, true
+	case "month":
+		return r.Month, true
+	}
+	return Window{}, false
+}
+
+// bucket holds deltas keyed by client and route (see UsageKey).
+type bucket struct {
+	Start time.Time          `json:"start"`
+	Usage map[string]Traffic `json:"usage"`
+}
+
+type storeData struct {
+	Version int                `json:"version"`
+	LastAt  time.Time          `json:"last_at"`
+	Last    map[string]Traffic `json:"last"`
+	Minutes []bucket           `json:"minutes"`
+	Hours   []bucket           `json:"hours"`
+}
+
+// Store is the on-disk ring buffer. Writes are atomic (temp file + rename),
+// so readers in other processes never see a partial file.
+type Store struct {
+	path string
+}
+
+// NewStore creates a store backed by the file at path.
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// StorePath returns the store location inside dataDir.
+func StorePath(dataDir string) string {
+	return filepath.Join(dataDir, StoreFile)
+}
+
+// Record stores a new sample of cumulative counters keyed by UsageKey.
+// The difference to the previous sample is added to the current buckets.
+// A counter lower than before means the rule was recreated (e.g. after a
+// restart), so its full value counts as the delta. The first sample only
+// sets the baseline.
+func (s *Store) Record(now time.Time, sample map[string]Traffic) error {
+	data, err := s.load()
+	if errors.Is(err, errCorrupt) {
+		slog.Warn("Traffic store is corrupt, starting over", "path", s.path, "error", err)
+		data, err = &storeData{}, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if !data.LastAt.IsZero() {
+		deltas := make(map[string]Traffic, len(sample))
+		for key, cur := range sample {
+			prev := data.Last[key] // zero for rules added since the last sample
+			d := Traffic{
+				Upload:   delta(cur.Upload, prev.Upload),
+				Download: delta(cur.Download, prev.Download),
+			}
+			if d != (Traffic{}) {
+				deltas[key] = d
+			}
+		}
+		data.Minutes = addToBuckets(data.Minutes, now.Truncate(minuteBucket), deltas)
+		data.Hours = addToBuckets(data.Hours, now.Truncate(hourBucket), deltas)
+	}
+
+	data.Version = storeVersion
+	data.LastAt = now
+	data.Last = sample
+	data.Minutes = trimBuckets(data.Minutes, now.Add(-minuteRetention-minuteBucket))
+	data.Hours = trimBuckets(data.Hours, now.Add(-hourRetention-hourBucket))
+
+	return s.save(data)
+}
+
+// Report summarizes the stored buckets relative to now.
+func (s *Store) Report(now time.Time) (*Report, error) {
+	data, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return &Report{
+		UpdatedAt: data.LastAt,
+		Hour:      summarize(data.Minutes, now.Add(-hourWindow), minuteBucket),
+		Day:       summarize(data.Hours, now.Add(-dayWindow), hourBucket),
+		Month:     summarize(data.Hours, now.Add(-monthWindow), hourBucket),
+	}, nil
+}
+
+// UsageKey builds the bucket key for a client and route.
+func UsageKey(client, route string) string {
+	return client + " " + route
+}
+
+func splitKey(key string) (client, route string) {
+	client, route, _ = strings.Cut(key, " ")
+	return client, route
+}
+
+// delta returns the growth of a counter since prev, or cur if it was reset.
+func delta(cur, prev Counter) Counter {
+	if cur.Bytes >= prev.Bytes && cur.Packets >= prev.Packets {
+		return Counter{Packets: cur.Packets - prev.Packets, Bytes: cur.Bytes - prev.Bytes}
+	}
+	return cur
+}
+
+func addToBuckets(buckets []bucket, start time.Time, deltas map[string]Traffic) []bucket {
+	if len(deltas) == 0 {
+		return buckets
+	}
+	if n := len(buckets); n == 0 || !buckets[n-1].Start.Equal(start) {
+		buckets = append(buckets, bucket{Start: start, Usage: map[string]Traffic{}})
+	}
+	cur := &buckets[len(buckets)-1]
+	if cur.Usage == nil {
+		cur.Usage = map[string]Traffic{}
+	}
+	for key, d := range deltas {
+		cur.Usage[key] = cur.Usage[key].Add(d)
+	}
+	return buckets
+}
+
+// trimBuckets drops buckets that started before cutoff.
+func trimBuckets(buckets []bucket, cutoff time.Time) []bucket {
+	i := 0
+	for i < len(buckets) && buckets[i].Start.Before(cutoff) {
+		i++
+	}
+	return buckets[i:]
+}
+
+// summarize totals buckets overlapping the window that starts at from.
+func summarize(buckets []bucket, from time.Time, size time.Duration) Window {
+	clients := map[string]Traffic{}
+	routes := map[string]Traffic{}
+	var w Window
+	for _, b := range buckets {
+		if !b.Start.Add(size).After(from) {
+			continue
+		}
+		for key, t := range b.Usage {
+			_, route := splitKey(key)
+			clients[key] = clients[key].Add(t)
+			routes[route] = routes[route].Add(t)
+			w.Traffic = w.Traffic.Add(t)
+		}
+	}
+
+	w.Clients = make([]Usage, 0, len(clients))
+	for key, t := range clients {
+		client, route := splitKey(key)
+		w.Clients = append(w.Clients, Usage{Client: client, Route: route, Traffic: t})
+	}
+	w.Routes = make([]Usage, 0, len(routes))
+	for route, t := range routes {
+		w.Routes = append(w.Routes, Usage{Route: route, Traffic: t})
+	}
+	sortUsage(w.Clients)
+	sortUsage(w.Routes)
+	return w
+}
+
+func sortUsage(u []Usage) {
+	sort.Slice(u, func(i, j int) bool {
+		if u[i].Bytes() != u[j].Bytes() {
+			return u[i].Bytes() > u[j].Bytes()
+		}
+		if u[i].Client != u[j].Client {
+			return u[i].Client < u[j].Client
+		}
+		return u[i].Route < u[j].Route
+	})
+}
+
+func (s *Store) load() (*storeData, error) {
+	raw, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &storeData{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read traffic store: %w", err)
+	}
+	var data storeData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("%w: %v", errCorrupt, err)
+	}
+	// Older stores hold upload-only counters in another layout: start over
+	if data.Version != storeVersion {
+		return &storeData{}, nil
+	}
+	return &data, nil
+}
+
+func (s *Store) save(data *storeData) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, raw, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.path)
+}
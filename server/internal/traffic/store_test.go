@@ -0,0 +1,177 @@
+package traffic
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	return NewStore(StorePath(t.TempDir()))
+}
+
+func TestStore_FirstSampleIsBaseline(t *testing.T) {
+	s := newTestStore(t)
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	if err := s.Record(now, map[string]Traffic{UsageKey("192.168.1.10", "xray"): {Upload: Counter{Packets: 10, Bytes: 5000}}}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	r, err := s.Report(now)
+	if err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+	if r.Day.Bytes() != 0 || len(r.Day.Clients) != 0 {
+		t.Errorf("expected empty report after baseline, got %+v", r.Day)
+	}
+	if !r.UpdatedAt.Equal(now) {
+		t.Errorf("UpdatedAt = %v, want %v", r.UpdatedAt, now)
+	}
+}
+
+func TestStore_RecordsDeltas(t *testing.T) {
+	s := newTestStore(t)
+	t0 := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	a := UsageKey("192.168.1.10", "xray")
+	b := UsageKey("192.168.1.20", "wgc1")
+
+	samples := []map[string]Traffic{
+		{a: {Upload: Counter{Packets: 10, Bytes: 1000}, Download: Counter{Packets: 20, Bytes: 4000}}, b: {Upload: Counter{Packets: 5, Bytes: 500}}},
+		{a: {Upload: Counter{Packets: 20, Bytes: 3000}, Download: Counter{Packets: 30, Bytes: 9000}}, b: {Upload: Counter{Packets: 6, Bytes: 600}}},
+		// b's rule was recreated: its counter restarted from zero.
+		{a: {Upload: Counter{Packets: 30, Bytes: 6000}, Download: Counter{Packets: 40, Bytes: 10000}}, b: {Upload: Counter{Packets: 2, Bytes: 50}}},
+	}
+	for i, sample := range samples {
+		if err := s.Record(t0.Add(time.Duration(i)*DefaultInterval), sample); err != nil {
+			t.Fatalf("Record %d: %v", i, err)
+		}
+	}
+
+	r, err := s.Report(t0.Add(10 * time.Minute))
+	if err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+	// a: 2000 + 3000 up, 5000 + 1000 down; b: 100 + 50 up
+	if r.Hour.Upload.Bytes != 5150 || r.Hour.Download.Bytes != 6000 {
+		t.Errorf("hour = %+v, want 5150 up, 6000 down", r.Hour.Traffic)
+	}
+	if len(r.Hour.Clients) != 2 || r.Hour.Clients[0].Client != "192.168.1.10" || r.Hour.Clients[0].Bytes() != 11000 {
+		t.Errorf("unexpected clients: %+v", r.Hour.Clients)
+	}
+	if r.Hour.Clients[1].Route != "wgc1" || r.Hour.Clients[1].Upload.Bytes != 150 {
+		t.Errorf("unexpected second client: %+v", r.Hour.Clients[1])
+	}
+	if len(r.Day.Routes) != 2 || r.Day.Routes[0].Route != "xray" || r.Day.Routes[0].Client != "" {
+		t.Errorf("unexpected routes: %+v", r.Day.Routes)
+	}
+	if r.Month.Traffic != r.Day.Traffic {
+		t.Errorf("month = %+v, want %+v", r.Month.Traffic, r.Day.Traffic)
+	}
+}
+
+func TestStore_Windows(t *testing.T) {
+	s := newTestStore(t)
+	key := UsageKey("192.168.1.10", "xray")
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Baseline, then 100 bytes a day and a half later, then 10 bytes now.
+	steps := []struct {
+		at    time.Time
+		bytes uint64
+	}{
+		{t0, 0},
+		{t0.Add(36 * time.Hour), 100},
+		{t0.Add(72 * time.Hour), 110},
+	}
+	for _, st := range steps {
+		if err := s.Record(st.at, map[string]Traffic{key: {Download: Counter{Bytes: st.bytes}}}); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	r, err := s.Report(t0.Add(72*time.Hour + time.Minute))
+	if err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+	if r.Hour.Bytes() != 10 {
+		t.Errorf("hour bytes = %d, want 10", r.Hour.Bytes())
+	}
+	if r.Day.Bytes() != 10 {
+		t.Errorf("day bytes = %d, want 10", r.Day.Bytes())
+	}
+	if r.Month.Bytes() != 110 {
+		t.Errorf("month bytes = %d, want 110", r.Month.Bytes())
+	}
+}
+
+func TestStore_TrimsOldBuckets(t *testing.T) {
+	s := newTestStore(t)
+	key := UsageKey("192.168.1.10", "xray")
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i <= 40; i++ {
+		at := t0.Add(time.Duration(i) * 24 * time.Hour)
+		if err := s.Record(at, map[string]Traffic{key: {Upload: Counter{Bytes: uint64(i) * 10}}}); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	data, err := s.load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(data.Minutes) != 1 {
+		t.Errorf("expected 1 minute bucket, got %d", len(data.Minutes))
+	}
+	if len(data.Hours) > 32 {
+		t.Errorf("expected at most 32 hourly buckets, got %d", len(data.Hours))
+	}
+}
+
+func TestStore_CorruptFileStartsOver(t *testing.T) {
+	s := newTestStore(t)
+	if err := os.WriteFile(s.path, []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Report(time.Now()); err == nil {
+		t.Error("expected Report to fail on corrupt store")
+	}
+	if err := s.Record(time.Now(), map[string]Traffic{}); err != nil {
+		t.Fatalf("Record should recover from corrupt store: %v", err)
+	}
+	if _, err := s.Report(time.Now()); err != nil {
+		t.Errorf("Report after recovery: %v", err)
+	}
+}
+
+func TestStore_OldVersionStartsOver(t *testing.T) {
+	s := newTestStore(t)
+	// Upload-only layout written before download counters
+	old := `{"last_at":"2026-01-10T12:00:00Z","last":{"192.168.1.10 xray":{"packets":10,"bytes":5000}},` +
+		`"minutes":[{"start":"2026-01-10T12:00:00Z","usage":{"192.168.1.10 xray":{"packets":1,"bytes":100}}}]}`
+	if err := os.WriteFile(s.path, []byte(old), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := s.Report(time.Date(2026, 1, 10, 12, 5, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+	if !r.UpdatedAt.IsZero() || len(r.Hour.Clients) != 0 {
+		t.Errorf("expected an empty report, got %+v", r)
+	}
+}
+
+func TestStore_MissingFile(t *testing.T) {
+	s := NewStore(filepath.Join(t.TempDir(), "nested", StoreFile))
+	r, err := s.Report(time.Now())
+	if err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+	if !r.UpdatedAt.IsZero() || r.Month.Clients == nil {
+		t.Errorf("expected empty report with non-nil slices, got %+v", r)
+	}
+}
@@ -34,12 +34,14 @@ var scriptFiles = []string{
 	"router/opt/vpn-director/lib/ipset.sh",
 	"router/opt/vpn-director/lib/tunnel.sh",
 	"router/opt/vpn-director/lib/tproxy.sh",
+	"router/opt/vpn-director/lib/ipv6.sh",
 	"router/opt/vpn-director/lib/send-email.sh",
 	"router/opt/etc/xray/config.json.template",
 	"router/opt/etc/init.d/S99vpn-director",
 	"router/opt/etc/init.d/S98telegram-bot",
 	"router/jffs/scripts/firewall-start",
 	"router/jffs/scripts/wan-event",
+	"router/jffs/scripts/dnsmasq.postconf",
 }
 
 // DownloadRelease downloads all files for the given release.
@@ -0,0 +1,74 @@
+package updater
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Watcher periodically checks for a newer release and remembers the result,
+// so that callers (e.g. the metrics endpoint) can read it without contacting
+// GitHub. Dev builds are never checked.
+type Watcher struct {
+	updater        Updater
+	currentVersion string
+
+	mu        sync.Mutex
+	latest    string
+	available bool
+	checked   bool
+}
+
+// NewWatcher creates a watcher for currentVersion.
+func NewWatcher(upd Updater, currentVersion string) *Watcher {
+	return &Watcher{updater: upd, currentVersion: currentVersion}
+}
+
+// Run checks for updates on an interval. Blocks until ctx is cancelled.
+func (w *Watcher) Run(ctx context.Context, interval time.Duration) {
+	if w.currentVersion == "dev" {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		w.checkOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Status returns the latest release tag and whether it is newer than the
+// running version. ok is false until the first successful check.
+func (w *Watcher) Status() (latest string, available, ok bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.latest, w.available, w.checked
+}
+
+func (w *Watcher) checkOnce(ctx context.Context) {
+	release, err := w.updater.GetLatestRelease(ctx)
+	if err != nil {
+		slog.Warn("Failed to check for updates", "error", err)
+		return
+	}
+
+	available, err := w.updater.ShouldUpdate(w.currentVersion, release.TagName)
+	if err != nil {
+		slog.Warn("Failed to compare versions", "error", err)
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.latest = release.TagName
+	w.available = available
+	w.checked = true
+}
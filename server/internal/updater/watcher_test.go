@@ -0,0 +1,73 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeUpdater stubs the release check; other Updater methods are unused.
+type fakeUpdater struct {
+	Updater
+	tag string
+	err error
+}
+
+func (f *fakeUpdater) GetLatestRelease(context.Context) (*Release, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &Release{TagName: f.tag}, nil
+}
+
+func (f *fakeUpdater) ShouldUpdate(current, latest string) (bool, error) {
+	return New().ShouldUpdate(current, latest)
+}
+
+func TestWatcher_Available(t *testing.T) {
+	w := NewWatcher(&fakeUpdater{tag: "v1.3.0"}, "v1.2.0")
+	w.checkOnce(context.Background())
+
+	latest, available, ok := w.Status()
+	if !ok || !available || latest != "v1.3.0" {
+		t.Errorf("got latest=%q available=%v ok=%v", latest, available, ok)
+	}
+}
+
+func TestWatcher_UpToDate(t *testing.T) {
+	w := NewWatcher(&fakeUpdater{tag: "v1.2.0"}, "v1.2.0")
+	w.checkOnce(context.Background())
+
+	if _, available, ok := w.Status(); !ok || available {
+		t.Errorf("expected checked and up to date, got available=%v ok=%v", available, ok)
+	}
+}
+
+func TestWatcher_ErrorKeepsUnchecked(t *testing.T) {
+	w := NewWatcher(&fakeUpdater{err: errors.New("offline")}, "v1.2.0")
+	w.checkOnce(context.Background())
+
+	if _, _, ok := w.Status(); ok {
+		t.Error("expected no status after failed check")
+	}
+}
+
+func TestWatcher_DevVersionNotChecked(t *testing.T) {
+	w := NewWatcher(&fakeUpdater{tag: "v1.3.0"}, "dev")
+
+	done := make(chan struct{})
+	go func() {
+		w.Run(context.Background(), time.Hour)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run should return immediately for dev builds")
+	}
+	if _, _, ok := w.Status(); ok {
+		t.Error("dev build should not be checked")
+	}
+}
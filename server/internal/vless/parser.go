@@ -35,8 +35,12 @@ type Server struct {
 	UUID    string   `json:"uuid"`
 	Name    string   `json:"name"`
 	IPs     []string `json:"ips"`
+	IPs6    []string `json:"ips6,omitempty"`
 }
 
+// lookupIP is replaced in tests to avoid real DNS queries
+var lookupIP = net.LookupIP
+
 func ParseURI(uri string) (*Server, error) {
 	if !strings.HasPrefix(uri, "vless://") {
 		return nil, errors.New("not a vless URI")
@@ -113,21 +117,27 @@ func ParseURI(uri string) (*Server, error) {
 	}, nil
 }
 
+// ResolveIPs resolves the server address into IPv4 (IPs) and IPv6 (IPs6)
+// addresses. Fails only if neither family resolves.
 func (s *Server) ResolveIPs() error {
-	ips, err := net.LookupIP(s.Address)
+	// IPv6 literals are stored in URI form ("[2001:db8::1]")
+	ips, err := lookupIP(strings.Trim(s.Address, "[]"))
 	if err != nil {
 		return err
 	}
-	var resolved []string
+	var v4, v6 []string
 	for _, ip := range ips {
 		if ipv4 := ip.To4(); ipv4 != nil {
-			resolved = append(resolved, ipv4.String())
+			v4 = append(v4, ipv4.String())
+		} else if ip.To16() != nil {
+			v6 = append(v6, ip.String())
 		}
 	}
-	if len(resolved) == 0 {
-		return fmt.Errorf("no IPv4 addresses found for %s", s.Address)
+	if len(v4) == 0 && len(v6) == 0 {
+		return fmt.Errorf("no IP addresses found for %s", s.Address)
 	}
-	s.IPs = resolved
+	s.IPs = v4
+	s.IPs6 = v6
 	return nil
 }
 
@@ -2,6 +2,8 @@ package vless
 
 import (
 	"encoding/base64"
+	"net"
+	"reflect"
 	"testing"
 )
 
@@ -334,6 +336,53 @@ func TestResolveIPs_InvalidHostname(t *testing.T) {
 	}
 }
 
+func TestResolveIPs_SplitsFamilies(t *testing.T) {
+	orig := lookupIP
+	defer func() { lookupIP = orig }()
+
+	var looked string
+	lookupIP = func(host string) ([]net.IP, error) {
+		looked = host
+		return []net.IP{
+			net.ParseIP("203.0.113.10"),
+			net.ParseIP("2001:db8::10"),
+			net.ParseIP("198.51.100.1"),
+		}, nil
+	}
+
+	server := &Server{Address: "[2001:db8::10]"}
+	if err := server.ResolveIPs(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if looked != "2001:db8::10" {
+		t.Errorf("expected brackets stripped before lookup, got %q", looked)
+	}
+	if want := []string{"203.0.113.10", "198.51.100.1"}; !reflect.DeepEqual(server.IPs, want) {
+		t.Errorf("IPs = %v, want %v", server.IPs, want)
+	}
+	if want := []string{"2001:db8::10"}; !reflect.DeepEqual(server.IPs6, want) {
+		t.Errorf("IPs6 = %v, want %v", server.IPs6, want)
+	}
+}
+
+func TestResolveIPs_IPv6Only(t *testing.T) {
+	orig := lookupIP
+	defer func() { lookupIP = orig }()
+
+	lookupIP = func(string) ([]net.IP, error) {
+		return []net.IP{net.ParseIP("2001:db8::10")}, nil
+	}
+
+	server := &Server{Address: "v6only.example.com"}
+	if err := server.ResolveIPs(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(server.IPs) != 0 || len(server.IPs6) != 1 {
+		t.Errorf("unexpected result: IPs=%v IPs6=%v", server.IPs, server.IPs6)
+	}
+}
+
 func TestDecodeSubscription_RawStdBase64(t *testing.T) {
 	// Raw standard base64 (without padding)
 	rawContent := "vless://uuid@server.com:443#RawTest"
@@ -0,0 +1,124 @@
+package vpnconfig
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/cidrset"
+)
+
+// ListChange is the before/after state of one address list.
+type ListChange struct {
+	List   string   `json:"list"`
+	Before []string `json:"before"`
+	After  []string `json:"after"`
+}
+
+// OptimizeReport describes what Optimize changed or would change.
+type OptimizeReport struct {
+	Changes   []ListChange       `json:"changes"`
+	Conflicts []cidrset.Conflict `json:"conflicts"`
+	Invalid   []string           `json:"invalid"`
+}
+
+// Lists returns the changed lists before and after, keyed by list name.
+func (r OptimizeReport) Lists() (before, after map[string][]string) {
+	before = make(map[string][]string, len(r.Changes))
+	after = make(map[string][]string, len(r.Changes))
+	for _, c := range r.Changes {
+		before[c.List], after[c.List] = c.Before, c.After
+	}
+	return before, after
+}
+
+// Fingerprint identifies the changes of a preview, so applying can check
+// that it writes what the user confirmed.
+func (r OptimizeReport) Fingerprint() string {
+	h := sha256.New()
+	for _, c := range r.Changes {
+		fmt.Fprintf(h, "%s\x00%s\x00%s\x00", c.List, strings.Join(c.Before, ","), strings.Join(c.After, ","))
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// Optimize aggregates exclude_ips into the minimal CIDR list and reports the
+// change, invalid entries, and active clients overlapping across routes.
+// Client lists are only analysed, never rewritten: merging them would fold
+// single hosts into ranges that can no longer be paused one by one.
+func Optimize(cfg *VPNDirectorConfig) OptimizeReport {
+	report := OptimizeReport{
+		Changes:   []ListChange{},
+		Conflicts: []cidrset.Conflict{},
+		Invalid:   []string{},
+	}
+
+	entries := cfg.Xray.ExcludeIPs
+	after, invalid := cidrset.Aggregate(entries)
+	// Invalid entries are kept so nothing is silently lost
+	after = append(after, invalid...)
+	report.Invalid = append(report.Invalid, invalid...)
+	if len(entries) > 0 && !equalStrings(entries, after) {
+		report.Changes = append(report.Changes, ListChange{List: "xray.exclude_ips", Before: entries, After: after})
+		cfg.Xray.ExcludeIPs = after
+	}
+
+	if conflicts := ClientConflicts(cfg); conflicts != nil {
+		report.Conflicts = conflicts
+	}
+	return report
+}
+
+// ClientConflicts reports active clients whose addresses overlap a client
+// of another route (e.g. a host inside a subnet assigned elsewhere).
+func ClientConflicts(cfg *VPNDirectorConfig) []cidrset.Conflict {
+	paused := pausedSet(cfg)
+	routes := map[string][]string{"xray": activeOnly(cfg.Xray.Clients, paused)}
+	for name, tunnel := range cfg.TunnelDirector.Tunnels {
+		routes[name] = activeOnly(tunnel.Clients, paused)
+	}
+	return cidrset.FindConflicts(routes)
+}
+
+// ClientConflictsOf returns the client conflicts that involve entry, as
+// warnings for a client that was just added.
+func ClientConflictsOf(cfg *VPNDirectorConfig, entry string) []cidrset.Conflict {
+	var conflicts []cidrset.Conflict
+	for _, c := range ClientConflicts(cfg) {
+		if c.Entry == entry || c.Overlaps == entry {
+			conflicts = append(conflicts, c)
+		}
+	}
+	return conflicts
+}
+
+func pausedSet(cfg *VPNDirectorConfig) map[string]bool {
+	paused := make(map[string]bool, len(cfg.PausedClients))
+	for _, ip := range cfg.PausedClients {
+		paused[ip] = true
+	}
+	return paused
+}
+
+func activeOnly(entries []string, paused map[string]bool) []string {
+	active := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !paused[e] {
+			active = append(active, e)
+		}
+	}
+	return active
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
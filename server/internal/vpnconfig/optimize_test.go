@@ -0,0 +1,128 @@
+package vpnconfig
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOptimize(t *testing.T) {
+	cfg := &VPNDirectorConfig{
+		PausedClients: []string{"192.168.1.200"},
+		Xray: XrayConfig{
+			Clients:    []string{"192.168.1.0/25", "192.168.1.128/25", "192.168.1.200"},
+			ExcludeIPs: []string{"10.0.0.0/9", "10.128.0.0/9", "bad-ip"},
+		},
+		TunnelDirector: TunnelDirectorConfig{
+			Tunnels: map[string]TunnelConfig{
+				"wgc1": {Clients: []string{"192.168.1.50"}},
+				"wgc2": {Clients: []string{"192.168.3.1"}},
+			},
+		},
+	}
+
+	report := Optimize(cfg)
+
+	// Clients are reported, never merged
+	if want := []string{"192.168.1.0/25", "192.168.1.128/25", "192.168.1.200"}; !reflect.DeepEqual(cfg.Xray.Clients, want) {
+		t.Errorf("xray clients = %v, want %v", cfg.Xray.Clients, want)
+	}
+	if want := []string{"10.0.0.0/8", "bad-ip"}; !reflect.DeepEqual(cfg.Xray.ExcludeIPs, want) {
+		t.Errorf("exclude_ips = %v, want %v", cfg.Xray.ExcludeIPs, want)
+	}
+
+	if len(report.Changes) != 1 || report.Changes[0].List != "xray.exclude_ips" {
+		t.Fatalf("expected only exclude_ips changed, got %+v", report.Changes)
+	}
+	if want := []string{"10.0.0.0/9", "10.128.0.0/9", "bad-ip"}; !reflect.DeepEqual(report.Changes[0].Before, want) {
+		t.Errorf("before = %v, want %v", report.Changes[0].Before, want)
+	}
+	if want := []string{"bad-ip"}; !reflect.DeepEqual(report.Invalid, want) {
+		t.Errorf("invalid = %v, want %v", report.Invalid, want)
+	}
+
+	if len(report.Conflicts) != 1 {
+		t.Fatalf("expected 1 conflict, got %+v", report.Conflicts)
+	}
+	c := report.Conflicts[0]
+	if c.Entry != "192.168.1.50" || c.Route != "wgc1" || c.Overlaps != "192.168.1.0/25" || c.OtherRoute != "xray" {
+		t.Errorf("unexpected conflict: %+v", c)
+	}
+}
+
+func TestOptimize_NothingToDo(t *testing.T) {
+	cfg := &VPNDirectorConfig{
+		Xray: XrayConfig{Clients: []string{"192.168.1.10"}},
+	}
+
+	report := Optimize(cfg)
+
+	if len(report.Changes) != 0 || len(report.Conflicts) != 0 || len(report.Invalid) != 0 {
+		t.Errorf("expected empty report, got %+v", report)
+	}
+	if cfg.Xray.ExcludeIPs != nil {
+		t.Errorf("expected nil exclude_ips to stay nil, got %v", cfg.Xray.ExcludeIPs)
+	}
+}
+
+func TestOptimizeReport_Lists(t *testing.T) {
+	report := OptimizeReport{Changes: []ListChange{
+		{List: "exclude_ips", Before: []string{"10.0.0.0/25", "10.0.0.128/25"}, After: []string{"10.0.0.0/24"}},
+	}}
+
+	before, after := report.Lists()
+	if len(before) != 1 || len(before["exclude_ips"]) != 2 {
+		t.Errorf("unexpected before %v", before)
+	}
+	if len(after) != 1 || after["exclude_ips"][0] != "10.0.0.0/24" {
+		t.Errorf("unexpected after %v", after)
+	}
+}
+
+func TestOptimizeReport_Fingerprint(t *testing.T) {
+	a := OptimizeReport{Changes: []ListChange{
+		{List: "xray.exclude_ips", Before: []string{"10.0.0.0/25", "10.0.0.128/25"}, After: []string{"10.0.0.0/24"}},
+	}}
+	b := OptimizeReport{Changes: []ListChange{
+		{List: "xray.exclude_ips", Before: []string{"10.0.0.0/25", "10.0.0.128/25", "10.0.1.0/24"}, After: []string{"10.0.0.0/23"}},
+	}}
+
+	if a.Fingerprint() != a.Fingerprint() {
+		t.Error("expected a stable fingerprint")
+	}
+	if a.Fingerprint() == b.Fingerprint() {
+		t.Error("expected different changes to differ")
+	}
+	if len(a.Fingerprint()) != 16 {
+		t.Errorf("unexpected fingerprint %q", a.Fingerprint())
+	}
+}
+
+func TestClientConflicts_IgnoresPaused(t *testing.T) {
+	cfg := &VPNDirectorConfig{
+		PausedClients: []string{"192.168.1.10"},
+		Xray:          XrayConfig{Clients: []string{"192.168.1.0/24"}},
+		TunnelDirector: TunnelDirectorConfig{
+			Tunnels: map[string]TunnelConfig{
+				"wgc1": {Clients: []string{"192.168.1.10"}},
+			},
+		},
+	}
+	if got := ClientConflicts(cfg); len(got) != 0 {
+		t.Errorf("expected no conflicts, got %v", got)
+	}
+}
+
+func TestClientConflictsOf(t *testing.T) {
+	cfg := &VPNDirectorConfig{
+		Xray: XrayConfig{Clients: []string{"192.168.1.0/24", "10.0.0.5"}},
+		TunnelDirector: TunnelDirectorConfig{
+			Tunnels: map[string]TunnelConfig{
+				"wgc1": {Clients: []string{"192.168.1.10", "10.0.0.0/8"}},
+			},
+		},
+	}
+	got := ClientConflictsOf(cfg, "192.168.1.10")
+	if len(got) != 1 || got[0].Overlaps != "192.168.1.0/24" || got[0].OtherRoute != "xray" {
+		t.Errorf("expected only the conflict of 192.168.1.10, got %v", got)
+	}
+}
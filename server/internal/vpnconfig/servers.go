@@ -0,0 +1,79 @@
+package vpnconfig
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// serverIDLen is the number of hex characters kept from the hash.
+const serverIDLen = 8
+
+// ServerID returns the stable ID of a server, derived from its UUID, address
+// and port. Renaming a server or re-resolving its IPs keeps the ID.
+func ServerID(s Server) string {
+	sum := sha256.Sum256([]byte(s.UUID + "@" + s.Address + ":" + strconv.Itoa(s.Port)))
+	return hex.EncodeToString(sum[:])[:serverIDLen]
+}
+
+// AssignServerIDs fills in missing server IDs in place. Servers written by
+// older versions or by the shell import script have no ID; identical servers
+// get a numeric suffix so IDs stay unique. Existing IDs are kept.
+func AssignServerIDs(servers []Server) {
+	used := make(map[string]bool, len(servers))
+	for _, s := range servers {
+		if s.ID != "" {
+			used[s.ID] = true
+		}
+	}
+	for i := range servers {
+		if servers[i].ID != "" {
+			continue
+		}
+		base := ServerID(servers[i])
+		id := base
+		for n := 2; used[id]; n++ {
+			id = fmt.Sprintf("%s-%d", base, n)
+		}
+		servers[i].ID = id
+		used[id] = true
+	}
+}
+
+// FindServer returns the index of the server with the given ID, or -1.
+func FindServer(servers []Server, id string) int {
+	for i, s := range servers {
+		if s.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// FindDuplicate returns the index of a server with the same address, port
+// and UUID as s (ignoring the server at index skip), or -1.
+func FindDuplicate(servers []Server, s Server, skip int) int {
+	for i, other := range servers {
+		if i == skip {
+			continue
+		}
+		if other.Address == s.Address && other.Port == s.Port && other.UUID == s.UUID {
+			return i
+		}
+	}
+	return -1
+}
+
+// FindActive returns the index of the server a generated Xray config points
+// to, or -1. active holds the address, port and UUID read from the config.
+func FindActive(servers []Server, active Server) int {
+	for i, s := range servers {
+		// Stored IPv6 literals keep their brackets; the config has them bare
+		if strings.Trim(s.Address, "[]") == active.Address && s.Port == active.Port && s.UUID == active.UUID {
+			return i
+		}
+	}
+	return -1
+}
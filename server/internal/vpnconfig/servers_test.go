@@ -0,0 +1,105 @@
+package vpnconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestServerID_Stable(t *testing.T) {
+	s := Server{Address: "a.example.com", Port: 443, UUID: "uuid1", Name: "A"}
+	id := ServerID(s)
+	if len(id) != serverIDLen {
+		t.Fatalf("expected %d-char ID, got %q", serverIDLen, id)
+	}
+
+	renamed := s
+	renamed.Name = "Renamed"
+	renamed.IPs = []string{"1.2.3.4"}
+	if ServerID(renamed) != id {
+		t.Error("ID must not depend on name or IPs")
+	}
+
+	moved := s
+	moved.Port = 8443
+	if ServerID(moved) == id {
+		t.Error("ID must depend on port")
+	}
+}
+
+func TestAssignServerIDs(t *testing.T) {
+	servers := []Server{
+		{ID: "keep", Address: "a.example.com", Port: 443, UUID: "uuid1"},
+		{Address: "b.example.com", Port: 443, UUID: "uuid2"},
+		{Address: "b.example.com", Port: 443, UUID: "uuid2"},
+	}
+	AssignServerIDs(servers)
+
+	if servers[0].ID != "keep" {
+		t.Errorf("existing ID changed: %q", servers[0].ID)
+	}
+	base := ServerID(servers[1])
+	if servers[1].ID != base {
+		t.Errorf("expected %q, got %q", base, servers[1].ID)
+	}
+	if servers[2].ID != base+"-2" {
+		t.Errorf("expected %q, got %q", base+"-2", servers[2].ID)
+	}
+}
+
+func TestLoadServers_AssignsIDs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "servers.json")
+	content := `[{"address": "a.example.com", "port": 443, "uuid": "uuid1", "name": "A", "ips": ["1.2.3.4"]}]`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	first, err := LoadServers(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := LoadServers(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first[0].ID == "" || first[0].ID != second[0].ID {
+		t.Errorf("expected stable non-empty ID, got %q and %q", first[0].ID, second[0].ID)
+	}
+}
+
+func TestFindServer(t *testing.T) {
+	servers := []Server{{ID: "aaa"}, {ID: "bbb"}}
+	if got := FindServer(servers, "bbb"); got != 1 {
+		t.Errorf("FindServer(bbb) = %d, want 1", got)
+	}
+	if got := FindServer(servers, "zzz"); got != -1 {
+		t.Errorf("FindServer(zzz) = %d, want -1", got)
+	}
+}
+
+func TestFindDuplicate(t *testing.T) {
+	servers := []Server{
+		{Address: "a.example.com", Port: 443, UUID: "uuid1"},
+		{Address: "b.example.com", Port: 443, UUID: "uuid2"},
+	}
+	dup := Server{Address: "b.example.com", Port: 443, UUID: "uuid2", Name: "other name"}
+	if got := FindDuplicate(servers, dup, -1); got != 1 {
+		t.Errorf("FindDuplicate = %d, want 1", got)
+	}
+	if got := FindDuplicate(servers, dup, 1); got != -1 {
+		t.Errorf("FindDuplicate skipping self = %d, want -1", got)
+	}
+}
+
+func TestFindActive(t *testing.T) {
+	servers := []Server{
+		{Address: "a.example.com", Port: 443, UUID: "uuid1"},
+		{Address: "[2001:db8::1]", Port: 443, UUID: "uuid2"},
+	}
+	if got := FindActive(servers, Server{Address: "2001:db8::1", Port: 443, UUID: "uuid2"}); got != 1 {
+		t.Errorf("FindActive(bare IPv6) = %d, want 1", got)
+	}
+	if got := FindActive(servers, Server{Address: "a.example.com", Port: 8443, UUID: "uuid1"}); got != -1 {
+		t.Errorf("FindActive(other port) = %d, want -1", got)
+	}
+}
@@ -4,14 +4,46 @@ import (
 	"encoding/json"
 	"os"
 	"sort"
+	"strconv"
+	"strings"
 )
 
+// DefaultXraySocksPort is the port of Xray's local SOCKS inbound.
+const DefaultXraySocksPort = 12346
+
 type Server struct {
+	// ID is stable across renames and re-resolves (see ServerID).
+	ID      string   `json:"id,omitempty"`
 	Address string   `json:"address"`
 	Port    int      `json:"port"`
 	UUID    string   `json:"uuid"`
 	Name    string   `json:"name"`
 	IPs     []string `json:"ips"`
+	IPs6    []string `json:"ips6,omitempty"`
+}
+
+// AllIPs returns the server's IPv4 addresses followed by its IPv6 addresses.
+func (s Server) AllIPs() []string {
+	ips := make([]string, 0, len(s.IPs)+len(s.IPs6))
+	ips = append(ips, s.IPs...)
+	return append(ips, s.IPs6...)
+}
+
+// ServerIPs returns the unique, sorted IPv4 and IPv6 addresses of all servers,
+// as stored in xray.servers.
+func ServerIPs(servers []Server) []string {
+	seen := make(map[string]bool)
+	var ips []string
+	for _, s := range servers {
+		for _, ip := range s.AllIPs() {
+			if ip != "" && !seen[ip] {
+				seen[ip] = true
+				ips = append(ips, ip)
+			}
+		}
+	}
+	sort.Strings(ips)
+	return ips
 }
 
 type WebUIConfig struct {
@@ -19,6 +51,49 @@ type WebUIConfig struct {
 	CertFile  string `json:"cert_file,omitempty"`
 	KeyFile   string `json:"key_file,omitempty"`
 	JWTSecret string `json:"jwt_secret,omitempty"`
+	// MetricsToken and MetricsAllow protect GET /metrics (Bearer token and/or
+	// allowed IPs/CIDRs). The endpoint is disabled when both are empty.
+	MetricsToken string   `json:"metrics_token,omitempty"`
+	MetricsAllow []string `json:"metrics_allow,omitempty"`
+	// Roles maps usernames to admin, operator or viewer. Users not listed
+	// are admins; local users have their own role instead.
+	Roles map[string]string `json:"roles,omitempty"`
+	// OperatorClients maps operator usernames to the clients (IPs or CIDRs
+	// as listed in the config) they may pause and resume. Operators cannot
+	// pause or resume anyone else.
+	OperatorClients map[string][]string `json:"operator_clients,omitempty"`
+	// AuthBackends lists where login passwords are checked, in order:
+	// "local" (Web UI accounts in the data dir) and "shadow" (/etc/shadow).
+	// Both when empty.
+	AuthBackends []string `json:"auth_backends,omitempty"`
+	// TOTPKey encrypts two-factor secrets in the data dir. Generated on
+	// first start when empty; changing it disables existing enrollments.
+	TOTPKey string `json:"totp_key,omitempty"`
+	// TelegramLogin lets bot users approve Web UI logins from Telegram
+	// instead of a password.
+	TelegramLogin bool `json:"telegram_login,omitempty"`
+	// Listen lists interfaces (e.g. "br0"), IPs or host:port pairs to serve
+	// on; the LAN bridge when empty. "*" means every interface.
+	Listen []string `json:"listen,omitempty"`
+	// Allow and Deny list IPs, CIDRs or interfaces clients may or may not
+	// connect from. Allow defaults to the LAN bridge subnets; guest network
+	// bridges are denied unless allowed by name.
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+	// RedirectPort, when set, serves plain HTTP on this port redirecting to
+	// HTTPS on Port.
+	RedirectPort int `json:"redirect_port,omitempty"`
+	// ACME obtains certificates for public domain names instead of the
+	// certificate in cert_file. Disabled when nil or without domains.
+	ACME *ACMEConfig `json:"acme,omitempty"`
+}
+
+// ACMEConfig configures certificates from an ACME CA.
+type ACMEConfig struct {
+	// DirectoryURL is the CA's ACME directory; Let's Encrypt when empty.
+	DirectoryURL string   `json:"directory_url,omitempty"`
+	Email        string   `json:"email,omitempty"`
+	Domains      []string `json:"domains"`
 }
 
 type VPNDirectorConfig struct {
@@ -33,6 +108,7 @@ type VPNDirectorConfig struct {
 type TunnelConfig struct {
 	Clients []string `json:"clients"`
 	Exclude []string `json:"exclude"`
+	Domains []string `json:"domains,omitempty"`
 }
 
 type TunnelDirectorConfig struct {
@@ -47,6 +123,29 @@ type XrayConfig struct {
 	Servers     []string `json:"servers"`
 	ExcludeIPs  []string `json:"exclude_ips"`
 	ExcludeSets []string `json:"exclude_sets"`
+	// ExcludeDomains bypass Xray via a dnsmasq-populated ipset.
+	ExcludeDomains []string `json:"exclude_domains,omitempty"`
+}
+
+// XraySocksAddr returns the address of Xray's local SOCKS inbound
+// (advanced.xray.socks_port, default 12346).
+func XraySocksAddr(cfg *VPNDirectorConfig) string {
+	port := DefaultXraySocksPort
+	if sec, ok := cfg.Advanced["xray"].(map[string]interface{}); ok {
+		if v, ok := sec["socks_port"].(float64); ok && v > 0 {
+			port = int(v)
+		}
+	}
+	return "127.0.0.1:" + strconv.Itoa(port)
+}
+
+// TunnelInterface maps a routing table name to its network interface:
+// WireGuard clients use their own name (wgc1), OpenVPN client N uses tun1N.
+func TunnelInterface(name string) string {
+	if n, ok := strings.CutPrefix(name, "ovpnc"); ok && n != "" {
+		return "tun1" + n
+	}
+	return name
 }
 
 // ClientInfo represents a VPN client with its route and pause status.
@@ -100,10 +199,16 @@ func LoadServers(path string) ([]Server, error) {
 		return nil, err
 	}
 	var servers []Server
-	return servers, json.Unmarshal(data, &servers)
+	if err := json.Unmarshal(data, &servers); err != nil {
+		return nil, err
+	}
+	AssignServerIDs(servers)
+	return servers, nil
 }
 
+// SaveServers writes servers to path, assigning IDs to servers without one.
 func SaveServers(path string, servers []Server) error {
+	AssignServerIDs(servers)
 	data, err := json.MarshalIndent(servers, "", "  ")
 	if err != nil {
 		return err
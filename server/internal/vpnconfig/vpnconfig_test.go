@@ -511,3 +511,40 @@ func TestSaveVPNDirectorConfig_FormattedJSON(t *testing.T) {
 		t.Error("expected properly formatted JSON")
 	}
 }
+
+func TestServerIPs_DualStack(t *testing.T) {
+	servers := []Server{
+		{Address: "a.example.com", IPs: []string{"203.0.113.2"}, IPs6: []string{"2001:db8::2"}},
+		{Address: "b.example.com", IPs: []string{"203.0.113.1", "203.0.113.2"}},
+		{Address: "c.example.com", IPs6: []string{"2001:db8::1"}},
+	}
+
+	got := ServerIPs(servers)
+	want := []string{"2001:db8::1", "2001:db8::2", "203.0.113.1", "203.0.113.2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if all := servers[0].AllIPs(); !reflect.DeepEqual(all, []string{"203.0.113.2", "2001:db8::2"}) {
+		t.Errorf("AllIPs = %v", all)
+	}
+}
+
+func TestXraySocksAddr(t *testing.T) {
+	if got := XraySocksAddr(&VPNDirectorConfig{}); got != "127.0.0.1:12346" {
+		t.Errorf("expected default address, got %q", got)
+	}
+	cfg := &VPNDirectorConfig{Advanced: map[string]interface{}{"xray": map[string]interface{}{"socks_port": float64(1080)}}}
+	if got := XraySocksAddr(cfg); got != "127.0.0.1:1080" {
+		t.Errorf("expected configured port, got %q", got)
+	}
+}
+
+func TestTunnelInterface(t *testing.T) {
+	tests := map[string]string{"wgc1": "wgc1", "ovpnc1": "tun11", "ovpnc5": "tun15", "ovpnc": "ovpnc"}
+	for name, want := range tests {
+		if got := TunnelInterface(name); got != want {
+			t.Errorf("TunnelInterface(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
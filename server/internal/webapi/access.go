@@ -0,0 +1,182 @@
+package webapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/apitoken"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/auth"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/localuser"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+)
+
+// permission is what a route requires of the caller.
+type permission string
+
+const (
+	permView          permission = "view"           // all reads
+	permPauseClients  permission = "pause_clients"  // pause and resume clients
+	permManageClients permission = "manage_clients" // add/remove clients, exclusions
+	permManageServers permission = "manage_servers" // add/edit/switch servers
+	permAdmin         permission = "admin"          // apply, stop, update, tokens, ...
+)
+
+var allPermissions = []permission{permView, permPauseClients, permManageClients, permManageServers, permAdmin}
+
+// rolePermissions grants permissions to web UI roles.
+var rolePermissions = map[string][]permission{
+	auth.RoleAdmin:    allPermissions,
+	auth.RoleOperator: {permView, permPauseClients},
+	auth.RoleViewer:   {permView},
+}
+
+// scopePermissions grants permissions to API token scopes.
+var scopePermissions = map[string][]permission{
+	apitoken.ScopeRead:    {permView},
+	apitoken.ScopeClients: {permView, permPauseClients, permManageClients},
+	apitoken.ScopeServers: {permView, permManageServers},
+	apitoken.ScopeAdmin:   allPermissions,
+}
+
+// principal is the authenticated caller: a logged-in user with a role, or
+// an API token with scopes.
+type principal struct {
+	Name    string
+	Role    string          // session role; empty for API tokens
+	Session string          // session ID; empty for API tokens
+	Token   *apitoken.Token // nil for sessions
+}
+
+// permissions returns what the principal may do, in allPermissions order.
+func (p *principal) permissions() []permission {
+	var granted []permission
+	if p.Token != nil {
+		for _, scope := range p.Token.Scopes {
+			granted = append(granted, scopePermissions[scope]...)
+		}
+	} else {
+		granted = rolePermissions[p.Role]
+	}
+
+	out := []permission{}
+	for _, perm := range allPermissions {
+		if slices.Contains(granted, perm) {
+			out = append(out, perm)
+		}
+	}
+	return out
+}
+
+// can reports whether the principal has perm.
+func (p *principal) can(perm permission) bool {
+	return slices.Contains(p.permissions(), perm)
+}
+
+type principalKey struct{}
+
+func withPrincipal(ctx context.Context, p *principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// principalFrom returns the caller set by authMiddleware, or nil.
+func principalFrom(ctx context.Context) *principal {
+	p, _ := ctx.Value(principalKey{}).(*principal)
+	return p
+}
+
+// require wraps a protected route with a permission check. Returns 403 if
+// the caller lacks perm.
+func require(perm permission, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p := principalFrom(r.Context())
+		if p == nil || !p.can(perm) {
+			jsonError(w, http.StatusForbidden, "forbidden: requires "+string(perm)+" permission")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// ownsClient reports whether the caller may pause or resume client. Callers
+// who may manage clients control every client; operators only the ones
+// listed for them in webui.operator_clients.
+func ownsClient(p *principal, cfg *vpnconfig.VPNDirectorConfig, client string) bool {
+	if p == nil || p.can(permManageClients) {
+		return true
+	}
+	return slices.Contains(cfg.WebUI.OperatorClients[p.Name], client)
+}
+
+// userRole returns the role of a local user, or of username in webui.roles
+// for everyone else. Users not listed are admins, so setups without roles
+// keep full access; an unknown role falls back to viewer.
+func userRole(deps *Deps, username string) (string, error) {
+	if deps.Users != nil {
+		u, err := deps.Users.Get(username)
+		if err == nil {
+			return u.Role, nil
+		}
+		if !errors.Is(err, localuser.ErrNotFound) {
+			return "", err
+		}
+	}
+
+	cfg, err := deps.Config.LoadVPNConfig()
+	if err != nil {
+		return "", err
+	}
+	var roles map[string]string
+	if cfg != nil {
+		roles = cfg.WebUI.Roles
+	}
+	role, ok := roles[username]
+	if !ok {
+		return auth.RoleAdmin, nil
+	}
+	if !auth.ValidRole(role) {
+		slog.Warn("Unknown web UI role, using viewer", "user", username, "role", role)
+		return auth.RoleViewer, nil
+	}
+	return role, nil
+}
+
+// whoamiResponse describes the caller so the SPA can hide actions.
+type whoamiResponse struct {
+	Username    string       `json:"username"`
+	Role        string       `json:"role,omitempty"`
+	Scopes      []string     `json:"scopes,omitempty"`
+	Auth        string       `json:"auth"`            // session or token
+	Local       bool         `json:"local,omitempty"` // a local user, who can change their password
+	Permissions []permission `json:"permissions"`
+	// Clients an operator may pause and resume (see ownsClient)
+	Clients []string `json:"clients,omitempty"`
+}
+
+// handleWhoami returns the authenticated user, role and permissions.
+func handleWhoami(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p := principalFrom(r.Context())
+		if p == nil {
+			jsonError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+
+		resp := whoamiResponse{Username: p.Name, Role: p.Role, Auth: "session", Permissions: p.permissions()}
+		if p.Token != nil {
+			resp.Auth = "token"
+			resp.Scopes = p.Token.Scopes
+		} else if deps.Users != nil {
+			_, err := deps.Users.Get(p.Name)
+			resp.Local = err == nil
+		}
+		if p.can(permPauseClients) && !p.can(permManageClients) {
+			if cfg, err := deps.Config.LoadVPNConfig(); err == nil && cfg != nil {
+				resp.Clients = cfg.WebUI.OperatorClients[p.Name]
+			}
+		}
+		jsonOK(w, resp)
+	}
+}
@@ -0,0 +1,192 @@
+package webapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/apitoken"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/auth"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+)
+
+func newRolesDeps(t *testing.T, roles map[string]string) *Deps {
+	t.Helper()
+	deps := newTestDeps(t)
+	deps.Config = &mockConfig{cfg: &vpnconfig.VPNDirectorConfig{WebUI: vpnconfig.WebUIConfig{
+		Roles:           roles,
+		OperatorClients: map[string][]string{"kid": {"192.168.1.10"}},
+	}}}
+	return deps
+}
+
+func TestPrincipal_Permissions(t *testing.T) {
+	tests := []struct {
+		name string
+		p    principal
+		want []permission
+	}{
+		{"admin", principal{Role: auth.RoleAdmin}, allPermissions},
+		{"operator", principal{Role: auth.RoleOperator}, []permission{permView, permPauseClients}},
+		{"viewer", principal{Role: auth.RoleViewer}, []permission{permView}},
+		{"unknown role", principal{Role: "root"}, []permission{}},
+		{"token scopes", principal{Token: &apitoken.Token{Scopes: []string{apitoken.ScopeServers, apitoken.ScopeClients}}},
+			[]permission{permView, permPauseClients, permManageClients, permManageServers}},
+	}
+	for _, tt := range tests {
+		if got := tt.p.permissions(); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUserRole(t *testing.T) {
+	deps := newRolesDeps(t, map[string]string{"kid": auth.RoleOperator, "guest": "superuser"})
+
+	tests := map[string]string{
+		"kid":   auth.RoleOperator,
+		"guest": auth.RoleViewer, // unknown role: least privilege
+		"admin": auth.RoleAdmin,  // not listed
+	}
+	for user, want := range tests {
+		got, err := userRole(deps, user)
+		if err != nil || got != want {
+			t.Errorf("userRole(%q) = %q, %v; want %q", user, got, err, want)
+		}
+	}
+}
+
+func TestRequire(t *testing.T) {
+	ok := func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) }
+
+	tests := []struct {
+		name string
+		p    *principal
+		want int
+	}{
+		{"no principal", nil, http.StatusForbidden},
+		{"viewer", &principal{Role: auth.RoleViewer}, http.StatusForbidden},
+		{"operator", &principal{Role: auth.RoleOperator}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/api/clients/pause", nil)
+		if tt.p != nil {
+			req = req.WithContext(withPrincipal(req.Context(), tt.p))
+		}
+		rec := httptest.NewRecorder()
+		require(permPauseClients, ok)(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, rec.Code)
+		}
+	}
+}
+
+func TestRouter_RolePermissions(t *testing.T) {
+	deps := newRolesDeps(t, map[string]string{"kid": auth.RoleOperator, "nephew": auth.RoleOperator, "grandma": auth.RoleViewer})
+	router := NewRouter(deps, nil)
+
+	session := func(user, role string) string {
+		token, err := deps.JWT.Create(user, role, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	tests := []struct {
+		name   string
+		token  string
+		method string
+		path   string
+		forbid bool
+	}{
+		{"viewer reads status", session("grandma", auth.RoleViewer), "GET", "/api/status", false},
+		{"viewer cannot pause", session("grandma", auth.RoleViewer), "POST", "/api/clients/pause?ip=192.168.1.10", true},
+		{"operator pauses own client", session("kid", auth.RoleOperator), "POST", "/api/clients/pause?ip=192.168.1.10", false},
+		{"operator resumes own client", session("kid", auth.RoleOperator), "POST", "/api/clients/resume?ip=192.168.1.10", false},
+		{"operator cannot pause other client", session("kid", auth.RoleOperator), "POST", "/api/clients/pause?ip=192.168.1.20", true},
+		{"operator cannot resume other client", session("kid", auth.RoleOperator), "POST", "/api/clients/resume?ip=192.168.1.20", true},
+		{"unlisted operator cannot pause", session("nephew", auth.RoleOperator), "POST", "/api/clients/pause?ip=192.168.1.10", true},
+		{"admin pauses any client", session("admin", auth.RoleAdmin), "POST", "/api/clients/pause?ip=192.168.1.20", false},
+		{"operator cannot stop", session("kid", auth.RoleOperator), "POST", "/api/stop", true},
+		{"operator cannot switch server", session("kid", auth.RoleOperator), "POST", "/api/servers/active", true},
+		{"operator cannot add exclusion", session("kid", auth.RoleOperator), "POST", "/api/excludes/ips", true},
+		{"admin stops", session("admin", auth.RoleAdmin), "POST", "/api/stop", false},
+		{"legacy session uses config role", session("kid", ""), "POST", "/api/stop", true},
+		{"stale role claim is ignored", session("grandma", auth.RoleAdmin), "POST", "/api/stop", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.AddCookie(&http.Cookie{Name: "token", Value: tt.token})
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if forbidden := rec.Code == http.StatusForbidden; forbidden != tt.forbid {
+				t.Errorf("expected forbidden=%v, got %d: %s", tt.forbid, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleWhoami(t *testing.T) {
+	deps := newRolesDeps(t, map[string]string{"kid": auth.RoleOperator})
+	tokens := newTestTokens(t)
+	deps.Tokens = tokens
+	router := NewRouter(deps, nil)
+
+	session, _ := deps.JWT.Create("kid", auth.RoleOperator, "")
+	secret, _, _ := tokens.Create("ha", []string{apitoken.ScopeRead})
+
+	for _, tt := range []struct {
+		bearer string
+		want   whoamiResponse
+	}{
+		{session, whoamiResponse{Username: "kid", Role: auth.RoleOperator, Auth: "session", Permissions: []permission{permView, permPauseClients}, Clients: []string{"192.168.1.10"}}},
+		{secret, whoamiResponse{Username: "ha", Scopes: []string{apitoken.ScopeRead}, Auth: "token", Permissions: []permission{permView}}},
+	} {
+		req := httptest.NewRequest("GET", "/api/whoami", nil)
+		req.Header.Set("Authorization", "Bearer "+tt.bearer)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var got whoamiResponse
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if got.Username != tt.want.Username || got.Role != tt.want.Role || got.Auth != tt.want.Auth ||
+			!slices.Equal(got.Scopes, tt.want.Scopes) || !slices.Equal(got.Permissions, tt.want.Permissions) ||
+			!slices.Equal(got.Clients, tt.want.Clients) {
+			t.Errorf("got %+v, want %+v", got, tt.want)
+		}
+	}
+}
+
+func TestHandleLogin_RoleClaim(t *testing.T) {
+	shadowPath := writeShadowFixture(t, "kid:"+testSHA256Hash+":19000:0:99999:7:::\n")
+	deps := newRolesDeps(t, map[string]string{"kid": auth.RoleOperator})
+	deps.Shadow = auth.NewShadowAuth(shadowPath)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/login", strings.NewReader(`{"username":"kid","password":"testpass"}`))
+	handleLogin(deps).ServeHTTP(rec, req)
+
+	var token string
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == "token" {
+			token = c.Value
+		}
+	}
+	claims, err := deps.JWT.Validate(token)
+	if err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if claims.Role != auth.RoleOperator {
+		t.Errorf("expected operator role claim, got %q", claims.Role)
+	}
+}
@@ -0,0 +1,90 @@
+package webapi
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/accesslog"
+)
+
+// handleAccess searches the indexed Xray access log, newest first.
+// Query params: client, destination (substring), outbound, status
+// (accepted|rejected), since (duration, e.g. 30m), limit (default 100, max 1000).
+func handleAccess(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if deps.Access == nil {
+			jsonError(w, http.StatusServiceUnavailable, "access log index is not available")
+			return
+		}
+
+		q, err := parseAccessQuery(r.URL.Query())
+		if err != nil {
+			jsonError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		jsonOK(w, deps.Access.Search(q))
+	}
+}
+
+// handleAccessTop aggregates the indexed access log.
+// Query params: by (destination|client|outbound, default destination) plus
+// the filters accepted by /api/access; limit caps the number of groups.
+func handleAccessTop(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if deps.Access == nil {
+			jsonError(w, http.StatusServiceUnavailable, "access log index is not available")
+			return
+		}
+
+		q, err := parseAccessQuery(r.URL.Query())
+		if err != nil {
+			jsonError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		groups, err := deps.Access.Top(q, r.URL.Query().Get("by"))
+		if err != nil {
+			jsonError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		jsonOK(w, map[string]any{"groups": groups})
+	}
+}
+
+func parseAccessQuery(v url.Values) (accesslog.Query, error) {
+	q := accesslog.Query{
+		Client:      v.Get("client"),
+		Destination: v.Get("destination"),
+		Outbound:    v.Get("outbound"),
+		Status:      v.Get("status"),
+	}
+
+	switch q.Status {
+	case "", accesslog.StatusAccepted, accesslog.StatusRejected:
+	default:
+		return q, errors.New("status must be accepted or rejected")
+	}
+
+	if s := v.Get("since"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return q, errors.New("since must be a positive duration, e.g. 30m or 2h")
+		}
+		q.Since = time.Now().Add(-d)
+	}
+
+	if s := v.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return q, errors.New("limit must be a positive integer")
+		}
+		q.Limit = n
+	}
+
+	return q, nil
+}
@@ -0,0 +1,110 @@
+package webapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/accesslog"
+)
+
+func newTestAccessIndex() *accesslog.Index {
+	now := time.Now()
+	idx := accesslog.NewIndex(100)
+	add := func(ago time.Duration, client, dest, outbound, status string) {
+		idx.Add(accesslog.Record{
+			Time: now.Add(-ago), Source: client, Destination: dest, DestPort: 443,
+			Outbound: outbound, Status: status,
+		})
+	}
+	add(3*time.Hour, "192.168.50.2", "old.example.com", "proxy-out", accesslog.StatusAccepted)
+	add(10*time.Minute, "192.168.50.2", "www.google.com", "proxy-out", accesslog.StatusAccepted)
+	add(5*time.Minute, "192.168.50.2", "www.google.com", "proxy-out", accesslog.StatusAccepted)
+	add(time.Minute, "192.168.50.3", "ya.ru", "direct", accesslog.StatusAccepted)
+	return idx
+}
+
+func TestHandleAccess(t *testing.T) {
+	deps := newTestDeps(t)
+	deps.Access = newTestAccessIndex()
+
+	req := httptest.NewRequest("GET", "/api/access?client=192.168.50.2&since=1h&limit=1", nil)
+	rec := httptest.NewRecorder()
+	handleAccess(deps).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp accesslog.Result
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Total != 2 || len(resp.Records) != 1 {
+		t.Fatalf("expected 1 of 2 records, got %d of %d", len(resp.Records), resp.Total)
+	}
+	if resp.Records[0].Destination != "www.google.com" {
+		t.Errorf("unexpected record: %+v", resp.Records[0])
+	}
+}
+
+func TestHandleAccess_BadParams(t *testing.T) {
+	deps := newTestDeps(t)
+	deps.Access = newTestAccessIndex()
+
+	for _, query := range []string{"since=yesterday", "since=-1h", "limit=0", "status=dropped"} {
+		rec := httptest.NewRecorder()
+		handleAccess(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/access?"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, rec.Code)
+		}
+	}
+}
+
+func TestHandleAccess_NotConfigured(t *testing.T) {
+	deps := newTestDeps(t)
+
+	rec := httptest.NewRecorder()
+	handleAccess(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/access", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503, got %d", rec.Code)
+	}
+}
+
+func TestHandleAccessTop(t *testing.T) {
+	deps := newTestDeps(t)
+	deps.Access = newTestAccessIndex()
+
+	req := httptest.NewRequest("GET", "/api/access/top?by=destination&since=1h", nil)
+	rec := httptest.NewRecorder()
+	handleAccessTop(deps).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Groups []accesslog.Group `json:"groups"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Groups) != 2 || resp.Groups[0].Key != "www.google.com" || resp.Groups[0].Count != 2 {
+		t.Errorf("unexpected groups: %+v", resp.Groups)
+	}
+}
+
+func TestHandleAccessTop_UnknownGroup(t *testing.T) {
+	deps := newTestDeps(t)
+	deps.Access = newTestAccessIndex()
+
+	rec := httptest.NewRecorder()
+	handleAccessTop(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/access/top?by=port", nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,245 @@
+package webapi
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/audit"
+)
+
+const (
+	defaultAuditDays  = 30
+	maxAuditDays      = 365
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+	// maxAuditErrorBody bounds the part of a failed response kept to read
+	// its error message.
+	maxAuditErrorBody = 1024
+)
+
+// auditActions names the actions of the mutating routes; the bot uses the
+// same names. Routes not listed are recorded under their pattern.
+var auditActions = map[string]string{
+	"POST /api/logout":                   "sessions.logout",
+	"DELETE /api/sessions":               "sessions.revoke_all",
+	"DELETE /api/sessions/{id}":          "sessions.revoke",
+	"POST /api/totp/enroll":              "totp.enroll",
+	"POST /api/totp/confirm":             "totp.confirm",
+	"POST /api/totp/disable":             "totp.disable",
+	"POST /api/apply":                    "apply",
+	"POST /api/restart":                  "restart",
+	"POST /api/stop":                     "stop",
+	"POST /api/ipsets/update":            "ipsets.update",
+	"POST /api/speedtest":                "speedtest",
+	"POST /api/tokens":                   "tokens.create",
+	"DELETE /api/tokens/{id}":            "tokens.revoke",
+	"POST /api/cert/generate":            "cert.generate",
+	"POST /api/cert":                     "cert.upload",
+	"POST /api/users":                    "users.add",
+	"DELETE /api/users/{username}":       "users.remove",
+	"PUT /api/users/{username}/password": "users.password",
+	"DELETE /api/bans":                   "bans.clear_all",
+	"DELETE /api/bans/{id}":              "bans.clear",
+	"POST /api/servers/active":           "servers.active",
+	"POST /api/servers/import":           "servers.import",
+	"POST /api/servers":                  "servers.add",
+	"PUT /api/servers/{id}":              "servers.update",
+	"DELETE /api/servers/{id}":           "servers.delete",
+	"POST /api/clients":                  "clients.add",
+	"POST /api/clients/pause":            "clients.pause",
+	"POST /api/clients/resume":           "clients.resume",
+	"DELETE /api/clients":                "clients.delete",
+	"POST /api/excludes/sets":            "excludes.sets",
+	"POST /api/excludes/ips":             "excludes.add",
+	"DELETE /api/excludes/ips":           "excludes.delete",
+	"POST /api/optimize":                 "optimize",
+	"POST /api/domains":                  "domains.add",
+	"DELETE /api/domains":                "domains.delete",
+	"POST /api/update":                   "update",
+}
+
+// auditRecord collects what a request changed; handlers fill it in with
+// auditChange.
+type auditRecord struct {
+	target string
+	before any
+	after  any
+}
+
+type auditKey struct{}
+
+// auditChange attaches the target and the changed values to the request's
+// audit entry. An empty target keeps the one taken from the URL.
+func auditChange(r *http.Request, target string, before, after any) {
+	rec, ok := r.Context().Value(auditKey{}).(*auditRecord)
+	if !ok {
+		return
+	}
+	if target != "" {
+		rec.target = target
+	}
+	rec.before, rec.after = before, after
+}
+
+// auditMiddleware records every mutating API call in deps.Audit once it
+// has finished: the caller, the action named by the route, the target and,
+// when the call failed, the error it returned. Calls to unknown routes are
+// not recorded.
+func auditMiddleware(deps *Deps, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if deps.Audit == nil || r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &auditRecord{}
+		r2 := r.WithContext(context.WithValue(r.Context(), auditKey{}, rec))
+		aw := &auditWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(aw, r2)
+		r.Pattern = r2.Pattern
+		if r2.Pattern == "" {
+			return
+		}
+
+		e := audit.Entry{
+			Source: audit.SourceWeb,
+			Actor:  auditActor(principalFrom(r.Context())),
+			Action: cmp.Or(auditActions[r2.Pattern], r2.Pattern),
+			Target: cmp.Or(rec.target, requestTarget(r2)),
+			Before: rec.before,
+			After:  rec.after,
+		}
+		if aw.status >= http.StatusBadRequest {
+			e.Error = aw.errorMessage()
+		}
+		deps.Audit.Record(e)
+	})
+}
+
+// auditActor names the caller: the username, or "token:<name>" for API
+// tokens.
+func auditActor(p *principal) string {
+	switch {
+	case p == nil:
+		return ""
+	case p.Token != nil:
+		return "token:" + p.Name
+	}
+	return p.Name
+}
+
+// requestTarget returns the path wildcards of the matched route, or the ip
+// query parameter used by the client and exclusion routes.
+func requestTarget(r *http.Request) string {
+	var parts []string
+	for _, seg := range strings.Split(r.Pattern, "/") {
+		if name, ok := strings.CutPrefix(seg, "{"); ok {
+			name = strings.TrimSuffix(strings.TrimSuffix(name, "}"), "...")
+			parts = append(parts, r.PathValue(name))
+		}
+	}
+	if len(parts) > 0 {
+		return strings.Join(parts, "/")
+	}
+	return r.URL.Query().Get("ip")
+}
+
+// auditWriter captures the status and the start of an error response.
+type auditWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (aw *auditWriter) WriteHeader(code int) {
+	aw.status = code
+	aw.ResponseWriter.WriteHeader(code)
+}
+
+func (aw *auditWriter) Write(p []byte) (int, error) {
+	if aw.status >= http.StatusBadRequest && aw.body.Len() < maxAuditErrorBody {
+		aw.body.Write(p[:min(len(p), maxAuditErrorBody-aw.body.Len())])
+	}
+	return aw.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (aw *auditWriter) Unwrap() http.ResponseWriter {
+	return aw.ResponseWriter
+}
+
+// errorMessage returns the "error" field of a failed JSON response, or the
+// status text.
+func (aw *auditWriter) errorMessage() string {
+	var resp struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(aw.body.Bytes(), &resp) == nil && resp.Error != "" {
+		return resp.Error
+	}
+	return strings.TrimSpace(cmp.Or(aw.body.String(), http.StatusText(aw.status)))
+}
+
+// handleAudit returns audit entries, newest first. Query params: days
+// (window, default 30, max 365), source, actor, action (an action or its
+// group, e.g. "clients"), target (substring), limit (default 100, max 1000).
+func handleAudit(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if deps.Audit == nil {
+			jsonError(w, http.StatusServiceUnavailable, "audit log is not available")
+			return
+		}
+
+		filter, err := parseAuditQuery(r.URL.Query())
+		if err != nil {
+			jsonError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		list, err := deps.Audit.List(filter)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to read audit log")
+			return
+		}
+		jsonOK(w, list)
+	}
+}
+
+func parseAuditQuery(v url.Values) (audit.Filter, error) {
+	f := audit.Filter{
+		Source: v.Get("source"),
+		Actor:  v.Get("actor"),
+		Action: v.Get("action"),
+		Target: v.Get("target"),
+		Limit:  defaultAuditLimit,
+	}
+	days := defaultAuditDays
+
+	if f.Source != "" && !slices.Contains(audit.Sources, f.Source) {
+		return f, errors.New("source must be one of web, bot, scheduler")
+	}
+	if s := v.Get("days"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxAuditDays {
+			return f, errors.New("days must be between 1 and 365")
+		}
+		days = n
+	}
+	if s := v.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxAuditLimit {
+			return f, errors.New("limit must be between 1 and 1000")
+		}
+		f.Limit = n
+	}
+	f.Since = time.Now().AddDate(0, 0, -days)
+	return f, nil
+}
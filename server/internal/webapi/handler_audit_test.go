@@ -0,0 +1,226 @@
+package webapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/apitoken"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/audit"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/auth"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+)
+
+// newAuditDeps returns deps with an audit store and a config holding the
+// xray client 192.168.50.10.
+func newAuditDeps(t *testing.T) (*Deps, *audit.Store) {
+	t.Helper()
+	deps := newTestDeps(t)
+	deps.Config = &mockConfig{
+		cfg: &vpnconfig.VPNDirectorConfig{Xray: vpnconfig.XrayConfig{Clients: []string{"192.168.50.10"}}},
+		servers: []vpnconfig.Server{
+			{ID: "s1", Name: "Frankfurt", Address: "fra.example.com", Port: 443, UUID: "uuid-1"},
+		},
+	}
+	store := audit.NewStore(audit.StorePath(t.TempDir()))
+	deps.Audit = store
+	return deps, store
+}
+
+// serveAudited runs a request as p through the audit middleware and the
+// given routes.
+func serveAudited(deps *Deps, routes map[string]http.HandlerFunc, req *http.Request, p *principal) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	for pattern, h := range routes {
+		mux.HandleFunc(pattern, h)
+	}
+	rec := httptest.NewRecorder()
+	auditMiddleware(deps, mux).ServeHTTP(rec, req.WithContext(withPrincipal(req.Context(), p)))
+	return rec
+}
+
+func TestAuditMiddleware_RecordsChange(t *testing.T) {
+	deps, store := newAuditDeps(t)
+	routes := map[string]http.HandlerFunc{"POST /api/clients/pause": handlePauseClient(deps)}
+	admin := &principal{Name: "admin", Role: auth.RoleAdmin}
+
+	rec := serveAudited(deps, routes, httptest.NewRequest("POST", "/api/clients/pause?ip=192.168.50.10", nil), admin)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	list, err := store.List(audit.Filter{})
+	if err != nil || len(list) != 1 {
+		t.Fatalf("expected one entry, got %v, %v", list, err)
+	}
+	e := list[0]
+	if e.Source != audit.SourceWeb || e.Actor != "admin" || e.Action != "clients.pause" || e.Target != "192.168.50.10" || e.Error != "" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+	if before, _ := json.Marshal(e.Before); string(before) != `{"paused":false}` {
+		t.Errorf("unexpected before %s", before)
+	}
+	if after, _ := json.Marshal(e.After); string(after) != `{"paused":true}` {
+		t.Errorf("unexpected after %s", after)
+	}
+}
+
+func TestAuditMiddleware_RecordsFailure(t *testing.T) {
+	deps, store := newAuditDeps(t)
+	routes := map[string]http.HandlerFunc{"DELETE /api/servers/{id}": handleDeleteServer(deps)}
+	token := &principal{Name: "ci", Token: &apitoken.Token{Name: "ci"}}
+
+	rec := serveAudited(deps, routes, httptest.NewRequest("DELETE", "/api/servers/nope", nil), token)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+
+	list, _ := store.List(audit.Filter{})
+	if len(list) != 1 {
+		t.Fatalf("expected one entry, got %d", len(list))
+	}
+	e := list[0]
+	if e.Actor != "token:ci" || e.Action != "servers.delete" || e.Target != "nope" || e.Error != "server not found: nope" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+}
+
+// stoppingVPN stops the Xray process reported by sys.
+type stoppingVPN struct {
+	mockVPN
+	sys *mockSystem
+}
+
+func (m *stoppingVPN) Stop() error {
+	m.sys.running = false
+	return nil
+}
+
+func TestAuditMiddleware_RecordsState(t *testing.T) {
+	deps, store := newAuditDeps(t)
+	sys := &mockSystem{running: true}
+	deps.System = sys
+	deps.VPN = &stoppingVPN{sys: sys}
+	routes := map[string]http.HandlerFunc{
+		"POST /api/stop":           handleStop(deps),
+		"POST /api/excludes/ips":   handleAddExcludeIP(deps),
+		"DELETE /api/excludes/ips": handleDeleteExcludeIP(deps),
+	}
+	admin := &principal{Name: "admin", Role: auth.RoleAdmin}
+
+	serveAudited(deps, routes, httptest.NewRequest("POST", "/api/stop", nil), admin)
+	serveAudited(deps, routes, httptest.NewRequest("POST", "/api/excludes/ips", strings.NewReader(`{"ip":"10.0.0.0/8"}`)), admin)
+	serveAudited(deps, routes, httptest.NewRequest("DELETE", "/api/excludes/ips?ip=10.0.0.0/8", nil), admin)
+
+	list, err := store.List(audit.Filter{})
+	if err != nil || len(list) != 3 {
+		t.Fatalf("expected three entries, got %v, %v", list, err)
+	}
+	want := map[string][2]string{
+		"stop":            {`{"xray_running":true}`, `{"xray_running":false}`},
+		"excludes.add":    {`null`, `["10.0.0.0/8"]`},
+		"excludes.delete": {`["10.0.0.0/8"]`, `[]`},
+	}
+	for _, e := range list {
+		w, ok := want[e.Action]
+		if !ok {
+			t.Errorf("unexpected entry %+v", e)
+			continue
+		}
+		before, _ := json.Marshal(e.Before)
+		after, _ := json.Marshal(e.After)
+		if string(before) != w[0] || string(after) != w[1] {
+			t.Errorf("%s: expected %s -> %s, got %s -> %s", e.Action, w[0], w[1], before, after)
+		}
+	}
+}
+
+func TestAuditMiddleware_OmitsUUID(t *testing.T) {
+	deps, store := newAuditDeps(t)
+	routes := map[string]http.HandlerFunc{"DELETE /api/servers/{id}": handleDeleteServer(deps)}
+
+	rec := serveAudited(deps, routes, httptest.NewRequest("DELETE", "/api/servers/s1", nil), &principal{Name: "admin"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	list, _ := store.List(audit.Filter{})
+	if len(list) != 1 {
+		t.Fatalf("expected one entry, got %d", len(list))
+	}
+	raw, _ := json.Marshal(list[0])
+	if !strings.Contains(string(raw), "Frankfurt") || strings.Contains(string(raw), "uuid-1") {
+		t.Errorf("expected the server without its UUID, got %s", raw)
+	}
+}
+
+func TestAuditMiddleware_SkipsReads(t *testing.T) {
+	deps, store := newAuditDeps(t)
+	routes := map[string]http.HandlerFunc{"GET /api/clients": handleListClients(deps)}
+
+	serveAudited(deps, routes, httptest.NewRequest("GET", "/api/clients", nil), &principal{Name: "admin"})
+	serveAudited(deps, routes, httptest.NewRequest("POST", "/api/unknown", nil), &principal{Name: "admin"})
+
+	if list, _ := store.List(audit.Filter{}); len(list) != 0 {
+		t.Errorf("expected reads and unknown routes not to be recorded, got %+v", list)
+	}
+}
+
+func TestHandleAudit(t *testing.T) {
+	deps, store := newAuditDeps(t)
+	store.Record(audit.Entry{Source: audit.SourceWeb, Actor: "admin", Action: "clients.add", Target: "192.168.50.20"})
+	store.Record(audit.Entry{Source: audit.SourceBot, Actor: "@alice", Action: "servers.active", Target: "s1"})
+
+	rec := httptest.NewRecorder()
+	handleAudit(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/audit?source=bot&action=servers", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var list []audit.Entry
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(list) != 1 || list[0].Actor != "@alice" {
+		t.Errorf("unexpected entries %+v", list)
+	}
+}
+
+func TestHandleAudit_Unavailable(t *testing.T) {
+	deps := newTestDeps(t)
+
+	rec := httptest.NewRecorder()
+	handleAudit(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/audit", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 without an audit log, got %d", rec.Code)
+	}
+}
+
+func TestParseAuditQuery(t *testing.T) {
+	f, err := parseAuditQuery(url.Values{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Limit != defaultAuditLimit {
+		t.Errorf("expected the default limit, got %d", f.Limit)
+	}
+	if got := time.Since(f.Since); got < 29*24*time.Hour || got > 31*24*time.Hour {
+		t.Errorf("expected a 30-day window, got %v", got)
+	}
+
+	for _, q := range []string{"days=0", "days=400", "limit=x", "limit=5000", "source=cron"} {
+		v, _ := url.ParseQuery(q)
+		if _, err := parseAuditQuery(v); err == nil {
+			t.Errorf("%s: expected an error", q)
+		}
+	}
+
+	v, _ := url.ParseQuery("source=scheduler&actor=resolver&limit=5")
+	f, err = parseAuditQuery(v)
+	if err != nil || f.Source != audit.SourceScheduler || f.Actor != "resolver" || f.Limit != 5 {
+		t.Errorf("unexpected filter %+v, %v", f, err)
+	}
+}
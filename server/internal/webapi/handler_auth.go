@@ -1,19 +1,30 @@
 package webapi
 
 import (
+	"errors"
 	"net"
 	"net/http"
+	"strings"
 	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/events"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/session"
 )
 
+// maxLoggedUsername bounds usernames copied into the event history.
+const maxLoggedUsername = 64
+
 // loginRequest is the expected JSON body for POST /api/login.
 type loginRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
 }
 
-// handleLogin returns a handler that authenticates the user against the shadow
-// file, creates a JWT, and sets it as an HttpOnly cookie.
+// handleLogin returns a handler that authenticates the user against the
+// enabled password backends (local users, the shadow file), creates a JWT
+// carrying the user's role, and sets it as an HttpOnly cookie. Users with
+// two-factor authentication get a challenge instead and finish at
+// POST /api/login/totp.
 func handleLogin(deps *Deps) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req loginRequest
@@ -24,48 +35,127 @@ func handleLogin(deps *Deps) http.HandlerFunc {
 
 		ip := remoteIP(r)
 
-		// Rate limit check.
-		if !deps.loginLimiter.allow(ip) {
+		// Rate limit and ban check.
+		if block := loginBlock(deps, ip); block != "" {
+			deps.metrics.loginFailed(block)
+			recordLogin(deps, req.Username, ip, false, strings.ReplaceAll(block, "_", " "))
 			jsonError(w, http.StatusTooManyRequests, "too many login attempts")
 			return
 		}
 
 		// Verify credentials.
-		ok, err := deps.Shadow.Verify(req.Username, req.Password)
+		ok, err := verifyPassword(deps, req.Username, req.Password)
 		if err != nil {
 			jsonError(w, http.StatusInternalServerError, "authentication error")
 			return
 		}
 		if !ok {
-			deps.loginLimiter.record(ip)
+			loginRejected(deps, ip, "bad credentials for "+truncateUsername(req.Username))
+			deps.metrics.loginFailed("bad_credentials")
+			recordLogin(deps, req.Username, ip, false, "bad credentials")
 			jsonError(w, http.StatusUnauthorized, "invalid credentials")
 			return
 		}
 
-		// Create JWT.
-		token, err := deps.JWT.Create(req.Username)
-		if err != nil {
-			jsonError(w, http.StatusInternalServerError, "create token")
+		// With 2FA on, the password only earns a challenge for the second step.
+		if deps.TOTP != nil {
+			st, err := deps.TOTP.Status(req.Username)
+			if err != nil {
+				jsonError(w, http.StatusInternalServerError, "authentication error")
+				return
+			}
+			if st.Enabled {
+				jsonOK(w, totpChallengeResponse{TOTPRequired: true, Challenge: deps.challenges.create(req.Username)})
+				return
+			}
+		}
+
+		if err := startSession(w, r, deps, req.Username); err != nil {
+			jsonError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
+		recordLogin(deps, req.Username, ip, true, "")
+
+		jsonOK(w, map[string]bool{"ok": true})
+	}
+}
+
+// startSession registers a session, creates a JWT carrying its ID and the
+// user's role, and sets it as an HttpOnly cookie. Failed logins from the
+// client are forgotten. The error is safe to show to the client.
+func startSession(w http.ResponseWriter, r *http.Request, deps *Deps, username string) error {
+	role, err := userRole(deps, username)
+	if err != nil {
+		return errors.New("failed to load configuration")
+	}
+
+	id := ""
+	if deps.Sessions != nil {
+		sess, err := deps.Sessions.Create(username, remoteIP(r), r.UserAgent(), time.Now().Add(deps.JWT.Duration()))
+		if err != nil {
+			return errors.New("failed to create session")
+		}
+		id = sess.ID
+	}
+
+	// Create JWT.
+	token, err := deps.JWT.Create(username, role, id)
+	if err != nil {
+		return errors.New("create token")
+	}
 
-		// Set HttpOnly cookie.
-		http.SetCookie(w, &http.Cookie{
-			Name:     "token",
-			Value:    token,
-			Path:     "/",
-			HttpOnly: true,
-			Secure:   true,
-			SameSite: http.SameSiteStrictMode,
-			MaxAge:   int(24 * time.Hour / time.Second),
-		})
+	setTokenCookie(w, token, deps.JWT.Duration())
+	loginAccepted(deps, remoteIP(r))
+	return nil
+}
+
+// setTokenCookie sets the session JWT as an HttpOnly cookie.
+func setTokenCookie(w http.ResponseWriter, token string, maxAge time.Duration) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "token",
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+		MaxAge:   int(maxAge / time.Second),
+	})
+}
 
+// recordLogin adds a login attempt to the event history.
+func recordLogin(deps *Deps, username, ip string, ok bool, reason string) {
+	msg := truncateUsername(username) + " from " + ip
+	if reason != "" {
+		msg += ": " + reason
+	}
+	deps.recordEvent(events.Event{Kind: events.KindLogin, OK: ok, Source: "webui", Message: msg})
+}
+
+// truncateUsername bounds a client-supplied username for logging.
+func truncateUsername(username string) string {
+	if len(username) > maxLoggedUsername {
+		return username[:maxLoggedUsername]
+	}
+	return username
+}
+
+// handleLogout revokes the caller's session and clears the authentication
+// cookie.
+func handleLogout(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if p := principalFrom(r.Context()); p != nil && p.Session != "" && deps.Sessions != nil {
+			if err := deps.Sessions.Revoke(p.Session); err != nil && !errors.Is(err, session.ErrNotFound) {
+				jsonError(w, http.StatusInternalServerError, "failed to end session")
+				return
+			}
+		}
+		clearTokenCookie(w)
 		jsonOK(w, map[string]bool{"ok": true})
 	}
 }
 
-// handleLogout clears the authentication cookie.
-func handleLogout(w http.ResponseWriter, _ *http.Request) {
+// clearTokenCookie removes the session cookie from the browser.
+func clearTokenCookie(w http.ResponseWriter) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     "token",
 		Value:    "",
@@ -75,8 +165,6 @@ func handleLogout(w http.ResponseWriter, _ *http.Request) {
 		SameSite: http.SameSiteStrictMode,
 		MaxAge:   -1,
 	})
-
-	jsonOK(w, map[string]bool{"ok": true})
 }
 
 // remoteIP extracts the IP address from the request's RemoteAddr,
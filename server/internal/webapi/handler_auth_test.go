@@ -150,7 +150,7 @@ func TestHandleLogout(t *testing.T) {
 	req := httptest.NewRequest("POST", "/api/logout", nil)
 	rec := httptest.NewRecorder()
 
-	handleLogout(rec, req)
+	handleLogout(newTestDeps(t))(rec, req)
 
 	if rec.Code != http.StatusOK {
 		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
@@ -0,0 +1,118 @@
+package webapi
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/banlist"
+)
+
+// loginBlock says why a client at ip may not try to log in now: it is over
+// the in-memory rate limit ("rate_limited") or on the shared ban list
+// ("banned"). Returns "" when it may.
+func loginBlock(deps *Deps, ip string) string {
+	if deps.loginLimiter != nil && !deps.loginLimiter.allow(ip) {
+		return "rate_limited"
+	}
+	if deps.Bans != nil {
+		if _, banned := deps.Bans.Banned(banlist.KindIP, ip); banned {
+			return "banned"
+		}
+	}
+	return ""
+}
+
+// loginRejected counts a failed login from ip against the rate limit and
+// the ban list.
+func loginRejected(deps *Deps, ip, reason string) {
+	if deps.loginLimiter != nil {
+		deps.loginLimiter.record(ip)
+	}
+	if deps.Bans != nil {
+		if _, err := deps.Bans.Fail(banlist.KindIP, ip, reason); err != nil {
+			slog.Warn("Failed to record login failure", "ip", ip, "error", err)
+		}
+	}
+}
+
+// loginAccepted forgets the failed logins from ip.
+func loginAccepted(deps *Deps, ip string) {
+	if deps.Bans != nil {
+		if err := deps.Bans.Reset(banlist.KindIP, ip); err != nil {
+			slog.Warn("Failed to reset login failures", "ip", ip, "error", err)
+		}
+	}
+}
+
+// handleListBans returns the active bans of Web UI clients and Telegram
+// users.
+func handleListBans(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if deps.Bans == nil {
+			jsonError(w, http.StatusServiceUnavailable, "ban list is not available")
+			return
+		}
+		bans, err := deps.Bans.List()
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to load ban list")
+			return
+		}
+		jsonOK(w, bans)
+	}
+}
+
+// handleClearBan lifts one ban by its ID ("ip:<address>" or
+// "telegram:<user>").
+func handleClearBan(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if deps.Bans == nil {
+			jsonError(w, http.StatusServiceUnavailable, "ban list is not available")
+			return
+		}
+		id := r.PathValue("id")
+		auditChange(r, "", auditBan(deps, id), nil)
+		err := deps.Bans.Clear(id)
+		if errors.Is(err, banlist.ErrNotFound) {
+			jsonError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to clear ban")
+			return
+		}
+		jsonOK(w, map[string]bool{"ok": true})
+	}
+}
+
+// handleClearBans lifts every ban.
+func handleClearBans(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if deps.Bans == nil {
+			jsonError(w, http.StatusServiceUnavailable, "ban list is not available")
+			return
+		}
+		n, err := deps.Bans.ClearAll()
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to clear bans")
+			return
+		}
+		auditChange(r, "", map[string]int{"bans": n}, map[string]int{"bans": 0})
+		jsonOK(w, map[string]int{"cleared": n})
+	}
+}
+
+// auditBan describes the ban with the given ID for the audit log, or
+// returns nil when there is none.
+func auditBan(deps *Deps, id string) map[string]any {
+	bans, err := deps.Bans.List()
+	if err != nil {
+		return nil
+	}
+	for _, b := range bans {
+		if b.ID == id {
+			return map[string]any{"reason": b.Reason, "banned_until": b.BannedUntil}
+		}
+	}
+	return nil
+}
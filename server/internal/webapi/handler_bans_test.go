@@ -0,0 +1,147 @@
+package webapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/apitoken"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/auth"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/banlist"
+)
+
+// newBansDeps returns deps with a real shadow file and ban list, and a
+// rate limit high enough to leave the bans to the ban list.
+func newBansDeps(t *testing.T) (*Deps, *banlist.Store) {
+	t.Helper()
+	deps := newTestDeps(t)
+	deps.Shadow = auth.NewShadowAuth(writeShadowFixture(t, "admin:"+testSHA256Hash+":19000:0:99999:7:::\n"))
+	deps.loginLimiter = newRateLimiter(100, time.Minute, 30*time.Second)
+	bans := banlist.NewStore(banlist.StorePath(t.TempDir()))
+	deps.Bans = bans
+	return deps, bans
+}
+
+func login(deps *Deps, ip, password string) int {
+	body := `{"username":"admin","password":"` + password + `"}`
+	req := httptest.NewRequest("POST", "/api/login", strings.NewReader(body))
+	req.RemoteAddr = ip + ":12345"
+	rec := httptest.NewRecorder()
+	handleLogin(deps).ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestHandleLogin_Banned(t *testing.T) {
+	deps, bans := newBansDeps(t)
+
+	for i := 0; i < 5; i++ {
+		if code := login(deps, "10.0.0.1", "wrong"); code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: expected 401, got %d", i+1, code)
+		}
+	}
+	if code := login(deps, "10.0.0.1", "testpass"); code != http.StatusTooManyRequests {
+		t.Errorf("expected a banned address to get 429, got %d", code)
+	}
+	if code := login(deps, "10.0.0.2", "testpass"); code != http.StatusOK {
+		t.Errorf("expected other addresses to log in, got %d", code)
+	}
+
+	list, err := bans.List()
+	if err != nil || len(list) != 1 || list[0].Source != "10.0.0.1" || list[0].Reason != "bad credentials for admin" {
+		t.Errorf("unexpected ban list %+v, %v", list, err)
+	}
+}
+
+func TestHandleLogin_SuccessResetsFailures(t *testing.T) {
+	deps, _ := newBansDeps(t)
+
+	for i := 0; i < 4; i++ {
+		login(deps, "10.0.0.1", "wrong")
+	}
+	if code := login(deps, "10.0.0.1", "testpass"); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	for i := 0; i < 4; i++ {
+		login(deps, "10.0.0.1", "wrong")
+	}
+	if code := login(deps, "10.0.0.1", "testpass"); code != http.StatusOK {
+		t.Errorf("expected failures before a login to be forgotten, got %d", code)
+	}
+}
+
+func TestAuthMiddleware_InvalidAPITokenCounts(t *testing.T) {
+	deps, bans := newBansDeps(t)
+	tokens := newTestTokens(t)
+	deps.Tokens = tokens
+	router := NewRouter(deps, nil)
+	valid, _, _ := tokens.Create("ro", []string{apitoken.ScopeRead})
+
+	call := func(token string) int {
+		req := httptest.NewRequest("GET", "/api/status", nil)
+		req.RemoteAddr = "10.0.0.1:12345"
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	for i := 0; i < 5; i++ {
+		call(apitoken.Prefix + "nope")
+	}
+	if _, banned := bans.Banned(banlist.KindIP, "10.0.0.1"); !banned {
+		t.Fatal("expected invalid API tokens to ban the address")
+	}
+	if code := call(valid); code != http.StatusTooManyRequests {
+		t.Errorf("expected 429 for a banned address, got %d", code)
+	}
+}
+
+func TestHandleBans(t *testing.T) {
+	deps, bans := newBansDeps(t)
+	for i := 0; i < 5; i++ {
+		bans.Fail(banlist.KindIP, "10.0.0.1", "bad credentials")
+		bans.Fail(banlist.KindTelegram, "@stranger", "unauthorized message")
+	}
+
+	rec := httptest.NewRecorder()
+	handleListBans(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/bans", nil))
+	var list []banlist.Entry
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil || len(list) != 2 {
+		t.Fatalf("expected 2 bans, got %+v, %v", list, err)
+	}
+
+	req := httptest.NewRequest("DELETE", "/api/bans/telegram:@stranger", nil)
+	req.SetPathValue("id", "telegram:@stranger")
+	rec = httptest.NewRecorder()
+	handleClearBan(deps).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	handleClearBan(deps).ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a lifted ban, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handleClearBans(deps).ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/bans", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"cleared":1`) {
+		t.Errorf("expected 1 ban cleared, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if code := login(deps, "10.0.0.1", "testpass"); code != http.StatusOK {
+		t.Errorf("expected the address to log in again, got %d", code)
+	}
+}
+
+func TestHandleBans_Unavailable(t *testing.T) {
+	deps := newTestDeps(t)
+	for _, h := range []http.HandlerFunc{handleListBans(deps), handleClearBan(deps), handleClearBans(deps)} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/api/bans", nil))
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected 503, got %d", rec.Code)
+		}
+	}
+}
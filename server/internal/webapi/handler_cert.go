@@ -0,0 +1,147 @@
+package webapi
+
+import (
+	"errors"
+	"net/http"
+	"os"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/tlscert"
+)
+
+// generateCertRequest is the JSON body for POST /api/cert/generate.
+// Empty Hosts means the router's own names and LAN addresses.
+type generateCertRequest struct {
+	Hosts []string `json:"hosts"`
+}
+
+// uploadCertRequest is the JSON body for POST /api/cert.
+type uploadCertRequest struct {
+	Cert string `json:"cert"`
+	Key  string `json:"key"`
+}
+
+// handleCertInfo describes the certificate the Web UI serves.
+func handleCertInfo(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		if deps.Certs == nil {
+			jsonError(w, http.StatusServiceUnavailable, "certificate management is not available")
+			return
+		}
+
+		info, err := deps.Certs.Info()
+		if errors.Is(err, os.ErrNotExist) {
+			jsonError(w, http.StatusNotFound, "no certificate installed")
+			return
+		}
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		jsonOK(w, info)
+	}
+}
+
+// handleCertCA downloads the local CA certificate for importing into
+// browsers.
+func handleCertCA(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		if deps.Certs == nil {
+			jsonError(w, http.StatusServiceUnavailable, "certificate management is not available")
+			return
+		}
+
+		ca, err := deps.Certs.CA()
+		if errors.Is(err, os.ErrNotExist) {
+			jsonError(w, http.StatusNotFound, "no local CA; the certificate was not generated here")
+			return
+		}
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-pem-file")
+		w.Header().Set("Content-Disposition", `attachment; filename="vpn-director-ca.crt"`)
+		_, _ = w.Write(ca)
+	}
+}
+
+// handleGenerateCert issues a new certificate from the local CA and starts
+// serving it.
+func handleGenerateCert(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if deps.Certs == nil {
+			jsonError(w, http.StatusServiceUnavailable, "certificate management is not available")
+			return
+		}
+
+		var req generateCertRequest
+		if err := decodeJSON(r, &req); err != nil {
+			jsonError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		if len(req.Hosts) > 0 {
+			if err := tlscert.ValidateHosts(req.Hosts); err != nil {
+				jsonError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+
+		before := auditCert(deps)
+		info, err := deps.Certs.Generate(req.Hosts)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		auditChange(r, "", before, auditCertInfo(info))
+		jsonOK(w, info)
+	}
+}
+
+// handleUploadCert installs a PEM certificate and key and starts serving
+// them.
+func handleUploadCert(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if deps.Certs == nil {
+			jsonError(w, http.StatusServiceUnavailable, "certificate management is not available")
+			return
+		}
+
+		var req uploadCertRequest
+		if err := decodeJSON(r, &req); err != nil {
+			jsonError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		certPEM, keyPEM := []byte(req.Cert), []byte(req.Key)
+		if err := tlscert.Validate(certPEM, keyPEM); err != nil {
+			jsonError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		before := auditCert(deps)
+		info, err := deps.Certs.Install(certPEM, keyPEM)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		auditChange(r, "", before, auditCertInfo(info))
+		jsonOK(w, info)
+	}
+}
+
+// auditCert describes the installed certificate for the audit log, or
+// returns nil when there is none.
+func auditCert(deps *Deps) map[string]any {
+	info, err := deps.Certs.Info()
+	if err != nil {
+		return nil
+	}
+	return auditCertInfo(info)
+}
+
+// auditCertInfo is the part of info recorded in the audit log.
+func auditCertInfo(info *tlscert.Info) map[string]any {
+	if info == nil {
+		return nil
+	}
+	return map[string]any{"fingerprint": info.Fingerprint, "not_after": info.NotAfter}
+}
@@ -0,0 +1,152 @@
+package webapi
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/tlscert"
+)
+
+func newCertDeps(t *testing.T) *Deps {
+	t.Helper()
+	dir := t.TempDir()
+	deps := newTestDeps(t)
+	deps.Certs = tlscert.NewService(filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"), nil)
+	return deps
+}
+
+// testKeyPair returns a self-signed PEM certificate and key valid for d.
+func testKeyPair(t *testing.T, d time.Duration) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "vpn.example.com"},
+		DNSNames:     []string{"vpn.example.com"},
+		NotBefore:    time.Now().Add(-2 * time.Hour),
+		NotAfter:     time.Now().Add(d),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	return string(certPEM), string(keyPEM)
+}
+
+func TestHandleCert_Unavailable(t *testing.T) {
+	deps := newTestDeps(t)
+	for _, h := range []http.HandlerFunc{handleCertInfo(deps), handleCertCA(deps), handleGenerateCert(deps), handleUploadCert(deps)} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("POST", "/api/cert", strings.NewReader("{}")))
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected 503, got %d", rec.Code)
+		}
+	}
+}
+
+func TestHandleCertInfo_NotInstalled(t *testing.T) {
+	deps := newCertDeps(t)
+	for _, h := range []http.HandlerFunc{handleCertInfo(deps), handleCertCA(deps)} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/api/cert", nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", rec.Code)
+		}
+	}
+}
+
+func TestHandleGenerateCert(t *testing.T) {
+	deps := newCertDeps(t)
+
+	rec := httptest.NewRecorder()
+	body := `{"hosts":["router.asus.com","192.168.50.1"]}`
+	handleGenerateCert(deps).ServeHTTP(rec, httptest.NewRequest("POST", "/api/cert/generate", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var info tlscert.Info
+	if err := json.NewDecoder(rec.Body).Decode(&info); err != nil {
+		t.Fatal(err)
+	}
+	if !info.LocalCA || len(info.DNSNames) != 1 || len(info.IPAddresses) != 1 {
+		t.Errorf("unexpected info: %+v", info)
+	}
+
+	rec = httptest.NewRecorder()
+	handleCertInfo(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/cert", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), info.Fingerprint) {
+		t.Errorf("expected the generated certificate, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handleCertCA(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/cert/ca", nil))
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Body.String(), "-----BEGIN CERTIFICATE-----") {
+		t.Errorf("expected the CA in PEM form, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Disposition"); !strings.Contains(got, "attachment") {
+		t.Errorf("expected a download, got %q", got)
+	}
+}
+
+func TestHandleGenerateCert_InvalidHost(t *testing.T) {
+	deps := newCertDeps(t)
+	rec := httptest.NewRecorder()
+	handleGenerateCert(deps).ServeHTTP(rec, httptest.NewRequest("POST", "/api/cert/generate", strings.NewReader(`{"hosts":["bad host"]}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rec.Code)
+	}
+}
+
+func TestHandleUploadCert(t *testing.T) {
+	deps := newCertDeps(t)
+	certPEM, keyPEM := testKeyPair(t, 24*time.Hour)
+	_, otherKey := testKeyPair(t, 24*time.Hour)
+	expiredCert, expiredKey := testKeyPair(t, -time.Hour)
+
+	tests := []struct {
+		name      string
+		cert, key string
+		want      int
+	}{
+		{"mismatched key", certPEM, otherKey, http.StatusBadRequest},
+		{"expired", expiredCert, expiredKey, http.StatusBadRequest},
+		{"empty", "", "", http.StatusBadRequest},
+		{"valid", certPEM, keyPEM, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(uploadCertRequest{Cert: tt.cert, Key: tt.key})
+			rec := httptest.NewRecorder()
+			handleUploadCert(deps).ServeHTTP(rec, httptest.NewRequest("POST", "/api/cert", strings.NewReader(string(body))))
+			if rec.Code != tt.want {
+				t.Errorf("expected %d, got %d: %s", tt.want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	info, err := deps.Certs.Info()
+	if err != nil || info.Subject != "CN=vpn.example.com" || info.LocalCA {
+		t.Errorf("expected the uploaded certificate, got %+v, %v", info, err)
+	}
+}
@@ -3,7 +3,9 @@ package webapi
 import (
 	"net"
 	"net/http"
+	"slices"
 
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/cidrset"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
 )
 
@@ -51,11 +53,23 @@ type addClientRequest struct {
 	Route string `json:"route"`
 }
 
-// handleAddClient returns a handler that adds a client IP to the specified route.
+// addClientResponse is the body of POST /api/clients. Conflicts lists
+// clients of other routes that overlap the new one.
+type addClientResponse struct {
+	OK        bool               `json:"ok"`
+	Conflicts []cidrset.Conflict `json:"conflicts,omitempty"`
+}
+
+// handleAddClient returns a handler that adds a client IP to the specified
+// route in canonical form. An entry already covered by a broader client of
+// the route is refused.
 func handleAddClient(deps *Deps) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		deps.OpMutex.Lock()
-		defer deps.OpMutex.Unlock()
+		unlock, ok := lockConfig(w, deps)
+		if !ok {
+			return
+		}
+		defer unlock()
 
 		var req addClientRequest
 		if err := decodeJSON(r, &req); err != nil {
@@ -67,10 +81,12 @@ func handleAddClient(deps *Deps) http.HandlerFunc {
 			jsonError(w, http.StatusBadRequest, "ip is required")
 			return
 		}
-		if !isValidIPOrCIDR(req.IP) {
+		prefix, err := cidrset.Parse(req.IP)
+		if err != nil {
 			jsonError(w, http.StatusBadRequest, "invalid ip address or CIDR")
 			return
 		}
+		ip := cidrset.Format(prefix)
 		if req.Route == "" {
 			jsonError(w, http.StatusBadRequest, "route is required")
 			return
@@ -86,10 +102,9 @@ func handleAddClient(deps *Deps) http.HandlerFunc {
 			return
 		}
 
+		before := clientRoutes(cfg, ip)
 		if req.Route == "xray" {
-			if !contains(cfg.Xray.Clients, req.IP) {
-				cfg.Xray.Clients = append(cfg.Xray.Clients, req.IP)
-			}
+			cfg.Xray.Clients, err = cidrset.Add(cfg.Xray.Clients, prefix)
 		} else {
 			// Tunnel route (wgc1, ovpnc1, etc.)
 			if cfg.TunnelDirector.Tunnels == nil {
@@ -102,26 +117,33 @@ func handleAddClient(deps *Deps) http.HandlerFunc {
 					Exclude: []string{},
 				}
 			}
-			if !contains(tunnel.Clients, req.IP) {
-				tunnel.Clients = append(tunnel.Clients, req.IP)
-			}
+			tunnel.Clients, err = cidrset.Add(tunnel.Clients, prefix)
 			cfg.TunnelDirector.Tunnels[req.Route] = tunnel
 		}
+		if err != nil {
+			jsonError(w, http.StatusConflict, err.Error())
+			return
+		}
+		auditChange(r, ip, before, clientRoutes(cfg, ip))
 
 		if err := deps.Config.SaveVPNConfig(cfg); err != nil {
 			jsonError(w, http.StatusInternalServerError, "failed to save configuration")
 			return
 		}
 
-		jsonOK(w, map[string]bool{"ok": true})
+		jsonOK(w, addClientResponse{OK: true, Conflicts: vpnconfig.ClientConflictsOf(cfg, ip)})
 	}
 }
 
-// handlePauseClient returns a handler that pauses a client by IP.
+// handlePauseClient returns a handler that pauses a client by IP. Operators
+// may only pause their own clients.
 func handlePauseClient(deps *Deps) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		deps.OpMutex.Lock()
-		defer deps.OpMutex.Unlock()
+		unlock, ok := lockConfig(w, deps)
+		if !ok {
+			return
+		}
+		defer unlock()
 
 		ip := r.URL.Query().Get("ip")
 		if ip == "" {
@@ -138,7 +160,12 @@ func handlePauseClient(deps *Deps) http.HandlerFunc {
 			jsonError(w, http.StatusInternalServerError, "failed to load configuration")
 			return
 		}
+		if !ownsClient(principalFrom(r.Context()), cfg, ip) {
+			jsonError(w, http.StatusForbidden, "forbidden: "+ip+" is not one of your clients")
+			return
+		}
 
+		auditChange(r, "", map[string]bool{"paused": contains(cfg.PausedClients, ip)}, map[string]bool{"paused": true})
 		if !contains(cfg.PausedClients, ip) {
 			cfg.PausedClients = append(cfg.PausedClients, ip)
 		}
@@ -153,10 +180,14 @@ func handlePauseClient(deps *Deps) http.HandlerFunc {
 }
 
 // handleResumeClient returns a handler that resumes a paused client by IP.
+// Operators may only resume their own clients.
 func handleResumeClient(deps *Deps) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		deps.OpMutex.Lock()
-		defer deps.OpMutex.Unlock()
+		unlock, ok := lockConfig(w, deps)
+		if !ok {
+			return
+		}
+		defer unlock()
 
 		ip := r.URL.Query().Get("ip")
 		if ip == "" {
@@ -173,7 +204,12 @@ func handleResumeClient(deps *Deps) http.HandlerFunc {
 			jsonError(w, http.StatusInternalServerError, "failed to load configuration")
 			return
 		}
+		if !ownsClient(principalFrom(r.Context()), cfg, ip) {
+			jsonError(w, http.StatusForbidden, "forbidden: "+ip+" is not one of your clients")
+			return
+		}
 
+		auditChange(r, "", map[string]bool{"paused": contains(cfg.PausedClients, ip)}, map[string]bool{"paused": false})
 		cfg.PausedClients = removeString(cfg.PausedClients, ip)
 
 		if err := deps.Config.SaveVPNConfig(cfg); err != nil {
@@ -188,8 +224,11 @@ func handleResumeClient(deps *Deps) http.HandlerFunc {
 // handleDeleteClient returns a handler that removes a client from all routes.
 func handleDeleteClient(deps *Deps) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		deps.OpMutex.Lock()
-		defer deps.OpMutex.Unlock()
+		unlock, ok := lockConfig(w, deps)
+		if !ok {
+			return
+		}
+		defer unlock()
 
 		ip := r.URL.Query().Get("ip")
 		if ip == "" {
@@ -207,6 +246,8 @@ func handleDeleteClient(deps *Deps) http.HandlerFunc {
 			return
 		}
 
+		auditChange(r, "", clientRoutes(cfg, ip), nil)
+
 		// Remove from Xray clients.
 		cfg.Xray.Clients = removeString(cfg.Xray.Clients, ip)
 
@@ -248,3 +289,18 @@ func removeString(slice []string, item string) []string {
 	}
 	return result
 }
+
+// clientRoutes returns the routes ip is assigned to, for the audit log.
+func clientRoutes(cfg *vpnconfig.VPNDirectorConfig, ip string) []string {
+	var routes []string
+	if contains(cfg.Xray.Clients, ip) {
+		routes = append(routes, "xray")
+	}
+	for name, tunnel := range cfg.TunnelDirector.Tunnels {
+		if contains(tunnel.Clients, ip) {
+			routes = append(routes, name)
+		}
+	}
+	slices.Sort(routes)
+	return routes
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/auth"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
 )
 
@@ -147,6 +148,46 @@ func TestHandleAddClient_XrayDuplicate(t *testing.T) {
 	}
 }
 
+func TestHandleAddClient_Canonical(t *testing.T) {
+	mc := &mockConfig{
+		cfg: &vpnconfig.VPNDirectorConfig{
+			Xray: vpnconfig.XrayConfig{Clients: []string{"192.168.50.0/24"}},
+			TunnelDirector: vpnconfig.TunnelDirectorConfig{
+				Tunnels: map[string]vpnconfig.TunnelConfig{"wgc1": {Clients: []string{}}},
+			},
+		},
+	}
+	deps := newTestDeps(t)
+	deps.Config = mc
+	handler := handleAddClient(deps)
+
+	// Covered by the xray subnet: refused
+	req := httptest.NewRequest("POST", "/api/clients", strings.NewReader(`{"ip": "192.168.50.7", "route": "xray"}`))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "already covered by 192.168.50.0/24") {
+		t.Fatalf("expected 409, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Another route: stored without /32, with the overlap as a warning
+	req = httptest.NewRequest("POST", "/api/clients", strings.NewReader(`{"ip": "192.168.50.7/32", "route": "wgc1"}`))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := mc.savedCfg.TunnelDirector.Tunnels["wgc1"].Clients; len(got) != 1 || got[0] != "192.168.50.7" {
+		t.Errorf("expected the canonical address, got %v", got)
+	}
+	var resp addClientResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Conflicts) != 1 || resp.Conflicts[0].Overlaps != "192.168.50.0/24" {
+		t.Errorf("expected the overlap with the xray subnet, got %+v", resp.Conflicts)
+	}
+}
+
 func TestHandleAddClient_TunnelRoute(t *testing.T) {
 	mc := &mockConfig{
 		cfg: &vpnconfig.VPNDirectorConfig{
@@ -317,6 +358,37 @@ func TestHandlePauseClient_AlreadyPaused(t *testing.T) {
 	}
 }
 
+func TestHandlePauseClient_Ownership(t *testing.T) {
+	kid := &principal{Name: "kid", Role: auth.RoleOperator}
+	for _, tt := range []struct {
+		ip     string
+		status int
+	}{
+		{"192.168.50.10", http.StatusOK},
+		{"192.168.50.20", http.StatusForbidden},
+	} {
+		mc := &mockConfig{
+			cfg: &vpnconfig.VPNDirectorConfig{
+				PausedClients: []string{},
+				WebUI:         vpnconfig.WebUIConfig{OperatorClients: map[string][]string{"kid": {"192.168.50.10"}}},
+			},
+		}
+		deps := newTestDeps(t)
+		deps.Config = mc
+
+		req := httptest.NewRequest("POST", "/api/clients/pause?ip="+tt.ip, nil)
+		rec := httptest.NewRecorder()
+		handlePauseClient(deps).ServeHTTP(rec, req.WithContext(withPrincipal(req.Context(), kid)))
+
+		if rec.Code != tt.status {
+			t.Fatalf("%s: expected %d, got %d: %s", tt.ip, tt.status, rec.Code, rec.Body.String())
+		}
+		if want := tt.status == http.StatusOK; (mc.savedCfg != nil) != want {
+			t.Errorf("%s: expected saved=%v, got %+v", tt.ip, want, mc.savedCfg)
+		}
+	}
+}
+
 func TestHandlePauseClient_MissingIP(t *testing.T) {
 	deps := newTestDeps(t)
 
@@ -363,6 +435,29 @@ func TestHandleResumeClient_OK(t *testing.T) {
 	}
 }
 
+func TestHandleResumeClient_NotOwned(t *testing.T) {
+	mc := &mockConfig{
+		cfg: &vpnconfig.VPNDirectorConfig{
+			PausedClients: []string{"192.168.50.20"},
+			WebUI:         vpnconfig.WebUIConfig{OperatorClients: map[string][]string{"kid": {"192.168.50.10"}}},
+		},
+	}
+	deps := newTestDeps(t)
+	deps.Config = mc
+
+	req := httptest.NewRequest("POST", "/api/clients/resume?ip=192.168.50.20", nil)
+	req = req.WithContext(withPrincipal(req.Context(), &principal{Name: "kid", Role: auth.RoleOperator}))
+	rec := httptest.NewRecorder()
+	handleResumeClient(deps).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if mc.savedCfg != nil {
+		t.Errorf("expected the client to stay paused, saved %+v", mc.savedCfg)
+	}
+}
+
 func TestHandleResumeClient_MissingIP(t *testing.T) {
 	deps := newTestDeps(t)
 
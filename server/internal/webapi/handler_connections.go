@@ -0,0 +1,56 @@
+package webapi
+
+import (
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/conntrack"
+)
+
+// handleConnections lists active connections of configured clients from the
+// conntrack table, with the route their mark selects.
+// Query params: client (IP or CIDR), route, limit (default 200, max 2000).
+func handleConnections(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if deps.Connections == nil {
+			jsonError(w, http.StatusServiceUnavailable, "connection tracking is not available")
+			return
+		}
+
+		q := conntrack.Query{
+			Client: r.URL.Query().Get("client"),
+			Route:  r.URL.Query().Get("route"),
+		}
+		if q.Client != "" && !validClient(q.Client) {
+			jsonError(w, http.StatusBadRequest, "client must be an IP address or CIDR")
+			return
+		}
+		if s := r.URL.Query().Get("limit"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 1 {
+				jsonError(w, http.StatusBadRequest, "limit must be a positive integer")
+				return
+			}
+			q.Limit = n
+		}
+
+		snap, err := deps.Connections.Connections(q)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to read connections")
+			return
+		}
+
+		jsonOK(w, snap)
+	}
+}
+
+func validClient(s string) bool {
+	if strings.Contains(s, "/") {
+		_, err := netip.ParsePrefix(s)
+		return err == nil
+	}
+	_, err := netip.ParseAddr(s)
+	return err == nil
+}
@@ -0,0 +1,79 @@
+package webapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/conntrack"
+)
+
+func TestHandleConnections(t *testing.T) {
+	deps := newTestDeps(t)
+	mock := &mockConnections{snap: &conntrack.Snapshot{
+		Clients: []conntrack.ClientSummary{{IP: "192.168.50.23", Client: "192.168.50.23", Route: "xray", Connections: 1, Marked: 1}},
+		Connections: []conntrack.Connection{{
+			Entry: conntrack.Entry{Proto: "tcp", Src: "192.168.50.23", Dst: "142.250.74.46", DstPort: 443, Mark: 0x100},
+			Route: "xray", MarkRoute: "xray",
+		}},
+		Total: 1,
+	}}
+	deps.Connections = mock
+
+	req := httptest.NewRequest("GET", "/api/connections?client=192.168.50.0/24&route=xray&limit=50", nil)
+	rec := httptest.NewRecorder()
+	handleConnections(deps).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if mock.query.Client != "192.168.50.0/24" || mock.query.Route != "xray" || mock.query.Limit != 50 {
+		t.Errorf("unexpected query: %+v", mock.query)
+	}
+
+	var resp conntrack.Snapshot
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Connections) != 1 || resp.Connections[0].MarkRoute != "xray" || resp.Connections[0].Dst != "142.250.74.46" {
+		t.Errorf("unexpected connections: %+v", resp.Connections)
+	}
+}
+
+func TestHandleConnections_BadParams(t *testing.T) {
+	deps := newTestDeps(t)
+	deps.Connections = &mockConnections{snap: &conntrack.Snapshot{}}
+
+	for _, query := range []string{"client=foo", "client=10.0.0.0/99", "limit=0", "limit=x"} {
+		rec := httptest.NewRecorder()
+		handleConnections(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/connections?"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, rec.Code)
+		}
+	}
+}
+
+func TestHandleConnections_Error(t *testing.T) {
+	deps := newTestDeps(t)
+	deps.Connections = &mockConnections{err: errors.New("no such file")}
+
+	rec := httptest.NewRecorder()
+	handleConnections(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/connections", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", rec.Code)
+	}
+}
+
+func TestHandleConnections_NotConfigured(t *testing.T) {
+	deps := newTestDeps(t)
+
+	rec := httptest.NewRecorder()
+	handleConnections(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/connections", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503, got %d", rec.Code)
+	}
+}
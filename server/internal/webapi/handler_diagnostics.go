@@ -0,0 +1,24 @@
+package webapi
+
+import (
+	"net/http"
+)
+
+// handleDiagnosticsRoutes probes every route in use (WAN, Xray, tunnels) for
+// its exit IP and DNS resolver and flags routes that leak to the WAN.
+func handleDiagnosticsRoutes(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if deps.Diagnostics == nil {
+			jsonError(w, http.StatusServiceUnavailable, "route diagnostics are not available")
+			return
+		}
+
+		report, err := deps.Diagnostics.CheckRoutes()
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to check routes")
+			return
+		}
+
+		jsonOK(w, report)
+	}
+}
@@ -0,0 +1,59 @@
+package webapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/diagnostics"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/service"
+)
+
+func TestHandleDiagnosticsRoutes(t *testing.T) {
+	deps := newTestDeps(t)
+	deps.Diagnostics = &mockDiagnostics{report: &diagnostics.Report{
+		Routes: []diagnostics.Result{{
+			Route:    diagnostics.Route{Name: "ovpnc1", Kind: diagnostics.KindTunnel, Via: "tun11"},
+			Exit:     &service.IPInfo{IP: "100.64.12.7", Country: "RU"},
+			Status:   diagnostics.StatusError,
+			Problems: []string{"ovpnc1 clients exit via WAN (100.64.12.7)"},
+		}},
+	}}
+
+	rec := httptest.NewRecorder()
+	handleDiagnosticsRoutes(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/diagnostics/routes", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp diagnostics.Report
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.OK || len(resp.Routes) != 1 || resp.Routes[0].Via != "tun11" || resp.Routes[0].Status != diagnostics.StatusError {
+		t.Errorf("unexpected report: %+v", resp)
+	}
+}
+
+func TestHandleDiagnosticsRoutes_Error(t *testing.T) {
+	deps := newTestDeps(t)
+	deps.Diagnostics = &mockDiagnostics{err: errors.New("config missing")}
+
+	rec := httptest.NewRecorder()
+	handleDiagnosticsRoutes(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/diagnostics/routes", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", rec.Code)
+	}
+}
+
+func TestHandleDiagnosticsRoutes_Unavailable(t *testing.T) {
+	deps := newTestDeps(t)
+
+	rec := httptest.NewRecorder()
+	handleDiagnosticsRoutes(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/diagnostics/routes", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503, got %d", rec.Code)
+	}
+}
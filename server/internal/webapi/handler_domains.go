@@ -0,0 +1,179 @@
+package webapi
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/dnsmasq"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+)
+
+// handleListDomains returns a handler that lists domain lists keyed by route.
+func handleListDomains(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		cfg, err := deps.Config.LoadVPNConfig()
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to load configuration")
+			return
+		}
+		jsonOK(w, map[string]interface{}{"domains": dnsmasq.DomainLists(cfg)})
+	}
+}
+
+// domainRequest is the expected JSON body for POST /api/domains.
+type domainRequest struct {
+	Domain string `json:"domain"`
+	Route  string `json:"route"`
+}
+
+// handleAddDomain returns a handler that adds a domain to a route's list,
+// saves the configuration, and regenerates the dnsmasq fragment. The first
+// domain of a route also applies the rules, as the bot does. Returns 409 if
+// any route already has the domain.
+func handleAddDomain(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		unlock, ok := lockConfig(w, deps)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		var req domainRequest
+		if err := decodeJSON(r, &req); err != nil {
+			jsonError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+
+		domain, err := dnsmasq.NormalizeDomain(req.Domain)
+		if err != nil {
+			jsonError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if !dnsmasq.ValidRoute(req.Route) {
+			jsonError(w, http.StatusBadRequest, "invalid route: must be one of direct, wgc1-wgc5, ovpnc1-ovpnc5")
+			return
+		}
+
+		cfg, err := deps.Config.LoadVPNConfig()
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to load configuration")
+			return
+		}
+
+		if !dnsmasq.AddDomain(cfg, req.Route, domain) {
+			jsonError(w, http.StatusConflict, domain+" is already routed via "+dnsmasq.RouteOf(cfg, domain))
+			return
+		}
+		auditChange(r, domain, nil, req.Route)
+		// The first domain of a route needs an apply to create its ipset rule.
+		applyRequired := len(dnsmasq.DomainLists(cfg)[req.Route]) == 1
+
+		restarted, ok := saveDomains(w, deps, cfg, applyRequired)
+		if !ok {
+			return
+		}
+		jsonOK(w, map[string]interface{}{
+			"ok":                true,
+			"domain":            domain,
+			"dnsmasq_restarted": restarted,
+			"applied":           applyRequired,
+		})
+	}
+}
+
+// handleDeleteDomain returns a handler that removes a domain from a route's
+// list. Removing the last domain of a route also applies the rules.
+func handleDeleteDomain(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		unlock, ok := lockConfig(w, deps)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		route := r.URL.Query().Get("route")
+		domain, err := dnsmasq.NormalizeDomain(r.URL.Query().Get("domain"))
+		if err != nil {
+			jsonError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if !dnsmasq.ValidRoute(route) {
+			jsonError(w, http.StatusBadRequest, "invalid route: must be one of direct, wgc1-wgc5, ovpnc1-ovpnc5")
+			return
+		}
+
+		cfg, err := deps.Config.LoadVPNConfig()
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to load configuration")
+			return
+		}
+
+		if !dnsmasq.RemoveDomain(cfg, route, domain) {
+			jsonError(w, http.StatusNotFound, "domain not found for route")
+			return
+		}
+		auditChange(r, domain, route, nil)
+		applyRequired := len(dnsmasq.DomainLists(cfg)[route]) == 0
+
+		restarted, ok := saveDomains(w, deps, cfg, applyRequired)
+		if !ok {
+			return
+		}
+		jsonOK(w, map[string]interface{}{
+			"ok":                true,
+			"dnsmasq_restarted": restarted,
+			"applied":           applyRequired,
+		})
+	}
+}
+
+// saveDomains saves cfg, regenerates the dnsmasq fragment and, when a
+// route's ipset rule appears or goes away, applies the rules. It writes the
+// error response and returns false on failure.
+func saveDomains(w http.ResponseWriter, deps *Deps, cfg *vpnconfig.VPNDirectorConfig, apply bool) (restarted, ok bool) {
+	if err := deps.Config.SaveVPNConfig(cfg); err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to save configuration")
+		return false, false
+	}
+
+	restarted, err := deps.Domains.Sync(cfg)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to update dnsmasq")
+		return false, false
+	}
+
+	if apply {
+		start := time.Now()
+		err := deps.VPN.Apply()
+		deps.metrics.observeOp("apply", start, err)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to apply configuration")
+			return false, false
+		}
+	}
+	return restarted, true
+}
+
+// handleResolvedDomains returns a handler that previews the addresses dnsmasq
+// has resolved into the route's ipset so far.
+func handleResolvedDomains(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		route := r.URL.Query().Get("route")
+		if !dnsmasq.ValidRoute(route) {
+			jsonError(w, http.StatusBadRequest, "invalid route: must be one of direct, wgc1-wgc5, ovpnc1-ovpnc5")
+			return
+		}
+
+		entries, err := deps.Domains.Resolved(route)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		jsonOK(w, map[string]interface{}{
+			"route":   route,
+			"ipset":   dnsmasq.SetName(route),
+			"entries": entries,
+		})
+	}
+}
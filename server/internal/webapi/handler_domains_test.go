@@ -0,0 +1,268 @@
+package webapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+)
+
+func TestHandleListDomains_OK(t *testing.T) {
+	deps := newTestDeps(t)
+	deps.Config = &mockConfig{
+		cfg: &vpnconfig.VPNDirectorConfig{
+			Xray: vpnconfig.XrayConfig{ExcludeDomains: []string{"bank.example"}},
+			TunnelDirector: vpnconfig.TunnelDirectorConfig{
+				Tunnels: map[string]vpnconfig.TunnelConfig{
+					"wgc1":   {Domains: []string{"netflix.com"}},
+					"ovpnc1": {Clients: []string{"192.168.50.30"}},
+				},
+			},
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	handleListDomains(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/domains", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Domains map[string][]string `json:"domains"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Domains) != 2 {
+		t.Fatalf("expected 2 routes, got %v", resp.Domains)
+	}
+	if resp.Domains["direct"][0] != "bank.example" || resp.Domains["wgc1"][0] != "netflix.com" {
+		t.Errorf("unexpected domains: %v", resp.Domains)
+	}
+}
+
+func TestHandleAddDomain_Tunnel(t *testing.T) {
+	deps := newTestDeps(t)
+	mc := &mockConfig{cfg: &vpnconfig.VPNDirectorConfig{}}
+	md := &mockDomains{}
+	deps.Config = mc
+	deps.Domains = md
+
+	body := `{"domain":"*.Netflix.com","route":"wgc1"}`
+	rec := httptest.NewRecorder()
+	handleAddDomain(deps).ServeHTTP(rec, httptest.NewRequest("POST", "/api/domains", strings.NewReader(body)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if mc.savedCfg == nil {
+		t.Fatal("expected config to be saved")
+	}
+	domains := mc.savedCfg.TunnelDirector.Tunnels["wgc1"].Domains
+	if len(domains) != 1 || domains[0] != "netflix.com" {
+		t.Errorf("expected [netflix.com], got %v", domains)
+	}
+	if md.syncCalls != 1 {
+		t.Errorf("expected 1 sync call, got %d", md.syncCalls)
+	}
+
+	var resp map[string]interface{}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp["applied"] != true {
+		t.Errorf("expected an apply for the first domain, got %v", resp["applied"])
+	}
+}
+
+func TestHandleAddDomain_Duplicate(t *testing.T) {
+	deps := newTestDeps(t)
+	mc := &mockConfig{cfg: &vpnconfig.VPNDirectorConfig{
+		Xray: vpnconfig.XrayConfig{ExcludeDomains: []string{"bank.example"}},
+	}}
+	deps.Config = mc
+
+	body := `{"domain":"bank.example","route":"direct"}`
+	rec := httptest.NewRecorder()
+	handleAddDomain(deps).ServeHTTP(rec, httptest.NewRequest("POST", "/api/domains", strings.NewReader(body)))
+
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected 409, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if mc.savedCfg != nil {
+		t.Error("expected config not to be saved")
+	}
+}
+
+func TestHandleAddDomain_OtherRoute(t *testing.T) {
+	deps := newTestDeps(t)
+	mc := &mockConfig{cfg: &vpnconfig.VPNDirectorConfig{
+		Xray: vpnconfig.XrayConfig{ExcludeDomains: []string{"bank.example"}},
+	}}
+	deps.Config = mc
+
+	body := `{"domain":"bank.example","route":"wgc1"}`
+	rec := httptest.NewRecorder()
+	handleAddDomain(deps).ServeHTTP(rec, httptest.NewRequest("POST", "/api/domains", strings.NewReader(body)))
+
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "already routed via direct") {
+		t.Errorf("expected 409 naming the direct route, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if mc.savedCfg != nil {
+		t.Error("expected config not to be saved")
+	}
+}
+
+func TestHandleAddDomain_ApplyError(t *testing.T) {
+	deps := newTestDeps(t)
+	deps.Config = &mockConfig{cfg: &vpnconfig.VPNDirectorConfig{}}
+	deps.VPN = &mockVPN{err: errors.New("apply failed")}
+
+	body := `{"domain":"netflix.com","route":"wgc1"}`
+	rec := httptest.NewRecorder()
+	handleAddDomain(deps).ServeHTTP(rec, httptest.NewRequest("POST", "/api/domains", strings.NewReader(body)))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500 when the first domain cannot be applied, got %d", rec.Code)
+	}
+}
+
+func TestHandleAddDomain_Direct(t *testing.T) {
+	deps := newTestDeps(t)
+	mc := &mockConfig{cfg: &vpnconfig.VPNDirectorConfig{
+		Xray: vpnconfig.XrayConfig{ExcludeDomains: []string{"bank.example"}},
+	}}
+	deps.Config = mc
+
+	body := `{"domain":"gosuslugi.ru","route":"direct"}`
+	rec := httptest.NewRecorder()
+	handleAddDomain(deps).ServeHTTP(rec, httptest.NewRequest("POST", "/api/domains", strings.NewReader(body)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := mc.savedCfg.Xray.ExcludeDomains; len(got) != 2 {
+		t.Errorf("expected 2 exclude domains, got %v", got)
+	}
+
+	var resp map[string]interface{}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp["applied"] != false {
+		t.Errorf("expected no apply for existing list, got %v", resp["applied"])
+	}
+}
+
+func TestHandleAddDomain_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"bad json", `{`},
+		{"bad domain", `{"domain":"not a domain","route":"wgc1"}`},
+		{"single label", `{"domain":"localhost","route":"wgc1"}`},
+		{"xray route", `{"domain":"example.com","route":"xray"}`},
+		{"unknown route", `{"domain":"example.com","route":"wgc9"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := newTestDeps(t)
+			deps.Config = &mockConfig{cfg: &vpnconfig.VPNDirectorConfig{}}
+
+			rec := httptest.NewRecorder()
+			handleAddDomain(deps).ServeHTTP(rec, httptest.NewRequest("POST", "/api/domains", strings.NewReader(tt.body)))
+
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleAddDomain_SyncError(t *testing.T) {
+	deps := newTestDeps(t)
+	deps.Config = &mockConfig{cfg: &vpnconfig.VPNDirectorConfig{}}
+	deps.Domains = &mockDomains{err: errors.New("restart failed")}
+
+	body := `{"domain":"example.com","route":"direct"}`
+	rec := httptest.NewRecorder()
+	handleAddDomain(deps).ServeHTTP(rec, httptest.NewRequest("POST", "/api/domains", strings.NewReader(body)))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", rec.Code)
+	}
+}
+
+func TestHandleDeleteDomain_OK(t *testing.T) {
+	deps := newTestDeps(t)
+	mc := &mockConfig{cfg: &vpnconfig.VPNDirectorConfig{
+		TunnelDirector: vpnconfig.TunnelDirectorConfig{
+			Tunnels: map[string]vpnconfig.TunnelConfig{
+				"wgc1": {Domains: []string{"netflix.com", "nflxvideo.net"}},
+			},
+		},
+	}}
+	deps.Config = mc
+
+	rec := httptest.NewRecorder()
+	handleDeleteDomain(deps).ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/domains?route=wgc1&domain=netflix.com", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	domains := mc.savedCfg.TunnelDirector.Tunnels["wgc1"].Domains
+	if len(domains) != 1 || domains[0] != "nflxvideo.net" {
+		t.Errorf("expected [nflxvideo.net], got %v", domains)
+	}
+}
+
+func TestHandleDeleteDomain_NotFound(t *testing.T) {
+	deps := newTestDeps(t)
+	deps.Config = &mockConfig{cfg: &vpnconfig.VPNDirectorConfig{}}
+
+	rec := httptest.NewRecorder()
+	handleDeleteDomain(deps).ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/domains?route=direct&domain=example.com", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestHandleResolvedDomains_OK(t *testing.T) {
+	deps := newTestDeps(t)
+	deps.Domains = &mockDomains{entries: []string{"1.2.3.4", "5.6.7.8"}}
+
+	rec := httptest.NewRecorder()
+	handleResolvedDomains(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/domains/resolved?route=wgc1", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		IPSet   string   `json:"ipset"`
+		Entries []string `json:"entries"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.IPSet != "VPD_DOM_WGC1" {
+		t.Errorf("expected VPD_DOM_WGC1, got %s", resp.IPSet)
+	}
+	if len(resp.Entries) != 2 {
+		t.Errorf("expected 2 entries, got %v", resp.Entries)
+	}
+}
+
+func TestHandleResolvedDomains_InvalidRoute(t *testing.T) {
+	deps := newTestDeps(t)
+
+	rec := httptest.NewRecorder()
+	handleResolvedDomains(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/domains/resolved?route=xray", nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,92 @@
+package webapi
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/events"
+)
+
+const (
+	defaultEventDays  = 7
+	maxEventDays      = 90
+	defaultEventLimit = 100
+	maxEventLimit     = 1000
+)
+
+// eventsResponse is the timeline for a window with per-component uptime.
+type eventsResponse struct {
+	From   time.Time       `json:"from"`
+	To     time.Time       `json:"to"`
+	Uptime []events.Uptime `json:"uptime"`
+	Events []events.Event  `json:"events"`
+}
+
+// handleEvents returns recorded events (newest first) and component uptime.
+// Query params: days (window, default 7, max 90), kind, component,
+// limit (default 100, max 1000). Filters apply to events, not to uptime.
+func handleEvents(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if deps.Events == nil {
+			jsonError(w, http.StatusServiceUnavailable, "event history is not available")
+			return
+		}
+
+		to := time.Now()
+		filter, days, err := parseEventsQuery(r.URL.Query())
+		if err != nil {
+			jsonError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		from := to.AddDate(0, 0, -days)
+		filter.Since, filter.Until = from, to
+
+		list, err := deps.Events.List(filter)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to read events")
+			return
+		}
+		uptime, err := deps.Events.Uptime(from, to)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to read events")
+			return
+		}
+
+		jsonOK(w, eventsResponse{From: from, To: to, Uptime: uptime, Events: list})
+	}
+}
+
+func parseEventsQuery(v url.Values) (events.Filter, int, error) {
+	f := events.Filter{Kind: v.Get("kind"), Component: v.Get("component"), Limit: defaultEventLimit}
+	days := defaultEventDays
+
+	if f.Component != "" && !slices.Contains(events.Components, f.Component) {
+		return f, 0, errors.New("component must be one of vpn-director, xray, wan, ipsets")
+	}
+	if s := v.Get("days"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxEventDays {
+			return f, 0, errors.New("days must be between 1 and 90")
+		}
+		days = n
+	}
+	if s := v.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxEventLimit {
+			return f, 0, errors.New("limit must be between 1 and 1000")
+		}
+		f.Limit = n
+	}
+	return f, days, nil
+}
+
+// recordEvent adds an event to the history if one is configured.
+func (d *Deps) recordEvent(e events.Event) {
+	if d.Events != nil {
+		d.Events.Record(e)
+	}
+}
@@ -0,0 +1,145 @@
+package webapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/auth"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/events"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+)
+
+func TestHandleEvents(t *testing.T) {
+	pct := 99.5
+	deps := newTestDeps(t)
+	mock := &mockEvents{
+		list:   []events.Event{{Kind: events.KindXrayRestart, OK: true, Component: events.ComponentXray, State: events.StateUp}},
+		uptime: []events.Uptime{{Component: events.ComponentXray, Percent: &pct}},
+	}
+	deps.Events = mock
+
+	rec := httptest.NewRecorder()
+	handleEvents(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/events?days=30&kind=xray_restart&component=xray&limit=10", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if mock.filter.Kind != events.KindXrayRestart || mock.filter.Component != events.ComponentXray || mock.filter.Limit != 10 {
+		t.Errorf("unexpected filter: %+v", mock.filter)
+	}
+	if got := mock.filter.Until.Sub(mock.filter.Since); got < 29*24*time.Hour || got > 31*24*time.Hour {
+		t.Errorf("expected a 30-day window, got %v", got)
+	}
+
+	var resp eventsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Events) != 1 || len(resp.Uptime) != 1 || *resp.Uptime[0].Percent != 99.5 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestHandleEvents_Defaults(t *testing.T) {
+	deps := newTestDeps(t)
+	mock := &mockEvents{}
+	deps.Events = mock
+
+	rec := httptest.NewRecorder()
+	handleEvents(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/events", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if mock.filter.Limit != defaultEventLimit || mock.filter.Kind != "" {
+		t.Errorf("unexpected default filter: %+v", mock.filter)
+	}
+	if got := mock.filter.Until.Sub(mock.filter.Since); got < 6*24*time.Hour || got > 8*24*time.Hour {
+		t.Errorf("expected a 7-day window, got %v", got)
+	}
+}
+
+func TestHandleEvents_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		events *mockEvents
+		want   int
+	}{
+		{"unavailable", "", nil, http.StatusServiceUnavailable},
+		{"bad days", "?days=0", &mockEvents{}, http.StatusBadRequest},
+		{"too many days", "?days=91", &mockEvents{}, http.StatusBadRequest},
+		{"bad limit", "?limit=5000", &mockEvents{}, http.StatusBadRequest},
+		{"bad component", "?component=eth0", &mockEvents{}, http.StatusBadRequest},
+		{"store error", "", &mockEvents{err: errors.New("read failed")}, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := newTestDeps(t)
+			if tt.events != nil {
+				deps.Events = tt.events
+			}
+
+			rec := httptest.NewRecorder()
+			handleEvents(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/events"+tt.query, nil))
+			if rec.Code != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
+
+func TestHandleLogin_RecordsEvents(t *testing.T) {
+	shadowPath := writeShadowFixture(t, "admin:"+testSHA256Hash+":19000:0:99999:7:::\n")
+
+	deps := newTestDeps(t)
+	deps.Shadow = auth.NewShadowAuth(shadowPath)
+	mock := &mockEvents{}
+	deps.Events = mock
+
+	for _, body := range []string{`{"username":"admin","password":"wrong"}`, `{"username":"admin","password":"testpass"}`} {
+		req := httptest.NewRequest("POST", "/api/login", strings.NewReader(body))
+		req.RemoteAddr = "192.168.50.10:51000"
+		handleLogin(deps).ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if len(mock.recorded) != 2 {
+		t.Fatalf("expected 2 login events, got %+v", mock.recorded)
+	}
+	failed, ok := mock.recorded[0], mock.recorded[1]
+	if failed.Kind != events.KindLogin || failed.OK || failed.Message != "admin from 192.168.50.10: bad credentials" {
+		t.Errorf("unexpected failed login event: %+v", failed)
+	}
+	if !ok.OK || ok.Message != "admin from 192.168.50.10" {
+		t.Errorf("unexpected login event: %+v", ok)
+	}
+}
+
+func TestHandleSelectServer_RecordsEvent(t *testing.T) {
+	deps := newTestDeps(t)
+	deps.Config = &mockConfig{
+		servers: []vpnconfig.Server{{Address: "s1.example.com", Port: 443, UUID: "uuid-1", Name: "S1", IPs: []string{"1.1.1.1"}}},
+		cfg:     &vpnconfig.VPNDirectorConfig{},
+	}
+	deps.VPN = &mockVPN{err: errors.New("restart xray failed (exit 1)")}
+	mock := &mockEvents{}
+	deps.Events = mock
+
+	rec := httptest.NewRecorder()
+	handleSelectServer(deps).ServeHTTP(rec, httptest.NewRequest("POST", "/api/servers/active", strings.NewReader(`{"index": 0}`)))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", rec.Code)
+	}
+	if len(mock.recorded) != 1 {
+		t.Fatalf("expected 1 event, got %+v", mock.recorded)
+	}
+	if e := mock.recorded[0]; e.Kind != events.KindServerSwitch || e.OK || e.Message != "S1: restart xray failed (exit 1)" {
+		t.Errorf("unexpected event: %+v", e)
+	}
+}
@@ -2,6 +2,9 @@ package webapi
 
 import (
 	"net/http"
+	"slices"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/cidrset"
 )
 
 // handleListExcludeSets returns a handler that lists configured exclusion sets.
@@ -24,8 +27,11 @@ type updateExcludeSetsRequest struct {
 // handleUpdateExcludeSets returns a handler that replaces the exclusion sets list.
 func handleUpdateExcludeSets(deps *Deps) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		deps.OpMutex.Lock()
-		defer deps.OpMutex.Unlock()
+		unlock, ok := lockConfig(w, deps)
+		if !ok {
+			return
+		}
+		defer unlock()
 
 		var req updateExcludeSetsRequest
 		if err := decodeJSON(r, &req); err != nil {
@@ -44,6 +50,7 @@ func handleUpdateExcludeSets(deps *Deps) http.HandlerFunc {
 			return
 		}
 
+		auditChange(r, "", cfg.Xray.ExcludeSets, *req.Sets)
 		cfg.Xray.ExcludeSets = *req.Sets
 
 		if err := deps.Config.SaveVPNConfig(cfg); err != nil {
@@ -72,11 +79,15 @@ type addExcludeIPRequest struct {
 	IP string `json:"ip"`
 }
 
-// handleAddExcludeIP returns a handler that adds an IP/CIDR to the exclusion list.
+// handleAddExcludeIP returns a handler that adds an IP/CIDR to the exclusion
+// list in canonical form. An entry already covered by the list is refused.
 func handleAddExcludeIP(deps *Deps) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		deps.OpMutex.Lock()
-		defer deps.OpMutex.Unlock()
+		unlock, ok := lockConfig(w, deps)
+		if !ok {
+			return
+		}
+		defer unlock()
 
 		var req addExcludeIPRequest
 		if err := decodeJSON(r, &req); err != nil {
@@ -88,10 +99,12 @@ func handleAddExcludeIP(deps *Deps) http.HandlerFunc {
 			jsonError(w, http.StatusBadRequest, "ip is required")
 			return
 		}
-		if !isValidIPOrCIDR(req.IP) {
+		prefix, err := cidrset.Parse(req.IP)
+		if err != nil {
 			jsonError(w, http.StatusBadRequest, "invalid ip address or CIDR")
 			return
 		}
+		ip := cidrset.Format(prefix)
 
 		cfg, err := deps.Config.LoadVPNConfig()
 		if err != nil {
@@ -99,9 +112,13 @@ func handleAddExcludeIP(deps *Deps) http.HandlerFunc {
 			return
 		}
 
-		if !contains(cfg.Xray.ExcludeIPs, req.IP) {
-			cfg.Xray.ExcludeIPs = append(cfg.Xray.ExcludeIPs, req.IP)
+		before := slices.Clone(cfg.Xray.ExcludeIPs)
+		cfg.Xray.ExcludeIPs, err = cidrset.Add(cfg.Xray.ExcludeIPs, prefix)
+		if err != nil {
+			jsonError(w, http.StatusConflict, err.Error())
+			return
 		}
+		auditChange(r, ip, before, cfg.Xray.ExcludeIPs)
 
 		if err := deps.Config.SaveVPNConfig(cfg); err != nil {
 			jsonError(w, http.StatusInternalServerError, "failed to save configuration")
@@ -115,8 +132,11 @@ func handleAddExcludeIP(deps *Deps) http.HandlerFunc {
 // handleDeleteExcludeIP returns a handler that removes an IP/CIDR from the exclusion list.
 func handleDeleteExcludeIP(deps *Deps) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		deps.OpMutex.Lock()
-		defer deps.OpMutex.Unlock()
+		unlock, ok := lockConfig(w, deps)
+		if !ok {
+			return
+		}
+		defer unlock()
 
 		ip := r.URL.Query().Get("ip")
 		if ip == "" {
@@ -134,7 +154,9 @@ func handleDeleteExcludeIP(deps *Deps) http.HandlerFunc {
 			return
 		}
 
+		before := cfg.Xray.ExcludeIPs
 		cfg.Xray.ExcludeIPs = removeString(cfg.Xray.ExcludeIPs, ip)
+		auditChange(r, "", before, cfg.Xray.ExcludeIPs)
 
 		if err := deps.Config.SaveVPNConfig(cfg); err != nil {
 			jsonError(w, http.StatusInternalServerError, "failed to save configuration")
@@ -246,6 +246,35 @@ func TestHandleAddExcludeIP_Duplicate(t *testing.T) {
 	}
 }
 
+func TestHandleAddExcludeIP_Covered(t *testing.T) {
+	mc := &mockConfig{
+		cfg: &vpnconfig.VPNDirectorConfig{
+			Xray: vpnconfig.XrayConfig{
+				ExcludeIPs: []string{"10.0.0.0/8"},
+			},
+		},
+	}
+	deps := newTestDeps(t)
+	deps.Config = mc
+
+	handler := handleAddExcludeIP(deps)
+
+	req := httptest.NewRequest("POST", "/api/excludes/ips", strings.NewReader(`{"ip": "10.1.2.3/16"}`))
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), "10.1.0.0/16 is already covered by 10.0.0.0/8") {
+		t.Errorf("expected the canonical entry and the covering one, got %s", rec.Body.String())
+	}
+	if mc.savedCfg != nil {
+		t.Error("expected config not to be saved")
+	}
+}
+
 func TestHandleAddExcludeIP_EmptyIP(t *testing.T) {
 	deps := newTestDeps(t)
 
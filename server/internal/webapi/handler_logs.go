@@ -1,16 +1,19 @@
 package webapi
 
 import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/logstream"
 )
 
-// logPaths maps log source names to their file paths on disk.
-var logPaths = map[string]string{
-	"vpn":  "/tmp/vpn-director.log",
-	"xray": "/tmp/xray-access.log",
-	"bot":  "/tmp/telegram-bot.log",
-}
+// maxLogLines caps the number of lines returned by /api/logs and the
+// backlog sent when a log stream starts.
+const maxLogLines = 500
 
 // handleLogs returns a handler that reads log files.
 // Query params: source (vpn|xray|bot), lines (default 50, max 500).
@@ -27,20 +30,22 @@ func handleLogs(deps *Deps) http.HandlerFunc {
 				jsonError(w, http.StatusBadRequest, "lines must be a positive integer")
 				return
 			}
-			if n > 500 {
-				n = 500
+			if n > maxLogLines {
+				n = maxLogLines
 			}
 			lines = n
 		}
 
+		sources := logstream.Sources(deps.Paths)
+
 		if source != "" {
-			path, ok := logPaths[source]
-			if !ok {
+			selected, err := logstream.SelectSources(sources, source)
+			if err != nil || len(selected) != 1 {
 				jsonError(w, http.StatusBadRequest, "unknown source: valid values are vpn, xray, bot")
 				return
 			}
 
-			output, err := deps.Logs.Read(path, lines)
+			output, err := deps.Logs.Read(selected[0].Path, lines)
 			if err != nil {
 				jsonError(w, http.StatusInternalServerError, "failed to read log file")
 				return
@@ -51,13 +56,13 @@ func handleLogs(deps *Deps) http.HandlerFunc {
 		}
 
 		// No source specified: return all logs.
-		result := make(map[string]string, len(logPaths))
-		for name, path := range logPaths {
-			output, err := deps.Logs.Read(path, lines)
+		result := make(map[string]string, len(sources))
+		for _, src := range sources {
+			output, err := deps.Logs.Read(src.Path, lines)
 			if err != nil {
-				result[name] = "error: " + err.Error()
+				result[src.Name] = "error: " + err.Error()
 			} else {
-				result[name] = output
+				result[src.Name] = output
 			}
 		}
 
@@ -65,6 +70,125 @@ func handleLogs(deps *Deps) http.HandlerFunc {
 	}
 }
 
+// Log stream timing. Variables so tests can shorten them.
+var (
+	logStreamPoll      = 500 * time.Millisecond
+	logStreamHeartbeat = 15 * time.Second
+)
+
+// handleLogStream returns a handler that follows log files as Server-Sent
+// Events.
+// Query params: sources (comma-separated vpn,xray,bot; default all),
+// level (debug|info|warn|error, minimum severity), q (regex), client (IP),
+// tail (lines of backlog per source, default 50, max 500) and from (cursor).
+//
+// Each line is sent as a "log" event whose ID is the stream cursor; browsers
+// send it back as Last-Event-ID when reconnecting, and it can be passed as
+// from to resume explicitly. A "rotated" event reports a truncated or
+// replaced file.
+func handleLogStream(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		sources, err := logstream.SelectSources(logstream.Sources(deps.Paths), q.Get("sources"))
+		if err != nil {
+			jsonError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		filter, err := logstream.NewFilter(q.Get("level"), q.Get("q"), q.Get("client"))
+		if err != nil {
+			jsonError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		tail := 50
+		if s := q.Get("tail"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 0 {
+				jsonError(w, http.StatusBadRequest, "tail must be a non-negative integer")
+				return
+			}
+			tail = min(n, maxLogLines)
+		}
+
+		from := r.Header.Get("Last-Event-ID")
+		if from == "" {
+			from = q.Get("from")
+		}
+		cursor, err := logstream.ParseCursor(from)
+		if err != nil {
+			jsonError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		stream, err := logstream.NewStream(sources, cursor, tail, filter)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to open log files")
+			return
+		}
+		defer stream.Close()
+
+		// The server write timeout would end the stream; lift it here.
+		rc := http.NewResponseController(w)
+		_ = rc.SetWriteDeadline(time.Time{})
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		// The cursor in event IDs advances only with sent lines, so lines
+		// skipped by the filter are re-checked after a reconnect.
+		current := stream.Cursor()
+
+		poll := time.NewTicker(logStreamPoll)
+		defer poll.Stop()
+		heartbeat := time.NewTicker(logStreamHeartbeat)
+		defer heartbeat.Stop()
+
+		for {
+			lines, rotated, err := stream.Poll()
+			if err != nil {
+				slog.Warn("log stream read failed", "error", err)
+			}
+			for _, name := range rotated {
+				current[name] = 0
+				writeSSE(w, "rotated", current.String(), map[string]string{"source": name})
+			}
+			for _, line := range lines {
+				current[line.Source] = line.Offset
+				writeSSE(w, "log", current.String(), line)
+			}
+			if len(lines) > 0 || len(rotated) > 0 {
+				if err := rc.Flush(); err != nil {
+					return
+				}
+			}
+
+			select {
+			case <-r.Context().Done():
+				return
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": ping\n\n")
+				if err := rc.Flush(); err != nil {
+					return
+				}
+			case <-poll.C:
+			}
+		}
+	}
+}
+
+// writeSSE writes one Server-Sent Event with a JSON payload.
+func writeSSE(w http.ResponseWriter, event, id string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", id, event, payload)
+}
+
 // handleConfig returns a handler that returns the VPN Director configuration
 // with sensitive fields redacted.
 func handleConfig(deps *Deps) http.HandlerFunc {
@@ -78,6 +202,8 @@ func handleConfig(deps *Deps) http.HandlerFunc {
 		// Make a shallow copy to avoid mutating the original.
 		redacted := *cfg
 		redacted.WebUI.JWTSecret = ""
+		redacted.WebUI.MetricsToken = ""
+		redacted.WebUI.TOTPKey = ""
 
 		jsonOK(w, &redacted)
 	}
@@ -1,12 +1,18 @@
 package webapi
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/paths"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
 )
 
@@ -179,6 +185,7 @@ func TestHandleConfig_OK(t *testing.T) {
 			WebUI: vpnconfig.WebUIConfig{
 				Port:      8443,
 				JWTSecret: "super-secret-key",
+				TOTPKey:   "totp-key",
 			},
 			Xray: vpnconfig.XrayConfig{
 				Clients:     []string{"192.168.50.10"},
@@ -207,6 +214,9 @@ func TestHandleConfig_OK(t *testing.T) {
 	if resp.WebUI.JWTSecret != "" {
 		t.Errorf("expected JWTSecret to be redacted, got %q", resp.WebUI.JWTSecret)
 	}
+	if resp.WebUI.TOTPKey != "" {
+		t.Errorf("expected TOTPKey to be redacted, got %q", resp.WebUI.TOTPKey)
+	}
 
 	// Other fields should be present.
 	if resp.WebUI.Port != 8443 {
@@ -260,3 +270,133 @@ func TestHandleUpdate_NotSupported(t *testing.T) {
 		t.Error("expected error message")
 	}
 }
+
+// streamLogs runs the log stream handler until timeout and returns the body.
+func streamLogs(t *testing.T, deps *Deps, target string, header http.Header, timeout time.Duration) *httptest.ResponseRecorder {
+	t.Helper()
+	logStreamPoll = 10 * time.Millisecond
+	t.Cleanup(func() { logStreamPoll = 500 * time.Millisecond })
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req := httptest.NewRequest("GET", target, nil).WithContext(ctx)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	handleLogStream(deps).ServeHTTP(rec, req)
+	return rec
+}
+
+func newLogStreamDeps(t *testing.T) *Deps {
+	t.Helper()
+	dir := t.TempDir()
+	deps := newTestDeps(t)
+	deps.Paths = paths.Paths{
+		VPNLogPath:  filepath.Join(dir, "vpn.log"),
+		XrayLogPath: filepath.Join(dir, "xray.log"),
+		BotLogPath:  filepath.Join(dir, "bot.log"),
+	}
+	os.WriteFile(deps.Paths.VPNLogPath, []byte("2026-01-11T12:00:00 INFO  [a] - applied\n2026-01-11T12:00:01 ERROR [a] - failed for 192.168.50.23\n"), 0644)
+	os.WriteFile(deps.Paths.BotLogPath, []byte("time=2026-01-11T12:00:02Z level=INFO msg=started\n"), 0644)
+	return deps
+}
+
+func TestHandleLogStream_Backlog(t *testing.T) {
+	deps := newLogStreamDeps(t)
+
+	rec := streamLogs(t, deps, "/api/logs/stream", nil, 100*time.Millisecond)
+
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("expected text/event-stream, got %q", ct)
+	}
+	body := rec.Body.String()
+	if strings.Count(body, "event: log\n") != 3 {
+		t.Errorf("expected 3 log events, got:\n%s", body)
+	}
+	if !strings.Contains(body, `"source":"bot"`) || !strings.Contains(body, `"line":"2026-01-11T12:00:00 INFO  [a] - applied"`) {
+		t.Errorf("missing expected lines:\n%s", body)
+	}
+}
+
+func TestHandleLogStream_Filters(t *testing.T) {
+	deps := newLogStreamDeps(t)
+
+	body := streamLogs(t, deps, "/api/logs/stream?level=error", nil, 100*time.Millisecond).Body.String()
+	if strings.Count(body, "event: log\n") != 1 || !strings.Contains(body, "failed for") {
+		t.Errorf("level filter: unexpected body:\n%s", body)
+	}
+
+	body = streamLogs(t, deps, "/api/logs/stream?client=192.168.50.23&sources=vpn", nil, 100*time.Millisecond).Body.String()
+	if strings.Count(body, "event: log\n") != 1 {
+		t.Errorf("client filter: unexpected body:\n%s", body)
+	}
+
+	body = streamLogs(t, deps, "/api/logs/stream?q=start", nil, 100*time.Millisecond).Body.String()
+	if strings.Count(body, "event: log\n") != 1 || !strings.Contains(body, `"source":"bot"`) {
+		t.Errorf("regex filter: unexpected body:\n%s", body)
+	}
+}
+
+func TestHandleLogStream_ResumeFromLastEventID(t *testing.T) {
+	deps := newLogStreamDeps(t)
+
+	// Resume vpn after its first line (40 bytes); bot/xray start at the end.
+	header := http.Header{"Last-Event-Id": {"bot:49,vpn:40,xray:0"}}
+	body := streamLogs(t, deps, "/api/logs/stream", header, 100*time.Millisecond).Body.String()
+
+	if strings.Count(body, "event: log\n") != 1 || !strings.Contains(body, "failed for") {
+		t.Errorf("unexpected body:\n%s", body)
+	}
+	if !strings.Contains(body, "id: bot:49,vpn:") {
+		t.Errorf("expected event ID cursor, got:\n%s", body)
+	}
+}
+
+func TestHandleLogStream_FollowsAppends(t *testing.T) {
+	deps := newLogStreamDeps(t)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		f, _ := os.OpenFile(deps.Paths.XrayLogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		f.WriteString("2026/01/11 12:00:03 from 192.168.50.23:5353 accepted udp:1.1.1.1:53 [proxy]\n")
+		f.Close()
+	}()
+
+	body := streamLogs(t, deps, "/api/logs/stream?tail=0", nil, 300*time.Millisecond).Body.String()
+	if strings.Count(body, "event: log\n") != 1 || !strings.Contains(body, `"source":"xray"`) {
+		t.Errorf("expected appended xray line only, got:\n%s", body)
+	}
+}
+
+func TestHandleLogStream_Rotation(t *testing.T) {
+	deps := newLogStreamDeps(t)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		os.WriteFile(deps.Paths.BotLogPath, []byte("new\n"), 0644)
+	}()
+
+	body := streamLogs(t, deps, "/api/logs/stream?sources=bot&tail=0", nil, 300*time.Millisecond).Body.String()
+	if !strings.Contains(body, "event: rotated\n") || !strings.Contains(body, `"line":"new"`) {
+		t.Errorf("expected rotation and new line, got:\n%s", body)
+	}
+}
+
+func TestHandleLogStream_BadRequest(t *testing.T) {
+	deps := newLogStreamDeps(t)
+
+	for _, target := range []string{
+		"/api/logs/stream?sources=kernel",
+		"/api/logs/stream?level=loud",
+		"/api/logs/stream?q=(",
+		"/api/logs/stream?client=nope",
+		"/api/logs/stream?tail=-1",
+		"/api/logs/stream?from=vpn",
+	} {
+		rec := streamLogs(t, deps, target, nil, 50*time.Millisecond)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package webapi
+
+import (
+	"net/http"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+)
+
+// optimizeResponse is the JSON body returned by GET and POST /api/optimize.
+type optimizeResponse struct {
+	vpnconfig.OptimizeReport
+	Fingerprint string `json:"fingerprint"` // pass to POST to apply these changes
+	Applied     bool   `json:"applied"`
+}
+
+// optimizeRequest is the expected JSON body for POST /api/optimize.
+type optimizeRequest struct {
+	Fingerprint string `json:"fingerprint"`
+}
+
+// handlePreviewOptimize returns a handler that reports what optimizing the
+// address lists would change, without saving.
+func handlePreviewOptimize(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		cfg, err := deps.Config.LoadVPNConfig()
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to load configuration")
+			return
+		}
+		report := vpnconfig.Optimize(cfg)
+		jsonOK(w, optimizeResponse{OptimizeReport: report, Fingerprint: report.Fingerprint()})
+	}
+}
+
+// handleApplyOptimize returns a handler that aggregates exclude_ips and
+// saves the result. Conflicts are reported but left for the user. The body
+// must carry the fingerprint of the preview the user confirmed; returns 409
+// if the lists have changed since.
+func handleApplyOptimize(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		unlock, ok := lockConfig(w, deps)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		var req optimizeRequest
+		if err := decodeJSON(r, &req); err != nil || req.Fingerprint == "" {
+			jsonError(w, http.StatusBadRequest, "fingerprint from GET /api/optimize is required")
+			return
+		}
+
+		cfg, err := deps.Config.LoadVPNConfig()
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to load configuration")
+			return
+		}
+
+		report := vpnconfig.Optimize(cfg)
+		if len(report.Changes) > 0 && report.Fingerprint() != req.Fingerprint {
+			jsonError(w, http.StatusConflict, "address lists changed since the preview")
+			return
+		}
+		if len(report.Changes) > 0 {
+			before, after := report.Lists()
+			auditChange(r, "", before, after)
+			if err := deps.Config.SaveVPNConfig(cfg); err != nil {
+				jsonError(w, http.StatusInternalServerError, "failed to save configuration")
+				return
+			}
+		}
+
+		jsonOK(w, optimizeResponse{OptimizeReport: report, Fingerprint: report.Fingerprint(), Applied: len(report.Changes) > 0})
+	}
+}
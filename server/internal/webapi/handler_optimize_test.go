@@ -0,0 +1,166 @@
+package webapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+)
+
+func optimizeTestConfig() *vpnconfig.VPNDirectorConfig {
+	return &vpnconfig.VPNDirectorConfig{
+		Xray: vpnconfig.XrayConfig{
+			Clients:    []string{"192.168.1.0/24"},
+			ExcludeIPs: []string{"10.0.0.0/25", "10.0.0.128/25"},
+		},
+		TunnelDirector: vpnconfig.TunnelDirectorConfig{
+			Tunnels: map[string]vpnconfig.TunnelConfig{
+				"wgc1": {Clients: []string{"192.168.1.50"}},
+			},
+		},
+	}
+}
+
+func decodeOptimizeResponse(t *testing.T, rec *httptest.ResponseRecorder) optimizeResponse {
+	t.Helper()
+	var resp optimizeResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	return resp
+}
+
+// applyOptimizeRequest posts the fingerprint of the preview of
+// optimizeTestConfig.
+func applyOptimizeRequest() *http.Request {
+	fp := vpnconfig.Optimize(optimizeTestConfig()).Fingerprint()
+	return httptest.NewRequest("POST", "/api/optimize", strings.NewReader(`{"fingerprint":"`+fp+`"}`))
+}
+
+func TestHandlePreviewOptimize_OK(t *testing.T) {
+	mc := &mockConfig{cfg: optimizeTestConfig()}
+	deps := newTestDeps(t)
+	deps.Config = mc
+
+	req := httptest.NewRequest("GET", "/api/optimize", nil)
+	rec := httptest.NewRecorder()
+	handlePreviewOptimize(deps).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	resp := decodeOptimizeResponse(t, rec)
+	if resp.Applied {
+		t.Error("preview must not report applied")
+	}
+	if len(resp.Changes) != 1 || resp.Changes[0].List != "xray.exclude_ips" {
+		t.Fatalf("unexpected changes: %+v", resp.Changes)
+	}
+	if len(resp.Changes[0].After) != 1 || resp.Changes[0].After[0] != "10.0.0.0/24" {
+		t.Errorf("unexpected after: %v", resp.Changes[0].After)
+	}
+	if resp.Fingerprint == "" {
+		t.Error("expected a fingerprint to apply the preview with")
+	}
+	if len(resp.Conflicts) != 1 || resp.Conflicts[0].Route != "wgc1" {
+		t.Errorf("unexpected conflicts: %+v", resp.Conflicts)
+	}
+	if mc.savedCfg != nil {
+		t.Error("preview must not save configuration")
+	}
+}
+
+func TestHandlePreviewOptimize_LoadError(t *testing.T) {
+	deps := newTestDeps(t)
+	deps.Config = &mockConfig{err: errors.New("load failed")}
+
+	req := httptest.NewRequest("GET", "/api/optimize", nil)
+	rec := httptest.NewRecorder()
+	handlePreviewOptimize(deps).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", rec.Code)
+	}
+}
+
+func TestHandleApplyOptimize_Saves(t *testing.T) {
+	mc := &mockConfig{cfg: optimizeTestConfig()}
+	deps := newTestDeps(t)
+	deps.Config = mc
+
+	rec := httptest.NewRecorder()
+	handleApplyOptimize(deps).ServeHTTP(rec, applyOptimizeRequest())
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	resp := decodeOptimizeResponse(t, rec)
+	if !resp.Applied {
+		t.Error("expected applied=true")
+	}
+	if mc.savedCfg == nil {
+		t.Fatal("expected configuration to be saved")
+	}
+	if got := mc.savedCfg.Xray.ExcludeIPs; len(got) != 1 || got[0] != "10.0.0.0/24" {
+		t.Errorf("unexpected saved exclude_ips: %v", got)
+	}
+}
+
+func TestHandleApplyOptimize_ChangedSincePreview(t *testing.T) {
+	cfg := optimizeTestConfig()
+	cfg.Xray.ExcludeIPs = append(cfg.Xray.ExcludeIPs, "10.0.1.0/24")
+	mc := &mockConfig{cfg: cfg}
+	deps := newTestDeps(t)
+	deps.Config = mc
+
+	rec := httptest.NewRecorder()
+	handleApplyOptimize(deps).ServeHTTP(rec, applyOptimizeRequest())
+
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if mc.savedCfg != nil {
+		t.Error("expected no save when the lists changed since the preview")
+	}
+}
+
+func TestHandleApplyOptimize_RequiresFingerprint(t *testing.T) {
+	mc := &mockConfig{cfg: optimizeTestConfig()}
+	deps := newTestDeps(t)
+	deps.Config = mc
+
+	rec := httptest.NewRecorder()
+	handleApplyOptimize(deps).ServeHTTP(rec, httptest.NewRequest("POST", "/api/optimize", nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+	if mc.savedCfg != nil {
+		t.Error("expected no save without a preview")
+	}
+}
+
+func TestHandleApplyOptimize_NoChanges(t *testing.T) {
+	mc := &mockConfig{cfg: &vpnconfig.VPNDirectorConfig{
+		Xray: vpnconfig.XrayConfig{Clients: []string{"192.168.1.10"}},
+	}}
+	deps := newTestDeps(t)
+	deps.Config = mc
+
+	rec := httptest.NewRecorder()
+	handleApplyOptimize(deps).ServeHTTP(rec, applyOptimizeRequest())
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if resp := decodeOptimizeResponse(t, rec); resp.Applied {
+		t.Error("expected applied=false")
+	}
+	if mc.savedCfg != nil {
+		t.Error("expected no save when nothing changed")
+	}
+}
@@ -7,9 +7,10 @@ import (
 	"net"
 	"net/http"
 	"net/url"
-	"sort"
+	"strings"
 	"time"
 
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/events"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vless"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
 )
@@ -27,12 +28,14 @@ func handleListServers(deps *Deps) http.HandlerFunc {
 }
 
 // selectServerRequest is the expected JSON body for POST /api/servers/active.
+// ID is preferred; Index is kept for older clients.
 type selectServerRequest struct {
-	Index *int `json:"index"`
+	ID    string `json:"id"`
+	Index *int   `json:"index"`
 }
 
-// handleSelectServer returns a handler that selects a server by index,
-// generates Xray config, updates vpn-director.json, and restarts Xray.
+// handleSelectServer returns a handler that selects a server by ID (or legacy
+// index), generates Xray config, updates vpn-director.json, and restarts Xray.
 func handleSelectServer(deps *Deps) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req selectServerRequest
@@ -41,26 +44,39 @@ func handleSelectServer(deps *Deps) http.HandlerFunc {
 			return
 		}
 
-		if req.Index == nil {
-			jsonError(w, http.StatusBadRequest, "index is required")
+		if req.ID == "" && req.Index == nil {
+			jsonError(w, http.StatusBadRequest, "id is required")
 			return
 		}
 
+		unlock, ok := lockConfig(w, deps)
+		if !ok {
+			return
+		}
+		defer unlock()
+
 		servers, err := deps.Config.LoadServers()
 		if err != nil {
 			jsonError(w, http.StatusInternalServerError, "failed to load servers")
 			return
 		}
 
-		if *req.Index < 0 || *req.Index >= len(servers) {
-			jsonError(w, http.StatusBadRequest, fmt.Sprintf("index out of range: %d (have %d servers)", *req.Index, len(servers)))
-			return
+		idx := -1
+		if req.ID != "" {
+			if idx = vpnconfig.FindServer(servers, req.ID); idx < 0 {
+				jsonError(w, http.StatusNotFound, fmt.Sprintf("server not found: %s", req.ID))
+				return
+			}
+		} else {
+			if *req.Index < 0 || *req.Index >= len(servers) {
+				jsonError(w, http.StatusBadRequest, fmt.Sprintf("index out of range: %d (have %d servers)", *req.Index, len(servers)))
+				return
+			}
+			idx = *req.Index
 		}
 
-		deps.OpMutex.Lock()
-		defer deps.OpMutex.Unlock()
-
-		server := servers[*req.Index]
+		server := servers[idx]
+		auditChange(r, server.ID, activeServerName(deps, servers), server.Name)
 
 		if err := deps.Xray.GenerateConfig(server); err != nil {
 			jsonError(w, http.StatusInternalServerError, "failed to generate xray config")
@@ -73,13 +89,20 @@ func handleSelectServer(deps *Deps) http.HandlerFunc {
 			return
 		}
 
-		cfg.Xray.Servers = server.IPs
+		// Every server stays bypassed, not only the active one
+		cfg.Xray.Servers = vpnconfig.ServerIPs(servers)
 		if err := deps.Config.SaveVPNConfig(cfg); err != nil {
 			jsonError(w, http.StatusInternalServerError, "failed to save vpn config")
 			return
 		}
 
-		if err := deps.VPN.RestartXray(); err != nil {
+		err = deps.VPN.RestartXray()
+		switchEvent := events.Event{Kind: events.KindServerSwitch, OK: err == nil, Source: "webui", Message: server.Name}
+		if err != nil {
+			switchEvent.Message += ": " + err.Error()
+		}
+		deps.recordEvent(switchEvent)
+		if err != nil {
 			jsonError(w, http.StatusInternalServerError, "failed to restart xray")
 			return
 		}
@@ -88,6 +111,340 @@ func handleSelectServer(deps *Deps) http.HandlerFunc {
 	}
 }
 
+// addServerRequest is the expected JSON body for POST /api/servers. Either
+// URI (a vless:// link) or Address, Port and UUID must be set.
+type addServerRequest struct {
+	URI     string `json:"uri"`
+	Address string `json:"address"`
+	Port    int    `json:"port"`
+	UUID    string `json:"uuid"`
+	Name    string `json:"name"`
+}
+
+// handleAddServer returns a handler that adds a single server. The address is
+// resolved before saving; xray.servers is synced and the Xray rules are
+// re-applied.
+func handleAddServer(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req addServerRequest
+		if err := decodeJSON(r, &req); err != nil {
+			jsonError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+
+		var vs *vless.Server
+		if req.URI != "" {
+			parsed, err := vless.ParseURI(strings.TrimSpace(req.URI))
+			if err != nil {
+				jsonError(w, http.StatusBadRequest, fmt.Sprintf("invalid vless URI: %s", err))
+				return
+			}
+			vs = parsed
+			if name := strings.TrimSpace(req.Name); name != "" {
+				vs.Name = name
+			}
+		} else {
+			vs = &vless.Server{
+				Address: strings.TrimSpace(req.Address),
+				Port:    req.Port,
+				UUID:    strings.TrimSpace(req.UUID),
+				Name:    strings.TrimSpace(req.Name),
+			}
+			if msg := validateServerFields(vs.Address, vs.Port, vs.UUID); msg != "" {
+				jsonError(w, http.StatusBadRequest, msg)
+				return
+			}
+			if vs.Name == "" {
+				vs.Name = vs.Address
+			}
+		}
+
+		if err := vs.ResolveIPs(); err != nil {
+			jsonError(w, http.StatusBadRequest, fmt.Sprintf("cannot resolve %s: %s", vs.Address, err))
+			return
+		}
+
+		unlock, ok := lockConfig(w, deps)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		servers, err := loadServersCopy(deps)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to load servers")
+			return
+		}
+
+		server := vpnconfig.Server{
+			Address: vs.Address,
+			Port:    vs.Port,
+			UUID:    vs.UUID,
+			Name:    vs.Name,
+			IPs:     vs.IPs,
+			IPs6:    vs.IPs6,
+		}
+		if dup := vpnconfig.FindDuplicate(servers, server, -1); dup >= 0 {
+			jsonError(w, http.StatusConflict, fmt.Sprintf("server already exists: %s", servers[dup].ID))
+			return
+		}
+
+		servers = append(servers, server)
+		vpnconfig.AssignServerIDs(servers)
+		auditChange(r, servers[len(servers)-1].ID, nil, auditServer(server))
+
+		if err := saveServers(deps, servers, nil); err != nil {
+			jsonError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		jsonOK(w, map[string]interface{}{"ok": true, "server": servers[len(servers)-1]})
+	}
+}
+
+// updateServerRequest is the expected JSON body for PUT /api/servers/{id}.
+// Only fields that are set are changed.
+type updateServerRequest struct {
+	Address *string `json:"address"`
+	Port    *int    `json:"port"`
+	UUID    *string `json:"uuid"`
+	Name    *string `json:"name"`
+}
+
+// handleUpdateServer returns a handler that edits or renames a server. The ID
+// is kept; the address is re-resolved if it changed. Editing the active
+// server regenerates the Xray config and restarts Xray.
+func handleUpdateServer(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		var req updateServerRequest
+		if err := decodeJSON(r, &req); err != nil {
+			jsonError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+
+		// Resolve a new address before taking the lock, so a slow lookup
+		// does not block other operations
+		var resolved *vless.Server
+		if req.Address != nil {
+			address := strings.TrimSpace(*req.Address)
+			servers, err := deps.Config.LoadServers()
+			if err != nil {
+				jsonError(w, http.StatusInternalServerError, "failed to load servers")
+				return
+			}
+			if idx := vpnconfig.FindServer(servers, id); address != "" && idx >= 0 && address != servers[idx].Address {
+				resolved = &vless.Server{Address: address}
+				if err := resolved.ResolveIPs(); err != nil {
+					jsonError(w, http.StatusBadRequest, fmt.Sprintf("cannot resolve %s: %s", address, err))
+					return
+				}
+			}
+		}
+
+		unlock, ok := lockConfig(w, deps)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		servers, err := loadServersCopy(deps)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to load servers")
+			return
+		}
+
+		idx := vpnconfig.FindServer(servers, id)
+		if idx < 0 {
+			jsonError(w, http.StatusNotFound, fmt.Sprintf("server not found: %s", id))
+			return
+		}
+
+		server := servers[idx]
+		if req.Address != nil {
+			server.Address = strings.TrimSpace(*req.Address)
+		}
+		if req.Port != nil {
+			server.Port = *req.Port
+		}
+		if req.UUID != nil {
+			server.UUID = strings.TrimSpace(*req.UUID)
+		}
+		if req.Name != nil {
+			server.Name = strings.TrimSpace(*req.Name)
+		}
+		if msg := validateServerFields(server.Address, server.Port, server.UUID); msg != "" {
+			jsonError(w, http.StatusBadRequest, msg)
+			return
+		}
+		if server.Name == "" {
+			server.Name = server.Address
+		}
+		if dup := vpnconfig.FindDuplicate(servers, server, idx); dup >= 0 {
+			jsonError(w, http.StatusConflict, fmt.Sprintf("server already exists: %s", servers[dup].ID))
+			return
+		}
+
+		if server.Address != servers[idx].Address {
+			// The server changed between the lookup and the lock
+			if resolved == nil || resolved.Address != server.Address {
+				jsonError(w, http.StatusConflict, "server changed during the update, try again")
+				return
+			}
+			server.IPs = resolved.IPs
+			server.IPs6 = resolved.IPs6
+		}
+		auditChange(r, "", auditServer(servers[idx]), auditServer(server))
+
+		var active *vpnconfig.Server
+		if activeServerIndex(deps, servers) == idx {
+			active = &server
+		}
+		servers[idx] = server
+
+		if err := saveServers(deps, servers, active); err != nil {
+			jsonError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		jsonOK(w, map[string]interface{}{"ok": true, "server": server})
+	}
+}
+
+// handleDeleteServer returns a handler that removes a server by ID. The
+// active server cannot be removed: Xray would keep using it.
+func handleDeleteServer(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		unlock, ok := lockConfig(w, deps)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		servers, err := loadServersCopy(deps)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to load servers")
+			return
+		}
+
+		idx := vpnconfig.FindServer(servers, id)
+		if idx < 0 {
+			jsonError(w, http.StatusNotFound, fmt.Sprintf("server not found: %s", id))
+			return
+		}
+		if activeServerIndex(deps, servers) == idx {
+			jsonError(w, http.StatusConflict, "cannot delete the active server, switch to another one first")
+			return
+		}
+		auditChange(r, "", auditServer(servers[idx]), nil)
+		servers = append(servers[:idx], servers[idx+1:]...)
+
+		if err := saveServers(deps, servers, nil); err != nil {
+			jsonError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		jsonOK(w, map[string]bool{"ok": true})
+	}
+}
+
+// auditServer is the part of a server recorded in the audit log; the UUID
+// is a credential and is left out.
+func auditServer(s vpnconfig.Server) map[string]any {
+	return map[string]any{"name": s.Name, "address": s.Address, "port": s.Port}
+}
+
+// activeServer returns the server Xray uses now, or nil when unknown.
+func activeServer(deps *Deps) *vpnconfig.Server {
+	if deps.XrayConfig == nil {
+		return nil
+	}
+	active, err := deps.XrayConfig.ActiveServer()
+	if err != nil {
+		return nil
+	}
+	return active
+}
+
+// activeServerIndex returns the index of the server Xray uses now, or -1.
+func activeServerIndex(deps *Deps, servers []vpnconfig.Server) int {
+	active := activeServer(deps)
+	if active == nil {
+		return -1
+	}
+	return vpnconfig.FindActive(servers, *active)
+}
+
+// activeServerName names the server Xray uses now: its name, its address
+// when it is not in servers, or "" when unknown.
+func activeServerName(deps *Deps, servers []vpnconfig.Server) string {
+	active := activeServer(deps)
+	if active == nil {
+		return ""
+	}
+	if idx := vpnconfig.FindActive(servers, *active); idx >= 0 {
+		return servers[idx].Name
+	}
+	return active.Address
+}
+
+// validateServerFields returns an error message for an invalid server, or "".
+func validateServerFields(address string, port int, uuid string) string {
+	switch {
+	case address == "":
+		return "address is required"
+	case port < 1 || port > 65535:
+		return "port must be between 1 and 65535"
+	case uuid == "":
+		return "uuid is required"
+	}
+	return ""
+}
+
+// loadServersCopy loads servers into a fresh slice so edits do not alias the
+// store's data before saving.
+func loadServersCopy(deps *Deps) ([]vpnconfig.Server, error) {
+	servers, err := deps.Config.LoadServers()
+	if err != nil {
+		return nil, err
+	}
+	return append([]vpnconfig.Server{}, servers...), nil
+}
+
+// saveServers writes servers.json, syncs xray.servers with all server IPs
+// and re-applies the Xray rules so the TPROXY bypass list matches. When
+// active is set, its Xray config is regenerated and Xray restarted instead.
+func saveServers(deps *Deps, servers []vpnconfig.Server, active *vpnconfig.Server) error {
+	if err := deps.Config.SaveServers(servers); err != nil {
+		return fmt.Errorf("failed to save servers")
+	}
+	cfg, err := deps.Config.LoadVPNConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load vpn config")
+	}
+	cfg.Xray.Servers = vpnconfig.ServerIPs(servers)
+	if err := deps.Config.SaveVPNConfig(cfg); err != nil {
+		return fmt.Errorf("failed to save vpn config")
+	}
+
+	if active == nil {
+		if err := deps.VPN.ApplyXray(); err != nil {
+			return fmt.Errorf("servers saved, but applying xray rules failed")
+		}
+		return nil
+	}
+	if err := deps.Xray.GenerateConfig(*active); err != nil {
+		return fmt.Errorf("servers saved, but generating xray config failed")
+	}
+	if err := deps.VPN.RestartXray(); err != nil {
+		return fmt.Errorf("servers saved, but restarting xray failed")
+	}
+	return nil
+}
+
 // importServersRequest is the expected JSON body for POST /api/servers/import.
 type importServersRequest struct {
 	URL string `json:"url"`
@@ -172,6 +529,7 @@ func handleImportServers(deps *Deps) http.HandlerFunc {
 				UUID:    s.UUID,
 				Name:    s.Name,
 				IPs:     s.IPs,
+				IPs6:    s.IPs6,
 			})
 		}
 
@@ -180,29 +538,19 @@ func handleImportServers(deps *Deps) http.HandlerFunc {
 			return
 		}
 
-		deps.OpMutex.Lock()
-		defer deps.OpMutex.Unlock()
-
-		if err := deps.Config.SaveServers(resolved); err != nil {
-			jsonError(w, http.StatusInternalServerError, "failed to save servers")
+		unlock, ok := lockConfig(w, deps)
+		if !ok {
 			return
 		}
+		defer unlock()
 
-		// Sync xray.servers with all imported server IPs.
-		if vpnCfg, err := deps.Config.LoadVPNConfig(); err == nil && vpnCfg != nil {
-			seen := make(map[string]bool)
-			var serverIPs []string
-			for _, s := range resolved {
-				for _, ip := range s.IPs {
-					if ip != "" && !seen[ip] {
-						seen[ip] = true
-						serverIPs = append(serverIPs, ip)
-					}
-				}
-			}
-			sort.Strings(serverIPs)
-			vpnCfg.Xray.Servers = serverIPs
-			_ = deps.Config.SaveVPNConfig(vpnCfg)
+		if old, err := deps.Config.LoadServers(); err == nil {
+			auditChange(r, parsed.Host, map[string]int{"servers": len(old)}, map[string]int{"servers": len(resolved)})
+		}
+		// Sync xray.servers with all imported server IPs and re-apply.
+		if err := saveServers(deps, resolved, nil); err != nil {
+			jsonError(w, http.StatusInternalServerError, err.Error())
+			return
 		}
 
 		jsonOK(w, map[string]interface{}{"ok": true, "count": len(resolved)})
@@ -5,12 +5,40 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
 )
 
+// countingVPN counts the Xray rule applies and restarts.
+type countingVPN struct {
+	mockVPN
+	applyXray   int
+	restartXray int
+}
+
+func (m *countingVPN) ApplyXray() error {
+	m.applyXray++
+	return m.err
+}
+
+func (m *countingVPN) RestartXray() error {
+	m.restartXray++
+	return m.err
+}
+
+// recordingXray records the servers Xray configs are generated for.
+type recordingXray struct {
+	generated []vpnconfig.Server
+}
+
+func (m *recordingXray) GenerateConfig(s vpnconfig.Server) error {
+	m.generated = append(m.generated, s)
+	return nil
+}
+
 func TestHandleListServers_OK(t *testing.T) {
 	deps := newTestDeps(t)
 	deps.Config = &mockConfig{
@@ -97,12 +125,12 @@ func TestHandleSelectServer_OK(t *testing.T) {
 		t.Error("expected ok: true")
 	}
 
-	// Verify saved config has updated IPs.
+	// Every server stays in the bypass list, not only the selected one
 	if mc.savedCfg == nil {
 		t.Fatal("expected config to be saved")
 	}
-	if len(mc.savedCfg.Xray.Servers) != 1 || mc.savedCfg.Xray.Servers[0] != "2.2.2.2" {
-		t.Errorf("expected Xray.Servers=[2.2.2.2], got %v", mc.savedCfg.Xray.Servers)
+	if want := []string{"1.1.1.1", "2.2.2.2"}; !reflect.DeepEqual(mc.savedCfg.Xray.Servers, want) {
+		t.Errorf("expected Xray.Servers=%v, got %v", want, mc.savedCfg.Xray.Servers)
 	}
 }
 
@@ -163,6 +191,341 @@ func TestHandleSelectServer_InvalidJSON(t *testing.T) {
 	}
 }
 
+func TestHandleSelectServer_ByID(t *testing.T) {
+	mc := &mockConfig{
+		servers: []vpnconfig.Server{
+			{ID: "aaaa1111", Address: "s1.example.com", Port: 443, UUID: "uuid-1", Name: "S1", IPs: []string{"1.1.1.1"}},
+			{ID: "bbbb2222", Address: "s2.example.com", Port: 443, UUID: "uuid-2", Name: "S2", IPs: []string{"2.2.2.2"}},
+		},
+		cfg: &vpnconfig.VPNDirectorConfig{},
+	}
+	xray := &mockXray{}
+	deps := newTestDeps(t)
+	deps.Config = mc
+	deps.Xray = xray
+
+	req := httptest.NewRequest("POST", "/api/servers/active", strings.NewReader(`{"id": "bbbb2222"}`))
+	rec := httptest.NewRecorder()
+	handleSelectServer(deps).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if xray.generated == nil || xray.generated.Name != "S2" {
+		t.Errorf("expected config generated for S2, got %+v", xray.generated)
+	}
+}
+
+func TestHandleSelectServer_UnknownID(t *testing.T) {
+	deps := newTestDeps(t)
+	deps.Config = &mockConfig{
+		servers: []vpnconfig.Server{{ID: "aaaa1111", Address: "s1.example.com", Port: 443, UUID: "uuid-1"}},
+	}
+
+	req := httptest.NewRequest("POST", "/api/servers/active", strings.NewReader(`{"id": "nope"}`))
+	rec := httptest.NewRecorder()
+	handleSelectServer(deps).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandleAddServer_Fields(t *testing.T) {
+	mc := &mockConfig{
+		servers: []vpnconfig.Server{
+			{ID: "aaaa1111", Address: "s1.example.com", Port: 443, UUID: "uuid-1", Name: "S1", IPs: []string{"1.1.1.1"}},
+		},
+		cfg: &vpnconfig.VPNDirectorConfig{},
+	}
+	deps := newTestDeps(t)
+	deps.Config = mc
+
+	// IP literal resolves without DNS
+	body := `{"address": "203.0.113.7", "port": 443, "uuid": "uuid-2", "name": "New"}`
+	req := httptest.NewRequest("POST", "/api/servers", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handleAddServer(deps).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Server vpnconfig.Server `json:"server"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Server.ID == "" || resp.Server.Name != "New" {
+		t.Errorf("unexpected server: %+v", resp.Server)
+	}
+	if len(mc.savedServers) != 2 {
+		t.Fatalf("expected 2 saved servers, got %d", len(mc.savedServers))
+	}
+	want := []string{"1.1.1.1", "203.0.113.7"}
+	if mc.savedCfg == nil || !reflect.DeepEqual(mc.savedCfg.Xray.Servers, want) {
+		t.Errorf("expected xray.servers %v, got %+v", want, mc.savedCfg)
+	}
+}
+
+func TestHandleAddServer_AppliesRules(t *testing.T) {
+	deps := newTestDeps(t)
+	deps.Config = &mockConfig{cfg: &vpnconfig.VPNDirectorConfig{}}
+	vpn := &countingVPN{}
+	deps.VPN = vpn
+
+	body := `{"address": "203.0.113.7", "port": 443, "uuid": "uuid-2"}`
+	rec := httptest.NewRecorder()
+	handleAddServer(deps).ServeHTTP(rec, httptest.NewRequest("POST", "/api/servers", strings.NewReader(body)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if vpn.applyXray != 1 || vpn.restartXray != 0 {
+		t.Errorf("expected the bypass list re-applied, got %d applies, %d restarts", vpn.applyXray, vpn.restartXray)
+	}
+}
+
+func TestHandleAddServer_URI(t *testing.T) {
+	mc := &mockConfig{cfg: &vpnconfig.VPNDirectorConfig{}}
+	deps := newTestDeps(t)
+	deps.Config = mc
+
+	body := `{"uri": "vless://uuid-3@203.0.113.8:8443?security=reality#Linked"}`
+	req := httptest.NewRequest("POST", "/api/servers", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handleAddServer(deps).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(mc.savedServers) != 1 || mc.savedServers[0].Port != 8443 || mc.savedServers[0].Name != "Linked" {
+		t.Errorf("unexpected saved servers: %+v", mc.savedServers)
+	}
+}
+
+func TestHandleAddServer_Duplicate(t *testing.T) {
+	deps := newTestDeps(t)
+	deps.Config = &mockConfig{
+		servers: []vpnconfig.Server{
+			{ID: "aaaa1111", Address: "203.0.113.7", Port: 443, UUID: "uuid-1"},
+		},
+		cfg: &vpnconfig.VPNDirectorConfig{},
+	}
+
+	body := `{"address": "203.0.113.7", "port": 443, "uuid": "uuid-1"}`
+	req := httptest.NewRequest("POST", "/api/servers", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handleAddServer(deps).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandleAddServer_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"missing address", `{"port": 443, "uuid": "u"}`},
+		{"bad port", `{"address": "203.0.113.7", "port": 70000, "uuid": "u"}`},
+		{"missing uuid", `{"address": "203.0.113.7", "port": 443}`},
+		{"bad uri", `{"uri": "vmess://abc"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := newTestDeps(t)
+			req := httptest.NewRequest("POST", "/api/servers", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			handleAddServer(deps).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400, got %d: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleUpdateServer_Rename(t *testing.T) {
+	mc := &mockConfig{
+		servers: []vpnconfig.Server{
+			{ID: "aaaa1111", Address: "s1.example.com", Port: 443, UUID: "uuid-1", Name: "S1", IPs: []string{"1.1.1.1"}},
+		},
+		cfg: &vpnconfig.VPNDirectorConfig{},
+	}
+	deps := newTestDeps(t)
+	deps.Config = mc
+
+	req := httptest.NewRequest("PUT", "/api/servers/aaaa1111", strings.NewReader(`{"name": "Renamed"}`))
+	req.SetPathValue("id", "aaaa1111")
+	rec := httptest.NewRecorder()
+	handleUpdateServer(deps).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	got := mc.savedServers[0]
+	if got.ID != "aaaa1111" || got.Name != "Renamed" || got.IPs[0] != "1.1.1.1" {
+		t.Errorf("unexpected saved server: %+v", got)
+	}
+	// The loaded slice must not be modified in place
+	if mc.servers[0].Name != "S1" {
+		t.Errorf("store data modified before save: %+v", mc.servers[0])
+	}
+}
+
+func TestHandleUpdateServer_AddressReresolved(t *testing.T) {
+	mc := &mockConfig{
+		servers: []vpnconfig.Server{
+			{ID: "aaaa1111", Address: "203.0.113.1", Port: 443, UUID: "uuid-1", Name: "S1", IPs: []string{"203.0.113.1"}},
+		},
+		cfg: &vpnconfig.VPNDirectorConfig{},
+	}
+	deps := newTestDeps(t)
+	deps.Config = mc
+
+	req := httptest.NewRequest("PUT", "/api/servers/aaaa1111", strings.NewReader(`{"address": "2001:db8::5"}`))
+	req.SetPathValue("id", "aaaa1111")
+	rec := httptest.NewRecorder()
+	handleUpdateServer(deps).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	got := mc.savedServers[0]
+	if len(got.IPs) != 0 || len(got.IPs6) != 1 || got.IPs6[0] != "2001:db8::5" {
+		t.Errorf("expected re-resolved IPv6 address, got %+v", got)
+	}
+	if !reflect.DeepEqual(mc.savedCfg.Xray.Servers, []string{"2001:db8::5"}) {
+		t.Errorf("unexpected xray.servers: %v", mc.savedCfg.Xray.Servers)
+	}
+}
+
+func TestHandleUpdateServer_ActiveRegenerates(t *testing.T) {
+	mc := &mockConfig{
+		servers: []vpnconfig.Server{
+			{ID: "aaaa1111", Address: "203.0.113.1", Port: 443, UUID: "uuid-1", Name: "S1", IPs: []string{"203.0.113.1"}},
+			{ID: "bbbb2222", Address: "203.0.113.2", Port: 443, UUID: "uuid-2", Name: "S2", IPs: []string{"203.0.113.2"}},
+		},
+		cfg: &vpnconfig.VPNDirectorConfig{},
+	}
+	deps := newTestDeps(t)
+	deps.Config = mc
+	deps.XrayConfig = &mockXrayConfig{server: &vpnconfig.Server{Address: "203.0.113.1", Port: 443, UUID: "uuid-1"}}
+	vpn := &countingVPN{}
+	deps.VPN = vpn
+	xray := &recordingXray{}
+	deps.Xray = xray
+
+	req := httptest.NewRequest("PUT", "/api/servers/aaaa1111", strings.NewReader(`{"address": "203.0.113.9"}`))
+	req.SetPathValue("id", "aaaa1111")
+	rec := httptest.NewRecorder()
+	handleUpdateServer(deps).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(xray.generated) != 1 || xray.generated[0].Address != "203.0.113.9" {
+		t.Errorf("expected the xray config regenerated for the new address, got %+v", xray.generated)
+	}
+	if vpn.restartXray != 1 || vpn.applyXray != 0 {
+		t.Errorf("expected xray restarted, got %d restarts, %d applies", vpn.restartXray, vpn.applyXray)
+	}
+
+	// Editing another server only re-applies the rules
+	req = httptest.NewRequest("PUT", "/api/servers/bbbb2222", strings.NewReader(`{"name": "Renamed"}`))
+	req.SetPathValue("id", "bbbb2222")
+	rec = httptest.NewRecorder()
+	handleUpdateServer(deps).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(xray.generated) != 1 || vpn.applyXray != 1 {
+		t.Errorf("expected only a re-apply, got %d generated, %d applies", len(xray.generated), vpn.applyXray)
+	}
+}
+
+func TestHandleUpdateServer_NotFound(t *testing.T) {
+	deps := newTestDeps(t)
+	deps.Config = &mockConfig{cfg: &vpnconfig.VPNDirectorConfig{}}
+
+	req := httptest.NewRequest("PUT", "/api/servers/nope", strings.NewReader(`{"name": "x"}`))
+	req.SetPathValue("id", "nope")
+	rec := httptest.NewRecorder()
+	handleUpdateServer(deps).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandleDeleteServer_OK(t *testing.T) {
+	mc := &mockConfig{
+		servers: []vpnconfig.Server{
+			{ID: "aaaa1111", Address: "s1.example.com", Port: 443, UUID: "uuid-1", IPs: []string{"1.1.1.1"}},
+			{ID: "bbbb2222", Address: "s2.example.com", Port: 443, UUID: "uuid-2", IPs: []string{"2.2.2.2"}},
+		},
+		cfg: &vpnconfig.VPNDirectorConfig{},
+	}
+	deps := newTestDeps(t)
+	deps.Config = mc
+
+	req := httptest.NewRequest("DELETE", "/api/servers/aaaa1111", nil)
+	req.SetPathValue("id", "aaaa1111")
+	rec := httptest.NewRecorder()
+	handleDeleteServer(deps).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(mc.savedServers) != 1 || mc.savedServers[0].ID != "bbbb2222" {
+		t.Errorf("unexpected saved servers: %+v", mc.savedServers)
+	}
+	if !reflect.DeepEqual(mc.savedCfg.Xray.Servers, []string{"2.2.2.2"}) {
+		t.Errorf("unexpected xray.servers: %v", mc.savedCfg.Xray.Servers)
+	}
+}
+
+func TestHandleDeleteServer_Active(t *testing.T) {
+	mc := &mockConfig{
+		servers: []vpnconfig.Server{
+			{ID: "aaaa1111", Address: "s1.example.com", Port: 443, UUID: "uuid-1", IPs: []string{"1.1.1.1"}},
+		},
+		cfg: &vpnconfig.VPNDirectorConfig{},
+	}
+	deps := newTestDeps(t)
+	deps.Config = mc
+	deps.XrayConfig = &mockXrayConfig{server: &vpnconfig.Server{Address: "s1.example.com", Port: 443, UUID: "uuid-1"}}
+
+	req := httptest.NewRequest("DELETE", "/api/servers/aaaa1111", nil)
+	req.SetPathValue("id", "aaaa1111")
+	rec := httptest.NewRecorder()
+	handleDeleteServer(deps).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if mc.savedServers != nil {
+		t.Errorf("expected the active server kept, saved %+v", mc.savedServers)
+	}
+}
+
+func TestHandleDeleteServer_NotFound(t *testing.T) {
+	deps := newTestDeps(t)
+	deps.Config = &mockConfig{cfg: &vpnconfig.VPNDirectorConfig{}}
+
+	req := httptest.NewRequest("DELETE", "/api/servers/nope", nil)
+	req.SetPathValue("id", "nope")
+	rec := httptest.NewRecorder()
+	handleDeleteServer(deps).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
 func TestHandleImportServers_InvalidURL(t *testing.T) {
 	deps := newTestDeps(t)
 
@@ -0,0 +1,130 @@
+package webapi
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/session"
+)
+
+// sessionResponse is a session as listed by GET /api/sessions.
+type sessionResponse struct {
+	session.Session
+	Current bool `json:"current"` // the caller's own session
+}
+
+// visibleSessions returns the sessions the caller may see and revoke:
+// everyone's for admins, otherwise their own. Other API tokens have no
+// sessions: a token's name is not a username.
+func visibleSessions(deps *Deps, p *principal) ([]session.Session, error) {
+	sessions, err := deps.Sessions.List()
+	if err != nil || p.can(permAdmin) {
+		return sessions, err
+	}
+	own := []session.Session{}
+	if p.Token != nil {
+		return own, nil
+	}
+	for _, s := range sessions {
+		if s.Username == p.Name {
+			own = append(own, s)
+		}
+	}
+	return own, nil
+}
+
+// handleListSessions returns active sessions with their address, browser
+// and last activity.
+func handleListSessions(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if deps.Sessions == nil {
+			jsonError(w, http.StatusServiceUnavailable, "sessions are not available")
+			return
+		}
+		p := principalFrom(r.Context())
+
+		sessions, err := visibleSessions(deps, p)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to load sessions")
+			return
+		}
+		resp := make([]sessionResponse, 0, len(sessions))
+		for _, s := range sessions {
+			resp = append(resp, sessionResponse{Session: s, Current: s.ID == p.Session})
+		}
+		jsonOK(w, resp)
+	}
+}
+
+// handleRevokeSession signs out one session. Revoking the caller's own
+// session also clears its cookie.
+func handleRevokeSession(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if deps.Sessions == nil {
+			jsonError(w, http.StatusServiceUnavailable, "sessions are not available")
+			return
+		}
+		p := principalFrom(r.Context())
+		id := r.PathValue("id")
+
+		sessions, err := visibleSessions(deps, p)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to load sessions")
+			return
+		}
+		var found *session.Session
+		for i := range sessions {
+			if sessions[i].ID == id {
+				found = &sessions[i]
+			}
+		}
+		if found == nil {
+			jsonError(w, http.StatusNotFound, session.ErrNotFound.Error())
+			return
+		}
+		auditChange(r, "", map[string]string{"username": found.Username, "ip": found.IP}, nil)
+
+		err = deps.Sessions.Revoke(id)
+		if errors.Is(err, session.ErrNotFound) {
+			jsonError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to revoke session")
+			return
+		}
+		if id == p.Session {
+			clearTokenCookie(w)
+		}
+		jsonOK(w, map[string]bool{"ok": true})
+	}
+}
+
+// handleRevokeSessions signs out every session the caller can see except
+// their own: all users' sessions for admins, otherwise the caller's other
+// browsers. Other API tokens are refused.
+func handleRevokeSessions(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if deps.Sessions == nil {
+			jsonError(w, http.StatusServiceUnavailable, "sessions are not available")
+			return
+		}
+		p := principalFrom(r.Context())
+
+		username := p.Name
+		switch {
+		case p.can(permAdmin):
+			username = ""
+		case p.Token != nil:
+			jsonError(w, http.StatusForbidden, "forbidden: API tokens have no sessions")
+			return
+		}
+		n, err := deps.Sessions.RevokeAll(username, p.Session)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to revoke sessions")
+			return
+		}
+		auditChange(r, username, map[string]int{"sessions": n}, map[string]int{"sessions": 0})
+		jsonOK(w, map[string]int{"revoked": n})
+	}
+}
@@ -0,0 +1,209 @@
+package webapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/apitoken"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/auth"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/session"
+)
+
+func newTestSessions(t *testing.T) *session.Store {
+	t.Helper()
+	return session.NewStore(filepath.Join(t.TempDir(), session.StoreFile))
+}
+
+// newSessionsDeps returns deps with sessions for admin (two browsers) and
+// kid (a viewer).
+func newSessionsDeps(t *testing.T) (*Deps, *session.Store, []session.Session) {
+	t.Helper()
+	deps := newTestDeps(t)
+	store := newTestSessions(t)
+	deps.Sessions = store
+
+	var created []session.Session
+	for _, user := range []string{"admin", "admin", "kid"} {
+		s, err := store.Create(user, "192.168.50.10", "Firefox", time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		created = append(created, s)
+	}
+	return deps, store, created
+}
+
+func sessionRequest(method, path string, p *principal) *http.Request {
+	req := httptest.NewRequest(method, path, nil)
+	return req.WithContext(withPrincipal(req.Context(), p))
+}
+
+func listSessions(t *testing.T, deps *Deps, p *principal) []sessionResponse {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handleListSessions(deps).ServeHTTP(rec, sessionRequest("GET", "/api/sessions", p))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var list []sessionResponse
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	return list
+}
+
+func TestHandleListSessions(t *testing.T) {
+	deps, _, created := newSessionsDeps(t)
+
+	admin := &principal{Name: "admin", Role: auth.RoleAdmin, Session: created[0].ID}
+	list := listSessions(t, deps, admin)
+	if len(list) != 3 {
+		t.Fatalf("expected admins to see all sessions, got %+v", list)
+	}
+	current := 0
+	for _, s := range list {
+		if s.Current {
+			current++
+			if s.ID != created[0].ID {
+				t.Errorf("expected %s marked current, got %s", created[0].ID, s.ID)
+			}
+		}
+		if s.IP != "192.168.50.10" || s.UserAgent != "Firefox" || s.LastSeen.IsZero() {
+			t.Errorf("unexpected session: %+v", s)
+		}
+	}
+	if current != 1 {
+		t.Errorf("expected one current session, got %d", current)
+	}
+
+	kid := &principal{Name: "kid", Role: auth.RoleViewer, Session: created[2].ID}
+	if list := listSessions(t, deps, kid); len(list) != 1 || list[0].Username != "kid" {
+		t.Errorf("expected only the viewer's own session, got %+v", list)
+	}
+}
+
+func TestHandleRevokeSession(t *testing.T) {
+	deps, store, created := newSessionsDeps(t)
+	kid := &principal{Name: "kid", Role: auth.RoleViewer, Session: created[2].ID}
+
+	// Other users' sessions are hidden from non-admins
+	req := sessionRequest("DELETE", "/api/sessions/"+created[0].ID, kid)
+	req.SetPathValue("id", created[0].ID)
+	rec := httptest.NewRecorder()
+	handleRevokeSession(deps).ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+
+	admin := &principal{Name: "admin", Role: auth.RoleAdmin, Session: created[0].ID}
+	req = sessionRequest("DELETE", "/api/sessions/"+created[2].ID, admin)
+	req.SetPathValue("id", created[2].ID)
+	rec = httptest.NewRecorder()
+	handleRevokeSession(deps).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if hasTokenCookie(rec) || len(rec.Result().Cookies()) != 0 {
+		t.Error("expected the caller's cookie untouched")
+	}
+	if list, _ := store.List(); len(list) != 2 {
+		t.Errorf("expected 2 sessions left, got %d", len(list))
+	}
+
+	// Revoking the own session clears the cookie
+	req = sessionRequest("DELETE", "/api/sessions/"+created[0].ID, admin)
+	req.SetPathValue("id", created[0].ID)
+	rec = httptest.NewRecorder()
+	handleRevokeSession(deps).ServeHTTP(rec, req)
+	cookies := rec.Result().Cookies()
+	if rec.Code != http.StatusOK || len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Errorf("expected 200 with a cleared cookie, got %d %v", rec.Code, cookies)
+	}
+}
+
+func TestHandleRevokeSessions(t *testing.T) {
+	deps, store, created := newSessionsDeps(t)
+
+	// A viewer signs out their other browsers only
+	extra, _ := store.Create("kid", "192.168.50.11", "Safari", time.Now().Add(time.Hour))
+	kid := &principal{Name: "kid", Role: auth.RoleViewer, Session: created[2].ID}
+	rec := httptest.NewRecorder()
+	handleRevokeSessions(deps).ServeHTTP(rec, sessionRequest("DELETE", "/api/sessions", kid))
+	if !strings.Contains(rec.Body.String(), `"revoked":1`) {
+		t.Errorf("expected 1 revoked, got %s", rec.Body.String())
+	}
+	if _, err := store.Check(extra.ID, "", ""); err == nil {
+		t.Error("expected the other browser signed out")
+	}
+
+	// An admin signs out everyone else
+	admin := &principal{Name: "admin", Role: auth.RoleAdmin, Session: created[0].ID}
+	rec = httptest.NewRecorder()
+	handleRevokeSessions(deps).ServeHTTP(rec, sessionRequest("DELETE", "/api/sessions", admin))
+	if !strings.Contains(rec.Body.String(), `"revoked":2`) {
+		t.Errorf("expected 2 revoked, got %s", rec.Body.String())
+	}
+	if list, _ := store.List(); len(list) != 1 || list[0].ID != created[0].ID {
+		t.Errorf("expected only the admin's session kept, got %+v", list)
+	}
+}
+
+func TestHandleSessions_TokenNamedLikeUser(t *testing.T) {
+	deps, store, created := newSessionsDeps(t)
+	token := &principal{Name: "kid", Token: &apitoken.Token{Name: "kid", Scopes: []string{"read"}}}
+
+	if list := listSessions(t, deps, token); len(list) != 0 {
+		t.Errorf("expected a token to see no sessions, got %+v", list)
+	}
+
+	req := sessionRequest("DELETE", "/api/sessions/"+created[2].ID, token)
+	req.SetPathValue("id", created[2].ID)
+	rec := httptest.NewRecorder()
+	handleRevokeSession(deps).ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handleRevokeSessions(deps).ServeHTTP(rec, sessionRequest("DELETE", "/api/sessions", token))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403, got %d", rec.Code)
+	}
+
+	if list, _ := store.List(); len(list) != 3 {
+		t.Errorf("expected all sessions kept, got %d", len(list))
+	}
+}
+
+func TestHandleSessions_Unavailable(t *testing.T) {
+	deps := newTestDeps(t)
+	p := &principal{Name: "admin", Role: auth.RoleAdmin}
+
+	rec := httptest.NewRecorder()
+	handleListSessions(deps).ServeHTTP(rec, sessionRequest("GET", "/api/sessions", p))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503, got %d", rec.Code)
+	}
+}
+
+func TestHandleLogout_RevokesSession(t *testing.T) {
+	deps, store, created := newSessionsDeps(t)
+	p := &principal{Name: "admin", Role: auth.RoleAdmin, Session: created[0].ID}
+
+	rec := httptest.NewRecorder()
+	handleLogout(deps).ServeHTTP(rec, sessionRequest("POST", "/api/logout", p))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if _, err := store.Check(created[0].ID, "", ""); err == nil {
+		t.Error("expected the session revoked on logout")
+	}
+	if _, err := store.Check(created[1].ID, "192.168.50.10", "Firefox"); err != nil {
+		t.Errorf("expected other sessions kept, got %v", err)
+	}
+}
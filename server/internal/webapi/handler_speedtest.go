@@ -0,0 +1,98 @@
+package webapi
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/speedtest"
+)
+
+type speedTestRequest struct {
+	Route string `json:"route"`
+}
+
+// handleStartSpeedTest starts a background speed test on a route ("wan" by
+// default, "xray" or a tunnel) and returns the running job to poll.
+func handleStartSpeedTest(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if deps.SpeedTest == nil {
+			jsonError(w, http.StatusServiceUnavailable, "speed test is not available")
+			return
+		}
+
+		var req speedTestRequest
+		if err := decodeJSON(r, &req); err != nil {
+			jsonError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		if req.Route == "" {
+			req.Route = speedtest.RouteWAN
+		}
+		if req.Route != speedtest.RouteWAN && !validRoutes[req.Route] {
+			jsonError(w, http.StatusBadRequest, "invalid route: must be one of wan, xray, wgc1-wgc5, ovpnc1-ovpnc5")
+			return
+		}
+
+		auditChange(r, req.Route, nil, nil)
+		job, err := deps.SpeedTest.Start(req.Route, nil)
+		if errors.Is(err, speedtest.ErrRunning) {
+			jsonError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to start speed test")
+			return
+		}
+
+		jsonOK(w, job)
+	}
+}
+
+// handleGetSpeedTest returns a job started by this process.
+func handleGetSpeedTest(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if deps.SpeedTest == nil {
+			jsonError(w, http.StatusServiceUnavailable, "speed test is not available")
+			return
+		}
+
+		job, ok := deps.SpeedTest.Job(r.PathValue("id"))
+		if !ok {
+			jsonError(w, http.StatusNotFound, "speed test not found")
+			return
+		}
+		jsonOK(w, job)
+	}
+}
+
+// handleSpeedTestHistory returns past results, newest first.
+// Query params: limit (default 20, max 100).
+func handleSpeedTestHistory(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if deps.SpeedTest == nil {
+			jsonError(w, http.StatusServiceUnavailable, "speed test is not available")
+			return
+		}
+
+		limit := 20
+		if s := r.URL.Query().Get("limit"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 1 || n > 100 {
+				jsonError(w, http.StatusBadRequest, "limit must be between 1 and 100")
+				return
+			}
+			limit = n
+		}
+
+		results, err := deps.SpeedTest.History(limit)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to read speed test history")
+			return
+		}
+		if results == nil {
+			results = []speedtest.Result{}
+		}
+		jsonOK(w, results)
+	}
+}
@@ -0,0 +1,133 @@
+package webapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/speedtest"
+)
+
+func TestHandleStartSpeedTest(t *testing.T) {
+	deps := newTestDeps(t)
+	mock := &mockSpeedTest{job: speedtest.Job{ID: "abc123", Route: "xray", Status: speedtest.StatusRunning}}
+	deps.SpeedTest = mock
+
+	rec := httptest.NewRecorder()
+	handleStartSpeedTest(deps).ServeHTTP(rec, httptest.NewRequest("POST", "/api/speedtest", strings.NewReader(`{"route":"xray"}`)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if mock.route != "xray" {
+		t.Errorf("expected route xray, got %q", mock.route)
+	}
+	var job speedtest.Job
+	if err := json.NewDecoder(rec.Body).Decode(&job); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if job.ID != "abc123" || job.Status != speedtest.StatusRunning {
+		t.Errorf("unexpected job: %+v", job)
+	}
+}
+
+func TestHandleStartSpeedTest_DefaultRoute(t *testing.T) {
+	deps := newTestDeps(t)
+	mock := &mockSpeedTest{}
+	deps.SpeedTest = mock
+
+	rec := httptest.NewRecorder()
+	handleStartSpeedTest(deps).ServeHTTP(rec, httptest.NewRequest("POST", "/api/speedtest", strings.NewReader(`{}`)))
+
+	if rec.Code != http.StatusOK || mock.route != speedtest.RouteWAN {
+		t.Errorf("expected a WAN test, got %d and %q", rec.Code, mock.route)
+	}
+}
+
+func TestHandleStartSpeedTest_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		err  error
+		want int
+	}{
+		{"bad body", `{`, nil, http.StatusBadRequest},
+		{"bad route", `{"route":"eth0"}`, nil, http.StatusBadRequest},
+		{"running", `{"route":"wan"}`, speedtest.ErrRunning, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := newTestDeps(t)
+			deps.SpeedTest = &mockSpeedTest{err: tt.err}
+
+			rec := httptest.NewRecorder()
+			handleStartSpeedTest(deps).ServeHTTP(rec, httptest.NewRequest("POST", "/api/speedtest", strings.NewReader(tt.body)))
+			if rec.Code != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
+
+func TestHandleGetSpeedTest(t *testing.T) {
+	deps := newTestDeps(t)
+	deps.SpeedTest = &mockSpeedTest{job: speedtest.Job{ID: "abc123", Status: speedtest.StatusDone, Result: &speedtest.Result{DownloadMbps: 93.5}}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/speedtest/{id}", handleGetSpeedTest(deps))
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/speedtest/abc123", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"download_mbps":93.5`) {
+		t.Errorf("unexpected response %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/speedtest/nope", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestHandleSpeedTestHistory(t *testing.T) {
+	deps := newTestDeps(t)
+	mock := &mockSpeedTest{history: []speedtest.Result{{Route: "xray", DownloadMbps: 50}}}
+	deps.SpeedTest = mock
+
+	rec := httptest.NewRecorder()
+	handleSpeedTestHistory(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/speedtest?limit=5", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var results []speedtest.Result
+	if err := json.NewDecoder(rec.Body).Decode(&results); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if mock.limit != 5 || len(results) != 1 || results[0].Route != "xray" {
+		t.Errorf("unexpected history: %+v (limit %d)", results, mock.limit)
+	}
+
+	rec = httptest.NewRecorder()
+	handleSpeedTestHistory(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/speedtest?limit=0", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for bad limit, got %d", rec.Code)
+	}
+}
+
+func TestHandleSpeedTest_Unavailable(t *testing.T) {
+	deps := newTestDeps(t)
+	for name, h := range map[string]http.HandlerFunc{
+		"start":   handleStartSpeedTest(deps),
+		"get":     handleGetSpeedTest(deps),
+		"history": handleSpeedTestHistory(deps),
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/api/speedtest", strings.NewReader(`{}`)))
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s: expected 503, got %d", name, rec.Code)
+		}
+	}
+}
@@ -2,27 +2,56 @@ package webapi
 
 import (
 	"net/http"
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/service"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/xraystats"
 )
 
-// handleStatus returns a handler that reports the current VPN Director status.
+// statusResponse is the body of GET /api/status. Xray counters are omitted
+// when the stats API is not configured; if it is unreachable (e.g. Xray is
+// stopped) the error is reported instead.
+type statusResponse struct {
+	Output         string             `json:"output"`
+	XrayStats      *xraystats.Summary `json:"xray_stats,omitempty"`
+	XrayStatsError string             `json:"xray_stats_error,omitempty"`
+}
+
+// handleStatus returns a handler that reports the current VPN Director status
+// and Xray traffic per inbound and outbound.
 func handleStatus(deps *Deps) http.HandlerFunc {
-	return func(w http.ResponseWriter, _ *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
 		output, err := deps.VPN.Status()
 		if err != nil {
 			jsonError(w, http.StatusInternalServerError, "failed to get status")
 			return
 		}
-		jsonOK(w, map[string]string{"output": output})
+
+		resp := statusResponse{Output: output}
+		if deps.XrayStats != nil {
+			stats, err := deps.XrayStats.Summary(r.Context())
+			if err != nil {
+				resp.XrayStatsError = err.Error()
+			} else {
+				resp.XrayStats = stats
+			}
+		}
+		jsonOK(w, resp)
 	}
 }
 
 // handleApply returns a handler that applies the VPN Director configuration.
 func handleApply(deps *Deps) http.HandlerFunc {
-	return func(w http.ResponseWriter, _ *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
 		deps.OpMutex.Lock()
 		defer deps.OpMutex.Unlock()
 
-		if err := deps.VPN.Apply(); err != nil {
+		before := xrayState(deps)
+		start := time.Now()
+		err := deps.VPN.Apply()
+		deps.metrics.observeOp("apply", start, err)
+		auditChange(r, "", before, xrayState(deps))
+		if err != nil {
 			jsonError(w, http.StatusInternalServerError, "failed to apply configuration")
 			return
 		}
@@ -32,11 +61,16 @@ func handleApply(deps *Deps) http.HandlerFunc {
 
 // handleRestart returns a handler that restarts the VPN Director.
 func handleRestart(deps *Deps) http.HandlerFunc {
-	return func(w http.ResponseWriter, _ *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
 		deps.OpMutex.Lock()
 		defer deps.OpMutex.Unlock()
 
-		if err := deps.VPN.Restart(); err != nil {
+		before := xrayState(deps)
+		start := time.Now()
+		err := deps.VPN.Restart()
+		deps.metrics.observeOp("restart", start, err)
+		auditChange(r, "", before, xrayState(deps))
+		if err != nil {
 			jsonError(w, http.StatusInternalServerError, "failed to restart")
 			return
 		}
@@ -46,11 +80,16 @@ func handleRestart(deps *Deps) http.HandlerFunc {
 
 // handleStop returns a handler that stops the VPN Director.
 func handleStop(deps *Deps) http.HandlerFunc {
-	return func(w http.ResponseWriter, _ *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
 		deps.OpMutex.Lock()
 		defer deps.OpMutex.Unlock()
 
-		if err := deps.VPN.Stop(); err != nil {
+		before := xrayState(deps)
+		start := time.Now()
+		err := deps.VPN.Stop()
+		deps.metrics.observeOp("stop", start, err)
+		auditChange(r, "", before, xrayState(deps))
+		if err != nil {
 			jsonError(w, http.StatusInternalServerError, "failed to stop")
 			return
 		}
@@ -63,11 +102,16 @@ func handleStop(deps *Deps) http.HandlerFunc {
 // Once available, replace deps.VPN.Apply() with deps.VPN.Update()
 // to run `vpn-director.sh update` instead of `vpn-director.sh apply`.
 func handleUpdateIPsets(deps *Deps) http.HandlerFunc {
-	return func(w http.ResponseWriter, _ *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
 		deps.OpMutex.Lock()
 		defer deps.OpMutex.Unlock()
 
-		if err := deps.VPN.Apply(); err != nil {
+		before := ipsetState(deps)
+		start := time.Now()
+		err := deps.VPN.Apply()
+		deps.metrics.observeOp("ipsets_update", start, err)
+		auditChange(r, "", before, ipsetState(deps))
+		if err != nil {
 			jsonError(w, http.StatusInternalServerError, "failed to update ipsets")
 			return
 		}
@@ -75,15 +119,49 @@ func handleUpdateIPsets(deps *Deps) http.HandlerFunc {
 	}
 }
 
-// handleIP returns a handler that reports the router's external IP address.
+// xrayState is the state audited around apply, restart and stop: whether
+// Xray is running. It is nil when that is unknown.
+func xrayState(deps *Deps) map[string]bool {
+	if deps.System == nil {
+		return nil
+	}
+	running, err := deps.System.ProcessRunning("xray")
+	if err != nil {
+		return nil
+	}
+	return map[string]bool{"xray_running": running}
+}
+
+// ipsetState is the entry count of each ipset, audited around an ipset
+// update. It is nil when the counts are unavailable.
+func ipsetState(deps *Deps) map[string]int {
+	if deps.System == nil {
+		return nil
+	}
+	counts, err := deps.System.IPSetCounts()
+	if err != nil {
+		return nil
+	}
+	return counts
+}
+
+// handleIP returns a handler that reports the router's external IP address
+// with the country and network the provider returned.
+// Query params: via ("xray" or a tunnel name; default: WAN).
 func handleIP(deps *Deps) http.HandlerFunc {
-	return func(w http.ResponseWriter, _ *http.Request) {
-		ip, err := deps.Network.GetExternalIP()
+	return func(w http.ResponseWriter, r *http.Request) {
+		via := r.URL.Query().Get("via")
+		if !service.ValidVia(via) {
+			jsonError(w, http.StatusBadRequest, "via must be xray or a tunnel name")
+			return
+		}
+
+		info, err := deps.Network.GetExternalIP(via)
 		if err != nil {
 			jsonError(w, http.StatusInternalServerError, "failed to get external IP")
 			return
 		}
-		jsonOK(w, map[string]string{"ip": ip})
+		jsonOK(w, info)
 	}
 }
 
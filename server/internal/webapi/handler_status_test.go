@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/xraystats"
 )
 
 func TestHandleStatus_OK(t *testing.T) {
@@ -32,6 +34,51 @@ func TestHandleStatus_OK(t *testing.T) {
 	}
 }
 
+func TestHandleStatus_XrayStats(t *testing.T) {
+	deps := newTestDeps(t)
+	deps.XrayStats = &mockXrayStats{summary: &xraystats.Summary{
+		Inbounds:  []xraystats.Traffic{{Tag: "tproxy-in", Uplink: 100, Downlink: 2000}},
+		Outbounds: []xraystats.Traffic{{Tag: "proxy-out", Uplink: 110, Downlink: 2100}},
+		Users:     []xraystats.Traffic{},
+	}}
+
+	rec := httptest.NewRecorder()
+	handleStatus(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/status", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp statusResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.XrayStats == nil || len(resp.XrayStats.Outbounds) != 1 || resp.XrayStats.Outbounds[0].Downlink != 2100 {
+		t.Errorf("unexpected xray stats: %+v", resp.XrayStats)
+	}
+	if resp.XrayStatsError != "" {
+		t.Errorf("unexpected stats error: %q", resp.XrayStatsError)
+	}
+}
+
+func TestHandleStatus_XrayStatsUnavailable(t *testing.T) {
+	deps := newTestDeps(t)
+	deps.XrayStats = &mockXrayStats{err: errors.New("connection refused")}
+
+	rec := httptest.NewRecorder()
+	handleStatus(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/status", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("stats failure must not fail status, got %d", rec.Code)
+	}
+	var resp statusResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.XrayStats != nil || resp.XrayStatsError != "connection refused" {
+		t.Errorf("expected stats error only, got %+v", resp)
+	}
+}
+
 func TestHandleStatus_Error(t *testing.T) {
 	deps := newTestDeps(t)
 	deps.VPN = &mockVPN{err: errors.New("status failed")}
@@ -189,6 +236,40 @@ func TestHandleIP_OK(t *testing.T) {
 	}
 }
 
+func TestHandleIP_Via(t *testing.T) {
+	deps := newTestDeps(t)
+	network := &mockNetwork{ip: "185.10.10.10"}
+	deps.Network = network
+
+	rec := httptest.NewRecorder()
+	handleIP(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/ip?via=wgc1", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if network.via != "wgc1" {
+		t.Errorf("expected lookup via wgc1, got %q", network.via)
+	}
+	var resp map[string]string
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp["ip"] != "185.10.10.10" || resp["country"] != "NL" || resp["provider"] != "ipinfo" {
+		t.Errorf("unexpected response: %v", resp)
+	}
+}
+
+func TestHandleIP_InvalidVia(t *testing.T) {
+	deps := newTestDeps(t)
+
+	rec := httptest.NewRecorder()
+	handleIP(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/ip?via=wgc1%3Breboot", nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rec.Code)
+	}
+}
+
 func TestHandleIP_Error(t *testing.T) {
 	deps := newTestDeps(t)
 	deps.Network = &mockNetwork{err: errors.New("network error")}
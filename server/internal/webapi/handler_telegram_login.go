@@ -0,0 +1,245 @@
+package webapi
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/loginapproval"
+)
+
+const (
+	// telegramLoginTTL is how long a login waits for an answer in Telegram.
+	telegramLoginTTL = 2 * time.Minute
+	// maxTelegramLogins bounds pending requests, so the login page cannot
+	// be used to flood the bot users with messages.
+	maxTelegramLogins = 10
+	// statusPending is reported until the bot answers.
+	statusPending = "pending"
+)
+
+var errTelegramLoginPending = errors.New("a Telegram login is already pending")
+
+// telegramLoginResponse starts a Telegram login. The code is shown on the
+// login page and in the Telegram message so the user can match them.
+type telegramLoginResponse struct {
+	ID        string `json:"id"`
+	Code      string `json:"code"`
+	ExpiresIn int    `json:"expires_in"` // seconds
+}
+
+// telegramLoginStatus is returned while polling a Telegram login.
+type telegramLoginStatus struct {
+	Status string `json:"status"` // pending, approved, denied, expired or error
+	Error  string `json:"error,omitempty"`
+}
+
+// telegramLogin is a login waiting for approval in Telegram.
+type telegramLogin struct {
+	ip       string
+	expires  time.Time
+	decision loginapproval.Decision
+}
+
+// telegramLogins holds pending Telegram logins in memory. The ID is only
+// known to the browser that started the login, which must poll from the
+// same address.
+type telegramLogins struct {
+	mu      sync.Mutex
+	pending map[string]*telegramLogin
+	now     func() time.Time
+}
+
+func newTelegramLogins() *telegramLogins {
+	return &telegramLogins{pending: make(map[string]*telegramLogin), now: time.Now}
+}
+
+// add registers a login; one per address at a time.
+func (l *telegramLogins) add(id, ip string, expires time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	for key, tl := range l.pending {
+		// Keep answered logins a little past expiry for the last poll.
+		if now.After(tl.expires.Add(time.Minute)) {
+			delete(l.pending, key)
+		}
+	}
+	for _, tl := range l.pending {
+		if tl.ip == ip && tl.decision.Status == statusPending {
+			return errTelegramLoginPending
+		}
+	}
+	if len(l.pending) >= maxTelegramLogins {
+		return errTelegramLoginPending
+	}
+	l.pending[id] = &telegramLogin{ip: ip, expires: expires, decision: loginapproval.Decision{Status: statusPending}}
+	return nil
+}
+
+// resolve records the bot's answer.
+func (l *telegramLogins) resolve(id string, dec loginapproval.Decision) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if tl, ok := l.pending[id]; ok {
+		tl.decision = dec
+	}
+}
+
+// remove forgets a login.
+func (l *telegramLogins) remove(id string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.pending, id)
+}
+
+// poll returns the decision for a login started from ip. A final decision
+// is returned once, then the login is forgotten.
+func (l *telegramLogins) poll(id, ip string) (loginapproval.Decision, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	tl, ok := l.pending[id]
+	if !ok || tl.ip != ip {
+		return loginapproval.Decision{}, false
+	}
+	if tl.decision.Status != statusPending {
+		delete(l.pending, id)
+	}
+	return tl.decision, true
+}
+
+// telegramLoginEnabled reports whether webui.telegram_login is on and the
+// bot socket is configured.
+func telegramLoginEnabled(deps *Deps) (bool, error) {
+	if deps.Approvals == nil {
+		return false, nil
+	}
+	cfg, err := deps.Config.LoadVPNConfig()
+	if err != nil {
+		return false, err
+	}
+	return cfg != nil && cfg.WebUI.TelegramLogin, nil
+}
+
+// handleTelegramLoginEnabled tells the login page whether to offer
+// Telegram login.
+func handleTelegramLoginEnabled(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		enabled, err := telegramLoginEnabled(deps)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to load configuration")
+			return
+		}
+		jsonOK(w, map[string]bool{"enabled": enabled})
+	}
+}
+
+// handleStartTelegramLogin asks the bot users to approve a login from the
+// caller's address.
+func handleStartTelegramLogin(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		enabled, err := telegramLoginEnabled(deps)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to load configuration")
+			return
+		}
+		if !enabled {
+			jsonError(w, http.StatusNotFound, "Telegram login is disabled")
+			return
+		}
+
+		ip := remoteIP(r)
+		if block := loginBlock(deps, ip); block != "" {
+			deps.metrics.loginFailed(block)
+			jsonError(w, http.StatusTooManyRequests, "too many login attempts")
+			return
+		}
+
+		code, err := loginCode()
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to create login request")
+			return
+		}
+		req := loginapproval.Request{
+			ID:        rand.Text(),
+			Code:      code,
+			IP:        ip,
+			UserAgent: r.UserAgent(),
+			Expires:   time.Now().Add(telegramLoginTTL),
+		}
+		if err := deps.tgLogins.add(req.ID, ip, req.Expires); err != nil {
+			jsonError(w, http.StatusTooManyRequests, err.Error())
+			return
+		}
+
+		// Not tied to r: the answer arrives after this response is sent.
+		ctx, cancel := context.WithDeadline(context.Background(), req.Expires.Add(10*time.Second))
+		answer, err := deps.Approvals.Ask(ctx, req)
+		if err != nil {
+			cancel()
+			deps.tgLogins.remove(req.ID)
+			jsonError(w, http.StatusServiceUnavailable, loginapproval.ErrUnavailable.Error())
+			return
+		}
+		go func() {
+			defer cancel()
+			deps.tgLogins.resolve(req.ID, <-answer)
+		}()
+
+		jsonOK(w, telegramLoginResponse{ID: req.ID, Code: code, ExpiresIn: int(telegramLoginTTL.Seconds())})
+	}
+}
+
+// handleTelegramLoginStatus reports a login's state and, once approved,
+// signs the browser in as the approving Telegram user (see telegramSubject).
+func handleTelegramLoginStatus(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ip := remoteIP(r)
+		dec, ok := deps.tgLogins.poll(r.PathValue("id"), ip)
+		if !ok {
+			jsonError(w, http.StatusNotFound, "login request not found")
+			return
+		}
+
+		switch dec.Status {
+		case loginapproval.StatusApproved:
+			if dec.By == "" {
+				jsonOK(w, telegramLoginStatus{Status: loginapproval.StatusError, Error: "approver has no Telegram username"})
+				return
+			}
+			if err := startSession(w, r, deps, telegramSubject(dec.By)); err != nil {
+				jsonError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			recordLogin(deps, telegramSubject(dec.By), ip, true, "approved in Telegram")
+		case loginapproval.StatusDenied:
+			loginRejected(deps, ip, "login denied in Telegram by "+dec.By)
+			deps.metrics.loginFailed("telegram_denied")
+			recordLogin(deps, telegramSubject(dec.By), ip, false, "denied in Telegram")
+		}
+		jsonOK(w, telegramLoginStatus{Status: dec.Status, Error: dec.Error})
+	}
+}
+
+// telegramSubject returns the session user for a Telegram username. The
+// prefix keeps Telegram users apart from local and router accounts, whose
+// names cannot contain ':', so @admin never signs in as the local admin.
+func telegramSubject(username string) string {
+	return "tg:" + username
+}
+
+// loginCode returns a random 6-digit code.
+func loginCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
@@ -0,0 +1,194 @@
+package webapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/auth"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/loginapproval"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+)
+
+// mockApprovals implements loginapproval.Asker for testing.
+type mockApprovals struct {
+	answer chan loginapproval.Decision
+	err    error
+	req    loginapproval.Request
+}
+
+func (m *mockApprovals) Ask(_ context.Context, req loginapproval.Request) (<-chan loginapproval.Decision, error) {
+	m.req = req
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.answer, nil
+}
+
+func newTelegramLoginDeps(t *testing.T, enabled bool) (*Deps, *mockApprovals) {
+	t.Helper()
+	deps := newTestDeps(t)
+	deps.Config = &mockConfig{cfg: &vpnconfig.VPNDirectorConfig{WebUI: vpnconfig.WebUIConfig{
+		TelegramLogin: enabled,
+		Roles:         map[string]string{"tg:alice": auth.RoleViewer, "alice": auth.RoleOperator},
+	}}}
+	approvals := &mockApprovals{answer: make(chan loginapproval.Decision, 1)}
+	deps.Approvals = approvals
+	return deps, approvals
+}
+
+func startTelegramLogin(deps *Deps) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/login/telegram", nil)
+	req.Header.Set("User-Agent", "Firefox")
+	handleStartTelegramLogin(deps).ServeHTTP(rec, req)
+	return rec
+}
+
+func pollTelegramLogin(deps *Deps, id, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/api/login/telegram/"+id, nil)
+	req.SetPathValue("id", id)
+	req.RemoteAddr = remoteAddr
+	rec := httptest.NewRecorder()
+	handleTelegramLoginStatus(deps).ServeHTTP(rec, req)
+	return rec
+}
+
+// waitTelegramLogin polls until the login leaves the pending state.
+func waitTelegramLogin(t *testing.T, deps *Deps, id string) (*httptest.ResponseRecorder, telegramLoginStatus) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		rec := pollTelegramLogin(deps, id, "192.0.2.1:1234")
+		var st telegramLoginStatus
+		if err := json.NewDecoder(rec.Body).Decode(&st); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if st.Status != statusPending || time.Now().After(deadline) {
+			return rec, st
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestHandleTelegramLoginEnabled(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		deps, _ := newTelegramLoginDeps(t, enabled)
+		rec := httptest.NewRecorder()
+		handleTelegramLoginEnabled(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/login/telegram", nil))
+
+		var resp map[string]bool
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if resp["enabled"] != enabled {
+			t.Errorf("expected enabled=%v, got %v", enabled, resp)
+		}
+	}
+
+	deps := newTestDeps(t) // no bot socket
+	rec := httptest.NewRecorder()
+	handleTelegramLoginEnabled(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/login/telegram", nil))
+	if !strings.Contains(rec.Body.String(), `"enabled":false`) {
+		t.Errorf("expected disabled without Approvals, got %s", rec.Body.String())
+	}
+}
+
+func TestHandleTelegramLogin_Approved(t *testing.T) {
+	deps, approvals := newTelegramLoginDeps(t, true)
+	ev := &mockEvents{}
+	deps.Events = ev
+
+	rec := startTelegramLogin(deps)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var start telegramLoginResponse
+	if err := json.NewDecoder(rec.Body).Decode(&start); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(start.Code) != 6 || start.Code != approvals.req.Code || start.ID != approvals.req.ID {
+		t.Errorf("expected the code and ID sent to the bot, got %+v vs %+v", start, approvals.req)
+	}
+	if approvals.req.IP != "192.0.2.1" || approvals.req.UserAgent != "Firefox" {
+		t.Errorf("unexpected request: %+v", approvals.req)
+	}
+
+	if rec := pollTelegramLogin(deps, start.ID, "192.0.2.1:1234"); !strings.Contains(rec.Body.String(), statusPending) || hasTokenCookie(rec) {
+		t.Errorf("expected pending without cookie, got %s", rec.Body.String())
+	}
+	if rec := pollTelegramLogin(deps, start.ID, "198.51.100.7:1234"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 from another address, got %d", rec.Code)
+	}
+
+	approvals.answer <- loginapproval.Decision{Status: loginapproval.StatusApproved, By: "alice"}
+	rec, st := waitTelegramLogin(t, deps, start.ID)
+	if st.Status != loginapproval.StatusApproved || !hasTokenCookie(rec) {
+		t.Fatalf("expected approved with cookie, got %+v", st)
+	}
+	for _, c := range rec.Result().Cookies() {
+		if claims, err := deps.JWT.Validate(c.Value); err != nil || claims.Subject != "tg:alice" || claims.Role != auth.RoleViewer {
+			t.Errorf("expected a session for tg:alice as viewer, got %+v, %v", claims, err)
+		}
+	}
+	if len(ev.recorded) != 1 || !ev.recorded[0].OK || !strings.Contains(ev.recorded[0].Message, "approved in Telegram") {
+		t.Errorf("expected a login event, got %+v", ev.recorded)
+	}
+
+	// The approval is used once
+	if rec := pollTelegramLogin(deps, start.ID, "192.0.2.1:1234"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 after sign-in, got %d", rec.Code)
+	}
+}
+
+func TestHandleTelegramLogin_Denied(t *testing.T) {
+	deps, approvals := newTelegramLoginDeps(t, true)
+	ev := &mockEvents{}
+	deps.Events = ev
+
+	var start telegramLoginResponse
+	json.NewDecoder(startTelegramLogin(deps).Body).Decode(&start)
+
+	approvals.answer <- loginapproval.Decision{Status: loginapproval.StatusDenied, By: "bob"}
+	rec, st := waitTelegramLogin(t, deps, start.ID)
+	if st.Status != loginapproval.StatusDenied || hasTokenCookie(rec) {
+		t.Fatalf("expected denied without cookie, got %+v", st)
+	}
+	if len(ev.recorded) != 1 || ev.recorded[0].OK {
+		t.Errorf("expected a failed login event, got %+v", ev.recorded)
+	}
+}
+
+func TestHandleTelegramLogin_OnePendingPerAddress(t *testing.T) {
+	deps, _ := newTelegramLoginDeps(t, true)
+
+	if rec := startTelegramLogin(deps); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if rec := startTelegramLogin(deps); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected 429 while a login is pending, got %d", rec.Code)
+	}
+}
+
+func TestHandleTelegramLogin_Unavailable(t *testing.T) {
+	deps, approvals := newTelegramLoginDeps(t, true)
+	approvals.err = loginapproval.ErrUnavailable
+
+	for range 2 {
+		if rec := startTelegramLogin(deps); rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected 503 without the bot, got %d", rec.Code)
+		}
+	}
+}
+
+func TestHandleTelegramLogin_Disabled(t *testing.T) {
+	deps, _ := newTelegramLoginDeps(t, false)
+
+	if rec := startTelegramLogin(deps); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 when disabled, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,112 @@
+package webapi
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/apitoken"
+)
+
+// createTokenRequest is the expected JSON body for POST /api/tokens.
+type createTokenRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// createTokenResponse carries the new token. Token is only returned here.
+type createTokenResponse struct {
+	Token string         `json:"token"`
+	Info  apitoken.Token `json:"info"`
+}
+
+// handleListTokens returns all API tokens without their secrets.
+func handleListTokens(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		if deps.Tokens == nil {
+			jsonError(w, http.StatusServiceUnavailable, "API tokens are not available")
+			return
+		}
+
+		tokens, err := deps.Tokens.List()
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to load API tokens")
+			return
+		}
+		if tokens == nil {
+			tokens = []apitoken.Token{}
+		}
+		jsonOK(w, tokens)
+	}
+}
+
+// handleCreateToken creates a named token with the given scopes.
+func handleCreateToken(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if deps.Tokens == nil {
+			jsonError(w, http.StatusServiceUnavailable, "API tokens are not available")
+			return
+		}
+
+		var req createTokenRequest
+		if err := decodeJSON(r, &req); err != nil {
+			jsonError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		req.Name = strings.TrimSpace(req.Name)
+		if err := apitoken.ValidateName(req.Name); err != nil {
+			jsonError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if _, err := apitoken.NormalizeScopes(req.Scopes); err != nil {
+			jsonError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		secret, info, err := deps.Tokens.Create(req.Name, req.Scopes)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		auditChange(r, req.Name, nil, info.Scopes)
+		jsonOK(w, createTokenResponse{Token: secret, Info: info})
+	}
+}
+
+// handleRevokeToken deletes a token by ID.
+func handleRevokeToken(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if deps.Tokens == nil {
+			jsonError(w, http.StatusServiceUnavailable, "API tokens are not available")
+			return
+		}
+
+		id := r.PathValue("id")
+		auditChange(r, "", auditToken(deps, id), nil)
+		err := deps.Tokens.Revoke(id)
+		if errors.Is(err, apitoken.ErrNotFound) {
+			jsonError(w, http.StatusNotFound, "token not found")
+			return
+		}
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to revoke token")
+			return
+		}
+		jsonOK(w, map[string]bool{"ok": true})
+	}
+}
+
+// auditToken describes the token with the given ID for the audit log, or
+// returns nil when there is none.
+func auditToken(deps *Deps, id string) map[string]any {
+	tokens, err := deps.Tokens.List()
+	if err != nil {
+		return nil
+	}
+	for _, t := range tokens {
+		if t.ID == id {
+			return map[string]any{"name": t.Name, "scopes": t.Scopes}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package webapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/apitoken"
+)
+
+func newTestTokens(t *testing.T) *apitoken.Store {
+	t.Helper()
+	return apitoken.NewStore(filepath.Join(t.TempDir(), apitoken.StoreFile))
+}
+
+func TestHandleCreateToken(t *testing.T) {
+	deps := newTestDeps(t)
+	tokens := newTestTokens(t)
+	deps.Tokens = tokens
+
+	rec := httptest.NewRecorder()
+	body := `{"name":"Home Assistant","scopes":["read","clients"]}`
+	handleCreateToken(deps).ServeHTTP(rec, httptest.NewRequest("POST", "/api/tokens", strings.NewReader(body)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp createTokenResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if !strings.HasPrefix(resp.Token, apitoken.Prefix) || resp.Info.Name != "Home Assistant" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if _, err := tokens.Authenticate(resp.Token); err != nil {
+		t.Errorf("expected the returned token to authenticate: %v", err)
+	}
+}
+
+func TestHandleCreateToken_BadRequest(t *testing.T) {
+	for _, body := range []string{
+		`not json`,
+		`{"name":"","scopes":["read"]}`,
+		`{"name":"ha","scopes":[]}`,
+		`{"name":"ha","scopes":["root"]}`,
+	} {
+		deps := newTestDeps(t)
+		deps.Tokens = newTestTokens(t)
+
+		rec := httptest.NewRecorder()
+		handleCreateToken(deps).ServeHTTP(rec, httptest.NewRequest("POST", "/api/tokens", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, rec.Code)
+		}
+	}
+}
+
+func TestHandleListTokens(t *testing.T) {
+	deps := newTestDeps(t)
+	tokens := newTestTokens(t)
+	deps.Tokens = tokens
+
+	rec := httptest.NewRecorder()
+	handleListTokens(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/tokens", nil))
+	if strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Errorf("expected empty list, got %s", rec.Body.String())
+	}
+
+	if _, _, err := tokens.Create("script", []string{apitoken.ScopeRead}); err != nil {
+		t.Fatal(err)
+	}
+	rec = httptest.NewRecorder()
+	handleListTokens(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/tokens", nil))
+
+	var list []apitoken.Token
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(list) != 1 || list[0].Name != "script" || list[0].Hash != "" {
+		t.Errorf("unexpected list: %+v", list)
+	}
+}
+
+func TestHandleRevokeToken(t *testing.T) {
+	deps := newTestDeps(t)
+	tokens := newTestTokens(t)
+	deps.Tokens = tokens
+	_, info, _ := tokens.Create("script", []string{apitoken.ScopeRead})
+
+	for _, tt := range []struct {
+		id   string
+		want int
+	}{{info.ID, http.StatusOK}, {info.ID, http.StatusNotFound}} {
+		req := httptest.NewRequest("DELETE", "/api/tokens/"+tt.id, nil)
+		req.SetPathValue("id", tt.id)
+		rec := httptest.NewRecorder()
+		handleRevokeToken(deps).ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("expected %d, got %d", tt.want, rec.Code)
+		}
+	}
+}
+
+func TestHandleTokens_Unavailable(t *testing.T) {
+	deps := newTestDeps(t)
+	for _, h := range []http.HandlerFunc{handleListTokens(deps), handleCreateToken(deps), handleRevokeToken(deps)} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/api/tokens", nil))
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected 503, got %d", rec.Code)
+		}
+	}
+}
+
+func TestRouter_APITokenScopes(t *testing.T) {
+	deps := newTestDeps(t)
+	tokens := newTestTokens(t)
+	deps.Tokens = tokens
+	router := NewRouter(deps, nil)
+
+	readOnly, _, _ := tokens.Create("ro", []string{apitoken.ScopeRead})
+	clients, _, _ := tokens.Create("clients", []string{apitoken.ScopeClients})
+
+	tests := []struct {
+		name   string
+		token  string
+		method string
+		path   string
+		want   int
+	}{
+		{"read allowed", readOnly, "GET", "/api/status", http.StatusOK},
+		{"read cannot apply", readOnly, "POST", "/api/apply", http.StatusForbidden},
+		{"read cannot pause", readOnly, "POST", "/api/clients/pause?ip=192.168.1.10", http.StatusForbidden},
+		{"clients can read", clients, "GET", "/api/status", http.StatusOK},
+		{"clients cannot switch server", clients, "POST", "/api/servers/active", http.StatusForbidden},
+		{"clients cannot list tokens", clients, "GET", "/api/tokens", http.StatusForbidden},
+		{"unknown token", apitoken.Prefix + "nope", "GET", "/api/status", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("expected %d, got %d: %s", tt.want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package webapi
+
+import (
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/totp"
+)
+
+const (
+	// challengeTTL is how long the second login step stays open.
+	challengeTTL = 5 * time.Minute
+	// challengeAttempts is how many wrong codes end a challenge.
+	challengeAttempts = 5
+)
+
+// totpChallengeResponse is returned by POST /api/login when the user has
+// 2FA on. No cookie is set until the challenge is answered.
+type totpChallengeResponse struct {
+	TOTPRequired bool   `json:"totp_required"`
+	Challenge    string `json:"challenge"`
+}
+
+// loginTOTPRequest is the expected JSON body for POST /api/login/totp.
+type loginTOTPRequest struct {
+	Challenge string `json:"challenge"`
+	Code      string `json:"code"`
+}
+
+// totpCodeRequest is the expected JSON body for confirming or disabling 2FA.
+type totpCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// totpEnrollResponse carries a new secret as text, otpauth URI and QR code.
+type totpEnrollResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+	QR     string `json:"qr"` // PNG data URI
+}
+
+// challenge is a login that passed the password step.
+type challenge struct {
+	username string
+	expires  time.Time
+	failures int
+}
+
+// loginChallenges holds pending second login steps in memory. They are
+// short-lived, so losing them on restart only means signing in again.
+type loginChallenges struct {
+	mu      sync.Mutex
+	pending map[string]*challenge
+	now     func() time.Time
+}
+
+func newLoginChallenges() *loginChallenges {
+	return &loginChallenges{pending: make(map[string]*challenge), now: time.Now}
+}
+
+// create opens a challenge for username and returns its ID.
+func (c *loginChallenges) create(username string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for id, ch := range c.pending {
+		if now.After(ch.expires) {
+			delete(c.pending, id)
+		}
+	}
+	id := rand.Text()
+	c.pending[id] = &challenge{username: username, expires: now.Add(challengeTTL)}
+	return id
+}
+
+// user returns the username of an open challenge.
+func (c *loginChallenges) user(id string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch, ok := c.pending[id]
+	if !ok || c.now().After(ch.expires) {
+		delete(c.pending, id)
+		return "", false
+	}
+	return ch.username, true
+}
+
+// fail counts a wrong code and closes the challenge after too many.
+func (c *loginChallenges) fail(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if ch, ok := c.pending[id]; ok {
+		ch.failures++
+		if ch.failures >= challengeAttempts {
+			delete(c.pending, id)
+		}
+	}
+}
+
+// done closes a challenge.
+func (c *loginChallenges) done(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pending, id)
+}
+
+// handleLoginTOTP finishes a login with a TOTP or recovery code and sets
+// the session cookie.
+func handleLoginTOTP(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if deps.TOTP == nil {
+			jsonError(w, http.StatusServiceUnavailable, "two-factor authentication is not available")
+			return
+		}
+
+		var req loginTOTPRequest
+		if err := decodeJSON(r, &req); err != nil {
+			jsonError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+
+		ip := remoteIP(r)
+		username, ok := deps.challenges.user(req.Challenge)
+		if !ok {
+			jsonError(w, http.StatusUnauthorized, "login expired, sign in again")
+			return
+		}
+
+		if block := loginBlock(deps, ip); block != "" {
+			deps.metrics.loginFailed(block)
+			recordLogin(deps, username, ip, false, strings.ReplaceAll(block, "_", " "))
+			jsonError(w, http.StatusTooManyRequests, "too many login attempts")
+			return
+		}
+
+		recovery, err := deps.TOTP.Verify(username, req.Code)
+		if errors.Is(err, totp.ErrInvalidCode) || errors.Is(err, totp.ErrNotEnrolled) {
+			deps.challenges.fail(req.Challenge)
+			loginRejected(deps, ip, "bad TOTP code for "+username)
+			deps.metrics.loginFailed("bad_totp")
+			recordLogin(deps, username, ip, false, "bad TOTP code")
+			jsonError(w, http.StatusUnauthorized, "invalid code")
+			return
+		}
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "authentication error")
+			return
+		}
+		deps.challenges.done(req.Challenge)
+
+		if err := startSession(w, r, deps, username); err != nil {
+			jsonError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		reason := ""
+		if recovery {
+			reason = "recovery code used"
+		}
+		recordLogin(deps, username, ip, true, reason)
+
+		jsonOK(w, map[string]bool{"ok": true})
+	}
+}
+
+// totpUser returns the session user whose 2FA is being managed. API tokens
+// are rejected: they do not log in, so they have no second factor.
+func totpUser(w http.ResponseWriter, r *http.Request, deps *Deps) (string, bool) {
+	if deps.TOTP == nil {
+		jsonError(w, http.StatusServiceUnavailable, "two-factor authentication is not available")
+		return "", false
+	}
+	p := principalFrom(r.Context())
+	if p == nil || p.Token != nil {
+		jsonError(w, http.StatusForbidden, "two-factor settings require a web session")
+		return "", false
+	}
+	return p.Name, true
+}
+
+// handleTOTPStatus reports whether 2FA is on for the current user.
+func handleTOTPStatus(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := totpUser(w, r, deps)
+		if !ok {
+			return
+		}
+
+		st, err := deps.TOTP.Status(user)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to load two-factor settings")
+			return
+		}
+		jsonOK(w, st)
+	}
+}
+
+// handleTOTPEnroll starts enrollment and returns the secret with its QR
+// code. 2FA stays off until the first code is confirmed.
+func handleTOTPEnroll(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := totpUser(w, r, deps)
+		if !ok {
+			return
+		}
+
+		secret, err := deps.TOTP.Begin(user)
+		if errors.Is(err, totp.ErrAlreadyEnabled) {
+			jsonError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to start enrollment")
+			return
+		}
+
+		uri := totp.URI(user, secret)
+		qr, err := totp.QRDataURI(uri)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to render QR code")
+			return
+		}
+		jsonOK(w, totpEnrollResponse{Secret: secret, URI: uri, QR: qr})
+	}
+}
+
+// handleTOTPConfirm turns 2FA on with a code from the authenticator and
+// returns the recovery codes. They are only shown here.
+func handleTOTPConfirm(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := totpUser(w, r, deps)
+		if !ok {
+			return
+		}
+
+		var req totpCodeRequest
+		if err := decodeJSON(r, &req); err != nil {
+			jsonError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+
+		before := totpState(deps, user)
+		codes, err := deps.TOTP.Confirm(user, req.Code)
+		auditChange(r, user, before, totpState(deps, user))
+		if err != nil {
+			totpError(w, err, "failed to enable two-factor authentication")
+			return
+		}
+		jsonOK(w, map[string][]string{"recovery_codes": codes})
+	}
+}
+
+// handleTOTPDisable turns 2FA off after checking a TOTP or recovery code.
+func handleTOTPDisable(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := totpUser(w, r, deps)
+		if !ok {
+			return
+		}
+
+		var req totpCodeRequest
+		if err := decodeJSON(r, &req); err != nil {
+			jsonError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+
+		before := totpState(deps, user)
+		err := deps.TOTP.Disable(user, req.Code)
+		auditChange(r, user, before, totpState(deps, user))
+		if err != nil {
+			totpError(w, err, "failed to disable two-factor authentication")
+			return
+		}
+		jsonOK(w, map[string]bool{"ok": true})
+	}
+}
+
+// totpState reports whether 2FA is on for user, for the audit log. It is
+// nil when the settings cannot be read.
+func totpState(deps *Deps, user string) map[string]bool {
+	st, err := deps.TOTP.Status(user)
+	if err != nil {
+		return nil
+	}
+	return map[string]bool{"enabled": st.Enabled}
+}
+
+// totpError maps enrollment errors to status codes.
+func totpError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case errors.Is(err, totp.ErrInvalidCode):
+		jsonError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, totp.ErrNotEnrolled), errors.Is(err, totp.ErrAlreadyEnabled):
+		jsonError(w, http.StatusConflict, err.Error())
+	default:
+		jsonError(w, http.StatusInternalServerError, msg)
+	}
+}
@@ -0,0 +1,287 @@
+package webapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/apitoken"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/auth"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/totp"
+)
+
+func newTestTOTP(t *testing.T) *totp.Store {
+	t.Helper()
+	return totp.NewStore(filepath.Join(t.TempDir(), totp.StoreFile), "test-key")
+}
+
+// enrollTOTP turns 2FA on for user and returns the secret and recovery codes.
+func enrollTOTP(t *testing.T, store *totp.Store, user string) (string, []string) {
+	t.Helper()
+	secret, err := store.Begin(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := totp.Code(secret, time.Now())
+	codes, err := store.Confirm(user, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return secret, codes
+}
+
+// nextCode returns a code the store has not seen yet (one period ahead,
+// within the accepted clock skew).
+func nextCode(t *testing.T, secret string) string {
+	t.Helper()
+	code, err := totp.Code(secret, time.Now().Add(30*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+// newTOTPLoginDeps returns deps with user "admin" (password "testpass")
+// enrolled in 2FA.
+func newTOTPLoginDeps(t *testing.T) (*Deps, string, []string) {
+	t.Helper()
+	deps := newTestDeps(t)
+	deps.Shadow = auth.NewShadowAuth(writeShadowFixture(t, "admin:"+testSHA256Hash+":19000:0:99999:7:::\n"))
+	store := newTestTOTP(t)
+	deps.TOTP = store
+	secret, codes := enrollTOTP(t, store, "admin")
+	return deps, secret, codes
+}
+
+// loginChallenge runs the password step and returns the challenge ID.
+func loginChallenge(t *testing.T, deps *Deps) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/login", strings.NewReader(`{"username":"admin","password":"testpass"}`))
+	handleLogin(deps).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(rec.Result().Cookies()) != 0 {
+		t.Fatal("expected no cookie before the second step")
+	}
+	var resp totpChallengeResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if !resp.TOTPRequired || resp.Challenge == "" {
+		t.Fatalf("expected a TOTP challenge, got %+v", resp)
+	}
+	return resp.Challenge
+}
+
+func postLoginTOTP(deps *Deps, challenge, code string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(loginTOTPRequest{Challenge: challenge, Code: code})
+	rec := httptest.NewRecorder()
+	handleLoginTOTP(deps).ServeHTTP(rec, httptest.NewRequest("POST", "/api/login/totp", strings.NewReader(string(body))))
+	return rec
+}
+
+func hasTokenCookie(rec *httptest.ResponseRecorder) bool {
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == "token" && c.Value != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func TestHandleLoginTOTP(t *testing.T) {
+	deps, secret, _ := newTOTPLoginDeps(t)
+	ev := &mockEvents{}
+	deps.Events = ev
+	challenge := loginChallenge(t, deps)
+
+	rec := postLoginTOTP(deps, challenge, "000000")
+	if rec.Code != http.StatusUnauthorized || hasTokenCookie(rec) {
+		t.Fatalf("expected 401 without cookie, got %d", rec.Code)
+	}
+	if len(ev.recorded) != 1 || ev.recorded[0].OK || !strings.Contains(ev.recorded[0].Message, "bad TOTP code") {
+		t.Errorf("expected a failed login event, got %+v", ev.recorded)
+	}
+
+	rec = postLoginTOTP(deps, challenge, nextCode(t, secret))
+	if rec.Code != http.StatusOK || !hasTokenCookie(rec) {
+		t.Fatalf("expected 200 with cookie, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// The challenge is used up
+	if rec := postLoginTOTP(deps, challenge, nextCode(t, secret)); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for a used challenge, got %d", rec.Code)
+	}
+}
+
+func TestHandleLoginTOTP_RecoveryCode(t *testing.T) {
+	deps, _, codes := newTOTPLoginDeps(t)
+	ev := &mockEvents{}
+	deps.Events = ev
+
+	rec := postLoginTOTP(deps, loginChallenge(t, deps), codes[0])
+	if rec.Code != http.StatusOK || !hasTokenCookie(rec) {
+		t.Fatalf("expected 200 with cookie, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(ev.recorded) != 1 || !strings.Contains(ev.recorded[0].Message, "recovery code used") {
+		t.Errorf("expected recovery code use in the event, got %+v", ev.recorded)
+	}
+
+	if rec := postLoginTOTP(deps, loginChallenge(t, deps), codes[0]); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for a used recovery code, got %d", rec.Code)
+	}
+}
+
+func TestHandleLoginTOTP_AttemptLimit(t *testing.T) {
+	deps, secret, _ := newTOTPLoginDeps(t)
+	deps.loginLimiter = newRateLimiter(100, time.Minute, 30*time.Second)
+	challenge := loginChallenge(t, deps)
+
+	for range challengeAttempts {
+		postLoginTOTP(deps, challenge, "000000")
+	}
+	if rec := postLoginTOTP(deps, challenge, nextCode(t, secret)); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected the challenge closed after %d failures, got %d", challengeAttempts, rec.Code)
+	}
+}
+
+func TestHandleLogin_WithoutTOTP(t *testing.T) {
+	deps := newTestDeps(t)
+	deps.Shadow = auth.NewShadowAuth(writeShadowFixture(t, "admin:"+testSHA256Hash+":19000:0:99999:7:::\n"))
+	deps.TOTP = newTestTOTP(t)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/login", strings.NewReader(`{"username":"admin","password":"testpass"}`))
+	handleLogin(deps).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || !hasTokenCookie(rec) {
+		t.Errorf("expected a session without enrollment, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestLoginChallenges_Expire(t *testing.T) {
+	c := newLoginChallenges()
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	id := c.create("admin")
+	if user, ok := c.user(id); !ok || user != "admin" {
+		t.Fatalf("expected open challenge, got %q, %v", user, ok)
+	}
+	now = now.Add(challengeTTL + time.Second)
+	if _, ok := c.user(id); ok {
+		t.Error("expected the challenge to expire")
+	}
+}
+
+// totpRequest builds a request made by a session user.
+func totpRequest(method, path, body string) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	return req.WithContext(withPrincipal(req.Context(), &principal{Name: "admin", Role: auth.RoleViewer}))
+}
+
+func TestHandleTOTP_Enrollment(t *testing.T) {
+	deps := newTestDeps(t)
+	store := newTestTOTP(t)
+	deps.TOTP = store
+
+	rec := httptest.NewRecorder()
+	handleTOTPEnroll(deps).ServeHTTP(rec, totpRequest("POST", "/api/totp/enroll", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var enroll totpEnrollResponse
+	if err := json.NewDecoder(rec.Body).Decode(&enroll); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if !strings.HasPrefix(enroll.URI, "otpauth://totp/") || !strings.HasPrefix(enroll.QR, "data:image/png;base64,") {
+		t.Errorf("unexpected enrollment: %+v", enroll)
+	}
+
+	rec = httptest.NewRecorder()
+	handleTOTPConfirm(deps).ServeHTTP(rec, totpRequest("POST", "/api/totp/confirm", `{"code":"000000"}`))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a wrong code, got %d", rec.Code)
+	}
+
+	code, _ := totp.Code(enroll.Secret, time.Now())
+	rec = httptest.NewRecorder()
+	handleTOTPConfirm(deps).ServeHTTP(rec, totpRequest("POST", "/api/totp/confirm", `{"code":"`+code+`"}`))
+	var confirm map[string][]string
+	if err := json.NewDecoder(rec.Body).Decode(&confirm); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if rec.Code != http.StatusOK || len(confirm["recovery_codes"]) == 0 {
+		t.Fatalf("expected recovery codes, got %d: %v", rec.Code, confirm)
+	}
+
+	rec = httptest.NewRecorder()
+	handleTOTPStatus(deps).ServeHTTP(rec, totpRequest("GET", "/api/totp", ""))
+	var st totp.Status
+	if err := json.NewDecoder(rec.Body).Decode(&st); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if !st.Enabled || st.RecoveryCodesLeft != len(confirm["recovery_codes"]) {
+		t.Errorf("unexpected status: %+v", st)
+	}
+
+	rec = httptest.NewRecorder()
+	handleTOTPEnroll(deps).ServeHTTP(rec, totpRequest("POST", "/api/totp/enroll", ""))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 when already enabled, got %d", rec.Code)
+	}
+}
+
+func TestHandleTOTP_Disable(t *testing.T) {
+	deps := newTestDeps(t)
+	store := newTestTOTP(t)
+	deps.TOTP = store
+	_, codes := enrollTOTP(t, store, "admin")
+
+	rec := httptest.NewRecorder()
+	handleTOTPDisable(deps).ServeHTTP(rec, totpRequest("POST", "/api/totp/disable", `{"code":"wrong"}`))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a wrong code, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handleTOTPDisable(deps).ServeHTTP(rec, totpRequest("POST", "/api/totp/disable", `{"code":"`+codes[0]+`"}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if st, _ := store.Status("admin"); st.Enabled {
+		t.Error("expected 2FA off")
+	}
+
+	rec = httptest.NewRecorder()
+	handleTOTPDisable(deps).ServeHTTP(rec, totpRequest("POST", "/api/totp/disable", `{"code":"`+codes[1]+`"}`))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 when not enrolled, got %d", rec.Code)
+	}
+}
+
+func TestHandleTOTP_RequiresSession(t *testing.T) {
+	deps := newTestDeps(t)
+
+	rec := httptest.NewRecorder()
+	handleTOTPStatus(deps).ServeHTTP(rec, totpRequest("GET", "/api/totp", ""))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 without TOTP, got %d", rec.Code)
+	}
+
+	deps.TOTP = newTestTOTP(t)
+	req := httptest.NewRequest("GET", "/api/totp", nil)
+	req = req.WithContext(withPrincipal(req.Context(), &principal{Name: "ha", Token: &apitoken.Token{Scopes: []string{apitoken.ScopeAdmin}}}))
+	rec = httptest.NewRecorder()
+	handleTOTPStatus(deps).ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for an API token, got %d", rec.Code)
+	}
+}
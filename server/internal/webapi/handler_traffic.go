@@ -0,0 +1,24 @@
+package webapi
+
+import (
+	"net/http"
+)
+
+// handleTraffic returns per-client and per-route traffic totals for the last
+// hour, day and month, split into upload and download.
+func handleTraffic(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		if deps.Traffic == nil {
+			jsonError(w, http.StatusServiceUnavailable, "traffic accounting is not available")
+			return
+		}
+
+		report, err := deps.Traffic.Report()
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to read traffic stats")
+			return
+		}
+
+		jsonOK(w, report)
+	}
+}
@@ -0,0 +1,80 @@
+package webapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/traffic"
+)
+
+func TestHandleTraffic(t *testing.T) {
+	deps := newTestDeps(t)
+	day := traffic.Traffic{
+		Upload:   traffic.Counter{Packets: 4, Bytes: 2048},
+		Download: traffic.Counter{Packets: 8, Bytes: 8192},
+	}
+	deps.Traffic = &mockTraffic{report: &traffic.Report{
+		UpdatedAt: time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC),
+		Day: traffic.Window{
+			Clients: []traffic.Usage{{Client: "192.168.1.10", Route: "xray", Traffic: day}},
+			Routes:  []traffic.Usage{{Route: "xray", Traffic: day}},
+			Traffic: day,
+		},
+	}}
+
+	req := httptest.NewRequest("GET", "/api/traffic", nil)
+	rec := httptest.NewRecorder()
+	handleTraffic(deps).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Day struct {
+			Clients []struct {
+				Client   string          `json:"client"`
+				Upload   traffic.Counter `json:"upload"`
+				Download traffic.Counter `json:"download"`
+			} `json:"clients"`
+			Download traffic.Counter `json:"download"`
+		} `json:"day"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Day.Download.Bytes != 8192 || len(resp.Day.Clients) != 1 || resp.Day.Clients[0].Client != "192.168.1.10" {
+		t.Errorf("unexpected day window: %+v", resp.Day)
+	}
+	if c := resp.Day.Clients[0]; c.Upload.Bytes != 2048 || c.Download.Bytes != 8192 {
+		t.Errorf("expected both directions per client, got %+v", c)
+	}
+}
+
+func TestHandleTraffic_Error(t *testing.T) {
+	deps := newTestDeps(t)
+	deps.Traffic = &mockTraffic{err: errors.New("corrupt")}
+
+	rec := httptest.NewRecorder()
+	handleTraffic(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/traffic", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", rec.Code)
+	}
+}
+
+func TestHandleTraffic_NotConfigured(t *testing.T) {
+	deps := newTestDeps(t)
+	deps.Traffic = nil
+
+	rec := httptest.NewRecorder()
+	handleTraffic(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/traffic", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503, got %d", rec.Code)
+	}
+}
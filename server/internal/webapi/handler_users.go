@@ -0,0 +1,249 @@
+package webapi
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/auth"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/localuser"
+)
+
+// usersResponse is the body of GET /api/users.
+type usersResponse struct {
+	Backends []string         `json:"backends"` // enabled password backends, in order
+	Users    []localuser.User `json:"users"`
+}
+
+// addUserRequest is the expected JSON body for POST /api/users.
+type addUserRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+// setPasswordRequest is the expected JSON body for
+// PUT /api/users/{username}/password. Current is required when users change
+// their own password.
+type setPasswordRequest struct {
+	Password string `json:"password"`
+	Current  string `json:"current"`
+}
+
+// authBackends returns the password backends from webui.auth_backends, or
+// all of them when none are configured. Unknown names are skipped.
+func authBackends(deps *Deps) ([]string, error) {
+	cfg, err := deps.Config.LoadVPNConfig()
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil || len(cfg.WebUI.AuthBackends) == 0 {
+		return auth.Backends, nil
+	}
+	var backends []string
+	for _, b := range cfg.WebUI.AuthBackends {
+		if auth.ValidBackend(b) {
+			backends = append(backends, b)
+		}
+	}
+	return backends, nil
+}
+
+// verifyPassword checks a login against the enabled backends in order. A
+// backend that fails is skipped; its error is returned only when no other
+// backend accepts the password.
+func verifyPassword(deps *Deps, username, password string) (bool, error) {
+	backends, err := authBackends(deps)
+	if err != nil {
+		return false, err
+	}
+	var errs []error
+	for _, backend := range backends {
+		var ok bool
+		switch {
+		case backend == auth.BackendLocal && deps.Users != nil:
+			ok, err = deps.Users.Verify(username, password)
+		case backend == auth.BackendShadow && deps.Shadow != nil:
+			ok, err = deps.Shadow.Verify(username, password)
+		default:
+			continue
+		}
+		if ok {
+			return true, nil
+		}
+		if err != nil {
+			slog.Warn("Password backend failed", "backend", backend, "error", err)
+			errs = append(errs, err)
+		}
+	}
+	return false, errors.Join(errs...)
+}
+
+// handleListUsers returns the local users and the enabled backends.
+func handleListUsers(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		if deps.Users == nil {
+			jsonError(w, http.StatusServiceUnavailable, "local users are not available")
+			return
+		}
+
+		backends, err := authBackends(deps)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to load configuration")
+			return
+		}
+		users, err := deps.Users.List()
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to load users")
+			return
+		}
+		if users == nil {
+			users = []localuser.User{}
+		}
+		jsonOK(w, usersResponse{Backends: backends, Users: users})
+	}
+}
+
+// handleAddUser creates a local user with a password and role.
+func handleAddUser(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if deps.Users == nil {
+			jsonError(w, http.StatusServiceUnavailable, "local users are not available")
+			return
+		}
+
+		var req addUserRequest
+		if err := decodeJSON(r, &req); err != nil {
+			jsonError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		req.Username = strings.TrimSpace(req.Username)
+		if err := localuser.ValidateUsername(req.Username); err != nil {
+			jsonError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := localuser.ValidatePassword(req.Password); err != nil {
+			jsonError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if !auth.ValidRole(req.Role) {
+			jsonError(w, http.StatusBadRequest, "role must be one of "+strings.Join(auth.Roles, ", "))
+			return
+		}
+
+		u, err := deps.Users.Add(req.Username, req.Password, req.Role)
+		if errors.Is(err, localuser.ErrExists) {
+			jsonError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		auditChange(r, u.Username, nil, u.Role)
+		jsonOK(w, u)
+	}
+}
+
+// handleRemoveUser deletes a local user and signs out their sessions.
+// Admins cannot remove themselves.
+func handleRemoveUser(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if deps.Users == nil {
+			jsonError(w, http.StatusServiceUnavailable, "local users are not available")
+			return
+		}
+		username := r.PathValue("username")
+		if p := principalFrom(r.Context()); p != nil && p.Token == nil && p.Name == username {
+			jsonError(w, http.StatusBadRequest, "cannot remove yourself")
+			return
+		}
+
+		if u, err := deps.Users.Get(username); err == nil {
+			auditChange(r, "", u.Role, nil)
+		}
+		err := deps.Users.Remove(username)
+		if errors.Is(err, localuser.ErrNotFound) {
+			jsonError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to remove user")
+			return
+		}
+		if deps.Sessions != nil {
+			if _, err := deps.Sessions.RevokeAll(username, ""); err != nil {
+				slog.Warn("Failed to sign out removed user", "user", username, "error", err)
+			}
+		}
+		jsonOK(w, map[string]bool{"ok": true})
+	}
+}
+
+// handleSetUserPassword changes a local user's password. Admins can change
+// anyone's; in a web session, users change their own by confirming the
+// current password. The user's other sessions are signed out.
+func handleSetUserPassword(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if deps.Users == nil {
+			jsonError(w, http.StatusServiceUnavailable, "local users are not available")
+			return
+		}
+		p := principalFrom(r.Context())
+		username := r.PathValue("username")
+		self := p.Token == nil && p.Name == username
+		if !self && !p.can(permAdmin) {
+			jsonError(w, http.StatusForbidden, "forbidden: requires admin permission")
+			return
+		}
+
+		var req setPasswordRequest
+		if err := decodeJSON(r, &req); err != nil {
+			jsonError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		if err := localuser.ValidatePassword(req.Password); err != nil {
+			jsonError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if _, err := deps.Users.Get(username); errors.Is(err, localuser.ErrNotFound) {
+			jsonError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		if self {
+			ip := remoteIP(r)
+			if block := loginBlock(deps, ip); block != "" {
+				jsonError(w, http.StatusTooManyRequests, "too many failed attempts")
+				return
+			}
+			ok, err := deps.Users.Verify(username, req.Current)
+			if err != nil {
+				jsonError(w, http.StatusInternalServerError, "authentication error")
+				return
+			}
+			if !ok {
+				loginRejected(deps, ip, "wrong current password for "+username)
+				jsonError(w, http.StatusBadRequest, "current password is wrong")
+				return
+			}
+		}
+
+		err := deps.Users.SetPassword(username, req.Password)
+		if errors.Is(err, localuser.ErrNotFound) {
+			jsonError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "failed to change password")
+			return
+		}
+		if deps.Sessions != nil {
+			if _, err := deps.Sessions.RevokeAll(username, p.Session); err != nil {
+				slog.Warn("Failed to sign out other sessions", "user", username, "error", err)
+			}
+		}
+		jsonOK(w, map[string]bool{"ok": true})
+	}
+}
@@ -0,0 +1,256 @@
+package webapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/auth"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/localuser"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+)
+
+// newUsersDeps returns deps with a shadow admin, a local store holding the
+// viewer bob and sessions.
+func newUsersDeps(t *testing.T) (*Deps, *localuser.Store) {
+	t.Helper()
+	deps := newTestDeps(t)
+	deps.Shadow = auth.NewShadowAuth(writeShadowFixture(t, "admin:"+testSHA256Hash+":19000:0:99999:7:::\n"))
+	deps.Sessions = newTestSessions(t)
+	users := localuser.NewStore(localuser.StorePath(t.TempDir()))
+	if _, err := users.Add("bob", "bob-secret", auth.RoleViewer); err != nil {
+		t.Fatal(err)
+	}
+	deps.Users = users
+	return deps, users
+}
+
+func usersRequest(method, path, body string, p *principal) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	return req.WithContext(withPrincipal(req.Context(), p))
+}
+
+func loginAs(deps *Deps, username, password string) int {
+	body := `{"username":"` + username + `","password":"` + password + `"}`
+	rec := httptest.NewRecorder()
+	handleLogin(deps).ServeHTTP(rec, httptest.NewRequest("POST", "/api/login", strings.NewReader(body)))
+	return rec.Code
+}
+
+func TestHandleListUsers(t *testing.T) {
+	deps, _ := newUsersDeps(t)
+
+	rec := httptest.NewRecorder()
+	handleListUsers(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/users", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "hash") {
+		t.Errorf("expected no password hashes in the response: %s", rec.Body.String())
+	}
+	var resp usersResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if !slices.Equal(resp.Backends, auth.Backends) {
+		t.Errorf("expected all backends by default, got %v", resp.Backends)
+	}
+	if len(resp.Users) != 1 || resp.Users[0].Username != "bob" || resp.Users[0].Role != auth.RoleViewer {
+		t.Errorf("unexpected users %+v", resp.Users)
+	}
+}
+
+func TestHandleListUsers_Unavailable(t *testing.T) {
+	deps := newTestDeps(t)
+
+	rec := httptest.NewRecorder()
+	handleListUsers(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/users", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 without local users, got %d", rec.Code)
+	}
+}
+
+func TestHandleAddUser(t *testing.T) {
+	deps, users := newUsersDeps(t)
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"valid", `{"username":"alice","password":"alice-secret","role":"operator"}`, http.StatusOK},
+		{"exists", `{"username":"bob","password":"bob-secret2","role":"viewer"}`, http.StatusConflict},
+		{"bad username", `{"username":"a b","password":"alice-secret","role":"viewer"}`, http.StatusBadRequest},
+		{"short password", `{"username":"carol","password":"short","role":"viewer"}`, http.StatusBadRequest},
+		{"bad role", `{"username":"carol","password":"carol-secret","role":"root"}`, http.StatusBadRequest},
+		{"bad body", `{`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handleAddUser(deps).ServeHTTP(rec, httptest.NewRequest("POST", "/api/users", strings.NewReader(tt.body)))
+		if rec.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d: %s", tt.name, tt.want, rec.Code, rec.Body.String())
+		}
+	}
+
+	if ok, err := users.Verify("alice", "alice-secret"); err != nil || !ok {
+		t.Errorf("expected alice to be added, got %v, %v", ok, err)
+	}
+}
+
+func TestHandleRemoveUser(t *testing.T) {
+	deps, users := newUsersDeps(t)
+	s, err := deps.Sessions.Create("bob", "192.168.50.10", "Firefox", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin := &principal{Name: "admin", Role: auth.RoleAdmin, Session: "admin-session"}
+
+	remove := func(username string, p *principal) int {
+		req := usersRequest("DELETE", "/api/users/"+username, "", p)
+		req.SetPathValue("username", username)
+		rec := httptest.NewRecorder()
+		handleRemoveUser(deps).ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := remove("bob", admin); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if _, err := users.Get("bob"); !errors.Is(err, localuser.ErrNotFound) {
+		t.Errorf("expected bob removed, got %v", err)
+	}
+	if _, err := deps.Sessions.Check(s.ID, "192.168.50.10", "Firefox"); err == nil {
+		t.Error("expected the removed user's sessions to be revoked")
+	}
+	if code := remove("bob", admin); code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown user, got %d", code)
+	}
+
+	if _, err := users.Add("root2", "root2-secret", auth.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	if code := remove("root2", &principal{Name: "root2", Role: auth.RoleAdmin}); code != http.StatusBadRequest {
+		t.Errorf("expected admins not to remove themselves, got %d", code)
+	}
+}
+
+func TestHandleSetUserPassword(t *testing.T) {
+	deps, users := newUsersDeps(t)
+	other, err := deps.Sessions.Create("bob", "192.168.50.11", "Chrome", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := deps.Sessions.Create("bob", "192.168.50.10", "Firefox", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob := &principal{Name: "bob", Role: auth.RoleViewer, Session: current.ID}
+	admin := &principal{Name: "admin", Role: auth.RoleAdmin}
+
+	set := func(username, body string, p *principal) int {
+		req := usersRequest("PUT", "/api/users/"+username+"/password", body, p)
+		req.SetPathValue("username", username)
+		rec := httptest.NewRecorder()
+		handleSetUserPassword(deps).ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	tests := []struct {
+		name     string
+		username string
+		body     string
+		p        *principal
+		want     int
+	}{
+		{"wrong current", "bob", `{"password":"new-secret","current":"nope"}`, bob, http.StatusBadRequest},
+		{"short", "bob", `{"password":"short","current":"bob-secret"}`, bob, http.StatusBadRequest},
+		{"other user", "alice", `{"password":"new-secret"}`, bob, http.StatusForbidden},
+		{"unknown", "alice", `{"password":"new-secret"}`, admin, http.StatusNotFound},
+		{"self", "bob", `{"password":"new-secret","current":"bob-secret"}`, bob, http.StatusOK},
+	}
+	for _, tt := range tests {
+		if code := set(tt.username, tt.body, tt.p); code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, code)
+		}
+	}
+	if ok, _ := users.Verify("bob", "new-secret"); !ok {
+		t.Error("expected bob's password to change")
+	}
+	if _, err := deps.Sessions.Check(current.ID, "192.168.50.10", "Firefox"); err != nil {
+		t.Errorf("expected the current session to stay, got %v", err)
+	}
+	if _, err := deps.Sessions.Check(other.ID, "192.168.50.11", "Chrome"); err == nil {
+		t.Error("expected other sessions to be revoked")
+	}
+
+	if code := set("bob", `{"password":"admin-set-1"}`, admin); code != http.StatusOK {
+		t.Errorf("expected admins to reset passwords without the current one, got %d", code)
+	}
+	if ok, _ := users.Verify("bob", "admin-set-1"); !ok {
+		t.Error("expected the admin reset to apply")
+	}
+}
+
+func TestHandleLogin_LocalUser(t *testing.T) {
+	deps, _ := newUsersDeps(t)
+
+	if code := loginAs(deps, "bob", "bob-secret"); code != http.StatusOK {
+		t.Errorf("expected local users to log in, got %d", code)
+	}
+	if code := loginAs(deps, "admin", "testpass"); code != http.StatusOK {
+		t.Errorf("expected shadow users to log in, got %d", code)
+	}
+	if code := loginAs(deps, "bob", "wrong-pass"); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for a wrong password, got %d", code)
+	}
+
+	role, err := userRole(deps, "bob")
+	if err != nil || role != auth.RoleViewer {
+		t.Errorf("expected bob's own role, got %q, %v", role, err)
+	}
+}
+
+func TestHandleLogin_AuthBackends(t *testing.T) {
+	deps, _ := newUsersDeps(t)
+	deps.Config = &mockConfig{cfg: &vpnconfig.VPNDirectorConfig{
+		WebUI: vpnconfig.WebUIConfig{AuthBackends: []string{auth.BackendLocal}},
+	}}
+
+	if code := loginAs(deps, "admin", "testpass"); code != http.StatusUnauthorized {
+		t.Errorf("expected the shadow backend to be disabled, got %d", code)
+	}
+	if code := loginAs(deps, "bob", "bob-secret"); code != http.StatusOK {
+		t.Errorf("expected local users to log in, got %d", code)
+	}
+
+	backends, err := authBackends(deps)
+	if err != nil || !slices.Equal(backends, []string{auth.BackendLocal}) {
+		t.Errorf("unexpected backends %v, %v", backends, err)
+	}
+}
+
+func TestHandleWhoami_Local(t *testing.T) {
+	deps, _ := newUsersDeps(t)
+
+	for _, tt := range []struct {
+		name string
+		want bool
+	}{{"bob", true}, {"admin", false}} {
+		req := usersRequest("GET", "/api/whoami", "", &principal{Name: tt.name, Role: auth.RoleViewer})
+		rec := httptest.NewRecorder()
+		handleWhoami(deps).ServeHTTP(rec, req)
+		var got whoamiResponse
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if got.Local != tt.want {
+			t.Errorf("%s: expected local=%v, got %v", tt.name, tt.want, got.Local)
+		}
+	}
+}
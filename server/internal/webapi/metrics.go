@@ -0,0 +1,314 @@
+package webapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/banlist"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/cidrset"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/metrics"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/xraystats"
+)
+
+// operationBuckets are histogram buckets (seconds) for apply/restart runs,
+// which rebuild ipsets and can take minutes on a router.
+var operationBuckets = []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+// scrapeTimeout bounds calls to Xray made while serving /metrics.
+const scrapeTimeout = 2 * time.Second
+
+// UpdateStatus reports whether a newer release is available.
+type UpdateStatus interface {
+	Status() (latest string, available, ok bool)
+}
+
+// apiMetrics holds the metrics exported on /metrics. A nil *apiMetrics
+// records nothing, so handlers work without the router (e.g. in tests).
+type apiMetrics struct {
+	registry      *metrics.Registry
+	requests      *metrics.CounterVec
+	latency       *metrics.HistogramVec
+	opDuration    *metrics.HistogramVec
+	opFailures    *metrics.CounterVec
+	loginFailures *metrics.CounterVec
+}
+
+// newAPIMetrics registers request/operation metrics and scrape-time gauges
+// that read state from deps.
+func newAPIMetrics(deps *Deps) *apiMetrics {
+	reg := metrics.NewRegistry()
+	m := &apiMetrics{
+		registry: reg,
+		requests: reg.NewCounterVec("vpnd_http_requests_total",
+			"HTTP requests by method, route pattern and status code.", "method", "route", "code"),
+		latency: reg.NewHistogramVec("vpnd_http_request_duration_seconds",
+			"HTTP request latency by method and route pattern.", metrics.DefaultBuckets, "method", "route"),
+		opDuration: reg.NewHistogramVec("vpnd_operation_duration_seconds",
+			"Duration of apply/restart/stop/ipset update runs.", operationBuckets, "operation"),
+		opFailures: reg.NewCounterVec("vpnd_operation_failures_total",
+			"Failed apply/restart/stop/ipset update runs.", "operation"),
+		loginFailures: reg.NewCounterVec("vpnd_login_failures_total",
+			"Rejected logins by reason (bad_credentials, rate_limited, banned).", "reason"),
+	}
+
+	reg.NewGaugeFunc("vpnd_build_info", "Version and commit of the running Web UI.",
+		[]string{"version", "commit"}, func() []metrics.Sample {
+			return []metrics.Sample{{LabelValues: []string{deps.Version, deps.Commit}, Value: 1}}
+		})
+
+	reg.NewGaugeFunc("vpnd_login_locked_ips", "IP addresses currently locked out of login.",
+		nil, func() []metrics.Sample {
+			if deps.loginLimiter == nil {
+				return nil
+			}
+			return []metrics.Sample{{Value: float64(deps.loginLimiter.lockedCount())}}
+		})
+
+	reg.NewGaugeFunc("vpnd_login_bans", "Sources on the shared ban list by kind (ip, telegram).",
+		[]string{"kind"}, func() []metrics.Sample {
+			if deps.Bans == nil {
+				return nil
+			}
+			bans, err := deps.Bans.List()
+			if err != nil {
+				return nil
+			}
+			counts := map[string]int{banlist.KindIP: 0, banlist.KindTelegram: 0}
+			for _, b := range bans {
+				counts[b.Kind]++
+			}
+			return []metrics.Sample{
+				{LabelValues: []string{banlist.KindIP}, Value: float64(counts[banlist.KindIP])},
+				{LabelValues: []string{banlist.KindTelegram}, Value: float64(counts[banlist.KindTelegram])},
+			}
+		})
+
+	reg.NewGaugeFunc("vpnd_update_available", "1 if a newer release is available.",
+		[]string{"latest"}, func() []metrics.Sample {
+			if deps.Updates == nil {
+				return nil
+			}
+			latest, available, ok := deps.Updates.Status()
+			if !ok {
+				return nil
+			}
+			return []metrics.Sample{{LabelValues: []string{latest}, Value: boolValue(available)}}
+		})
+
+	reg.NewGaugeFunc("vpnd_xray_up", "1 if the Xray process is running.",
+		nil, func() []metrics.Sample {
+			if deps.System == nil {
+				return nil
+			}
+			running, err := deps.System.ProcessRunning("xray")
+			if err != nil {
+				return nil
+			}
+			return []metrics.Sample{{Value: boolValue(running)}}
+		})
+
+	reg.NewGaugeFunc("vpnd_active_server_info", "Server the generated Xray config points to.",
+		[]string{"id", "name", "address"}, func() []metrics.Sample {
+			return activeServerSamples(deps)
+		})
+
+	reg.NewGaugeFunc("vpnd_ipset_entries", "Number of entries per ipset.",
+		[]string{"set"}, func() []metrics.Sample {
+			if deps.System == nil {
+				return nil
+			}
+			counts, err := deps.System.IPSetCounts()
+			if err != nil {
+				return nil
+			}
+			samples := make([]metrics.Sample, 0, len(counts))
+			for _, name := range sortedNames(counts) {
+				samples = append(samples, metrics.Sample{LabelValues: []string{name}, Value: float64(counts[name])})
+			}
+			return samples
+		})
+
+	reg.NewGaugeFunc("vpnd_client_traffic_bytes", "Bytes sent (upload) or received (download) by a client over the last hour or day.",
+		[]string{"client", "route", "window", "direction"}, func() []metrics.Sample {
+			return clientTrafficSamples(deps)
+		})
+
+	reg.NewCounterFunc("vpnd_xray_traffic_bytes_total", "Xray traffic per inbound/outbound tag from the stats API.",
+		[]string{"type", "tag", "direction"}, func() []metrics.Sample {
+			return xrayTrafficSamples(deps)
+		})
+
+	return m
+}
+
+// observeRequest records one HTTP request. The route is the matched mux
+// pattern without its method, so path parameters do not create new series.
+func (m *apiMetrics) observeRequest(r *http.Request, status int, d time.Duration) {
+	if m == nil {
+		return
+	}
+	route := r.Pattern
+	if _, path, ok := strings.Cut(route, " "); ok {
+		route = path
+	}
+	if route == "" {
+		route = "other"
+	}
+	m.requests.Inc(r.Method, route, strconv.Itoa(status))
+	m.latency.Observe(d.Seconds(), r.Method, route)
+}
+
+// observeOp records the duration and outcome of a mutating operation.
+func (m *apiMetrics) observeOp(op string, start time.Time, err error) {
+	if m == nil {
+		return
+	}
+	m.opDuration.Observe(time.Since(start).Seconds(), op)
+	if err != nil {
+		m.opFailures.Inc(op)
+	}
+}
+
+// loginFailed counts a rejected login.
+func (m *apiMetrics) loginFailed(reason string) {
+	if m == nil {
+		return
+	}
+	m.loginFailures.Inc(reason)
+}
+
+// handleMetrics serves Prometheus metrics. The endpoint is disabled (404)
+// unless webui.metrics_token or webui.metrics_allow is configured; when both
+// are set, a request must come from an allowed address and carry the token.
+// Wrong tokens count as failed logins, like invalid API tokens.
+func handleMetrics(deps *Deps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cfg, err := deps.Config.LoadVPNConfig()
+		if err != nil {
+			http.Error(w, "failed to load configuration", http.StatusInternalServerError)
+			return
+		}
+		token, allow := cfg.WebUI.MetricsToken, cfg.WebUI.MetricsAllow
+		if token == "" && len(allow) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		ip := remoteIP(r)
+		if len(allow) > 0 {
+			addr, err := netip.ParseAddr(ip)
+			if err != nil || !cidrset.Match(allow, addr) {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+		}
+		if token != "" {
+			if block := loginBlock(deps, ip); block != "" {
+				deps.metrics.loginFailed(block)
+				http.Error(w, "too many failed attempts", http.StatusTooManyRequests)
+				return
+			}
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				loginRejected(deps, ip, "bad_metrics_token")
+				deps.metrics.loginFailed("bad_metrics_token")
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		deps.metrics.registry.Handler().ServeHTTP(w, r)
+	}
+}
+
+// activeServerSamples matches the generated Xray config against the server
+// list. A server missing from the list is exported with empty id and name.
+func activeServerSamples(deps *Deps) []metrics.Sample {
+	if deps.XrayConfig == nil {
+		return nil
+	}
+	active, err := deps.XrayConfig.ActiveServer()
+	if err != nil {
+		return nil
+	}
+	var id, name string
+	if servers, err := deps.Config.LoadServers(); err == nil {
+		if idx := vpnconfig.FindActive(servers, *active); idx >= 0 {
+			id, name = servers[idx].ID, servers[idx].Name
+		}
+	}
+	address := active.Address + ":" + strconv.Itoa(active.Port)
+	if strings.Contains(active.Address, ":") {
+		address = "[" + active.Address + "]:" + strconv.Itoa(active.Port)
+	}
+	return []metrics.Sample{{LabelValues: []string{id, name, address}, Value: 1}}
+}
+
+// clientTrafficSamples exports per-client bytes in both directions for the
+// hour and day windows.
+func clientTrafficSamples(deps *Deps) []metrics.Sample {
+	if deps.Traffic == nil {
+		return nil
+	}
+	report, err := deps.Traffic.Report()
+	if err != nil {
+		return nil
+	}
+	var samples []metrics.Sample
+	for _, window := range []string{"hour", "day"} {
+		win, _ := report.WindowByName(window)
+		for _, u := range win.Clients {
+			samples = append(samples,
+				metrics.Sample{LabelValues: []string{u.Client, u.Route, window, "upload"}, Value: float64(u.Upload.Bytes)},
+				metrics.Sample{LabelValues: []string{u.Client, u.Route, window, "download"}, Value: float64(u.Download.Bytes)},
+			)
+		}
+	}
+	return samples
+}
+
+// xrayTrafficSamples exports Xray inbound/outbound counters.
+func xrayTrafficSamples(deps *Deps) []metrics.Sample {
+	if deps.XrayStats == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), scrapeTimeout)
+	defer cancel()
+	summary, err := deps.XrayStats.Summary(ctx)
+	if err != nil {
+		return nil
+	}
+	var samples []metrics.Sample
+	add := func(kind string, list []xraystats.Traffic) {
+		for _, t := range list {
+			samples = append(samples,
+				metrics.Sample{LabelValues: []string{kind, t.Tag, "uplink"}, Value: float64(t.Uplink)},
+				metrics.Sample{LabelValues: []string{kind, t.Tag, "downlink"}, Value: float64(t.Downlink)},
+			)
+		}
+	}
+	add("inbound", summary.Inbounds)
+	add("outbound", summary.Outbounds)
+	return samples
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func sortedNames(m map[string]int) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
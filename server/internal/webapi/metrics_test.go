@@ -0,0 +1,245 @@
+package webapi
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/auth"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/banlist"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/traffic"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/xraystats"
+)
+
+// newMetricsDeps returns deps with the metrics endpoint protected by token
+// "secret" and all metric sources populated.
+func newMetricsDeps(t *testing.T) *Deps {
+	t.Helper()
+	deps := newTestDeps(t)
+	deps.Config = &mockConfig{
+		cfg: &vpnconfig.VPNDirectorConfig{
+			WebUI: vpnconfig.WebUIConfig{MetricsToken: "secret"},
+		},
+		servers: []vpnconfig.Server{
+			{ID: "a1b2c3d4", Name: "Frankfurt", Address: "de.example.com", Port: 443, UUID: "uuid-1"},
+		},
+	}
+	deps.XrayConfig = &mockXrayConfig{server: &vpnconfig.Server{Address: "de.example.com", Port: 443, UUID: "uuid-1"}}
+	deps.System = &mockSystem{running: true, ipsets: map[string]int{"ru": 12000, "ua": 300}}
+	deps.Updates = &mockUpdates{latest: "v2.0.0", available: true}
+	deps.Traffic = &mockTraffic{report: &traffic.Report{
+		Hour: traffic.Window{Clients: []traffic.Usage{{Client: "192.168.50.10", Route: "xray", Traffic: traffic.Traffic{
+			Upload: traffic.Counter{Bytes: 1024}, Download: traffic.Counter{Bytes: 4096},
+		}}}},
+	}}
+	deps.XrayStats = &mockXrayStats{summary: &xraystats.Summary{
+		Outbounds: []xraystats.Traffic{{Tag: "proxy", Uplink: 10, Downlink: 20}},
+	}}
+	return deps
+}
+
+func scrape(t *testing.T, router http.Handler, remoteAddr, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.RemoteAddr = remoteAddr
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMetrics_DisabledByDefault(t *testing.T) {
+	deps := newTestDeps(t)
+	deps.Config = &mockConfig{cfg: &vpnconfig.VPNDirectorConfig{}}
+	router := NewRouter(deps, nil)
+
+	rec := scrape(t, router, "10.0.0.1:1234", "")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestMetrics_Token(t *testing.T) {
+	router := NewRouter(newMetricsDeps(t), nil)
+
+	if rec := scrape(t, router, "10.0.0.1:1234", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("no token: expected 401, got %d", rec.Code)
+	}
+	if rec := scrape(t, router, "10.0.0.1:1234", "wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("wrong token: expected 401, got %d", rec.Code)
+	}
+	if rec := scrape(t, router, "10.0.0.1:1234", "secret"); rec.Code != http.StatusOK {
+		t.Errorf("valid token: expected 200, got %d", rec.Code)
+	}
+}
+
+func TestMetrics_BadTokenBlocked(t *testing.T) {
+	deps := newMetricsDeps(t)
+	deps.Bans = banlist.NewStore(banlist.StorePath(t.TempDir()))
+	router := NewRouter(deps, nil)
+
+	for i := 0; i < 5; i++ {
+		if rec := scrape(t, router, "10.0.0.9:1234", "wrong"); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: expected 401, got %d", i+1, rec.Code)
+		}
+	}
+	// Blocked even with the right token
+	if rec := scrape(t, router, "10.0.0.9:1234", "secret"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected 429 after failed attempts, got %d", rec.Code)
+	}
+
+	body := scrape(t, router, "10.0.0.1:1234", "secret").Body.String()
+	if want := `vpnd_login_failures_total{reason="bad_metrics_token"} 5`; !strings.Contains(body, want+"\n") {
+		t.Errorf("missing %q in:\n%s", want, body)
+	}
+}
+
+func TestMetrics_AllowList(t *testing.T) {
+	deps := newMetricsDeps(t)
+	deps.Config.(*mockConfig).cfg.WebUI = vpnconfig.WebUIConfig{MetricsAllow: []string{"192.168.50.0/24", "10.0.0.5"}}
+	router := NewRouter(deps, nil)
+
+	tests := []struct {
+		remote string
+		want   int
+	}{
+		{"192.168.50.7:5555", http.StatusOK},
+		{"10.0.0.5:5555", http.StatusOK},
+		{"[::ffff:10.0.0.5]:5555", http.StatusOK},
+		{"10.0.0.6:5555", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		if rec := scrape(t, router, tt.remote, ""); rec.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.remote, tt.want, rec.Code)
+		}
+	}
+}
+
+func TestMetrics_TokenAndAllowList(t *testing.T) {
+	deps := newMetricsDeps(t)
+	deps.Config.(*mockConfig).cfg.WebUI.MetricsAllow = []string{"192.168.50.0/24"}
+	router := NewRouter(deps, nil)
+
+	if rec := scrape(t, router, "10.0.0.1:1234", "secret"); rec.Code != http.StatusForbidden {
+		t.Errorf("disallowed IP: expected 403, got %d", rec.Code)
+	}
+	if rec := scrape(t, router, "192.168.50.2:1234", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("missing token: expected 401, got %d", rec.Code)
+	}
+	if rec := scrape(t, router, "192.168.50.2:1234", "secret"); rec.Code != http.StatusOK {
+		t.Errorf("allowed IP with token: expected 200, got %d", rec.Code)
+	}
+}
+
+func TestMetrics_Content(t *testing.T) {
+	deps := newMetricsDeps(t)
+	deps.VPN = &mockVPN{err: errors.New("boom")}
+	router := NewRouter(deps, nil)
+
+	// Unauthenticated API call and a failed apply via the handler.
+	req := httptest.NewRequest("GET", "/api/servers/abc", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	handleApply(deps).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/apply", nil))
+
+	rec := scrape(t, router, "10.0.0.1:1234", "secret")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+
+	for _, want := range []string{
+		`vpnd_http_requests_total{method="GET",route="/api/",code="401"} 1`,
+		`vpnd_operation_failures_total{operation="apply"} 1`,
+		`vpnd_operation_duration_seconds_count{operation="apply"} 1`,
+		`vpnd_build_info{version="1.0.0-test",commit="abc1234"} 1`,
+		`vpnd_update_available{latest="v2.0.0"} 1`,
+		`vpnd_xray_up 1`,
+		`vpnd_active_server_info{id="a1b2c3d4",name="Frankfurt",address="de.example.com:443"} 1`,
+		`vpnd_ipset_entries{set="ru"} 12000`,
+		`vpnd_ipset_entries{set="ua"} 300`,
+		`vpnd_client_traffic_bytes{client="192.168.50.10",route="xray",window="hour",direction="upload"} 1024`,
+		`vpnd_client_traffic_bytes{client="192.168.50.10",route="xray",window="hour",direction="download"} 4096`,
+		`vpnd_xray_traffic_bytes_total{type="outbound",tag="proxy",direction="downlink"} 20`,
+		`vpnd_login_locked_ips 0`,
+	} {
+		if !strings.Contains(body, want+"\n") {
+			t.Errorf("missing %q in:\n%s", want, body)
+		}
+	}
+}
+
+func TestMetrics_RoutePatternWithoutParams(t *testing.T) {
+	deps := newMetricsDeps(t)
+	router := NewRouter(deps, nil)
+
+	token, err := deps.JWT.Create("admin", auth.RoleAdmin, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"one", "two"} {
+		req := httptest.NewRequest("DELETE", "/api/servers/"+id, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	body := scrape(t, router, "10.0.0.1:1234", "secret").Body.String()
+	if !strings.Contains(body, `vpnd_http_requests_total{method="DELETE",route="/api/servers/{id}",code="404"} 2`) {
+		t.Errorf("expected requests grouped by pattern:\n%s", body)
+	}
+}
+
+func TestMetrics_LoginFailures(t *testing.T) {
+	deps := newMetricsDeps(t)
+	deps.Bans = banlist.NewStore(banlist.StorePath(t.TempDir()))
+	router := NewRouter(deps, nil)
+
+	for i := 0; i < 6; i++ {
+		req := httptest.NewRequest("POST", "/api/login", strings.NewReader(`{"username":"admin","password":"bad"}`))
+		req.RemoteAddr = "10.0.0.9:1234"
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	body := scrape(t, router, "10.0.0.1:1234", "secret").Body.String()
+	for _, want := range []string{
+		`vpnd_login_failures_total{reason="bad_credentials"} 5`,
+		`vpnd_login_failures_total{reason="rate_limited"} 1`,
+		`vpnd_login_locked_ips 1`,
+		`vpnd_login_bans{kind="ip"} 1`,
+		`vpnd_login_bans{kind="telegram"} 0`,
+	} {
+		if !strings.Contains(body, want+"\n") {
+			t.Errorf("missing %q in:\n%s", want, body)
+		}
+	}
+}
+
+func TestMetrics_SourcesUnavailable(t *testing.T) {
+	deps := newMetricsDeps(t)
+	deps.System = &mockSystem{err: errors.New("no ipset")}
+	deps.XrayConfig = &mockXrayConfig{err: errors.New("no config")}
+	deps.XrayStats = &mockXrayStats{err: errors.New("connection refused")}
+	router := NewRouter(deps, nil)
+
+	body := scrape(t, router, "10.0.0.1:1234", "secret").Body.String()
+	for _, absent := range []string{"vpnd_xray_up", "vpnd_ipset_entries", "vpnd_active_server_info", "vpnd_xray_traffic_bytes_total"} {
+		if strings.Contains(body, absent) {
+			t.Errorf("%s should be omitted when its source fails:\n%s", absent, body)
+		}
+	}
+}
+
+func TestHandleConfig_RedactsMetricsToken(t *testing.T) {
+	deps := newMetricsDeps(t)
+
+	rec := httptest.NewRecorder()
+	handleConfig(deps).ServeHTTP(rec, httptest.NewRequest("GET", "/api/config", nil))
+
+	if strings.Contains(rec.Body.String(), "secret") {
+		t.Errorf("metrics token leaked: %s", rec.Body.String())
+	}
+}
@@ -2,19 +2,29 @@ package webapi
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"net/http"
+	"net/netip"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/apitoken"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/auth"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/netaccess"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/session"
 )
 
 // authMiddleware returns HTTP middleware that validates JWT tokens.
 // It checks the "token" cookie first, then the Authorization: Bearer header.
+// A Bearer value starting with apitoken.Prefix is checked as an API token
+// instead (disabled when deps.Tokens is nil); invalid ones count as failed
+// logins. A JWT must name a live session in deps.Sessions; cookie sessions
+// are renewed as they near expiry. The caller is stored in the request
+// context for the per-route checks in require.
 // Returns 401 if no valid token is found.
-func authMiddleware(jwt *auth.JWTService) func(http.Handler) http.Handler {
+func authMiddleware(deps *Deps) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			token := extractToken(r)
@@ -23,22 +33,108 @@ func authMiddleware(jwt *auth.JWTService) func(http.Handler) http.Handler {
 				return
 			}
 
-			_, err := jwt.Validate(token)
+			if strings.HasPrefix(token, apitoken.Prefix) && deps.Tokens != nil {
+				ip := remoteIP(r)
+				if loginBlock(deps, ip) != "" {
+					jsonError(w, http.StatusTooManyRequests, "too many failed attempts")
+					return
+				}
+				t, err := deps.Tokens.Authenticate(token)
+				if errors.Is(err, apitoken.ErrInvalid) {
+					loginRejected(deps, ip, "invalid API token")
+					jsonError(w, http.StatusUnauthorized, "invalid API token")
+					return
+				}
+				if err != nil {
+					slog.Warn("API token check failed", "error", err)
+					jsonError(w, http.StatusInternalServerError, "authentication error")
+					return
+				}
+				serveAs(next, w, r, &principal{Name: t.Name, Token: t})
+				return
+			}
+
+			claims, err := deps.JWT.Validate(token)
 			if err != nil {
 				jsonError(w, http.StatusUnauthorized, "invalid or expired token")
 				return
 			}
 
-			next.ServeHTTP(w, r)
+			// A session token must name a live registry entry. Tokens issued
+			// before the registry carry no ID and are refused.
+			if deps.Sessions != nil {
+				sess, err := deps.Sessions.Check(claims.ID, remoteIP(r), r.UserAgent())
+				if errors.Is(err, session.ErrNotFound) || (err == nil && sess.Username != claims.Subject) {
+					jsonError(w, http.StatusUnauthorized, "session expired or revoked")
+					return
+				}
+				if err != nil {
+					slog.Warn("Session check failed", "error", err)
+					jsonError(w, http.StatusInternalServerError, "authentication error")
+					return
+				}
+			}
+
+			// The role claim may be stale: a role change or a deleted user
+			// takes effect on the next request, not when the token expires
+			role, err := userRole(deps, claims.Subject)
+			if err != nil {
+				jsonError(w, http.StatusInternalServerError, "authentication error")
+				return
+			}
+
+			if token == cookieToken(r) {
+				renewSession(w, deps, claims)
+			}
+			serveAs(next, w, r, &principal{Name: claims.Subject, Role: role, Session: claims.ID})
 		})
 	}
 }
 
+// renewSession re-issues the session cookie once less than half of the
+// token's lifetime is left, so an active browser stays signed in. The role
+// claim is refreshed too, although requests always use the current role.
+// Failures are only logged: the current token is still valid.
+func renewSession(w http.ResponseWriter, deps *Deps, claims *auth.Claims) {
+	if deps.Sessions == nil || claims.ID == "" {
+		return
+	}
+	duration := deps.JWT.Duration()
+	if time.Until(time.Unix(claims.ExpiresAt, 0)) > duration/2 {
+		return
+	}
+
+	role, err := userRole(deps, claims.Subject)
+	if err != nil {
+		slog.Warn("Session renewal failed", "error", err)
+		return
+	}
+	if err := deps.Sessions.Renew(claims.ID, time.Now().Add(duration)); err != nil {
+		slog.Warn("Session renewal failed", "error", err)
+		return
+	}
+	token, err := deps.JWT.Create(claims.Subject, role, claims.ID)
+	if err != nil {
+		slog.Warn("Session renewal failed", "error", err)
+		return
+	}
+	setTokenCookie(w, token, duration)
+}
+
+// serveAs calls next with p in the request context. The protected mux sets
+// the matched pattern on that copy; it is copied back so request logging
+// and metrics see the route.
+func serveAs(next http.Handler, w http.ResponseWriter, r *http.Request, p *principal) {
+	r2 := r.WithContext(withPrincipal(r.Context(), p))
+	next.ServeHTTP(w, r2)
+	r.Pattern = r2.Pattern
+}
+
 // extractToken retrieves the JWT from the request. It checks the "token"
 // cookie first, then falls back to the Authorization: Bearer header.
 func extractToken(r *http.Request) string {
-	if cookie, err := r.Cookie("token"); err == nil && cookie.Value != "" {
-		return cookie.Value
+	if token := cookieToken(r); token != "" {
+		return token
 	}
 
 	header := r.Header.Get("Authorization")
@@ -49,6 +145,14 @@ func extractToken(r *http.Request) string {
 	return ""
 }
 
+// cookieToken returns the "token" cookie, or "".
+func cookieToken(r *http.Request) string {
+	if cookie, err := r.Cookie("token"); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
 // attemptInfo tracks failed login attempts from a single IP address.
 type attemptInfo struct {
 	count     int
@@ -147,6 +251,21 @@ func (rl *rateLimiter) record(ip string) {
 	}
 }
 
+// lockedCount returns the number of IPs that are currently locked out.
+func (rl *rateLimiter) lockedCount() int {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := time.Now()
+	n := 0
+	for _, info := range rl.attempts {
+		if !info.lockedAt.IsZero() && now.Before(info.lockedAt.Add(rl.lockout)) {
+			n++
+		}
+	}
+	return n
+}
+
 // cleanupLoop periodically removes stale entries from the rate limiter.
 // It stops when ctx is cancelled.
 func (rl *rateLimiter) cleanupLoop(ctx context.Context) {
@@ -182,18 +301,37 @@ func (rl *rateLimiter) cleanup() {
 	}
 }
 
+// allowlistMiddleware refuses requests from clients the policy does not
+// allow. A nil policy allows everyone.
+func allowlistMiddleware(policy *netaccess.Policy, next http.Handler) http.Handler {
+	if policy == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		addr, err := netip.ParseAddr(remoteIP(r))
+		if err != nil || !policy.Allowed(addr) {
+			slog.Warn("request from disallowed address", "remote", r.RemoteAddr, "path", r.URL.Path)
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // loggingMiddleware logs each HTTP request's method, path, duration,
-// status code, and remote address using slog.
-func loggingMiddleware(next http.Handler) http.Handler {
+// status code, and remote address using slog, and records request metrics.
+func loggingMiddleware(m *apiMetrics, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
 		next.ServeHTTP(sw, r)
+		duration := time.Since(start)
+		m.observeRequest(r, sw.status, duration)
 		slog.Info("http request",
 			"method", r.Method,
 			"path", r.URL.Path,
 			"status", sw.status,
-			"duration", time.Since(start).String(),
+			"duration", duration.String(),
 			"remote", r.RemoteAddr,
 		)
 	})
@@ -209,3 +347,9 @@ func (sw *statusWriter) WriteHeader(code int) {
 	sw.status = code
 	sw.ResponseWriter.WriteHeader(code)
 }
+
+// Unwrap lets http.ResponseController reach the underlying writer (used to
+// flush and extend deadlines for streaming responses).
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/auth"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/netaccess"
 )
 
 func newTestJWT(t *testing.T) *auth.JWTService {
@@ -16,12 +17,12 @@ func newTestJWT(t *testing.T) *auth.JWTService {
 
 func TestAuthMiddleware_ValidCookie(t *testing.T) {
 	jwt := newTestJWT(t)
-	token, err := jwt.Create("admin")
+	token, err := jwt.Create("admin", auth.RoleAdmin, "")
 	if err != nil {
 		t.Fatalf("create token: %v", err)
 	}
 
-	handler := authMiddleware(jwt)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	handler := authMiddleware(&Deps{JWT: jwt, Config: &mockConfig{}})(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -38,12 +39,12 @@ func TestAuthMiddleware_ValidCookie(t *testing.T) {
 
 func TestAuthMiddleware_ValidBearerHeader(t *testing.T) {
 	jwt := newTestJWT(t)
-	token, err := jwt.Create("admin")
+	token, err := jwt.Create("admin", auth.RoleAdmin, "")
 	if err != nil {
 		t.Fatalf("create token: %v", err)
 	}
 
-	handler := authMiddleware(jwt)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	handler := authMiddleware(&Deps{JWT: jwt, Config: &mockConfig{}})(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -61,7 +62,7 @@ func TestAuthMiddleware_ValidBearerHeader(t *testing.T) {
 func TestAuthMiddleware_NoToken(t *testing.T) {
 	jwt := newTestJWT(t)
 
-	handler := authMiddleware(jwt)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	handler := authMiddleware(&Deps{JWT: jwt, Config: &mockConfig{}})(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -78,7 +79,7 @@ func TestAuthMiddleware_NoToken(t *testing.T) {
 func TestAuthMiddleware_ExpiredToken(t *testing.T) {
 	// Create a JWT service with a very short duration.
 	jwt := auth.NewJWTService("test-secret-key-32bytes!!!!!!!!", 1*time.Millisecond)
-	token, err := jwt.Create("admin")
+	token, err := jwt.Create("admin", auth.RoleAdmin, "")
 	if err != nil {
 		t.Fatalf("create token: %v", err)
 	}
@@ -86,7 +87,7 @@ func TestAuthMiddleware_ExpiredToken(t *testing.T) {
 	// Wait for token to expire.
 	time.Sleep(10 * time.Millisecond)
 
-	handler := authMiddleware(jwt)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	handler := authMiddleware(&Deps{JWT: jwt, Config: &mockConfig{}})(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -146,3 +147,147 @@ func TestRateLimiter_BlockedThenAllowedAfterLockout(t *testing.T) {
 		t.Error("should be allowed after lockout expires")
 	}
 }
+
+func TestAuthMiddleware_SessionRegistry(t *testing.T) {
+	jwt := newTestJWT(t)
+	sessions := newTestSessions(t)
+	deps := &Deps{JWT: jwt, Config: &mockConfig{}, Sessions: sessions}
+
+	var got *principal
+	handler := authMiddleware(deps)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = principalFrom(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(token string) int {
+		req := httptest.NewRequest("GET", "/api/status", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	sess, _ := sessions.Create("admin", "192.0.2.1", "", time.Now().Add(time.Hour))
+	token, _ := jwt.Create("admin", auth.RoleAdmin, sess.ID)
+	if code := serve(token); code != http.StatusOK || got.Session != sess.ID {
+		t.Fatalf("expected 200 for a live session, got %d", code)
+	}
+
+	// Tokens from before the registry carry no session ID
+	legacy, _ := jwt.Create("admin", auth.RoleAdmin, "")
+	if code := serve(legacy); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a session ID, got %d", code)
+	}
+
+	// A session ID belongs to one user
+	forged, _ := jwt.Create("kid", auth.RoleAdmin, sess.ID)
+	if code := serve(forged); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for another user's session, got %d", code)
+	}
+
+	sessions.Revoke(sess.ID)
+	if code := serve(token); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 after revocation, got %d", code)
+	}
+}
+
+func TestAuthMiddleware_RenewsSession(t *testing.T) {
+	jwt := newTestJWT(t)
+	sessions := newTestSessions(t)
+	deps := &Deps{JWT: jwt, Config: &mockConfig{}, Sessions: sessions}
+	handler := authMiddleware(deps)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/status", nil)
+		req.AddCookie(&http.Cookie{Name: "token", Value: token})
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	sess, _ := sessions.Create("admin", "192.0.2.1", "", time.Now().Add(time.Hour))
+	fresh, _ := jwt.Create("admin", auth.RoleAdmin, sess.ID)
+	if rec := serve(fresh); rec.Code != http.StatusOK || hasTokenCookie(rec) {
+		t.Errorf("expected no renewal for a fresh token, got %d", rec.Code)
+	}
+
+	// Less than half of the lifetime left
+	short := auth.NewJWTService("test-secret-key-32bytes!!!!!!!!", 20*time.Minute)
+	old, _ := short.Create("admin", auth.RoleAdmin, sess.ID)
+	rec := serve(old)
+	if rec.Code != http.StatusOK || !hasTokenCookie(rec) {
+		t.Fatalf("expected a renewed cookie, got %d", rec.Code)
+	}
+	claims, err := jwt.Validate(rec.Result().Cookies()[0].Value)
+	if err != nil || claims.ID != sess.ID || time.Until(time.Unix(claims.ExpiresAt, 0)) < 50*time.Minute {
+		t.Errorf("expected a full-length token for the same session, got %+v, %v", claims, err)
+	}
+	list, _ := sessions.List()
+	if time.Until(list[0].Expires) < 50*time.Minute {
+		t.Errorf("expected the session extended, got %v", list[0].Expires)
+	}
+}
+
+func TestStartSession_RegistersSession(t *testing.T) {
+	deps := newTestDeps(t)
+	sessions := newTestSessions(t)
+	deps.Sessions = sessions
+
+	req := httptest.NewRequest("POST", "/api/login", nil)
+	req.Header.Set("User-Agent", "Firefox")
+	rec := httptest.NewRecorder()
+	if err := startSession(rec, req, deps, "admin"); err != nil {
+		t.Fatal(err)
+	}
+
+	list, _ := sessions.List()
+	if len(list) != 1 || list[0].Username != "admin" || list[0].UserAgent != "Firefox" || list[0].IP != "192.0.2.1" {
+		t.Fatalf("unexpected sessions: %+v", list)
+	}
+	claims, err := deps.JWT.Validate(rec.Result().Cookies()[0].Value)
+	if err != nil || claims.ID != list[0].ID {
+		t.Errorf("expected the token to carry the session ID, got %+v, %v", claims, err)
+	}
+}
+
+func TestAllowlistMiddleware(t *testing.T) {
+	policy, err := netaccess.NewPolicy([]string{"192.168.50.0/24"}, []string{"192.168.50.66"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := allowlistMiddleware(policy, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	tests := []struct {
+		remote string
+		want   int
+	}{
+		{"192.168.50.10:50000", http.StatusTeapot},
+		{"127.0.0.1:50000", http.StatusTeapot},
+		{"[::ffff:192.168.50.10]:50000", http.StatusTeapot},
+		{"192.168.50.66:50000", http.StatusForbidden},
+		{"203.0.113.9:50000", http.StatusForbidden},
+		{"garbage", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/api/status", nil)
+		req.RemoteAddr = tt.remote
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.remote, tt.want, rec.Code)
+		}
+	}
+}
+
+func TestAllowlistMiddleware_NilPolicy(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {})
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "203.0.113.9:50000"
+	rec := httptest.NewRecorder()
+	allowlistMiddleware(nil, next).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", rec.Code)
+	}
+}
@@ -6,8 +6,23 @@ import (
 	"sync"
 	"time"
 
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/accesslog"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/apitoken"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/audit"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/auth"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/banlist"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/conntrack"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/diagnostics"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/events"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/localuser"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/loginapproval"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/paths"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/service"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/session"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/speedtest"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/tlscert"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/totp"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/traffic"
 )
 
 // Deps holds all dependencies required by the HTTP API handlers.
@@ -17,12 +32,53 @@ type Deps struct {
 	Xray         service.XrayGenerator
 	Network      service.NetworkInfo
 	Logs         service.LogReader
+	Domains      service.DomainRouter
+	Traffic      traffic.Reporter
+	XrayStats    service.XrayStats
+	XrayConfig   service.XrayConfigReader
+	System       service.SystemInfo
+	Access       accesslog.Searcher
+	Connections  conntrack.Lister
+	Diagnostics  diagnostics.RouteChecker
+	SpeedTest    speedtest.Tester
+	Events       events.Timeline   // event history; nil disables recording
+	Tokens       apitoken.Manager  // API tokens; nil disables them
+	TOTP         totp.Manager      // two-factor login; nil disables it
+	Sessions     session.Manager   // login registry; nil skips revocation
+	Certs        tlscert.Manager   // TLS certificate; nil disables management
+	Bans         banlist.Manager   // shared ban list; nil keeps only the rate limit
+	Users        localuser.Manager // local accounts; nil disables them
+	Audit        audit.Log         // audit trail; nil disables recording and /api/audit
+	Approvals    loginapproval.Asker
+	Updates      UpdateStatus
+	Paths        paths.Paths // log file locations
 	Shadow       *auth.ShadowAuth
 	JWT          *auth.JWTService
 	Version      string
 	Commit       string
-	OpMutex      *sync.Mutex  // serializes mutating shell operations
-	loginLimiter *rateLimiter // rate limiter for login endpoint
+	OpMutex      *sync.Mutex      // serializes mutating shell operations
+	loginLimiter *rateLimiter     // rate limiter for login endpoint
+	challenges   *loginChallenges // pending two-factor login steps
+	tgLogins     *telegramLogins  // logins waiting for approval in Telegram
+	metrics      *apiMetrics      // exported on /metrics
+}
+
+// lockConfig takes deps.OpMutex and the cross-process config lock for a
+// load-modify-save of the configuration. When the config lock cannot be
+// taken it replies 500 and returns false; otherwise the caller defers the
+// returned unlock.
+func lockConfig(w http.ResponseWriter, deps *Deps) (func(), bool) {
+	deps.OpMutex.Lock()
+	unlock, err := deps.Config.LockConfig()
+	if err != nil {
+		deps.OpMutex.Unlock()
+		jsonError(w, http.StatusInternalServerError, "failed to lock configuration")
+		return nil, false
+	}
+	return func() {
+		unlock()
+		deps.OpMutex.Unlock()
+	}, true
 }
 
 // NewRouter creates the top-level HTTP handler with all routes registered.
@@ -31,70 +87,140 @@ func NewRouter(deps *Deps, staticFS fs.FS) http.Handler {
 	mux := http.NewServeMux()
 
 	deps.loginLimiter = newRateLimiter(5, 1*time.Minute, 30*time.Second)
+	deps.challenges = newLoginChallenges()
+	deps.tgLogins = newTelegramLogins()
+	deps.metrics = newAPIMetrics(deps)
 
 	// Public routes (no auth required).
 	mux.HandleFunc("POST /api/login", handleLogin(deps))
+	mux.HandleFunc("POST /api/login/totp", handleLoginTOTP(deps))
+	mux.HandleFunc("GET /api/login/telegram", handleTelegramLoginEnabled(deps))
+	mux.HandleFunc("POST /api/login/telegram", handleStartTelegramLogin(deps))
+	mux.HandleFunc("GET /api/login/telegram/{id}", handleTelegramLoginStatus(deps))
+
+	// Prometheus metrics (token/IP protected, see handleMetrics).
+	mux.HandleFunc("GET /metrics", handleMetrics(deps))
 
-	// Protected routes (require valid JWT).
+	// Protected routes (require valid JWT or API token).
 	protectedMux := http.NewServeMux()
 	registerProtectedRoutes(protectedMux, deps)
 
-	authMW := authMiddleware(deps.JWT)
-	mux.Handle("/api/", authMW(protectedMux))
+	authMW := authMiddleware(deps)
+	mux.Handle("/api/", authMW(auditMiddleware(deps, protectedMux)))
 
 	// SPA fallback: serve static files and fall back to index.html.
 	if staticFS != nil {
 		mux.Handle("/", spaHandler(staticFS))
 	}
 
-	return loggingMiddleware(mux)
+	return loggingMiddleware(deps.metrics, mux)
 }
 
 // registerProtectedRoutes adds all authenticated API endpoints to the mux.
+// Each route names the permission it needs; see rolePermissions and
+// scopePermissions for who has which.
 func registerProtectedRoutes(mux *http.ServeMux, deps *Deps) {
 	// Auth
-	mux.HandleFunc("POST /api/logout", handleLogout)
+	mux.HandleFunc("GET /api/whoami", require(permView, handleWhoami(deps)))
+	mux.HandleFunc("POST /api/logout", require(permView, handleLogout(deps)))
+	mux.HandleFunc("GET /api/sessions", require(permView, handleListSessions(deps)))
+	mux.HandleFunc("DELETE /api/sessions", require(permView, handleRevokeSessions(deps)))
+	mux.HandleFunc("DELETE /api/sessions/{id}", require(permView, handleRevokeSession(deps)))
+	mux.HandleFunc("GET /api/totp", require(permView, handleTOTPStatus(deps)))
+	mux.HandleFunc("POST /api/totp/enroll", require(permView, handleTOTPEnroll(deps)))
+	mux.HandleFunc("POST /api/totp/confirm", require(permView, handleTOTPConfirm(deps)))
+	mux.HandleFunc("POST /api/totp/disable", require(permView, handleTOTPDisable(deps)))
 
 	// Status & control
-	mux.HandleFunc("GET /api/status", handleStatus(deps))
-	mux.HandleFunc("POST /api/apply", handleApply(deps))
-	mux.HandleFunc("POST /api/restart", handleRestart(deps))
-	mux.HandleFunc("POST /api/stop", handleStop(deps))
+	mux.HandleFunc("GET /api/status", require(permView, handleStatus(deps)))
+	mux.HandleFunc("POST /api/apply", require(permAdmin, handleApply(deps)))
+	mux.HandleFunc("POST /api/restart", require(permAdmin, handleRestart(deps)))
+	mux.HandleFunc("POST /api/stop", require(permAdmin, handleStop(deps)))
 
 	// IPSets
-	mux.HandleFunc("POST /api/ipsets/update", handleUpdateIPsets(deps))
+	mux.HandleFunc("POST /api/ipsets/update", require(permAdmin, handleUpdateIPsets(deps)))
 
 	// Info
-	mux.HandleFunc("GET /api/ip", handleIP(deps))
-	mux.HandleFunc("GET /api/version", handleVersion(deps))
+	mux.HandleFunc("GET /api/ip", require(permView, handleIP(deps)))
+	mux.HandleFunc("GET /api/version", require(permView, handleVersion(deps)))
+	mux.HandleFunc("GET /api/diagnostics/routes", require(permView, handleDiagnosticsRoutes(deps)))
+	mux.HandleFunc("GET /api/speedtest", require(permView, handleSpeedTestHistory(deps)))
+	mux.HandleFunc("POST /api/speedtest", require(permAdmin, handleStartSpeedTest(deps)))
+	mux.HandleFunc("GET /api/speedtest/{id}", require(permView, handleGetSpeedTest(deps)))
+	mux.HandleFunc("GET /api/events", require(permView, handleEvents(deps)))
+	mux.HandleFunc("GET /api/audit", require(permAdmin, handleAudit(deps)))
+
+	// API tokens
+	mux.HandleFunc("GET /api/tokens", require(permAdmin, handleListTokens(deps)))
+	mux.HandleFunc("POST /api/tokens", require(permAdmin, handleCreateToken(deps)))
+	mux.HandleFunc("DELETE /api/tokens/{id}", require(permAdmin, handleRevokeToken(deps)))
+
+	// TLS certificate
+	mux.HandleFunc("GET /api/cert", require(permView, handleCertInfo(deps)))
+	mux.HandleFunc("GET /api/cert/ca", require(permView, handleCertCA(deps)))
+	mux.HandleFunc("POST /api/cert/generate", require(permAdmin, handleGenerateCert(deps)))
+	mux.HandleFunc("POST /api/cert", require(permAdmin, handleUploadCert(deps)))
+
+	// Local users
+	mux.HandleFunc("GET /api/users", require(permAdmin, handleListUsers(deps)))
+	mux.HandleFunc("POST /api/users", require(permAdmin, handleAddUser(deps)))
+	mux.HandleFunc("DELETE /api/users/{username}", require(permAdmin, handleRemoveUser(deps)))
+	mux.HandleFunc("PUT /api/users/{username}/password", require(permView, handleSetUserPassword(deps)))
+
+	// Login bans
+	mux.HandleFunc("GET /api/bans", require(permAdmin, handleListBans(deps)))
+	mux.HandleFunc("DELETE /api/bans", require(permAdmin, handleClearBans(deps)))
+	mux.HandleFunc("DELETE /api/bans/{id}", require(permAdmin, handleClearBan(deps)))
 
 	// Servers
-	mux.HandleFunc("GET /api/servers", handleListServers(deps))
-	mux.HandleFunc("POST /api/servers/active", handleSelectServer(deps))
-	mux.HandleFunc("POST /api/servers/import", handleImportServers(deps))
+	mux.HandleFunc("GET /api/servers", require(permView, handleListServers(deps)))
+	mux.HandleFunc("POST /api/servers/active", require(permManageServers, handleSelectServer(deps)))
+	mux.HandleFunc("POST /api/servers/import", require(permManageServers, handleImportServers(deps)))
+	mux.HandleFunc("POST /api/servers", require(permManageServers, handleAddServer(deps)))
+	mux.HandleFunc("PUT /api/servers/{id}", require(permManageServers, handleUpdateServer(deps)))
+	mux.HandleFunc("DELETE /api/servers/{id}", require(permManageServers, handleDeleteServer(deps)))
 
 	// Clients
-	mux.HandleFunc("GET /api/clients", handleListClients(deps))
-	mux.HandleFunc("POST /api/clients", handleAddClient(deps))
-	mux.HandleFunc("POST /api/clients/pause", handlePauseClient(deps))
-	mux.HandleFunc("POST /api/clients/resume", handleResumeClient(deps))
-	mux.HandleFunc("DELETE /api/clients", handleDeleteClient(deps))
+	mux.HandleFunc("GET /api/clients", require(permView, handleListClients(deps)))
+	mux.HandleFunc("GET /api/connections", require(permView, handleConnections(deps)))
+	mux.HandleFunc("POST /api/clients", require(permManageClients, handleAddClient(deps)))
+	mux.HandleFunc("POST /api/clients/pause", require(permPauseClients, handlePauseClient(deps)))
+	mux.HandleFunc("POST /api/clients/resume", require(permPauseClients, handleResumeClient(deps)))
+	mux.HandleFunc("DELETE /api/clients", require(permManageClients, handleDeleteClient(deps)))
 
 	// Exclusions — sets
-	mux.HandleFunc("GET /api/excludes/sets", handleListExcludeSets(deps))
-	mux.HandleFunc("POST /api/excludes/sets", handleUpdateExcludeSets(deps))
+	mux.HandleFunc("GET /api/excludes/sets", require(permView, handleListExcludeSets(deps)))
+	mux.HandleFunc("POST /api/excludes/sets", require(permManageClients, handleUpdateExcludeSets(deps)))
 
 	// Exclusions — IPs
-	mux.HandleFunc("GET /api/excludes/ips", handleListExcludeIPs(deps))
-	mux.HandleFunc("POST /api/excludes/ips", handleAddExcludeIP(deps))
-	mux.HandleFunc("DELETE /api/excludes/ips", handleDeleteExcludeIP(deps))
+	mux.HandleFunc("GET /api/excludes/ips", require(permView, handleListExcludeIPs(deps)))
+	mux.HandleFunc("POST /api/excludes/ips", require(permManageClients, handleAddExcludeIP(deps)))
+	mux.HandleFunc("DELETE /api/excludes/ips", require(permManageClients, handleDeleteExcludeIP(deps)))
+
+	// Address list optimization (CIDR aggregation, overlap report)
+	mux.HandleFunc("GET /api/optimize", require(permView, handlePreviewOptimize(deps)))
+	mux.HandleFunc("POST /api/optimize", require(permAdmin, handleApplyOptimize(deps)))
+
+	// Domain routing (dnsmasq ipsets)
+	mux.HandleFunc("GET /api/domains", require(permView, handleListDomains(deps)))
+	mux.HandleFunc("POST /api/domains", require(permAdmin, handleAddDomain(deps)))
+	mux.HandleFunc("DELETE /api/domains", require(permAdmin, handleDeleteDomain(deps)))
+	mux.HandleFunc("GET /api/domains/resolved", require(permView, handleResolvedDomains(deps)))
+
+	// Traffic accounting
+	mux.HandleFunc("GET /api/traffic", require(permView, handleTraffic(deps)))
+
+	// Xray access log search
+	mux.HandleFunc("GET /api/access", require(permView, handleAccess(deps)))
+	mux.HandleFunc("GET /api/access/top", require(permView, handleAccessTop(deps)))
 
 	// Logs & config
-	mux.HandleFunc("GET /api/logs", handleLogs(deps))
-	mux.HandleFunc("GET /api/config", handleConfig(deps))
+	mux.HandleFunc("GET /api/logs", require(permView, handleLogs(deps)))
+	mux.HandleFunc("GET /api/logs/stream", require(permView, handleLogStream(deps)))
+	mux.HandleFunc("GET /api/config", require(permView, handleConfig(deps)))
 
 	// Self-update
-	mux.HandleFunc("POST /api/update", handleUpdate(deps))
+	mux.HandleFunc("POST /api/update", require(permAdmin, handleUpdate(deps)))
 }
 
 // spaHandler serves static files from the embedded filesystem. If a file is
@@ -3,31 +3,38 @@ package webapi
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"io/fs"
 	"log/slog"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/netaccess"
 )
 
 // ServerConfig holds HTTP/HTTPS server configuration.
 type ServerConfig struct {
-	Port     int
-	CertFile string
-	KeyFile  string
-	DevMode  bool // when true, use plain HTTP instead of TLS
+	Port          int
+	Addrs         []string          // host:port to listen on; ":Port" when empty
+	RedirectAddrs []string          // plain-HTTP listeners redirecting to HTTPS
+	TLS           *tls.Config       // certificates via GetCertificate; see tlscert.ServerTLS
+	Access        *netaccess.Policy // allowed clients; nil allows everyone
+	DevMode       bool              // when true, use plain HTTP instead of TLS
 }
 
 // ListenAndServe starts the HTTP/HTTPS server and blocks until ctx is cancelled
 // or an unrecoverable error occurs. When cfg.DevMode is true it uses plain HTTP;
-// otherwise it requires TLS certificates. On context cancellation it performs a
+// otherwise it serves TLS with cfg.TLS. On context cancellation it performs a
 // graceful shutdown with a 5-second deadline.
 func ListenAndServe(ctx context.Context, cfg ServerConfig, deps *Deps, staticFS fs.FS) error {
 	router := NewRouter(deps, staticFS)
 
 	server := &http.Server{
-		Addr:              fmt.Sprintf(":%d", cfg.Port),
-		Handler:           router,
+		Handler:           allowlistMiddleware(cfg.Access, router),
 		ReadHeaderTimeout: 10 * time.Second,
 		ReadTimeout:       30 * time.Second,
 		WriteTimeout:      30 * time.Second,
@@ -35,29 +42,113 @@ func ListenAndServe(ctx context.Context, cfg ServerConfig, deps *Deps, staticFS
 		MaxHeaderBytes:    1 << 20, // 1 MB
 	}
 	if !cfg.DevMode {
-		server.TLSConfig = &tls.Config{
-			MinVersion: tls.VersionTLS12,
-		}
+		server.TLSConfig = cfg.TLS
 	}
+	servers := []*http.Server{server}
 
-	errCh := make(chan error, 1)
-	go func() {
-		if cfg.DevMode {
-			slog.Info("starting HTTP server (dev mode)", "addr", server.Addr)
-			errCh <- server.ListenAndServe()
-		} else {
-			slog.Info("starting HTTPS server", "addr", server.Addr)
-			errCh <- server.ListenAndServeTLS(cfg.CertFile, cfg.KeyFile)
+	addrs := cfg.Addrs
+	if len(addrs) == 0 {
+		addrs = []string{fmt.Sprintf(":%d", cfg.Port)}
+	}
+	listeners, err := listenAll(addrs)
+	if err != nil {
+		return err
+	}
+
+	var redirect *http.Server
+	var redirectListeners []net.Listener
+	if len(cfg.RedirectAddrs) > 0 && !cfg.DevMode {
+		redirectListeners, err = listenAll(cfg.RedirectAddrs)
+		if err != nil {
+			closeAll(listeners)
+			return err
 		}
-	}()
+		redirect = &http.Server{
+			Handler:           allowlistMiddleware(cfg.Access, redirectHandler(cfg.Port)),
+			ReadHeaderTimeout: 10 * time.Second,
+			IdleTimeout:       30 * time.Second,
+			MaxHeaderBytes:    1 << 20,
+		}
+		servers = append(servers, redirect)
+	}
+
+	errCh := make(chan error, len(listeners)+len(redirectListeners))
+	for _, ln := range listeners {
+		go func() {
+			if cfg.DevMode {
+				slog.Info("starting HTTP server (dev mode)", "addr", ln.Addr().String())
+				errCh <- server.Serve(ln)
+			} else {
+				slog.Info("starting HTTPS server", "addr", ln.Addr().String())
+				errCh <- server.ServeTLS(ln, "", "")
+			}
+		}()
+	}
+	for _, ln := range redirectListeners {
+		go func() {
+			slog.Info("starting HTTP redirect server", "addr", ln.Addr().String())
+			errCh <- redirect.Serve(ln)
+		}()
+	}
 
 	select {
 	case err := <-errCh:
+		shutdown(servers)
 		return err
 	case <-ctx.Done():
 		slog.Info("shutting down server")
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		return server.Shutdown(shutdownCtx)
+		return shutdown(servers)
 	}
 }
+
+// listenAll opens a TCP listener for each address.
+func listenAll(addrs []string) ([]net.Listener, error) {
+	var listeners []net.Listener
+	for _, addr := range addrs {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			closeAll(listeners)
+			return nil, err
+		}
+		listeners = append(listeners, ln)
+	}
+	return listeners, nil
+}
+
+func closeAll(listeners []net.Listener) {
+	for _, ln := range listeners {
+		ln.Close()
+	}
+}
+
+// shutdown stops the servers gracefully with a 5-second deadline.
+func shutdown(servers []*http.Server) error {
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var errs []error
+	for _, s := range servers {
+		errs = append(errs, s.Shutdown(shutdownCtx))
+	}
+	return errors.Join(errs...)
+}
+
+// redirectHandler sends plain-HTTP requests to the same host and path over
+// HTTPS on port.
+func redirectHandler(port int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		} else {
+			host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+		}
+		if host == "" {
+			if local, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+				host, _, _ = net.SplitHostPort(local.String())
+			}
+		}
+		target := "https://" + net.JoinHostPort(host, strconv.Itoa(port))
+		target = strings.TrimSuffix(target, ":443")
+		http.Redirect(w, r, target+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
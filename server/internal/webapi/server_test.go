@@ -0,0 +1,128 @@
+package webapi
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/tlscert"
+)
+
+// freeAddr returns a loopback address with a port nothing listens on.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+// waitListening waits until something accepts connections on addr.
+func waitListening(t *testing.T, addr string) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if conn, err := net.Dial("tcp", addr); err == nil {
+			conn.Close()
+			return
+		}
+	}
+	t.Fatalf("nothing listens on %s", addr)
+}
+
+func TestRedirectHandler(t *testing.T) {
+	tests := []struct {
+		host string
+		port int
+		want string
+	}{
+		{"192.168.50.1:8080", 8444, "https://192.168.50.1:8444/api/status?x=1"},
+		{"router.asus.com", 8444, "https://router.asus.com:8444/api/status?x=1"},
+		{"[2001:db8::1]:80", 8444, "https://[2001:db8::1]:8444/api/status?x=1"},
+		{"[2001:db8::1]", 443, "https://[2001:db8::1]/api/status?x=1"},
+		{"vpn.example.com:80", 443, "https://vpn.example.com/api/status?x=1"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/api/status?x=1", nil)
+		req.Host = tt.host
+		rec := httptest.NewRecorder()
+		redirectHandler(tt.port).ServeHTTP(rec, req)
+		if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != tt.want {
+			t.Errorf("%s: expected redirect to %s, got %d %s", tt.host, tt.want, rec.Code, rec.Header().Get("Location"))
+		}
+	}
+}
+
+func TestListenAndServe_TLSAndRedirect(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	if _, err := tlscert.Generate(certFile, keyFile, []string{"127.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+	reloader, err := tlscert.NewReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr, redirectAddr := freeAddr(t), freeAddr(t)
+	_, port, _ := net.SplitHostPort(addr)
+	cfg := ServerConfig{
+		Addrs:         []string{addr},
+		RedirectAddrs: []string{redirectAddr},
+		TLS:           tlscert.ServerTLS(reloader, nil, nil),
+	}
+	cfg.Port, _ = net.LookupPort("tcp", port)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- ListenAndServe(ctx, cfg, newTestDeps(t), nil) }()
+	waitListening(t, addr)
+	waitListening(t, redirectAddr)
+
+	client := &http.Client{
+		Timeout:       5 * time.Second,
+		Transport:     &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	resp, err := client.Get("http://" + redirectAddr + "/api/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if want := "https://" + addr + "/api/status"; resp.StatusCode != http.StatusMovedPermanently || resp.Header.Get("Location") != want {
+		t.Errorf("expected redirect to %s, got %d %s", want, resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	resp, err = client.Get("https://" + addr + "/api/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 over HTTPS, got %d", resp.StatusCode)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("expected a clean shutdown, got %v", err)
+	}
+}
+
+func TestListenAndServe_AddressInUse(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	cfg := ServerConfig{Addrs: []string{ln.Addr().String()}, DevMode: true}
+	if err := ListenAndServe(context.Background(), cfg, newTestDeps(t), nil); err == nil {
+		t.Error("expected an error for an address in use")
+	}
+}
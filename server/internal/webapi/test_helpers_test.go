@@ -1,12 +1,20 @@
 package webapi
 
 import (
+	"context"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/auth"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/conntrack"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/diagnostics"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/events"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/service"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/speedtest"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/traffic"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/xraystats"
 )
 
 // mockVPN implements service.VPNDirector for testing.
@@ -18,6 +26,7 @@ type mockVPN struct {
 func (m *mockVPN) Status() (string, error)  { return m.statusOutput, m.err }
 func (m *mockVPN) Apply() error             { return m.err }
 func (m *mockVPN) Restart() error           { return m.err }
+func (m *mockVPN) ApplyXray() error         { return m.err }
 func (m *mockVPN) RestartXray() error       { return m.err }
 func (m *mockVPN) Stop() error              { return m.err }
 
@@ -25,9 +34,16 @@ func (m *mockVPN) Stop() error              { return m.err }
 type mockNetwork struct {
 	ip  string
 	err error
+	via string
 }
 
-func (m *mockNetwork) GetExternalIP() (string, error) { return m.ip, m.err }
+func (m *mockNetwork) GetExternalIP(via string) (*service.IPInfo, error) {
+	m.via = via
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &service.IPInfo{IP: m.ip, Country: "NL", Provider: "ipinfo"}, nil
+}
 
 // mockLogs implements service.LogReader for testing.
 type mockLogs struct {
@@ -62,12 +78,136 @@ func (m *mockConfig) DataDir() (string, error) { return "/tmp/test-data", m.err
 func (m *mockConfig) DataDirOrDefault() string  { return "/tmp/test-data" }
 func (m *mockConfig) ScriptsDir() string        { return "/tmp/test-scripts" }
 
+func (m *mockConfig) LockConfig() (func(), error) { return func() {}, nil }
+
 // mockXray implements service.XrayGenerator for testing.
 type mockXray struct {
-	err error
+	err       error
+	generated *vpnconfig.Server
+}
+
+func (m *mockXray) GenerateConfig(s vpnconfig.Server) error {
+	m.generated = &s
+	return m.err
+}
+
+// mockDomains implements service.DomainRouter for testing.
+type mockDomains struct {
+	entries   []string
+	err       error
+	syncCalls int
+}
+
+func (m *mockDomains) Sync(_ *vpnconfig.VPNDirectorConfig) (bool, error) {
+	m.syncCalls++
+	return m.err == nil, m.err
+}
+func (m *mockDomains) Resolved(_ string) ([]string, error) { return m.entries, m.err }
+
+// mockTraffic implements traffic.Reporter for testing.
+type mockTraffic struct {
+	report *traffic.Report
+	err    error
+}
+
+func (m *mockTraffic) Report() (*traffic.Report, error) { return m.report, m.err }
+
+// mockXrayStats implements service.XrayStats for testing.
+type mockXrayStats struct {
+	summary *xraystats.Summary
+	err     error
+}
+
+func (m *mockXrayStats) Summary(_ context.Context) (*xraystats.Summary, error) {
+	return m.summary, m.err
+}
+
+// mockConnections implements conntrack.Lister for testing.
+type mockConnections struct {
+	snap  *conntrack.Snapshot
+	err   error
+	query conntrack.Query
+}
+
+func (m *mockConnections) Connections(q conntrack.Query) (*conntrack.Snapshot, error) {
+	m.query = q
+	return m.snap, m.err
+}
+
+// mockDiagnostics implements diagnostics.RouteChecker for testing.
+type mockDiagnostics struct {
+	report *diagnostics.Report
+	err    error
+}
+
+func (m *mockDiagnostics) CheckRoutes() (*diagnostics.Report, error) { return m.report, m.err }
+
+// mockSpeedTest implements speedtest.Tester for testing.
+type mockSpeedTest struct {
+	job     speedtest.Job
+	err     error
+	route   string
+	history []speedtest.Result
+	limit   int
+}
+
+func (m *mockSpeedTest) Start(route string, _ func(speedtest.Job)) (speedtest.Job, error) {
+	m.route = route
+	return m.job, m.err
+}
+
+func (m *mockSpeedTest) Job(id string) (speedtest.Job, bool) {
+	return m.job, id == m.job.ID
+}
+
+func (m *mockSpeedTest) History(limit int) ([]speedtest.Result, error) {
+	m.limit = limit
+	return m.history, m.err
+}
+
+// mockEvents implements events.Timeline for testing.
+type mockEvents struct {
+	recorded []events.Event
+	list     []events.Event
+	uptime   []events.Uptime
+	err      error
+	filter   events.Filter
+}
+
+func (m *mockEvents) Record(e events.Event) { m.recorded = append(m.recorded, e) }
+
+func (m *mockEvents) List(f events.Filter) ([]events.Event, error) {
+	m.filter = f
+	return m.list, m.err
+}
+
+func (m *mockEvents) Uptime(_, _ time.Time) ([]events.Uptime, error) { return m.uptime, m.err }
+
+// mockXrayConfig implements service.XrayConfigReader for testing.
+type mockXrayConfig struct {
+	server *vpnconfig.Server
+	err    error
+}
+
+func (m *mockXrayConfig) ActiveServer() (*vpnconfig.Server, error) { return m.server, m.err }
+
+// mockSystem implements service.SystemInfo for testing.
+type mockSystem struct {
+	running bool
+	ipsets  map[string]int
+	err     error
+}
+
+func (m *mockSystem) ProcessRunning(_ string) (bool, error) { return m.running, m.err }
+func (m *mockSystem) IPSetCounts() (map[string]int, error)  { return m.ipsets, m.err }
+
+// mockUpdates implements UpdateStatus for testing.
+type mockUpdates struct {
+	latest    string
+	available bool
 }
 
-func (m *mockXray) GenerateConfig(_ vpnconfig.Server) error { return m.err }
+func (m *mockUpdates) Status() (string, bool, bool) { return m.latest, m.available, true }
 
 // mockShadow implements password verification for testing.
 // It acts as a thin wrapper that allows tests to control Verify results.
@@ -101,11 +241,15 @@ func newTestDeps(t *testing.T) *Deps {
 		Xray:         &mockXray{},
 		Network:      &mockNetwork{ip: "203.0.113.42"},
 		Logs:         &mockLogs{output: "log line 1\nlog line 2"},
+		Domains:      &mockDomains{},
+		Traffic:      &mockTraffic{report: &traffic.Report{}},
 		Shadow:       shadow,
 		JWT:          jwt,
 		Version:      "1.0.0-test",
 		Commit:       "abc1234",
 		OpMutex:      &sync.Mutex{},
 		loginLimiter: newRateLimiter(5, 1*time.Minute, 30*time.Second),
+		challenges:   newLoginChallenges(),
+		tgLogins:     newTelegramLogins(),
 	}
 }
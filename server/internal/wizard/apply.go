@@ -51,6 +51,13 @@ func (a *Applier) Apply(chatID int64, state *State) error {
 
 	a.sender.SendPlain(chatID, "Applying configuration...")
 
+	unlock, err := a.config.LockConfig()
+	if err != nil {
+		a.sender.SendPlain(chatID, fmt.Sprintf("Config lock error: %v", err))
+		return err
+	}
+	defer unlock()
+
 	// Load current config
 	vpnCfg, err := a.config.LoadVPNConfig()
 	if err != nil {
@@ -68,7 +75,7 @@ func (a *Applier) Apply(chatID int64, state *State) error {
 	// Get state data with thread-safe getters
 	clients := state.GetClients()
 	exclusions := state.GetExclusions()
-	serverIndex := state.GetServerIndex()
+	serverIndex := vpnconfig.FindServer(servers, state.GetServerID())
 
 	// Build exclusion list (sorted for deterministic config)
 	var excl []string
@@ -118,18 +125,21 @@ func (a *Applier) Apply(chatID int64, state *State) error {
 		}
 	}
 
-	// Server IPs (unique, non-empty, sorted) — collect ALL IPs from ALL servers
-	seen := make(map[string]bool)
-	var serverIPs []string
-	for _, s := range servers {
-		for _, ip := range s.IPs {
-			if ip != "" && !seen[ip] {
-				seen[ip] = true
-				serverIPs = append(serverIPs, ip)
-			}
+	// Keep domain lists: the wizard does not edit them
+	for name, old := range vpnCfg.TunnelDirector.Tunnels {
+		if len(old.Domains) == 0 {
+			continue
+		}
+		tunnel, ok := tunnels[name]
+		if !ok {
+			tunnel = vpnconfig.TunnelConfig{Clients: []string{}, Exclude: excl}
 		}
+		tunnel.Domains = old.Domains
+		tunnels[name] = tunnel
 	}
-	sort.Strings(serverIPs)
+
+	// Server IPs (unique, non-empty, sorted) — collect ALL IPs from ALL servers
+	serverIPs := vpnconfig.ServerIPs(servers)
 
 	// Exclude IPs from wizard state
 	excludeIPs := state.GetExcludeIPs()
@@ -149,7 +159,7 @@ func (a *Applier) Apply(chatID int64, state *State) error {
 	a.sender.SendPlain(chatID, "vpn-director.json updated")
 
 	// Generate Xray config if server index is valid
-	if serverIndex >= 0 && serverIndex < len(servers) {
+	if serverIndex >= 0 {
 		s := servers[serverIndex]
 		if err := a.xray.GenerateConfig(s); err != nil {
 			a.sender.SendPlain(chatID, fmt.Sprintf("Xray config generation error: %v", err))
@@ -22,6 +22,8 @@ func (m *mockVPNDirector) Apply() error {
 	return m.applyErr
 }
 func (m *mockVPNDirector) Restart() error        { return nil }
+func (m *mockVPNDirector) ApplyXray() error { return nil }
+
 func (m *mockVPNDirector) RestartXray() error {
 	m.restartXrayCalled = true
 	return m.restartXrayErr
@@ -81,6 +83,8 @@ func (m *trackingConfigStore) ScriptsDir() string {
 	return "/opt/vpn-director"
 }
 
+func (m *trackingConfigStore) LockConfig() (func(), error) { return func() {}, nil }
+
 // trackingSender extends mockSender to track messages
 type trackingSender struct {
 	messages []string
@@ -136,8 +140,8 @@ func TestApplier_Apply_Success(t *testing.T) {
 		sender := &trackingSender{}
 		configStore := &trackingConfigStore{
 			servers: []vpnconfig.Server{
-				{Name: "Server1", IPs: []string{"1.2.3.4"}, Address: "srv1.example.com", Port: 443, UUID: "uuid-1"},
-				{Name: "Server2", IPs: []string{"5.6.7.8"}, Address: "srv2.example.com", Port: 443, UUID: "uuid-2"},
+				{ID: "srv1", Name: "Server1", IPs: []string{"1.2.3.4"}, Address: "srv1.example.com", Port: 443, UUID: "uuid-1"},
+				{ID: "srv2", Name: "Server2", IPs: []string{"5.6.7.8"}, Address: "srv2.example.com", Port: 443, UUID: "uuid-2"},
 			},
 			vpnConfig: &vpnconfig.VPNDirectorConfig{
 				DataDir: "/opt/vpn-director/data",
@@ -155,7 +159,7 @@ func TestApplier_Apply_Success(t *testing.T) {
 		state := &State{
 			ChatID:      123,
 			Step:        StepConfirm,
-			ServerIndex: 0,
+			ServerID:    "srv1",
 			Exclusions:  map[string]bool{"ru": true, "by": true},
 			Clients: []ClientRoute{
 				{IP: "192.168.1.10", Route: "xray"},
@@ -230,7 +234,7 @@ func TestApplier_Apply_DefaultExclusions(t *testing.T) {
 		sender := &trackingSender{}
 		configStore := &trackingConfigStore{
 			servers: []vpnconfig.Server{
-				{Name: "Server1", IPs: []string{"1.2.3.4"}},
+				{ID: "srv1", Name: "Server1", IPs: []string{"1.2.3.4"}},
 			},
 			vpnConfig: &vpnconfig.VPNDirectorConfig{
 				DataDir: "/opt/vpn-director/data",
@@ -248,7 +252,7 @@ func TestApplier_Apply_DefaultExclusions(t *testing.T) {
 		state := &State{
 			ChatID:      123,
 			Step:        StepConfirm,
-			ServerIndex: 0,
+			ServerID:    "srv1",
 			Exclusions:  map[string]bool{}, // Empty
 			Clients: []ClientRoute{
 				{IP: "192.168.1.10", Route: "wgc1"},
@@ -308,7 +312,7 @@ func TestApplier_Apply_ClearsStateOnError(t *testing.T) {
 		sender := &trackingSender{}
 		configStore := &trackingConfigStore{
 			servers: []vpnconfig.Server{
-				{Name: "Server1", IPs: []string{"1.2.3.4"}},
+				{ID: "srv1", Name: "Server1", IPs: []string{"1.2.3.4"}},
 			},
 			vpnConfig: &vpnconfig.VPNDirectorConfig{
 				DataDir: "/opt/vpn-director/data",
@@ -328,7 +332,7 @@ func TestApplier_Apply_ClearsStateOnError(t *testing.T) {
 		state := &State{
 			ChatID:      123,
 			Step:        StepConfirm,
-			ServerIndex: 0,
+			ServerID:    "srv1",
 			Exclusions:  map[string]bool{"ru": true},
 			Clients:     []ClientRoute{},
 		}
@@ -353,9 +357,9 @@ func TestApplier_Apply_ServerIPs(t *testing.T) {
 		sender := &trackingSender{}
 		configStore := &trackingConfigStore{
 			servers: []vpnconfig.Server{
-				{Name: "Server1", IPs: []string{"5.6.7.8"}},
-				{Name: "Server2", IPs: []string{"1.2.3.4"}},
-				{Name: "Server3", IPs: []string{"5.6.7.8"}}, // Duplicate
+				{ID: "srv1", Name: "Server1", IPs: []string{"5.6.7.8"}},
+				{ID: "srv2", Name: "Server2", IPs: []string{"1.2.3.4"}},
+				{ID: "srv3", Name: "Server3", IPs: []string{"5.6.7.8"}}, // Duplicate
 			},
 			vpnConfig: &vpnconfig.VPNDirectorConfig{
 				DataDir: "/opt/vpn-director/data",
@@ -373,7 +377,7 @@ func TestApplier_Apply_ServerIPs(t *testing.T) {
 		state := &State{
 			ChatID:      123,
 			Step:        StepConfirm,
-			ServerIndex: 0,
+			ServerID:    "srv1",
 			Exclusions:  map[string]bool{"ru": true},
 			Clients:     []ClientRoute{},
 		}
@@ -399,7 +403,7 @@ func TestApplier_Apply_MultipleClientsToSameTunnel(t *testing.T) {
 		sender := &trackingSender{}
 		configStore := &trackingConfigStore{
 			servers: []vpnconfig.Server{
-				{Name: "Server1", IPs: []string{"1.2.3.4"}},
+				{ID: "srv1", Name: "Server1", IPs: []string{"1.2.3.4"}},
 			},
 			vpnConfig: &vpnconfig.VPNDirectorConfig{
 				DataDir: "/opt/vpn-director/data",
@@ -417,7 +421,7 @@ func TestApplier_Apply_MultipleClientsToSameTunnel(t *testing.T) {
 		state := &State{
 			ChatID:      123,
 			Step:        StepConfirm,
-			ServerIndex: 0,
+			ServerID:    "srv1",
 			Exclusions:  map[string]bool{"ru": true},
 			Clients: []ClientRoute{
 				{IP: "192.168.1.10", Route: "wgc1"},
@@ -443,13 +447,54 @@ func TestApplier_Apply_MultipleClientsToSameTunnel(t *testing.T) {
 	})
 }
 
-func TestApplier_Apply_SkipsXrayGenForInvalidServerIndex(t *testing.T) {
-	t.Run("skips Xray config generation for invalid server index", func(t *testing.T) {
+func TestApplier_Apply_PreservesTunnelDomains(t *testing.T) {
+	manager := &trackingManager{}
+	sender := &trackingSender{}
+	configStore := &trackingConfigStore{
+		servers: []vpnconfig.Server{
+			{ID: "srv1", Name: "Server1", IPs: []string{"1.2.3.4"}},
+		},
+		vpnConfig: &vpnconfig.VPNDirectorConfig{
+			DataDir: "/opt/vpn-director/data",
+			TunnelDirector: vpnconfig.TunnelDirectorConfig{
+				Tunnels: map[string]vpnconfig.TunnelConfig{
+					"wgc1":   {Clients: []string{"192.168.1.5/32"}, Domains: []string{"netflix.com"}},
+					"ovpnc1": {Clients: []string{}, Domains: []string{"bbc.co.uk"}},
+				},
+			},
+		},
+	}
+
+	applier := NewApplier(manager, sender, configStore, &mockVPNDirector{}, &mockXrayGenerator{})
+
+	state := &State{
+		ChatID:      123,
+		Step:        StepConfirm,
+		ServerID:    "srv1",
+		Exclusions:  map[string]bool{"ru": true},
+		Clients: []ClientRoute{
+			{IP: "192.168.1.10", Route: "wgc1"},
+		},
+	}
+
+	_ = applier.Apply(123, state)
+
+	tunnels := configStore.savedConfig.TunnelDirector.Tunnels
+	if got := tunnels["wgc1"]; len(got.Clients) != 1 || len(got.Domains) != 1 || got.Domains[0] != "netflix.com" {
+		t.Errorf("expected wgc1 with new client and kept domain, got %+v", got)
+	}
+	if got := tunnels["ovpnc1"]; len(got.Clients) != 0 || len(got.Domains) != 1 {
+		t.Errorf("expected domain-only ovpnc1 to be kept, got %+v", got)
+	}
+}
+
+func TestApplier_Apply_SkipsXrayGenForUnknownServerID(t *testing.T) {
+	t.Run("skips Xray config generation for unknown server ID", func(t *testing.T) {
 		manager := &trackingManager{}
 		sender := &trackingSender{}
 		configStore := &trackingConfigStore{
 			servers: []vpnconfig.Server{
-				{Name: "Server1", IPs: []string{"1.2.3.4"}},
+				{ID: "srv1", Name: "Server1", IPs: []string{"1.2.3.4"}},
 			},
 			vpnConfig: &vpnconfig.VPNDirectorConfig{
 				DataDir: "/opt/vpn-director/data",
@@ -467,7 +512,7 @@ func TestApplier_Apply_SkipsXrayGenForInvalidServerIndex(t *testing.T) {
 		state := &State{
 			ChatID:      123,
 			Step:        StepConfirm,
-			ServerIndex: 5, // Out of bounds
+			ServerID:    "srv6", // Not in servers.json
 			Exclusions:  map[string]bool{"ru": true},
 			Clients:     []ClientRoute{},
 		}
@@ -476,12 +521,12 @@ func TestApplier_Apply_SkipsXrayGenForInvalidServerIndex(t *testing.T) {
 
 		// Xray generation should be skipped
 		if xrayGen.generateCalled {
-			t.Error("expected GenerateConfig NOT to be called for invalid index")
+			t.Error("expected GenerateConfig NOT to be called for unknown server ID")
 		}
 
 		// VPN apply should still be called
 		if !vpnDirector.applyCalled {
-			t.Error("expected Apply to be called even with invalid server index")
+			t.Error("expected Apply to be called even with unknown server ID")
 		}
 
 		// Should have warning message about invalid server
@@ -504,7 +549,7 @@ func TestApplier_Apply_XrayGenerateError(t *testing.T) {
 		sender := &trackingSender{}
 		configStore := &trackingConfigStore{
 			servers: []vpnconfig.Server{
-				{Name: "Server1", IPs: []string{"1.2.3.4"}},
+				{ID: "srv1", Name: "Server1", IPs: []string{"1.2.3.4"}},
 			},
 			vpnConfig: &vpnconfig.VPNDirectorConfig{
 				DataDir: "/opt/vpn-director/data",
@@ -524,7 +569,7 @@ func TestApplier_Apply_XrayGenerateError(t *testing.T) {
 		state := &State{
 			ChatID:      123,
 			Step:        StepConfirm,
-			ServerIndex: 0,
+			ServerID:    "srv1",
 			Exclusions:  map[string]bool{"ru": true},
 			Clients:     []ClientRoute{},
 		}
@@ -566,7 +611,7 @@ func TestApplier_Apply_SaveConfigError(t *testing.T) {
 		sender := &trackingSender{}
 		configStore := &trackingConfigStore{
 			servers: []vpnconfig.Server{
-				{Name: "Server1", IPs: []string{"1.2.3.4"}},
+				{ID: "srv1", Name: "Server1", IPs: []string{"1.2.3.4"}},
 			},
 			vpnConfig: &vpnconfig.VPNDirectorConfig{
 				DataDir: "/opt/vpn-director/data",
@@ -585,7 +630,7 @@ func TestApplier_Apply_SaveConfigError(t *testing.T) {
 		state := &State{
 			ChatID:      123,
 			Step:        StepConfirm,
-			ServerIndex: 0,
+			ServerID:    "srv1",
 			Exclusions:  map[string]bool{"ru": true},
 			Clients:     []ClientRoute{},
 		}
@@ -627,7 +672,7 @@ func TestApplier_Apply_RestartXrayError(t *testing.T) {
 		sender := &trackingSender{}
 		configStore := &trackingConfigStore{
 			servers: []vpnconfig.Server{
-				{Name: "Server1", IPs: []string{"1.2.3.4"}},
+				{ID: "srv1", Name: "Server1", IPs: []string{"1.2.3.4"}},
 			},
 			vpnConfig: &vpnconfig.VPNDirectorConfig{
 				DataDir: "/opt/vpn-director/data",
@@ -647,7 +692,7 @@ func TestApplier_Apply_RestartXrayError(t *testing.T) {
 		state := &State{
 			ChatID:      123,
 			Step:        StepConfirm,
-			ServerIndex: 0,
+			ServerID:    "srv1",
 			Exclusions:  map[string]bool{"ru": true},
 			Clients:     []ClientRoute{},
 		}
@@ -682,7 +727,7 @@ func TestApplier_Apply_ExclusionsSorted(t *testing.T) {
 		sender := &trackingSender{}
 		configStore := &trackingConfigStore{
 			servers: []vpnconfig.Server{
-				{Name: "Server1", IPs: []string{"1.2.3.4"}},
+				{ID: "srv1", Name: "Server1", IPs: []string{"1.2.3.4"}},
 			},
 			vpnConfig: &vpnconfig.VPNDirectorConfig{
 				DataDir: "/opt/vpn-director/data",
@@ -700,7 +745,7 @@ func TestApplier_Apply_ExclusionsSorted(t *testing.T) {
 		state := &State{
 			ChatID:      123,
 			Step:        StepConfirm,
-			ServerIndex: 0,
+			ServerID:    "srv1",
 			Exclusions:  map[string]bool{"ua": true, "by": true, "ru": true},
 			Clients: []ClientRoute{
 				{IP: "192.168.1.10", Route: "wgc1"},
@@ -728,9 +773,9 @@ func TestApplier_Apply_FiltersEmptyServerIPs(t *testing.T) {
 		sender := &trackingSender{}
 		configStore := &trackingConfigStore{
 			servers: []vpnconfig.Server{
-				{Name: "Server1", IPs: []string{"1.2.3.4"}},
-				{Name: "Server2", IPs: nil},         // No IPs
-				{Name: "Server3", IPs: []string{"5.6.7.8"}},
+				{ID: "srv1", Name: "Server1", IPs: []string{"1.2.3.4"}},
+				{ID: "srv2", Name: "Server2", IPs: nil},         // No IPs
+				{ID: "srv3", Name: "Server3", IPs: []string{"5.6.7.8"}},
 			},
 			vpnConfig: &vpnconfig.VPNDirectorConfig{
 				DataDir: "/opt/vpn-director/data",
@@ -748,7 +793,7 @@ func TestApplier_Apply_FiltersEmptyServerIPs(t *testing.T) {
 		state := &State{
 			ChatID:      123,
 			Step:        StepConfirm,
-			ServerIndex: 0,
+			ServerID:    "srv1",
 			Exclusions:  map[string]bool{"ru": true},
 			Clients:     []ClientRoute{},
 		}
@@ -768,7 +813,7 @@ func TestApplier_Apply_SkipsInvalidRoutes(t *testing.T) {
 		sender := &trackingSender{}
 		configStore := &trackingConfigStore{
 			servers: []vpnconfig.Server{
-				{Name: "Server1", IPs: []string{"1.2.3.4"}},
+				{ID: "srv1", Name: "Server1", IPs: []string{"1.2.3.4"}},
 			},
 			vpnConfig: &vpnconfig.VPNDirectorConfig{
 				DataDir: "/opt/vpn-director/data",
@@ -786,7 +831,7 @@ func TestApplier_Apply_SkipsInvalidRoutes(t *testing.T) {
 		state := &State{
 			ChatID:      123,
 			Step:        StepConfirm,
-			ServerIndex: 0,
+			ServerID:    "srv1",
 			Exclusions:  map[string]bool{"ru": true},
 			Clients: []ClientRoute{
 				{IP: "192.168.1.10", Route: "xray"},
@@ -2,6 +2,7 @@ package wizard
 
 import (
 	"fmt"
+	"net"
 	"strconv"
 	"strings"
 
@@ -157,8 +158,14 @@ func isValidRoute(route string) bool {
 	return false
 }
 
-// isValidLANIP validates that the IP is a valid LAN (private) IP address
+// isValidLANIP validates that the IP is a valid LAN (private) IPv4 address
+// or a unicast IPv6 address (LAN hosts usually get global IPv6 from the ISP)
 func isValidLANIP(ip string) bool {
+	if strings.Contains(ip, ":") {
+		parsed := net.ParseIP(ip)
+		return parsed != nil && parsed.To4() == nil && parsed.IsGlobalUnicast()
+	}
+
 	parts := strings.Split(ip, ".")
 	if len(parts) != 4 {
 		return false
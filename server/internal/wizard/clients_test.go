@@ -39,6 +39,14 @@ func TestIsValidLANIP(t *testing.T) {
 		{"1.1.1.1", "1.1.1.1", false},
 		{"203.0.113.1", "203.0.113.1", false},
 
+		// IPv6: ULA and global unicast are valid LAN clients
+		{"ula", "fd00::10", true},
+		{"global", "2001:db8::10", true},
+		{"link-local", "fe80::1", false},
+		{"loopback", "::1", false},
+		{"multicast", "ff02::1", false},
+		{"ipv4-mapped", "::ffff:192.168.1.1", false},
+
 		// Malformed IPs
 		{"not an ip", "not an ip", false},
 		{"192.168.1", "192.168.1", false},
@@ -7,6 +7,7 @@ import (
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/telegram"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
 )
 
 // ConfirmStep handles Step 4: confirmation before applying
@@ -29,7 +30,7 @@ func (s *ConfirmStep) Render(chatID int64, state *State) {
 		return
 	}
 
-	serverIndex := state.GetServerIndex()
+	serverIndex := vpnconfig.FindServer(servers, state.GetServerID())
 	exclusions := state.GetExclusions()
 	clients := state.GetClients()
 
@@ -37,9 +38,9 @@ func (s *ConfirmStep) Render(chatID int64, state *State) {
 	sb.WriteString(telegram.EscapeMarkdownV2("Step 4/4: Confirmation") + "\n\n")
 
 	// Show selected server
-	if serverIndex >= 0 && serverIndex < len(servers) {
+	if serverIndex >= 0 {
 		srv := servers[serverIndex]
-		sb.WriteString(telegram.EscapeMarkdownV2(fmt.Sprintf("Xray server: %s (%s)", srv.Name, strings.Join(srv.IPs, ", "))) + "\n")
+		sb.WriteString(telegram.EscapeMarkdownV2(fmt.Sprintf("Xray server: %s (%s)", srv.Name, strings.Join(srv.AllIPs(), ", "))) + "\n")
 	}
 
 	// Show exclusions (sorted alphabetically)
@@ -13,8 +13,8 @@ func TestConfirmStep_Render(t *testing.T) {
 		sender := &mockSender{}
 		configStore := &mockConfigStore{
 			servers: []vpnconfig.Server{
-				{Name: "Server1", IPs: []string{"1.2.3.4"}},
-				{Name: "Server2", IPs: []string{"5.6.7.8"}},
+				{ID: "srv1", Name: "Server1", IPs: []string{"1.2.3.4"}},
+				{ID: "srv2", Name: "Server2", IPs: []string{"5.6.7.8"}},
 			},
 		}
 
@@ -26,10 +26,10 @@ func TestConfirmStep_Render(t *testing.T) {
 		step := NewConfirmStep(deps)
 
 		state := &State{
-			ChatID:      123,
-			Step:        StepConfirm,
-			ServerIndex: 1,
-			Exclusions:  map[string]bool{"ru": true, "by": true, "ua": false},
+			ChatID:     123,
+			Step:       StepConfirm,
+			ServerID:   "srv2",
+			Exclusions: map[string]bool{"ru": true, "by": true, "ua": false},
 			Clients: []ClientRoute{
 				{IP: "192.168.1.10", Route: "xray"},
 				{IP: "192.168.1.20", Route: "wgc1"},
@@ -97,7 +97,7 @@ func TestConfirmStep_Render_NoClients(t *testing.T) {
 		sender := &mockSender{}
 		configStore := &mockConfigStore{
 			servers: []vpnconfig.Server{
-				{Name: "Server1", IPs: []string{"1.2.3.4"}},
+				{ID: "srv1", Name: "Server1", IPs: []string{"1.2.3.4"}},
 			},
 		}
 
@@ -109,11 +109,11 @@ func TestConfirmStep_Render_NoClients(t *testing.T) {
 		step := NewConfirmStep(deps)
 
 		state := &State{
-			ChatID:      123,
-			Step:        StepConfirm,
-			ServerIndex: 0,
-			Exclusions:  map[string]bool{"ru": true},
-			Clients:     []ClientRoute{}, // No clients
+			ChatID:     123,
+			Step:       StepConfirm,
+			ServerID:   "srv1",
+			Exclusions: map[string]bool{"ru": true},
+			Clients:    []ClientRoute{}, // No clients
 		}
 
 		step.Render(123, state)
@@ -130,7 +130,7 @@ func TestConfirmStep_Render_NoExclusions(t *testing.T) {
 		sender := &mockSender{}
 		configStore := &mockConfigStore{
 			servers: []vpnconfig.Server{
-				{Name: "Server1", IPs: []string{"1.2.3.4"}},
+				{ID: "srv1", Name: "Server1", IPs: []string{"1.2.3.4"}},
 			},
 		}
 
@@ -142,10 +142,10 @@ func TestConfirmStep_Render_NoExclusions(t *testing.T) {
 		step := NewConfirmStep(deps)
 
 		state := &State{
-			ChatID:      123,
-			Step:        StepConfirm,
-			ServerIndex: 0,
-			Exclusions:  map[string]bool{}, // No exclusions
+			ChatID:     123,
+			Step:       StepConfirm,
+			ServerID:   "srv1",
+			Exclusions: map[string]bool{}, // No exclusions
 			Clients: []ClientRoute{
 				{IP: "192.168.1.10", Route: "xray"},
 			},
@@ -173,7 +173,7 @@ func TestConfirmStep_Render_AllExclusionsFalse(t *testing.T) {
 		sender := &mockSender{}
 		configStore := &mockConfigStore{
 			servers: []vpnconfig.Server{
-				{Name: "Server1", IPs: []string{"1.2.3.4"}},
+				{ID: "srv1", Name: "Server1", IPs: []string{"1.2.3.4"}},
 			},
 		}
 
@@ -185,10 +185,10 @@ func TestConfirmStep_Render_AllExclusionsFalse(t *testing.T) {
 		step := NewConfirmStep(deps)
 
 		state := &State{
-			ChatID:      123,
-			Step:        StepConfirm,
-			ServerIndex: 0,
-			Exclusions:  map[string]bool{"ru": false, "by": false},
+			ChatID:     123,
+			Step:       StepConfirm,
+			ServerID:   "srv1",
+			Exclusions: map[string]bool{"ru": false, "by": false},
 			Clients: []ClientRoute{
 				{IP: "192.168.1.10", Route: "xray"},
 			},
@@ -203,12 +203,12 @@ func TestConfirmStep_Render_AllExclusionsFalse(t *testing.T) {
 	})
 }
 
-func TestConfirmStep_Render_InvalidServerIndex(t *testing.T) {
-	t.Run("handles server index out of bounds gracefully", func(t *testing.T) {
+func TestConfirmStep_Render_UnknownServerID(t *testing.T) {
+	t.Run("handles unknown server ID gracefully", func(t *testing.T) {
 		sender := &mockSender{}
 		configStore := &mockConfigStore{
 			servers: []vpnconfig.Server{
-				{Name: "Server1", IPs: []string{"1.2.3.4"}},
+				{ID: "srv1", Name: "Server1", IPs: []string{"1.2.3.4"}},
 			},
 		}
 
@@ -220,11 +220,11 @@ func TestConfirmStep_Render_InvalidServerIndex(t *testing.T) {
 		step := NewConfirmStep(deps)
 
 		state := &State{
-			ChatID:      123,
-			Step:        StepConfirm,
-			ServerIndex: 5, // Out of bounds
-			Exclusions:  map[string]bool{},
-			Clients:     []ClientRoute{},
+			ChatID:     123,
+			Step:       StepConfirm,
+			ServerID:   "srv6", // Not in servers.json
+			Exclusions: map[string]bool{},
+			Clients:    []ClientRoute{},
 		}
 
 		step.Render(123, state)
@@ -377,7 +377,7 @@ func TestConfirmStep_ExclusionsSorted(t *testing.T) {
 		sender := &mockSender{}
 		configStore := &mockConfigStore{
 			servers: []vpnconfig.Server{
-				{Name: "Server1", IPs: []string{"1.2.3.4"}},
+				{ID: "srv1", Name: "Server1", IPs: []string{"1.2.3.4"}},
 			},
 		}
 
@@ -389,11 +389,11 @@ func TestConfirmStep_ExclusionsSorted(t *testing.T) {
 		step := NewConfirmStep(deps)
 
 		state := &State{
-			ChatID:      123,
-			Step:        StepConfirm,
-			ServerIndex: 0,
-			Exclusions:  map[string]bool{"ua": true, "by": true, "ru": true},
-			Clients:     []ClientRoute{},
+			ChatID:     123,
+			Step:       StepConfirm,
+			ServerID:   "srv1",
+			Exclusions: map[string]bool{"ua": true, "by": true, "ru": true},
+			Clients:    []ClientRoute{},
 		}
 
 		step.Render(123, state)
@@ -2,10 +2,10 @@ package wizard
 
 import (
 	"fmt"
-	"net"
 	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/cidrset"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/telegram"
 )
 
@@ -68,21 +68,10 @@ func (s *ExcludeIPsStep) HandleMessage(msg *tgbotapi.Message, state *State) bool
 		return false
 	}
 
-	if !IsValidIPOrCIDR(input) {
-		s.deps.Sender.SendPlain(msg.Chat.ID,
-			"Invalid format. Enter IPv4 address (1.2.3.4) or CIDR (10.0.0.0/8):")
+	if reply, ok := AddExcludeInput(state, input); !ok {
+		s.deps.Sender.SendPlain(msg.Chat.ID, reply)
 		return true
 	}
-
-	// Check for duplicate
-	for _, existing := range state.GetExcludeIPs() {
-		if existing == input {
-			s.deps.Sender.SendPlain(msg.Chat.ID, "This IP/CIDR is already in the list")
-			return true
-		}
-	}
-
-	state.AddExcludeIP(input)
 	s.Render(msg.Chat.ID, state)
 	return true
 }
@@ -117,15 +106,22 @@ func (s *ExcludeIPsStep) buildUI(state *State) (string, tgbotapi.InlineKeyboardM
 	return sb.String(), kb.Build()
 }
 
-// IsValidIPOrCIDR validates input as IPv4 or IPv4 CIDR
-func IsValidIPOrCIDR(s string) bool {
-	if strings.Contains(s, "/") {
-		ip, _, err := net.ParseCIDR(s)
-		if err != nil {
-			return false
-		}
-		return ip.To4() != nil
+// AddExcludeInput adds an IP or CIDR typed by the user to the state's
+// exclude list in canonical form. When the input is invalid or the list
+// already covers it, the list is unchanged and the reply to send is returned.
+func AddExcludeInput(state *State, input string) (string, bool) {
+	prefix, err := cidrset.Parse(input)
+	if err != nil {
+		return "Invalid format. Enter IPv4/IPv6 address (1.2.3.4, 2001:db8::1) or CIDR (10.0.0.0/8):", false
+	}
+	ips := state.GetExcludeIPs()
+	added, err := cidrset.Add(ips, prefix)
+	if err != nil {
+		return err.Error(), false
+	}
+	if len(added) == len(ips) {
+		return "This IP/CIDR is already in the list", false
 	}
-	ip := net.ParseIP(s)
-	return ip != nil && ip.To4() != nil
+	state.AddExcludeIP(cidrset.Format(prefix))
+	return "", true
 }
@@ -2,7 +2,7 @@ package wizard
 
 import "testing"
 
-func TestIsValidIPOrCIDR(t *testing.T) {
+func TestAddExcludeInput(t *testing.T) {
 	tests := []struct {
 		input string
 		valid bool
@@ -13,15 +13,41 @@ func TestIsValidIPOrCIDR(t *testing.T) {
 		{"not-an-ip", false},
 		{"256.1.1.1", false},
 		{"", false},
-		{"::1", false},
-		{"::1/128", false},
-		{"2001:db8::/32", false},
+		{"::1", true},
+		{"::1/128", true},
+		{"2001:db8::/32", true},
+		{"2001:db8::/129", false},
+		{"fe80::1%eth0", false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.input, func(t *testing.T) {
-			if got := IsValidIPOrCIDR(tt.input); got != tt.valid {
-				t.Errorf("IsValidIPOrCIDR(%q) = %v, want %v", tt.input, got, tt.valid)
+			state := &State{}
+			if _, ok := AddExcludeInput(state, tt.input); ok != tt.valid {
+				t.Errorf("AddExcludeInput(%q) = %v, want %v", tt.input, ok, tt.valid)
 			}
 		})
 	}
 }
+
+func TestAddExcludeInput_Canonical(t *testing.T) {
+	state := &State{}
+	if _, ok := AddExcludeInput(state, "10.1.2.3/16"); !ok {
+		t.Fatal("expected the prefix to be added")
+	}
+	if got := state.GetExcludeIPs(); len(got) != 1 || got[0] != "10.1.0.0/16" {
+		t.Errorf("expected the canonical prefix, got %v", got)
+	}
+
+	tests := map[string]string{
+		"10.1.0.0/16": "This IP/CIDR is already in the list",
+		"10.1.5.5":    "10.1.5.5 is already covered by 10.1.0.0/16",
+	}
+	for input, want := range tests {
+		if reply, ok := AddExcludeInput(state, input); ok || reply != want {
+			t.Errorf("AddExcludeInput(%q) = %q, %v, want %q", input, reply, ok, want)
+		}
+	}
+	if got := state.GetExcludeIPs(); len(got) != 1 {
+		t.Errorf("expected the list unchanged, got %v", got)
+	}
+}
@@ -2,6 +2,7 @@ package wizard
 
 import (
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/audit"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/service"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/telegram"
 )
@@ -12,14 +13,18 @@ type Handler struct {
 	steps   map[Step]StepHandler
 	sender  telegram.MessageSender
 	applier *Applier
+	config  service.ConfigStore
+	audit   audit.Recorder // nil disables recording
 }
 
-// NewHandler creates a new wizard Handler
+// NewHandler creates a new wizard Handler. Applied configurations are
+// recorded in rec unless it is nil.
 func NewHandler(
 	sender telegram.MessageSender,
 	config service.ConfigStore,
 	vpn service.VPNDirector,
 	xray service.XrayGenerator,
+	rec audit.Recorder,
 ) *Handler {
 	deps := &StepDeps{Sender: sender, Config: config}
 	manager := NewManager()
@@ -68,6 +73,8 @@ func NewHandler(
 		},
 		sender:  sender,
 		applier: NewApplier(manager, sender, config, vpn, xray),
+		config:  config,
+		audit:   rec,
 	}
 }
 
@@ -106,7 +113,22 @@ func (h *Handler) HandleCallback(cb *tgbotapi.CallbackQuery) {
 	}
 
 	if data == "apply" {
-		h.applier.Apply(chatID, state)
+		before := h.configSummary()
+		err := h.applier.Apply(chatID, state)
+		if h.audit != nil {
+			e := audit.Entry{
+				Source: audit.SourceBot,
+				Actor:  telegram.UserRef(cb.From),
+				Action: "configure",
+				Target: state.GetServerID(),
+				Before: before,
+				After:  h.configSummary(),
+			}
+			if err != nil {
+				e.Error = err.Error()
+			}
+			h.audit.Record(e)
+		}
 		return
 	}
 
@@ -134,4 +156,21 @@ func (h *Handler) HandleTextInput(msg *tgbotapi.Message) {
 	if step, ok := h.steps[currentStep]; ok {
 		step.HandleMessage(msg, state)
 	}
+}
+
+// configSummary returns the settings the wizard replaces, for the audit log
+func (h *Handler) configSummary() any {
+	cfg, err := h.config.LoadVPNConfig()
+	if err != nil {
+		return nil
+	}
+	clients := map[string][]string{"xray": cfg.Xray.Clients}
+	for name, tunnel := range cfg.TunnelDirector.Tunnels {
+		clients[name] = tunnel.Clients
+	}
+	return map[string]any{
+		"clients":      clients,
+		"exclude_sets": cfg.Xray.ExcludeSets,
+		"exclude_ips":  cfg.Xray.ExcludeIPs,
+	}
 }
\ No newline at end of file
@@ -1,9 +1,13 @@
 package wizard
 
 import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/audit"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
 )
 
@@ -12,14 +16,14 @@ func TestHandler_Start(t *testing.T) {
 		sender := &trackingSender{}
 		configStore := &trackingConfigStore{
 			servers: []vpnconfig.Server{
-				{Name: "Server1", IPs: []string{"1.2.3.4"}},
+				{ID: "srv1", Name: "Server1", IPs: []string{"1.2.3.4"}},
 			},
 			vpnConfig: &vpnconfig.VPNDirectorConfig{},
 		}
 		vpnDirector := &mockVPNDirector{}
 		xrayGen := &mockXrayGenerator{}
 
-		handler := NewHandler(sender, configStore, vpnDirector, xrayGen)
+		handler := NewHandler(sender, configStore, vpnDirector, xrayGen, nil)
 		handler.Start(123)
 
 		// Verify at least one message was sent (server selection step)
@@ -38,13 +42,13 @@ func TestHandler_HandleCallback_Cancel(t *testing.T) {
 	t.Run("clears state on cancel", func(t *testing.T) {
 		sender := &trackingSender{}
 		configStore := &trackingConfigStore{
-			servers:   []vpnconfig.Server{{Name: "Server1", IPs: []string{"1.2.3.4"}}},
+			servers:   []vpnconfig.Server{{ID: "srv1", Name: "Server1", IPs: []string{"1.2.3.4"}}},
 			vpnConfig: &vpnconfig.VPNDirectorConfig{},
 		}
 		vpnDirector := &mockVPNDirector{}
 		xrayGen := &mockXrayGenerator{}
 
-		handler := NewHandler(sender, configStore, vpnDirector, xrayGen)
+		handler := NewHandler(sender, configStore, vpnDirector, xrayGen, nil)
 		handler.Start(123)
 
 		// Clear messages from Start
@@ -86,13 +90,13 @@ func TestHandler_HandleCallback_NoSession(t *testing.T) {
 		vpnDirector := &mockVPNDirector{}
 		xrayGen := &mockXrayGenerator{}
 
-		handler := NewHandler(sender, configStore, vpnDirector, xrayGen)
+		handler := NewHandler(sender, configStore, vpnDirector, xrayGen, nil)
 
 		// No Start() called - no session
 
 		cb := &tgbotapi.CallbackQuery{
 			ID:      "cb1",
-			Data:    "server:0",
+			Data:    "server:srv1",
 			Message: &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 123}},
 		}
 		handler.HandleCallback(cb)
@@ -109,7 +113,7 @@ func TestHandler_HandleCallback_Apply(t *testing.T) {
 		sender := &trackingSender{}
 		configStore := &trackingConfigStore{
 			servers: []vpnconfig.Server{
-				{Name: "Server1", IPs: []string{"1.2.3.4"}, Address: "srv.example.com", Port: 443, UUID: "uuid-1"},
+				{ID: "srv1", Name: "Server1", IPs: []string{"1.2.3.4"}, Address: "srv.example.com", Port: 443, UUID: "uuid-1"},
 			},
 			vpnConfig: &vpnconfig.VPNDirectorConfig{
 				DataDir: "/opt/vpn-director/data",
@@ -122,12 +126,12 @@ func TestHandler_HandleCallback_Apply(t *testing.T) {
 		vpnDirector := &mockVPNDirector{}
 		xrayGen := &mockXrayGenerator{}
 
-		handler := NewHandler(sender, configStore, vpnDirector, xrayGen)
+		handler := NewHandler(sender, configStore, vpnDirector, xrayGen, nil)
 		handler.Start(123)
 
 		// Setup state for apply
 		state := handler.manager.Get(123)
-		state.SetServerIndex(0)
+		state.SetServerID("srv1")
 		state.SetExclusion("ru", true)
 		state.AddClient(ClientRoute{IP: "192.168.1.10", Route: "xray"})
 		state.SetStep(StepConfirm)
@@ -152,6 +156,42 @@ func TestHandler_HandleCallback_Apply(t *testing.T) {
 			t.Error("expected VPN Apply to be called")
 		}
 	})
+
+	t.Run("records the change in the audit log", func(t *testing.T) {
+		configStore := &trackingConfigStore{
+			servers:   []vpnconfig.Server{{ID: "srv1", Name: "Server1", IPs: []string{"1.2.3.4"}}},
+			vpnConfig: &vpnconfig.VPNDirectorConfig{Xray: vpnconfig.XrayConfig{Clients: []string{"192.168.1.20"}}},
+		}
+		store := audit.NewStore(filepath.Join(t.TempDir(), audit.StoreFile))
+
+		handler := NewHandler(&trackingSender{}, configStore, &mockVPNDirector{}, &mockXrayGenerator{}, store)
+		handler.Start(123)
+		state := handler.manager.Get(123)
+		state.SetServerID("srv1")
+		state.AddClient(ClientRoute{IP: "192.168.1.10", Route: "xray"})
+		state.SetStep(StepConfirm)
+
+		handler.HandleCallback(&tgbotapi.CallbackQuery{
+			ID:      "cb1",
+			Data:    "apply",
+			From:    &tgbotapi.User{ID: 1, UserName: "admin"},
+			Message: &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 123}},
+		})
+
+		list, _ := store.List(audit.Filter{})
+		if len(list) != 1 {
+			t.Fatalf("expected one entry, got %d", len(list))
+		}
+		e := list[0]
+		if e.Actor != "@admin" || e.Action != "configure" || e.Target != "srv1" || e.Error != "" {
+			t.Errorf("unexpected entry %+v", e)
+		}
+		before, _ := json.Marshal(e.Before)
+		after, _ := json.Marshal(e.After)
+		if !strings.Contains(string(before), "192.168.1.20") || !strings.Contains(string(after), "192.168.1.10") {
+			t.Errorf("unexpected change %s -> %s", before, after)
+		}
+	})
 }
 
 func TestHandler_HandleCallback_RoutesToStep(t *testing.T) {
@@ -159,15 +199,15 @@ func TestHandler_HandleCallback_RoutesToStep(t *testing.T) {
 		sender := &trackingSender{}
 		configStore := &trackingConfigStore{
 			servers: []vpnconfig.Server{
-				{Name: "Server1", IPs: []string{"1.2.3.4"}},
-				{Name: "Server2", IPs: []string{"5.6.7.8"}},
+				{ID: "srv1", Name: "Server1", IPs: []string{"1.2.3.4"}},
+				{ID: "srv2", Name: "Server2", IPs: []string{"5.6.7.8"}},
 			},
 			vpnConfig: &vpnconfig.VPNDirectorConfig{},
 		}
 		vpnDirector := &mockVPNDirector{}
 		xrayGen := &mockXrayGenerator{}
 
-		handler := NewHandler(sender, configStore, vpnDirector, xrayGen)
+		handler := NewHandler(sender, configStore, vpnDirector, xrayGen, nil)
 		handler.Start(123)
 
 		// State should be at StepSelectServer
@@ -182,7 +222,7 @@ func TestHandler_HandleCallback_RoutesToStep(t *testing.T) {
 		// Select server (this should route to ServerStep)
 		cb := &tgbotapi.CallbackQuery{
 			ID:      "cb1",
-			Data:    "server:0",
+			Data:    "server:srv1",
 			Message: &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 123}, MessageID: 100},
 		}
 		handler.HandleCallback(cb)
@@ -192,9 +232,9 @@ func TestHandler_HandleCallback_RoutesToStep(t *testing.T) {
 			t.Errorf("expected step StepExclusions, got %s", state.GetStep())
 		}
 
-		// Server index should be set
-		if state.GetServerIndex() != 0 {
-			t.Errorf("expected server index 0, got %d", state.GetServerIndex())
+		// Server ID should be set
+		if state.GetServerID() != "srv1" {
+			t.Errorf("expected server ID srv1, got %q", state.GetServerID())
 		}
 	})
 }
@@ -209,7 +249,7 @@ func TestHandler_HandleTextInput(t *testing.T) {
 		vpnDirector := &mockVPNDirector{}
 		xrayGen := &mockXrayGenerator{}
 
-		handler := NewHandler(sender, configStore, vpnDirector, xrayGen)
+		handler := NewHandler(sender, configStore, vpnDirector, xrayGen, nil)
 
 		// No Start() - no session
 
@@ -229,14 +269,14 @@ func TestHandler_HandleTextInput(t *testing.T) {
 		sender := &trackingSender{}
 		configStore := &trackingConfigStore{
 			servers: []vpnconfig.Server{
-				{Name: "Server1", IPs: []string{"1.2.3.4"}},
+				{ID: "srv1", Name: "Server1", IPs: []string{"1.2.3.4"}},
 			},
 			vpnConfig: &vpnconfig.VPNDirectorConfig{},
 		}
 		vpnDirector := &mockVPNDirector{}
 		xrayGen := &mockXrayGenerator{}
 
-		handler := NewHandler(sender, configStore, vpnDirector, xrayGen)
+		handler := NewHandler(sender, configStore, vpnDirector, xrayGen, nil)
 		handler.Start(123)
 
 		// Set step to StepClientIP (awaiting IP input)
@@ -274,7 +314,7 @@ func TestHandler_GetManager(t *testing.T) {
 		vpnDirector := &mockVPNDirector{}
 		xrayGen := &mockXrayGenerator{}
 
-		handler := NewHandler(sender, configStore, vpnDirector, xrayGen)
+		handler := NewHandler(sender, configStore, vpnDirector, xrayGen, nil)
 
 		if handler.GetManager() == nil {
 			t.Error("expected manager to be accessible")
@@ -292,12 +332,12 @@ func TestHandler_HandleCallback_NilMessage(t *testing.T) {
 		vpnDirector := &mockVPNDirector{}
 		xrayGen := &mockXrayGenerator{}
 
-		handler := NewHandler(sender, configStore, vpnDirector, xrayGen)
+		handler := NewHandler(sender, configStore, vpnDirector, xrayGen, nil)
 
 		// Callback with nil Message (can happen in inline mode)
 		cb := &tgbotapi.CallbackQuery{
 			ID:      "cb1",
-			Data:    "server:0",
+			Data:    "server:srv1",
 			Message: nil,
 		}
 
@@ -317,12 +357,12 @@ func TestHandler_HandleCallback_NilMessage(t *testing.T) {
 		vpnDirector := &mockVPNDirector{}
 		xrayGen := &mockXrayGenerator{}
 
-		handler := NewHandler(sender, configStore, vpnDirector, xrayGen)
+		handler := NewHandler(sender, configStore, vpnDirector, xrayGen, nil)
 
 		// Callback with nil Chat
 		cb := &tgbotapi.CallbackQuery{
 			ID:      "cb1",
-			Data:    "server:0",
+			Data:    "server:srv1",
 			Message: &tgbotapi.Message{Chat: nil},
 		}
 
@@ -341,7 +381,7 @@ func TestHandler_HandleCallback_CancelWithoutSession(t *testing.T) {
 		vpnDirector := &mockVPNDirector{}
 		xrayGen := &mockXrayGenerator{}
 
-		handler := NewHandler(sender, configStore, vpnDirector, xrayGen)
+		handler := NewHandler(sender, configStore, vpnDirector, xrayGen, nil)
 
 		// No Start() called - no session exists
 
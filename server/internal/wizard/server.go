@@ -2,11 +2,11 @@ package wizard
 
 import (
 	"fmt"
-	"strconv"
 	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/telegram"
+	"github.com/zinin/asuswrt-merlin-vpn-director/server/internal/vpnconfig"
 )
 
 // ServerStep handles Step 1: server selection
@@ -38,7 +38,7 @@ func (s *ServerStep) Render(chatID int64, state *State) {
 	kb := telegram.NewKeyboard()
 	for i, srv := range servers {
 		btnText := fmt.Sprintf("%d. %s", i+1, srv.Name)
-		kb.Button(btnText, fmt.Sprintf("server:%d", i))
+		kb.Button(btnText, "server:"+srv.ID)
 	}
 	kb.Columns(cols)
 	kb.Button("Cancel", "cancel").Row()
@@ -55,26 +55,21 @@ func (s *ServerStep) HandleCallback(cb *tgbotapi.CallbackQuery, state *State) {
 		return
 	}
 
-	idxStr := strings.TrimPrefix(data, "server:")
-	idx, err := strconv.Atoi(idxStr)
-	if err != nil {
-		s.deps.Sender.Send(cb.Message.Chat.ID, telegram.EscapeMarkdownV2("Invalid server index"))
-		return
-	}
+	id := strings.TrimPrefix(data, "server:")
 
-	// Validate server index bounds
+	// Validate that the server still exists
 	servers, err := s.deps.Config.LoadServers()
 	if err != nil {
 		s.deps.Sender.Send(cb.Message.Chat.ID, telegram.EscapeMarkdownV2("Failed to load servers"))
 		return
 	}
 
-	if idx < 0 || idx >= len(servers) {
-		s.deps.Sender.Send(cb.Message.Chat.ID, telegram.EscapeMarkdownV2("Invalid server index"))
+	if vpnconfig.FindServer(servers, id) < 0 {
+		s.deps.Sender.Send(cb.Message.Chat.ID, telegram.EscapeMarkdownV2("Server not found"))
 		return
 	}
 
-	state.SetServerIndex(idx)
+	state.SetServerID(id)
 	state.SetStep(StepExclusions)
 	// Default: include ru in exclusions
 	state.SetExclusion("ru", true)
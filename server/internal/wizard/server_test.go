@@ -93,6 +93,8 @@ func (m *mockConfigStore) ScriptsDir() string {
 	return "/opt/vpn-director"
 }
 
+func (m *mockConfigStore) LockConfig() (func(), error) { return func() {}, nil }
+
 // mockManager to track wizard state clearing
 type mockManager struct {
 	clearedChatID int64
@@ -131,9 +133,9 @@ func TestServerStep_Render(t *testing.T) {
 		sender := &mockSender{}
 		configStore := &mockConfigStore{
 			servers: []vpnconfig.Server{
-				{Name: "Server1"},
-				{Name: "Server2"},
-				{Name: "Server3"},
+				{ID: "srv1", Name: "Server1"},
+				{ID: "srv2", Name: "Server2"},
+				{ID: "srv3", Name: "Server3"},
 			},
 		}
 
@@ -257,8 +259,8 @@ func TestServerStep_HandleCallback(t *testing.T) {
 		sender := &mockSender{}
 		configStore := &mockConfigStore{
 			servers: []vpnconfig.Server{
-				{Name: "Server1"},
-				{Name: "Server2"},
+				{ID: "srv1", Name: "Server1"},
+				{ID: "srv2", Name: "Server2"},
 			},
 		}
 
@@ -281,7 +283,7 @@ func TestServerStep_HandleCallback(t *testing.T) {
 		}
 
 		cb := &tgbotapi.CallbackQuery{
-			Data: "server:1",
+			Data: "server:srv2",
 			Message: &tgbotapi.Message{
 				Chat: &tgbotapi.Chat{ID: 123},
 			},
@@ -289,9 +291,9 @@ func TestServerStep_HandleCallback(t *testing.T) {
 
 		step.HandleCallback(cb, state)
 
-		// Should set server index
-		if state.GetServerIndex() != 1 {
-			t.Errorf("expected server index 1, got %d", state.GetServerIndex())
+		// Should set server ID
+		if state.GetServerID() != "srv2" {
+			t.Errorf("expected server ID srv2, got %q", state.GetServerID())
 		}
 
 		// Should advance to exclusions step
@@ -315,11 +317,11 @@ func TestServerStep_HandleCallback(t *testing.T) {
 		}
 	})
 
-	t.Run("ignores invalid server index", func(t *testing.T) {
+	t.Run("ignores unknown server ID", func(t *testing.T) {
 		sender := &mockSender{}
 		configStore := &mockConfigStore{
 			servers: []vpnconfig.Server{
-				{Name: "Server1"},
+				{ID: "srv1", Name: "Server1"},
 			},
 		}
 
@@ -340,7 +342,7 @@ func TestServerStep_HandleCallback(t *testing.T) {
 		}
 
 		cb := &tgbotapi.CallbackQuery{
-			Data: "server:5", // Invalid index
+			Data: "server:srv6", // Unknown ID
 			Message: &tgbotapi.Message{
 				Chat: &tgbotapi.Chat{ID: 123},
 			},
@@ -355,15 +357,15 @@ func TestServerStep_HandleCallback(t *testing.T) {
 
 		// Should not call next
 		if nextCalled {
-			t.Error("next callback should not be called for invalid index")
+			t.Error("next callback should not be called for unknown ID")
 		}
 	})
 
-	t.Run("ignores negative server index", func(t *testing.T) {
+	t.Run("ignores stale index-based callback", func(t *testing.T) {
 		sender := &mockSender{}
 		configStore := &mockConfigStore{
 			servers: []vpnconfig.Server{
-				{Name: "Server1"},
+				{ID: "srv1", Name: "Server1"},
 			},
 		}
 
@@ -393,7 +395,7 @@ func TestServerStep_HandleCallback(t *testing.T) {
 		step.HandleCallback(cb, state)
 
 		if nextCalled {
-			t.Error("next callback should not be called for negative index")
+			t.Error("next callback should not be called for index-based data")
 		}
 	})
 
@@ -401,7 +403,7 @@ func TestServerStep_HandleCallback(t *testing.T) {
 		sender := &mockSender{}
 		configStore := &mockConfigStore{
 			servers: []vpnconfig.Server{
-				{Name: "Server1"},
+				{ID: "srv1", Name: "Server1"},
 			},
 		}
 
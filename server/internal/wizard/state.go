@@ -21,14 +21,14 @@ type ClientRoute struct {
 }
 
 type State struct {
-	mu          sync.RWMutex
-	ChatID      int64
-	Step        Step
-	ServerIndex int
-	Exclusions  map[string]bool
-	ExcludeIPs  []string
-	Clients     []ClientRoute
-	PendingIP   string
+	mu         sync.RWMutex
+	ChatID     int64
+	Step       Step
+	ServerID   string
+	Exclusions map[string]bool
+	ExcludeIPs []string
+	Clients    []ClientRoute
+	PendingIP  string
 }
 
 // Thread-safe setters
@@ -38,10 +38,10 @@ func (s *State) SetStep(step Step) {
 	s.Step = step
 }
 
-func (s *State) SetServerIndex(idx int) {
+func (s *State) SetServerID(id string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.ServerIndex = idx
+	s.ServerID = id
 }
 
 func (s *State) SetExclusion(key string, value bool) {
@@ -111,10 +111,10 @@ func (s *State) GetStep() Step {
 	return s.Step
 }
 
-func (s *State) GetServerIndex() int {
+func (s *State) GetServerID() string {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.ServerIndex
+	return s.ServerID
 }
 
 func (s *State) GetPendingIP() string {
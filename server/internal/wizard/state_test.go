@@ -1,6 +1,7 @@
 package wizard
 
 import (
+	"fmt"
 	"sync"
 	"testing"
 )
@@ -51,11 +52,11 @@ func TestState_Step(t *testing.T) {
 	}
 }
 
-func TestState_ServerIndex(t *testing.T) {
+func TestState_ServerID(t *testing.T) {
 	s := &State{Exclusions: make(map[string]bool)}
-	s.SetServerIndex(5)
-	if s.GetServerIndex() != 5 {
-		t.Errorf("expected 5, got %d", s.GetServerIndex())
+	s.SetServerID("abcd1234")
+	if s.GetServerID() != "abcd1234" {
+		t.Errorf("expected abcd1234, got %q", s.GetServerID())
 	}
 }
 
@@ -177,7 +178,7 @@ func TestState_ConcurrentAccess(t *testing.T) {
 		go func(i int) {
 			defer wg.Done()
 			s.SetExclusion("key", i%2 == 0)
-			s.SetServerIndex(i)
+			s.SetServerID(fmt.Sprint(i))
 			s.SetStep(StepExclusions)
 			s.SetPendingIP("192.168.1.1")
 			s.AddClient(ClientRoute{IP: "1.1.1.1", Route: "xray"})
@@ -191,7 +192,7 @@ func TestState_ConcurrentAccess(t *testing.T) {
 		go func() {
 			defer wg.Done()
 			_ = s.GetExclusions()
-			_ = s.GetServerIndex()
+			_ = s.GetServerID()
 			_ = s.GetClients()
 			_ = s.GetStep()
 			_ = s.GetPendingIP()
@@ -211,7 +212,7 @@ func TestManager_ConcurrentSessions(t *testing.T) {
 		go func(chatID int64) {
 			defer wg.Done()
 			state := m.Start(chatID)
-			state.SetServerIndex(int(chatID))
+			state.SetServerID(fmt.Sprint(chatID))
 			state.SetStep(StepExclusions)
 
 			got := m.Get(chatID)
@@ -219,8 +220,8 @@ func TestManager_ConcurrentSessions(t *testing.T) {
 				t.Errorf("chatID %d: expected state, got nil", chatID)
 				return
 			}
-			if got.GetServerIndex() != int(chatID) {
-				t.Errorf("chatID %d: wrong server index", chatID)
+			if got.GetServerID() != fmt.Sprint(chatID) {
+				t.Errorf("chatID %d: wrong server ID", chatID)
 			}
 
 			m.Clear(chatID)
@@ -236,12 +237,12 @@ func TestManager_StartOverwritesPrevious(t *testing.T) {
 
 	// First session
 	state1 := m.Start(chatID)
-	state1.SetServerIndex(5)
+	state1.SetServerID("abcd1234")
 
 	// Start new session - should overwrite
 	state2 := m.Start(chatID)
-	if state2.GetServerIndex() != 0 {
-		t.Error("new session should have default server index 0")
+	if state2.GetServerID() != "" {
+		t.Error("new session should have no server selected")
 	}
 
 	// Old state should not be the same object
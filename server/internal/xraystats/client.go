@@ -0,0 +1,192 @@
+// Package xraystats queries traffic counters from the Xray StatsService.
+//
+// The service speaks gRPC. To keep the binary small it is called directly
+// over cleartext HTTP/2 with hand-encoded protobuf messages instead of
+// pulling in the gRPC and protobuf libraries.
+package xraystats
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultAddr is the loopback address of the Xray API in the generated config.
+	DefaultAddr = "127.0.0.1:10085"
+	// DefaultTimeout bounds a single query.
+	DefaultTimeout = 3 * time.Second
+
+	queryStatsMethod = "/xray.app.stats.command.StatsService/QueryStats"
+	maxResponseSize  = 4 << 20
+)
+
+// Stat is a raw named counter, e.g. "outbound>>>proxy-out>>>traffic>>>uplink".
+type Stat struct {
+	Name  string
+	Value int64
+}
+
+// Traffic is the uplink/downlink byte count of one inbound, outbound or user.
+type Traffic struct {
+	Tag      string `json:"tag"`
+	Uplink   int64  `json:"uplink"`
+	Downlink int64  `json:"downlink"`
+}
+
+// Summary groups traffic counters by kind. Counters are cumulative since
+// Xray started.
+type Summary struct {
+	Inbounds  []Traffic `json:"inbounds"`
+	Outbounds []Traffic `json:"outbounds"`
+	Users     []Traffic `json:"users"`
+}
+
+// Client calls the Xray StatsService.
+type Client struct {
+	addr string
+	http *http.Client
+}
+
+// NewClient creates a client for the API listening on addr (host:port).
+func NewClient(addr string) *Client {
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	return &Client{
+		addr: addr,
+		http: &http.Client{
+			Transport: &http.Transport{Protocols: &protocols},
+			Timeout:   DefaultTimeout,
+		},
+	}
+}
+
+// QueryStats returns all counters whose name contains pattern (all counters
+// if pattern is empty). If reset is true, Xray zeroes the returned counters.
+func (c *Client) QueryStats(ctx context.Context, pattern string, reset bool) ([]Stat, error) {
+	msg := encodeQueryStatsRequest(pattern, reset)
+
+	// gRPC message framing: compressed flag + big-endian length + message
+	body := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(body[1:], uint32(len(msg)))
+	body = append(body, msg...)
+
+	u := url.URL{Scheme: "http", Host: c.addr, Path: queryStatsMethod}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("xray api: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("xray api: HTTP %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("xray api: read response: %w", err)
+	}
+
+	// Trailers are only populated after the body is read. Errors without a
+	// body may also arrive as headers ("trailers-only" response).
+	status := resp.Trailer.Get("Grpc-Status")
+	if status == "" {
+		status = resp.Header.Get("Grpc-Status")
+	}
+	if status != "" && status != "0" {
+		message := resp.Trailer.Get("Grpc-Message")
+		if message == "" {
+			message = resp.Header.Get("Grpc-Message")
+		}
+		if m, err := url.PathUnescape(message); err == nil {
+			message = m
+		}
+		return nil, fmt.Errorf("xray api: grpc status %s: %s", status, message)
+	}
+
+	if len(data) < 5 {
+		return nil, fmt.Errorf("xray api: empty response")
+	}
+	if data[0] != 0 {
+		return nil, fmt.Errorf("xray api: compressed responses are not supported")
+	}
+	n := binary.BigEndian.Uint32(data[1:5])
+	if uint64(len(data)-5) < uint64(n) {
+		return nil, fmt.Errorf("xray api: truncated response")
+	}
+
+	stats, err := decodeQueryStatsResponse(data[5 : 5+n])
+	if err != nil {
+		return nil, fmt.Errorf("xray api: %w", err)
+	}
+	return stats, nil
+}
+
+// Summary queries all traffic counters and groups them by inbound, outbound
+// and user.
+func (c *Client) Summary(ctx context.Context) (*Summary, error) {
+	stats, err := c.QueryStats(ctx, "", false)
+	if err != nil {
+		return nil, err
+	}
+	return Summarize(stats), nil
+}
+
+// Summarize groups "<kind>>>><tag>>>>traffic>>><uplink|downlink>" counters.
+// Other counters are ignored. Entries are sorted by tag.
+func Summarize(stats []Stat) *Summary {
+	groups := map[string]map[string]*Traffic{
+		"inbound":  {},
+		"outbound": {},
+		"user":     {},
+	}
+	for _, s := range stats {
+		parts := strings.Split(s.Name, ">>>")
+		if len(parts) != 4 || parts[2] != "traffic" {
+			continue
+		}
+		group, ok := groups[parts[0]]
+		if !ok {
+			continue
+		}
+		t := group[parts[1]]
+		if t == nil {
+			t = &Traffic{Tag: parts[1]}
+			group[parts[1]] = t
+		}
+		switch parts[3] {
+		case "uplink":
+			t.Uplink = s.Value
+		case "downlink":
+			t.Downlink = s.Value
+		}
+	}
+	return &Summary{
+		Inbounds:  sortedTraffic(groups["inbound"]),
+		Outbounds: sortedTraffic(groups["outbound"]),
+		Users:     sortedTraffic(groups["user"]),
+	}
+}
+
+func sortedTraffic(m map[string]*Traffic) []Traffic {
+	out := make([]Traffic, 0, len(m))
+	for _, t := range m {
+		out = append(out, *t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Tag < out[j].Tag })
+	return out
+}
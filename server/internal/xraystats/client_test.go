@@ -0,0 +1,184 @@
+package xraystats
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// encodeStats builds a QueryStatsResponse the way Xray would.
+func encodeStats(stats []Stat) []byte {
+	var out []byte
+	for _, s := range stats {
+		var msg []byte
+		msg = binary.AppendUvarint(msg, 1<<3|wireBytes)
+		msg = binary.AppendUvarint(msg, uint64(len(s.Name)))
+		msg = append(msg, s.Name...)
+		msg = binary.AppendUvarint(msg, 2<<3|wireVarint)
+		msg = binary.AppendUvarint(msg, uint64(s.Value))
+
+		out = binary.AppendUvarint(out, 1<<3|wireBytes)
+		out = binary.AppendUvarint(out, uint64(len(msg)))
+		out = append(out, msg...)
+	}
+	return out
+}
+
+type fakeRequest struct {
+	pattern string
+	reset   bool
+}
+
+// newStandIn starts a cleartext HTTP/2 server acting as the Xray StatsService.
+func newStandIn(t *testing.T, handler func(req fakeRequest) ([]Stat, string)) (*httptest.Server, *[]fakeRequest) {
+	t.Helper()
+	var seen []fakeRequest
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 {
+			t.Errorf("expected HTTP/2, got %s", r.Proto)
+		}
+		if r.URL.Path != queryStatsMethod {
+			t.Errorf("unexpected method path %q", r.URL.Path)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/grpc" {
+			t.Errorf("unexpected content type %q", ct)
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		if len(body) < 5 || int(binary.BigEndian.Uint32(body[1:5])) != len(body)-5 {
+			t.Errorf("malformed gRPC frame: %v", body)
+			return
+		}
+		var req fakeRequest
+		_ = walkFields(body[5:], func(field, wire int, v uint64, b []byte) error {
+			switch field {
+			case 1:
+				req.pattern = string(b)
+			case 2:
+				req.reset = v == 1
+			}
+			return nil
+		})
+		seen = append(seen, req)
+
+		stats, status := handler(req)
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+		if status == "0" {
+			msg := encodeStats(stats)
+			frame := make([]byte, 5, 5+len(msg))
+			binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+			w.Write(append(frame, msg...))
+		} else {
+			w.Header().Set("Grpc-Message", "stats%20disabled")
+		}
+		w.Header().Set("Grpc-Status", status)
+	}))
+	srv.Config.Protocols = new(http.Protocols)
+	srv.Config.Protocols.SetUnencryptedHTTP2(true)
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv, &seen
+}
+
+func TestClient_QueryStats(t *testing.T) {
+	want := []Stat{
+		{Name: "outbound>>>proxy-out>>>traffic>>>uplink", Value: 1024},
+		{Name: "outbound>>>proxy-out>>>traffic>>>downlink", Value: 1 << 40},
+	}
+	srv, seen := newStandIn(t, func(fakeRequest) ([]Stat, string) { return want, "0" })
+
+	c := NewClient(strings.TrimPrefix(srv.URL, "http://"))
+	got, err := c.QueryStats(context.Background(), "outbound", true)
+	if err != nil {
+		t.Fatalf("QueryStats: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("QueryStats() = %+v, want %+v", got, want)
+	}
+	if len(*seen) != 1 || (*seen)[0] != (fakeRequest{pattern: "outbound", reset: true}) {
+		t.Errorf("unexpected request: %+v", *seen)
+	}
+}
+
+func TestClient_QueryStats_GRPCError(t *testing.T) {
+	srv, _ := newStandIn(t, func(fakeRequest) ([]Stat, string) { return nil, "12" })
+
+	c := NewClient(strings.TrimPrefix(srv.URL, "http://"))
+	_, err := c.QueryStats(context.Background(), "", false)
+	if err == nil || !strings.Contains(err.Error(), "stats disabled") {
+		t.Errorf("expected grpc error with message, got %v", err)
+	}
+}
+
+func TestClient_QueryStats_Unreachable(t *testing.T) {
+	c := NewClient("127.0.0.1:1")
+	if _, err := c.QueryStats(context.Background(), "", false); err == nil {
+		t.Error("expected error for unreachable API")
+	}
+}
+
+func TestClient_Summary(t *testing.T) {
+	srv, seen := newStandIn(t, func(fakeRequest) ([]Stat, string) {
+		return []Stat{
+			{Name: "inbound>>>tproxy-in>>>traffic>>>uplink", Value: 10},
+			{Name: "inbound>>>tproxy-in>>>traffic>>>downlink", Value: 20},
+			{Name: "outbound>>>proxy-out>>>traffic>>>uplink", Value: 30},
+			{Name: "outbound>>>proxy-out>>>traffic>>>downlink", Value: 40},
+		}, "0"
+	})
+
+	c := NewClient(strings.TrimPrefix(srv.URL, "http://"))
+	s, err := c.Summary(context.Background())
+	if err != nil {
+		t.Fatalf("Summary: %v", err)
+	}
+	if len(s.Inbounds) != 1 || s.Inbounds[0] != (Traffic{Tag: "tproxy-in", Uplink: 10, Downlink: 20}) {
+		t.Errorf("unexpected inbounds: %+v", s.Inbounds)
+	}
+	if len(s.Outbounds) != 1 || s.Outbounds[0] != (Traffic{Tag: "proxy-out", Uplink: 30, Downlink: 40}) {
+		t.Errorf("unexpected outbounds: %+v", s.Outbounds)
+	}
+	if (*seen)[0].reset {
+		t.Error("Summary must not reset counters")
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	s := Summarize([]Stat{
+		{Name: "outbound>>>proxy-out>>>traffic>>>downlink", Value: 5},
+		{Name: "outbound>>>direct>>>traffic>>>uplink", Value: 1},
+		{Name: "user>>>alice@example.com>>>traffic>>>uplink", Value: 7},
+		{Name: "inbound>>>api>>>other>>>uplink", Value: 99},
+		{Name: "garbage", Value: 1},
+	})
+	wantOut := []Traffic{{Tag: "direct", Uplink: 1}, {Tag: "proxy-out", Downlink: 5}}
+	if !reflect.DeepEqual(s.Outbounds, wantOut) {
+		t.Errorf("outbounds = %+v, want %+v", s.Outbounds, wantOut)
+	}
+	if len(s.Users) != 1 || s.Users[0].Tag != "alice@example.com" || s.Users[0].Uplink != 7 {
+		t.Errorf("unexpected users: %+v", s.Users)
+	}
+	if s.Inbounds == nil || len(s.Inbounds) != 0 {
+		t.Errorf("expected empty non-nil inbounds, got %+v", s.Inbounds)
+	}
+}
+
+func TestDecodeQueryStatsResponse_Truncated(t *testing.T) {
+	data := encodeStats([]Stat{{Name: "outbound>>>x>>>traffic>>>uplink", Value: 1}})
+	if _, err := decodeQueryStatsResponse(data[:len(data)-3]); err == nil {
+		t.Error("expected error for truncated message")
+	}
+}
+
+func TestEncodeQueryStatsRequest_Empty(t *testing.T) {
+	if got := encodeQueryStatsRequest("", false); len(got) != 0 {
+		t.Errorf("expected empty message, got %v", got)
+	}
+}
@@ -0,0 +1,117 @@
+package xraystats
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Minimal protobuf encoding for the two StatsService messages used here:
+//
+//	message QueryStatsRequest { string pattern = 1; bool reset = 2; }
+//	message Stat { string name = 1; int64 value = 2; }
+//	message QueryStatsResponse { repeated Stat stat = 1; }
+
+const (
+	wireVarint = 0
+	wireI64    = 1
+	wireBytes  = 2
+	wireI32    = 5
+)
+
+var errTruncated = errors.New("truncated protobuf message")
+
+// encodeQueryStatsRequest serializes a QueryStatsRequest.
+func encodeQueryStatsRequest(pattern string, reset bool) []byte {
+	var buf []byte
+	if pattern != "" {
+		buf = binary.AppendUvarint(buf, 1<<3|wireBytes)
+		buf = binary.AppendUvarint(buf, uint64(len(pattern)))
+		buf = append(buf, pattern...)
+	}
+	if reset {
+		buf = binary.AppendUvarint(buf, 2<<3|wireVarint)
+		buf = append(buf, 1)
+	}
+	return buf
+}
+
+// decodeQueryStatsResponse parses a QueryStatsResponse. Unknown fields are
+// skipped so newer Xray versions stay compatible.
+func decodeQueryStatsResponse(data []byte) ([]Stat, error) {
+	var stats []Stat
+	err := walkFields(data, func(field int, wire int, v uint64, b []byte) error {
+		if field != 1 || wire != wireBytes {
+			return nil
+		}
+		s, err := decodeStat(b)
+		if err != nil {
+			return err
+		}
+		stats = append(stats, s)
+		return nil
+	})
+	return stats, err
+}
+
+func decodeStat(data []byte) (Stat, error) {
+	var s Stat
+	err := walkFields(data, func(field int, wire int, v uint64, b []byte) error {
+		switch {
+		case field == 1 && wire == wireBytes:
+			s.Name = string(b)
+		case field == 2 && wire == wireVarint:
+			s.Value = int64(v)
+		}
+		return nil
+	})
+	return s, err
+}
+
+// walkFields calls fn for every field of a message. For varints v holds the
+// value; for length-delimited fields b holds the payload.
+func walkFields(data []byte, fn func(field int, wire int, v uint64, b []byte) error) error {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errTruncated
+		}
+		data = data[n:]
+		field, wire := int(key>>3), int(key&7)
+
+		var v uint64
+		var b []byte
+		switch wire {
+		case wireVarint:
+			v, n = binary.Uvarint(data)
+			if n <= 0 {
+				return errTruncated
+			}
+			data = data[n:]
+		case wireI64:
+			if len(data) < 8 {
+				return errTruncated
+			}
+			data = data[8:]
+		case wireI32:
+			if len(data) < 4 {
+				return errTruncated
+			}
+			data = data[4:]
+		case wireBytes:
+			l, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < l {
+				return errTruncated
+			}
+			b = data[n : n+int(l)]
+			data = data[n+int(l):]
+		default:
+			return fmt.Errorf("unsupported protobuf wire type %d", wire)
+		}
+
+		if err := fn(field, wire, v, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,4 +1,5 @@
 import axios from 'axios'
+import type { AccessQuery, AuditQuery, EventsQuery, LogStreamParams } from './types'
 
 const api = axios.create({
   withCredentials: true,
@@ -20,14 +21,33 @@ api.interceptors.response.use(
   },
 )
 
+// withoutEmpty drops unset filters so they are not sent as empty params.
+function withoutEmpty(query: AccessQuery | AuditQuery | EventsQuery): Record<string, string | number> {
+  const params: Record<string, string | number> = {}
+  for (const [key, value] of Object.entries(query)) {
+    if (value !== undefined && value !== '') params[key] = value
+  }
+  return params
+}
+
 export default {
   // Auth
   checkAuth: () =>
     api.get('/api/version', { skipAuthRedirect: true } as any),
   login: (username: string, password: string) =>
     api.post('/api/login', { username, password }),
+  loginTOTP: (challenge: string, code: string) =>
+    api.post('/api/login/totp', { challenge, code }),
+  telegramLoginEnabled: () =>
+    api.get('/api/login/telegram'),
+  startTelegramLogin: () =>
+    api.post('/api/login/telegram'),
+  pollTelegramLogin: (id: string) =>
+    api.get(`/api/login/telegram/${encodeURIComponent(id)}`),
   logout: () =>
     api.post('/api/logout'),
+  whoami: () =>
+    api.get('/api/whoami'),
 
   // Status & Control
   getStatus: () =>
@@ -42,22 +62,40 @@ export default {
     api.post('/api/ipsets/update'),
 
   // Info
-  getIP: () =>
-    api.get('/api/ip'),
+  getIP: (via?: string) =>
+    api.get('/api/ip', { params: via ? { via } : {} }),
   getVersion: () =>
     api.get('/api/version'),
+  checkRoutes: () =>
+    api.get('/api/diagnostics/routes'),
+  startSpeedTest: (route: string) =>
+    api.post('/api/speedtest', { route }),
+  getSpeedTest: (id: string) =>
+    api.get(`/api/speedtest/${encodeURIComponent(id)}`),
+  getSpeedTestHistory: (limit = 10) =>
+    api.get('/api/speedtest', { params: { limit } }),
+  getEvents: (query: EventsQuery = {}) =>
+    api.get('/api/events', { params: withoutEmpty(query) }),
 
   // Servers
   getServers: () =>
     api.get('/api/servers'),
-  selectServer: (index: number) =>
-    api.post('/api/servers/active', { index }),
+  selectServer: (id: string) =>
+    api.post('/api/servers/active', { id }),
   importServers: (url: string) =>
     api.post('/api/servers/import', { url }),
+  addServer: (uri: string) =>
+    api.post('/api/servers', { uri }),
+  updateServer: (id: string, fields: { name?: string; address?: string; port?: number; uuid?: string }) =>
+    api.put(`/api/servers/${encodeURIComponent(id)}`, fields),
+  deleteServer: (id: string) =>
+    api.delete(`/api/servers/${encodeURIComponent(id)}`),
 
   // Clients
   getClients: () =>
     api.get('/api/clients'),
+  getConnections: (client: string) =>
+    api.get('/api/connections', { params: { client } }),
   addClient: (ip: string, route: string) =>
     api.post('/api/clients', { ip, route }),
   pauseClient: (ip: string) =>
@@ -79,9 +117,99 @@ export default {
   deleteExcludeIP: (ip: string) =>
     api.delete('/api/excludes/ips', { params: { ip } }),
 
+  // Domain routing
+  getDomains: () =>
+    api.get('/api/domains'),
+  addDomain: (domain: string, route: string) =>
+    api.post('/api/domains', { domain, route }),
+  deleteDomain: (domain: string, route: string) =>
+    api.delete('/api/domains', { params: { domain, route } }),
+  getResolvedDomains: (route: string) =>
+    api.get('/api/domains/resolved', { params: { route } }),
+
+  // Address list optimization
+  previewOptimize: () =>
+    api.get('/api/optimize'),
+  applyOptimize: (fingerprint: string) =>
+    api.post('/api/optimize', { fingerprint }),
+
+  // Traffic accounting
+  getTraffic: () =>
+    api.get('/api/traffic'),
+
+  // Xray access log
+  searchAccess: (query: AccessQuery) =>
+    api.get('/api/access', { params: withoutEmpty(query) }),
+  topAccess: (by: string, query: AccessQuery) =>
+    api.get('/api/access/top', { params: { by, ...withoutEmpty(query) } }),
+
+  // API tokens
+  getTokens: () =>
+    api.get('/api/tokens'),
+  createToken: (name: string, scopes: string[]) =>
+    api.post('/api/tokens', { name, scopes }),
+  revokeToken: (id: string) =>
+    api.delete(`/api/tokens/${encodeURIComponent(id)}`),
+
+  // Sessions
+  getSessions: () =>
+    api.get('/api/sessions'),
+  revokeSession: (id: string) =>
+    api.delete(`/api/sessions/${encodeURIComponent(id)}`),
+  revokeOtherSessions: () =>
+    api.delete('/api/sessions'),
+
+  // Login bans
+  getBans: () =>
+    api.get('/api/bans'),
+  clearBan: (id: string) =>
+    api.delete(`/api/bans/${encodeURIComponent(id)}`),
+  clearAllBans: () =>
+    api.delete('/api/bans'),
+
+  // Local users
+  getUsers: () =>
+    api.get('/api/users'),
+  addUser: (username: string, password: string, role: string) =>
+    api.post('/api/users', { username, password, role }),
+  removeUser: (username: string) =>
+    api.delete(`/api/users/${encodeURIComponent(username)}`),
+  setUserPassword: (username: string, password: string, current?: string) =>
+    api.put(`/api/users/${encodeURIComponent(username)}/password`, { password, current }),
+
+  // Audit log
+  getAudit: (query: AuditQuery = {}) =>
+    api.get('/api/audit', { params: withoutEmpty(query) }),
+
+  // TLS certificate (the CA is downloaded via a plain link to /api/cert/ca)
+  getCert: () =>
+    api.get('/api/cert'),
+  generateCert: (hosts: string[]) =>
+    api.post('/api/cert/generate', { hosts }),
+  uploadCert: (cert: string, key: string) =>
+    api.post('/api/cert', { cert, key }),
+
+  // Two-factor authentication
+  getTOTP: () =>
+    api.get('/api/totp'),
+  enrollTOTP: () =>
+    api.post('/api/totp/enroll'),
+  confirmTOTP: (code: string) =>
+    api.post('/api/totp/confirm', { code }),
+  disableTOTP: (code: string) =>
+    api.post('/api/totp/disable', { code }),
+
   // Logs & Config
   getLogs: (source?: string, lines?: number) =>
     api.get('/api/logs', { params: { ...(source ? { source } : {}), ...(lines ? { lines } : {}) } }),
+  // Live logs over Server-Sent Events; the browser resumes via Last-Event-ID.
+  streamLogs: (params: LogStreamParams) => {
+    const query = new URLSearchParams()
+    for (const [key, value] of Object.entries(params)) {
+      if (value !== undefined && value !== '') query.set(key, String(value))
+    }
+    return new EventSource(`/api/logs/stream?${query}`, { withCredentials: true })
+  },
   getConfig: () =>
     api.get('/api/config'),
 
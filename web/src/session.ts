@@ -0,0 +1,25 @@
+import { ref } from 'vue'
+import api from './api'
+import type { Permission, WhoAmI } from './types'
+
+// The logged-in user, loaded from /api/whoami after login.
+export const whoami = ref<WhoAmI | null>(null)
+
+export async function loadWhoami() {
+  try {
+    whoami.value = (await api.whoami()).data
+  } catch {
+    whoami.value = null
+  }
+}
+
+// can hides actions the user's role does not allow; the server enforces them.
+export function can(permission: Permission): boolean {
+  return whoami.value?.permissions.includes(permission) ?? false
+}
+
+// canPause hides pause and resume for clients an operator does not own.
+export function canPause(ip: string): boolean {
+  if (can('manage_clients')) return true
+  return can('pause_clients') && (whoami.value?.clients?.includes(ip) ?? false)
+}
@@ -1,9 +1,11 @@
 export interface Server {
+  id: string
   name: string
   address: string
   port: number
   uuid: string
   ips: string[]
+  ips6?: string[]
 }
 
 export interface ClientInfo {
@@ -12,11 +14,361 @@ export interface ClientInfo {
   paused: boolean
 }
 
+export interface XrayTraffic {
+  tag: string
+  uplink: number
+  downlink: number
+}
+
+export interface XrayStats {
+  inbounds: XrayTraffic[]
+  outbounds: XrayTraffic[]
+  users: XrayTraffic[]
+}
+
 export interface StatusResponse {
   output: string
+  xray_stats?: XrayStats
+  xray_stats_error?: string
 }
 
 export interface VersionResponse {
   version: string
   commit: string
 }
+
+export interface DomainChangeResponse {
+  ok: boolean
+  dnsmasq_restarted: boolean
+  applied: boolean // the route's firewall rule was created or removed
+}
+
+export interface ResolvedDomainsResponse {
+  route: string
+  ipset: string
+  entries: string[]
+}
+
+export interface ListChange {
+  list: string
+  before: string[]
+  after: string[]
+}
+
+export interface ClientConflict {
+  entry: string
+  route: string
+  overlaps: string
+  other_route: string
+}
+
+export interface AddClientResponse {
+  ok: boolean
+  conflicts?: ClientConflict[] // clients of other routes overlapping the new one
+}
+
+export interface OptimizeResponse {
+  changes: ListChange[]
+  conflicts: ClientConflict[]
+  invalid: string[]
+  fingerprint: string // pass to applyOptimize to write exactly these changes
+  applied: boolean
+}
+
+export interface TrafficCounter {
+  bytes: number
+  packets: number
+}
+
+export interface TrafficUsage {
+  client?: string
+  route: string
+  upload: TrafficCounter
+  download: TrafficCounter
+}
+
+export interface TrafficWindow {
+  clients: TrafficUsage[]
+  routes: TrafficUsage[]
+  upload: TrafficCounter
+  download: TrafficCounter
+}
+
+export interface TrafficReport {
+  updated_at: string
+  hour: TrafficWindow
+  day: TrafficWindow
+  month: TrafficWindow
+}
+
+export interface Connection {
+  family: string
+  proto: string
+  state?: string
+  timeout: number
+  src: string
+  src_port?: number
+  dst: string
+  dst_port?: number
+  reply_dst?: string
+  mark: number
+  unreplied?: boolean
+  packets?: number
+  bytes?: number
+  route: string
+  mark_route?: string
+}
+
+export interface ConnectionClient {
+  ip: string
+  client: string
+  route: string
+  paused: boolean
+  connections: number
+  marked: number
+}
+
+export interface ConnectionSnapshot {
+  clients: ConnectionClient[]
+  connections: Connection[]
+  total: number
+}
+
+export interface IPInfo {
+  ip: string
+  country?: string
+  city?: string
+  asn?: string
+  isp?: string
+  provider: string
+}
+
+export interface RouteCheck {
+  name: string
+  kind: 'wan' | 'xray' | 'tunnel'
+  via?: string
+  exit?: IPInfo
+  resolver?: { ip: string; geo?: string }
+  status: 'ok' | 'warning' | 'error'
+  problems?: string[]
+}
+
+export interface RouteReport {
+  checked_at: string
+  routes: RouteCheck[]
+  ok: boolean
+}
+
+export interface SpeedTestResult {
+  time: string
+  route: string
+  latency_ms: number
+  jitter_ms: number
+  download_mbps: number
+  upload_mbps: number
+  download_bytes: number
+  upload_bytes: number
+  error?: string
+}
+
+export interface SpeedTestJob {
+  id: string
+  route: string
+  status: 'running' | 'done' | 'failed'
+  started_at: string
+  finished_at?: string
+  result?: SpeedTestResult
+  error?: string
+}
+
+export interface TimelineEvent {
+  time: string
+  kind: string
+  ok: boolean
+  component?: string
+  state?: 'up' | 'down'
+  source?: string
+  message?: string
+}
+
+export interface ComponentUptime {
+  component: string
+  state?: 'up' | 'down'
+  percent: number | null
+  known_seconds: number
+  downtime_seconds: number
+  outages: number
+}
+
+export interface EventsResponse {
+  from: string
+  to: string
+  uptime: ComponentUptime[]
+  events: TimelineEvent[]
+}
+
+export interface EventsQuery {
+  days?: number
+  kind?: string
+  component?: string
+  limit?: number
+}
+
+export interface AuditEntry {
+  time: string
+  source: 'web' | 'bot' | 'scheduler'
+  actor: string
+  action: string
+  target?: string
+  before?: unknown
+  after?: unknown
+  error?: string
+}
+
+export interface AuditQuery {
+  days?: number
+  source?: string
+  actor?: string
+  action?: string
+  target?: string
+  limit?: number
+}
+
+export type Permission = 'view' | 'pause_clients' | 'manage_clients' | 'manage_servers' | 'admin'
+
+export interface WhoAmI {
+  username: string
+  role?: 'admin' | 'operator' | 'viewer'
+  scopes?: string[]
+  auth: 'session' | 'token'
+  local?: boolean // a local user, who can change their password
+  permissions: Permission[]
+  clients?: string[] // clients an operator may pause and resume
+}
+
+export interface APIToken {
+  id: string
+  name: string
+  scopes: string[]
+  prefix: string
+  created: string
+  last_used?: string
+}
+
+export interface CreatedAPIToken {
+  token: string
+  info: APIToken
+}
+
+export interface TelegramLogin {
+  id: string
+  code: string
+  expires_in: number
+}
+
+export interface TelegramLoginStatus {
+  status: 'pending' | 'approved' | 'denied' | 'expired' | 'error'
+  error?: string
+}
+
+export interface Session {
+  id: string
+  username: string
+  ip: string
+  user_agent: string
+  created: string
+  last_seen: string
+  expires: string
+  current: boolean
+}
+
+export interface Ban {
+  id: string
+  kind: 'ip' | 'telegram'
+  source: string
+  reason: string
+  bans: number
+  banned_until: string
+}
+
+export interface LocalUser {
+  username: string
+  role: 'admin' | 'operator' | 'viewer'
+  created: string
+  updated: string
+}
+
+export interface UsersResponse {
+  backends: string[]
+  users: LocalUser[]
+}
+
+export interface CertInfo {
+  subject: string
+  issuer: string
+  dns_names: string[] | null
+  ip_addresses: string[] | null
+  not_before: string
+  not_after: string
+  fingerprint: string
+  local_ca: boolean
+}
+
+export interface TOTPStatus {
+  enabled: boolean
+  recovery_codes_left: number
+}
+
+export interface TOTPEnrollment {
+  secret: string
+  uri: string
+  qr: string // PNG data URI
+}
+
+export interface AccessRecord {
+  time: string
+  source: string
+  source_port: number
+  network?: string
+  destination: string
+  dest_port: number
+  inbound?: string
+  outbound?: string
+  status: 'accepted' | 'rejected'
+  reason?: string
+  email?: string
+}
+
+export interface AccessResult {
+  records: AccessRecord[]
+  total: number
+}
+
+export interface AccessGroup {
+  key: string
+  count: number
+  last_seen: string
+}
+
+export interface AccessQuery {
+  client?: string
+  destination?: string
+  outbound?: string
+  status?: string
+  since?: string
+  limit?: number
+}
+
+export interface LogLine {
+  source: string
+  offset: number
+  line: string
+}
+
+export interface LogStreamParams {
+  sources?: string
+  level?: string
+  q?: string
+  client?: string
+  tail?: number
+}